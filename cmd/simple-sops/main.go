@@ -1,80 +1,153 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/spf13/cobra"
 	"simple-sops/internal/cli"
+	"simple-sops/internal/cli/commands"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/run"
+	"simple-sops/internal/timing"
 	"simple-sops/pkg/logging"
-	"github.com/spf13/cobra"
 )
 
 var (
-	debug bool
-	quiet bool
+	debug         bool
+	quiet         bool
+	porcelain     bool
+	failFast      bool
+	timings       bool
+	installDeps   bool
+	identityStdin bool
+	project       string
+	sopsConfig    string
+	sopsConfigRef string
 )
 
 func main() {
+	// Remove temporary keys and decrypted files also when interrupted
+	keymgmt.HandleExitSignals()
+
 	// Initialize root command
 	rootCmd := &cobra.Command{
 		Use:   "simple-sops",
 		Short: "Simple SOPS Helper - Making encryption easier",
 		Long:  `A tool to simplify working with SOPS encryption and Age keys`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			logging.SetDebugMode(debug)
 			logging.SetQuietMode(quiet)
+			logging.SetPorcelainMode(porcelain)
+			encrypt.SetFailFast(failFast)
+			timing.Enable(timings)
+
+			// Operate on a registered project from anywhere
+			if project != "" {
+				if err := commands.UseProject(project); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+			}
+
+			// Evaluate the rules of a canonical .sops.yaml, e.g. in CI on a dirty tree
+			if err := useSopsConfig(); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+
+			// Apply the settings of the config and the environment, e.g. on CI
+			if err := cli.ApplyGlobalSettings(); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+
+			// Take the key from a wrapper script instead of a file or 1Password
+			if identityStdin {
+				if err := keymgmt.ReadStdinIdentity(os.Stdin); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+			}
+
+			// Remove temporary keys and decrypted files of killed processes
+			if cmd.Name() != "gc" {
+				if removed, err := keymgmt.SweepTempDirs(keymgmt.DefaultTempMaxAge, false); err != nil {
+					logging.Debug("Failed to sweep temporary directories: %v", err)
+				} else if len(removed) > 0 {
+					logging.Debug("Removed %d orphaned temporary directories", len(removed))
+				}
+			}
+
+			// Fail early with install hints instead of a raw exec error
+			if err := cli.CheckDependencies(cmd, installDeps); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			return nil
 		},
 	}
 
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug output")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Minimal output")
+	rootCmd.PersistentFlags().BoolVar(&porcelain, "porcelain", false, "Print tab-separated records of file, action and result for scripts")
+	rootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Stop bulk operations at the first file that fails")
+	rootCmd.PersistentFlags().BoolVar(&timings, "timings", false, "Report the duration of each phase per file")
+	rootCmd.PersistentFlags().StringVar(&project, "project", "", "Operate on a project registered with 'project add'")
+	_ = rootCmd.RegisterFlagCompletionFunc("project", commands.CompleteProjects)
+	rootCmd.PersistentFlags().StringVar(&sopsConfig, "config", "", "Read the rules from this .sops.yaml path or https:// URL")
+	rootCmd.PersistentFlags().StringVar(&sopsConfigRef, "config-ref", "", "Read the rules from a git object like origin/main:.sops.yaml")
+	rootCmd.PersistentFlags().BoolVar(&installDeps, "install-deps", false, "Install missing sops or age with brew, nix, apt-get or scoop")
+	rootCmd.PersistentFlags().BoolVar(&identityStdin, "identity-stdin", false, "Read the Age identity from stdin instead of the key file")
 
 	// Register all commands
 	cli.RegisterCommands(rootCmd)
 
-	// Special handling for no sub-commands (edit mode)
-	if len(os.Args) > 1 && !isCommand(os.Args[1]) && !isFlag(os.Args[1]) {
-		// Assume edit mode if first arg is a file
-		if fileExists(os.Args[1]) {
-			args := []string{"edit"}
-			args = append(args, os.Args[1:]...)
-			os.Args = append(os.Args[:1], args...)
-		}
+	// Edit a file given instead of a command (simple-sops secrets.yaml)
+	cli.EnableImplicitEdit(rootCmd)
+
+	// Add the aliases of the config and expand the one being run
+	aliases, err := config.LoadAliases()
+	if err != nil {
+		logging.Warn("Aliases are not available: %v", err)
 	}
+	aliases = cli.RegisterAliases(rootCmd, aliases)
+	os.Args = append(os.Args[:1], cli.ExpandAlias(rootCmd, os.Args[1:], aliases)...)
 
 	// Execute command
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
-	}
-}
+	err = rootCmd.Execute()
+	keymgmt.ClearStdinIdentity()
+	keymgmt.CleanupOnExit()
 
-// isCommand checks if the argument is a defined command
-func isCommand(arg string) bool {
-	commands := []string{
-		"encrypt", "decrypt", "edit", "set-keys", "config",
-		"rm", "clean-config", "get-key", "clear-key", "help",
-		"gen-key", "run", // New commands
-	}
-	for _, cmd := range commands {
-		if arg == cmd {
-			return true
+	// Report timings on stderr, so they don't mix with decrypted output
+	timing.Report(os.Stderr)
+
+	if err != nil {
+		// Pass on the exit code of commands started by run
+		var exitErr *run.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
 		}
-	}
-	return false
-}
 
-// isFlag checks if the argument is a flag
-func isFlag(arg string) bool {
-	return len(arg) > 0 && arg[0] == '-'
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitcode.Code(err))
+	}
 }
 
-// fileExists checks if a file exists
-func fileExists(path string) bool {
-	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
-		return false
+// useSopsConfig applies --config or --config-ref
+func useSopsConfig() error {
+	switch {
+	case sopsConfig != "" && sopsConfigRef != "":
+		return fmt.Errorf("--config and --config-ref can't be used together")
+	case sopsConfig != "":
+		return config.UseSopsConfig(sopsConfig)
+	case sopsConfigRef != "":
+		return config.UseSopsConfigRef(sopsConfigRef)
 	}
-	return !info.IsDir()
+	return nil
 }
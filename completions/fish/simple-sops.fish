@@ -2,7 +2,7 @@
 # Save this file to ~/.config/fish/completions/simple-sops.fish
 
 # Define all the subcommands
-set -l commands encrypt decrypt edit set-keys config rm clean-config get-key clear-key gen-key run help completion
+set -l commands encrypt decrypt edit set-keys config rm clean-config get-key clear-key gen-key run help completion trust status doctor rotate put ssh-keys migrate kubeconfig talos git verify drift version shell-init prompt-status gc clean re-encrypt apply key add-recipient new copy get expire view project migrate-recipients resolve bundle preflight sync explain team import-age export-age repl
 
 # Define files that can be encrypted/decrypted (matching supported file types)
 function __fish_simple_sops_files
@@ -22,6 +22,11 @@ function __fish_simple_sops_encrypted_files
     end
 end
 
+# Define the registered projects
+function __fish_simple_sops_projects
+    simple-sops __complete project remove "" 2>/dev/null | string match -v ":*"
+end
+
 # Complete subcommands
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a encrypt -d "Encrypt files with Age"
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a decrypt -d "Decrypt files"
@@ -35,6 +40,43 @@ complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a cle
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a gen-key -d "Generate a new Age key pair"
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a run -d "Run a command with a decrypted file"
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a completion -d "Generate shell completion scripts"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a trust -d "Manage the recipient trust store"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a status -d "Show encrypted files and rotation state"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a doctor -d "Check the environment for problems"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a rotate -d "Rotate data keys of encrypted files"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a put -d "Set a single value in an encrypted file"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a ssh-keys -d "List ssh-agent keys usable as Age identities"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a migrate -d "Migrate configuration of older versions"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a kubeconfig -d "Encrypt kubeconfig files with a preset"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a talos -d "Encrypt Talos configs with a preset"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a git -d "Integrate with git"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a verify -d "Check that covered files are encrypted"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a drift -d "Compare file recipients with .sops.yaml"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a version -d "Show the version of simple-sops"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a shell-init -d "Print shell functions that manage the key lifecycle"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a prompt-status -d "Print the key status for shell prompts"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a gc -d "Remove orphaned temporary keys and decrypted files"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a clean -d "Re-encrypt or delete decrypted files left in the repository"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a re-encrypt -d "Encrypt files decrypted in-place again"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a apply -d "Reconcile .sops.yaml and files with a manifest"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a key -d "Manage labels of recipients and back up keys"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a add-recipient -d "Add recipients to encrypted files"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a new -d "Create an encrypted file from a template"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a copy -d "Copy a decrypted value to the clipboard"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a get -d "Print a single decrypted value"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a expire -d "Record when a value expires"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a view -d "View a decrypted file in a read-only pager"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a project -d "Register repositories to use with --project"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a migrate-recipients -d "Move every file from an old recipient to new ones"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a resolve -d "Resolve a merge conflict in an encrypted file"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a bundle -d "Package encrypted files for an air-gapped machine"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a preflight -d "Check that secrets can be decrypted"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a sync -d "Keep decrypted copies of files in a directory"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a explain -d "Show the effective rule of a file"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a team -d "Maintain the team key registry"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a import-age -d "Convert files encrypted with age into sops files"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a export-age -d "Export an encrypted file as a plain age file"
+complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a repl -d "Work on encrypted files in an interactive session"
 complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands" -a help -d "Show help message"
 
 # File completions for the shorthand method (when no command is given, assume it's edit)
@@ -42,21 +84,44 @@ complete -c simple-sops -f -n "not __fish_seen_subcommand_from $commands && coun
 
 # Global options
 complete -c simple-sops -s q -l quiet -d "Minimal output"
+complete -c simple-sops -l porcelain -d "Print tab-separated records for scripts"
+complete -c simple-sops -l fail-fast -d "Stop bulk operations at the first failure"
+complete -c simple-sops -r -l config -d "Read the rules from this .sops.yaml path or https:// URL"
+complete -c simple-sops -x -l config-ref -d "Read the rules from a git object like origin/main:.sops.yaml"
 complete -c simple-sops -s d -l debug -d "Show debug information"
+complete -c simple-sops -l timings -d "Report the duration of each phase per file"
+complete -c simple-sops -l install-deps -d "Install missing sops or age"
+complete -c simple-sops -l identity-stdin -d "Read the Age identity from stdin"
+complete -c simple-sops -x -l project -a "(__fish_simple_sops_projects)" -d "Operate on a registered project"
 complete -c simple-sops -s k -l key-file -d "Age key file to use"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt decrypt rm rotate migrate kubeconfig talos gc clean re-encrypt apply add-recipient migrate-recipients" -l dry-run -d "Print the planned changes without making them"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from encrypt status verify rotate" -l scope -a "(__fish_complete_directories)" -d "Only work on files below this directory"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt set-keys rm" -s y -l yes -d "Save changes to .sops.yaml without showing them first"
 
 # Complete file arguments for encrypt (use non-encrypted files)
 complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt" -a "(__fish_simple_sops_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt" -l strict -d "Fail on recipients not in the trust store"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from encrypt" -l github-user -d "Also encrypt to a GitHub user's ed25519 SSH keys"
 
 # Complete file arguments for decrypt
 complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt" -a "(__fish_simple_sops_encrypted_files)"
 complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt" -a --stdout -d "Output to stdout"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt" -l format -a "shell github-env dotenv" -d "Print as environment variables"
+complete -c simple-sops -n "__fish_seen_subcommand_from decrypt" -s o -l output -r -d "Write the decrypted file to a path, or auto"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt" -l audience -r -d "Only decrypt files tagged for this audience"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt" -l require-recipient -r -d "Only decrypt files encrypted to these recipients"
+complete -c simple-sops -n "__fish_seen_subcommand_from encrypt" -s o -l output -r -d "Write an encrypted copy to a path, or auto"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from decrypt run" -l auto -d "Add plaintext outputs to .gitignore without asking"
 
 # Complete file arguments for edit
 complete -c simple-sops -f -n "__fish_seen_subcommand_from edit" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -n "__fish_seen_subcommand_from edit" -l lock -d "Record an advisory lock while editing"
 
 # Complete file arguments for set-keys (any yaml/json/ini files)
 complete -c simple-sops -f -n "__fish_seen_subcommand_from set-keys" -a "(__fish_simple_sops_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from set-keys" -l mac-only-encrypted -d "Compute the MAC over encrypted values only"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from set-keys" -l encrypted-comment-regex -d "Also encrypt comments matching this regex"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from set-keys" -l sops-version -d "Pin the sops version of the repository"
 
 # Complete file arguments for rm (any yaml/json/ini files)
 complete -c simple-sops -f -n "__fish_seen_subcommand_from rm" -a "(__fish_simple_sops_files)"
@@ -64,12 +129,202 @@ complete -c simple-sops -f -n "__fish_seen_subcommand_from rm" -a "(__fish_simpl
 # Complete file arguments for run
 complete -c simple-sops -f -n "__fish_seen_subcommand_from run && count (commandline -opc) = 2" -a "(__fish_simple_sops_encrypted_files)"
 complete -c simple-sops -f -n "__fish_seen_subcommand_from run && count (commandline -opc) = 3" -a "(__fish_complete_command)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -s s -l shell -d "Run the command with \$SHELL -c"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -s w -l watch -d "Restart the command on changes"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -l env-name -d "Variable for the decrypted file path"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -l insecure-output -d "Allow an output file in a directory other users can write to"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -l no-pty -d "Don't run the command on a pseudo-terminal"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -l no-template -d "Pass {{ }} in the arguments on unchanged"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from run" -l clean-env -d "Start the command from a minimal environment"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from run" -l keep-env -d "Variable to pass on with --clean-env, as NAME or PREFIX_*" -a "(set -n)"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from run" -l metrics-addr -d "Serve Prometheus metrics in watch mode"
 
 # Complete gen-key arguments
 complete -c simple-sops -f -n "__fish_seen_subcommand_from gen-key" -l force -s f -d "Overwrite existing key file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from gen-key" -l piv -d "Generate the identity on a YubiKey"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l slot -a "(seq 1 20)" -d "PIV slot of the identity"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l serial -d "Serial number of the YubiKey"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l name -d "Name of the identity on the YubiKey"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l pin-policy -a "always once never" -d "When the PIN is required"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l touch-policy -a "always cached never" -d "When a touch is required"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from gen-key" -l profile -d "Profile to use the YubiKey identity in"
+
+# Complete trust subcommands
+complete -c simple-sops -f -n "__fish_seen_subcommand_from trust" -a "add rm list" -d "Trust store action"
+
+# Complete rotate arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from rotate" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from rotate" -l due -d "Only rotate overdue files"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from rotate re-encrypt" -s j -l jobs -x -d "Number of files to process at once"
+
+# Complete put arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from put" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from put" -l path -d "Path of the value to set"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from put" -l from-stdin -d "Read the value from stdin"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from put" -l json -d "Treat the value as JSON"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from get copy put" -l document -x -d "Document of a multi-document YAML file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from put" -l generate -a "password hex uuid" -d "Generate a random value"
+
+# Complete migrate arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate" -a config -d "Migrate .sops.yaml and the config file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate" -s y -l yes -d "Apply the changes without asking"
+
+# Complete kubeconfig and talos arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from kubeconfig talos; and not __fish_seen_subcommand_from encrypt" -a encrypt -d "Encrypt files with the preset"
+complete -c simple-sops -n "__fish_seen_subcommand_from kubeconfig talos" -l recipient -d "Additional Age recipient" -r
+
+# Complete git arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from git; and not __fish_seen_subcommand_from credential-helper install-hook" -a credential-helper -d "Serve git credentials from an encrypted file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from git; and not __fish_seen_subcommand_from credential-helper install-hook" -a install-hook -d "Install hooks that verify encryption"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from install-hook" -l pre-commit -d "Install the pre-commit hook"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from install-hook" -l pre-push -d "Install the pre-push hook"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from install-hook" -l force -d "Replace existing hooks"
+
+# Complete verify arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from verify" -l staged -d "Check staged files"
+
+# Complete drift arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from drift" -a "(__fish_simple_sops_encrypted_files)"
+
+# Complete version arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from version" -s v -l verbose -d "Show build metadata and tool versions"
+
+# Complete shell-init arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from shell-init" -a "bash zsh fish"
+
+# Complete prompt-status arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from prompt-status" -l format -a "short json" -d "Output format"
+
+# Complete gc arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from gc" -l older-than -d "Only remove directories older than this"
+
+# Complete clean arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from clean" -l all -d "Clean decrypted files of all repositories"
+
+# Complete re-encrypt arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from re-encrypt" -a "(__fish_simple_sops_files)"
+
+# Complete apply arguments
+complete -c simple-sops -n "__fish_seen_subcommand_from apply" -s f -l file -r -F -d "Manifest describing the encrypted files"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from apply" -l prune -d "Remove rules for files missing from the manifest"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from apply" -s y -l yes -d "Apply the changes without asking"
+
+# Complete key subcommands
+complete -c simple-sops -f -n "__fish_seen_subcommand_from key; and not __fish_seen_subcommand_from label labels backup" -a label -d "Set or remove the label of a recipient"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from key; and not __fish_seen_subcommand_from label labels backup" -a labels -d "List the labels of recipients"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from key; and not __fish_seen_subcommand_from label labels backup" -a backup -d "Print the secret keys for a backup"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from label" -l repo -d "Store the label in .sops-keys.yaml"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from label" -l remove -d "Remove the label of a key"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from backup" -l qr -d "Show each key as a QR code"
+
+# Complete add-recipient arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from add-recipient" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt add-recipient" -l to -x -d "Members or groups of .simple-sops/keys.yaml"
+
+# Complete new arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from new; and test (count (commandline -opc)) -eq 2" -a "(path basename ~/.config/simple-sops/templates/* 2>/dev/null)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from new" -l list -d "List the available templates"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from new encrypt" -l set -x -d "Value of a template placeholder as NAME=value"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from new encrypt" -l generate -x -d "Generate a placeholder value as NAME=password[:len], hex[:len] or uuid"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from encrypt" -l from-template -x -a "(path basename ~/.config/simple-sops/templates/* 2>/dev/null)" -d "Create the file from a template"
+
+# Complete copy arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from copy" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from copy" -l qr -d "Show the value as a QR code"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from copy" -l timeout -x -d "Clear the clipboard after this duration"
+
+# Complete get arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from get" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from get" -l otp -d "Print the current code of an otpauth:// URI"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from get" -l qr -d "Show the value as a QR code"
+
+# Complete expire arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from expire" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from expire" -l note -x -d "Describe the value"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from expire" -l remove -d "Remove the expiry date of a value"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from status" -l expiring-within -x -d "Warn about values expiring within this time"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from status" -l certs -d "Show the certificates in encrypted files"
+
+# Complete view arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from view" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from view" -l timeout -x -d "Close the view after this duration"
+
+# Complete project arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from project; and not __fish_seen_subcommand_from add list remove" -a "add list remove" -d "Project command"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from project; and __fish_seen_subcommand_from add" -l profile -d "Key profile to use for the project"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from project; and __fish_seen_subcommand_from add" -l sops-config -d "The project's .sops.yaml, relative to its path"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from project; and __fish_seen_subcommand_from add" -l pattern -d "Restrict found encrypted files to a pattern"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from project; and __fish_seen_subcommand_from remove" -a "(__fish_simple_sops_projects)" -d "Project"
+
+# Complete migrate-recipients arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate-recipients" -l from -d "Recipients to remove"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate-recipients" -l to -d "Recipients to add instead"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate-recipients" -l also -d "Additional recipients, e.g. kms:<arn>"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from migrate-recipients" -l no-rotate -d "Apply the rules with updatekeys, keeping the data keys"
+
+# Complete resolve arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from resolve" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -n "__fish_seen_subcommand_from resolve" -l tool -d "Merge tool command" -r
+
+# Complete bundle arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from bundle; and not __fish_seen_subcommand_from decrypt" -a decrypt -d "Extract and decrypt a bundle"
+complete -c simple-sops -n "__fish_seen_subcommand_from bundle; and not __fish_seen_subcommand_from decrypt" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -n "__fish_seen_subcommand_from bundle; and not __fish_seen_subcommand_from decrypt" -s o -l output -r -d "Path of the bundle to write"
+complete -c simple-sops -n "__fish_seen_subcommand_from bundle; and __fish_seen_subcommand_from decrypt" -l dir -r -a "(__fish_complete_directories)" -d "Directory to extract into"
+
+# Complete preflight arguments
+complete -c simple-sops -n "__fish_seen_subcommand_from preflight" -s k -l key-file -r -d "Age key file to use"
+complete -c simple-sops -n "__fish_seen_subcommand_from preflight" -l canary -r -a "(__fish_simple_sops_encrypted_files)" -d "Encrypted file that must decrypt"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from preflight" -l format -a "text json" -d "Output format"
+
+# Complete sync arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from sync" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -n "__fish_seen_subcommand_from sync" -s k -l key-file -r -d "Age key file to use"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from sync" -l dir -a "(__fish_complete_directories)" -d "Directory to keep the decrypted files in"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from sync" -l interval -d "How often to check the files for changes"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from sync" -l mode -d "Permissions of the decrypted files"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from sync" -l once -d "Sync the files once and exit"
+complete -c simple-sops -x -n "__fish_seen_subcommand_from sync" -l metrics-addr -d "Serve Prometheus metrics on this address"
+
+complete -c simple-sops -n "__fish_seen_subcommand_from explain" -F
+
+complete -c simple-sops -f -n "__fish_seen_subcommand_from team; and not __fish_seen_subcommand_from sync" -a sync -d "Update a group from a GitHub team or GitLab group"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from team; and __fish_seen_subcommand_from sync" -l github-team -x -d "GitHub team as org/team"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from team; and __fish_seen_subcommand_from sync" -l gitlab-group -x -d "GitLab group by its full path"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from team; and __fish_seen_subcommand_from sync" -l group -x -d "Group of the registry to update"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from team; and __fish_seen_subcommand_from sync" -l rotate -d "Rotate the affected files without asking"
+
+complete -c simple-sops -n "__fish_seen_subcommand_from import-age" -F
+complete -c simple-sops -n "__fish_seen_subcommand_from import-age" -s o -l output -r -d "Path of the sops file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from import-age" -l remove -d "Delete the age files after importing them"
+
+complete -c simple-sops -f -n "__fish_seen_subcommand_from export-age" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -n "__fish_seen_subcommand_from export-age" -s o -l output -r -d "Path of the age file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from export-age" -l to -x -d "Recipients of the age file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from export-age" -s a -l armor -d "Write a PEM-encoded age file"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from export-age" -l strict -d "Fail on recipients not in the trust store"
 
 # Complete completion subcommand
 complete -c simple-sops -f -n "__fish_seen_subcommand_from completion" -a "bash zsh fish powershell" -d "Shell type"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from completion" -l install -d "Write the script to the completion directory of the shell"
+
+# Complete config arguments
+complete -c simple-sops -f -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from reorder import-from-files audience sign" -a reorder -d "Move specific rules before broader ones"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from reorder import-from-files audience sign" -a import-from-files -d "Create rules from encrypted files"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from reorder import-from-files audience sign" -a audience -d "Tag a rule with the audiences that need its files"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from reorder import-from-files audience sign" -a sign -d "Sign .sops.yaml with an SSH key"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from reorder import-from-files audience sign" -l full-keys -d "Show full public keys"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from import-from-files" -a "(__fish_simple_sops_encrypted_files)"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from reorder import-from-files audience" -l dry-run -d "Only show the changes"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from reorder import-from-files audience" -s y -l yes -d "Apply the changes without asking"
+complete -c simple-sops -n "__fish_seen_subcommand_from config; and __fish_seen_subcommand_from sign" -l key -r -d "SSH key to sign with"
+
+# No arguments for clean-config, get-key or clear-key
+complete -c simple-sops -f -n "__fish_seen_subcommand_from clean-config get-key clear-key"
 
-# No arguments for config, clean-config, get-key, clear-key, or help
-complete -c simple-sops -f -n "__fish_seen_subcommand_from config clean-config get-key clear-key help"
+# Complete help topics
+complete -c simple-sops -f -n "__fish_seen_subcommand_from help" -a keys -d "Creating, sharing and rotating Age keys"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from help" -a onepassword -d "Keeping the Age key in 1Password"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from help" -a kubernetes -d "Secrets, kubeconfigs and Talos configs for Kubernetes"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from help" -a exit-codes -d "Exit codes for scripts and CI"
+complete -c simple-sops -f -n "__fish_seen_subcommand_from get-key" -l print-path -d "Only print the path of the temporary key file"
@@ -0,0 +1,207 @@
+package bundle
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the files added to every bundle
+const (
+	// ConfigName is the subset of .sops.yaml with the rules of the bundled files
+	ConfigName = ".sops.yaml"
+	// InstructionsName explains how to decrypt the bundle
+	InstructionsName = "README.txt"
+)
+
+// instructions is the README of a bundle, with the list of files appended
+const instructions = `Encrypted files exported with simple-sops for offline decryption.
+
+The files are still encrypted. To decrypt them with simple-sops:
+
+    simple-sops bundle decrypt <bundle.tar> -k <age key file>
+
+Or extract the bundle and decrypt each file with sops alone:
+
+    tar -xf <bundle.tar>
+    SOPS_AGE_KEY_FILE=<age key file> sops --decrypt --in-place <file>
+
+.sops.yaml holds the rules of the bundled files, so they can be encrypted
+again with the same recipients.
+
+Files:
+`
+
+// Create writes a tar archive of encrypted files for offline decryption
+// The files keep their paths relative to .sops.yaml. The rules that apply to
+// them and instructions are added, so the bundle can be decrypted without the
+// repository. Plaintext files are refused.
+func Create(w io.Writer, configPath string, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no files specified")
+	}
+
+	var relPaths []string
+	sources := make(map[string]string)
+	for _, file := range files {
+		if !config.IsFileEncrypted(file) {
+			return fmt.Errorf("%s is not encrypted", file)
+		}
+		relPath := config.ConfigRelativePath(configPath, file)
+		if relPath == ConfigName || relPath == InstructionsName {
+			return fmt.Errorf("%s can't be bundled, the name is reserved", file)
+		}
+		if _, ok := sources[relPath]; ok {
+			continue
+		}
+		sources[relPath] = file
+		relPaths = append(relPaths, relPath)
+	}
+
+	sopsConfig := &config.SopsConfig{}
+	if _, err := os.Stat(configPath); err == nil {
+		loaded, err := config.LoadSopsConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load SOPS config: %w", err)
+		}
+		sopsConfig = subsetConfig(loaded, relPaths)
+	}
+	configData, err := yaml.Marshal(sopsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+
+	readme := instructions
+	for _, relPath := range relPaths {
+		readme += "    " + relPath + "\n"
+	}
+
+	archive := tar.NewWriter(w)
+	now := time.Now()
+	if err := writeEntry(archive, InstructionsName, []byte(readme), now); err != nil {
+		return err
+	}
+	if err := writeEntry(archive, ConfigName, configData, now); err != nil {
+		return err
+	}
+	for _, relPath := range relPaths {
+		data, err := os.ReadFile(sources[relPath])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", sources[relPath], err)
+		}
+		if err := writeEntry(archive, relPath, data, now); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// writeEntry adds a private file to a tar archive
+func writeEntry(archive *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0600,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", name, err)
+	}
+	if _, err := archive.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// subsetConfig returns the rules sops applies to the given files, in their order
+func subsetConfig(sopsConfig *config.SopsConfig, relPaths []string) *config.SopsConfig {
+	var pathRegexes []string
+	for _, relPath := range relPaths {
+		if rule, ok := config.MatchingRule(sopsConfig, relPath); ok {
+			pathRegexes = append(pathRegexes, rule.PathRegex)
+		}
+	}
+
+	subset := &config.SopsConfig{}
+	for _, rule := range sopsConfig.CreationRules {
+		if slices.Contains(pathRegexes, rule.PathRegex) {
+			subset.CreationRules = append(subset.CreationRules, rule)
+		}
+	}
+	return subset
+}
+
+// Extract unpacks a bundle into dir and returns the paths of the encrypted files
+// Only regular files with relative paths inside dir are accepted, and
+// existing files are never overwritten.
+func Extract(r io.Reader, dir string) ([]string, error) {
+	archive := tar.NewReader(r)
+	var files []string
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg {
+			return files, fmt.Errorf("unexpected entry %s in bundle, only files are allowed", header.Name)
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return files, fmt.Errorf("entry %s in bundle is outside of the bundle", header.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return files, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+		}
+		if err := writeExclusive(target, archive); err != nil {
+			return files, err
+		}
+
+		if name != ConfigName && name != InstructionsName {
+			files = append(files, target)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("the bundle contains no encrypted files")
+	}
+	return files, nil
+}
+
+// writeExclusive writes a new private file, failing if it already exists
+func writeExclusive(target string, r io.Reader) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists", target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const encryptedContent = "password: ENC[AES256_GCM,data:abc,type:str]\nsops:\n  version: 3.9.0\n"
+
+func TestCreateAndExtract(t *testing.T) {
+	repo := t.TempDir()
+	configPath := filepath.Join(repo, ".sops.yaml")
+	rules := `creation_rules:
+  - path_regex: (^|/)secrets/prod\.enc\.yaml$
+    age: age1prod
+  - path_regex: (^|/)secrets/dev\.enc\.yaml$
+    age: age1dev
+  - path_regex: .*
+    age: age1all
+`
+	if err := os.WriteFile(configPath, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "secrets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(repo, "secrets", "prod.enc.yaml")
+	if err := os.WriteFile(secret, []byte(encryptedContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Create(&buf, configPath, []string{secret, secret}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	files, err := Extract(bytes.NewReader(buf.Bytes()), dir)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := filepath.Join(dir, "secrets", "prod.enc.yaml")
+	if len(files) != 1 || files[0] != want {
+		t.Fatalf("Extract() = %v, want [%s]", files, want)
+	}
+	if data, _ := os.ReadFile(want); string(data) != encryptedContent {
+		t.Errorf("extracted file = %q", data)
+	}
+
+	// Only the rule of the bundled file is included
+	subset, err := os.ReadFile(filepath.Join(dir, ConfigName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(subset), "age1prod") || strings.Contains(string(subset), "age1dev") || strings.Contains(string(subset), "age1all") {
+		t.Errorf("bundled .sops.yaml = %s", subset)
+	}
+	if readme, _ := os.ReadFile(filepath.Join(dir, InstructionsName)); !strings.Contains(string(readme), "secrets/prod.enc.yaml") {
+		t.Errorf("README doesn't list the bundled file: %s", readme)
+	}
+
+	// Existing files are never overwritten
+	if _, err := Extract(bytes.NewReader(buf.Bytes()), dir); err == nil {
+		t.Error("Extract() overwrote existing files")
+	}
+}
+
+func TestCreateRefusesPlaintext(t *testing.T) {
+	repo := t.TempDir()
+	plain := filepath.Join(repo, "plain.yaml")
+	if err := os.WriteFile(plain, []byte("password: hunter2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Create(&bytes.Buffer{}, filepath.Join(repo, ".sops.yaml"), []string{plain}); err == nil {
+		t.Error("Create() bundled a plaintext file")
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	for _, header := range []*tar.Header{
+		{Typeflag: tar.TypeReg, Name: "../escape.yaml", Mode: 0600},
+		{Typeflag: tar.TypeReg, Name: "/etc/escape.yaml", Mode: 0600},
+		{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/passwd"},
+	} {
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		archive.Close()
+
+		if _, err := Extract(&buf, t.TempDir()); err == nil {
+			t.Errorf("Extract() accepted %s", header.Name)
+		}
+	}
+}
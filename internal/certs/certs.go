@@ -0,0 +1,101 @@
+// Package certs finds PEM certificates in decrypted files, so their expiry is
+// visible without piping values through openssl
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pemCertificateHeader starts a PEM encoded certificate
+const pemCertificateHeader = "-----BEGIN CERTIFICATE-----"
+
+// Certificate is a certificate found in a decrypted file
+type Certificate struct {
+	// Path is the sops tree path of the value, empty if the file isn't structured
+	Path     string
+	Subject  string
+	Issuer   string
+	NotAfter time.Time
+}
+
+// Find returns the certificates in the content of a decrypted file
+// Values of YAML and JSON files are searched one by one, other files as a whole.
+func Find(content []byte) []Certificate {
+	var document interface{}
+	if err := yaml.Unmarshal(content, &document); err == nil {
+		switch document.(type) {
+		case map[string]interface{}, []interface{}:
+			var certificates []Certificate
+			walk(document, "", &certificates)
+			return certificates
+		}
+	}
+
+	// Dotenv files keep multi-line values with escaped line breaks after the key
+	content = bytes.ReplaceAll(content, []byte(`\n`), []byte("\n"))
+	content = bytes.ReplaceAll(content, []byte(pemCertificateHeader), []byte("\n"+pemCertificateHeader))
+	return parse(content, "")
+}
+
+// walk collects the certificates in the string values of a document
+func walk(node interface{}, path string, certificates *[]Certificate) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walk(value[key], fmt.Sprintf("%s[%q]", path, key), certificates)
+		}
+	case []interface{}:
+		for i, item := range value {
+			walk(item, fmt.Sprintf("%s[%d]", path, i), certificates)
+		}
+	case string:
+		if strings.Contains(value, pemCertificateHeader) {
+			*certificates = append(*certificates, parse([]byte(value), path)...)
+			return
+		}
+		// Kubernetes secrets keep certificates base64 encoded in data
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && bytes.Contains(decoded, []byte(pemCertificateHeader)) {
+			*certificates = append(*certificates, parse(decoded, path)...)
+		}
+	}
+}
+
+// parse parses the PEM certificates in data, skipping invalid ones
+func parse(data []byte, path string) []Certificate {
+	var certificates []Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certificates
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certificates = append(certificates, Certificate{
+			Path:     path,
+			Subject:  certificate.Subject.String(),
+			Issuer:   certificate.Issuer.String(),
+			NotAfter: certificate.NotAfter,
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCertificate returns a self-signed PEM certificate
+func newCertificate(t *testing.T, commonName string, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestFind(t *testing.T) {
+	notAfter := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	leaf := newCertificate(t, "example.com", notAfter)
+	ca := newCertificate(t, "Example CA", notAfter.AddDate(5, 0, 0))
+
+	// Values of YAML files, including base64 encoded Kubernetes data
+	yamlContent := "tls:\n  crt: |\n" + indent(leaf+ca) + "  key: secret\n" +
+		"data:\n  tls.crt: " + base64.StdEncoding.EncodeToString([]byte(leaf)) + "\n"
+	certificates := Find([]byte(yamlContent))
+	if len(certificates) != 3 {
+		t.Fatalf("Expected 3 certificates, got %+v", certificates)
+	}
+	if certificates[0].Path != `["data"]["tls.crt"]` || certificates[1].Path != `["tls"]["crt"]` || certificates[2].Subject != "CN=Example CA" {
+		t.Errorf("Unexpected certificates: %+v", certificates)
+	}
+	if !certificates[1].NotAfter.Equal(notAfter) || certificates[1].Issuer != "CN=example.com" {
+		t.Errorf("Unexpected certificate: %+v", certificates[1])
+	}
+
+	// JSON arrays
+	jsonContent, _ := json.Marshal(map[string]interface{}{"chain": []string{leaf}})
+	if certificates := Find(jsonContent); len(certificates) != 1 || certificates[0].Path != `["chain"][0]` {
+		t.Errorf("Unexpected certificates in JSON: %+v", certificates)
+	}
+
+	// Plain PEM files and dotenv files with escaped line breaks
+	if certificates := Find([]byte(leaf)); len(certificates) != 1 || certificates[0].Path != "" {
+		t.Errorf("Unexpected certificates in PEM: %+v", certificates)
+	}
+	dotenv := "TLS_CRT=" + strings.ReplaceAll(leaf, "\n", `\n`) + "\n"
+	if certificates := Find([]byte(dotenv)); len(certificates) != 1 {
+		t.Errorf("Unexpected certificates in dotenv: %+v", certificates)
+	}
+
+	if certificates := Find([]byte("password: hunter2\n")); len(certificates) != 0 {
+		t.Errorf("Expected no certificates, got %+v", certificates)
+	}
+}
+
+// indent indents every line for a YAML block scalar
+func indent(text string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	return "    " + strings.Join(lines, "\n    ") + "\n"
+}
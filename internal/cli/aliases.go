@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// RegisterAliases adds the aliases of the config as commands
+// Aliases must start with a built-in command and can't replace one. The
+// commands only show up in help and completion, ExpandAlias rewrites the
+// arguments before they run.
+func RegisterAliases(rootCmd *cobra.Command, aliases config.Aliases) config.Aliases {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Aliases of aliases aren't expanded again, so only built-ins are targets
+	builtins := map[string]bool{"help": true, "completion": true}
+	for _, cmd := range rootCmd.Commands() {
+		builtins[cmd.Name()] = true
+		for _, alias := range cmd.Aliases {
+			builtins[alias] = true
+		}
+	}
+
+	registered := config.Aliases{}
+	for _, name := range names {
+		args, ok := aliases.Args(name)
+		if !ok {
+			continue
+		}
+		if builtins[name] {
+			logging.Warn("Alias %s is ignored, it would replace a built-in command", name)
+			continue
+		}
+		if !builtins[args[0]] {
+			logging.Warn("Alias %s is ignored, %s is not a simple-sops command", name, args[0])
+			continue
+		}
+
+		command := aliases[name]
+		rootCmd.AddCommand(&cobra.Command{
+			Use:                name,
+			Short:              "Alias for: " + command,
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, extra []string) error {
+				return fmt.Errorf("alias %s was not expanded", cmd.Name())
+			},
+		})
+		registered[name] = command
+	}
+	return registered
+}
+
+// ExpandAlias replaces an alias in the arguments with its command line
+// The alias is the first argument that is neither a global flag nor the value
+// of one, like the path of --project <path>. Arguments after the alias are
+// appended. Completion requests are expanded too, so they complete the
+// arguments of the aliased command.
+func ExpandAlias(rootCmd *cobra.Command, args []string, aliases config.Aliases) []string {
+	start := 0
+	if len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd) {
+		start = 1
+	}
+
+	for i := start; i < len(args); i++ {
+		if args[i] == "--" {
+			return args
+		}
+		if strings.HasPrefix(args[i], "-") {
+			if takesValue(rootCmd.PersistentFlags(), args[i]) {
+				i++
+			}
+			continue
+		}
+		expansion, ok := aliases.Args(args[i])
+		if !ok {
+			return args
+		}
+
+		expanded := append([]string{}, args[:i]...)
+		expanded = append(expanded, expansion...)
+		return append(expanded, args[i+1:]...)
+	}
+	return args
+}
+
+// takesValue checks if a flag argument like --project or -qk is followed by its value
+// Values given with = or attached to a shorthand, like -kkey.txt, are part of the argument.
+func takesValue(flags *pflag.FlagSet, arg string) bool {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		if strings.Contains(name, "=") {
+			return false
+		}
+		flag := flags.Lookup(name)
+		return flag != nil && flag.NoOptDefVal == ""
+	}
+
+	shorthands := strings.TrimPrefix(arg, "-")
+	for i := range len(shorthands) {
+		flag := flags.ShorthandLookup(shorthands[i : i+1])
+		if flag == nil {
+			return false
+		}
+		if flag.NoOptDefVal == "" {
+			return i == len(shorthands)-1
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"simple-sops/internal/config"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExpandAlias(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "simple-sops"}
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "")
+	rootCmd.PersistentFlags().BoolP("debug", "d", false, "")
+	rootCmd.PersistentFlags().String("project", "", "")
+	rootCmd.PersistentFlags().StringP("key-file", "k", "", "")
+	aliases := config.Aliases{"prod": "decrypt --stdout secrets/prod.yaml"}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"alias", []string{"prod", "-k", "key.txt"}, []string{"decrypt", "--stdout", "secrets/prod.yaml", "-k", "key.txt"}},
+		{"bool flag", []string{"-q", "prod"}, []string{"-q", "decrypt", "--stdout", "secrets/prod.yaml"}},
+		{"string flag", []string{"--project", "prod", "status"}, []string{"--project", "prod", "status"}},
+		{"string flag with alias", []string{"--project", "api", "prod"}, []string{"--project", "api", "decrypt", "--stdout", "secrets/prod.yaml"}},
+		{"string flag with =", []string{"--project=api", "prod"}, []string{"--project=api", "decrypt", "--stdout", "secrets/prod.yaml"}},
+		{"shorthands", []string{"-qk", "prod", "status"}, []string{"-qk", "prod", "status"}},
+		{"attached shorthand value", []string{"-kprod", "prod"}, []string{"-kprod", "decrypt", "--stdout", "secrets/prod.yaml"}},
+		{"completion", []string{cobra.ShellCompRequestCmd, "-d", "prod", ""}, []string{cobra.ShellCompRequestCmd, "-d", "decrypt", "--stdout", "secrets/prod.yaml", ""}},
+		{"after --", []string{"--", "prod"}, []string{"--", "prod"}},
+		{"no alias", []string{"status", "prod"}, []string{"status", "prod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandAlias(rootCmd, tt.args, aliases); !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandAlias(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
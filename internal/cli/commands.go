@@ -23,4 +23,46 @@ func RegisterCommands(rootCmd *cobra.Command) {
 	rootCmd.AddCommand(commands.GenerateKeyCmd())
 	rootCmd.AddCommand(commands.RunCmd())
 	rootCmd.AddCommand(commands.CompletionCmd())
+	rootCmd.AddCommand(commands.TrustCmd())
+	rootCmd.AddCommand(commands.StatusCmd())
+	rootCmd.AddCommand(commands.DoctorCmd())
+	rootCmd.AddCommand(commands.RotateCmd())
+	rootCmd.AddCommand(commands.PutCmd())
+	rootCmd.AddCommand(commands.SSHKeysCmd())
+	rootCmd.AddCommand(commands.MigrateCmd())
+	rootCmd.AddCommand(commands.KubeconfigCmd())
+	rootCmd.AddCommand(commands.TalosCmd())
+	rootCmd.AddCommand(commands.GitCmd())
+	rootCmd.AddCommand(commands.VerifyCmd())
+	rootCmd.AddCommand(commands.DriftCmd())
+	rootCmd.AddCommand(commands.VersionCmd())
+	rootCmd.AddCommand(commands.ShellInitCmd())
+	rootCmd.AddCommand(commands.PromptStatusCmd())
+	rootCmd.AddCommand(commands.GCCmd())
+	rootCmd.AddCommand(commands.CleanCmd())
+	rootCmd.AddCommand(commands.ReencryptCmd())
+	rootCmd.AddCommand(commands.ApplyCmd())
+	rootCmd.AddCommand(commands.KeyCmd())
+	rootCmd.AddCommand(commands.AddRecipientCmd())
+	rootCmd.AddCommand(commands.NewCmd())
+	rootCmd.AddCommand(commands.GetCmd())
+	rootCmd.AddCommand(commands.CopyCmd())
+	rootCmd.AddCommand(commands.ClearClipboardCmd())
+	rootCmd.AddCommand(commands.ExpireCmd())
+	rootCmd.AddCommand(commands.ViewCmd())
+	rootCmd.AddCommand(commands.ProjectCmd())
+	rootCmd.AddCommand(commands.MigrateRecipientsCmd())
+	rootCmd.AddCommand(commands.ResolveCmd())
+	rootCmd.AddCommand(commands.BundleCmd())
+	rootCmd.AddCommand(commands.PreflightCmd())
+	rootCmd.AddCommand(commands.SyncCmd())
+	rootCmd.AddCommand(commands.ExitCodesCmd())
+	rootCmd.AddCommand(commands.ExplainCmd())
+	rootCmd.AddCommand(commands.TeamCmd())
+	rootCmd.AddCommand(commands.ImportAgeCmd())
+	rootCmd.AddCommand(commands.ExportAgeCmd())
+	rootCmd.AddCommand(commands.ReplCmd())
+
+	// Help topics
+	rootCmd.AddCommand(commands.HelpTopicCmds()...)
 }
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// AddRecipientCmd returns the add-recipient command
+func AddRecipientCmd() *cobra.Command {
+	var (
+		to      []string
+		keyFile string
+		dryRun  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add-recipient --to <name|group|age1...> [file...]",
+		Short: "Add recipients to encrypted files",
+		Long: `Add recipients to the .sops.yaml rules of files and update the keys of the
+files, so the new recipients can decrypt them. Recipients are Age public keys
+or the names of members and groups in .simple-sops/keys.yaml.`,
+		Example: `  simple-sops add-recipient --to alice secrets.yaml
+  simple-sops add-recipient --to backend k8s/*.yaml`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetDryRun(dryRun)
+
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			recipients, err := resolveTeamRecipients(config.RepoRoot(configPath), to)
+			if err != nil {
+				return err
+			}
+
+			// Add the recipients to every rule in one change
+			var changed []string
+			addRecipients := func(sopsConfig *config.SopsConfig) error {
+				changed = nil
+				for _, file := range args {
+					added, err := config.AddRecipients(sopsConfig, config.ConfigRelativePath(configPath, file), recipients)
+					if err != nil {
+						return err
+					}
+					if len(added) > 0 {
+						changed = append(changed, file)
+					}
+				}
+				return nil
+			}
+
+			rules := config.NewRuleSet(configPath)
+			if dryRun {
+				sopsConfig, err := rules.Preview(addRecipients)
+				if err != nil {
+					return err
+				}
+				if err := encrypt.PrintConfigPreview(configPath, sopsConfig); err != nil {
+					return err
+				}
+			} else if _, err := rules.Update(addRecipients); err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
+			}
+
+			if len(changed) == 0 {
+				logging.Success("All files are already encrypted to these recipients.")
+				return nil
+			}
+
+			// Updating the keys of a file needs its current key
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			var updateErr error
+			for _, file := range changed {
+				if !config.IsFileEncrypted(file) {
+					logging.Info("%s is not encrypted, its rule applies when it is encrypted", file)
+					continue
+				}
+				if err := encrypt.UpdateKeys(file, keyPath); err != nil {
+					logging.Error("Failed to update the keys of %s: %v", file, err)
+					updateErr = err
+				}
+			}
+
+			return updateErr
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&to, "to", nil, "Recipients to add: Age public keys, member or group names")
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the planned changes without making them")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+// resolveTeamRecipients resolves member and group names of the team registry to their keys
+// Offboarded and expired members are left out.
+func resolveTeamRecipients(root string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	team, err := config.LoadTeam(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return team.Resolve(names)
+}
+
+// resolveRevokedTeamRecipients resolves names like resolveTeamRecipients, including revoked members
+func resolveRevokedTeamRecipients(root string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	team, err := config.LoadTeam(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return team.ResolveRevoked(names)
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// ApplyCmd returns the apply command
+func ApplyCmd() *cobra.Command {
+	var (
+		manifestPath string
+		keyFile      string
+		prune        bool
+		dryRun       bool
+		yes          bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply -f operations.yaml",
+		Short: "Reconcile .sops.yaml and encrypted files with a manifest",
+		Long: `Reconcile .sops.yaml and the encryption of files with a manifest listing
+every encrypted file, its recipients and its encrypted_regex. Missing rules
+are added and changed rules are updated, with a diff shown before anything is
+changed. Files that are not encrypted yet are encrypted, and files encrypted
+to other recipients get their recipients updated. Patterns of the manifest
+only add or update rules for path regexes. Rules of the manifest shadowed by
+an earlier rule are warned about. With --prune, rules for files missing from
+the manifest are removed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			manifest, err := config.LoadManifest(manifestPath)
+			if err != nil {
+				return err
+			}
+
+			// Get the SOPS config path
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			encrypt.SetDryRun(dryRun)
+
+			applied, err := applyManifestRules(configPath, manifest, prune, dryRun, yes)
+			if err != nil || !applied {
+				return err
+			}
+
+			return applyManifestFiles(configPath, manifest, keyFile, appConfig.AlwaysUseOnePassword, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVarP(&manifestPath, "file", "f", "", "Manifest describing the encrypted files")
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Remove rules for files missing from the manifest")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply the changes without asking")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// applyManifestRules updates the rules of .sops.yaml to match the manifest
+// It reports whether the files can be reconciled, which is not the case if the
+// changes were declined.
+func applyManifestRules(configPath string, manifest *config.Manifest, prune bool, dryRun bool, yes bool) (bool, error) {
+	rules := config.NewRuleSet(configPath)
+
+	var changes []string
+	apply := func(sopsConfig *config.SopsConfig) error {
+		var err error
+		changes, err = config.ApplyManifest(sopsConfig, manifest, prune)
+		return err
+	}
+
+	sopsConfig, err := rules.Preview(apply)
+	if err != nil {
+		return false, fmt.Errorf("failed to apply manifest: %w", err)
+	}
+	for _, conflict := range manifest.Conflicts(sopsConfig) {
+		logging.Warn("The rule %s is shadowed by the earlier rule %s, sops uses its recipients instead. Run 'simple-sops config reorder' to fix the order.", conflict.Rule.PathRegex, conflict.ShadowedBy.PathRegex)
+	}
+	if len(changes) == 0 {
+		logging.Success("Rules in %s match the manifest.", configPath)
+		return true, nil
+	}
+
+	diff, err := config.PreviewSopsConfig(configPath, sopsConfig)
+	if err != nil {
+		return false, err
+	}
+	if !confirmMigration(configPath, changes, diff, dryRun, yes) {
+		return dryRun, nil
+	}
+
+	if _, err := rules.Update(apply); err != nil {
+		return false, fmt.Errorf("failed to update SOPS config: %w", err)
+	}
+
+	logging.Success("Updated %d rule(s) in %s.", len(changes), configPath)
+	return true, nil
+}
+
+// applyManifestFiles encrypts the files of the manifest to their recipients
+// Plaintext files are encrypted with their rule and encrypted files with other
+// recipients get their recipients updated. The key is only needed for the latter.
+func applyManifestFiles(configPath string, manifest *config.Manifest, keyFile string, alwaysUseOnePassword bool, dryRun bool) error {
+	baseDir := config.RepoRoot(configPath)
+
+	var (
+		keyPath  string
+		applyErr error
+	)
+	for _, file := range manifest.Files {
+		if encrypt.FailFast() && applyErr != nil {
+			break
+		}
+
+		filePath := filepath.Join(baseDir, filepath.FromSlash(file.Path))
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			logging.Warn("%s does not exist, skipping", file.Path)
+			continue
+		}
+
+		if !config.IsFileEncrypted(filePath) {
+			// In dry-run mode the rule may not be saved, so sops can't be asked
+			if dryRun {
+				encrypt.PrintDryRun("Would encrypt: %s", filePath)
+				continue
+			}
+			if err := encrypt.ReencryptFile(filePath); err != nil {
+				logging.Error("Failed to encrypt %s: %v", file.Path, err)
+				applyErr = err
+			}
+			continue
+		}
+
+		metadata, err := config.ReadFileMetadata(filePath)
+		if err != nil {
+			logging.Error("Failed to read the metadata of %s: %v", file.Path, err)
+			applyErr = err
+			continue
+		}
+		if sameRecipients(metadata.Recipients, file.Recipients) {
+			logging.Debug("%s is encrypted to the manifest's recipients", file.Path)
+			continue
+		}
+		if dryRun {
+			encrypt.PrintDryRun("Would update the recipients of: %s", filePath)
+			continue
+		}
+
+		if keyPath == "" {
+			var isTemp bool
+			keyPath, isTemp, err = keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+		}
+
+		if err := encrypt.UpdateKeys(filePath, keyPath); err != nil {
+			logging.Error("Failed to update the recipients of %s: %v", file.Path, err)
+			applyErr = err
+		}
+	}
+
+	return applyErr
+}
+
+// sameRecipients checks if two recipient lists hold the same recipients in any order
+func sameRecipients(a []string, b []string) bool {
+	a = config.UniqueRecipients(a)
+	b = config.UniqueRecipients(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/bundle"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// BundleCmd returns the bundle command
+func BundleCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "bundle <file...>",
+		Short: "Package encrypted files for an air-gapped machine",
+		Long: `Package encrypted files into a tar archive for transfer to an air-gapped
+machine. The files stay encrypted and keep their paths relative to .sops.yaml.
+The rules of .sops.yaml that apply to them and instructions for decrypting
+them with or without simple-sops are added. Decrypt the bundle on the
+receiving side with bundle decrypt.`,
+		Example: `  simple-sops bundle secrets/prod.enc.yaml secrets/db.enc.env -o bundle.tar
+  simple-sops bundle decrypt bundle.tar -k /media/usb/key.txt`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			if err := bundle.Create(file, configPath, args); err != nil {
+				file.Close()
+				os.Remove(output)
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+
+			logging.Success("Bundled %d file(s) into %s", len(args), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Path of the bundle to write")
+	cmd.MarkFlagRequired("output")
+
+	cmd.AddCommand(bundleDecryptCmd())
+
+	return cmd
+}
+
+// bundleDecryptCmd returns the bundle decrypt command
+func bundleDecryptCmd() *cobra.Command {
+	var (
+		keyFile string
+		dir     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "decrypt <bundle>",
+		Short: "Extract and decrypt a bundle",
+		Long: `Extract a bundle made with simple-sops bundle and decrypt its files in place.
+The files are extracted into a directory named after the bundle, or the one
+given with --dir. Existing files are never overwritten.`,
+		Example: `  simple-sops bundle decrypt bundle.tar -k /media/usb/key.txt
+  simple-sops bundle decrypt bundle.tar --dir /srv/secrets`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+			if dir == "" {
+				dir = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+
+			// Check the key before anything is extracted
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open bundle: %w", err)
+			}
+			defer file.Close()
+
+			files, err := bundle.Extract(file, dir)
+			if err != nil {
+				return err
+			}
+			logging.Info("Extracted %d file(s) into %s", len(files), dir)
+
+			var decryptErr error
+			failed, succeeded := 0, 0
+			for _, path := range files {
+				if encrypt.FailFast() && decryptErr != nil {
+					break
+				}
+				if err := encrypt.DecryptFile(path, keyPath, encrypt.DecryptModeInPlace); err != nil {
+					logging.Error("Failed to decrypt %s: %v", path, err)
+					decryptErr = err
+					failed++
+					continue
+				}
+				succeeded++
+			}
+			return encrypt.BulkError(decryptErr, failed, succeeded)
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory to extract into (defaults to the name of the bundle)")
+
+	return cmd
+}
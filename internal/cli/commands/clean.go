@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// CleanCmd returns the clean command
+func CleanCmd() *cobra.Command {
+	var (
+		all    bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Re-encrypt or delete decrypted files left in the repository",
+		Long: `Re-encrypt or delete the plaintext files simple-sops wrote in the repository.
+Files decrypted in-place are encrypted again with the recipients of their
+.sops.yaml rule. Decrypted copies written with 'decrypt --output' or
+'run' with an output file are overwritten and deleted.
+
+Decrypted files are recorded in ~/.config/simple-sops/decrypted.yaml. With
+--dry-run the files that are still decrypted are listed without changing them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := ""
+			if !all {
+				var err error
+				if root, err = getRepoRoot(); err != nil {
+					return err
+				}
+			}
+
+			encrypt.SetDryRun(dryRun)
+
+			remaining, err := encrypt.CleanDecrypted(root)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case dryRun && len(remaining) == 0:
+				logging.Info("No decrypted files left.")
+			case dryRun:
+				logging.Info("%d decrypted file(s) left.", len(remaining))
+			case len(remaining) > 0:
+				// The failures are listed above, the usage doesn't help
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d decrypted file(s) could not be cleaned", len(remaining))
+			default:
+				logging.Success("No decrypted files left.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Clean decrypted files of all repositories")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the decrypted files without changing them")
+
+	return cmd
+}
@@ -3,19 +3,25 @@ package commands
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
 	"simple-sops/pkg/logging"
 )
 
 // ConfigCmd returns the config command
 func ConfigCmd() *cobra.Command {
+	var fullKeys bool
+
 	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Show current SOPS configurations",
-		Long:  `Display the current SOPS configuration settings.`,
+		Long: `Display the current SOPS configuration settings.
+Recipients are shown by their label in the trust store and a short
+fingerprint, unless --full-keys is used.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get the SOPS config path
 			configPath, err := config.GetSopsConfigPath()
@@ -39,16 +45,54 @@ func ConfigCmd() *cobra.Command {
 			logging.Info("Current SOPS configuration (%s):", configPath)
 			logging.Info("--------------------------")
 
+			// Load the trust store to flag unknown recipients
+			trustStore, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+			if err != nil {
+				return fmt.Errorf("failed to load trust store: %w", err)
+			}
+			labels, err := keymgmt.LoadKeyLabels(config.RepoRoot(configPath))
+			if err != nil {
+				return err
+			}
+
 			// Display rules
 			logging.Info("Rules:")
 			for _, rule := range sopsConfig.CreationRules {
 				logging.Info("")
 				logging.Info("File pattern: %s", rule.PathRegex)
-				logging.Info("  Age key: %s", rule.Age)
+				if fullKeys {
+					logging.Info("  Age key: %s", rule.Age)
+				} else {
+					logging.Info("  Age key: %s", labels.DescribeAll(rule.Age))
+				}
+
+				if len(trustStore.Keys) > 0 {
+					for _, pubKey := range trustStore.UnknownKeys(rule.Age) {
+						logging.Warn("Recipient %s is not in the trust store", pubKey)
+					}
+				}
 
 				if rule.EncryptedRegex != "" {
 					logging.Info("  Encrypts: %s", rule.EncryptedRegex)
 				}
+				if rule.EncryptedCommentRegex != "" {
+					logging.Info("  Encrypts comments: %s", rule.EncryptedCommentRegex)
+				}
+				if rule.MacOnlyEncrypted {
+					logging.Info("  MAC over encrypted values only")
+				}
+				if len(rule.Audiences) > 0 {
+					logging.Info("  Audiences: %s", strings.Join(rule.Audiences, ", "))
+				}
+			}
+
+			// sops applies the first matching rule, flag rules that never apply
+			if conflicts := config.FindConflicts(sopsConfig); len(conflicts) > 0 {
+				logging.Info("")
+				for _, conflict := range conflicts {
+					logging.Warn("Rule %s is shadowed by the earlier rule %s and never applies", conflict.Rule.PathRegex, conflict.ShadowedBy.PathRegex)
+				}
+				logging.Warn("Run 'simple-sops config reorder' to move specific rules first.")
 			}
 
 			logging.Info("")
@@ -58,6 +102,320 @@ func ConfigCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&fullKeys, "full-keys", false, "Show full public keys instead of labels and fingerprints")
+
+	cmd.AddCommand(configReorderCmd())
+	cmd.AddCommand(configImportCmd())
+	cmd.AddCommand(configAudienceCmd())
+	cmd.AddCommand(configSignCmd())
+
+	return cmd
+}
+
+// configReorderCmd returns the config reorder command
+func configReorderCmd() *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reorder",
+		Short: "Move specific rules before broader ones in .sops.yaml",
+		Long: `Reorder the rules of .sops.yaml, since sops applies the first matching rule.
+File rules are moved first, followed by patterns in their current order and
+the catch-all rule last. A diff is shown before anything is changed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get the SOPS config path
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			rules := config.NewRuleSet(configPath)
+
+			var resolved []config.RuleConflict
+			reorder := func(sopsConfig *config.SopsConfig) error {
+				resolved = config.ReorderRules(sopsConfig)
+				return nil
+			}
+
+			sopsConfig, err := rules.Preview(reorder)
+			if err != nil {
+				return fmt.Errorf("failed to load SOPS config: %w", err)
+			}
+			if len(sopsConfig.CreationRules) == 0 {
+				logging.Info("No SOPS configuration found at %s.", configPath)
+				return nil
+			}
+
+			diff, err := config.PreviewSopsConfig(configPath, sopsConfig)
+			if err != nil {
+				return err
+			}
+
+			if diff == "" {
+				logging.Success("The rules in %s are already ordered.", configPath)
+			} else {
+				var changes []string
+				for _, conflict := range resolved {
+					changes = append(changes, fmt.Sprintf("rule %s is no longer shadowed by %s", conflict.Rule.PathRegex, conflict.ShadowedBy.PathRegex))
+				}
+				if !confirmMigration(configPath, changes, diff, dryRun, yes) {
+					return nil
+				}
+
+				if sopsConfig, err = rules.Update(reorder); err != nil {
+					return fmt.Errorf("failed to update SOPS config: %w", err)
+				}
+				logging.Success("Reordered the rules in %s.", configPath)
+			}
+
+			// Rules shadowed by a broader pattern with the same priority need manual review
+			for _, conflict := range config.FindConflicts(sopsConfig) {
+				logging.Warn("Rule %s is still shadowed by %s, edit %s to resolve it", conflict.Rule.PathRegex, conflict.ShadowedBy.PathRegex, configPath)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply the changes without asking")
+
+	return cmd
+}
+
+// configAudienceCmd returns the config audience command
+func configAudienceCmd() *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audience <file|path_regex> [audience...]",
+		Short: "Tag a rule with the audiences that need its files",
+		Long: `Replace the audiences of the rule sops applies to a file, or of the rule
+with the given path regex. decrypt --audience only decrypts the files of rules
+tagged for the audience, so e.g. a frontend checkout doesn't hold the
+plaintext of the database secrets. Without audiences the tags are removed.
+sops ignores the audiences, they don't restrict who can decrypt a file.`,
+		Example: `  simple-sops config audience frontend/ frontend
+  simple-sops config audience secrets/db.enc.yaml ops backend
+  simple-sops decrypt --audience frontend`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encrypt.SetConfirmConfigChanges(!yes)
+			encrypt.SetDryRun(dryRun)
+
+			// Get the SOPS config path
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			// Files are relative to .sops.yaml, path regexes are taken as they are
+			target := args[0]
+			if _, err := os.Stat(target); err == nil {
+				target = config.ConfigRelativePath(configPath, target)
+			}
+
+			var pathRegex string
+			tag := func(sopsConfig *config.SopsConfig) error {
+				pathRegex, err = config.SetRuleAudiences(sopsConfig, target, args[1:])
+				return err
+			}
+
+			rules := config.NewRuleSet(configPath)
+			if dryRun {
+				sopsConfig, err := rules.Preview(tag)
+				if err != nil {
+					return err
+				}
+				return encrypt.PrintConfigPreview(configPath, sopsConfig)
+			}
+			if err := encrypt.ConfirmConfigChange(configPath, tag); err != nil {
+				return err
+			}
+			if _, err := rules.Update(tag); err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
+			}
+
+			if len(args) == 1 {
+				logging.Success("Removed the audiences of rule %s.", pathRegex)
+			} else {
+				logging.Success("Rule %s is tagged for %s.", pathRegex, strings.Join(args[1:], ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Save the change to .sops.yaml without showing it first")
+
+	return cmd
+}
+
+// configSignCmd returns the config sign command
+func configSignCmd() *cobra.Command {
+	var key string
+
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign .sops.yaml with an SSH key",
+		Long: `Sign .sops.yaml with an SSH key, so changes to its rules that didn't come from
+a trusted signer are noticed. The signature is stored in .sops.yaml.sig and
+must be committed with it. decrypt and verify check it against the allowed
+signers file of ssh-keygen in ~/.config/simple-sops/allowed_signers, once it
+exists. With config_signing.key set in the config, every change simple-sops
+makes to .sops.yaml is signed again.`,
+		Example: `  simple-sops config sign --key ~/.ssh/id_ed25519
+  echo "alice@example.com $(cat ~/.ssh/id_ed25519.pub)" >> ~/.config/simple-sops/allowed_signers`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			signing, err := config.LoadConfigSigning()
+			if err != nil {
+				return err
+			}
+			if key == "" {
+				key = signing.Key
+			}
+			if key == "" {
+				return fmt.Errorf("no signing key, pass --key or set config_signing.key in the config")
+			}
+
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			if _, err := os.Stat(configPath); err != nil {
+				return fmt.Errorf("no SOPS configuration found at %s", configPath)
+			}
+			if err := config.SignConfig(configPath, key); err != nil {
+				return err
+			}
+			logging.Success("Signed %s, commit %s with it.", configPath, config.SignaturePath(configPath))
+
+			// Tell the signer whether others can check the signature
+			status, signer, err := config.CheckConfigSignature(configPath, signing)
+			switch {
+			case err != nil:
+				logging.Warn("Failed to check the signature: %v", err)
+			case status == config.SignatureDisabled:
+				logging.Info("Add trusted signers to the allowed signers file to check signatures.")
+			case status == config.SignatureValid:
+				logging.Info("The signature of %s is valid.", signer)
+			default:
+				logging.Warn("Your key is not in the allowed signers file, the signature is reported as invalid.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&key, "key", "", "SSH key to sign with (defaults to config_signing.key)")
+
+	return cmd
+}
+
+// warnUnsignedConfig warns if .sops.yaml changed without a valid signature
+// Someone able to change .sops.yaml could add their recipient to the rules.
+func warnUnsignedConfig(configPath string) {
+	if _, err := os.Stat(configPath); err != nil {
+		return
+	}
+	signing, err := config.LoadConfigSigning()
+	if err != nil {
+		logging.Debug("Failed to load the signing settings: %v", err)
+		return
+	}
+
+	status, signer, err := config.CheckConfigSignature(configPath, signing)
+	switch {
+	case err != nil:
+		logging.Warn("Failed to check the signature of %s: %v", configPath, err)
+	case status == config.SignatureMissing:
+		logging.Warn("%s is not signed. Check its recipients before trusting it.", configPath)
+	case status == config.SignatureInvalid:
+		logging.Warn("%s changed since it was signed or isn't signed by an allowed signer. Check its recipients before trusting it.", configPath)
+	case status == config.SignatureValid:
+		logging.Debug("%s is signed by %s", configPath, signer)
+	}
+}
+
+// configImportCmd returns the config import-from-files command
+func configImportCmd() *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import-from-files [file...]",
+		Short: "Create .sops.yaml rules from the metadata of encrypted files",
+		Long: `Create creation rules for files that were encrypted with sops directly.
+The recipients and encrypted_regex of each rule are read from the file's sops
+metadata. Without arguments all encrypted files in the repository are
+imported. Files already covered by a rule are skipped. A diff is shown before
+anything is changed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get the SOPS config path
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			files := args
+			if len(files) == 0 {
+				if files, err = config.FindEncryptedFiles(config.RepoRoot(configPath)); err != nil {
+					return fmt.Errorf("failed to find encrypted files: %w", err)
+				}
+			}
+
+			rules := config.NewRuleSet(configPath)
+
+			var imported []config.ImportedRule
+			importRules := func(sopsConfig *config.SopsConfig) error {
+				var err error
+				imported, err = config.ImportRules(sopsConfig, configPath, files)
+				return err
+			}
+
+			sopsConfig, err := rules.Preview(importRules)
+			if err != nil {
+				return fmt.Errorf("failed to import rules: %w", err)
+			}
+			if len(imported) == 0 {
+				logging.Success("All encrypted files are covered by a rule in %s.", configPath)
+				return nil
+			}
+
+			diff, err := config.PreviewSopsConfig(configPath, sopsConfig)
+			if err != nil {
+				return err
+			}
+
+			var changes []string
+			for _, rule := range imported {
+				changes = append(changes, fmt.Sprintf("added rule for %s with recipients %s", rule.Path, config.AgeRecipients(rule.Recipients)))
+			}
+			if !confirmMigration(configPath, changes, diff, dryRun, yes) {
+				return nil
+			}
+
+			if _, err := rules.Update(importRules); err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
+			}
+
+			logging.Success("Imported %d rule(s) into %s.", len(imported), configPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply the changes without asking")
+
 	return cmd
 }
 
@@ -74,22 +432,31 @@ func CleanConfigCmd() *cobra.Command {
 				return fmt.Errorf("failed to determine SOPS config path: %w", err)
 			}
 
-			// Load the SOPS config
-			sopsConfig, err := config.LoadSopsConfig(configPath)
-			if err != nil {
-				return fmt.Errorf("failed to load SOPS config: %w", err)
+			rules := config.NewRuleSet(configPath)
+
+			// Clean orphaned rules, the rules may change while waiting for confirmation
+			var orphanedCount int
+			cleanOrphaned := func(sopsConfig *config.SopsConfig) error {
+				count, err := config.CleanOrphanedRules(sopsConfig, config.RepoRoot(configPath))
+				if err != nil {
+					return fmt.Errorf("failed to clean orphaned rules: %w", err)
+				}
+				orphanedCount = count
+				return nil
 			}
 
 			// Check if config file exists and has rules
+			sopsConfig, err := rules.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load SOPS config: %w", err)
+			}
 			if len(sopsConfig.CreationRules) == 0 {
 				logging.Info("No SOPS configuration found at %s. Nothing to clean up.", configPath)
 				return nil
 			}
 
-			// Clean orphaned rules
-			orphanedCount, err := config.CleanOrphanedRules(sopsConfig)
-			if err != nil {
-				return fmt.Errorf("failed to clean orphaned rules: %w", err)
+			if _, err := rules.Preview(cleanOrphaned); err != nil {
+				return err
 			}
 
 			if orphanedCount == 0 {
@@ -104,8 +471,9 @@ func CleanConfigCmd() *cobra.Command {
 			}
 
 			// Save the updated config
-			if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
-				return fmt.Errorf("failed to save SOPS config: %w", err)
+			sopsConfig, err = rules.Update(cleanOrphaned)
+			if err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
 			}
 
 			logging.Success("Removed %d orphaned rules from %s.", orphanedCount, configPath)
@@ -131,26 +499,36 @@ func CleanConfigCmd() *cobra.Command {
 
 // RemoveCmd returns the rm command
 func RemoveCmd() *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
 	cmd := &cobra.Command{
 		Use:   "rm [file...]",
 		Short: "Remove files and their SOPS configurations",
-		Long:  `Remove files and their corresponding rules from the SOPS configuration.`,
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Remove files and their corresponding rules from the SOPS configuration.
+Each change to .sops.yaml is shown as a diff and saved only once confirmed,
+unless --yes is given.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			encrypt.SetConfirmConfigChanges(!yes)
+
 			// Get the SOPS config path
 			configPath, err := config.GetSopsConfigPath()
 			if err != nil {
 				return fmt.Errorf("failed to determine SOPS config path: %w", err)
 			}
 
-			// Load the SOPS config
-			sopsConfig, err := config.LoadSopsConfig(configPath)
+			// In dry-run mode the changes are collected and previewed once
+			rules := config.NewRuleSet(configPath)
+			preview, err := rules.Load()
 			if err != nil {
 				return fmt.Errorf("failed to load SOPS config: %w", err)
 			}
 
 			for _, filePath := range args {
-				fileName := filepath.Base(filePath)
+				fileName := config.ConfigRelativePath(configPath, filePath)
 
 				// Check if the file exists
 				fileExists := true
@@ -158,10 +536,12 @@ func RemoveCmd() *cobra.Command {
 					logging.Info("Warning: File %s not found.", filePath)
 					fileExists = false
 
-					if !logging.Confirm("Do you want to still check and clean up SOPS configuration for this file?") {
+					if !dryRun && !logging.Confirm("Do you want to still check and clean up SOPS configuration for this file?") {
 						logging.Info("Skipping %s...", filePath)
 						continue
 					}
+				} else if dryRun {
+					encrypt.PrintDryRun("Would remove: %s", filePath)
 				} else if fileExists {
 					// Prompt for confirmation
 					if !logging.Confirm(fmt.Sprintf("This will remove the file %s and its SOPS configuration. Are you sure?", filePath)) {
@@ -177,13 +557,28 @@ func RemoveCmd() *cobra.Command {
 					}
 				}
 
-				// Check if there's a rule for this file
+				// Remove the rule for this file, if there is one
 				ruleExists := false
-				for _, rule := range sopsConfig.CreationRules {
-					if rule.PathRegex == fileName {
-						ruleExists = true
-						break
+				removeRule := func(sopsConfig *config.SopsConfig) error {
+					if _, ruleExists = config.GetCreationRule(sopsConfig, fileName); !ruleExists {
+						return nil
+					}
+					if err := config.RemoveCreationRule(sopsConfig, fileName); err != nil {
+						return fmt.Errorf("failed to remove rule for %s: %w", fileName, err)
 					}
+					return nil
+				}
+
+				// The config isn't saved in dry-run mode, so the preview shows
+				// the combined changes once all files are processed
+				if dryRun {
+					err = removeRule(preview)
+				} else if err = encrypt.ConfirmConfigChange(configPath, removeRule); err == nil {
+					_, err = rules.Update(removeRule)
+				}
+				if err != nil {
+					logging.Error("Failed to update SOPS config: %v", err)
+					continue
 				}
 
 				if !ruleExists {
@@ -191,19 +586,22 @@ func RemoveCmd() *cobra.Command {
 					continue
 				}
 
-				// Remove the rule
-				if err := config.RemoveCreationRule(sopsConfig, fileName); err != nil {
-					logging.Error("Failed to remove rule for %s: %v", fileName, err)
-					continue
+				if !dryRun {
+					logging.Success("SOPS configuration for %s removed successfully.", fileName)
 				}
+			}
 
-				// Save the updated config
-				if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
-					logging.Error("Failed to save SOPS config: %v", err)
-					continue
+			if dryRun {
+				if len(preview.CreationRules) == 0 {
+					encrypt.PrintDryRun("Would offer to remove %s since it no longer contains any rules", configPath)
+					return nil
 				}
+				return encrypt.PrintConfigPreview(configPath, preview)
+			}
 
-				logging.Success("SOPS configuration for %s removed successfully.", fileName)
+			sopsConfig, err := rules.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load SOPS config: %w", err)
 			}
 
 			// Check if the config is now empty
@@ -222,5 +620,8 @@ func RemoveCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files and SOPS configuration changes without removing anything")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Save changes to .sops.yaml without showing them first")
+
 	return cmd
 }
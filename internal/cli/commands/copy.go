@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"simple-sops/internal/clipboard"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/qr"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// CopyCmd returns the copy command
+func CopyCmd() *cobra.Command {
+	var (
+		keyFile  string
+		timeout  time.Duration
+		showQR   bool
+		document int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "copy [file] [path]",
+		Short: "Copy a single decrypted value to the clipboard",
+		Long: `Copy a single decrypted value to the system clipboard, e.g. to paste a secret
+into a web console. The value is never printed. It's cleared from the clipboard
+after a timeout (45s by default, clipboard.timeout in the config), unless
+something else was copied in the meantime. A timeout of 0 keeps the value.
+
+The clipboard is accessed with pbcopy on macOS, clip on Windows and wl-copy,
+xclip or xsel on Linux. With --qr the value is shown as a QR code instead, e.g.
+to transfer a wifi password to a phone. In files with several YAML documents,
+select the document with --document.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			if !cmd.Flags().Changed("timeout") {
+				if timeout, err = appConfig.Clipboard.ClearAfter(); err != nil {
+					return err
+				}
+			}
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+
+			// Find the clipboard utility before decrypting anything
+			var tool clipboard.Tool
+			if !showQR {
+				if tool, err = clipboard.Detect(); err != nil {
+					return err
+				}
+			}
+
+			value, err := extractValue(args[0], args[1], document, keyFile, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+
+			if showQR {
+				return qr.Render(os.Stdout, string(value))
+			}
+
+			if err := tool.Write(value); err != nil {
+				return err
+			}
+
+			if timeout == 0 {
+				logging.Success("Copied %s of %s to the clipboard", args[1], args[0])
+				return nil
+			}
+
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to schedule clearing the clipboard: %w", err)
+			}
+			command := []string{executable, "clear-clipboard", "--after", timeout.String()}
+			if err := clipboard.ScheduleClear(command, clipboard.Hash(value)); err != nil {
+				return err
+			}
+
+			logging.Success("Copied %s of %s to the clipboard, clearing it in %s", args[1], args[0], timeout)
+			return nil
+		},
+		Example: `  simple-sops copy secrets.enc.yaml '["db"]["password"]'
+  simple-sops copy secrets.enc.yaml '["api"]["token"]' --timeout 2m
+  simple-sops copy wifi.enc.yaml '["password"]' --qr`,
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().DurationVar(&timeout, "timeout", config.DefaultClipboardTimeout, "Clear the clipboard after this duration, 0 to keep the value")
+	cmd.Flags().BoolVar(&showQR, "qr", false, "Show the value as a QR code instead of copying it")
+	cmd.Flags().IntVar(&document, "document", encrypt.NoDocument, "Document of a multi-document YAML file, starting at 1")
+
+	return cmd
+}
+
+// ClearClipboardCmd returns the clear-clipboard command started by copy
+// It reads the hash of the copied value from stdin.
+func ClearClipboardCmd() *cobra.Command {
+	var after time.Duration
+
+	cmd := &cobra.Command{
+		Use:    "clear-clipboard",
+		Short:  "Clear a copied value from the clipboard (started by copy)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hash, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read the hash of the copied value: %w", err)
+			}
+
+			time.Sleep(after)
+
+			tool, err := clipboard.Detect()
+			if err != nil {
+				return err
+			}
+			_, err = tool.ClearIfUnchanged(strings.TrimSpace(string(hash)))
+			return err
+		},
+	}
+
+	cmd.Flags().DurationVar(&after, "after", config.DefaultClipboardTimeout, "Wait this long before clearing")
+
+	return cmd
+}
@@ -4,6 +4,12 @@ import (
 	"fmt"
 	"simple-sops/internal/config"
 	"simple-sops/internal/encrypt"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -13,13 +19,33 @@ func DecryptCmd() *cobra.Command {
 	var (
 		keyFile   string
 		useStdout bool
+		format    string
+		dryRun    bool
+		output    string
+		auto      bool
+		audience  string
+		required  []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "decrypt [file...]",
 		Short: "Decrypt one or more files",
-		Long:  `Decrypt one or more files encrypted with SOPS.`,
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Decrypt one or more files encrypted with SOPS.
+With --format the decrypted values are printed to stdout as environment variables
+that can be eval'd by a shell or appended to $GITHUB_ENV. With --output a single
+file is decrypted to another file, which is offered to be added to .gitignore
+when it is inside a git repository. --output auto decrypts every file to its
+plaintext name, e.g. secrets.enc.yaml to secrets.yaml. With --audience only
+the files whose .sops.yaml rule lists the audience are decrypted, by default
+all encrypted files of the repository. With --require-recipient nothing is
+decrypted unless every file is encrypted to the given recipients, catching
+files encrypted again to an unexpected key.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if audience != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load application config
 			appConfig, err := config.LoadConfig()
@@ -32,17 +58,183 @@ func DecryptCmd() *cobra.Command {
 				keyFile = appConfig.KeyFile
 			}
 
-			// Decrypt the files
-			if err := encrypt.DecryptFiles(args, keyFile, useStdout, appConfig.AlwaysUseOnePassword); err != nil {
-				return err
+			encrypt.SetDryRun(dryRun)
+
+			// Warn about rules someone changed without signing them
+			if configPath, err := config.GetSopsConfigPath(); err == nil {
+				warnUnsignedConfig(configPath)
+			}
+
+			// Only decrypt the files of rules tagged for the audience
+			if audience != "" {
+				if args, err = audienceFiles(args, audience); err != nil {
+					return err
+				}
 			}
 
-			return nil
+			// Refuse files not encrypted to the expected recipients
+			if len(required) > 0 {
+				if err := checkRequiredRecipients(args, required); err != nil {
+					return err
+				}
+			}
+
+			return encrypt.WithHooks(appConfig.Hooks, encrypt.OperationDecrypt, args, func() error {
+				// Print values as environment variables if a format was requested
+				if format != "" {
+					envFormat, err := encrypt.ParseEnvFormat(format)
+					if err != nil {
+						return err
+					}
+					return encrypt.DecryptFilesAsEnv(args, keyFile, envFormat, appConfig.AlwaysUseOnePassword)
+				}
+
+				// Decrypt to other files
+				if output != "" {
+					if useStdout || (len(args) != 1 && output != config.AutoOutput) {
+						return fmt.Errorf("--output takes exactly one file unless it is auto, and can't be combined with --stdout")
+					}
+
+					outputs, err := plainOutputs(args, output, appConfig.Naming)
+					if err != nil {
+						return err
+					}
+					for _, outputPath := range outputs {
+						guardPlaintextOutput(outputPath, auto)
+					}
+
+					// Ensure we have the key available
+					keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+					if err != nil {
+						return err
+					}
+
+					// Clean up the key if it's temporary
+					if isTemp {
+						defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+					}
+
+					for i, filePath := range args {
+						if err := encrypt.DecryptToFile(filePath, outputs[i], keyPath); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+
+				// Decrypt the files
+				return encrypt.DecryptFiles(args, keyFile, useStdout, appConfig.AlwaysUseOnePassword)
+			})
 		},
 	}
 
 	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
 	cmd.Flags().BoolVar(&useStdout, "stdout", false, "Output to stdout instead of files")
+	cmd.Flags().StringVar(&format, "format", "", "Print values as environment variables (shell, github-env, dotenv)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the sops commands without running them")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Decrypt to this file instead of in-place, or auto for the plaintext name")
+	cmd.Flags().BoolVar(&auto, "auto", false, "Add the output file to .gitignore without asking")
+	cmd.Flags().StringVar(&audience, "audience", "", "Only decrypt files whose rule is tagged for this audience")
+	cmd.Flags().StringSliceVar(&required, "require-recipient", nil, "Only decrypt if the files are encrypted to these Age recipients")
+	_ = cmd.RegisterFlagCompletionFunc("audience", completeAudiences)
 
 	return cmd
 }
+
+// audienceFiles returns the files of rules tagged for an audience
+// Without files, all encrypted files next to and below .sops.yaml are considered.
+func audienceFiles(files []string, audience string) ([]string, error) {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SOPS config: %w", err)
+	}
+
+	if len(files) == 0 {
+		if files, err = config.FindEncryptedFiles(config.RepoRoot(configPath)); err != nil {
+			return nil, fmt.Errorf("failed to find encrypted files: %w", err)
+		}
+	}
+
+	selected := config.FilterByAudience(sopsConfig, configPath, files, audience)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no encrypted files are tagged for audience %s", audience)
+	}
+	if skipped := len(files) - len(selected); skipped > 0 {
+		logging.Info("Skipping %d files not tagged for audience %s", skipped, audience)
+	}
+	return selected, nil
+}
+
+// checkRequiredRecipients fails unless every file is encrypted to the required recipients
+// The metadata of all files is checked before anything is decrypted.
+func checkRequiredRecipients(files []string, required []string) error {
+	for _, recipient := range required {
+		if !strings.HasPrefix(recipient, "age1") {
+			return fmt.Errorf("%s is not an Age recipient (age1...)", recipient)
+		}
+	}
+
+	var failed int
+	for _, file := range files {
+		metadata, err := config.ReadFileMetadata(file)
+		if err != nil {
+			return fmt.Errorf("failed to read the recipients of %s: %w", file, err)
+		}
+		missing := metadata.MissingRecipients(required)
+		for _, recipient := range missing {
+			logging.Error("%s is not encrypted to %s", file, recipient)
+		}
+		if len(missing) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) are not encrypted to the required recipients, nothing was decrypted", failed))
+	}
+	return nil
+}
+
+// completeAudiences completes the audiences used in .sops.yaml
+func completeAudiences(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var audiences []string
+	for _, rule := range sopsConfig.CreationRules {
+		for _, audience := range rule.Audiences {
+			if !slices.Contains(audiences, audience) {
+				audiences = append(audiences, audience)
+			}
+		}
+	}
+	sort.Strings(audiences)
+	return audiences, cobra.ShellCompDirectiveNoFileComp
+}
+
+// plainOutputs returns the files to decrypt the encrypted files to
+// With auto, the plaintext name follows the naming convention.
+func plainOutputs(files []string, output string, naming config.NamingConvention) ([]string, error) {
+	if output != config.AutoOutput {
+		return []string{output}, nil
+	}
+
+	var outputs []string
+	for _, file := range files {
+		outputPath, ok := naming.PlainName(file)
+		if !ok {
+			return nil, fmt.Errorf("%s doesn't follow the naming convention of encrypted files, e.g. %s", file, naming.EncryptedName("secrets.yaml"))
+		}
+		outputs = append(outputs, outputPath)
+	}
+	return outputs, nil
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// DriftCmd returns the drift command
+func DriftCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drift [file...]",
+		Short: "Compare the recipients of encrypted files with .sops.yaml",
+		Long: `Compare the Age recipients in the sops metadata of encrypted files with the
+recipients of the .sops.yaml rule that applies to them. Without arguments all
+encrypted files in the repository are checked.
+
+A file drifts when a recipient was added to or removed from its rule after it
+was encrypted. Added recipients can't decrypt the file and removed recipients
+still can, until the file's keys are updated.
+
+Rules of .sops.yaml files in subdirectories add to the recipients of their
+parents, see 'simple-sops explain'.
+
+With a team registry in .simple-sops/keys.yaml, files that members marked as
+offboarded or past their expiry date can still decrypt are reported too.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			team, err := config.LoadTeam(root)
+			if err != nil {
+				return err
+			}
+
+			files := args
+			if len(files) == 0 {
+				if files, err = config.FindEncryptedFiles(root); err != nil {
+					return fmt.Errorf("failed to find encrypted files: %w", err)
+				}
+			}
+
+			var (
+				drifted []config.RecipientDrift
+				revoked []config.RevokedRecipients
+			)
+			now := time.Now()
+			for _, file := range files {
+				metadata, err := config.ReadFileMetadata(file)
+				if err != nil {
+					if len(args) > 0 {
+						return err
+					}
+					logging.Debug("Skipping %s: %v", file, err)
+					continue
+				}
+
+				// .sops.yaml files in subdirectories add their recipients
+				effective, found, err := config.ResolveRule(configPath, file)
+				if err != nil {
+					return fmt.Errorf("failed to load SOPS config: %w", err)
+				}
+				drift := config.CompareRuleRecipients(config.ConfigRelativePath(configPath, file), effective.Rule, found, metadata.Recipients)
+				result := logging.ResultOK
+				if drift.Drifted() {
+					drifted = append(drifted, drift)
+					result = logging.ResultFailed
+				}
+				if found, ok := team.FindRevoked(config.ConfigRelativePath(configPath, file), metadata.Recipients, now); ok {
+					revoked = append(revoked, found)
+					result = logging.ResultFailed
+				}
+				logging.Record(file, "drift", result)
+			}
+
+			// Show labels and fingerprints instead of full keys
+			labels, err := keymgmt.LoadKeyLabels(root)
+			if err != nil {
+				return err
+			}
+
+			return reportDrift(cmd, drifted, revoked, labels)
+		},
+	}
+
+	return cmd
+}
+
+// reportDrift prints the files whose recipients differ from .sops.yaml and the
+// files revoked recipients can decrypt, and fails if there are any
+func reportDrift(cmd *cobra.Command, drifted []config.RecipientDrift, revoked []config.RevokedRecipients, labels *keymgmt.TrustStore) error {
+	reportRevoked(revoked)
+	if len(drifted) == 0 && len(revoked) > 0 {
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) can still be decrypted by revoked recipients", len(revoked)))
+	}
+	if len(drifted) == 0 {
+		logging.Success("All encrypted files match the recipients in .sops.yaml.")
+		return nil
+	}
+
+	for _, drift := range drifted {
+		if drift.PathRegex == "" {
+			logging.Info("%s: no rule in .sops.yaml matches", drift.Path)
+			continue
+		}
+
+		logging.Info("%s (rule %s)", drift.Path, drift.PathRegex)
+		for _, recipient := range drift.Missing {
+			logging.Info("  + %s (in .sops.yaml, can't decrypt the file)", labels.Describe(recipient))
+		}
+		for _, recipient := range drift.Extra {
+			logging.Info("  - %s (not in .sops.yaml, can still decrypt the file)", labels.Describe(recipient))
+		}
+	}
+
+	logging.Info("Run 'sops updatekeys <file>' to apply the recipients of .sops.yaml, then 'simple-sops rotate <file>' so removed recipients can't read new values.")
+
+	// The files are listed above, the usage doesn't help
+	cmd.SilenceUsage = true
+	return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) drifted from .sops.yaml", len(drifted)))
+}
@@ -5,19 +5,28 @@ import (
 	"simple-sops/internal/config"
 	"simple-sops/internal/encrypt"
 	"simple-sops/pkg/logging"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 // EditCmd returns the edit command
 func EditCmd() *cobra.Command {
-	var keyFile string
+	var (
+		keyFile string
+		lock    bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "edit [file]",
 		Short: "Edit an encrypted file",
-		Long:  `Edit an encrypted file using SOPS.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Edit an encrypted file using SOPS.
+
+With --lock or edit_lock in the config, the edit is recorded in a <file>.lock
+next to the file, and anyone else editing the file meanwhile is warned that
+one of the edits will be lost. The lock is removed when the editor closes.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load application config
 			appConfig, err := config.LoadConfig()
@@ -30,6 +39,9 @@ func EditCmd() *cobra.Command {
 				keyFile = appConfig.KeyFile
 			}
 
+			// Warn others editing the same file, if enabled
+			encrypt.SetEditLocking(lock || appConfig.EditLock)
+
 			// Edit the file
 			if err := encrypt.EditFile(args[0], keyFile, appConfig.AlwaysUseOnePassword); err != nil {
 				return err
@@ -40,19 +52,32 @@ func EditCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&lock, "lock", false, "Record an advisory lock while editing (defaults to config setting)")
 
 	return cmd
 }
 
 // SetKeysCmd returns the set-keys command
 func SetKeysCmd() *cobra.Command {
-	var keyFile string
+	var (
+		keyFile               string
+		macOnlyEncrypted      bool
+		encryptedCommentRegex string
+		sopsVersion           string
+		yes                   bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "set-keys [file]",
 		Short: "Choose which keys to encrypt in a file",
-		Long:  `Set the encryption rules for a specific file in the SOPS configuration.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Set the encryption rules for a specific file in the SOPS configuration.
+Advanced sops options of the rule can be set with flags, options not given
+keep their value. They need a recent sops version, which is checked before
+the rule is changed. --sops-version pins the sops version of the repository
+in .simple-sops/versions.yaml, so the whole team uses one that supports them. The change to
+.sops.yaml is shown as a diff and saved only once confirmed, unless --yes is
+given.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load application config
 			appConfig, err := config.LoadConfig()
@@ -68,41 +93,83 @@ func SetKeysCmd() *cobra.Command {
 			// Get predefined patterns
 			patterns := encrypt.PredefinedEncryptionPatterns()
 
-			// Create list of choices
-			var choices []string
-			for name := range patterns {
-				choices = append(choices, name)
+			var encryptedRegex string
+
+			// Propose the pattern matching the file's content
+			if detected, ok := encrypt.DetectEncryptionPattern(args[0]); ok {
+				if logging.Confirm(fmt.Sprintf("Detected %s. Use the %s pattern?", detected.Reason, detected.Name)) {
+					encryptedRegex = patterns[detected.Name]
+				}
 			}
-			choices = append(choices, "Custom pattern")
 
-			// Prompt user for encryption pattern
-			choice, err := logging.PromptChoice("What do you want to encrypt in this file?", choices)
-			if err != nil {
-				return fmt.Errorf("invalid choice: %w", err)
+			if encryptedRegex == "" {
+				// Create list of choices
+				var choices []string
+				for name := range patterns {
+					choices = append(choices, name)
+				}
+				sort.Strings(choices)
+				choices = append(choices, "Custom pattern")
+
+				// Prompt user for encryption pattern
+				choice, err := logging.PromptChoice("What do you want to encrypt in this file?", choices)
+				if err != nil {
+					return fmt.Errorf("invalid choice: %w", err)
+				}
+
+				if choice <= len(patterns) {
+					// Use predefined pattern
+					encryptedRegex = patterns[choices[choice-1]]
+				} else {
+					// Custom pattern
+					logging.Info("Enter your regex pattern to match keys you want to encrypt:")
+					logging.Info("Example: ^(password|api_key|secret)")
+					encryptedRegex = logging.PromptInput("Pattern")
+				}
 			}
 
-			var encryptedRegex string
+			// Show which values of each document the pattern encrypts
+			if previews, err := encrypt.PreviewEncryption(args[0], encryptedRegex); err == nil {
+				logging.Info("Values encrypted by %s:", encryptedRegex)
+				logging.Info("%s", strings.TrimSuffix(encrypt.FormatPreview(previews), "\n"))
+			}
 
-			if choice <= len(patterns) {
-				// Use predefined pattern
-				encryptedRegex = patterns[choices[choice-1]]
-			} else {
-				// Custom pattern
-				logging.Info("Enter your regex pattern to match keys you want to encrypt:")
-				logging.Info("Example: ^(password|api_key|secret)")
-				encryptedRegex = logging.PromptInput("Pattern")
+			// Only the options given as flags change the rule
+			var options config.RuleOptions
+			if cmd.Flags().Changed("mac-only-encrypted") {
+				options.MacOnlyEncrypted = &macOnlyEncrypted
+			}
+			if cmd.Flags().Changed("encrypted-comment-regex") {
+				options.EncryptedCommentRegex = &encryptedCommentRegex
 			}
 
 			// Set encryption keys for the file
-			if err := encrypt.SetEncryptionKeys(args[0], keyFile, encryptedRegex, appConfig.AlwaysUseOnePassword); err != nil {
+			encrypt.SetWildcardPolicy(appConfig.Wildcard)
+			encrypt.SetConfirmConfigChanges(!yes)
+			if err := encrypt.SetEncryptionKeys(args[0], keyFile, encryptedRegex, options, appConfig.AlwaysUseOnePassword); err != nil {
 				return err
 			}
 
+			if sopsVersion != "" {
+				root, err := getRepoRoot()
+				if err != nil {
+					return err
+				}
+				if err := config.PinSopsVersion(root, sopsVersion); err != nil {
+					return err
+				}
+				logging.Success("Pinned sops %s in %s", sopsVersion, config.VersionsFileName)
+			}
+
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Save the change to .sops.yaml without showing it first")
+	cmd.Flags().BoolVar(&macOnlyEncrypted, "mac-only-encrypted", false, "Compute the MAC over encrypted values only (sops 3.9.0+)")
+	cmd.Flags().StringVar(&encryptedCommentRegex, "encrypted-comment-regex", "", "Also encrypt comments matching this regex (sops 3.7.0+)")
+	cmd.Flags().StringVar(&sopsVersion, "sops-version", "", "Pin the sops version of the repository, e.g. \">=3.9, <4\"")
 
 	return cmd
 }
@@ -2,9 +2,12 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"simple-sops/internal/config"
 	"simple-sops/internal/encrypt"
 	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -18,20 +21,136 @@ func EncryptCmd() *cobra.Command {
 		opItems     []string
 		opVaults    []string
 		opFieldName string
+		strict      bool
+		githubUsers []string
+		to          []string
+		dryRun      bool
+		output      string
+		template    string
+		values      []string
+		generated   []string
+		yes         bool
+		scope       string
 	)
 
 	cmd := &cobra.Command{
-		Use:   "encrypt [file...]",
+		Use:   "encrypt [file|dir...]",
 		Short: "Encrypt one or more files with Age",
-		Long:  `Encrypt one or more files using SOPS with Age encryption.`,
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Encrypt one or more files using SOPS with Age encryption.
+Directories are searched recursively for supported files that are not encrypted
+yet. Paths listed in .sopsignore at the repository root are skipped.
+
+With --output the plaintext is kept and an encrypted copy is written instead.
+--output auto names every copy after the naming convention, e.g. secrets.yaml
+is encrypted to secrets.enc.yaml, and the .sops.yaml rule is created for the
+encrypted name.
+
+With --from-template the file is first created from a template, as with the
+new command.
+
+Files that get a new rule without --to and are owned by someone in CODEOWNERS
+get a suggestion of the members or groups of .simple-sops/keys.yaml to add as
+recipients, so encryption follows code ownership.
+
+With --scope the files must be below a directory, which is encrypted when no
+files are given, and the catch-all rule is created for that directory only,
+so each service of a monorepo keeps its own recipients.
+
+Changes to .sops.yaml are shown as a diff and saved only once confirmed,
+unless --yes is given.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && scope == "" {
+				return fmt.Errorf("requires at least 1 file or directory, or --scope")
+			}
+
 			// Load application config
 			appConfig, err := config.LoadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
+			// Fail instead of warning on recipients missing from the trust store
+			encrypt.SetStrictRecipients(strict)
+			if scope, err = resolveScope(scope); err != nil {
+				return err
+			}
+			wildcard := appConfig.Wildcard
+			wildcard.Scope = scope
+			encrypt.SetWildcardPolicy(wildcard)
+			encrypt.SetRecipientPolicy(appConfig.RecipientPolicy)
+			encrypt.SetEncryptedRegexDefaults(appConfig.EncryptedRegexDefaults)
+			encrypt.SetDryRun(dryRun)
+			encrypt.SetConfirmConfigChanges(!yes)
+
+			if len(args) == 0 {
+				root, err := getRepoRoot()
+				if err != nil {
+					return err
+				}
+				args = []string{scopeDir(root, scope)}
+			}
+
+			// Create the file from a template first
+			if template != "" {
+				if len(args) != 1 || dryRun {
+					return fmt.Errorf("--from-template takes exactly one file and can't be combined with --dry-run")
+				}
+				templatesDir, err := config.GetTemplatesDir()
+				if err != nil {
+					return fmt.Errorf("failed to determine templates directory: %w", err)
+				}
+				if err := createFromTemplate(templatesDir, template, args[0], values, generated); err != nil {
+					return err
+				}
+			}
+
+			// Expand directories to the files they contain
+			args, err = expandDirectories(args)
+			if err != nil {
+				return err
+			}
+			if err := checkInScope(args, scope); err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				logging.Info("No files to encrypt.")
+				return nil
+			}
+
+			// Encrypt copies and keep the plaintext files
+			if output != "" {
+				if args, err = encryptedOutputs(args, output, appConfig.Naming, dryRun); err != nil {
+					return err
+				}
+				if len(args) == 0 {
+					return nil
+				}
+			}
+
+			// Point out the owners of files that get a new rule
+			if len(to) == 0 {
+				suggestCodeOwners(args)
+			}
+
+			// Look up additional recipients from GitHub users
+			extraRecipients, err := lookupGitHubRecipients(githubUsers)
+			if err != nil {
+				return err
+			}
+
+			// Resolve members and groups of the team registry
+			if len(to) > 0 {
+				root, err := getRepoRoot()
+				if err != nil {
+					return err
+				}
+				teamRecipients, err := resolveTeamRecipients(root, to)
+				if err != nil {
+					return err
+				}
+				extraRecipients = append(extraRecipients, teamRecipients...)
+			}
+
 			// If both a key file is specified AND AlwaysUseOnePassword is true,
 			// use both keys for encryption
 			if keyFile != "" && appConfig.AlwaysUseOnePassword && appConfig.OnePasswordEnabled {
@@ -48,8 +167,8 @@ func EncryptCmd() *cobra.Command {
 				return encrypt.EncryptFilesWithMultipleKeys(
 					args,
 					keyFilesSlice,
-					nil,  // pubKeys can be nil, they'll be extracted from the key files
-					true, // Always use 1Password
+					extraRecipients, // Own public keys are extracted from the key files
+					true,            // Always use 1Password
 					opItemsSlice)
 			}
 
@@ -89,17 +208,17 @@ func EncryptCmd() *cobra.Command {
 				if err := encrypt.EncryptFilesWithMultipleKeys(
 					args,
 					nil,
-					nil,
+					extraRecipients,
 					appConfig.AlwaysUseOnePassword,
 					opItemsList); err != nil {
 					return err
 				}
-			} else if len(multipleKeyFiles) > 1 {
-				// Encrypt with multiple key files
+			} else if len(multipleKeyFiles) > 1 || len(extraRecipients) > 0 {
+				// Encrypt with multiple key files or additional recipients
 				if err := encrypt.EncryptFilesWithMultipleKeys(
 					args,
 					multipleKeyFiles,
-					nil,
+					extraRecipients,
 					appConfig.AlwaysUseOnePassword,
 					nil); err != nil {
 					return err
@@ -121,6 +240,174 @@ func EncryptCmd() *cobra.Command {
 	cmd.Flags().StringSliceVar(&opItems, "op-items", nil, "1Password items to fetch keys from")
 	cmd.Flags().StringSliceVar(&opVaults, "op-vaults", nil, "1Password vaults for the items (defaults to 'Personal' if not specified)")
 	cmd.Flags().StringVar(&opFieldName, "op-field", "", "Field name in 1Password items (defaults to 'text')")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail when encrypting to recipients that are not in the trust store")
+	cmd.Flags().StringSliceVar(&githubUsers, "github-user", nil, "Also encrypt to the ed25519 SSH keys of these GitHub users")
+	cmd.Flags().StringSliceVar(&to, "to", nil, "Also encrypt to these members or groups of .simple-sops/keys.yaml")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the sops commands and SOPS configuration changes without running them")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Save changes to .sops.yaml without showing them first")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Encrypt a copy to this file, or auto for the encrypted name")
+	cmd.Flags().StringVar(&template, "from-template", "", "Create the file from this template before encrypting it")
+	cmd.Flags().StringArrayVar(&values, "set", nil, "Value of a template placeholder as NAME=value")
+	cmd.Flags().StringArrayVar(&generated, "generate", nil, "Generate a template placeholder's value as NAME=spec")
+	cmd.Flags().StringVar(&scope, "scope", "", "Only encrypt files below this directory and scope the catch-all rule to it")
 
 	return cmd
 }
+
+// encryptedOutputs copies plaintext files to the paths they are encrypted at
+// and returns those paths. With auto, the path follows the naming convention.
+// Existing outputs are only overwritten after confirmation.
+func encryptedOutputs(files []string, output string, naming config.NamingConvention, dryRun bool) ([]string, error) {
+	if output != config.AutoOutput && len(files) != 1 {
+		return nil, fmt.Errorf("--output takes exactly one file, use --output auto for several")
+	}
+
+	var outputs []string
+	for _, file := range files {
+		outputPath := output
+		if output == config.AutoOutput {
+			if _, encryptedName := naming.PlainName(file); encryptedName {
+				return nil, fmt.Errorf("%s already has the name of an encrypted file, encrypt it without --output", file)
+			}
+			outputPath = naming.EncryptedName(file)
+		}
+
+		if _, err := os.Stat(outputPath); err == nil && !dryRun {
+			if !logging.Confirm(fmt.Sprintf("%s already exists. Overwrite it with the encrypted %s?", outputPath, file)) {
+				logging.Info("Skipping %s...", file)
+				continue
+			}
+		}
+
+		if err := encrypt.CopyToOutput(file, outputPath); err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, outputPath)
+	}
+
+	return outputs, nil
+}
+
+// expandDirectories replaces directory arguments with the supported, not yet
+// encrypted files below them, respecting .sopsignore
+func expandDirectories(args []string) ([]string, error) {
+	var ignore *config.IgnoreMatcher
+
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			// Files are passed on as given, missing ones are reported later
+			files = append(files, arg)
+			continue
+		}
+
+		if ignore == nil {
+			root, err := getRepoRoot()
+			if err != nil {
+				return nil, err
+			}
+			if ignore, err = config.LoadIgnoreFile(root); err != nil {
+				return nil, err
+			}
+		}
+
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", arg, err)
+		}
+
+		dirFiles, err := config.FindFilesToEncrypt(dir, ignore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", arg, err)
+		}
+		logging.Debug("Found %d files to encrypt in %s", len(dirFiles), arg)
+		files = append(files, dirFiles...)
+	}
+
+	return files, nil
+}
+
+// lookupGitHubRecipients fetches the Age recipients of GitHub users and records
+// them in the trust store, since the user explicitly asked to encrypt to them
+func lookupGitHubRecipients(users []string) ([]string, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	trustStore, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trust store: %w", err)
+	}
+
+	var recipients []string
+	for _, user := range users {
+		userRecipients, err := keymgmt.FetchGitHubRecipients(user)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range userRecipients {
+			logging.Info("Adding recipient %s from GitHub user %s", r.Recipient, user)
+			recipients = append(recipients, r.Recipient)
+
+			if _, ok := trustStore.Lookup(r.Recipient); !ok {
+				trustStore.Add(r.Recipient, fmt.Sprintf("GitHub: %s", user))
+			}
+		}
+	}
+
+	if err := keymgmt.SaveTrustStore(keymgmt.DefaultTrustStoreFile, trustStore); err != nil {
+		return nil, fmt.Errorf("failed to save trust store: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// suggestCodeOwners suggests encrypting files without a rule to the members or
+// groups that own them in CODEOWNERS. Suggestions are hints, so problems
+// reading the files only skip them.
+func suggestCodeOwners(files []string) {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return
+	}
+	root := config.RepoRoot(configPath)
+
+	team, err := config.LoadTeam(root)
+	if err != nil || team.IsEmpty() {
+		return
+	}
+	codeOwners, err := config.LoadCodeOwners(root)
+	if err != nil {
+		logging.Debug("Skipping the CODEOWNERS suggestions: %v", err)
+		return
+	}
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		return
+	}
+
+	// Group the files by the recipients they should get
+	var suggestions []string
+	owned := make(map[string][]string)
+	for _, file := range files {
+		relPath := config.ConfigRelativePath(configPath, file)
+		if _, exists := config.GetCreationRule(sopsConfig, relPath); exists {
+			continue
+		}
+		names := team.ForCodeOwners(codeOwners.Owners(relPath))
+		if len(names) == 0 {
+			continue
+		}
+		suggestion := strings.Join(names, ",")
+		if _, ok := owned[suggestion]; !ok {
+			suggestions = append(suggestions, suggestion)
+		}
+		owned[suggestion] = append(owned[suggestion], file)
+	}
+
+	for _, suggestion := range suggestions {
+		logging.Info("CODEOWNERS assigns %s to %s, run 'simple-sops add-recipient --to %s %s' to encrypt for them", strings.Join(owned[suggestion], ", "), suggestion, suggestion, strings.Join(owned[suggestion], " "))
+	}
+}
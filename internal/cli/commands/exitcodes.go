@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// ExitCodesCmd returns the exit-codes help topic
+func ExitCodesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "exit-codes",
+		Short: "Exit codes for scripts and CI",
+		Long: `simple-sops ends with an exit code that tells scripts what failed:
+
+  0  Success
+  1  General error, e.g. invalid arguments
+  2  Config error: the application config or .sops.yaml is invalid
+  3  Key unavailable: no key file, 1Password item or SSH key to use
+  4  sops failed, e.g. the key can't decrypt the file
+  5  Verification failed: verify, drift or decrypt --require-recipient found problems
+  6  Partial failure: a bulk operation failed for some files and succeeded for others
+
+run passes on the exit code of the command it started.
+
+Bulk operations continue with the remaining files when one fails and report
+the failure at the end. With --fail-fast they stop at the first file that fails.`,
+	}
+}
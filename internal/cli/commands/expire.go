@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ExpireCmd returns the expire command
+func ExpireCmd() *cobra.Command {
+	var (
+		note   string
+		remove bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "expire [file] [path] [date]",
+		Short: "Record when a value expires",
+		Long: `Record the expiry date of a value, e.g. a certificate or an API token, so
+status and doctor warn before it expires. The date is YYYY-MM-DD or a time from
+today like 90d. Expiry dates are stored unencrypted in .simple-sops/expiry.yaml
+next to .sops.yaml, which can be committed.`,
+		Example: `  simple-sops expire tls.enc.yaml '["tls"]["crt"]' 2026-12-01 --note "Let's Encrypt"
+  simple-sops expire api.enc.yaml '["token"]' 90d
+  simple-sops expire api.enc.yaml '["token"]' --remove`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remove != (len(args) == 2) {
+				return fmt.Errorf("give a file, a path and a date, or a file and a path with --remove")
+			}
+
+			filePath, valuePath := args[0], args[1]
+			if err := encrypt.ValidateValuePath(valuePath); err != nil {
+				return err
+			}
+
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			expiries, err := config.LoadExpiries(root)
+			if err != nil {
+				return err
+			}
+
+			relPath := config.ConfigRelativePath(configPath, filePath)
+			if remove {
+				if !expiries.Remove(relPath, valuePath) {
+					return fmt.Errorf("%s of %s has no expiry date", valuePath, filePath)
+				}
+				if err := config.SaveExpiries(root, expiries); err != nil {
+					return err
+				}
+				logging.Success("Removed the expiry date of %s in %s", valuePath, filePath)
+				return nil
+			}
+
+			if _, err := os.Stat(filePath); os.IsNotExist(err) {
+				return fmt.Errorf("file not found: %s", filePath)
+			}
+
+			expires, err := parseExpiryDate(args[2], time.Now())
+			if err != nil {
+				return err
+			}
+
+			expiries.Set(relPath, valuePath, expires, note)
+			if err := config.SaveExpiries(root, expiries); err != nil {
+				return err
+			}
+
+			logging.Success("%s in %s expires on %s", valuePath, filePath, expires.Format(config.ExpiryDateFormat))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&note, "note", "", "Describe the value, e.g. who issued it")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the expiry date of a value")
+
+	return cmd
+}
+
+// parseExpiryDate parses a date like 2026-12-01 or a time from now like 90d
+func parseExpiryDate(value string, now time.Time) (time.Time, error) {
+	if date, err := time.Parse(config.ExpiryDateFormat, value); err == nil {
+		return date, nil
+	}
+
+	duration, err := config.ParseMaxAge(value)
+	if err != nil || duration <= 0 {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or a time from today like 90d", value)
+	}
+	return now.Add(duration), nil
+}
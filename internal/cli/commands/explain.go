@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// ExplainCmd returns the explain command
+func ExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <file...>",
+		Short: "Show the effective .sops.yaml rule of files",
+		Long: `Show the rule that applies to files after merging nested .sops.yaml files.
+
+A .sops.yaml in a subdirectory extends the recipients of the .sops.yaml files
+above it, up to the one of the repository. Set override: true in it to use
+only its own rules. The other settings, like encrypted_regex, come from the
+nearest matching rule. For each file the merged recipients and the rules they
+come from are listed, nearest first.`,
+		Example: `  simple-sops explain clusters/prod/secrets.enc.yaml`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			// Show labels and fingerprints instead of full keys
+			labels, err := keymgmt.LoadKeyLabels(root)
+			if err != nil {
+				return err
+			}
+
+			for i, file := range args {
+				if i > 0 {
+					logging.Info("")
+				}
+				effective, found, err := config.ResolveRule(configPath, file)
+				if err != nil {
+					return fmt.Errorf("failed to load SOPS config: %w", err)
+				}
+				if !found {
+					logging.Info("%s: no rule in .sops.yaml matches", file)
+					logging.Record(file, "explain", logging.ResultFailed)
+					continue
+				}
+				explainRule(root, file, effective, labels)
+				logging.Record(file, "explain", logging.ResultOK)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// explainRule prints the effective rule of a file and where it comes from
+func explainRule(root string, file string, effective config.EffectiveRule, labels *keymgmt.TrustStore) {
+	logging.Info("%s", file)
+	logging.Info("  recipients: %s", labels.DescribeAll(effective.Rule.Age))
+	if effective.Rule.KMS != "" {
+		logging.Info("  kms: %s", effective.Rule.KMS)
+	}
+	if effective.Rule.EncryptedRegex != "" {
+		logging.Info("  encrypted_regex: %s", effective.Rule.EncryptedRegex)
+	}
+
+	logging.Info("  from:")
+	for _, source := range effective.Sources {
+		name := source.ConfigPath
+		if rel, err := filepath.Rel(root, source.ConfigPath); err == nil {
+			name = rel
+		}
+		if source.Override {
+			name += " (override)"
+		}
+		logging.Info("    %s: rule %s (%s)", name, source.Rule.PathRegex, labels.DescribeAll(source.Rule.Age))
+	}
+}
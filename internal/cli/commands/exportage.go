@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// ExportAgeCmd returns the export-age command
+func ExportAgeCmd() *cobra.Command {
+	var (
+		keyFile string
+		output  string
+		to      []string
+		armor   bool
+		strict  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export-age <file> [-o <file.age>]",
+		Short: "Export an encrypted file as a plain age file",
+		Long: `Decrypt a sops file and encrypt its content with the age CLI, for recipients
+who only have age and not sops. The age file holds the whole plaintext in its
+original format and decrypts with 'age --decrypt -i key.txt file.age'.
+
+The age file is encrypted to the recipients of the sops file, or to the ones
+given with --to: Age public keys or the names of members and groups in
+.simple-sops/keys.yaml. It is written next to the file with .age appended,
+unless --output is given. The plaintext never touches the disk.`,
+		Example: `  simple-sops export-age secrets.enc.yaml -o secrets.yaml.age
+  simple-sops export-age secrets.enc.yaml --to age1... --armor`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filePath := args[0]
+			if !config.IsFileEncrypted(filePath) {
+				return fmt.Errorf("%s is not encrypted", filePath)
+			}
+			if output == "" {
+				output = filePath + encrypt.AgeExtension
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetStrictRecipients(strict)
+
+			recipients, err := exportRecipients(filePath, to)
+			if err != nil {
+				return err
+			}
+
+			if _, err := os.Stat(output); err == nil {
+				if !logging.Confirm(fmt.Sprintf("%s already exists. Overwrite it?", output)) {
+					return fmt.Errorf("%s already exists", output)
+				}
+			}
+
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			plaintext, err := encrypt.DecryptToBytes(filePath, keyPath)
+			if err != nil {
+				return err
+			}
+			defer clear(plaintext)
+
+			if err := encrypt.EncryptAgeFile(plaintext, output, recipients, armor); err != nil {
+				return err
+			}
+			logging.Success("Exported %s to %s for %d recipient(s)", filePath, output, len(recipients))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to decrypt with (defaults to config setting)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Path of the age file (defaults to the file with .age appended)")
+	cmd.Flags().StringSliceVar(&to, "to", nil, "Recipients of the age file: Age public keys, member or group names (defaults to those of the file)")
+	cmd.Flags().BoolVarP(&armor, "armor", "a", false, "Write a PEM-encoded age file that can be pasted as text")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail when exporting to recipients that are not in the trust store")
+
+	return cmd
+}
+
+// exportRecipients returns the recipients of the age file exported from an encrypted file
+// Without names the file is exported to the Age recipients it is encrypted to.
+func exportRecipients(filePath string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		metadata, err := config.ReadFileMetadata(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if len(metadata.Recipients) == 0 {
+			return nil, fmt.Errorf("%s has no Age recipients, give them with --to", filePath)
+		}
+		return metadata.Recipients, nil
+	}
+
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	return resolveTeamRecipients(config.RepoRoot(configPath), names)
+}
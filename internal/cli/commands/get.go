@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/otp"
+	"simple-sops/internal/qr"
+	"simple-sops/pkg/logging"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// GetCmd returns the get command
+func GetCmd() *cobra.Command {
+	var (
+		keyFile  string
+		showOTP  bool
+		showQR   bool
+		document int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "get [file] [path]",
+		Short: "Print a single decrypted value",
+		Long: `Print a single decrypted value of an encrypted file to stdout.
+With --otp the value must be an otpauth://totp/ URI, and the current one-time
+password is printed instead, so an encrypted file can serve as a minimal 2FA
+vault. With --qr the value is shown as a QR code, e.g. to move a wifi password
+or an age key to a phone without writing a file. In files with several YAML
+documents, select the document with --document.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showOTP && showQR {
+				return fmt.Errorf("--otp can't be combined with --qr")
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			value, err := extractValue(args[0], args[1], document, keyFile, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+
+			if showOTP {
+				totp, err := otp.Parse(string(value))
+				if err != nil {
+					return fmt.Errorf("%s of %s: %w", args[1], args[0], err)
+				}
+
+				now := time.Now()
+				fmt.Println(totp.Code(now))
+				if remaining := totp.Remaining(now); remaining <= 5*time.Second {
+					logging.Warn("The code expires in %s", remaining)
+				}
+				return nil
+			}
+
+			if showQR {
+				return qr.Render(os.Stdout, string(value))
+			}
+
+			if !bytes.HasSuffix(value, []byte("\n")) {
+				value = append(value, '\n')
+			}
+			_, err = os.Stdout.Write(value)
+			return err
+		},
+		Example: `  simple-sops get secrets.enc.yaml '["db"]["password"]'
+  simple-sops get 2fa.enc.yaml '["github"]' --otp
+  simple-sops get wifi.enc.yaml '["password"]' --qr`,
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&showOTP, "otp", false, "Print the current code of an otpauth:// URI")
+	cmd.Flags().BoolVar(&showQR, "qr", false, "Show the value as a QR code")
+	cmd.Flags().IntVar(&document, "document", encrypt.NoDocument, "Document of a multi-document YAML file, starting at 1")
+
+	return cmd
+}
+
+// extractValue decrypts a single value of an encrypted file
+func extractValue(filePath string, valuePath string, document int, keyFile string, alwaysUseOnePassword bool) ([]byte, error) {
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	return encrypt.ExtractValue(filePath, keyPath, valuePath, document)
+}
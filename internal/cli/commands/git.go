@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/git"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// GitCmd returns the git command
+func GitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git",
+		Short: "Integrate simple-sops with git",
+	}
+
+	cmd.AddCommand(gitCredentialHelperCmd())
+	cmd.AddCommand(gitInstallHookCmd())
+	cmd.AddCommand(gitPrePushCmd())
+
+	return cmd
+}
+
+// gitCredentialHelperCmd returns the git credential-helper command
+func gitCredentialHelperCmd() *cobra.Command {
+	var keyFile string
+
+	cmd := &cobra.Command{
+		Use:   "credential-helper <file> <get|store|erase>",
+		Short: "Serve git credentials from an encrypted file",
+		Long: `Serve git credentials from an encrypted file, speaking git's credential
+helper protocol on stdin and stdout. Configure it with:
+
+  git config --global credential.helper '!simple-sops git credential-helper ~/.config/simple-sops/git-credentials.yaml'
+
+The file lists credentials by host, optionally narrowed by protocol, username
+and path:
+
+  credentials:
+    - host: github.com
+      username: alice
+      password: ghp_...
+
+The file is only read. Git's store and erase requests are ignored, use
+'simple-sops edit' to change credentials.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filePath, operation := args[0], args[1]
+
+			// Only get is answered; the credentials stay in the encrypted file
+			if operation != "get" {
+				_, err := io.Copy(io.Discard, os.Stdin)
+				return err
+			}
+
+			// Stdout belongs to the credential protocol
+			logging.SetQuietMode(true)
+			logging.SetDebugMode(false)
+
+			request, err := git.ReadCredentialRequest(os.Stdin)
+			if err != nil {
+				return err
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			// Ensure we have the key available
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+
+			// Clean up the key if it's temporary
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			plaintext, err := encrypt.DecryptToBytes(filePath, keyPath)
+			if err != nil {
+				return err
+			}
+
+			credentials, err := git.ParseCredentialFile(plaintext)
+			if err != nil {
+				return err
+			}
+
+			// Without a match git asks the next helper or the user
+			credential, found := git.FindCredential(credentials, request)
+			if !found {
+				return nil
+			}
+			return git.WriteCredential(os.Stdout, credential)
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+
+	return cmd
+}
+
+// gitInstallHookCmd returns the git install-hook command
+func gitInstallHookCmd() *cobra.Command {
+	var (
+		preCommit bool
+		prePush   bool
+		force     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "install-hook",
+		Short: "Install git hooks that verify encryption",
+		Long: `Install git hooks that verify that files covered by .sops.yaml rules are
+encrypted. The pre-commit hook checks staged files and is installed by
+default. The pre-push hook checks every commit of a push, catching plaintext
+introduced by rebases or merges that bypassed the pre-commit hook.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := getRepoRoot()
+			if err != nil {
+				return err
+			}
+
+			// Without flags, install the pre-commit hook
+			var hooks []string
+			if preCommit || !prePush {
+				hooks = append(hooks, "pre-commit")
+			}
+			if prePush {
+				hooks = append(hooks, "pre-push")
+			}
+
+			for _, hook := range hooks {
+				path, err := git.InstallHook(root, hook, force)
+				if err != nil {
+					return err
+				}
+				logging.Success("Installed %s hook: %s", hook, path)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&preCommit, "pre-commit", false, "Install the pre-commit hook")
+	cmd.Flags().BoolVar(&prePush, "pre-push", false, "Install the pre-push hook")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace hooks not installed by simple-sops")
+
+	return cmd
+}
+
+// gitPrePushCmd returns the git pre-push command run by the pre-push hook
+func gitPrePushCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "pre-push [remote] [url]",
+		Short:  "Verify the commits of a push (run by the pre-push hook)",
+		Hidden: true,
+		Args:   cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			sopsConfig, err := config.LoadSopsConfig(configPath)
+			if err != nil {
+				return fmt.Errorf("failed to load SOPS config: %w", err)
+			}
+
+			ignore, err := config.LoadIgnoreFile(root)
+			if err != nil {
+				return err
+			}
+
+			updates, err := git.ReadPushUpdates(os.Stdin)
+			if err != nil {
+				return err
+			}
+
+			violations, err := git.VerifyPush(sopsConfig, root, ignore, updates)
+			if err != nil {
+				return err
+			}
+			return reportViolations(cmd, violations, nil, nil, nil, nil)
+		},
+	}
+
+	return cmd
+}
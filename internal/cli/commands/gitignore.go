@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/git"
+	"simple-sops/pkg/logging"
+)
+
+// guardPlaintextOutput keeps a decrypted output file out of git
+// Inside a git repository the file is added to .gitignore after asking, or
+// right away with auto.
+func guardPlaintextOutput(outputPath string, auto bool) {
+	if encrypt.IsDryRun() {
+		return
+	}
+
+	root, ok := git.RepoRoot(outputPath)
+	if !ok || git.IsIgnored(root, outputPath) {
+		return
+	}
+
+	// Ignoring a tracked file has no effect
+	if git.IsTracked(root, outputPath) {
+		logging.Warn("%s is tracked by git, its decrypted content may be committed", outputPath)
+		return
+	}
+
+	if !auto && !logging.Confirm(fmt.Sprintf("%s is inside a git repository. Add it to .gitignore?", outputPath)) {
+		logging.Warn("%s holds decrypted content and is not ignored by git", outputPath)
+		return
+	}
+
+	if err := git.AddToGitignore(root, outputPath); err != nil {
+		logging.Warn("Failed to add %s to .gitignore: %v", outputPath, err)
+		return
+	}
+	logging.Info("Added %s to %s", outputPath, filepath.Join(root, ".gitignore"))
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/helptopic"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// maxHelpWidth is the width help topics wrap at on wide terminals
+const maxHelpWidth = 100
+
+// HelpTopicCmds returns the task-oriented help topics, like help keys
+func HelpTopicCmds() []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, topic := range helptopic.Topics {
+		cmd := &cobra.Command{
+			Use:   topic.Name,
+			Short: topic.Short,
+			Long:  topic.Intro,
+		}
+		cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			if err := showHelpTopic(cmd, topic); err != nil {
+				logging.Error("%v", err)
+			}
+		})
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// showHelpTopic renders a help topic with the commands and flags of the CLI
+// The markdown is printed as it is when stdout isn't a terminal, e.g. for a pager.
+func showHelpTopic(cmd *cobra.Command, topic helptopic.Topic) error {
+	markdown, err := topic.Markdown(cmd.Root())
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		_, err = fmt.Fprint(cmd.OutOrStdout(), markdown)
+		return err
+	}
+
+	width := 80
+	if w, _, err := term.GetSize(fd); err == nil && w > 0 {
+		width = min(w, maxHelpWidth)
+	}
+	rendered, err := helptopic.Render(markdown, width)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), rendered)
+	return err
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/dotenv"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// ImportAgeCmd returns the import-age command
+func ImportAgeCmd() *cobra.Command {
+	var (
+		keyFile string
+		output  string
+		remove  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import-age <file.age>...",
+		Short: "Convert files encrypted with age into sops files",
+		Long: `Decrypt files encrypted with the plain age CLI, not sops, with the configured
+key and encrypt them as sops files with a rule in .sops.yaml, like encrypt
+does. The sops file is named after the age file without .age, e.g.
+secrets.yaml.age becomes secrets.yaml, or given with --output for a single
+file. Only structured formats (YAML, JSON, dotenv, INI, TOML) can be imported.
+
+The age files are kept unless --remove is given. If encrypting fails, the
+plaintext is deleted again, so nothing is left unencrypted, and a file that
+was overwritten is restored.`,
+		Example: `  simple-sops import-age secrets.yaml.age
+  simple-sops import-age token.age --output secrets/token.env --remove`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "" && len(args) > 1 {
+				return fmt.Errorf("--output can only be used with a single file")
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetWildcardPolicy(appConfig.Wildcard)
+			encrypt.SetRecipientPolicy(appConfig.RecipientPolicy)
+			encrypt.SetEncryptedRegexDefaults(appConfig.EncryptedRegexDefaults)
+
+			dests := make(map[string]string, len(args))
+			for _, agePath := range args {
+				dest, err := importDest(agePath, output)
+				if err != nil {
+					return err
+				}
+				dests[agePath] = dest
+			}
+
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			for _, agePath := range args {
+				if err := importAgeFile(agePath, dests[agePath], keyPath, remove); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to decrypt and encrypt with (defaults to config setting)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Path of the sops file (defaults to the age file without .age)")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Delete the age files after importing them")
+
+	return cmd
+}
+
+// importDest returns the path of the sops file an age file is imported into
+// The path must have a structured format, since sops would otherwise store
+// the whole plaintext as a single binary value.
+func importDest(agePath string, output string) (string, error) {
+	dest := output
+	if dest == "" {
+		plainName, ok := encrypt.AgePlainName(agePath)
+		if !ok {
+			return "", fmt.Errorf("%s doesn't end with %s, give the path of the sops file with --output", agePath, encrypt.AgeExtension)
+		}
+		dest = plainName
+	}
+
+	if !config.IsSupportedFile(dest) && !dotenv.IsDotenvFile(dest) {
+		return "", fmt.Errorf("%s has no structured format, give a path like secrets.yaml with --output", dest)
+	}
+	return dest, nil
+}
+
+// importAgeFile decrypts an age file into dest and encrypts it with sops
+func importAgeFile(agePath string, dest string, keyPath string, remove bool) error {
+	plaintext, err := encrypt.DecryptAgeFile(agePath, keyPath)
+	if err != nil {
+		return err
+	}
+	defer clear(plaintext)
+
+	// An existing file is moved aside and only deleted once the import succeeded
+	backup := ""
+	if _, err := os.Stat(dest); err == nil {
+		if !logging.Confirm(fmt.Sprintf("%s already exists. Overwrite it?", dest)) {
+			return fmt.Errorf("%s already exists", dest)
+		}
+		if backup, err = moveAside(dest); err != nil {
+			return err
+		}
+	}
+
+	err = os.WriteFile(dest, plaintext, 0600)
+	if err == nil {
+		// The key is on disk already, so it isn't fetched from 1Password again
+		err = encrypt.EncryptFiles([]string{dest}, keyPath, false)
+	}
+	if err != nil {
+		if removeErr := os.Remove(dest); removeErr != nil && !os.IsNotExist(removeErr) {
+			logging.Warn("Failed to delete the plaintext %s: %v", dest, removeErr)
+		}
+		if backup != "" {
+			if restoreErr := os.Rename(backup, dest); restoreErr != nil {
+				logging.Error("Failed to restore %s, it was moved to %s: %v", dest, backup, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to import %s: %w", agePath, err)
+	}
+	if backup != "" {
+		if err := os.Remove(backup); err != nil {
+			logging.Warn("Failed to delete the previous %s at %s: %v", dest, backup, err)
+		}
+	}
+	logging.Success("Imported %s into %s", agePath, dest)
+
+	if remove {
+		if err := os.Remove(agePath); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", agePath, err)
+		}
+		logging.Info("Deleted %s", agePath)
+	}
+	return nil
+}
+
+// moveAside renames a file to a hidden name in its directory and returns the new path
+func moveAside(path string) (string, error) {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.bak")
+	if err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	temp.Close()
+	if err := os.Rename(path, temp.Name()); err != nil {
+		os.Remove(temp.Name())
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return temp.Name(), nil
+}
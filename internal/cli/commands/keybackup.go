@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/qr"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// keyBackupCmd returns the key backup command
+func keyBackupCmd() *cobra.Command {
+	var (
+		keyFile string
+		showQR  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Print the secret keys for a backup",
+		Long: `Print the secret keys of your Age key file, e.g. to store them in a password
+manager. With --qr each key is shown as a QR code instead, to transfer it to a
+phone or an air-gapped machine without writing a file.`,
+		Example: `  simple-sops key backup --qr
+  simple-sops key backup -k ~/.keys/work.txt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			// Ensure we have the key available
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+
+			// Clean up the key if it's temporary
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			secretKeys, err := keymgmt.GetSecretKeysFromFile(keyPath)
+			if err != nil {
+				return err
+			}
+
+			if !showQR {
+				for _, secretKey := range secretKeys {
+					fmt.Println(secretKey)
+				}
+				return nil
+			}
+
+			for i, secretKey := range secretKeys {
+				if len(secretKeys) > 1 {
+					logging.Info("Secret key %d of %d:", i+1, len(secretKeys))
+				}
+				if err := qr.Render(os.Stdout, secretKey); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to back up (defaults to config setting)")
+	cmd.Flags().BoolVar(&showQR, "qr", false, "Show each key as a QR code")
+
+	return cmd
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// KeyCmd returns the key command
+func KeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "key",
+		Short: "Manage labels of recipients and back up keys",
+		Long: `Manage the labels shown for recipients in config, status and drift output.
+Labels are stored locally, or with --repo in a .sops-keys.yaml file next to
+.sops.yaml that can be committed. Labelling a key doesn't add it to the trust
+store. backup prints your secret keys, optionally as QR codes.`,
+	}
+
+	cmd.AddCommand(keyLabelCmd())
+	cmd.AddCommand(keyLabelsCmd())
+	cmd.AddCommand(keyBackupCmd())
+
+	return cmd
+}
+
+// labelsPath returns the labels file to change, the committed one if repo is set
+func labelsPath(repo bool) (string, error) {
+	if !repo {
+		return keymgmt.DefaultLabelsFile, nil
+	}
+
+	root, err := getRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, keymgmt.RepoLabelsFile), nil
+}
+
+// keyLabelCmd returns the key label command
+func keyLabelCmd() *cobra.Command {
+	var (
+		repo   bool
+		remove bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "label <age1...> [label]",
+		Short: "Set or remove the label of a recipient",
+		Example: `  simple-sops key label age1xyz... "Alice (laptop)"
+  simple-sops key label --repo age1xyz... "CI"
+  simple-sops key label --remove "Alice (laptop)"`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remove != (len(args) == 1) {
+				return fmt.Errorf("give a public key and a label, or a key with --remove")
+			}
+
+			path, err := labelsPath(repo)
+			if err != nil {
+				return err
+			}
+
+			labels, err := keymgmt.LoadTrustStore(path)
+			if err != nil {
+				return fmt.Errorf("failed to load labels: %w", err)
+			}
+
+			if remove {
+				key, err := labels.Resolve(args[0])
+				if err != nil {
+					return err
+				}
+				if err := labels.Remove(key.PublicKey); err != nil {
+					return err
+				}
+				if err := keymgmt.SaveTrustStore(path, labels); err != nil {
+					return err
+				}
+
+				logging.Success("Removed the label %s of %s", key.Label, keymgmt.Fingerprint(key.PublicKey))
+				return nil
+			}
+
+			if err := keymgmt.ValidateRecipient(args[0]); err != nil {
+				return err
+			}
+			labels.Add(args[0], args[1])
+			if err := keymgmt.SaveTrustStore(path, labels); err != nil {
+				return err
+			}
+
+			logging.Success("Labelled %s as %s in %s", keymgmt.Fingerprint(args[0]), args[1], path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&repo, "repo", false, "Store the label in "+keymgmt.RepoLabelsFile+" next to .sops.yaml")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the label of a key, given by key, label or fingerprint")
+
+	return cmd
+}
+
+// keyLabelsCmd returns the key labels command
+func keyLabelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "labels",
+		Short: "List the labels of recipients",
+		Long: `List the labels of recipients from the trust store, the local labels and
+the .sops-keys.yaml of the repository. A label in the trust store takes
+precedence over a local one, which takes precedence over a committed one.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Outside a repository only local labels exist
+			root, err := getRepoRoot()
+			if err != nil {
+				logging.Debug("No repository labels: %v", err)
+			}
+
+			labels, err := keymgmt.LoadKeyLabels(root)
+			if err != nil {
+				return err
+			}
+
+			if len(labels.Keys) == 0 {
+				logging.Info("No labels found. Add labels with 'simple-sops key label'.")
+				return nil
+			}
+
+			for _, key := range labels.Keys {
+				fmt.Printf("%s\t%s\n", key.PublicKey, key.Label)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"simple-sops/internal/config"
@@ -14,17 +14,34 @@ import (
 
 // GetKeyCmd returns the get-key command
 func GetKeyCmd() *cobra.Command {
+	var printPath bool
+
 	cmd := &cobra.Command{
 		Use:   "get-key",
 		Short: "Load SOPS Age key from 1Password",
-		Long:  `Retrieve the SOPS Age key from 1Password and store it in a temporary file.`,
+		Long: `Retrieve the SOPS Age key from 1Password and store it in a temporary file.
+A process can't change the environment of your shell, so use the functions of
+'simple-sops shell-init' to set SOPS_AGE_KEY_FILE and remove the key when the
+shell exits.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// The key is handed to the calling shell, so gc keeps it while the shell runs
+			keymgmt.SetTempDirOwner(os.Getppid())
+
 			// Get the key from 1Password
 			tempKeyFile, err := keymgmt.GetKeyFromOnePassword(keymgmt.DefaultOnePasswordConfig)
 			if err != nil {
 				return fmt.Errorf("failed to get key from 1Password: %w", err)
 			}
 
+			// The shell removes the key, simple-sops only does if it is interrupted before handing it over
+			defer keymgmt.KeepOnExit(filepath.Dir(tempKeyFile))
+
+			// Shell integration reads the path from stdout
+			if printPath {
+				fmt.Println(tempKeyFile)
+				return nil
+			}
+
 			// Set the environment variable
 			os.Setenv("SOPS_AGE_KEY_FILE", tempKeyFile)
 
@@ -32,13 +49,12 @@ func GetKeyCmd() *cobra.Command {
 			logging.Info("SOPS_AGE_KEY_FILE set to %s", tempKeyFile)
 			logging.Info("The key will be removed when the shell exits or when clear-key is called.")
 
-			// Register cleanup on exit
-			keymgmt.RegisterCleanupOnExit(tempKeyFile)
-
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&printPath, "print-path", false, "Only print the path of the temporary key file")
+
 	return cmd
 }
 
@@ -57,7 +73,7 @@ func ClearKeyCmd() *cobra.Command {
 			}
 
 			// Check if it's a temporary file
-			if tempDir := filepath.Dir(keyFile); strings.HasPrefix(filepath.Base(tempDir), "simple-sops-") {
+			if keymgmt.IsTempKeyFile(keyFile) {
 				// Remove the temporary directory
 				if err := keymgmt.CleanupTempAgeKeyFile(keyFile); err != nil {
 					return fmt.Errorf("failed to remove temporary key file: %w", err)
@@ -76,17 +92,77 @@ func ClearKeyCmd() *cobra.Command {
 	return cmd
 }
 
+// GCCmd returns the gc command
+func GCCmd() *cobra.Command {
+	var (
+		olderThan time.Duration
+		dryRun    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove orphaned temporary keys and decrypted files",
+		Long: `Remove simple-sops temporary directories left behind by processes that were
+killed before they could clean up. A directory is removed when it is older
+than --older-than and the process it belongs to is no longer running. Keys
+loaded with get-key belong to the shell that loaded them. Files are
+overwritten with zeros before they are removed.
+
+Every simple-sops command runs the same sweep with the default age on startup.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := keymgmt.SweepTempDirs(olderThan, dryRun)
+			if err != nil {
+				return err
+			}
+
+			if len(removed) == 0 {
+				logging.Info("No orphaned temporary directories found.")
+				return nil
+			}
+
+			for _, dir := range removed {
+				if dryRun {
+					logging.Info("[dry-run] Would remove: %s", dir)
+				} else {
+					logging.Info("Removed %s", dir)
+				}
+			}
+			if !dryRun {
+				logging.Success("Removed %d orphaned temporary directories.", len(removed))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&olderThan, "older-than", keymgmt.DefaultTempMaxAge, "Only remove directories older than this")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the directories without removing them")
+
+	return cmd
+}
+
 // GenerateKeyCmd returns the gen-key command
 func GenerateKeyCmd() *cobra.Command {
 	var (
 		keyFile string
 		force   bool
+		piv     bool
+		profile string
+		options keymgmt.PIVOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "gen-key",
 		Short: "Generate a new Age key pair",
-		Long:  `Generate a new Age key pair for use with SOPS.`,
+		Long: `Generate a new Age key pair for use with SOPS.
+
+With --piv the identity is generated on a YubiKey with age-plugin-yubikey
+instead, so the private key never leaves the hardware. The key file only
+references the PIV slot. Its recipient is added to the trust store, and the
+active profile, or the one given with --profile, is set to use it.`,
+		Example: `  simple-sops gen-key
+  simple-sops gen-key --piv --slot 2 --touch-policy cached
+  simple-sops gen-key --piv --profile work`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load application config
 			appConfig, err := config.LoadConfig()
@@ -94,6 +170,10 @@ func GenerateKeyCmd() *cobra.Command {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
+			if piv {
+				return generatePIVKey(appConfig, keyFile, profile, force, options)
+			}
+
 			// If key file not specified in flags, use the one from config
 			if keyFile == "" {
 				keyFile = appConfig.KeyFile
@@ -114,12 +194,95 @@ func GenerateKeyCmd() *cobra.Command {
 				return fmt.Errorf("failed to generate Age key: %w", err)
 			}
 
+			// Trust our own key so it doesn't show up as an unknown recipient
+			pubKey, err := keymgmt.GetPublicKeyFromFile(keyFile)
+			if err != nil {
+				return fmt.Errorf("failed to get public key: %w", err)
+			}
+
+			trustStore, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+			if err != nil {
+				return fmt.Errorf("failed to load trust store: %w", err)
+			}
+			trustStore.Add(pubKey, fmt.Sprintf("Local key (%s)", expandedPath))
+			if err := keymgmt.SaveTrustStore(keymgmt.DefaultTrustStoreFile, trustStore); err != nil {
+				return fmt.Errorf("failed to save trust store: %w", err)
+			}
+
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Path to save the generated key (defaults to config setting)")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing key file if it exists")
+	cmd.Flags().BoolVar(&piv, "piv", false, "Generate the identity on a YubiKey PIV slot with age-plugin-yubikey")
+	cmd.Flags().IntVar(&options.Slot, "slot", keymgmt.MinPIVSlot, "PIV slot of the identity, 1 to 20")
+	cmd.Flags().StringVar(&options.Serial, "serial", "", "Serial number of the YubiKey to use if several are plugged in")
+	cmd.Flags().StringVar(&options.Name, "name", "", "Name of the identity on the YubiKey")
+	cmd.Flags().StringVar(&options.PINPolicy, "pin-policy", "", "When the PIN is required: always, once or never")
+	cmd.Flags().StringVar(&options.TouchPolicy, "touch-policy", "", "When a touch is required: always, cached or never")
+	cmd.Flags().StringVar(&profile, "profile", "", "Profile to use the YubiKey identity in (defaults to the active profile)")
 
 	return cmd
 }
+
+// generatePIVKey generates an identity on a YubiKey, trusts it and configures it
+// The key file defaults to one per slot in the config directory, so an
+// existing software key is kept.
+func generatePIVKey(appConfig *config.AppConfig, keyFile string, profile string, force bool, options keymgmt.PIVOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	if keyFile == "" {
+		keyFile = filepath.Join(configDir, fmt.Sprintf("yubikey-slot-%d.txt", options.Slot))
+	}
+	expandedPath, err := keymgmt.ExpandPath(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to expand path: %w", err)
+	}
+	if _, err := os.Stat(expandedPath); err == nil && !force {
+		return fmt.Errorf("key file already exists at %s. Use --force to overwrite", expandedPath)
+	}
+
+	// The old file is only replaced once the new identity exists
+	recipient, err := keymgmt.GeneratePIVKey(expandedPath, options, force)
+	if err != nil {
+		return err
+	}
+
+	trustStore, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+	if err != nil {
+		return fmt.Errorf("failed to load trust store: %w", err)
+	}
+	trustStore.Add(recipient, fmt.Sprintf("YubiKey PIV slot %d (%s)", options.Slot, expandedPath))
+	if err := keymgmt.SaveTrustStore(keymgmt.DefaultTrustStoreFile, trustStore); err != nil {
+		return fmt.Errorf("failed to save trust store: %w", err)
+	}
+
+	if profile == "" {
+		profile = appConfig.Profile
+	}
+	configPath, err := config.GetConfigFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to determine config path: %w", err)
+	}
+	if err := config.SaveKeyFile(configPath, profile, expandedPath); err != nil {
+		return err
+	}
+
+	switch {
+	case profile == "":
+		logging.Success("simple-sops now uses the YubiKey identity.")
+	case profile == appConfig.Profile:
+		logging.Success("Profile %s now uses the YubiKey identity.", profile)
+	default:
+		logging.Success("Profile %s now uses the YubiKey identity. Select it with profile: %s in %s.", profile, profile, configPath)
+	}
+	logging.Info("Add %s as a recipient with 'simple-sops add-recipient' to re-encrypt existing files for it.", recipient)
+	return nil
+}
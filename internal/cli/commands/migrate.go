@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// MigrateCmd returns the migrate command
+func MigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate configuration written by older versions",
+	}
+
+	cmd.AddCommand(migrateConfigCmd())
+
+	return cmd
+}
+
+// migrateConfigCmd returns the migrate config command
+func migrateConfigCmd() *cobra.Command {
+	var (
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Rewrite .sops.yaml and the application config to the current schema",
+		Long: `Rewrite .sops.yaml and the application config to the current schema.
+Rules holding a plain file name are anchored to the file's path, duplicated
+and shadowing catch-all rules are fixed and old setting names are renamed.
+A diff is shown before anything is changed, and a .bak copy of every
+rewritten file is kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get the SOPS config path
+			sopsConfigPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+
+			appConfigPath, err := config.GetConfigFilePath()
+			if err != nil {
+				return fmt.Errorf("failed to determine config path: %w", err)
+			}
+
+			sopsChanged, err := migrateSopsConfig(sopsConfigPath, dryRun, yes)
+			if err != nil {
+				return err
+			}
+
+			appChanged, err := migrateAppConfig(appConfigPath, dryRun, yes)
+			if err != nil {
+				return err
+			}
+
+			if !sopsChanged && !appChanged {
+				logging.Success("Configuration is up to date.")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply the changes without asking")
+
+	return cmd
+}
+
+// migrateSopsConfig migrates the .sops.yaml file and reports whether it needed changes
+func migrateSopsConfig(configPath string, dryRun bool, yes bool) (bool, error) {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		logging.Debug("No SOPS config at %s", configPath)
+		return false, nil
+	}
+
+	rules := config.NewRuleSet(configPath)
+
+	var changes []string
+	migrate := func(sopsConfig *config.SopsConfig) error {
+		migrated, err := config.MigrateSopsConfig(sopsConfig, config.RepoRoot(configPath))
+		if err != nil {
+			return fmt.Errorf("failed to migrate SOPS config: %w", err)
+		}
+		changes = migrated
+		return nil
+	}
+
+	sopsConfig, err := rules.Preview(migrate)
+	if err != nil {
+		return false, err
+	}
+
+	diff, err := config.PreviewSopsConfig(configPath, sopsConfig)
+	if err != nil {
+		return false, err
+	}
+	if len(changes) == 0 && diff == "" {
+		return false, nil
+	}
+
+	if !confirmMigration(configPath, changes, diff, dryRun, yes) {
+		return true, nil
+	}
+
+	backupPath, err := config.BackupFile(configPath)
+	if err != nil {
+		return true, err
+	}
+
+	if _, err := rules.Update(migrate); err != nil {
+		return true, fmt.Errorf("failed to update SOPS config: %w", err)
+	}
+
+	logging.Success("Migrated %s (backup: %s)", configPath, backupPath)
+	return true, nil
+}
+
+// migrateAppConfig migrates the application config file and reports whether it needed changes
+func migrateAppConfig(configPath string, dryRun bool, yes bool) (bool, error) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		logging.Debug("No config file at %s", configPath)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	migrated, changes, err := config.MigrateAppConfig(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to migrate %s: %w", configPath, err)
+	}
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	diff := config.DiffLines(string(data), string(migrated))
+	if !confirmMigration(configPath, changes, diff, dryRun, yes) {
+		return true, nil
+	}
+
+	backupPath, err := config.BackupFile(configPath)
+	if err != nil {
+		return true, err
+	}
+
+	if err := os.WriteFile(configPath, migrated, 0600); err != nil {
+		return true, fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	logging.Success("Migrated %s (backup: %s)", configPath, backupPath)
+	return true, nil
+}
+
+// confirmMigration shows the planned changes of a file and asks whether to apply them
+func confirmMigration(configPath string, changes []string, diff string, dryRun bool, yes bool) bool {
+	logging.Info("Changes to %s:", configPath)
+	for _, change := range changes {
+		logging.Info("  - %s", change)
+	}
+	fmt.Print(diff)
+
+	if dryRun {
+		return false
+	}
+
+	if !yes && !logging.Confirm(fmt.Sprintf("Apply these changes to %s?", configPath)) {
+		logging.Info("Skipping %s.", configPath)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// kmsPrefix marks AWS KMS keys given to --also
+const kmsPrefix = "kms:"
+
+// MigrateRecipientsCmd returns the migrate-recipients command
+func MigrateRecipientsCmd() *cobra.Command {
+	var (
+		from     []string
+		to       []string
+		also     []string
+		keyFile  string
+		dryRun   bool
+		noRotate bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate-recipients --from <name|age1...> --to <name|age1...> [--also kms:<arn>]",
+		Short: "Move every file from an old recipient to new ones",
+		Long: `Replace a recipient in every .sops.yaml rule and every encrypted file it can
+read, e.g. when a developer leaves or a key is compromised. The rules get the
+--to recipients instead of the --from ones, plus the AWS KMS keys given with
+--also. The files readable by the old recipient get a new data key for the
+new set of recipients in one sops run, which works even with the old key, so
+the old key can't read values written afterwards. With --no-rotate the files
+only get the recipients of their rules with sops updatekeys. Values the old
+key could read before must still be changed separately.`,
+		Example: `  simple-sops migrate-recipients --from age1old... --to age1new...
+  simple-sops migrate-recipients --from alice --to backend --also kms:arn:aws:kms:eu-west-1:111122223333:key/abcd
+  simple-sops migrate-recipients --from age1old... --to age1new... --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetDryRun(dryRun)
+
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			// The old recipients are usually those of a member who was offboarded
+			oldRecipients, err := resolveRevokedTeamRecipients(root, from)
+			if err != nil {
+				return err
+			}
+			newRecipients, err := resolveTeamRecipients(root, to)
+			if err != nil {
+				return err
+			}
+			for _, recipient := range newRecipients {
+				if slices.Contains(oldRecipients, recipient) {
+					return fmt.Errorf("%s is both an old and a new recipient", recipient)
+				}
+			}
+
+			var kmsKeys []string
+			for _, key := range also {
+				arn, ok := strings.CutPrefix(key, kmsPrefix)
+				if !ok || arn == "" {
+					return fmt.Errorf("unsupported recipient %q for --also, expected kms:<arn>", key)
+				}
+				kmsKeys = append(kmsKeys, arn)
+			}
+
+			// Find the files the old recipients can read before the rules change
+			files, err := config.FindEncryptedFiles(root)
+			if err != nil {
+				return fmt.Errorf("failed to find encrypted files: %w", err)
+			}
+			var readable []string
+			fileRecipients := make(map[string][]string)
+			for _, file := range files {
+				metadata, err := config.ReadFileMetadata(file)
+				if err != nil {
+					logging.Warn("Skipping %s: %v", file, err)
+					continue
+				}
+				if slices.ContainsFunc(metadata.Recipients, func(recipient string) bool { return slices.Contains(oldRecipients, recipient) }) {
+					readable = append(readable, file)
+					fileRecipients[file] = metadata.Recipients
+				}
+			}
+
+			// Replace the recipients in every rule in one change
+			var changed []string
+			replace := func(sopsConfig *config.SopsConfig) error {
+				changed = config.ReplaceRecipients(sopsConfig, oldRecipients, newRecipients, kmsKeys)
+				return nil
+			}
+
+			rules := config.NewRuleSet(configPath)
+			if dryRun {
+				sopsConfig, err := rules.Preview(replace)
+				if err != nil {
+					return err
+				}
+				if err := encrypt.PrintConfigPreview(configPath, sopsConfig); err != nil {
+					return err
+				}
+			} else if _, err := rules.Update(replace); err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
+			}
+			if !dryRun {
+				logging.Info("Updated %d rules in %s", len(changed), configPath)
+			}
+
+			if len(readable) == 0 {
+				logging.Success("No encrypted files are readable by the old recipients.")
+				return nil
+			}
+
+			// Updating the keys of a file needs its current key
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			var failed []string
+			for _, file := range readable {
+				if encrypt.FailFast() && len(failed) > 0 {
+					break
+				}
+				if noRotate {
+					if err := encrypt.UpdateKeys(file, keyPath); err != nil {
+						logging.Error("Failed to update the keys of %s: %v", file, err)
+						failed = append(failed, file)
+					}
+					continue
+				}
+
+				// Only change the recipients the file has or lacks
+				current := fileRecipients[file]
+				var remove, add []string
+				for _, recipient := range oldRecipients {
+					if slices.Contains(current, recipient) {
+						remove = append(remove, recipient)
+					}
+				}
+				for _, recipient := range newRecipients {
+					if !slices.Contains(current, recipient) {
+						add = append(add, recipient)
+					}
+				}
+				if err := encrypt.RotateRecipients(file, keyPath, remove, add, kmsKeys); err != nil {
+					logging.Error("Failed to rotate %s: %v", file, err)
+					failed = append(failed, file)
+				}
+			}
+
+			if len(failed) > 0 {
+				err := fmt.Errorf("%d of %d files could not be migrated: %s", len(failed), len(readable), strings.Join(failed, ", "))
+				// The rules changed already, so any failure leaves the files partly migrated
+				return exitcode.Wrap(exitcode.Partial, err)
+			}
+			if !dryRun {
+				logging.Success("Migrated %d files. Change the values the old key could read, too.", len(readable))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&from, "from", nil, "Recipients to remove: Age public keys, member or group names")
+	cmd.Flags().StringSliceVar(&to, "to", nil, "Recipients to add instead: Age public keys, member or group names")
+	cmd.Flags().StringSliceVar(&also, "also", nil, "Additional recipients of other key types, e.g. kms:<arn>")
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the planned changes without making them")
+	cmd.Flags().BoolVar(&noRotate, "no-rotate", false, "Apply the rules with updatekeys, keeping the data keys")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// NewCmd returns the new command
+func NewCmd() *cobra.Command {
+	var (
+		keyFile   string
+		values    []string
+		generated []string
+		list      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "new <template> <file>",
+		Short: "Create an encrypted file from a template",
+		Long: `Create a secret file from a template in ~/.config/simple-sops/templates/
+and encrypt it. Templates are plaintext files with placeholders like
+{{ DB_PASSWORD }}. The value of every placeholder is asked for without echoing
+it, unless it is given with --set or generated with --generate, e.g.
+DB_PASSWORD=password:32. Without a terminal every value must be given. Values
+are quoted for YAML and JSON files. Generated values are never printed. The
+template is given by its file name, with or without extension.`,
+		Example: `  simple-sops new database secrets/db.yaml
+  simple-sops new database secrets/db.yaml --set DB_USER=app --generate DB_PASSWORD=password:32
+  simple-sops new --list`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templatesDir, err := config.GetTemplatesDir()
+			if err != nil {
+				return fmt.Errorf("failed to determine templates directory: %w", err)
+			}
+
+			if list {
+				names, err := config.ListTemplates(templatesDir)
+				if err != nil {
+					return err
+				}
+				if len(names) == 0 {
+					logging.Info("No templates found in %s.", templatesDir)
+					return nil
+				}
+				for _, name := range names {
+					fmt.Println(name)
+				}
+				return nil
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetWildcardPolicy(appConfig.Wildcard)
+			encrypt.SetRecipientPolicy(appConfig.RecipientPolicy)
+			encrypt.SetEncryptedRegexDefaults(appConfig.EncryptedRegexDefaults)
+
+			dest := args[1]
+			if err := createFromTemplate(templatesDir, args[0], dest, values, generated); err != nil {
+				return err
+			}
+
+			if err := encrypt.EncryptFiles([]string{dest}, keyFile, appConfig.AlwaysUseOnePassword); err != nil {
+				logging.Warn("%s was created but not encrypted. Encrypt it with 'simple-sops encrypt %s' or delete it.", dest, dest)
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringArrayVar(&values, "set", nil, "Value of a placeholder as NAME=value, asked for if missing")
+	cmd.Flags().StringArrayVar(&generated, "generate", nil, "Generate a placeholder's value as NAME=spec, e.g. DB_PASSWORD=password:32")
+	cmd.Flags().BoolVar(&list, "list", false, "List the available templates")
+
+	return cmd
+}
+
+// createFromTemplate writes the plaintext file dest from a template
+// Placeholders get their value from values (NAME=value) or a random value from
+// generated (NAME=spec), others are asked for on the terminal. An existing
+// file is only overwritten after confirmation.
+func createFromTemplate(templatesDir string, name string, dest string, values []string, generated []string) error {
+	templatePath, err := config.FindTemplate(templatesDir, name)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	given := make(map[string]string)
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set %s, expected NAME=value", value)
+		}
+		given[key] = val
+	}
+	for _, spec := range generated {
+		key, kind, ok := strings.Cut(spec, "=")
+		if !ok {
+			return fmt.Errorf("invalid --generate %s, expected NAME=spec", spec)
+		}
+		value, err := generateValue(kind)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		given[key] = value
+	}
+
+	for _, placeholder := range config.TemplatePlaceholders(string(content)) {
+		if _, ok := given[placeholder]; !ok {
+			value, err := readPlaceholder(placeholder)
+			if err != nil {
+				return err
+			}
+			given[placeholder] = value
+		}
+	}
+
+	rendered, err := config.RenderTemplate(string(content), given, dest)
+	if err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		if !logging.Confirm(fmt.Sprintf("%s already exists. Overwrite it?", dest)) {
+			return fmt.Errorf("%s already exists", dest)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dest, err)
+	}
+	if err := os.WriteFile(dest, []byte(rendered), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+
+	logging.Info("Created %s from template %s", dest, templatePath)
+	return nil
+}
+
+// readPlaceholder asks for the value of a placeholder without echoing it
+// Without a terminal, e.g. on CI, values must be given with --set or --generate.
+func readPlaceholder(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if logging.IsNonInteractive || !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no value for %s, give it with --set or --generate", name)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", name)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(value) == 0 {
+		return "", fmt.Errorf("no value for %s", name)
+	}
+	return string(value), nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/deps"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// Results of a preflight check
+const (
+	preflightOK      = "ok"
+	preflightFailed  = "failed"
+	preflightSkipped = "skipped"
+)
+
+// preflightCheck is the result of one check of preflight
+type preflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// preflightResult is the output of preflight
+type preflightResult struct {
+	OK     bool             `json:"ok"`
+	Checks []preflightCheck `json:"checks"`
+}
+
+// PreflightCmd returns the preflight command
+func PreflightCmd() *cobra.Command {
+	var (
+		keyFile  string
+		canaries []string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that secrets can be decrypted, for container entrypoints",
+		Long: `Check that the config loads, sops is installed, the key is available and
+canary files decrypt, then exit. The checks stop at the first failure and the
+exit code tells which one failed (see 'simple-sops help exit-codes'), so an
+initContainer can keep a pod from starting without its secrets. Decrypted
+canaries are discarded. Use --format json for a single JSON object.`,
+		Example: `  simple-sops preflight --canary secrets/app.enc.yaml
+  SIMPLE_SOPS_KEY_FILE=/run/secrets/age-key simple-sops preflight --canary /secrets/db.enc.env --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unsupported format: %s (supported: text, json)", format)
+			}
+
+			// The checks report their failures, usage doesn't help
+			cmd.SilenceUsage = true
+
+			result, err := runPreflight(keyFile, canaries)
+			if format == "json" {
+				if encodeErr := json.NewEncoder(os.Stdout).Encode(result); encodeErr != nil {
+					return encodeErr
+				}
+			} else {
+				for _, check := range result.Checks {
+					line := fmt.Sprintf("[%s] %s", check.Status, check.Name)
+					if check.Detail != "" {
+						line += ": " + check.Detail
+					}
+					logging.Info("%s", line)
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringSliceVar(&canaries, "canary", nil, "Encrypted file that must decrypt (repeatable)")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// runPreflight runs the checks of preflight until one fails
+// Checks after a failure are reported as skipped. The error carries the exit
+// code of the failed check.
+func runPreflight(keyFile string, canaries []string) (preflightResult, error) {
+	result := preflightResult{OK: true}
+	var failure error
+	check := func(name string, detail string, err error) {
+		switch {
+		case failure != nil:
+			result.Checks = append(result.Checks, preflightCheck{Name: name, Status: preflightSkipped})
+		case err != nil:
+			result.OK = false
+			failure = err
+			result.Checks = append(result.Checks, preflightCheck{Name: name, Status: preflightFailed, Detail: err.Error()})
+		default:
+			result.Checks = append(result.Checks, preflightCheck{Name: name, Status: preflightOK, Detail: detail})
+		}
+	}
+
+	appConfig, err := config.LoadConfig()
+	check("config", "", err)
+	if failure == nil && keyFile == "" {
+		keyFile = appConfig.KeyFile
+	}
+
+	if failure == nil && len(deps.Missing(deps.Sops)) > 0 {
+		err = exitcode.Wrap(exitcode.Sops, fmt.Errorf("sops not found in PATH"))
+	}
+	check("sops", "", err)
+
+	var (
+		keyPath string
+		isTemp  bool
+	)
+	if failure == nil {
+		keyPath, isTemp, err = keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+		if err == nil && isTemp {
+			defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+		}
+	}
+	keyDetail := keyPath
+	if isTemp {
+		keyDetail = "temporary key"
+	}
+	check("key", keyDetail, err)
+
+	for _, canary := range canaries {
+		if failure == nil {
+			var plaintext []byte
+			plaintext, err = encrypt.DecryptToBytes(canary, keyPath)
+			clear(plaintext)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", canary, err)
+			}
+		}
+		check("decrypt", canary, err)
+	}
+
+	return result, failure
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// KubeconfigCmd returns the kubeconfig command
+func KubeconfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Manage kubeconfig files with the kubeconfig preset",
+	}
+
+	cmd.AddCommand(presetEncryptCmd(encrypt.PresetKubeconfig, "kubeconfig files"))
+
+	return cmd
+}
+
+// TalosCmd returns the talos command
+func TalosCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "talos",
+		Short: "Manage Talos configs with the talos preset",
+	}
+
+	cmd.AddCommand(presetEncryptCmd(encrypt.PresetTalos, "talosconfig, machine configs and secrets bundles"))
+
+	return cmd
+}
+
+// presetEncryptCmd returns the encrypt subcommand of a preset
+func presetEncryptCmd(presetName string, description string) *cobra.Command {
+	var (
+		keyFile    string
+		recipients []string
+		dryRun     bool
+	)
+
+	preset, _ := encrypt.GetPreset(presetName)
+
+	cmd := &cobra.Command{
+		Use:   "encrypt [file...]",
+		Short: fmt.Sprintf("Encrypt %s in one step", description),
+		Long: fmt.Sprintf(`Encrypt %s in one step.
+The .sops.yaml rule of every file gets the %s encrypted_regex, replacing the
+one of an existing rule, and the recipients of the preset in the config file.
+Without arguments, the files named %s in the current
+directory are encrypted, if they exist and are not encrypted yet.`,
+			description, preset.Pattern, strings.Join(preset.DefaultFiles, ", ")),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			settings := appConfig.Presets[presetName]
+
+			files := args
+			if len(files) == 0 {
+				wd, err := os.Getwd()
+				if err != nil {
+					return fmt.Errorf("failed to get current directory: %w", err)
+				}
+				if files = preset.FindDefaultFiles(wd); len(files) == 0 {
+					logging.Info("No %s to encrypt in %s.", description, wd)
+					return nil
+				}
+			}
+
+			encryptedRegex := preset.EncryptedRegex(settings)
+			logging.Info("Using the %s preset with encrypted_regex %s", presetName, encryptedRegex)
+
+			encrypt.SetWildcardPolicy(appConfig.Wildcard)
+			encrypt.SetRecipientPolicy(appConfig.RecipientPolicy)
+			encrypt.SetEncryptedRegexOverride(encryptedRegex)
+			encrypt.SetDryRun(dryRun)
+
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			// Encrypt to the preset's recipients in addition to your own key
+			extraRecipients := config.UniqueRecipients(append(settings.Recipients, recipients...))
+			if len(extraRecipients) > 0 {
+				return encrypt.EncryptFilesWithMultipleKeys(
+					files,
+					[]string{keyFile},
+					extraRecipients,
+					appConfig.AlwaysUseOnePassword,
+					nil)
+			}
+
+			return encrypt.EncryptFiles(files, keyFile, appConfig.AlwaysUseOnePassword)
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringSliceVar(&recipients, "recipient", nil, "Additional Age recipients to encrypt to")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the sops commands and SOPS configuration changes without running them")
+
+	return cmd
+}
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ProjectCmd returns the project command
+func ProjectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "project",
+		Short: "Register repositories to use with --project from anywhere",
+		Long: `Register repositories with their defaults, so any command can operate on them
+with --project <name> from any directory. File arguments are then relative to
+the project. A project can select a key profile, a .sops.yaml other than the
+one at the repository root, and patterns that restrict the encrypted files
+commands like status and rotate find without file arguments.`,
+	}
+
+	cmd.AddCommand(projectAddCmd())
+	cmd.AddCommand(projectListCmd())
+	cmd.AddCommand(projectRemoveCmd())
+
+	return cmd
+}
+
+// projectAddCmd returns the project add command
+func projectAddCmd() *cobra.Command {
+	var project config.Project
+
+	cmd := &cobra.Command{
+		Use:   "add <name> <path>",
+		Short: "Register a repository or update its defaults",
+		Example: `  simple-sops project add homelab ~/src/homelab --profile personal
+  simple-sops project add infra ~/work/infra --pattern 'clusters/' --sops-config clusters/.sops.yaml
+  simple-sops --project homelab status`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if name == "" || strings.ContainsAny(name, " \t/") {
+				return fmt.Errorf("invalid project name %q", name)
+			}
+
+			path, err := filepath.Abs(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", args[1], err)
+			}
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", path)
+			}
+			project.Path = path
+
+			if project.Profile != "" {
+				appConfig, err := config.LoadConfig()
+				if err != nil {
+					return fmt.Errorf("failed to load config: %w", err)
+				}
+				if _, ok := appConfig.Profiles[project.Profile]; !ok {
+					return fmt.Errorf("profile %s not found in the config", project.Profile)
+				}
+			}
+			if err := project.Validate(); err != nil {
+				return err
+			}
+
+			registryPath, err := config.ProjectsFilePath()
+			if err != nil {
+				return err
+			}
+			projects, err := config.LoadProjects(registryPath)
+			if err != nil {
+				return err
+			}
+
+			_, exists := projects.Projects[name]
+			projects.Projects[name] = &project
+			if err := config.SaveProjects(registryPath, projects); err != nil {
+				return err
+			}
+
+			if exists {
+				logging.Success("Updated project %s: %s", name, path)
+			} else {
+				logging.Success("Added project %s: %s", name, path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&project.Profile, "profile", "", "Key profile to use for the project")
+	cmd.Flags().StringVar(&project.SopsConfig, "sops-config", "", "The project's .sops.yaml, relative to its path")
+	cmd.Flags().StringArrayVar(&project.Patterns, "pattern", nil, "Restrict found encrypted files to a gitignore-style pattern (repeatable)")
+
+	return cmd
+}
+
+// projectListCmd returns the project list command
+func projectListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the registered projects",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registryPath, err := config.ProjectsFilePath()
+			if err != nil {
+				return err
+			}
+			projects, err := config.LoadProjects(registryPath)
+			if err != nil {
+				return err
+			}
+
+			if len(projects.Projects) == 0 {
+				logging.Info("No projects registered. Add one with 'simple-sops project add <name> <path>'.")
+				return nil
+			}
+
+			for _, name := range projects.Names() {
+				project := projects.Projects[name]
+				line := fmt.Sprintf("%s\t%s", name, project.Path)
+				if project.Profile != "" {
+					line += "\tprofile=" + project.Profile
+				}
+				if project.SopsConfig != "" {
+					line += "\tsops_config=" + project.SopsConfig
+				}
+				if len(project.Patterns) > 0 {
+					line += "\tpatterns=" + strings.Join(project.Patterns, ",")
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+// projectRemoveCmd returns the project remove command
+func projectRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "remove <name>",
+		Short:             "Unregister a project, its files are kept",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: CompleteProjects,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registryPath, err := config.ProjectsFilePath()
+			if err != nil {
+				return err
+			}
+			projects, err := config.LoadProjects(registryPath)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := projects.Projects[args[0]]; !ok {
+				return fmt.Errorf("project %s not found", args[0])
+			}
+			delete(projects.Projects, args[0])
+			if err := config.SaveProjects(registryPath, projects); err != nil {
+				return err
+			}
+
+			logging.Success("Removed project %s", args[0])
+			return nil
+		},
+	}
+}
+
+// CompleteProjects completes the names of registered projects
+func CompleteProjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	registryPath, err := config.ProjectsFilePath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	projects, err := config.LoadProjects(registryPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return projects.Names(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// UseProject activates a registered project for the running command
+func UseProject(name string) error {
+	registryPath, err := config.ProjectsFilePath()
+	if err != nil {
+		return err
+	}
+	projects, err := config.LoadProjects(registryPath)
+	if err != nil {
+		return err
+	}
+
+	project, err := config.ActivateProject(projects, name)
+	if err != nil {
+		return err
+	}
+	logging.Debug("Operating on project %s in %s", name, project.Path)
+	return nil
+}
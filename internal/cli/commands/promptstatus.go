@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// promptStatus is the status printed by prompt-status
+type promptStatus struct {
+	Key        keymgmt.KeyState `json:"key"`
+	Path       string           `json:"path,omitempty"`
+	AgeSeconds int64            `json:"age_seconds,omitempty"`
+	TempKeys   int              `json:"temp_keys"`
+	Profile    string           `json:"profile,omitempty"`
+}
+
+// PromptStatusCmd returns the prompt-status command
+func PromptStatusCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "prompt-status",
+		Short: "Print the key status for shell prompts",
+		Long: `Print a short, machine-readable status for shell prompt segments:
+
+  key=temp age=12m temp_keys=1 profile=work
+
+key is none, file, temp (loaded by get-key) or missing (SOPS_AGE_KEY_FILE
+points to a removed file). age is how long a temporary key has existed and
+temp_keys counts the temporary keys left in the temp directory, including
+ones loaded by other shells. Nothing is printed when no key is loaded and no
+temporary keys exist, so prompt segments can hide. Use --format json for
+all fields.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keyStatus := keymgmt.CurrentKeyStatus(time.Now())
+			status := promptStatus{
+				Key:        keyStatus.State,
+				Path:       keyStatus.Path,
+				AgeSeconds: int64(keyStatus.Age.Seconds()),
+				TempKeys:   keyStatus.TempKeys,
+			}
+
+			// A broken config shouldn't break the prompt
+			if appConfig, err := config.LoadConfig(); err == nil {
+				status.Profile = appConfig.Profile
+			}
+
+			switch format {
+			case "short":
+				if line := formatPromptStatus(status); line != "" {
+					fmt.Println(line)
+				}
+				return nil
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(status)
+			default:
+				return fmt.Errorf("unsupported format: %s (supported: short, json)", format)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "short", "Output format: short or json")
+
+	return cmd
+}
+
+// formatPromptStatus returns the status as key=value pairs, or nothing if there is nothing to show
+func formatPromptStatus(status promptStatus) string {
+	if status.Key == keymgmt.KeyNone && status.TempKeys == 0 {
+		return ""
+	}
+
+	fields := []string{"key=" + string(status.Key)}
+	if status.Key == keymgmt.KeyTemporary {
+		fields = append(fields, "age="+formatShortDuration(time.Duration(status.AgeSeconds)*time.Second))
+	}
+	if status.TempKeys > 0 {
+		fields = append(fields, fmt.Sprintf("temp_keys=%d", status.TempKeys))
+	}
+	if status.Profile != "" {
+		fields = append(fields, "profile="+status.Profile)
+	}
+	return strings.Join(fields, " ")
+}
+
+// formatShortDuration formats a duration in its largest whole unit, e.g. 45s, 12m, 3h or 2d
+func formatShortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
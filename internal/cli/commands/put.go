@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/generate"
+	"simple-sops/internal/keymgmt"
+
+	"github.com/spf13/cobra"
+)
+
+// PutCmd returns the put command
+func PutCmd() *cobra.Command {
+	var (
+		keyFile      string
+		valuePath    string
+		fromStdin    bool
+		value        string
+		generateSpec string
+		rawJSON      bool
+		document     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "put [file]",
+		Short: "Insert or update a single value in an encrypted file",
+		Long: `Insert or update a single value in an encrypted file without opening an editor.
+The value is read from stdin (--from-stdin), so multi-line values like PEM
+certificates can be piped in directly. --generate creates a random value that
+is stored without ever being printed, e.g. password:32, hex:64 or uuid.
+sops only sets values in the first document of a file, so files with several
+YAML documents need --document 1.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			sources := 0
+			for _, source := range []bool{fromStdin, cmd.Flags().Changed("value"), generateSpec != ""} {
+				if source {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return fmt.Errorf("specify exactly one of --from-stdin, --value or --generate")
+			}
+
+			data := []byte(value)
+			switch {
+			case fromStdin:
+				if keymgmt.HasStdinIdentity() {
+					return fmt.Errorf("--from-stdin can't be combined with --identity-stdin, which reads stdin first")
+				}
+				data, err = io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read stdin: %w", err)
+				}
+			case generateSpec != "":
+				if rawJSON {
+					return fmt.Errorf("--generate can't be combined with --json")
+				}
+				generated, err := generateValue(generateSpec)
+				if err != nil {
+					return err
+				}
+				data = []byte(generated)
+			}
+
+			return encrypt.PutValue(args[0], keyFile, valuePath, data, rawJSON, document, appConfig.AlwaysUseOnePassword)
+		},
+		Example: `  cat tls.key | simple-sops put secrets.enc.yaml --from-stdin --path '["tls"]["key"]'
+  echo '{"user":"admin"}' | simple-sops put config.enc.json --from-stdin --json --path '["db"]'
+  simple-sops put secrets.enc.yaml --generate password:32 --path '["db"]["password"]'`,
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringVar(&valuePath, "path", "", `Path of the value to set, e.g. '["tls"]["key"]'`)
+	cmd.Flags().BoolVar(&fromStdin, "from-stdin", false, "Read the value from stdin")
+	cmd.Flags().StringVar(&value, "value", "", "Value to set (visible in shell history, prefer --from-stdin)")
+	cmd.Flags().StringVar(&generateSpec, "generate", "", "Generate a random value: password[:length], hex[:length] or uuid")
+	cmd.Flags().BoolVar(&rawJSON, "json", false, "Treat the value as JSON instead of a string")
+	cmd.Flags().IntVar(&document, "document", encrypt.NoDocument, "Document of a multi-document YAML file, starting at 1")
+	cmd.MarkFlagRequired("path")
+
+	return cmd
+}
+
+// generateValue generates a random value from a spec like password:32
+func generateValue(spec string) (string, error) {
+	value, err := generate.Value(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate value: %w", err)
+	}
+	return value, nil
+}
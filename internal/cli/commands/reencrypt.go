@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"simple-sops/internal/encrypt"
+
+	"github.com/spf13/cobra"
+)
+
+// ReencryptCmd returns the re-encrypt command
+func ReencryptCmd() *cobra.Command {
+	var (
+		dryRun bool
+		jobs   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "re-encrypt [file...]",
+		Short: "Encrypt files decrypted in-place again with their .sops.yaml rule",
+		Long: `Encrypt files that were decrypted in-place again with the recipients and
+encrypted_regex of their .sops.yaml rule. Unlike encrypt, the rule is not
+changed and no key is needed, so the file ends up encrypted exactly as
+before without repeating the original flags.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encrypt.SetDryRun(dryRun)
+
+			return encrypt.ReencryptFiles(args, jobs)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the sops commands without running them")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", encrypt.DefaultJobs, "Number of files to encrypt at once")
+
+	return cmd
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/repl"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// ReplCmd returns the repl command
+func ReplCmd() *cobra.Command {
+	var keyFile string
+
+	cmd := &cobra.Command{
+		Use:   "repl",
+		Short: "Work on encrypted files in an interactive session",
+		Long: `Start an interactive session that fetches the key once, e.g. from 1Password,
+and reads commands from stdin: get and set single values, list encrypted files
+and edit files, without authenticating again for every command. Type help for
+the commands and exit or Ctrl-D to end the session, which removes a temporary
+key. Ctrl-C cancels the current line and keeps the session going. Commands can also be piped in, one per line, and the session then fails
+if any of them failed.`,
+		Example: `  simple-sops repl
+  printf 'get secrets.yaml db.password\n' | simple-sops repl`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if keymgmt.HasStdinIdentity() {
+				return fmt.Errorf("repl can't be combined with --identity-stdin, which reads stdin first")
+			}
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+			if err != nil {
+				return err
+			}
+			if isTemp {
+				defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+			}
+
+			interactive := term.IsTerminal(int(os.Stdin.Fd()))
+			session := repl.NewSession(keyPath, os.Stdin, os.Stdout, interactive)
+
+			// Ctrl-C at the prompt cancels the line instead of ending the session
+			if interactive {
+				defer keymgmt.SuspendInterrupt()()
+				interrupts := make(chan os.Signal, 1)
+				signal.Notify(interrupts, os.Interrupt)
+				defer func() {
+					signal.Stop(interrupts)
+					close(interrupts)
+				}()
+				go func() {
+					for range interrupts {
+						session.Interrupt()
+					}
+				}()
+			}
+
+			return session.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+
+	return cmd
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+
+	"github.com/spf13/cobra"
+)
+
+// ResolveCmd returns the resolve command
+func ResolveCmd() *cobra.Command {
+	var (
+		keyFile string
+		tool    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "resolve <file>",
+		Short: "Resolve a merge conflict in an encrypted file",
+		Long: `Resolve a git merge conflict in an encrypted file on its plaintext. The
+conflict markers around the ciphertext can't be resolved by hand, so both
+sides and their common ancestor are taken from the index, decrypted and
+merged again. Remaining conflicts open in $EDITOR, or in the command given
+with --tool, which runs in a shell with $LOCAL, $BASE, $REMOTE and $MERGED
+set to the decrypted files. The resolution is encrypted with the file's rule
+in .sops.yaml and only needs to be staged with git add.`,
+		Example: `  simple-sops resolve secrets/prod.enc.yaml
+  simple-sops resolve secrets/prod.enc.yaml --tool 'vimdiff "$LOCAL" "$MERGED" "$REMOTE"'
+  simple-sops resolve .env.enc --tool 'meld "$LOCAL" "$MERGED" "$REMOTE"'`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			return encrypt.ResolveConflict(args[0], keyFile, tool, appConfig.AlwaysUseOnePassword)
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringVar(&tool, "tool", "", "Merge tool command, run with $LOCAL, $BASE, $REMOTE and $MERGED set")
+
+	return cmd
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// RotateCmd returns the rotate command
+func RotateCmd() *cobra.Command {
+	var (
+		keyFile string
+		due     bool
+		dryRun  bool
+		jobs    int
+		scope   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate [file...]",
+		Short: "Rotate the data keys of encrypted files",
+		Long: `Rotate the data keys of encrypted files using SOPS.
+Without file arguments all encrypted files in the repository are rotated.
+With --due only files that exceed the rotation policy are rotated. Rotations
+are recorded in .simple-sops/rotations.yaml, since edits don't rotate. Files
+are rotated with the sops library, without starting sops for every file, and
+up to --jobs files at once. With --scope only files below a directory are
+rotated.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetDryRun(dryRun)
+
+			if scope, err = resolveScope(scope); err != nil {
+				return err
+			}
+			if err := checkInScope(args, scope); err != nil {
+				return err
+			}
+
+			files := args
+			if len(files) == 0 || due {
+				root, err := getRepoRoot()
+				if err != nil {
+					return err
+				}
+
+				statuses, err := collectFileStatus(root, scopeDir(root, scope), appConfig.ActiveRotationPolicy())
+				if err != nil {
+					return err
+				}
+
+				// Restrict to the given files, if any
+				requested := make(map[string]bool)
+				for _, file := range args {
+					absPath, err := filepath.Abs(file)
+					if err != nil {
+						return fmt.Errorf("failed to resolve %s: %w", file, err)
+					}
+					requested[absPath] = true
+				}
+
+				files = nil
+				for _, status := range statuses {
+					if len(requested) > 0 && !requested[status.Path] {
+						continue
+					}
+					if due && !status.Overdue {
+						continue
+					}
+					files = append(files, status.Path)
+				}
+			}
+
+			if len(files) == 0 {
+				logging.Info("No files need rotation.")
+				return nil
+			}
+
+			return encrypt.WithHooks(appConfig.Hooks, encrypt.OperationRotate, files, func() error {
+				return encrypt.RotateFiles(files, keyFile, appConfig.AlwaysUseOnePassword, jobs)
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVar(&due, "due", false, "Only rotate files that exceed the rotation policy")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the sops commands without running them")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", encrypt.DefaultJobs, "Number of files to rotate at once")
+	cmd.Flags().StringVar(&scope, "scope", "", "Only rotate files below this directory")
+
+	return cmd
+}
@@ -1,24 +1,71 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/metrics"
 	"simple-sops/internal/run"
+	"simple-sops/internal/shellinit"
+	"simple-sops/pkg/logging"
 
 	"github.com/spf13/cobra"
 )
 
 // RunCmd returns the run command
 func RunCmd() *cobra.Command {
-	var keyFile string
+	var (
+		keyFile     string
+		envName     string
+		shellMode   bool
+		watch       bool
+		auto        bool
+		insecure    bool
+		noPTY       bool
+		metricsAddr string
+		cleanEnv    bool
+		keepEnv     []string
+		noTemplate  bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "run [encrypted-file] [output-file (optional)] [command...]",
 		Short: "Run a command with a decrypted file",
-		Long:  `Decrypt a file, run a command with the decrypted content, and clean up afterward.`,
-		Args:  cobra.MinimumNArgs(2),
+		Long: `Decrypt a file, run a command with the decrypted content, and clean up afterward.
+The decrypted path is available in the DECRYPTED_FILE environment variable
+(see --env-name) and replaces any {} placeholder in the command.
+
+Templates like {{ .api.token }} in the arguments are filled in with values of
+the decrypted document, which then stays in memory instead of a file. The
+filled values are part of the command line, where other users on the same host
+can read them with ps or /proc/<pid>/cmdline. Use --no-template to pass {{ }}
+on as it is, e.g. for kubectl -o go-template.
+
+An output file is only readable by you, and is refused in directories other
+users can write to unless --insecure-output is given. Inside a git repository
+you're asked to add it to .gitignore.
+
+When stdin and stdout are terminals, the command runs on a pseudo-terminal of
+its own, so interactive commands like ssh work. Redirected or piped output
+bypasses it. --no-pty passes the terminal on directly.
+
+--clean-env starts the command from a minimal environment (PATH, HOME, USER,
+SHELL, TERM, locale and what Windows needs to start programs), so unrelated
+tokens don't leak into it. --keep-env and run_env.allow in the config, also
+per profile, pass on more variables by name or prefix, like AWS_*.
+
+With --watch, --metrics-addr serves Prometheus metrics, like decryption
+failures and the time of the last successful decryption, at /metrics.`,
+		Args: cobra.MinimumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if metricsAddr != "" && !watch {
+				return fmt.Errorf("--metrics-addr needs --watch")
+			}
+
 			// Load application config
 			appConfig, err := config.LoadConfig()
 			if err != nil {
@@ -31,13 +78,59 @@ func RunCmd() *cobra.Command {
 			}
 
 			// Parse run command arguments
-			encryptedFile, outputFile, command, commandArgs, err := run.ParseRunCommand(args)
+			var (
+				encryptedFile, outputFile, command string
+				commandArgs                        []string
+			)
+			if shellMode {
+				encryptedFile, outputFile, command, err = run.ParseShellRunCommand(args)
+			} else {
+				encryptedFile, outputFile, command, commandArgs, err = run.ParseRunCommand(args)
+			}
 			if err != nil {
 				return err
 			}
 
+			// Keep a decrypted file away from other users and out of git
+			if outputFile != "" {
+				if !insecure {
+					if err := encrypt.CheckOutputDir(outputFile); err != nil {
+						return err
+					}
+				}
+				guardPlaintextOutput(outputFile, auto)
+			}
+
+			if metricsAddr != "" {
+				stopMetrics, err := metrics.Serve(metricsAddr)
+				if err != nil {
+					return err
+				}
+				defer stopMetrics()
+			}
+
+			// The config can always clean the environment, and allow variables per profile
+			runEnv := appConfig.ActiveRunEnv()
+			if len(keepEnv) > 0 && !cleanEnv && !runEnv.Clean {
+				return fmt.Errorf("--keep-env needs --clean-env")
+			}
+
 			// Run the command with the decrypted file - pass the new parameter
-			if err := run.RunWithEncryptedFile(encryptedFile, outputFile, command, commandArgs, keyFile, appConfig.AlwaysUseOnePassword); err != nil {
+			if err := run.RunWithEncryptedFile(encryptedFile, outputFile, command, commandArgs, keyFile, appConfig.AlwaysUseOnePassword, run.Options{
+				EnvName:    envName,
+				Shell:      shellMode,
+				Watch:      watch,
+				NoPTY:      noPTY,
+				CleanEnv:   cleanEnv || runEnv.Clean,
+				KeepEnv:    append(runEnv.Allow, keepEnv...),
+				NoTemplate: noTemplate,
+			}); err != nil {
+				// The command reported its own failure, only pass on the exit code
+				var exitErr *run.ExitError
+				if errors.As(err, &exitErr) {
+					cmd.SilenceErrors = true
+					cmd.SilenceUsage = true
+				}
 				return err
 			}
 
@@ -45,16 +138,34 @@ func RunCmd() *cobra.Command {
 		},
 		Example: `  simple-sops run config.enc.yaml "kubectl apply -f config.enc.yaml"
   simple-sops run secret.enc.yaml plain.yaml "cat plain.yaml"
-  simple-sops run ~/.env.enc cat`,
+  simple-sops run ~/.env.enc cat
+  simple-sops run --env-name SECRETS_FILE secrets.enc.yaml ./deploy.sh
+  simple-sops run secrets.enc.yaml diff template.yaml {}
+  simple-sops run secrets.enc.yaml -s "yq .db.password {} | ./import.sh"
+  simple-sops run creds.enc.env ssh host
+  simple-sops run secrets.enc.yaml -- curl -H "Authorization: Bearer {{ .api.token }}" https://api.example.com
+  simple-sops run --clean-env --keep-env 'AWS_*' creds.enc.env ./deploy.sh`,
 	}
 
 	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().BoolVarP(&shellMode, "shell", "s", false, "Run the command string with $SHELL -c (allows pipes and redirections)")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Restart the command whenever the encrypted file changes")
+	cmd.Flags().StringVar(&envName, "env-name", run.DefaultEnvName, "Environment variable that receives the decrypted file path")
+	cmd.Flags().BoolVar(&auto, "auto", false, "Add the output file to .gitignore without asking")
+	cmd.Flags().BoolVar(&insecure, "insecure-output", false, "Allow an output file in a directory other users can write to")
+	cmd.Flags().BoolVar(&noPTY, "no-pty", false, "Don't run the command on a pseudo-terminal")
+	cmd.Flags().BoolVar(&cleanEnv, "clean-env", false, "Start the command from a minimal environment instead of yours")
+	cmd.Flags().StringSliceVar(&keepEnv, "keep-env", nil, "Variable to pass on with --clean-env, as NAME or PREFIX_* (repeatable)")
+	cmd.Flags().BoolVar(&noTemplate, "no-template", false, "Pass {{ }} in the arguments on unchanged instead of filling in values")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address in watch mode, e.g. :9090 for localhost")
 
 	return cmd
 }
 
 // CompletionCmd returns the completion command for generating shell completions
 func CompletionCmd() *cobra.Command {
+	var install bool
+
 	cmd := &cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
 		Short: "Generate shell completion scripts",
@@ -77,30 +188,68 @@ Fish:
 
 PowerShell:
   PS> simple-sops completion powershell | Out-String | Invoke-Expression
+
+With --install the script of bash, zsh or fish is written to the directory
+the shell loads completions from. The shell is detected from $SHELL unless
+it is named.
 `,
-		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
 		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var err error
+			var shell string
+			switch {
+			case len(args) == 1:
+				shell = args[0]
+			case install:
+				detected, err := shellinit.DetectShell()
+				if err != nil {
+					return err
+				}
+				shell = detected
+			default:
+				return fmt.Errorf("name a shell: bash, zsh, fish or powershell")
+			}
 
-			switch args[0] {
+			var script bytes.Buffer
+			var err error
+			switch shell {
 			case "bash":
-				err = cmd.Root().GenBashCompletion(os.Stdout)
+				err = cmd.Root().GenBashCompletion(&script)
 			case "zsh":
-				err = cmd.Root().GenZshCompletion(os.Stdout)
+				err = cmd.Root().GenZshCompletion(&script)
 			case "fish":
-				err = cmd.Root().GenFishCompletion(os.Stdout, true)
+				err = cmd.Root().GenFishCompletion(&script, true)
 			case "powershell":
-				err = cmd.Root().GenPowerShellCompletion(os.Stdout)
+				err = cmd.Root().GenPowerShellCompletion(&script)
 			}
 
 			if err != nil {
 				return fmt.Errorf("failed to generate completion script: %w", err)
 			}
 
+			if !install {
+				_, err := os.Stdout.Write(script.Bytes())
+				return err
+			}
+
+			path, onPath, err := shellinit.CompletionPath(shell)
+			if err != nil {
+				return err
+			}
+			if err := shellinit.InstallCompletion(path, script.Bytes()); err != nil {
+				return err
+			}
+
+			logging.Success("Installed %s completions to %s", shell, path)
+			if !onPath {
+				logging.Info("Add the directory to fpath in ~/.zshrc before compinit: fpath=(%s $fpath)", filepath.Dir(path))
+			}
+			logging.Info("Start a new shell to load them")
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&install, "install", false, "Write the script to the completion directory of the shell")
+
 	return cmd
 }
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/config"
+)
+
+// resolveScope returns the directory given with --scope relative to the repository root
+// An empty scope covers the whole repository.
+func resolveScope(scope string) (string, error) {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	return config.ResolveScope(configPath, scope)
+}
+
+// scopeDir returns the directory a scope confines a command to
+func scopeDir(root string, scope string) string {
+	return filepath.Join(root, filepath.FromSlash(scope))
+}
+
+// checkInScope fails if a file is outside the scope
+func checkInScope(files []string, scope string) error {
+	if scope == "" {
+		return nil
+	}
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	for _, file := range files {
+		if !config.InScope(config.ConfigRelativePath(configPath, file), scope) {
+			return fmt.Errorf("%s is outside of the scope %s", file, scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/shellinit"
+
+	"github.com/spf13/cobra"
+)
+
+// ShellInitCmd returns the shell-init command
+func ShellInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell-init [bash|zsh|fish]",
+		Short: "Print shell functions that manage the key lifecycle",
+		Long: `Print shell functions wrapping simple-sops, so get-key sets SOPS_AGE_KEY_FILE
+in your shell, run, decrypt, edit, put and rotate use the loaded key, and
+clear-key unsets it. A key still loaded is removed when the shell exits.
+
+To load the functions:
+
+Bash (~/.bashrc):
+  eval "$(simple-sops shell-init bash)"
+
+Zsh (~/.zshrc):
+  eval "$(simple-sops shell-init zsh)"
+
+Fish (~/.config/fish/config.fish):
+  simple-sops shell-init fish | source`,
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: shellinit.Shells(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			script, err := shellinit.Script(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(script)
+			return nil
+		},
+	}
+
+	return cmd
+}
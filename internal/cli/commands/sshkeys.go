@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// SSHKeysCmd returns the ssh-keys command
+func SSHKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh-keys",
+		Short: "List ssh-agent keys usable as Age identities",
+		Long: `List the ed25519 keys held by ssh-agent together with the Age recipient
+derived from each of them. Use a key by setting the key file to
+'ssh-agent:<comment or fingerprint>', or 'ssh-agent:' for the first key.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, err := keymgmt.ListAgentKeys()
+			if err != nil {
+				return err
+			}
+
+			found := false
+			for _, key := range keys {
+				if key.Type != "ssh-ed25519" {
+					logging.Debug("Skipping %s key %s", key.Type, key.Comment)
+					continue
+				}
+
+				_, recipient, err := keymgmt.DeriveAgeKeyFromAgent(key)
+				if err != nil {
+					logging.Error("Failed to derive Age key from %s: %v", key.Comment, err)
+					continue
+				}
+
+				fmt.Printf("%s\t%s\t%s\n", recipient, key.Fingerprint(), key.Comment)
+				found = true
+			}
+
+			if !found {
+				logging.Info("No ed25519 keys found in ssh-agent. Add one with 'ssh-add'.")
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
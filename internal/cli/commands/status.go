@@ -0,0 +1,436 @@
+package commands
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/certs"
+	"simple-sops/internal/config"
+	"simple-sops/internal/deps"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/git"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/version"
+	"simple-sops/pkg/logging"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// fileStatus describes the rotation state of an encrypted file
+type fileStatus struct {
+	Path        string
+	Metadata    *config.FileMetadata
+	LastRotated time.Time
+	Overdue     bool
+}
+
+// getRepoRoot returns the directory holding the SOPS config
+func getRepoRoot() (string, error) {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+
+	return config.RepoRoot(configPath), nil
+}
+
+// collectFileStatus reads the metadata of all encrypted files below dir in the repository at root
+// and checks their last rotation against the rotation policy
+func collectFileStatus(root string, dir string, policy config.RotationPolicy) ([]fileStatus, error) {
+	files, err := config.FindEncryptedFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find encrypted files: %w", err)
+	}
+
+	rotations, err := config.LoadRotations(root)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var statuses []fileStatus
+	for _, file := range files {
+		metadata, err := config.ReadFileMetadata(file)
+		if err != nil {
+			logging.Debug("Skipping %s: %v", file, err)
+			continue
+		}
+
+		relPath, err := filepath.Rel(root, file)
+		if err != nil {
+			relPath = file
+		}
+		lastRotated := rotations.LastRotated(filepath.ToSlash(relPath), metadata)
+		overdue, err := policy.IsOverdue(lastRotated, now)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, fileStatus{Path: file, Metadata: metadata, LastRotated: lastRotated, Overdue: overdue})
+	}
+
+	return statuses, nil
+}
+
+// StatusCmd returns the status command
+func StatusCmd() *cobra.Command {
+	var (
+		expiringWithin string
+		showCerts      bool
+		keyFile        string
+		scope          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show encrypted files and their rotation state",
+		Long: `List the encrypted files in the repository with their last rotation date and
+warn about files that exceed the rotation policy, and values that expired or
+expire soon. With --certs the files are decrypted and the subject, issuer and
+expiry of PEM certificates in them are shown. With --scope only the files
+below a directory are listed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			policy := appConfig.ActiveRotationPolicy()
+
+			root, err := getRepoRoot()
+			if err != nil {
+				return err
+			}
+			if scope, err = resolveScope(scope); err != nil {
+				return err
+			}
+			dir := scopeDir(root, scope)
+
+			statuses, err := collectFileStatus(root, dir, policy)
+			if err != nil {
+				return err
+			}
+
+			warnBefore, err := config.ParseMaxAge(expiringWithin)
+			if err != nil {
+				return fmt.Errorf("invalid --expiring-within: %w", err)
+			}
+			expiries, err := config.LoadExpiries(root)
+			if err != nil {
+				return err
+			}
+			expiring := expiries.Expiring(time.Now(), warnBefore)
+
+			if len(statuses) == 0 {
+				logging.Info("No encrypted files found in %s.", dir)
+				return nil
+			}
+
+			// Show recipients by their label and fingerprint
+			labels, err := keymgmt.LoadKeyLabels(root)
+			if err != nil {
+				return err
+			}
+
+			var certificates map[string][]certs.Certificate
+			if showCerts {
+				if keyFile == "" {
+					keyFile = appConfig.KeyFile
+				}
+				if certificates, err = findCertificates(statuses, keyFile, appConfig.AlwaysUseOnePassword); err != nil {
+					return err
+				}
+			}
+
+			logging.Info("Encrypted files in %s:", dir)
+			overdueCount := 0
+			for _, status := range statuses {
+				relPath, err := filepath.Rel(root, status.Path)
+				if err != nil {
+					relPath = status.Path
+				}
+
+				line := fmt.Sprintf("  %s", relPath)
+				if !status.LastRotated.IsZero() {
+					days := int(time.Since(status.LastRotated).Hours() / 24)
+					line += fmt.Sprintf("  (last rotated %s, %d days ago)", status.LastRotated.Format("2006-01-02"), days)
+				}
+				if status.Overdue {
+					line += "  OVERDUE"
+					overdueCount++
+					logging.Record(relPath, "status", logging.ResultOverdue)
+				} else {
+					logging.Record(relPath, "status", logging.ResultOK)
+				}
+				logging.Info("%s", line)
+				if len(status.Metadata.Recipients) > 0 {
+					logging.Info("    recipients: %s", labels.DescribeAll(status.Metadata.Recipients))
+				}
+				for _, secret := range expiries.ForFile(relPath) {
+					logging.Info("    %s", describeExpiry(secret, expiring))
+				}
+				for _, certificate := range certificates[status.Path] {
+					logging.Info("    %s", describeCertificate(certificate, warnBefore))
+				}
+			}
+
+			if overdueCount > 0 {
+				logging.Warn("%d file(s) exceed the rotation policy (max age %s). Run 'simple-sops rotate --due' to rotate them.", overdueCount, policy.MaxAge)
+			}
+
+			if overdue, err := policy.KeyOverdue(time.Now()); err == nil && overdue {
+				logging.Warn("Your key was last rotated on %s and exceeds the rotation policy (max age %s).", policy.LastRotated.Format("2006-01-02"), policy.MaxAge)
+			}
+
+			for _, secret := range expiring {
+				if !config.InScope(secret.File, scope) {
+					continue
+				}
+				if secret.Expired {
+					logging.Warn("%s in %s expired on %s.", secret.Path, secret.File, secret.Expires)
+				} else {
+					logging.Warn("%s in %s expires on %s.", secret.Path, secret.File, secret.Expires)
+				}
+			}
+
+			expiringCerts := 0
+			for _, fileCertificates := range certificates {
+				for _, certificate := range fileCertificates {
+					if time.Until(certificate.NotAfter) <= warnBefore {
+						expiringCerts++
+					}
+				}
+			}
+			if expiringCerts > 0 {
+				logging.Warn("%d certificate(s) expired or expire within %s.", expiringCerts, expiringWithin)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&expiringWithin, "expiring-within", config.DefaultExpiryWarning, "Warn about values expiring within this time, e.g. 30d")
+	cmd.Flags().BoolVar(&showCerts, "certs", false, "Decrypt the files and show the certificates in them")
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use with --certs (defaults to config setting)")
+	cmd.Flags().StringVar(&scope, "scope", "", "Only show the files below this directory")
+
+	return cmd
+}
+
+// findCertificates decrypts the files and returns the certificates in them by file
+// Files that can't be decrypted are skipped with a warning.
+func findCertificates(statuses []fileStatus, keyFile string, alwaysUseOnePassword bool) (map[string][]certs.Certificate, error) {
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	certificates := make(map[string][]certs.Certificate)
+	for _, status := range statuses {
+		plaintext, err := encrypt.DecryptToBytes(status.Path, keyPath)
+		if err != nil {
+			logging.Warn("Skipping the certificates of %s: %v", status.Path, err)
+			continue
+		}
+		certificates[status.Path] = certs.Find(plaintext)
+		keymgmt.Wipe(plaintext)
+	}
+	return certificates, nil
+}
+
+// describeCertificate formats a certificate for the status output
+func describeCertificate(certificate certs.Certificate, warnBefore time.Duration) string {
+	line := "certificate:"
+	if certificate.Path != "" {
+		line += " " + certificate.Path
+	}
+	line += fmt.Sprintf(" %s, issued by %s, expires %s", certificate.Subject, certificate.Issuer, certificate.NotAfter.Format("2006-01-02"))
+
+	switch remaining := time.Until(certificate.NotAfter); {
+	case remaining <= 0:
+		line += "  EXPIRED"
+	case remaining <= warnBefore:
+		line += "  EXPIRES SOON"
+	}
+	return line
+}
+
+// describeExpiry formats the expiry date of a value for the status output
+func describeExpiry(secret config.SecretExpiry, expiring []config.ExpiringSecret) string {
+	line := fmt.Sprintf("expires: %s on %s", secret.Path, secret.Expires)
+	if secret.Note != "" {
+		line += fmt.Sprintf(" (%s)", secret.Note)
+	}
+	for _, candidate := range expiring {
+		if candidate.File == secret.File && candidate.Path == secret.Path {
+			if candidate.Expired {
+				return line + "  EXPIRED"
+			}
+			return line + "  EXPIRES SOON"
+		}
+	}
+	return line
+}
+
+// DoctorCmd returns the doctor command
+func DoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for common problems",
+		Long:  `Check that required tools are installed, a key is available, keys and files follow the rotation policy, no values expired and no decrypted files are tracked by git.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := 0
+
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				logging.Info("[fail] Config: %v", err)
+				return fmt.Errorf("doctor found problems")
+			}
+			logging.Info("[ok]   Config loaded")
+
+			// Check required and optional tools
+			for _, tool := range []deps.Tool{deps.Sops, deps.Age} {
+				if len(deps.Missing(tool)) > 0 {
+					logging.Info("[fail] %s not found in PATH, install it with: %s", tool.Binary, deps.Hint(tool))
+					problems++
+				} else {
+					logging.Info("[ok]   %s found", tool.Binary)
+				}
+			}
+			if appConfig.OnePasswordEnabled {
+				if _, err := exec.LookPath("op"); err != nil {
+					logging.Info("[warn] op (1Password CLI) not found in PATH")
+				} else {
+					logging.Info("[ok]   op found")
+				}
+			}
+
+			// Check key availability
+			if _, err := keymgmt.LoadAgeKey(appConfig.KeyFile); err != nil {
+				if appConfig.OnePasswordEnabled {
+					logging.Info("[warn] Key file: %v (1Password will be used)", err)
+				} else {
+					logging.Info("[fail] Key file: %v", err)
+					problems++
+				}
+			} else {
+				logging.Info("[ok]   Key file %s", appConfig.KeyFile)
+			}
+
+			// Check rotation policy
+			policy := appConfig.ActiveRotationPolicy()
+			if overdue, err := policy.KeyOverdue(time.Now()); err != nil {
+				logging.Info("[fail] Rotation policy: %v", err)
+				problems++
+			} else if overdue {
+				logging.Info("[warn] Key last rotated %s exceeds max age %s", policy.LastRotated.Format("2006-01-02"), policy.MaxAge)
+			}
+
+			if root, err := getRepoRoot(); err == nil {
+				if statuses, err := collectFileStatus(root, root, policy); err == nil {
+					overdueCount := 0
+					for _, status := range statuses {
+						if status.Overdue {
+							overdueCount++
+						}
+					}
+					if overdueCount > 0 {
+						logging.Info("[warn] %d encrypted file(s) exceed the rotation policy", overdueCount)
+					}
+				}
+
+				if expiries, err := config.LoadExpiries(root); err != nil {
+					logging.Info("[fail] Expiry dates: %v", err)
+					problems++
+				} else if warnBefore, err := config.ParseMaxAge(config.DefaultExpiryWarning); err == nil {
+					if expiring := expiries.Expiring(time.Now(), warnBefore); len(expiring) > 0 {
+						logging.Info("[warn] %d value(s) expired or expire within %s (run 'simple-sops status')", len(expiring), config.DefaultExpiryWarning)
+					}
+				}
+
+				if pin, enabled, err := config.LoadVersionPin(root); err != nil {
+					logging.Info("[fail] Pinned versions: %v", err)
+					problems++
+				} else if reported, found := version.ToolVersion(deps.Sops.Binary); enabled && found {
+					if err := pin.CheckSops(reported); err != nil && pin.Fail {
+						logging.Info("[fail] %v", err)
+						problems++
+					} else if err != nil {
+						logging.Info("[warn] %v", err)
+					} else {
+						logging.Info("[ok]   sops version matches %s", pin.SopsVersion)
+					}
+				}
+
+				problems += checkTrackedPlaintext(root)
+				checkRuleConflicts(root)
+			}
+
+			if problems > 0 {
+				return fmt.Errorf("doctor found %d problem(s)", problems)
+			}
+
+			logging.Success("No problems found.")
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// checkRuleConflicts warns about rules in .sops.yaml that never apply
+func checkRuleConflicts(root string) {
+	sopsConfig, err := config.LoadSopsConfig(filepath.Join(root, ".sops.yaml"))
+	if err != nil {
+		return
+	}
+
+	if conflicts := config.FindConflicts(sopsConfig); len(conflicts) > 0 {
+		logging.Info("[warn] %d rule(s) in .sops.yaml are shadowed by earlier rules, run 'simple-sops config reorder'", len(conflicts))
+	}
+}
+
+// checkTrackedPlaintext reports decrypted files that are tracked by git
+// These are outputs listed in .gitignore that were committed anyway, and
+// files covered by a .sops.yaml rule that are decrypted in the working tree.
+// Returns the number of problems found.
+func checkTrackedPlaintext(root string) int {
+	if _, ok := git.RepoRoot(root); !ok {
+		return 0
+	}
+
+	problems := 0
+	if entries, err := git.DecryptedEntries(root); err == nil {
+		for _, entry := range entries {
+			if git.IsTracked(root, entry) {
+				logging.Info("[fail] Decrypted file %s is tracked by git (run 'git rm --cached %s')", entry, entry)
+				problems++
+			}
+		}
+	}
+
+	configPath := filepath.Join(root, ".sops.yaml")
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		return problems
+	}
+	tracked, err := git.TrackedFiles(root)
+	if err != nil {
+		return problems
+	}
+	ignore, _ := config.LoadIgnoreFile(root)
+	violations, err := git.Verify(sopsConfig, root, ignore, tracked, git.WorkTreeReader(root))
+	if err == nil && len(violations) > 0 {
+		logging.Info("[warn] %d tracked file(s) covered by .sops.yaml are decrypted (run 'simple-sops verify')", len(violations))
+	}
+
+	return problems
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/metrics"
+	"simple-sops/internal/secretsync"
+	"simple-sops/pkg/logging"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// SyncCmd returns the sync command
+func SyncCmd() *cobra.Command {
+	var (
+		keyFile     string
+		dir         string
+		interval    time.Duration
+		mode        string
+		once        bool
+		metricsAddr string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync <file...>",
+		Short: "Keep decrypted copies of files in a directory, as a sidecar",
+		Long: `Decrypt files into a directory, e.g. a tmpfs volume shared with an app that
+can't read secrets from a secret store, and keep them up to date. The files
+are checked every --interval and swapped all at once when any of them changed:
+they are links through ..data to the current version, like the files of a
+mounted Kubernetes Secret. If a file fails to decrypt, the current files are
+kept. The copies are named after the encrypted files without the infix of the
+naming convention, e.g. app.enc.yaml becomes app.yaml.
+
+sync runs until it is stopped with SIGINT or SIGTERM. With --once it syncs the
+files and exits, e.g. in an initContainer. SIGHUP reloads the config and
+fetches the key again without stopping; if that fails, the current ones are
+kept. --metrics-addr serves Prometheus metrics, like the time of the last
+successful sync, at /metrics.`,
+		Example: `  simple-sops sync secrets/app.enc.yaml secrets/db.enc.env --dir /secrets --interval 1m
+  simple-sops sync secrets/app.enc.yaml --dir /secrets --once
+  simple-sops sync secrets/app.enc.yaml --dir /secrets --metrics-addr :9090`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			fileMode, err := strconv.ParseUint(mode, 8, 32)
+			if err != nil || fileMode&^0777 != 0 || fileMode&0400 == 0 {
+				return fmt.Errorf("invalid --mode %s, expected octal permissions readable by the owner like 0600 or 0640", mode)
+			}
+
+			if metricsAddr != "" && once {
+				return fmt.Errorf("--metrics-addr can't be combined with --once")
+			}
+
+			// Serve metrics before the key is fetched, so failures show up in them
+			if metricsAddr != "" {
+				stopMetrics, err := metrics.Serve(metricsAddr)
+				if err != nil {
+					return err
+				}
+				defer stopMetrics()
+			}
+
+			appConfig, keyPath, isTemp, err := loadSyncConfig(keyFile)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if isTemp {
+					keymgmt.CleanupTempAgeKeyFile(keyPath)
+				}
+			}()
+
+			syncer, err := secretsync.NewSyncer(dir, args, keyPath, os.FileMode(fileMode), appConfig.Naming)
+			if err != nil {
+				return err
+			}
+
+			if once {
+				if _, err := syncer.Sync(); err != nil {
+					return err
+				}
+				logging.Success("Synced %d file(s) into %s", len(args), dir)
+				return nil
+			}
+
+			// Reload the config and the key on SIGHUP, keeping the old ones on failure
+			reload := func() error {
+				appConfig, newKeyPath, newIsTemp, err := loadSyncConfig(keyFile)
+				if err != nil {
+					return err
+				}
+				if err := syncer.Reconfigure(newKeyPath, appConfig.Naming); err != nil {
+					if newIsTemp {
+						keymgmt.CleanupTempAgeKeyFile(newKeyPath)
+					}
+					return err
+				}
+				if isTemp {
+					keymgmt.CleanupTempAgeKeyFile(keyPath)
+				}
+				keyPath, isTemp = newKeyPath, newIsTemp
+				return nil
+			}
+
+			// Run returns on exit signals, so the deferred cleanup runs
+			defer keymgmt.SuspendExitSignals()()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+			defer signal.Stop(signals)
+			return syncer.Run(interval, signals, reload)
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory to keep the decrypted files in, e.g. a tmpfs volume")
+	cmd.Flags().DurationVar(&interval, "interval", time.Minute, "How often to check the files for changes")
+	cmd.Flags().StringVar(&mode, "mode", "0600", "Permissions of the decrypted files, e.g. 0640 for an app in the same group")
+	cmd.Flags().BoolVar(&once, "once", false, "Sync the files once and exit")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9090 for localhost")
+	_ = cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
+
+// loadSyncConfig loads the application config and fetches the key for sync
+// The key file of the flag wins over the one of the config.
+func loadSyncConfig(keyFile string) (*config.AppConfig, string, bool, error) {
+	appConfig, err := config.LoadConfig()
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to load config: %w", err)
+	}
+	if keyFile == "" {
+		keyFile = appConfig.KeyFile
+	}
+
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, appConfig.AlwaysUseOnePassword)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return appConfig, keyPath, isTemp, nil
+}
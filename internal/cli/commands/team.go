@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// TeamCmd returns the team command
+func TeamCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "team",
+		Short: "Maintain the team key registry",
+		Long: `Maintain the team key registry in .simple-sops/keys.yaml. sync keeps a group
+of the registry in line with the members of a GitHub team or GitLab group.`,
+	}
+
+	cmd.AddCommand(teamSyncCmd())
+
+	return cmd
+}
+
+// teamSyncCmd returns the team sync command
+func teamSyncCmd() *cobra.Command {
+	var (
+		githubTeam  string
+		gitlabGroup string
+		group       string
+		keyFile     string
+		dryRun      bool
+		yes         bool
+		rotate      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync --github-team <org/team> | --gitlab-group <group>",
+		Short: "Update a group of the registry from a GitHub team or GitLab group",
+		Long: `Pull the members of a GitHub team or GitLab group and their ed25519 SSH keys,
+converted to Age recipients, into a group of .simple-sops/keys.yaml named after
+the team or group, or the one given with --group. Members who left are removed
+from the group, and from the registry unless they are in another group.
+
+Listing GitHub team members needs a token with read:org in GITHUB_TOKEN or
+GH_TOKEN. Listing GitLab group members needs a token with read_api in
+GITLAB_TOKEN, and self-hosted instances are set with GITLAB_URL. Only direct
+members of a GitLab group are synced.
+
+Rules of .sops.yaml encrypting to the group get the keys of new members, and
+keys no member owns anymore are removed from every rule. Afterwards the files
+encrypted to the group can be rotated to the new recipients right away, which
+is asked for unless --rotate is given.`,
+		Example: `  simple-sops team sync --github-team acme/infra --dry-run
+  simple-sops team sync --github-team acme/infra --group backend --rotate
+  simple-sops team sync --gitlab-group acme/platform/infra`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			encrypt.SetDryRun(dryRun)
+			encrypt.SetConfirmConfigChanges(!yes)
+
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+
+			source := teamSource{name: githubTeam, members: keymgmt.FetchGitHubTeamMembers, keys: keymgmt.FetchGitHubRecipients}
+			if gitlabGroup != "" {
+				source = teamSource{name: gitlabGroup, members: keymgmt.FetchGitLabGroupMembers, keys: keymgmt.FetchGitLabRecipients}
+			}
+			if group == "" {
+				group = path.Base(strings.Trim(source.name, "/"))
+			}
+
+			team, err := config.LoadTeam(root)
+			if err != nil {
+				return err
+			}
+			previous, err := team.Resolve([]string{group})
+			if err != nil {
+				previous = nil
+			}
+
+			members, err := fetchTeamKeys(team, source)
+			if err != nil {
+				return err
+			}
+
+			sync := team.SyncGroup(group, members)
+			if sync.IsEmpty() {
+				logging.Success("Group %s is in sync with %s.", group, source.name)
+				return nil
+			}
+			for _, name := range sync.Added {
+				logging.Info("+ %s joined %s", name, group)
+			}
+			for _, name := range sync.Removed {
+				logging.Info("- %s left %s", name, group)
+			}
+
+			var changed []string
+			update := func(sopsConfig *config.SopsConfig) error {
+				changed, err = config.SyncGroupRecipients(sopsConfig, previous, sync)
+				return err
+			}
+
+			rules := config.NewRuleSet(configPath)
+			if dryRun {
+				encrypt.PrintDryRun("Would update %s: %d key(s) added, %d removed", config.TeamFileName, len(sync.AddedKeys), len(sync.RemovedKeys))
+				sopsConfig, err := rules.Preview(update)
+				if err != nil {
+					return err
+				}
+				return encrypt.PrintConfigPreview(configPath, sopsConfig)
+			}
+
+			if err := encrypt.ConfirmConfigChange(configPath, update); err != nil {
+				return err
+			}
+			// Update the rules first, a failure there leaves the registry unchanged
+			if _, err := rules.Update(update); err != nil {
+				return fmt.Errorf("failed to update SOPS config: %w", err)
+			}
+			if err := config.SaveTeam(root, team); err != nil {
+				return fmt.Errorf("updated %s, but not %s: %w", configPath, config.TeamFileName, err)
+			}
+			logging.Info("Updated %s and %d rule(s) in %s", config.TeamFileName, len(changed), configPath)
+
+			return rotateGroupFiles(root, previous, sync, keyFile, appConfig.AlwaysUseOnePassword, rotate)
+		},
+	}
+
+	cmd.Flags().StringVar(&githubTeam, "github-team", "", "GitHub team to sync from, as org/team")
+	cmd.Flags().StringVar(&gitlabGroup, "gitlab-group", "", "GitLab group to sync from, by its full path")
+	cmd.Flags().StringVar(&group, "group", "", "Group of the registry to update (defaults to the team name)")
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use for rotating (defaults to config setting)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the planned changes without making them")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Save changes to .sops.yaml without showing them first")
+	cmd.Flags().BoolVar(&rotate, "rotate", false, "Rotate the affected files without asking")
+	cmd.MarkFlagsOneRequired("github-team", "gitlab-group")
+	cmd.MarkFlagsMutuallyExclusive("github-team", "gitlab-group")
+
+	return cmd
+}
+
+// teamSource is a GitHub team or GitLab group to sync a group of the registry from
+type teamSource struct {
+	name    string
+	members func(name string) ([]string, error)
+	keys    func(user string) ([]keymgmt.GitHubRecipient, error)
+}
+
+// fetchTeamKeys returns the Age recipients of the members of a team by login
+// Members whose keys can't be fetched keep the keys they have in the registry,
+// so a failed request doesn't remove anyone. New members without keys are skipped.
+func fetchTeamKeys(team *config.Team, source teamSource) (map[string][]string, error) {
+	logins, err := source.members(source.name)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]string)
+	for _, login := range logins {
+		recipients, err := source.keys(login)
+		if err != nil {
+			if member, registered := team.Member(login); registered {
+				logging.Warn("Keeping the registered keys of %s: %v", login, err)
+				members[login] = member.Keys
+			} else {
+				logging.Warn("Skipping %s: %v", login, err)
+			}
+			continue
+		}
+		for _, recipient := range recipients {
+			members[login] = append(members[login], recipient.Recipient)
+		}
+	}
+	return members, nil
+}
+
+// recipientChange is the recipients a file loses and gains
+type recipientChange struct {
+	remove []string
+	add    []string
+}
+
+// rotateGroupFiles rotates the files encrypted to a group to its new keys
+// The files are those with a key the group had before the sync, and only the
+// recipients they have or lack are changed. Rotation is asked for unless
+// rotate is set.
+func rotateGroupFiles(root string, previous []string, sync config.GroupSync, keyFile string, alwaysUseOnePassword bool, rotate bool) error {
+	files, err := config.FindEncryptedFiles(root)
+	if err != nil {
+		return fmt.Errorf("failed to find encrypted files: %w", err)
+	}
+	var affected []string
+	changes := make(map[string]recipientChange)
+	for _, file := range files {
+		metadata, err := config.ReadFileMetadata(file)
+		if err != nil {
+			logging.Warn("Skipping %s: %v", file, err)
+			continue
+		}
+		if !slices.ContainsFunc(metadata.Recipients, func(recipient string) bool { return slices.Contains(previous, recipient) }) {
+			continue
+		}
+
+		var change recipientChange
+		for _, recipient := range sync.RemovedKeys {
+			if slices.Contains(metadata.Recipients, recipient) {
+				change.remove = append(change.remove, recipient)
+			}
+		}
+		for _, recipient := range sync.AddedKeys {
+			if !slices.Contains(metadata.Recipients, recipient) {
+				change.add = append(change.add, recipient)
+			}
+		}
+		if len(change.remove) > 0 || len(change.add) > 0 {
+			affected = append(affected, file)
+			changes[file] = change
+		}
+	}
+
+	if len(affected) == 0 {
+		logging.Success("No encrypted files need the new recipients.")
+		return nil
+	}
+	if !rotate && !logging.Confirm(fmt.Sprintf("Rotate the %d file(s) encrypted to the group now?", len(affected))) {
+		logging.Info("Skipped rotating, 'simple-sops drift' lists the files that still differ from their rules.")
+		return nil
+	}
+
+	// Changing the recipients of a file needs its current key
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	var failed []string
+	for _, file := range affected {
+		if encrypt.FailFast() && len(failed) > 0 {
+			break
+		}
+
+		change := changes[file]
+		if err := encrypt.RotateRecipients(file, keyPath, change.remove, change.add, nil); err != nil {
+			logging.Error("Failed to rotate %s: %v", file, err)
+			failed = append(failed, file)
+		}
+	}
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("%d of %d files could not be rotated: %s", len(failed), len(affected), strings.Join(failed, ", "))
+		// The registry and rules changed already, so any failure leaves the files partly updated
+		return exitcode.Wrap(exitcode.Partial, err)
+	}
+	logging.Success("Rotated %d files. Change the values removed members could read, too.", len(affected))
+	return nil
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// TrustCmd returns the trust command
+func TrustCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Manage the recipient trust store",
+		Long: `Manage the local list of known public keys and who they belong to.
+Encrypting to a recipient that is not in the trust store prints a warning,
+or fails when 'encrypt --strict' is used.`,
+	}
+
+	cmd.AddCommand(trustAddCmd())
+	cmd.AddCommand(trustRemoveCmd())
+	cmd.AddCommand(trustListCmd())
+
+	return cmd
+}
+
+// trustAddCmd returns the trust add command
+func trustAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [public-key] [label]",
+		Short: "Add a public key to the trust store",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+			if err != nil {
+				return err
+			}
+
+			store.Add(args[0], args[1])
+
+			if err := keymgmt.SaveTrustStore(keymgmt.DefaultTrustStoreFile, store); err != nil {
+				return err
+			}
+
+			logging.Success("Trusted %s as %s", args[0], args[1])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// trustRemoveCmd returns the trust rm command
+func trustRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm [public-key|label|fingerprint...]",
+		Short: "Remove public keys from the trust store",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+			if err != nil {
+				return err
+			}
+
+			for _, ref := range args {
+				key, err := store.Resolve(ref)
+				if err != nil {
+					return err
+				}
+				description := store.Describe(key.PublicKey)
+				if err := store.Remove(key.PublicKey); err != nil {
+					return err
+				}
+				logging.Success("Removed %s from the trust store", description)
+			}
+
+			return keymgmt.SaveTrustStore(keymgmt.DefaultTrustStoreFile, store)
+		},
+	}
+
+	return cmd
+}
+
+// trustListCmd returns the trust list command
+func trustListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List trusted public keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := keymgmt.LoadTrustStore(keymgmt.DefaultTrustStoreFile)
+			if err != nil {
+				return err
+			}
+
+			if len(store.Keys) == 0 {
+				logging.Info("The trust store is empty. Add keys with 'simple-sops trust add'.")
+				return nil
+			}
+
+			for _, key := range store.Keys {
+				fmt.Printf("%s\t%s\n", key.PublicKey, key.Label)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
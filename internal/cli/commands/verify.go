@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/git"
+	"simple-sops/pkg/logging"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// VerifyCmd returns the verify command
+func VerifyCmd() *cobra.Command {
+	var (
+		staged bool
+		scope  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify [file...]",
+		Short: "Check that files covered by .sops.yaml rules are encrypted",
+		Long: `Check that files covered by a .sops.yaml rule are encrypted.
+Without arguments, all files below the repository root are checked, except
+for paths listed in .sopsignore. The catch-all rule doesn't count as covering
+a file. With --staged, the staged content of staged files is checked, which
+is what the pre-commit hook does.
+
+With --scope only the files below a directory are checked, and only the rules
+written for files or the catch-all rule of that directory, so each service of
+a monorepo can verify its own secrets.
+
+If the repository has a team registry in .simple-sops/keys.yaml, rules with
+recipients no team member owns are reported too, e.g. keys of people who left,
+and so are encrypted files that members marked as offboarded or past their
+expiry date can still decrypt. The latter isn't checked with --staged.
+Once an allowed signers file exists, a .sops.yaml changed without a valid
+signature is reported as well. Rules that violate the recipient policy of
+.simple-sops/policy.yaml or the config, e.g. by missing the backup key, fail
+the check, and so do encrypted files missing a recovery recipient of the
+policy.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, err := config.GetSopsConfigPath()
+			if err != nil {
+				return fmt.Errorf("failed to determine SOPS config path: %w", err)
+			}
+			root := config.RepoRoot(configPath)
+			if scope, err = config.ResolveScope(configPath, scope); err != nil {
+				return err
+			}
+
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			sopsConfig, err := config.LoadSopsConfig(configPath)
+			if err != nil {
+				return fmt.Errorf("failed to load SOPS config: %w", err)
+			}
+			warnUnsignedConfig(configPath)
+
+			ignore, err := config.LoadIgnoreFile(root)
+			if err != nil {
+				return err
+			}
+
+			var paths []string
+			read := git.WorkTreeReader(root)
+			switch {
+			case staged:
+				if paths, err = git.StagedFiles(root); err != nil {
+					return err
+				}
+				read = git.StagedReader(root)
+			case len(args) > 0:
+				if err := checkInScope(args, scope); err != nil {
+					return err
+				}
+				for _, arg := range args {
+					paths = append(paths, config.ConfigRelativePath(configPath, arg))
+				}
+			default:
+				dir := scopeDir(root, scope)
+				err := config.WalkFiles(dir, ignore, func(path string) error {
+					paths = append(paths, config.ConfigRelativePath(configPath, path))
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("failed to search %s: %w", dir, err)
+				}
+			}
+			if staged && scope != "" {
+				paths = slices.DeleteFunc(paths, func(path string) bool { return !config.InScope(path, scope) })
+			}
+
+			violations, err := git.Verify(sopsConfig, root, ignore, paths, read)
+			if err != nil {
+				return err
+			}
+
+			policy, err := config.LoadRecipientPolicy(root, appConfig.RecipientPolicy)
+			if err != nil {
+				return err
+			}
+
+			team, err := config.LoadTeam(root)
+			if err != nil {
+				return err
+			}
+			var (
+				unregistered []config.UnregisteredRecipients
+				revoked      []config.RevokedRecipients
+			)
+			if !team.IsEmpty() {
+				unregistered = team.FindUnregistered(sopsConfig, policy.RecoveryRecipients)
+				unregistered = slices.DeleteFunc(unregistered, func(rule config.UnregisteredRecipients) bool {
+					return !config.RuleInScope(rule.PathRegex, scope)
+				})
+				// A commit shouldn't fail because someone left, so the hook skips this
+				if !staged {
+					revoked = findRevoked(team, root, paths)
+				}
+			}
+
+			policyViolations := slices.DeleteFunc(config.FindPolicyViolations(sopsConfig, policy), func(violation config.PolicyViolation) bool {
+				return !config.RuleInScope(violation.PathRegex, scope)
+			})
+
+			missingRecovery := findMissingRecovery(policy, paths, read)
+
+			recordVerified(paths, violations, len(unregistered) > 0 || len(policyViolations) > 0, revoked, missingRecovery)
+			return reportViolations(cmd, violations, unregistered, policyViolations, revoked, missingRecovery)
+		},
+	}
+
+	cmd.Flags().BoolVar(&staged, "staged", false, "Check the staged content of staged files")
+	cmd.Flags().StringVar(&scope, "scope", "", "Only check the files and rules below this directory")
+
+	return cmd
+}
+
+// reportViolations prints the files that should be encrypted, the rules with
+// recipients missing from the team registry, the rules violating the
+// recipient policy, the files revoked recipients can decrypt and the files
+// missing recovery recipients, and fails if there are any
+func reportViolations(cmd *cobra.Command, violations []git.Violation, unregistered []config.UnregisteredRecipients, policyViolations []config.PolicyViolation, revoked []config.RevokedRecipients, missingRecovery []config.MissingRecovery) error {
+	for _, violation := range violations {
+		if violation.Commit != "" {
+			logging.Error("%s is not encrypted in commit %s (rule %s)", violation.Path, shortCommit(violation.Commit), violation.PathRegex)
+		} else {
+			logging.Error("%s is not encrypted (rule %s)", violation.Path, violation.PathRegex)
+		}
+	}
+
+	for _, rule := range unregistered {
+		for _, recipient := range rule.Recipients {
+			logging.Error("Rule %s encrypts to %s, which is not in %s", rule.PathRegex, recipient, config.TeamFileName)
+		}
+	}
+
+	for _, violation := range policyViolations {
+		for _, problem := range violation.Problems {
+			logging.Error("Rule %s violates the recipient policy: %s", violation.PathRegex, problem)
+		}
+	}
+
+	reportRevoked(revoked)
+
+	for _, file := range missingRecovery {
+		logging.Error("%s is not encrypted to the recovery recipient(s) %s", file.Path, strings.Join(file.Recipients, ", "))
+	}
+	if len(missingRecovery) > 0 {
+		logging.Info("Run 'simple-sops add-recipient --to <recovery recipient> <file>' to add them.")
+	}
+
+	if len(violations) > 0 {
+		// The files are listed above, the usage doesn't help
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) covered by .sops.yaml are not encrypted", len(violations)))
+	}
+	if len(unregistered) > 0 {
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d rule(s) encrypt to recipients missing from %s", len(unregistered), config.TeamFileName))
+	}
+	if len(policyViolations) > 0 {
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d rule(s) violate the recipient policy", len(policyViolations)))
+	}
+	if len(revoked) > 0 {
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) can still be decrypted by revoked recipients", len(revoked)))
+	}
+	if len(missingRecovery) > 0 {
+		cmd.SilenceUsage = true
+		return exitcode.Wrap(exitcode.Verification, fmt.Errorf("%d file(s) are missing recovery recipients", len(missingRecovery)))
+	}
+
+	logging.Success("All files covered by .sops.yaml are encrypted.")
+	return nil
+}
+
+// recordVerified prints a porcelain record for every checked file
+// Problems of rules are recorded for .sops.yaml, since they aren't about one file.
+func recordVerified(paths []string, violations []git.Violation, rulesFailed bool, revoked []config.RevokedRecipients, missingRecovery []config.MissingRecovery) {
+	failed := make(map[string]bool)
+	for _, violation := range violations {
+		failed[violation.Path] = true
+	}
+	for _, file := range revoked {
+		failed[file.Path] = true
+	}
+	for _, file := range missingRecovery {
+		failed[file.Path] = true
+	}
+
+	if rulesFailed {
+		logging.Record(".sops.yaml", "verify", logging.ResultFailed)
+	}
+	for _, path := range paths {
+		if failed[path] {
+			logging.Record(path, "verify", logging.ResultFailed)
+		} else {
+			logging.Record(path, "verify", logging.ResultOK)
+		}
+	}
+}
+
+// findRevoked returns the encrypted files among paths that offboarded or expired members can decrypt
+// The paths are relative to root, files that aren't encrypted are skipped.
+func findRevoked(team *config.Team, root string, paths []string) []config.RevokedRecipients {
+	now := time.Now()
+	var revoked []config.RevokedRecipients
+	for _, path := range paths {
+		file := filepath.Join(root, filepath.FromSlash(path))
+		if !config.IsFileEncrypted(file) {
+			continue
+		}
+		metadata, err := config.ReadFileMetadata(file)
+		if err != nil {
+			logging.Debug("Skipping %s: %v", path, err)
+			continue
+		}
+		if found, ok := team.FindRevoked(path, metadata.Recipients, now); ok {
+			revoked = append(revoked, found)
+		}
+	}
+	return revoked
+}
+
+// findMissingRecovery returns the encrypted files among paths the recovery recipients can't decrypt
+// The files are read with read, so the staged content is checked by the hook.
+// Files that aren't encrypted are skipped.
+func findMissingRecovery(policy config.RecipientPolicy, paths []string, read git.FileReader) []config.MissingRecovery {
+	if len(policy.RecoveryRecipients) == 0 {
+		return nil
+	}
+	var missing []config.MissingRecovery
+	for _, path := range paths {
+		data, err := read(path)
+		if err != nil {
+			continue
+		}
+		metadata, err := config.ParseFileMetadata(data, path)
+		if err != nil {
+			continue
+		}
+		if recipients := metadata.MissingRecipients(policy.RecoveryRecipients); len(recipients) > 0 {
+			missing = append(missing, config.MissingRecovery{Path: path, Recipients: recipients})
+		}
+	}
+	return missing
+}
+
+// reportRevoked prints the files revoked recipients can decrypt and how to rotate them
+func reportRevoked(revoked []config.RevokedRecipients) {
+	for _, file := range revoked {
+		logging.Error("%s can still be decrypted by %s", file.Path, strings.Join(file.Members, ", "))
+	}
+	if len(revoked) > 0 {
+		logging.Info("Run 'simple-sops migrate-recipients --from <member> --to <recipients>' to rotate these files to new recipients.")
+	}
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+// VersionCmd returns the version command
+func VersionCmd() *cobra.Command {
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show the version of simple-sops",
+		Long: `Show the version of simple-sops.
+With --verbose, the commit, build date, Go runtime and the versions of the
+installed sops, age and op tools are printed as well. Include this output
+in bug reports.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			info := version.Get()
+			fmt.Printf("simple-sops %s\n", info.Version)
+			if !verbose {
+				return
+			}
+
+			commit := info.Commit
+			if commit == "" {
+				commit = "unknown"
+			} else if info.Modified {
+				commit += " (modified)"
+			}
+			date := info.Date
+			if date == "" {
+				date = "unknown"
+			}
+
+			fmt.Printf("  commit:  %s\n", commit)
+			fmt.Printf("  built:   %s\n", date)
+			fmt.Printf("  go:      %s %s\n", info.GoVersion, info.Platform)
+			for _, tool := range []string{"sops", "age", "op"} {
+				toolVersion, found := version.ToolVersion(tool)
+				if !found {
+					toolVersion = "not found"
+				}
+				fmt.Printf("  %-8s %s\n", tool+":", toolVersion)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also show build metadata and the versions of sops, age and op")
+
+	return cmd
+}
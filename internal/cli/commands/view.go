@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// DefaultViewTimeout is how long a decrypted view stays open
+const DefaultViewTimeout = 10 * time.Minute
+
+// ViewCmd returns the view command
+func ViewCmd() *cobra.Command {
+	var (
+		keyFile string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "view [file]",
+		Short: "View a decrypted file in a read-only pager",
+		Long: `View a decrypted file in a pager without writing it to disk. The plaintext is
+passed to the pager through a pipe, and less is started in secure mode so it
+can't save it. Unlike 'decrypt --stdout | less', the output can't be redirected
+to a file by accident: view refuses to run if stdout isn't a terminal.
+
+The pager is $PAGER, or less if it isn't set. It's closed after a timeout
+(10m by default, 0 to keep it open). Decrypt hooks run as for decrypt.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load application config
+			appConfig, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// If key file not specified in flags, use the one from config
+			if keyFile == "" {
+				keyFile = appConfig.KeyFile
+			}
+
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+
+			return encrypt.WithHooks(appConfig.Hooks, encrypt.OperationDecrypt, args, func() error {
+				return encrypt.ViewFile(args[0], keyFile, appConfig.AlwaysUseOnePassword, timeout)
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyFile, "key-file", "k", "", "Age key file to use (defaults to config setting)")
+	cmd.Flags().DurationVar(&timeout, "timeout", DefaultViewTimeout, "Close the view after this duration, 0 to keep it open")
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"simple-sops/internal/config"
+	"simple-sops/internal/deps"
+	"simple-sops/internal/version"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// requiredTools lists the external tools the top-level commands run
+// Commands that only read metadata or manage keys in 1Password aren't listed,
+// so they keep working without sops. Subcommands are listed by their path if
+// they need other tools than their top-level command.
+var requiredTools = map[string][]deps.Tool{
+	"encrypt":            {deps.Sops},
+	"decrypt":            {deps.Sops},
+	"edit":               {deps.Sops},
+	"set-keys":           {deps.Sops},
+	"rotate":             {deps.Sops},
+	"put":                {deps.Sops},
+	"run":                {deps.Sops},
+	"re-encrypt":         {deps.Sops},
+	"apply":              {deps.Sops},
+	"add-recipient":      {deps.Sops},
+	"migrate-recipients": {deps.Sops},
+	"resolve":            {deps.Sops},
+	"bundle decrypt":     {deps.Sops},
+	"sync":               {deps.Sops},
+	"new":                {deps.Sops},
+	"get":                {deps.Sops},
+	"copy":               {deps.Sops},
+	"view":               {deps.Sops},
+	"kubeconfig":         {deps.Sops},
+	"talos":              {deps.Sops},
+	"gen-key":            {deps.Age},
+	"import-age":         {deps.Sops, deps.AgeCLI},
+	"export-age":         {deps.Sops, deps.AgeCLI},
+	"repl":               {deps.Sops},
+}
+
+// flagTools lists the tools commands only run with a flag
+var flagTools = map[string]map[string][]deps.Tool{
+	"gen-key": {"piv": {deps.AgePluginYubikey}},
+}
+
+// CheckDependencies checks that the tools a command runs are installed
+// With install missing tools are installed first.
+func CheckDependencies(cmd *cobra.Command, install bool) error {
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+
+	// Subcommands share the tools of their top-level command
+	for cmd.HasParent() && cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+
+	required, ok := requiredTools[path]
+	if !ok {
+		required = requiredTools[cmd.Name()]
+	}
+	tools := append([]deps.Tool{}, required...)
+	for flag, flagged := range flagTools[cmd.Name()] {
+		if cmd.Flags().Changed(flag) {
+			tools = append(tools, flagged...)
+		}
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+	if err := deps.Check(install, tools...); err != nil {
+		return err
+	}
+
+	for _, tool := range tools {
+		if tool.Binary == deps.Sops.Binary {
+			return checkSopsVersion()
+		}
+	}
+	return nil
+}
+
+// checkSopsVersion checks the installed sops against the version the repository pins
+// A mismatch fails if the repository asks to, and is reported otherwise.
+func checkSopsVersion() error {
+	configPath, err := config.GetSopsConfigPath()
+	if err != nil {
+		return nil
+	}
+	pin, enabled, err := config.LoadVersionPin(config.RepoRoot(configPath))
+	if err != nil || !enabled {
+		return err
+	}
+
+	reported, found := version.ToolVersion(deps.Sops.Binary)
+	if !found {
+		return nil
+	}
+	if err := pin.CheckSops(reported); err != nil {
+		if pin.Fail {
+			return err
+		}
+		logging.Warn("%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxFileHints is the number of similar files suggested for an unknown argument
+const maxFileHints = 5
+
+// UnknownCommandError explains a first argument that is neither a command nor a file
+// Files with a similar path below the working directory are suggested, since
+// the argument may be a file to edit, and so are commands with a similar name
+// or, without any, all commands.
+func UnknownCommandError(rootCmd *cobra.Command, arg string) error {
+	var message strings.Builder
+	fmt.Fprintf(&message, "%s is neither a command nor a file", arg)
+
+	files, err := config.SimilarFiles(".", arg, maxFileHints)
+	if err != nil {
+		logging.Debug("Failed to search for similar files: %v", err)
+	}
+	if len(files) > 0 {
+		message.WriteString("\n\nDid you mean one of these files?")
+		for _, file := range files {
+			fmt.Fprintf(&message, "\n  %s", file)
+		}
+	}
+
+	// cobra only sets its default distance when it reports unknown commands itself
+	if rootCmd.SuggestionsMinimumDistance <= 0 {
+		rootCmd.SuggestionsMinimumDistance = 2
+	}
+	if suggestions := rootCmd.SuggestionsFor(arg); len(suggestions) > 0 {
+		message.WriteString("\n\nDid you mean this command?")
+		for _, suggestion := range suggestions {
+			fmt.Fprintf(&message, "\n  %s %s", rootCmd.Name(), suggestion)
+		}
+	} else {
+		var names []string
+		for _, cmd := range rootCmd.Commands() {
+			if cmd.IsAvailableCommand() {
+				names = append(names, cmd.Name())
+			}
+		}
+		fmt.Fprintf(&message, "\n\nAvailable commands: %s", strings.Join(names, ", "))
+	}
+
+	fmt.Fprintf(&message, "\n\nRun '%s help' for usage.", rootCmd.Name())
+	return fmt.Errorf("%s", message.String())
+}
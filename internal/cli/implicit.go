@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// EnableImplicitEdit makes the root command edit a file given instead of a command
+// simple-sops secrets.yaml runs edit, and the flags of edit work before and
+// after the file. Commands and aliases are found by cobra first, so a
+// mistyped command is never taken for a file. With implicit_edit: false in
+// the config, files have to be edited with the edit command.
+func EnableImplicitEdit(rootCmd *cobra.Command) {
+	editCmd, _, err := rootCmd.Find([]string{"edit"})
+	if err != nil || editCmd == rootCmd {
+		return
+	}
+
+	// The root command parses the flags of edit into the same variables,
+	// hidden so they don't show up in its help
+	editCmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if rootCmd.Flags().Lookup(flag.Name) != nil || rootCmd.Flags().ShorthandLookup(flag.Shorthand) != nil {
+			return
+		}
+		hidden := *flag
+		hidden.Hidden = true
+		rootCmd.Flags().AddFlag(&hidden)
+	})
+
+	rootCmd.Args = func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return nil
+		}
+
+		// The errors explain themselves, usage and cobra's own message don't help
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if !isFile(args[0]) {
+			return UnknownCommandError(cmd, args[0])
+		}
+		if !implicitEditEnabled() {
+			return fmt.Errorf("%s is not a command, run '%s edit %s' to edit it (implicit_edit is off)", args[0], cmd.Name(), args[0])
+		}
+		cmd.SilenceErrors = false
+		return editCmd.ValidateArgs(args)
+	}
+
+	// Complete files next to the commands, a single one like edit does
+	rootCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 || !implicitEditEnabled() {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return cmd.Help()
+		}
+
+		// The checks of PersistentPreRunE ran for the root command, which needs no tools
+		install, _ := cmd.Flags().GetBool("install-deps")
+		if err := CheckDependencies(editCmd, install); err != nil {
+			return err
+		}
+		return editCmd.RunE(editCmd, args)
+	}
+}
+
+// implicitEditEnabled reports whether implicit_edit allows editing a file without the edit command
+// A config that fails to load keeps the default, the command reports the error.
+func implicitEditEnabled() bool {
+	appConfig, err := config.LoadConfig()
+	if err != nil {
+		logging.Debug("Failed to load config: %v", err)
+		return true
+	}
+	return appConfig.ImplicitEditEnabled()
+}
+
+// isFile checks if a path is an existing file, not a directory
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
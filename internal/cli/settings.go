@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"fmt"
+	"simple-sops/internal/config"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+)
+
+// ApplyGlobalSettings applies the settings of the application config that affect every command
+// An invalid config is left to the commands loading it, so the commands
+// fixing it keep working.
+func ApplyGlobalSettings() error {
+	appConfig, err := config.LoadConfig()
+	if err != nil {
+		logging.Debug("Global settings not applied: %v", err)
+		return nil
+	}
+
+	if appConfig.OnePasswordItem != "" {
+		item, err := keymgmt.ParseOnePasswordItem(appConfig.OnePasswordItem)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid onepassword_item: %w", err))
+		}
+		keymgmt.SetDefaultOnePasswordItem(item)
+	}
+	if appConfig.NonInteractive {
+		logging.SetNonInteractive(true)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package clipboard copies values to the system clipboard through the
+// platform's command line utilities
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Tool is a command line utility that accesses the clipboard
+type Tool struct {
+	// Name is the name of the utility
+	Name string
+	// Copy writes stdin to the clipboard
+	Copy []string
+	// Paste prints the clipboard to stdout
+	Paste []string
+	// Clear empties the clipboard, Copy with empty input is used if unset
+	Clear []string
+}
+
+// Use variables for the environment lookups to allow mocking in tests
+var (
+	lookPath = exec.LookPath
+	getenv   = os.Getenv
+	goos     = runtime.GOOS
+)
+
+// candidates returns the utilities that may access the clipboard on this platform
+func candidates() []Tool {
+	switch goos {
+	case "darwin":
+		return []Tool{{Name: "pbcopy", Copy: []string{"pbcopy"}, Paste: []string{"pbpaste"}}}
+	case "windows":
+		return []Tool{{
+			Name:  "clip",
+			Copy:  []string{"clip"},
+			Paste: []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+		}}
+	}
+
+	var tools []Tool
+	if getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, Tool{
+			Name:  "wl-copy",
+			Copy:  []string{"wl-copy"},
+			Paste: []string{"wl-paste", "--no-newline"},
+			Clear: []string{"wl-copy", "--clear"},
+		})
+	}
+	return append(tools,
+		Tool{
+			Name:  "xclip",
+			Copy:  []string{"xclip", "-selection", "clipboard"},
+			Paste: []string{"xclip", "-selection", "clipboard", "-o"},
+		},
+		Tool{
+			Name:  "xsel",
+			Copy:  []string{"xsel", "--clipboard", "--input"},
+			Paste: []string{"xsel", "--clipboard", "--output"},
+		},
+	)
+}
+
+// Detect returns the first clipboard utility that is installed
+func Detect() (Tool, error) {
+	var names []string
+	for _, tool := range candidates() {
+		if _, err := lookPath(tool.Copy[0]); err == nil {
+			return tool, nil
+		}
+		names = append(names, tool.Name)
+	}
+	return Tool{}, fmt.Errorf("no clipboard utility found, install one of: %v", names)
+}
+
+// Write puts a value on the clipboard
+func (t Tool) Write(value []byte) error {
+	cmd := exec.Command(t.Copy[0], t.Copy[1:]...)
+	cmd.Stdin = bytes.NewReader(value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write to the clipboard with %s: %s\n%s", t.Name, err, string(output))
+	}
+	return nil
+}
+
+// Read returns the current content of the clipboard
+func (t Tool) Read() ([]byte, error) {
+	cmd := exec.Command(t.Paste[0], t.Paste[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard with %s: %s\n%s", t.Name, err, stderr.String())
+	}
+	return output, nil
+}
+
+// Hash returns the hash of a value, so a copied value can be recognized later
+// without keeping it in memory or passing it to another process
+func Hash(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearIfUnchanged clears the clipboard if it still holds the value with the
+// given hash. Anything copied in the meantime is left alone. It reports
+// whether the clipboard was cleared.
+func (t Tool) ClearIfUnchanged(hash string) (bool, error) {
+	current, err := t.Read()
+	if err != nil {
+		return false, err
+	}
+
+	// Some utilities append a line break when pasting
+	if Hash(current) != hash && Hash(bytes.TrimRight(current, "\r\n")) != hash {
+		return false, nil
+	}
+
+	if len(t.Clear) == 0 {
+		return true, t.Write(nil)
+	}
+	cmd := exec.Command(t.Clear[0], t.Clear[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("failed to clear the clipboard with %s: %s\n%s", t.Name, err, string(output))
+	}
+	return true, nil
+}
+
+// ScheduleClear starts a background process that clears the clipboard later
+// The command is run detached, with the hash of the copied value on stdin so
+// it doesn't show up in the process list. It's expected to read the hash,
+// wait and call ClearIfUnchanged.
+func ScheduleClear(command []string, hash string) error {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to schedule clearing the clipboard: %w", err)
+	}
+	defer reader.Close()
+
+	// The hash fits into the pipe buffer, so it's written before the process starts
+	if _, err := writer.WriteString(hash); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to schedule clearing the clipboard: %w", err)
+	}
+	writer.Close()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = reader
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to schedule clearing the clipboard: %w", err)
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,117 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// fileTool returns a tool that keeps the clipboard in a file
+func fileTool(t *testing.T) (Tool, string) {
+	path := filepath.Join(t.TempDir(), "clipboard")
+	return Tool{
+		Name:  "file",
+		Copy:  []string{"sh", "-c", fmt.Sprintf("cat > %s", path)},
+		Paste: []string{"cat", path},
+	}, path
+}
+
+func TestDetect(t *testing.T) {
+	originalLookPath, originalGetenv, originalGoos := lookPath, getenv, goos
+	defer func() { lookPath, getenv, goos = originalLookPath, originalGetenv, originalGoos }()
+
+	installed := map[string]bool{}
+	lookPath = func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	env := map[string]string{}
+	getenv = func(key string) string { return env[key] }
+
+	tests := []struct {
+		name      string
+		goos      string
+		installed []string
+		wayland   bool
+		want      string
+	}{
+		{"macOS", "darwin", []string{"pbcopy"}, false, "pbcopy"},
+		{"xclip", "linux", []string{"xclip", "xsel", "wl-copy"}, false, "xclip"},
+		{"xsel", "linux", []string{"xsel"}, false, "xsel"},
+		{"wayland", "linux", []string{"xclip", "wl-copy"}, true, "wl-copy"},
+		{"none", "linux", nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goos = tt.goos
+			installed = map[string]bool{}
+			for _, name := range tt.installed {
+				installed[name] = true
+			}
+			env = map[string]string{}
+			if tt.wayland {
+				env["WAYLAND_DISPLAY"] = "wayland-0"
+			}
+
+			tool, err := Detect()
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Expected error, got %s", tool.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if tool.Name != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, tool.Name)
+			}
+		})
+	}
+}
+
+func TestClearIfUnchanged(t *testing.T) {
+	tool, path := fileTool(t)
+
+	if err := tool.Write([]byte("s3cret")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	hash := Hash([]byte("s3cret"))
+
+	// Values copied in the meantime are kept
+	if err := os.WriteFile(path, []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cleared, err := tool.ClearIfUnchanged(hash)
+	if err != nil {
+		t.Fatalf("ClearIfUnchanged failed: %v", err)
+	}
+	if cleared {
+		t.Error("Expected a changed clipboard to be kept")
+	}
+
+	// A trailing line break added by the paste utility is ignored
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cleared, err = tool.ClearIfUnchanged(hash)
+	if err != nil {
+		t.Fatalf("ClearIfUnchanged failed: %v", err)
+	}
+	if !cleared {
+		t.Error("Expected the copied value to be cleared")
+	}
+
+	content, err := tool.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(content) != 0 {
+		t.Errorf("Expected an empty clipboard, got %q", content)
+	}
+}
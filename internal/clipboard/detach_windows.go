@@ -0,0 +1,8 @@
+//go:build windows
+
+package clipboard
+
+import "os/exec"
+
+// detach is a no-op on Windows, where child processes keep running after we exit
+func detach(cmd *exec.Cmd) {}
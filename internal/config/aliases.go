@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Aliases map command names to simple-sops command lines
+// e.g. prod-env: run secrets/prod.enc.env --env --
+type Aliases map[string]string
+
+// Validate checks that every alias has a name and a parsable command line
+func (a Aliases) Validate() error {
+	for name, command := range a {
+		if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("aliases: invalid name %q", name)
+		}
+		args, err := SplitCommand(command)
+		if err != nil {
+			return fmt.Errorf("aliases: %s: %w", name, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("aliases: %s has no command", name)
+		}
+	}
+	return nil
+}
+
+// Args returns the arguments an alias expands to
+func (a Aliases) Args(name string) ([]string, bool) {
+	command, ok := a[name]
+	if !ok {
+		return nil, false
+	}
+	args, err := SplitCommand(command)
+	if err != nil || len(args) == 0 {
+		return nil, false
+	}
+	return args, true
+}
+
+// LoadAliases reads only the aliases of the application config
+// It runs before the commands are set up, so it neither validates the other
+// settings nor warns about them.
+func LoadAliases() (Aliases, error) {
+	configPath, err := GetConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var partial struct {
+		Aliases Aliases `yaml:"aliases"`
+	}
+	if err := yaml.Unmarshal(data, &partial); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	if err := partial.Aliases.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	return partial.Aliases, nil
+}
+
+// SplitCommand splits a command line into arguments like a POSIX shell
+// Single and double quotes group words and a backslash escapes the next
+// character. Variables and globs are not expanded.
+func SplitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, command)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"run secrets/prod.enc.env -- make deploy", []string{"run", "secrets/prod.enc.env", "--", "make", "deploy"}, false},
+		{`  get  api.enc.yaml '["token"]' `, []string{"get", "api.enc.yaml", `["token"]`}, false},
+		{`run s.enc.env -s -- "echo \"$FOO\" > out"`, []string{"run", "s.enc.env", "-s", "--", `echo "$FOO" > out`}, false},
+		{`put a.yaml key\ with\ spaces ''`, []string{"put", "a.yaml", "key with spaces", ""}, false},
+		{`get 'unterminated`, nil, true},
+		{`get trailing\`, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitCommand(tt.command)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitCommand(%q) error = %v, wantErr %v", tt.command, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestAliasesValidate(t *testing.T) {
+	valid := Aliases{"prod-env": "run secrets/prod.enc.env -- make deploy"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid aliases, got %v", err)
+	}
+	if args, ok := valid.Args("prod-env"); !ok || len(args) != 5 {
+		t.Errorf("Expected 5 arguments, got %q", args)
+	}
+
+	for name, aliases := range map[string]Aliases{
+		"empty command": {"x": "  "},
+		"flag name":     {"-x": "version"},
+		"spaced name":   {"a b": "version"},
+		"bad quoting":   {"x": `get "a`},
+	} {
+		if err := aliases.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
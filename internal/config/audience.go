@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// HasAudience checks if the rule is tagged for an audience
+func (r CreationRule) HasAudience(audience string) bool {
+	return slices.Contains(r.Audiences, audience)
+}
+
+// ValidateAudience checks that an audience name has no spaces or commas
+func ValidateAudience(audience string) error {
+	if audience == "" || strings.ContainsAny(audience, " \t,") {
+		return fmt.Errorf("invalid audience %q", audience)
+	}
+	return nil
+}
+
+// SetRuleAudiences replaces the audiences of a rule and returns its path regex
+// target is a rule's path regex or a file relative to .sops.yaml, which
+// selects the rule sops applies to it. The catch-all rule can't be tagged.
+// No audiences remove the tags.
+func SetRuleAudiences(config *SopsConfig, target string, audiences []string) (string, error) {
+	for _, audience := range audiences {
+		if err := ValidateAudience(audience); err != nil {
+			return "", err
+		}
+	}
+
+	index := slices.IndexFunc(config.CreationRules, func(rule CreationRule) bool {
+		return rule.PathRegex == target || ruleMatchesFile(rule, target)
+	})
+	if index < 0 {
+		index = slices.IndexFunc(config.CreationRules, func(rule CreationRule) bool {
+			pattern, err := regexp.Compile(rule.PathRegex)
+			return err == nil && pattern.MatchString(target)
+		})
+	}
+	if index < 0 {
+		return "", fmt.Errorf("no rule found for %s", target)
+	}
+	rule := &config.CreationRules[index]
+	if IsWildcardRule(rule.PathRegex) {
+		return "", fmt.Errorf("only the catch-all rule matches %s, add a rule for it first", target)
+	}
+
+	var unique []string
+	for _, audience := range audiences {
+		if !slices.Contains(unique, audience) {
+			unique = append(unique, audience)
+		}
+	}
+	rule.Audiences = unique
+	return rule.PathRegex, nil
+}
+
+// FilterByAudience returns the files whose rule is tagged for an audience
+// Audiences name the roles that need the files of a rule, e.g. frontend, so
+// a checkout only decrypts what the role needs. Files without a rule or with
+// an untagged rule are left out.
+func FilterByAudience(config *SopsConfig, configPath string, files []string, audience string) []string {
+	var selected []string
+	for _, file := range files {
+		rule, found := MatchingRule(config, ConfigRelativePath(configPath, file))
+		if found && rule.HasAudience(audience) {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
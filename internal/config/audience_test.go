@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetRuleAudiences(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secrets/db.yaml")},
+		{PathRegex: "^frontend/"},
+		{PathRegex: WildcardPattern},
+	}}
+
+	// A file selects the rule sops applies to it
+	pathRegex, err := SetRuleAudiences(sopsConfig, "frontend/app.env", []string{"frontend", "ops", "frontend"})
+	if err != nil || pathRegex != "^frontend/" {
+		t.Fatalf("SetRuleAudiences() = %q, %v", pathRegex, err)
+	}
+	if got := sopsConfig.CreationRules[1].Audiences; !reflect.DeepEqual(got, []string{"frontend", "ops"}) {
+		t.Errorf("Audiences = %v, want [frontend ops]", got)
+	}
+
+	// A path regex selects its rule, no audiences remove the tags
+	if _, err := SetRuleAudiences(sopsConfig, "^frontend/", nil); err != nil {
+		t.Fatalf("SetRuleAudiences() error = %v", err)
+	}
+	if got := sopsConfig.CreationRules[1].Audiences; len(got) != 0 {
+		t.Errorf("Audiences = %v, want none", got)
+	}
+
+	if _, err := SetRuleAudiences(sopsConfig, "other.yaml", []string{"ops"}); err == nil {
+		t.Error("SetRuleAudiences() tagged the catch-all rule")
+	}
+	if _, err := SetRuleAudiences(sopsConfig, "secrets/db.yaml", []string{"a,b"}); err == nil {
+		t.Error("SetRuleAudiences() accepted an invalid audience")
+	}
+}
+
+func TestFilterByAudience(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".sops.yaml")
+	content := `creation_rules:
+  - path_regex: ^frontend/
+    age: age1frontend
+    audiences:
+      - frontend
+  - path_regex: (^|/)secrets/db\.yaml$
+    age: age1ops
+    audiences: [ops, backend]
+  - path_regex: .*
+    age: age1all
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sopsConfig, err := LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig() error = %v", err)
+	}
+
+	files := []string{
+		filepath.Join(dir, "frontend", "app.env"),
+		filepath.Join(dir, "secrets", "db.yaml"),
+		filepath.Join(dir, "other.yaml"),
+	}
+	if got := FilterByAudience(sopsConfig, configPath, files, "frontend"); !reflect.DeepEqual(got, files[:1]) {
+		t.Errorf("FilterByAudience(frontend) = %v", got)
+	}
+	if got := FilterByAudience(sopsConfig, configPath, files, "backend"); !reflect.DeepEqual(got, files[1:2]) {
+		t.Errorf("FilterByAudience(backend) = %v", got)
+	}
+	if got := FilterByAudience(sopsConfig, configPath, files, "qa"); len(got) != 0 {
+		t.Errorf("FilterByAudience(qa) = %v, want none", got)
+	}
+}
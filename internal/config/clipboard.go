@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultClipboardTimeout is how long copied values stay on the clipboard
+const DefaultClipboardTimeout = 45 * time.Second
+
+// ClipboardSettings control the copy command
+type ClipboardSettings struct {
+	// Timeout after which a copied value is cleared (e.g. "45s", "2m", "0" to keep it)
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Validate checks that the timeout is a valid duration
+func (c ClipboardSettings) Validate() error {
+	if _, err := c.ClearAfter(); err != nil {
+		return fmt.Errorf("clipboard: %w", err)
+	}
+	return nil
+}
+
+// ClearAfter returns the timeout after which the clipboard is cleared
+// Zero means the value is never cleared.
+func (c ClipboardSettings) ClearAfter() (time.Duration, error) {
+	if c.Timeout == "" {
+		return DefaultClipboardTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(c.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", c.Timeout, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must not be negative", c.Timeout)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersFiles are the locations of CODEOWNERS relative to the repository root
+// As on GitHub, the first one that exists is used.
+var CodeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnersRule is a single line of a CODEOWNERS file
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// CodeOwners maps paths to their owners, like @acme/payments or @alice
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+// LoadCodeOwners loads the CODEOWNERS file of the repository at root
+// A missing file results in no owners.
+func LoadCodeOwners(root string) (*CodeOwners, error) {
+	for _, name := range CodeOwnersFiles {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		owners, err := ParseCodeOwners(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", name, err)
+		}
+		return owners, nil
+	}
+	return &CodeOwners{}, nil
+}
+
+// ParseCodeOwners parses the content of a CODEOWNERS file
+// Patterns use gitignore syntax, except that negation isn't supported.
+func ParseCodeOwners(data []byte) (*CodeOwners, error) {
+	codeOwners := &CodeOwners{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if comment := strings.Index(line, " #"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "!") {
+			return nil, fmt.Errorf("line %d: negated patterns are not supported", lineNumber)
+		}
+
+		pattern := fields[0]
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		anchored := strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+
+		expr := globToRegexp(pattern)
+		if pattern == "" || pattern == "*" {
+			expr = ".*"
+		}
+		if anchored {
+			expr = "^" + expr
+		} else {
+			expr = "(^|/)" + expr
+		}
+		// A pattern matching a directory owns everything below it
+		if dirOnly {
+			expr += "/"
+		} else {
+			expr += "(/|$)"
+		}
+
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", lineNumber, fields[0], err)
+		}
+		codeOwners.rules = append(codeOwners.rules, codeOwnersRule{pattern: compiled, owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return codeOwners, nil
+}
+
+// Owners returns the owners of a path relative to the repository root
+// The last matching line wins, and a line without owners leaves the path unowned.
+func (c *CodeOwners) Owners(relPath string) []string {
+	relPath = filepath.ToSlash(relPath)
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(relPath) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
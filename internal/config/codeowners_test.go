@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeOwners(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `# Default owners
+*                 @acme/platform
+*.env             @acme/ops
+/services/api/    @acme/api @alice  # API team
+docs/             @acme/writers
+/services/api/vendor/
+`
+	if err := os.WriteFile(filepath.Join(root, ".github", "CODEOWNERS"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	codeOwners, err := LoadCodeOwners(root)
+	if err != nil {
+		t.Fatalf("LoadCodeOwners failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "secret.yaml", want: "@acme/platform"},
+		{path: "k8s/app.env", want: "@acme/ops"},
+		{path: "services/api/secret.yaml", want: "@acme/api @alice"},
+		{path: "services/api/k8s/secret.env", want: "@acme/api @alice"},
+		{path: "other/services/api/secret.yaml", want: "@acme/platform"},
+		{path: "guide/docs/secret.yaml", want: "@acme/writers"},
+		{path: "services/api/vendor/secret.yaml", want: ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(codeOwners.Owners(tt.path), " "); got != tt.want {
+			t.Errorf("Owners(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := ParseCodeOwners([]byte("!secret.yaml @alice\n")); err == nil {
+		t.Error("Expected an error for a negated pattern")
+	}
+}
+
+func TestTeamForCodeOwners(t *testing.T) {
+	root := t.TempDir()
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+  - name: bob
+    keys: age1bob
+groups:
+  api: [alice, bob]
+  ops: [bob]
+owners:
+  "@acme/platform-team": ops
+`)
+
+	team, err := LoadTeam(root)
+	if err != nil {
+		t.Fatalf("LoadTeam failed: %v", err)
+	}
+
+	got := team.ForCodeOwners([]string{"@acme/api", "@alice", "@acme/platform-team", "@acme/unknown", "alice@example.com"})
+	if strings.Join(got, ",") != "api,alice,ops" {
+		t.Errorf("ForCodeOwners() = %v, want [api alice ops]", got)
+	}
+
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+owners:
+  "@acme/api": missing
+`)
+	if _, err := LoadTeam(root); err == nil {
+		t.Error("Expected an error for an owner mapped to an unknown member")
+	}
+}
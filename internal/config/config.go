@@ -1,27 +1,78 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"simple-sops/internal/exitcode"
 	"simple-sops/pkg/logging"
+
+	"gopkg.in/yaml.v3"
 )
 
 // AppConfig represents the application configuration
 type AppConfig struct {
 	// KeyFile is the path to the Age key file
-	KeyFile string
+	KeyFile string `yaml:"key_file,omitempty"`
 	// OnePasswordEnabled indicates whether to use 1Password for key storage
-	OnePasswordEnabled bool
+	OnePasswordEnabled bool `yaml:"onepassword_enabled"`
 	// AlwaysUseOnePassword indicates whether to always get the key from 1Password for each operation
-	AlwaysUseOnePassword bool
+	AlwaysUseOnePassword bool `yaml:"always_use_onepassword"`
 	// Debug mode
-	Debug bool
+	Debug bool `yaml:"debug,omitempty"`
 	// Quiet mode
-	Quiet bool
+	Quiet bool `yaml:"quiet,omitempty"`
 	// List of supported file extensions
-	SupportedExtensions []string
+	SupportedExtensions []string `yaml:"supported_extensions,omitempty"`
+	// Profile is the name of the active key profile
+	Profile string `yaml:"profile,omitempty"`
+	// Profiles holds named key profiles
+	Profiles map[string]*KeyProfile `yaml:"profiles,omitempty"`
+	// Rotation is the default rotation policy for keys and encrypted files
+	Rotation RotationPolicy `yaml:"rotation,omitempty"`
+	// Wildcard controls the recipients of the catch-all rule in .sops.yaml
+	Wildcard WildcardPolicy `yaml:"wildcard,omitempty"`
+	// EncryptedRegexDefaults give new rules an encrypted_regex based on the file
+	EncryptedRegexDefaults EncryptedRegexDefaults `yaml:"encrypted_regex_defaults,omitempty"`
+	// Presets customize the kubeconfig and talos commands
+	Presets PresetSettingsMap `yaml:"presets,omitempty"`
+	// Naming relates plaintext files to their encrypted counterparts for --output auto
+	Naming NamingConvention `yaml:"naming,omitempty"`
+	// Hooks are external commands run before and after decrypt and rotate
+	Hooks HookSettings `yaml:"hooks,omitempty"`
+	// Clipboard controls how long the copy command keeps values on the clipboard
+	Clipboard ClipboardSettings `yaml:"clipboard,omitempty"`
+	// Aliases are shortcuts for simple-sops command lines, added as commands
+	Aliases Aliases `yaml:"aliases,omitempty"`
+	// EditLock makes edit record an advisory lock next to the file it edits
+	EditLock bool `yaml:"edit_lock,omitempty"`
+	// ImplicitEdit makes simple-sops <file> edit the file, enabled if unset
+	ImplicitEdit *bool `yaml:"implicit_edit,omitempty"`
+	// ConfigSigning signs .sops.yaml and checks its signature
+	ConfigSigning ConfigSigning `yaml:"config_signing,omitempty"`
+	// RecipientPolicy sets the recipients every rule must have, with the one of the repository
+	RecipientPolicy RecipientPolicy `yaml:"recipient_policy,omitempty"`
+	// OnePasswordItem replaces the default 1Password item of the key, as item name or op://vault/item/field
+	OnePasswordItem string `yaml:"onepassword_item,omitempty"`
+	// NonInteractive makes prompts fail or decline instead of waiting for input, e.g. on CI
+	NonInteractive bool `yaml:"non_interactive,omitempty"`
+	// RunEnv controls the environment of commands started by run
+	RunEnv RunEnvSettings `yaml:"run_env,omitempty"`
+}
+
+// KeyProfile represents a named key and its metadata
+type KeyProfile struct {
+	// KeyFile is the path to the Age key file for this profile
+	KeyFile string `yaml:"key_file,omitempty"`
+	// Rotation overrides the default rotation policy for this profile
+	Rotation RotationPolicy `yaml:"rotation,omitempty"`
+	// RunEnv adds to the run environment settings while this profile is active
+	RunEnv RunEnvSettings `yaml:"run_env,omitempty"`
 }
 
+// ProfileEnvVar is the environment variable that overrides the active profile
+const ProfileEnvVar = "SIMPLE_SOPS_PROFILE"
+
 // DefaultConfig returns the default application configuration
 func DefaultConfig() *AppConfig {
 	return &AppConfig{
@@ -49,6 +100,11 @@ func getDefaultKeyPath() string {
 	return filepath.Join(home, ".config", "simple-sops", "key.txt")
 }
 
+// ImplicitEditEnabled reports whether simple-sops <file> edits the file
+func (c *AppConfig) ImplicitEditEnabled() bool {
+	return c.ImplicitEdit == nil || *c.ImplicitEdit
+}
+
 // IsSupportedFileType checks if a file is a supported type
 func (c *AppConfig) IsSupportedFileType(filename string) bool {
 	ext := filepath.Ext(filename)
@@ -75,9 +131,128 @@ func GetConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// GetConfigFilePath returns the path to the application config file
+// SIMPLE_SOPS_CONFIG replaces the default path.
+func GetConfigFilePath() (string, error) {
+	if path := os.Getenv(ConfigEnvVar); path != "" {
+		return path, nil
+	}
+
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "config.yaml"), nil
+}
+
 // LoadConfig loads the application configuration
 func LoadConfig() (*AppConfig, error) {
-	// For now, just return the default config
-	// In the future, this could load from a config file
-	return DefaultConfig(), nil
+	configPath, err := GetConfigFilePath()
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to determine config path: %w", err))
+	}
+
+	appConfig, err := LoadConfigFile(configPath)
+	return appConfig, exitcode.Wrap(exitcode.Config, err)
+}
+
+// warnedLegacyKeys reports outdated setting names only once per run
+// The config is loaded before every command and again by the command.
+var warnedLegacyKeys bool
+
+// LoadConfigFile loads the application configuration from a specific file
+// Settings missing from the file keep their default values, and SIMPLE_SOPS_*
+// environment variables override the file.
+func LoadConfigFile(configPath string) (*AppConfig, error) {
+	appConfig := DefaultConfig()
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		if err := applyEnv(appConfig); err != nil {
+			return nil, err
+		}
+		return appConfig, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, appConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	if HasLegacyAppConfigKeys(data) && !warnedLegacyKeys {
+		warnedLegacyKeys = true
+		logging.Warn("%s uses setting names of an older version. Run 'simple-sops migrate config' to update it.", configPath)
+	}
+
+	if err := appConfig.Wildcard.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.EncryptedRegexDefaults.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.Presets.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.Naming.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.Hooks.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.Clipboard.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.Aliases.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	if err := appConfig.RunEnv.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	for name, profile := range appConfig.Profiles {
+		if profile == nil {
+			continue
+		}
+		if err := profile.RunEnv.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: profile %s: %w", configPath, name, err)
+		}
+	}
+	if err := appConfig.RecipientPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: recipient_policy: %w", configPath, err)
+	}
+
+	// The environment can select a profile, e.g. on CI runners
+	if profile := os.Getenv(ProfileEnvVar); profile != "" {
+		appConfig.Profile = profile
+	}
+
+	// Apply the active profile
+	if appConfig.Profile != "" {
+		profile, ok := appConfig.Profiles[appConfig.Profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %s not found in %s", appConfig.Profile, configPath)
+		}
+		if profile.KeyFile != "" {
+			appConfig.KeyFile = profile.KeyFile
+		}
+	}
+
+	// The environment overrides the file, e.g. in containers on CI
+	if err := applyEnv(appConfig); err != nil {
+		return nil, err
+	}
+
+	return appConfig, nil
+}
+
+// ActiveProfile returns the active key profile, if any
+func (c *AppConfig) ActiveProfile() (*KeyProfile, bool) {
+	if c.Profile == "" {
+		return nil, false
+	}
+
+	profile, ok := c.Profiles[c.Profile]
+	return profile, ok
 }
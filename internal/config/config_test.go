@@ -4,7 +4,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadSopsConfig(t *testing.T) {
@@ -43,8 +47,8 @@ func TestLoadSopsConfig(t *testing.T) {
 	if config.CreationRules[0].PathRegex != "test.env" {
 		t.Errorf("Expected path_regex 'test.env', got '%s'", config.CreationRules[0].PathRegex)
 	}
-	if config.CreationRules[0].Age != "age123" {
-		t.Errorf("Expected age 'age123', got '%s'", config.CreationRules[0].Age)
+	if config.CreationRules[0].Age.String() != "age123" {
+		t.Errorf("Expected age 'age123', got '%s'", config.CreationRules[0].Age.String())
 	}
 
 	// Test loading non-existent config
@@ -64,7 +68,7 @@ func TestAddCreationRuleWithMultipleKeys(t *testing.T) {
 	}
 
 	// Test adding a rule with multiple keys
-	err := AddCreationRuleWithMultipleKeys(config, "test.env", "age123,age456", "")
+	err := AddCreationRuleWithMultipleKeys(config, "test.env", []string{"age123", "age456"}, "", WildcardPolicy{})
 	if err != nil {
 		t.Fatalf("AddCreationRuleWithMultipleKeys failed: %v", err)
 	}
@@ -77,10 +81,10 @@ func TestAddCreationRuleWithMultipleKeys(t *testing.T) {
 	// Check the added rule
 	found := false
 	for _, rule := range config.CreationRules {
-		if rule.PathRegex == "test.env" {
+		if rule.PathRegex == RulePathRegex("test.env") {
 			found = true
-			if rule.Age != "age123,age456" {
-				t.Errorf("Expected age 'age123,age456', got '%s'", rule.Age)
+			if rule.Age.String() != "age123,age456" {
+				t.Errorf("Expected age 'age123,age456', got '%s'", rule.Age.String())
 			}
 		}
 	}
@@ -90,17 +94,349 @@ func TestAddCreationRuleWithMultipleKeys(t *testing.T) {
 	}
 
 	// Test updating an existing rule
-	err = AddCreationRuleWithMultipleKeys(config, "test.env", "age789,age101112", "")
+	err = AddCreationRuleWithMultipleKeys(config, "test.env", []string{"age789", "age101112"}, "", WildcardPolicy{})
 	if err != nil {
 		t.Fatalf("AddCreationRuleWithMultipleKeys failed when updating: %v", err)
 	}
 
 	// Verify the rule was updated correctly
 	for _, rule := range config.CreationRules {
-		if rule.PathRegex == "test.env" {
-			if rule.Age != "age789,age101112" {
-				t.Errorf("Expected updated age 'age789,age101112', got '%s'", rule.Age)
+		if rule.PathRegex == RulePathRegex("test.env") {
+			if rule.Age.String() != "age789,age101112" {
+				t.Errorf("Expected updated age 'age789,age101112', got '%s'", rule.Age.String())
+			}
+		}
+	}
+}
+
+func TestAddCreationRuleWithDuplicateKeys(t *testing.T) {
+	config := &SopsConfig{}
+
+	// The same key from a file and 1Password is only written once
+	if err := AddCreationRuleWithMultipleKeys(config, "test.env", []string{"age123", " age456", "age123", ""}, "", WildcardPolicy{}); err != nil {
+		t.Fatalf("AddCreationRuleWithMultipleKeys failed: %v", err)
+	}
+
+	rule, found := GetCreationRule(config, "test.env")
+	if !found {
+		t.Fatal("Rule for test.env not found")
+	}
+	if rule.Age.String() != "age123,age456" {
+		t.Errorf("Expected deduplicated recipients 'age123,age456', got '%s'", rule.Age.String())
+	}
+}
+
+func TestAgeRecipientsYAML(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".sops.yaml")
+
+	// sops accepts both a comma-separated string and a list
+	configContent := `creation_rules:
+  - path_regex: string.env
+    age: age123, age456
+  - path_regex: list.env
+    age:
+      - age123
+      - age456
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+
+	for _, rule := range config.CreationRules {
+		if len(rule.Age) != 2 || rule.Age[0] != "age123" || rule.Age[1] != "age456" {
+			t.Errorf("Expected [age123 age456] for %s, got %v", rule.PathRegex, rule.Age)
+		}
+	}
+
+	// Recipients are written as a comma-separated string
+	if err := SaveSopsConfig(configPath, config); err != nil {
+		t.Fatalf("SaveSopsConfig failed: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if strings.Count(string(data), "age: age123,age456") != 2 {
+		t.Errorf("Expected comma-separated recipients, got:\n%s", data)
+	}
+
+	// Other YAML types are rejected
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: x\n    age: {a: b}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadSopsConfig(configPath); err == nil {
+		t.Error("Expected error for invalid age field, got nil")
+	}
+}
+
+func TestWildcardPolicy(t *testing.T) {
+	wildcardRecipients := func(config *SopsConfig) (AgeRecipients, bool) {
+		rule, found := GetCreationRule(config, WildcardPattern)
+		return rule.Age, found
+	}
+
+	// By default the catch-all rule gets every recipient, including later ones
+	config := &SopsConfig{}
+	if err := AddCreationRuleWithMultipleKeys(config, "a.env", []string{"age123", "age456"}, "", WildcardPolicy{}); err != nil {
+		t.Fatalf("AddCreationRuleWithMultipleKeys failed: %v", err)
+	}
+	if err := AddCreationRule(config, "b.env", "age789", "", WildcardPolicy{Mode: WildcardAll}); err != nil {
+		t.Fatalf("AddCreationRule failed: %v", err)
+	}
+	if recipients, _ := wildcardRecipients(config); recipients.String() != "age123,age456,age789" {
+		t.Errorf("Expected all recipients in the wildcard rule, got '%s'", recipients)
+	}
+
+	// The default mode uses the designated recipients
+	config = &SopsConfig{}
+	policy := WildcardPolicy{Mode: WildcardDefault, Recipients: []string{"age999"}}
+	if err := AddCreationRuleWithMultipleKeys(config, "a.env", []string{"age123", "age456"}, "", policy); err != nil {
+		t.Fatalf("AddCreationRuleWithMultipleKeys failed: %v", err)
+	}
+	if recipients, _ := wildcardRecipients(config); recipients.String() != "age999" {
+		t.Errorf("Expected designated recipients in the wildcard rule, got '%s'", recipients)
+	}
+
+	// The none mode leaves the catch-all rule alone
+	config = &SopsConfig{}
+	if err := AddCreationRule(config, "a.env", "age123", "", WildcardPolicy{Mode: WildcardNone}); err != nil {
+		t.Fatalf("AddCreationRule failed: %v", err)
+	}
+	if _, found := wildcardRecipients(config); found {
+		t.Error("Expected no wildcard rule in none mode")
+	}
+
+	// Invalid policies are rejected
+	for _, invalid := range []WildcardPolicy{{Mode: "some"}, {Mode: WildcardDefault}} {
+		if err := AddCreationRule(config, "a.env", "age123", "", invalid); err == nil {
+			t.Errorf("Expected error for policy %+v, got nil", invalid)
+		}
+	}
+}
+
+func TestPreviewSopsConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".sops.yaml")
+
+	configContent := "creation_rules:\n    - path_regex: a.env\n      age: age123\n"
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+
+	// An unchanged config has no diff
+	diff, err := PreviewSopsConfig(configPath, config)
+	if err != nil {
+		t.Fatalf("PreviewSopsConfig failed: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("Expected no diff for an unchanged config, got:\n%s", diff)
+	}
+
+	config.CreationRules[0].Age = AgeRecipients{"age456"}
+	diff, err = PreviewSopsConfig(configPath, config)
+	if err != nil {
+		t.Fatalf("PreviewSopsConfig failed: %v", err)
+	}
+	expected := " creation_rules:\n     - path_regex: a.env\n-      age: age123\n+      age: age456\n"
+	if diff != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, diff)
+	}
+
+	// The file on disk is untouched
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if string(data) != configContent {
+		t.Errorf("Expected config file to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestEncryptedRegexDefaults(t *testing.T) {
+	defaults := EncryptedRegexDefaults{
+		{Match: "k8s/", EncryptedRegex: "^(data|stringData)$"},
+		{Match: "*.talos.yaml", EncryptedRegex: "^(secrets|privateKey|token|key|crt)$"},
+		{Match: ".ENV", EncryptedRegex: ".*"},
+	}
+	if err := defaults.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"k8s/apps/secret.yaml":     "^(data|stringData)$",
+		"cluster/node.talos.yaml":  "^(secrets|privateKey|token|key|crt)$",
+		"app.env":                  ".*",
+		".env":                     ".*",
+		"config/values.yaml":       "",
+		"not-k8s/apps/secret.yaml": "",
+	}
+	for path, expected := range tests {
+		if got := defaults.For(path); got != expected {
+			t.Errorf("For(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+
+	// Invalid entries are rejected
+	for _, invalid := range []EncryptedRegexDefaults{
+		{{Match: "", EncryptedRegex: ".*"}},
+		{{Match: ".env", EncryptedRegex: "("}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, got nil", invalid)
+		}
+	}
+}
+
+func TestSetRuleOptions(t *testing.T) {
+	config := &SopsConfig{}
+	if err := AddCreationRule(config, "app.yaml", "age123", "^(password)$", WildcardPolicy{Mode: WildcardNone}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	macOnlyEncrypted, commentRegex := true, "^sops:"
+	options := RuleOptions{MacOnlyEncrypted: &macOnlyEncrypted, EncryptedCommentRegex: &commentRegex}
+	if err := SetRuleOptions(config, "app.yaml", options); err != nil {
+		t.Fatalf("SetRuleOptions failed: %v", err)
+	}
+	if options.MinSopsVersion() != "3.9.0" {
+		t.Errorf("Expected mac_only_encrypted to need sops 3.9.0, got %s", options.MinSopsVersion())
+	}
+
+	// Updating the recipients keeps the options
+	if err := AddCreationRule(config, "app.yaml", "age456", "", WildcardPolicy{Mode: WildcardNone}); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	for _, expected := range []string{"mac_only_encrypted: true", "encrypted_comment_regex: '^sops:'", "age: age456"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+
+	// Options that aren't given keep their value
+	otherRegex := "^note:"
+	if err := SetRuleOptions(config, "app.yaml", RuleOptions{EncryptedCommentRegex: &otherRegex}); err != nil {
+		t.Fatalf("SetRuleOptions failed: %v", err)
+	}
+	if rule, _ := GetCreationRule(config, "app.yaml"); !rule.MacOnlyEncrypted || rule.EncryptedCommentRegex != otherRegex {
+		t.Errorf("Expected only encrypted_comment_regex to change, got %+v", rule)
+	}
+
+	invalidRegex := "("
+	if err := SetRuleOptions(config, "app.yaml", RuleOptions{EncryptedCommentRegex: &invalidRegex}); err == nil {
+		t.Error("Expected an invalid comment regex to be rejected")
+	}
+	if err := SetRuleOptions(config, "other.yaml", options); err == nil {
+		t.Error("Expected an error for a file without rule")
+	}
+}
+
+func TestClipboardSettings(t *testing.T) {
+	tests := []struct {
+		timeout string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", DefaultClipboardTimeout, false},
+		{"2m", 2 * time.Minute, false},
+		{"0", 0, false},
+		{"-5s", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		settings := ClipboardSettings{Timeout: tt.timeout}
+		got, err := settings.ClearAfter()
+		if tt.wantErr {
+			if err == nil || settings.Validate() == nil {
+				t.Errorf("Expected error for timeout %q, got nil", tt.timeout)
 			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ClearAfter(%q) failed: %v", tt.timeout, err)
+		}
+		if got != tt.want {
+			t.Errorf("ClearAfter(%q) = %s, want %s", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestImplicitEdit(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"edit_lock: true\n", true},
+		{"implicit_edit: true\n", true},
+		{"implicit_edit: false\n", false},
+	}
+	for _, tt := range tests {
+		var appConfig AppConfig
+		if err := yaml.Unmarshal([]byte(tt.content), &appConfig); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", tt.content, err)
 		}
+		if got := appConfig.ImplicitEditEnabled(); got != tt.want {
+			t.Errorf("ImplicitEditEnabled() for %q = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestActiveRunEnv(t *testing.T) {
+	appConfig := &AppConfig{
+		RunEnv:  RunEnvSettings{Allow: []string{"KUBECONFIG"}},
+		Profile: "aws",
+		Profiles: map[string]*KeyProfile{
+			"aws": {RunEnv: RunEnvSettings{Clean: true, Allow: []string{"AWS_*"}}},
+		},
+	}
+
+	settings := appConfig.ActiveRunEnv()
+	if !settings.Clean || len(settings.Allow) != 2 || settings.Allow[0] != "KUBECONFIG" || settings.Allow[1] != "AWS_*" {
+		t.Errorf("Expected the profile to add to the global settings, got %+v", settings)
+	}
+	if len(appConfig.RunEnv.Allow) != 1 {
+		t.Errorf("Expected the global settings to be unchanged, got %+v", appConfig.RunEnv)
+	}
+
+	for _, pattern := range []string{"AWS_*", "*", "_X"} {
+		if err := (RunEnvSettings{Allow: []string{pattern}}).Validate(); err != nil {
+			t.Errorf("Validate(%q) failed: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"", "A=B", "*_TOKEN", "1X"} {
+		if err := (RunEnvSettings{Allow: []string{pattern}}).Validate(); err == nil {
+			t.Errorf("Expected error for %q, got nil", pattern)
+		}
+	}
+}
+
+func TestTrimDiff(t *testing.T) {
+	diff := " a\n b\n c\n d\n-e\n+E\n f\n g\n h\n i\n j\n"
+	want := "@@\n b\n c\n d\n-e\n+E\n f\n g\n h\n@@\n"
+	if got := TrimDiff(diff, 3); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Changes close to each other share their context
+	diff = "-a\n b\n+c\n d\n e\n"
+	want = "-a\n b\n+c\n d\n@@\n"
+	if got := TrimDiff(diff, 1); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
 	}
 }
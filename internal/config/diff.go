@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DiffLines returns a line-based diff of two texts
+// Removed lines are prefixed with "-", added lines with "+" and unchanged
+// lines with a space. An empty string is returned if the texts are equal.
+func DiffLines(oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			fmt.Fprintf(&diff, " %s\n", oldLines[i])
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&diff, "-%s\n", oldLines[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+%s\n", newLines[j])
+			j++
+		}
+	}
+
+	return diff.String()
+}
+
+// TrimDiff keeps only the changed lines of a diff and context unchanged lines around them
+// Skipped unchanged lines are replaced by a "@@" line.
+func TrimDiff(diff string, context int) string {
+	lines := splitLines(diff)
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if strings.HasPrefix(line, " ") {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+
+	var trimmed strings.Builder
+	skipped := false
+	for i, line := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped || (i > 0 && trimmed.Len() == 0) {
+			trimmed.WriteString("@@\n")
+		}
+		skipped = false
+		trimmed.WriteString(line + "\n")
+	}
+	if skipped {
+		trimmed.WriteString("@@\n")
+	}
+	return trimmed.String()
+}
+
+// splitLines splits text into lines without a trailing empty line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// PreviewSopsConfig returns the diff between the .sops.yaml file on disk and
+// the given config, without writing anything
+func PreviewSopsConfig(configPath string, config *SopsConfig) (string, error) {
+	current, err := readSopsConfigData(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SOPS config file: %w", err)
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+
+	return DiffLines(string(current), string(data)), nil
+}
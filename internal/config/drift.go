@@ -0,0 +1,52 @@
+package config
+
+import "slices"
+
+// RecipientDrift is the difference between the recipients an encrypted file
+// is encrypted to and the recipients of the creation rule sops applies to it
+type RecipientDrift struct {
+	// Path is the path of the file relative to .sops.yaml
+	Path string
+	// PathRegex is the path regex of the matching rule, empty if no rule matches
+	PathRegex string
+	// Missing are recipients of the rule the file isn't encrypted to
+	Missing []string
+	// Extra are recipients the file is encrypted to that the rule doesn't list
+	Extra []string
+}
+
+// Drifted checks if the file's recipients no longer follow .sops.yaml
+func (d RecipientDrift) Drifted() bool {
+	return d.PathRegex == "" || len(d.Missing) > 0 || len(d.Extra) > 0
+}
+
+// CompareRecipients compares the recipients of an encrypted file with its creation rule
+// The file is given by its path relative to .sops.yaml.
+func CompareRecipients(config *SopsConfig, relPath string, fileRecipients []string) RecipientDrift {
+	rule, found := MatchingRule(config, relPath)
+	return CompareRuleRecipients(relPath, rule, found, fileRecipients)
+}
+
+// CompareRuleRecipients compares the recipients of an encrypted file with a given rule
+// Use it with the effective rule of nested .sops.yaml files.
+func CompareRuleRecipients(relPath string, rule CreationRule, found bool, fileRecipients []string) RecipientDrift {
+	drift := RecipientDrift{Path: relPath}
+	if !found {
+		return drift
+	}
+	drift.PathRegex = rule.PathRegex
+
+	fileRecipients = UniqueRecipients(fileRecipients)
+	for _, recipient := range rule.Age {
+		if !slices.Contains(fileRecipients, recipient) {
+			drift.Missing = append(drift.Missing, recipient)
+		}
+	}
+	for _, recipient := range fileRecipients {
+		if !slices.Contains(rule.Age, recipient) {
+			drift.Extra = append(drift.Extra, recipient)
+		}
+	}
+
+	return drift
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// EncryptedRegexDefault is the encrypted_regex given to new rules for matching files
+type EncryptedRegexDefault struct {
+	// Match is a file extension such as .env, or a gitignore-style pattern
+	// relative to the directory holding .sops.yaml such as k8s/
+	Match string `yaml:"match"`
+	// EncryptedRegex selects the keys whose values are encrypted
+	EncryptedRegex string `yaml:"encrypted_regex"`
+}
+
+// EncryptedRegexDefaults are evaluated in order; the first match wins
+type EncryptedRegexDefaults []EncryptedRegexDefault
+
+// isExtension checks if the match is a file extension rather than a pattern
+func (d EncryptedRegexDefault) isExtension() bool {
+	return strings.HasPrefix(d.Match, ".") && !strings.ContainsAny(d.Match, `/*?[\`)
+}
+
+// matches checks if a file, given by its path relative to .sops.yaml, matches
+func (d EncryptedRegexDefault) matches(relPath string) bool {
+	if d.isExtension() {
+		return strings.EqualFold(filepath.Ext(relPath), d.Match)
+	}
+
+	matcher, err := NewIgnoreMatcher("/", d.Match)
+	if err != nil {
+		return false
+	}
+	return matcher.Ignored("/"+filepath.ToSlash(relPath), false)
+}
+
+// Validate checks that every entry has a pattern and a valid regular expression
+func (d EncryptedRegexDefaults) Validate() error {
+	for _, entry := range d {
+		if entry.Match == "" {
+			return fmt.Errorf("encrypted_regex_defaults: match is required")
+		}
+		if _, err := NewIgnoreMatcher("/", entry.Match); err != nil {
+			return fmt.Errorf("encrypted_regex_defaults: %w", err)
+		}
+		if _, err := regexp.Compile(entry.EncryptedRegex); err != nil {
+			return fmt.Errorf("encrypted_regex_defaults: invalid encrypted_regex for %s: %w", entry.Match, err)
+		}
+	}
+	return nil
+}
+
+// For returns the encrypted_regex for a file, given by its path relative to
+// .sops.yaml, or an empty string if no entry matches
+func (d EncryptedRegexDefaults) For(relPath string) string {
+	for _, entry := range d {
+		if entry.matches(relPath) {
+			return entry.EncryptedRegex
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables that configure simple-sops without a config file
+const (
+	// ConfigEnvVar is the path of the config file to use instead of the default
+	ConfigEnvVar = "SIMPLE_SOPS_CONFIG"
+	// KeyFileEnvVar overrides key_file, even the one of the active profile
+	KeyFileEnvVar = "SIMPLE_SOPS_KEY_FILE"
+	// OnePasswordItemEnvVar overrides onepassword_item
+	OnePasswordItemEnvVar = "SIMPLE_SOPS_OP_ITEM"
+	// NonInteractiveEnvVar overrides non_interactive
+	NonInteractiveEnvVar = "SIMPLE_SOPS_NON_INTERACTIVE"
+)
+
+// envBinding maps an environment variable onto a setting of the application config
+type envBinding struct {
+	name  string
+	apply func(appConfig *AppConfig, value string) error
+}
+
+// envBindings are the settings the environment overrides, after profiles are applied
+// SIMPLE_SOPS_PROFILE is applied before, since it selects the profile.
+var envBindings = []envBinding{
+	{KeyFileEnvVar, func(appConfig *AppConfig, value string) error {
+		appConfig.KeyFile = value
+		return nil
+	}},
+	{OnePasswordItemEnvVar, func(appConfig *AppConfig, value string) error {
+		appConfig.OnePasswordItem = value
+		return nil
+	}},
+	{NonInteractiveEnvVar, func(appConfig *AppConfig, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		appConfig.NonInteractive = enabled
+		return nil
+	}},
+}
+
+// applyEnv overrides settings of the application config with the environment
+// Empty variables are ignored, so they can't unset a setting.
+func applyEnv(appConfig *AppConfig) error {
+	for _, binding := range envBindings {
+		value := os.Getenv(binding.name)
+		if value == "" {
+			continue
+		}
+		if err := binding.apply(appConfig, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", binding.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := `profile: work
+profiles:
+  work:
+    key_file: /keys/work.txt
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(KeyFileEnvVar, "/keys/ci.txt")
+	t.Setenv(OnePasswordItemEnvVar, "op://CI/sops/key")
+	t.Setenv(NonInteractiveEnvVar, "true")
+
+	// The environment wins over the profile
+	appConfig, err := LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if appConfig.KeyFile != "/keys/ci.txt" {
+		t.Errorf("Expected key file from the environment, got %s", appConfig.KeyFile)
+	}
+	if appConfig.OnePasswordItem != "op://CI/sops/key" {
+		t.Errorf("Expected 1Password item from the environment, got %s", appConfig.OnePasswordItem)
+	}
+	if !appConfig.NonInteractive {
+		t.Error("Expected non-interactive mode from the environment")
+	}
+
+	// The environment applies without a config file
+	appConfig, err = LoadConfigFile(filepath.Join(tempDir, "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if appConfig.KeyFile != "/keys/ci.txt" {
+		t.Errorf("Expected key file from the environment without a config file, got %s", appConfig.KeyFile)
+	}
+
+	t.Setenv(NonInteractiveEnvVar, "maybe")
+	if _, err := LoadConfigFile(configPath); err == nil {
+		t.Error("Expected error for an invalid boolean, got nil")
+	}
+
+	// The config file path can be replaced
+	t.Setenv(ConfigEnvVar, configPath)
+	path, err := GetConfigFilePath()
+	if err != nil || path != configPath {
+		t.Errorf("GetConfigFilePath() = %s, %v, want %s", path, err, configPath)
+	}
+}
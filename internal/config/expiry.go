@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExpiryFileName is the path of the expiry dates relative to the repository root
+const ExpiryFileName = ".simple-sops/expiry.yaml"
+
+// ExpiryDateFormat is the format of expiry dates
+const ExpiryDateFormat = "2006-01-02"
+
+// DefaultExpiryWarning is how long before their expiry secrets are reported
+const DefaultExpiryWarning = "30d"
+
+// Expiries are the expiry dates of values in encrypted files
+// They are kept in a committed, unencrypted file next to .sops.yaml, so status
+// can report expiring certificates and tokens without decrypting anything.
+type Expiries struct {
+	Secrets []SecretExpiry `yaml:"secrets"`
+}
+
+// SecretExpiry is the expiry date of one value
+type SecretExpiry struct {
+	// File is the encrypted file relative to the repository root
+	File string `yaml:"file"`
+	// Path is the sops tree path of the value, e.g. ["tls"]["crt"]
+	Path string `yaml:"path"`
+	// Expires is the date the value expires on
+	Expires string `yaml:"expires"`
+	// Note describes the value, e.g. who issued it
+	Note string `yaml:"note,omitempty"`
+}
+
+// ExpiringSecret is a value that expired or expires soon
+type ExpiringSecret struct {
+	SecretExpiry
+	ExpiresAt time.Time
+	Expired   bool
+}
+
+// LoadExpiries loads the expiry dates of the repository at root
+// A missing file results in no expiry dates.
+func LoadExpiries(root string) (*Expiries, error) {
+	path := filepath.Join(root, filepath.FromSlash(ExpiryFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Expiries{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ExpiryFileName, err)
+	}
+
+	var expiries Expiries
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&expiries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ExpiryFileName, err)
+	}
+
+	if err := expiries.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ExpiryFileName, err)
+	}
+	return &expiries, nil
+}
+
+// SaveExpiries saves the expiry dates of the repository at root
+func SaveExpiries(root string, expiries *Expiries) error {
+	path := filepath.Join(root, filepath.FromSlash(ExpiryFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(expiries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", ExpiryFileName, err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ExpiryFileName, err)
+	}
+	return nil
+}
+
+// Validate checks that every entry names a value and has a valid date
+func (e *Expiries) Validate() error {
+	seen := make(map[string]bool)
+	for i, secret := range e.Secrets {
+		if secret.File == "" || secret.Path == "" {
+			return fmt.Errorf("secrets[%d]: file and path are required", i)
+		}
+		if _, err := secret.ExpiresAt(); err != nil {
+			return fmt.Errorf("secrets[%d]: %w", i, err)
+		}
+
+		key := secret.File + secret.Path
+		if seen[key] {
+			return fmt.Errorf("%s of %s is listed more than once", secret.Path, secret.File)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ExpiresAt parses the expiry date
+func (s SecretExpiry) ExpiresAt() (time.Time, error) {
+	expires, err := time.Parse(ExpiryDateFormat, s.Expires)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry date %q, expected YYYY-MM-DD", s.Expires)
+	}
+	return expires, nil
+}
+
+// Set sets the expiry date of a value, replacing an existing one
+func (e *Expiries) Set(file string, path string, expires time.Time, note string) {
+	entry := SecretExpiry{File: filepath.ToSlash(file), Path: path, Expires: expires.Format(ExpiryDateFormat), Note: note}
+	for i, secret := range e.Secrets {
+		if secret.File == entry.File && secret.Path == path {
+			e.Secrets[i] = entry
+			return
+		}
+	}
+
+	e.Secrets = append(e.Secrets, entry)
+	sort.SliceStable(e.Secrets, func(i, j int) bool {
+		if e.Secrets[i].File != e.Secrets[j].File {
+			return e.Secrets[i].File < e.Secrets[j].File
+		}
+		return e.Secrets[i].Path < e.Secrets[j].Path
+	})
+}
+
+// Remove removes the expiry date of a value, reporting whether it had one
+func (e *Expiries) Remove(file string, path string) bool {
+	file = filepath.ToSlash(file)
+	for i, secret := range e.Secrets {
+		if secret.File == file && secret.Path == path {
+			e.Secrets = append(e.Secrets[:i], e.Secrets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ForFile returns the expiry dates of the values of a file
+func (e *Expiries) ForFile(file string) []SecretExpiry {
+	file = filepath.ToSlash(file)
+	var secrets []SecretExpiry
+	for _, secret := range e.Secrets {
+		if secret.File == file {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// Expiring returns the values that expired or expire within the warning period
+// A value expires at the start of its expiry date.
+func (e *Expiries) Expiring(now time.Time, within time.Duration) []ExpiringSecret {
+	var expiring []ExpiringSecret
+	for _, secret := range e.Secrets {
+		expiresAt, err := secret.ExpiresAt()
+		if err != nil {
+			continue
+		}
+		if expiresAt.After(now.Add(within)) {
+			continue
+		}
+		expiring = append(expiring, ExpiringSecret{SecretExpiry: secret, ExpiresAt: expiresAt, Expired: !expiresAt.After(now)})
+	}
+	return expiring
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpiries(t *testing.T) {
+	root := t.TempDir()
+
+	// A missing file has no expiry dates
+	expiries, err := LoadExpiries(root)
+	if err != nil {
+		t.Fatalf("LoadExpiries failed: %v", err)
+	}
+	if len(expiries.Secrets) != 0 {
+		t.Fatalf("Expected no expiry dates, got %v", expiries.Secrets)
+	}
+
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse(ExpiryDateFormat, value)
+		return parsed
+	}
+	expiries.Set("tls.enc.yaml", `["tls"]["crt"]`, date("2025-03-01"), "Let's Encrypt")
+	expiries.Set("api.enc.yaml", `["token"]`, date("2025-06-01"), "")
+	expiries.Set("tls.enc.yaml", `["tls"]["crt"]`, date("2025-02-01"), "Let's Encrypt")
+
+	if err := SaveExpiries(root, expiries); err != nil {
+		t.Fatalf("SaveExpiries failed: %v", err)
+	}
+	loaded, err := LoadExpiries(root)
+	if err != nil {
+		t.Fatalf("LoadExpiries failed: %v", err)
+	}
+	if len(loaded.Secrets) != 2 || loaded.Secrets[0].File != "api.enc.yaml" || loaded.Secrets[1].Expires != "2025-02-01" {
+		t.Errorf("Unexpected expiry dates: %+v", loaded.Secrets)
+	}
+	if secrets := loaded.ForFile("tls.enc.yaml"); len(secrets) != 1 || secrets[0].Note != "Let's Encrypt" {
+		t.Errorf("Unexpected expiry dates of tls.enc.yaml: %+v", secrets)
+	}
+
+	// The certificate expired, the token expires within 90 days
+	now := date("2025-03-15")
+	if expiring := loaded.Expiring(now, 30*24*time.Hour); len(expiring) != 1 || !expiring[0].Expired {
+		t.Errorf("Expected the certificate to be expired, got %+v", expiring)
+	}
+	if expiring := loaded.Expiring(now, 90*24*time.Hour); len(expiring) != 2 || expiring[0].Expired {
+		t.Errorf("Expected the token to expire soon, got %+v", expiring)
+	}
+
+	if !loaded.Remove("tls.enc.yaml", `["tls"]["crt"]`) || loaded.Remove("tls.enc.yaml", `["tls"]["crt"]`) {
+		t.Error("Expected the expiry date to be removed once")
+	}
+}
+
+func TestLoadExpiriesInvalid(t *testing.T) {
+	invalid := []string{
+		"secrets:\n  - file: a.enc.yaml\n    path: '[\"a\"]'\n    expires: next week\n",
+		"secrets:\n  - file: a.enc.yaml\n    expires: 2025-01-01\n",
+		"secrets:\n  - file: a.enc.yaml\n    path: '[\"a\"]'\n    expires: 2025-01-01\n  - file: a.enc.yaml\n    path: '[\"a\"]'\n    expires: 2025-02-01\n",
+		"secret: []\n",
+	}
+
+	for _, content := range invalid {
+		root := t.TempDir()
+		path := filepath.Join(root, filepath.FromSlash(ExpiryFileName))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadExpiries(root); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
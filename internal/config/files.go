@@ -0,0 +1,85 @@
+package config
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// SupportedExtensions are the file types that are encrypted when a directory is given
+var SupportedExtensions = []string{".yaml", ".yml", ".json", ".ini", ".env", ".toml", ".properties"}
+
+// IsSupportedFile checks if a file has one of the supported extensions
+func IsSupportedFile(path string) bool {
+	ext := filepath.Ext(path)
+	for _, supportedExt := range SupportedExtensions {
+		if strings.EqualFold(ext, supportedExt) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkFiles walks a directory tree and calls fn for every regular file
+// The .git directory, .sops.yaml and paths excluded by the ignore matcher are skipped.
+func WalkFiles(root string, ignore *IgnoreMatcher, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" || ignore.Ignored(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || d.Name() == ".sops.yaml" || d.Name() == IgnoreFileName {
+			return nil
+		}
+
+		if ignore.Ignored(path, false) {
+			return nil
+		}
+
+		return fn(path)
+	})
+}
+
+// FindEncryptedFiles walks a directory tree and returns all SOPS-encrypted files
+// Paths listed in the .sopsignore file in root are skipped, as are files not
+// matching the patterns of the active project.
+func FindEncryptedFiles(root string) ([]string, error) {
+	ignore, err := LoadIgnoreFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = WalkFiles(root, ignore, func(path string) error {
+		if filePatterns != nil && !filePatterns.Ignored(path, false) {
+			return nil
+		}
+		if IsFileEncrypted(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// FindFilesToEncrypt walks a directory tree and returns all supported files
+// that are not encrypted yet and not excluded by the ignore matcher
+func FindFilesToEncrypt(dir string, ignore *IgnoreMatcher) ([]string, error) {
+	var files []string
+	err := WalkFiles(dir, ignore, func(path string) error {
+		if IsSupportedFile(path) && !IsFileEncrypted(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hook stages
+const (
+	// HookPre runs before an operation, a failing hook aborts it
+	HookPre = "pre"
+	// HookPost runs after an operation, whether it succeeded or not
+	HookPost = "post"
+)
+
+// HookSettings are external commands run before and after operations
+// Each command runs through the shell, e.g. to notify a chat or to require a
+// ticket ID from the environment before secrets are decrypted.
+type HookSettings struct {
+	PreDecrypt  []string `yaml:"pre_decrypt,omitempty"`
+	PostDecrypt []string `yaml:"post_decrypt,omitempty"`
+	PreRotate   []string `yaml:"pre_rotate,omitempty"`
+	PostRotate  []string `yaml:"post_rotate,omitempty"`
+}
+
+// Validate checks that no hook command is empty
+func (h HookSettings) Validate() error {
+	hooks := map[string][]string{
+		"pre_decrypt":  h.PreDecrypt,
+		"post_decrypt": h.PostDecrypt,
+		"pre_rotate":   h.PreRotate,
+		"post_rotate":  h.PostRotate,
+	}
+	for name, commands := range hooks {
+		for i, command := range commands {
+			if strings.TrimSpace(command) == "" {
+				return fmt.Errorf("hooks: %s[%d] is empty", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// Commands returns the hook commands of an operation and stage
+func (h HookSettings) Commands(operation string, stage string) []string {
+	switch operation + "_" + stage {
+	case "decrypt_pre":
+		return h.PreDecrypt
+	case "decrypt_post":
+		return h.PostDecrypt
+	case "rotate_pre":
+		return h.PreRotate
+	case "rotate_post":
+		return h.PostRotate
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file listing paths excluded from secret handling
+const IgnoreFileName = ".sopsignore"
+
+// ignoreRule is a single pattern of an ignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher matches paths against the patterns of a .sopsignore file
+// Patterns use gitignore syntax and are relative to the directory holding the file.
+type IgnoreMatcher struct {
+	base  string
+	rules []ignoreRule
+}
+
+// LoadIgnoreFile loads the .sopsignore file in dir
+// A missing file results in a matcher that ignores nothing.
+func LoadIgnoreFile(dir string) (*IgnoreMatcher, error) {
+	matcher := &IgnoreMatcher{base: dir}
+
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return matcher, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := matcher.AddPattern(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", IgnoreFileName, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+
+	return matcher, nil
+}
+
+// NewIgnoreMatcher creates a matcher for paths under base from gitignore-style patterns
+func NewIgnoreMatcher(base string, patterns ...string) (*IgnoreMatcher, error) {
+	matcher := &IgnoreMatcher{base: base}
+	for _, pattern := range patterns {
+		if err := matcher.AddPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return matcher, nil
+}
+
+// AddPattern adds a gitignore-style pattern
+// Blank lines and comments are skipped.
+func (m *IgnoreMatcher) AddPattern(line string) error {
+	// Trailing spaces are ignored unless escaped
+	line = strings.TrimRight(line, " \t")
+	if strings.HasSuffix(line, "\\") {
+		line += " "
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// Patterns containing a slash are relative to the base directory,
+	// others match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return nil
+	}
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "(^|/)" + expr + "$"
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	rule.pattern = pattern
+
+	m.rules = append(m.rules, rule)
+	return nil
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				rest := glob[i+2:]
+				switch {
+				case strings.HasPrefix(rest, "/"):
+					// "**/" matches zero or more directories
+					expr.WriteString("(.*/)?")
+					i += 2
+				case rest == "":
+					expr.WriteString(".*")
+					i++
+				default:
+					expr.WriteString("[^/]*")
+					i++
+				}
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// Ignored reports whether a path is excluded by the ignore patterns
+// A path is also excluded if one of its parent directories is.
+func (m *IgnoreMatcher) Ignored(path string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+
+	rel, err := filepath.Rel(m.base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// Files in an excluded directory can't be included again, as in git
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return m.matches(rel, isDir)
+}
+
+// matches applies the rules to a relative path; the last matching rule wins
+func (m *IgnoreMatcher) matches(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	base := "/repo"
+	matcher, err := NewIgnoreMatcher(base,
+		"# vendored code",
+		"vendor/",
+		"/build",
+		"*.fixture.yaml",
+		"testdata/**/*.env",
+		"!testdata/keep/real.env",
+		"\\#literal",
+	)
+	if err != nil {
+		t.Fatalf("NewIgnoreMatcher failed: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"vendor", true, true},
+		{"vendor/lib/secrets.yaml", false, true},
+		{"nested/vendor/app.env", false, true},
+		{"vendor", false, false}, // Directory-only pattern
+		{"build/out.json", false, true},
+		{"nested/build/out.json", false, false}, // Anchored pattern
+		{"app.fixture.yaml", false, true},
+		{"deep/dir/app.fixture.yaml", false, true},
+		{"app.yaml", false, false},
+		{"testdata/a.env", false, true},
+		{"testdata/x/y/a.env", false, true},
+		{"testdata/keep/real.env", false, false},
+		{"#literal", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := matcher.Ignored(filepath.Join(base, tt.path), tt.isDir); got != tt.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, expected %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+
+	// Paths outside the base directory are never ignored
+	if matcher.Ignored("/other/vendor/app.env", false) {
+		t.Error("Expected paths outside the base directory not to be ignored")
+	}
+
+	// A nil matcher ignores nothing
+	var empty *IgnoreMatcher
+	if empty.Ignored("/repo/vendor/app.env", false) {
+		t.Error("Expected nil matcher to ignore nothing")
+	}
+}
+
+func TestFindFilesRespectsIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"secrets.yaml":              "password: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.8.1\n",
+		"vendor/lib/secrets.yaml":   "password: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.8.1\n",
+		"app.env":                   "TOKEN=plain",
+		"fixtures/test.env":         "TOKEN=plain",
+		IgnoreFileName:              "vendor/\nfixtures/\n",
+		"notes.txt":                 "not a supported file",
+		"already/encrypted.json":    `{"data": "ENC[AES256_GCM,data:abc]"}`,
+		"already/not-encrypted.ini": "key=value",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	encrypted, err := FindEncryptedFiles(root)
+	if err != nil {
+		t.Fatalf("FindEncryptedFiles failed: %v", err)
+	}
+	expected := []string{filepath.Join(root, "already/encrypted.json"), filepath.Join(root, "secrets.yaml")}
+	if !slices.Equal(encrypted, expected) {
+		t.Errorf("Expected encrypted files %v, got %v", expected, encrypted)
+	}
+
+	ignore, err := LoadIgnoreFile(root)
+	if err != nil {
+		t.Fatalf("LoadIgnoreFile failed: %v", err)
+	}
+	toEncrypt, err := FindFilesToEncrypt(root, ignore)
+	if err != nil {
+		t.Fatalf("FindFilesToEncrypt failed: %v", err)
+	}
+	expected = []string{filepath.Join(root, "already/not-encrypted.ini"), filepath.Join(root, "app.env")}
+	if !slices.Equal(toEncrypt, expected) {
+		t.Errorf("Expected files to encrypt %v, got %v", expected, toEncrypt)
+	}
+}
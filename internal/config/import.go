@@ -0,0 +1,49 @@
+package config
+
+import (
+	"simple-sops/pkg/logging"
+)
+
+// ImportedRule is a creation rule synthesized from the metadata of an encrypted file
+type ImportedRule struct {
+	// Path is the path of the file relative to .sops.yaml
+	Path string
+	// Recipients are the Age recipients the file is encrypted to
+	Recipients []string
+	// EncryptedRegex is the encrypted_regex the file was encrypted with, if any
+	EncryptedRegex string
+}
+
+// ImportRules adds a rule for every encrypted file no rule covers yet
+// Each rule gets the recipients and encrypted_regex from the file's sops
+// metadata. Files only matched by the catch-all rule get their own rule, which
+// is added before it. Files without metadata or Age recipients are skipped.
+func ImportRules(sopsConfig *SopsConfig, configPath string, files []string) ([]ImportedRule, error) {
+	var imported []ImportedRule
+	for _, file := range files {
+		relPath := ConfigRelativePath(configPath, file)
+		if rule, covered := CoveringRule(sopsConfig, relPath); covered {
+			logging.Debug("Skipping %s, it is covered by rule %s", relPath, rule.PathRegex)
+			continue
+		}
+
+		metadata, err := ReadFileMetadata(file)
+		if err != nil {
+			logging.Debug("Skipping %s: %v", relPath, err)
+			continue
+		}
+		if len(metadata.Recipients) == 0 {
+			logging.Warn("Skipping %s, it has no Age recipients", relPath)
+			continue
+		}
+
+		recipients := UniqueRecipients(metadata.Recipients)
+		if err := AddCreationRuleWithMultipleKeys(sopsConfig, relPath, recipients, metadata.EncryptedRegex, WildcardPolicy{Mode: WildcardNone}); err != nil {
+			return nil, err
+		}
+
+		imported = append(imported, ImportedRule{Path: relPath, Recipients: recipients, EncryptedRegex: metadata.EncryptedRegex})
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetKeyFile sets the key file of a profile in an application config file
+// Without a profile the top-level key_file is set. Comments and the order of
+// settings are kept, missing sections are added.
+func SetKeyFile(data []byte, profile string, keyFile string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file is not a mapping")
+	}
+
+	if profile != "" {
+		profiles, err := mappingEntry(mapping, "profiles")
+		if err != nil {
+			return nil, err
+		}
+		if mapping, err = mappingEntry(profiles, profile); err != nil {
+			return nil, err
+		}
+	}
+	setScalar(mapping, "key_file", keyFile)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveKeyFile sets the key file of a profile in the application config file
+// A missing config file is created.
+func SaveKeyFile(configPath string, profile string, keyFile string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	updated, err := SetKeyFile(data, profile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", configPath, err)
+	}
+	if err := os.WriteFile(configPath, updated, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// mappingEntry returns the mapping under a key, adding an empty one if it is missing
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, error) {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			*value = yaml.Node{Kind: yaml.MappingNode}
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a mapping", key)
+		}
+		return value, nil
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value, nil
+}
+
+// setScalar sets a string value in a mapping, adding the key if it is missing
+func setScalar(mapping *yaml.Node, key string, value string) {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			// Keep the comments of the old value
+			node := mapping.Content[i+1]
+			node.Kind, node.Tag, node.Style, node.Value, node.Content = yaml.ScalarNode, "", 0, value, nil
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
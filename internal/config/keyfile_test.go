@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetKeyFile(t *testing.T) {
+	data := []byte(`# Personal settings
+key_file: ~/.config/simple-sops/key.txt # software key
+profile: work
+profiles:
+  work:
+    key_file: ~/.keys/work.txt
+`)
+
+	updated, err := SetKeyFile(data, "work", "/home/me/yubikey.txt")
+	if err != nil {
+		t.Fatalf("SetKeyFile() error = %v", err)
+	}
+	appConfig := loadTestConfig(t, updated)
+	if got := appConfig.Profiles["work"].KeyFile; got != "/home/me/yubikey.txt" {
+		t.Errorf("work key_file = %q", got)
+	}
+	if !strings.Contains(string(updated), "# Personal settings") || !strings.Contains(string(updated), "# software key") {
+		t.Errorf("comments were lost:\n%s", updated)
+	}
+
+	// A missing profile is added, without one the top-level key is set
+	updated, err = SetKeyFile(updated, "home", "/home/me/home.txt")
+	if err != nil {
+		t.Fatalf("SetKeyFile() error = %v", err)
+	}
+	if got := loadTestConfig(t, updated).Profiles["home"]; got == nil || got.KeyFile != "/home/me/home.txt" {
+		t.Errorf("home profile = %+v", got)
+	}
+
+	updated, err = SetKeyFile(nil, "", "/home/me/yubikey.txt")
+	if err != nil {
+		t.Fatalf("SetKeyFile() error = %v", err)
+	}
+	if got := loadTestConfig(t, updated).KeyFile; got != "/home/me/yubikey.txt" {
+		t.Errorf("key_file = %q", got)
+	}
+}
+
+// loadTestConfig loads an application config from content
+func loadTestConfig(t *testing.T, data []byte) *AppConfig {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	appConfig, err := LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v\n%s", err, data)
+	}
+	return appConfig
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintFileName is the path of the lint settings relative to the repository root
+// Linting is opt-in: values are only checked if the file exists.
+const LintFileName = ".simple-sops/lint.yaml"
+
+// Defaults of the lint settings
+const (
+	DefaultLintMinLength = 12
+	DefaultLintKeys      = `(?i)(pass|pwd|secret|token|api_?key|private_?key|credential)`
+)
+
+// LintSettings control the strength checks of secret values on encrypt and edit
+type LintSettings struct {
+	// MinLength is the minimum length of a secret value
+	MinLength int `yaml:"min_length,omitempty"`
+	// Keys is a regular expression selecting the values to check by their key
+	Keys string `yaml:"keys,omitempty"`
+	// Fail aborts encryption on weak values instead of warning
+	Fail bool `yaml:"fail,omitempty"`
+	// Ignore lists the paths of values that are never reported, e.g. ["db"]["password"]
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// LoadLintSettings loads the lint settings of the repository at root
+// It reports false if the repository didn't opt in to linting.
+func LoadLintSettings(root string) (*LintSettings, bool, error) {
+	path := filepath.Join(root, filepath.FromSlash(LintFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", LintFileName, err)
+	}
+
+	settings := &LintSettings{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(settings); err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", LintFileName, err)
+	}
+
+	if settings.MinLength == 0 {
+		settings.MinLength = DefaultLintMinLength
+	}
+	if settings.Keys == "" {
+		settings.Keys = DefaultLintKeys
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %w", LintFileName, err)
+	}
+	return settings, true, nil
+}
+
+// Validate checks the minimum length and the key pattern
+func (s *LintSettings) Validate() error {
+	if s.MinLength < 0 {
+		return fmt.Errorf("min_length must not be negative")
+	}
+	if _, err := regexp.Compile(s.Keys); err != nil {
+		return fmt.Errorf("keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLintSettings(t *testing.T) {
+	root := t.TempDir()
+
+	// Linting is opt-in
+	if _, enabled, err := LoadLintSettings(root); err != nil || enabled {
+		t.Fatalf("Expected linting to be disabled without %s, got %v, %v", LintFileName, enabled, err)
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(LintFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty file enables linting with the defaults
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, enabled, err := LoadLintSettings(root)
+	if err != nil || !enabled {
+		t.Fatalf("Expected linting to be enabled, got %v, %v", enabled, err)
+	}
+	if settings.MinLength != DefaultLintMinLength || settings.Keys != DefaultLintKeys {
+		t.Errorf("Expected the defaults, got %+v", settings)
+	}
+
+	for _, invalid := range []string{"keys: '('\n", "min_length: -1\n", "minimum: 8\n"} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadLintSettings(root); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares the encrypted files of a repository
+// apply reconciles .sops.yaml and the files with it.
+type Manifest struct {
+	Files    []ManifestFile    `yaml:"files"`
+	Patterns []ManifestPattern `yaml:"patterns,omitempty"`
+}
+
+// ManifestFile is a file that must be encrypted to the given recipients
+type ManifestFile struct {
+	// Path is the path of the file relative to .sops.yaml
+	Path string `yaml:"path"`
+	// Recipients are the Age recipients of the file's rule
+	Recipients []string `yaml:"recipients"`
+	// EncryptedRegex selects the encrypted values, all values if empty
+	EncryptedRegex string `yaml:"encrypted_regex,omitempty"`
+}
+
+// ManifestPattern is a rule for every file matching a path regex
+type ManifestPattern struct {
+	// PathRegex is the path_regex of the rule
+	PathRegex string `yaml:"path_regex"`
+	// Recipients are the Age recipients of the rule
+	Recipients []string `yaml:"recipients"`
+	// EncryptedRegex selects the encrypted values, all values if empty
+	EncryptedRegex string `yaml:"encrypted_regex,omitempty"`
+}
+
+// LoadManifest reads and validates an operations manifest
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Validate checks that every file and pattern is unique and has recipients and a valid encrypted_regex
+func (m *Manifest) Validate() error {
+	seen := make(map[string]bool)
+	for i, file := range m.Files {
+		if file.Path == "" {
+			return fmt.Errorf("files[%d]: path is required", i)
+		}
+		if filepath.IsAbs(file.Path) {
+			return fmt.Errorf("%s: path must be relative to .sops.yaml", file.Path)
+		}
+
+		path := filepath.ToSlash(filepath.Clean(file.Path))
+		if seen[path] {
+			return fmt.Errorf("%s: listed more than once", file.Path)
+		}
+		seen[path] = true
+
+		if len(UniqueRecipients(file.Recipients)) == 0 {
+			return fmt.Errorf("%s: recipients are required", file.Path)
+		}
+		if file.EncryptedRegex != "" {
+			if _, err := regexp.Compile(file.EncryptedRegex); err != nil {
+				return fmt.Errorf("%s: invalid encrypted_regex: %w", file.Path, err)
+			}
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, pattern := range m.Patterns {
+		if pattern.PathRegex == "" {
+			return fmt.Errorf("patterns[%d]: path_regex is required", i)
+		}
+		if _, err := regexp.Compile(pattern.PathRegex); err != nil {
+			return fmt.Errorf("%s: invalid path_regex: %w", pattern.PathRegex, err)
+		}
+		if seen[pattern.PathRegex] {
+			return fmt.Errorf("%s: listed more than once", pattern.PathRegex)
+		}
+		seen[pattern.PathRegex] = true
+
+		if len(UniqueRecipients(pattern.Recipients)) == 0 {
+			return fmt.Errorf("%s: recipients are required", pattern.PathRegex)
+		}
+		if pattern.EncryptedRegex != "" {
+			if _, err := regexp.Compile(pattern.EncryptedRegex); err != nil {
+				return fmt.Errorf("%s: invalid encrypted_regex: %w", pattern.PathRegex, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Conflicts returns the rules of the manifest that are shadowed by an earlier rule
+func (m *Manifest) Conflicts(sopsConfig *SopsConfig) []RuleConflict {
+	declared := make(map[string]bool)
+	for _, file := range m.Files {
+		declared[RulePathRegex(filepath.ToSlash(filepath.Clean(file.Path)))] = true
+	}
+	for _, pattern := range m.Patterns {
+		declared[pattern.PathRegex] = true
+	}
+
+	var conflicts []RuleConflict
+	for _, conflict := range FindConflicts(sopsConfig) {
+		if declared[conflict.Rule.PathRegex] {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// ApplyManifest makes the rules of a config match the manifest
+// Rules are added or updated for every file and pattern of the manifest. New
+// patterns are added before the catch-all rule. With prune, rules for files
+// missing from the manifest are removed. Other patterns and the catch-all rule
+// are left alone. It returns a description of every change.
+func ApplyManifest(sopsConfig *SopsConfig, manifest *Manifest, prune bool) ([]string, error) {
+	var changes []string
+	var paths []string
+	for _, file := range manifest.Files {
+		path := filepath.ToSlash(filepath.Clean(file.Path))
+		paths = append(paths, path)
+		recipients := AgeRecipients(UniqueRecipients(file.Recipients))
+
+		rule, exists := GetCreationRule(sopsConfig, path)
+		if !exists {
+			if err := AddCreationRuleWithMultipleKeys(sopsConfig, path, recipients, file.EncryptedRegex, WildcardPolicy{Mode: WildcardNone}); err != nil {
+				return nil, err
+			}
+			changes = append(changes, fmt.Sprintf("added rule for %s with recipients %s", path, recipients))
+			continue
+		}
+
+		if slices.Equal(rule.Age, recipients) && rule.EncryptedRegex == file.EncryptedRegex && rule.PathRegex == RulePathRegex(path) {
+			continue
+		}
+
+		for i := range sopsConfig.CreationRules {
+			if ruleMatchesFile(sopsConfig.CreationRules[i], path) {
+				sopsConfig.CreationRules[i].PathRegex = RulePathRegex(path)
+				sopsConfig.CreationRules[i].Age = recipients
+				sopsConfig.CreationRules[i].EncryptedRegex = file.EncryptedRegex
+				break
+			}
+		}
+		changes = append(changes, fmt.Sprintf("updated rule for %s to recipients %s", path, recipients))
+	}
+
+	for _, pattern := range manifest.Patterns {
+		recipients := AgeRecipients(UniqueRecipients(pattern.Recipients))
+		index := slices.IndexFunc(sopsConfig.CreationRules, func(rule CreationRule) bool { return rule.PathRegex == pattern.PathRegex })
+		if index < 0 {
+			rule := CreationRule{PathRegex: pattern.PathRegex, Age: recipients, EncryptedRegex: pattern.EncryptedRegex}
+			catchAll := slices.IndexFunc(sopsConfig.CreationRules, func(rule CreationRule) bool { return rulePriority(rule) == 2 })
+			if catchAll < 0 {
+				catchAll = len(sopsConfig.CreationRules)
+			}
+			sopsConfig.CreationRules = slices.Insert(sopsConfig.CreationRules, catchAll, rule)
+			changes = append(changes, fmt.Sprintf("added rule for pattern %s with recipients %s", pattern.PathRegex, recipients))
+			continue
+		}
+
+		rule := &sopsConfig.CreationRules[index]
+		if slices.Equal(rule.Age, recipients) && rule.EncryptedRegex == pattern.EncryptedRegex {
+			continue
+		}
+		rule.Age = recipients
+		rule.EncryptedRegex = pattern.EncryptedRegex
+		changes = append(changes, fmt.Sprintf("updated rule for pattern %s to recipients %s", pattern.PathRegex, recipients))
+	}
+
+	if prune {
+		var rules []CreationRule
+		for _, rule := range sopsConfig.CreationRules {
+			path, isFileRule := RulePath(rule.PathRegex)
+			if isFileRule && !slices.ContainsFunc(paths, func(p string) bool { return ruleMatchesFile(rule, p) }) {
+				changes = append(changes, fmt.Sprintf("removed rule for %s, it isn't in the manifest", path))
+				continue
+			}
+			rules = append(rules, rule)
+		}
+		sopsConfig.CreationRules = rules
+	}
+
+	return changes, nil
+}
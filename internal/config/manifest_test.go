@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			manifest: "files:\n  - path: secrets.yaml\n    recipients: [age1alice]\n    encrypted_regex: ^data$\n",
+		},
+		{
+			name:     "missing recipients",
+			manifest: "files:\n  - path: secrets.yaml\n",
+			wantErr:  "recipients are required",
+		},
+		{
+			name:     "duplicate path",
+			manifest: "files:\n  - path: secrets.yaml\n    recipients: [age1alice]\n  - path: ./secrets.yaml\n    recipients: [age1bob]\n",
+			wantErr:  "listed more than once",
+		},
+		{
+			name:     "invalid regex",
+			manifest: "files:\n  - path: secrets.yaml\n    recipients: [age1alice]\n    encrypted_regex: \"(\"\n",
+			wantErr:  "invalid encrypted_regex",
+		},
+		{
+			name:     "unknown field",
+			manifest: "files:\n  - path: secrets.yaml\n    recipient: age1alice\n",
+			wantErr:  "field recipient not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "operations.yaml")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadManifest(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadManifest failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadManifest error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyManifest(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secrets.yaml"), Age: AgeRecipients{"age1alice"}},
+		{PathRegex: RulePathRegex("old.yaml"), Age: AgeRecipients{"age1alice"}},
+		{PathRegex: `\.env$`, Age: AgeRecipients{"age1alice"}},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1alice"}},
+	}}
+	manifest := &Manifest{Files: []ManifestFile{
+		{Path: "secrets.yaml", Recipients: []string{"age1alice", "age1bob"}},
+		{Path: "k8s/secret.yaml", Recipients: []string{"age1alice"}, EncryptedRegex: "^data$"},
+	}}
+
+	changes, err := ApplyManifest(sopsConfig, manifest, true)
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected 3 changes, got %d: %v", len(changes), changes)
+	}
+
+	rule, found := GetCreationRule(sopsConfig, "secrets.yaml")
+	if !found || rule.Age.String() != "age1alice,age1bob" {
+		t.Errorf("Expected updated rule for secrets.yaml, got %+v", rule)
+	}
+	rule, found = GetCreationRule(sopsConfig, "k8s/secret.yaml")
+	if !found || rule.EncryptedRegex != "^data$" {
+		t.Errorf("Expected new rule for k8s/secret.yaml, got %+v", rule)
+	}
+	if _, found := GetCreationRule(sopsConfig, "old.yaml"); found {
+		t.Error("Expected rule for old.yaml to be pruned")
+	}
+	if len(sopsConfig.CreationRules) != 4 {
+		t.Errorf("Expected patterns and the catch-all rule to be kept, got %+v", sopsConfig.CreationRules)
+	}
+
+	// Applying the manifest again changes nothing
+	changes, err = ApplyManifest(sopsConfig, manifest, true)
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+func TestApplyManifestPatterns(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: `\.env$`, Age: AgeRecipients{"age1alice"}},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1alice"}},
+	}}
+	manifest := &Manifest{
+		Files: []ManifestFile{{Path: "services/api/prod.env", Recipients: []string{"age1api"}}},
+		Patterns: []ManifestPattern{
+			{PathRegex: `\.env$`, Recipients: []string{"age1alice", "age1bob"}},
+			{PathRegex: `(^|/)k8s/.*\.yaml$`, Recipients: []string{"age1ops"}, EncryptedRegex: "^data$"},
+		},
+	}
+	if err := manifest.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	changes, err := ApplyManifest(sopsConfig, manifest, false)
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected 3 changes, got %d: %v", len(changes), changes)
+	}
+
+	// New patterns go before the catch-all rule
+	var regexes []string
+	for _, rule := range sopsConfig.CreationRules {
+		regexes = append(regexes, rule.PathRegex)
+	}
+	want := []string{RulePathRegex("services/api/prod.env"), `\.env$`, `(^|/)k8s/.*\.yaml$`, WildcardPattern}
+	if !slices.Equal(regexes, want) {
+		t.Errorf("Expected rules %v, got %v", want, regexes)
+	}
+	if rule := sopsConfig.CreationRules[1]; rule.Age.String() != "age1alice,age1bob" {
+		t.Errorf("Expected updated pattern rule, got %+v", rule)
+	}
+	if conflicts := manifest.Conflicts(sopsConfig); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	// A pattern before a file rule of the manifest shadows it
+	sopsConfig.CreationRules[0], sopsConfig.CreationRules[1] = sopsConfig.CreationRules[1], sopsConfig.CreationRules[0]
+	conflicts := manifest.Conflicts(sopsConfig)
+	if len(conflicts) != 1 || conflicts[0].ShadowedBy.PathRegex != `\.env$` {
+		t.Errorf("Expected the file rule to be shadowed by the .env pattern, got %+v", conflicts)
+	}
+
+	invalid := &Manifest{Patterns: []ManifestPattern{{PathRegex: "(", Recipients: []string{"age1alice"}}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected an invalid path_regex to be rejected")
+	}
+}
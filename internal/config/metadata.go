@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileMetadata represents the sops metadata embedded in an encrypted file
+type FileMetadata struct {
+	// Recipients are the Age recipients the data key is encrypted to
+	Recipients []string
+	// LastModified is when sops last wrote the file
+	LastModified time.Time
+	// Version is the sops version that wrote the file
+	Version string
+	// EncryptedRegex is the encrypted_regex the file was encrypted with, if any
+	EncryptedRegex string
+}
+
+// ageRecipientKey matches flattened recipient keys in dotenv and ini files
+var ageRecipientKey = regexp.MustCompile(`^age__list_\d+__map_recipient$`)
+
+// ReadFileMetadata reads the sops metadata from an encrypted file
+func ReadFileMetadata(filePath string) (*FileMetadata, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParseFileMetadata(data, filePath)
+}
+
+// ParseFileMetadata reads the sops metadata from the content of an encrypted file
+// The name is only used in errors.
+func ParseFileMetadata(data []byte, name string) (*FileMetadata, error) {
+	// YAML and JSON files keep the metadata in a "sops" tree
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err == nil {
+		if sops, ok := document["sops"].(map[string]interface{}); ok {
+			return parseMetadataTree(sops), nil
+		}
+	}
+
+	// Dotenv and ini files keep the metadata in flattened keys
+	metadata := parseFlatMetadata(data)
+	if metadata == nil {
+		return nil, fmt.Errorf("no sops metadata found in %s", name)
+	}
+
+	return metadata, nil
+}
+
+// MissingRecipients returns the required recipients the file is not encrypted to
+func (m *FileMetadata) MissingRecipients(required []string) []string {
+	var missing []string
+	for _, recipient := range UniqueRecipients(required) {
+		if !slices.Contains(m.Recipients, recipient) {
+			missing = append(missing, recipient)
+		}
+	}
+	return missing
+}
+
+// parseMetadataTree parses the metadata of a YAML or JSON file
+func parseMetadataTree(sops map[string]interface{}) *FileMetadata {
+	metadata := &FileMetadata{}
+
+	if ageEntries, ok := sops["age"].([]interface{}); ok {
+		for _, entry := range ageEntries {
+			if ageEntry, ok := entry.(map[string]interface{}); ok {
+				if recipient, ok := ageEntry["recipient"].(string); ok {
+					metadata.Recipients = append(metadata.Recipients, recipient)
+				}
+			}
+		}
+	}
+
+	switch lastModified := sops["lastmodified"].(type) {
+	case time.Time:
+		metadata.LastModified = lastModified
+	case string:
+		metadata.LastModified, _ = time.Parse(time.RFC3339, lastModified)
+	}
+
+	if version, ok := sops["version"].(string); ok {
+		metadata.Version = version
+	}
+
+	if encryptedRegex, ok := sops["encrypted_regex"].(string); ok {
+		metadata.EncryptedRegex = encryptedRegex
+	}
+
+	return metadata
+}
+
+// parseFlatMetadata parses the metadata of a dotenv or ini file
+func parseFlatMetadata(data []byte) *FileMetadata {
+	metadata := &FileMetadata{}
+	found := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	inSopsSection := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Track ini sections, metadata lives in [sops]
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSopsSection = line == "[sops]"
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(key, "sops_") {
+			key = strings.TrimPrefix(key, "sops_")
+		} else if !inSopsSection {
+			continue
+		}
+
+		switch {
+		case ageRecipientKey.MatchString(key):
+			metadata.Recipients = append(metadata.Recipients, value)
+			found = true
+		case key == "lastmodified":
+			metadata.LastModified, _ = time.Parse(time.RFC3339, value)
+			found = true
+		case key == "version":
+			metadata.Version = value
+			found = true
+		case key == "encrypted_regex":
+			metadata.EncryptedRegex = value
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	return metadata
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadFileMetadata(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "metadata-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"secret.yaml": `password: ENC[AES256_GCM,data:abc,type:str]
+sops:
+    age:
+        - recipient: age123
+          enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            -----END AGE ENCRYPTED FILE-----
+        - recipient: age456
+    lastmodified: "2024-01-02T03:04:05Z"
+    encrypted_regex: ^(password)$
+    version: 3.8.1
+`,
+		"secret.json": `{
+	"password": "ENC[AES256_GCM,data:abc,type:str]",
+	"sops": {
+		"age": [{"recipient": "age123"}, {"recipient": "age456"}],
+		"lastmodified": "2024-01-02T03:04:05Z",
+		"version": "3.8.1"
+	}
+}`,
+		"secret.env": `PASSWORD=ENC[AES256_GCM,data:abc,type:str]
+sops_age__list_0__map_recipient=age123
+sops_age__list_1__map_recipient=age456
+sops_lastmodified=2024-01-02T03:04:05Z
+sops_encrypted_regex=^(PASSWORD)$
+sops_version=3.8.1
+`,
+		"secret.ini": `[app]
+password = ENC[AES256_GCM,data:abc,type:str]
+
+[sops]
+age__list_0__map_recipient = age123
+age__list_1__map_recipient = age456
+lastmodified = 2024-01-02T03:04:05Z
+version = 3.8.1
+`,
+	}
+
+	expectedTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+
+		metadata, err := ReadFileMetadata(path)
+		if err != nil {
+			t.Errorf("ReadFileMetadata failed for %s: %v", name, err)
+			continue
+		}
+		if len(metadata.Recipients) != 2 || metadata.Recipients[0] != "age123" || metadata.Recipients[1] != "age456" {
+			t.Errorf("%s: unexpected recipients %v", name, metadata.Recipients)
+		}
+		if !metadata.LastModified.Equal(expectedTime) {
+			t.Errorf("%s: expected last modified %v, got %v", name, expectedTime, metadata.LastModified)
+		}
+		if metadata.Version != "3.8.1" {
+			t.Errorf("%s: expected version 3.8.1, got %s", name, metadata.Version)
+		}
+
+		expectedRegex := map[string]string{"secret.yaml": "^(password)$", "secret.env": "^(PASSWORD)$"}[name]
+		if metadata.EncryptedRegex != expectedRegex {
+			t.Errorf("%s: expected encrypted_regex %q, got %q", name, expectedRegex, metadata.EncryptedRegex)
+		}
+	}
+
+	// Plain files have no metadata
+	plainPath := filepath.Join(tempDir, "plain.yaml")
+	os.WriteFile(plainPath, []byte("password: hunter2\n"), 0644)
+	if _, err := ReadFileMetadata(plainPath); err == nil {
+		t.Error("Expected error for plain file, got nil")
+	}
+}
+
+func TestRotationPolicy(t *testing.T) {
+	durations := map[string]time.Duration{
+		"90d":  90 * 24 * time.Hour,
+		"2w":   14 * 24 * time.Hour,
+		"720h": 720 * time.Hour,
+		"":     0,
+	}
+	for input, expected := range durations {
+		got, err := ParseMaxAge(input)
+		if err != nil {
+			t.Errorf("ParseMaxAge(%q) failed: %v", input, err)
+		}
+		if got != expected {
+			t.Errorf("ParseMaxAge(%q) = %v, expected %v", input, got, expected)
+		}
+	}
+
+	if _, err := ParseMaxAge("soon"); err == nil {
+		t.Error("Expected error for invalid max age, got nil")
+	}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	policy := RotationPolicy{
+		LastRotated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaxAge:      "90d",
+	}
+
+	overdue, err := policy.KeyOverdue(now)
+	if err != nil || !overdue {
+		t.Errorf("Expected key to be overdue, got %v (%v)", overdue, err)
+	}
+
+	overdue, err = policy.IsOverdue(now.AddDate(0, 0, -10), now)
+	if err != nil || overdue {
+		t.Errorf("Expected recent file not to be overdue, got %v (%v)", overdue, err)
+	}
+}
+
+func TestLoadConfigFileWithProfile(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "config-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := `profile: work
+rotation:
+  max_age: 180d
+profiles:
+  work:
+    key_file: /keys/work.txt
+    rotation:
+      last_rotated: 2024-01-01T00:00:00Z
+      max_age: 90d
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	appConfig, err := LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+
+	if appConfig.KeyFile != "/keys/work.txt" {
+		t.Errorf("Expected profile key file, got '%s'", appConfig.KeyFile)
+	}
+	if !appConfig.OnePasswordEnabled {
+		t.Error("Defaults should be kept for settings missing from the file")
+	}
+
+	policy := appConfig.ActiveRotationPolicy()
+	if policy.MaxAge != "90d" || policy.LastRotated.IsZero() {
+		t.Errorf("Expected profile rotation policy, got %+v", policy)
+	}
+
+	// The environment overrides the active profile
+	t.Setenv(ProfileEnvVar, "ci")
+	if _, err := LoadConfigFile(configPath); err == nil {
+		t.Error("Expected error for unknown profile from the environment, got nil")
+	}
+	t.Setenv(ProfileEnvVar, "")
+
+	// Missing config file returns defaults
+	appConfig, err = LoadConfigFile(filepath.Join(tempDir, "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile should not fail for missing file: %v", err)
+	}
+	if appConfig.Profile != "" {
+		t.Errorf("Expected no profile for default config, got '%s'", appConfig.Profile)
+	}
+}
+
+func TestMissingRecipients(t *testing.T) {
+	metadata := &FileMetadata{Recipients: []string{"age123", "age456"}}
+
+	if missing := metadata.MissingRecipients([]string{"age456", "age123"}); len(missing) != 0 {
+		t.Errorf("MissingRecipients() = %v, want none", missing)
+	}
+	missing := metadata.MissingRecipients([]string{"age123", "age789", "age789"})
+	if len(missing) != 1 || missing[0] != "age789" {
+		t.Errorf("MissingRecipients() = %v, want [age789]", missing)
+	}
+}
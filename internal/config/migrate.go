@@ -0,0 +1,212 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// legacyAppConfigKeys maps keys written by versions without YAML tags to their current names
+var legacyAppConfigKeys = map[string]string{
+	"keyfile":              "key_file",
+	"onepasswordenabled":   "onepassword_enabled",
+	"alwaysuseonepassword": "always_use_onepassword",
+	"supportedextensions":  "supported_extensions",
+}
+
+// MigrateSopsConfig rewrites legacy creation rules to the current schema
+// Plain file names are replaced by anchored paths relative to root, the
+// directory holding .sops.yaml, duplicated rules are dropped and the catch-all
+// rule is moved to the end. It returns a description of every change.
+func MigrateSopsConfig(sopsConfig *SopsConfig, root string) ([]string, error) {
+	var changes []string
+
+	// Look up the files of rules that only hold a file name
+	var filesByName map[string][]string
+	for _, rule := range sopsConfig.CreationRules {
+		if IsLegacyRule(rule.PathRegex) && !containsSlash(rule.PathRegex) {
+			var err error
+			if filesByName, err = findFilesByName(root); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	for i, rule := range sopsConfig.CreationRules {
+		if !IsLegacyRule(rule.PathRegex) {
+			continue
+		}
+
+		target := rule.PathRegex
+		if !containsSlash(rule.PathRegex) {
+			switch matches := filesByName[rule.PathRegex]; len(matches) {
+			case 1:
+				target = matches[0]
+			case 0:
+				changes = append(changes, fmt.Sprintf("no file named %s was found, its rule matches the name in any directory", rule.PathRegex))
+			default:
+				changes = append(changes, fmt.Sprintf("%d files are named %s, its rule matches the name in any directory", len(matches), rule.PathRegex))
+			}
+		}
+
+		sopsConfig.CreationRules[i].PathRegex = RulePathRegex(target)
+		changes = append(changes, fmt.Sprintf("anchored rule %s as %s", rule.PathRegex, sopsConfig.CreationRules[i].PathRegex))
+	}
+
+	// sops uses the first matching rule, later duplicates never apply
+	var rules []CreationRule
+	for _, rule := range sopsConfig.CreationRules {
+		if slices.ContainsFunc(rules, func(r CreationRule) bool { return r.PathRegex == rule.PathRegex }) {
+			changes = append(changes, fmt.Sprintf("removed duplicated rule %s with recipients %s", rule.PathRegex, rule.Age))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	// A catch-all rule shadows every rule after it
+	if index := slices.IndexFunc(rules, func(r CreationRule) bool { return r.PathRegex == WildcardPattern }); index >= 0 && index < len(rules)-1 {
+		wildcard := rules[index]
+		shadowed := len(rules) - index - 1
+		rules = append(slices.Delete(rules, index, index+1), wildcard)
+		changes = append(changes, fmt.Sprintf("moved the catch-all rule to the end, it shadowed %d rules", shadowed))
+	}
+
+	sopsConfig.CreationRules = rules
+	return changes, nil
+}
+
+// containsSlash checks if a rule path has a directory component
+func containsSlash(rulePath string) bool {
+	return path.Base(rulePath) != rulePath
+}
+
+// findFilesByName returns the paths relative to root of all files, keyed by file name
+func findFilesByName(root string) (map[string][]string, error) {
+	ignore, err := LoadIgnoreFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	filesByName := make(map[string][]string)
+	err = WalkFiles(root, ignore, func(file string) error {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(file)
+		filesByName[name] = append(filesByName[name], filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", root, err)
+	}
+
+	return filesByName, nil
+}
+
+// HasLegacyAppConfigKeys checks if an application config file uses key names
+// from versions without YAML tags
+func HasLegacyAppConfigKeys(data []byte) bool {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return false
+	}
+
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if _, ok := legacyAppConfigKeys[mapping.Content[i].Value]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateAppConfig renames legacy keys of an application config file
+// Comments and the order of settings are kept. If both the legacy and the
+// current key are set, the current one wins. It returns the rewritten file and
+// a description of every change.
+func MigrateAppConfig(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+
+	mapping := doc.Content[0]
+	present := make(map[string]bool)
+	for i := 0; i < len(mapping.Content); i += 2 {
+		present[mapping.Content[i].Value] = true
+	}
+
+	var changes []string
+	var content []*yaml.Node
+	for i := 0; i < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		if current, ok := legacyAppConfigKeys[key.Value]; ok {
+			if present[current] {
+				changes = append(changes, fmt.Sprintf("removed %s, %s is already set", key.Value, current))
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("renamed %s to %s", key.Value, current))
+			key.Value = current
+			present[current] = true
+		}
+
+		content = append(content, key, value)
+	}
+
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+	mapping.Content = content
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config file: %w", err)
+	}
+
+	return buf.Bytes(), changes, nil
+}
+
+// BackupFile copies a file next to itself with a .bak suffix
+// An existing backup is never overwritten; a timestamp is added instead.
+func BackupFile(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	backupPath := filePath + ".bak"
+	if _, err := os.Stat(backupPath); err == nil {
+		backupPath = fmt.Sprintf("%s.%s.bak", filePath, time.Now().Format("20060102150405"))
+	}
+
+	if err := os.WriteFile(backupPath, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+
+	return backupPath, nil
+}
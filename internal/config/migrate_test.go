@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateSopsConfig(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"config/app.env", "a/shared.yaml", "b/shared.yaml"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x: 1"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1"}},
+		{PathRegex: "app.env", Age: AgeRecipients{"age1"}},
+		{PathRegex: "shared.yaml", Age: AgeRecipients{"age2"}},
+		{PathRegex: "config/app.env", Age: AgeRecipients{"age3"}},
+		{PathRegex: `^secrets/.*$`, Age: AgeRecipients{"age4"}},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age5"}},
+	}}
+
+	changes, err := MigrateSopsConfig(config, root)
+	if err != nil {
+		t.Fatalf("MigrateSopsConfig failed: %v", err)
+	}
+	if len(changes) == 0 {
+		t.Fatal("Expected changes")
+	}
+
+	expected := []string{
+		RulePathRegex("config/app.env"), // Unique file name, anchored to its path
+		RulePathRegex("shared.yaml"),    // Ambiguous file name, anchored to the name
+		`^secrets/.*$`,                  // Real regular expressions are kept
+		WildcardPattern,                 // Catch-all rule moved to the end
+	}
+	if len(config.CreationRules) != len(expected) {
+		t.Fatalf("Expected %d rules, got %+v", len(expected), config.CreationRules)
+	}
+	for i, pathRegex := range expected {
+		if config.CreationRules[i].PathRegex != pathRegex {
+			t.Errorf("Rule %d: expected %s, got %s", i, pathRegex, config.CreationRules[i].PathRegex)
+		}
+	}
+
+	// The first of the duplicated rules is kept, since sops applies it
+	if config.CreationRules[0].Age.String() != "age1" || config.CreationRules[3].Age.String() != "age1" {
+		t.Errorf("Expected the first duplicated rules to be kept, got %+v", config.CreationRules)
+	}
+
+	// Migrating again changes nothing
+	changes, err = MigrateSopsConfig(config, root)
+	if err != nil {
+		t.Fatalf("MigrateSopsConfig failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes for a migrated config, got %v", changes)
+	}
+}
+
+func TestMigrateAppConfig(t *testing.T) {
+	legacy := `# my settings
+keyfile: ~/.keys/key.txt
+onepasswordenabled: false
+always_use_onepassword: false
+alwaysuseonepassword: true
+`
+	if !HasLegacyAppConfigKeys([]byte(legacy)) {
+		t.Error("Expected legacy keys to be detected")
+	}
+
+	migrated, changes, err := MigrateAppConfig([]byte(legacy))
+	if err != nil {
+		t.Fatalf("MigrateAppConfig failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected 3 changes, got %v", changes)
+	}
+
+	expected := `# my settings
+key_file: ~/.keys/key.txt
+onepassword_enabled: false
+always_use_onepassword: false
+`
+	if string(migrated) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, migrated)
+	}
+	if HasLegacyAppConfigKeys(migrated) {
+		t.Error("Expected no legacy keys after migration")
+	}
+
+	// The migrated file loads with the renamed settings
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, migrated, 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	appConfig, err := LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if appConfig.KeyFile != "~/.keys/key.txt" || appConfig.OnePasswordEnabled {
+		t.Errorf("Unexpected config after migration: %+v", appConfig)
+	}
+
+	// Current configs are left alone
+	if _, changes, err := MigrateAppConfig(migrated); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v, %v", changes, err)
+	}
+}
+
+func TestBackupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".sops.yaml")
+	if err := os.WriteFile(path, []byte("creation_rules: []\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	first, err := BackupFile(path)
+	if err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+	if first != path+".bak" {
+		t.Errorf("Expected %s.bak, got %s", path, first)
+	}
+
+	// An existing backup is not overwritten
+	second, err := BackupFile(path)
+	if err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+	if second == first || !strings.HasSuffix(second, ".bak") {
+		t.Errorf("Expected a second backup next to %s, got %s", first, second)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultEncryptedInfix marks encrypted files, as in secrets.enc.yaml
+const DefaultEncryptedInfix = "enc"
+
+// AutoOutput is the --output value that picks the name from the naming convention
+const AutoOutput = "auto"
+
+// NamingConvention relates plaintext files to their encrypted counterparts
+// The encrypted file has the infix before its extension, so secrets.yaml is
+// encrypted to secrets.enc.yaml and both can be kept side by side.
+type NamingConvention struct {
+	// Infix is inserted before the extension of encrypted files, "enc" by default
+	Infix string `yaml:"infix,omitempty"`
+}
+
+// Validate checks that the infix is a plain name part
+func (n NamingConvention) Validate() error {
+	if strings.ContainsAny(n.Infix, `./\`) {
+		return fmt.Errorf("naming: infix %q must not contain dots or path separators", n.Infix)
+	}
+	return nil
+}
+
+// infix returns the configured infix or the default
+func (n NamingConvention) infix() string {
+	if n.Infix == "" {
+		return DefaultEncryptedInfix
+	}
+	return n.Infix
+}
+
+// EncryptedName returns the path of the encrypted counterpart of a plaintext file
+func (n NamingConvention) EncryptedName(path string) string {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	return dir + strings.TrimSuffix(name, ext) + "." + n.infix() + ext
+}
+
+// PlainName returns the path of the plaintext counterpart of an encrypted file
+// Returns false if the file name doesn't follow the convention.
+func (n NamingConvention) PlainName(path string) (string, bool) {
+	dir, name := filepath.Split(path)
+	marker := "." + n.infix()
+
+	ext := filepath.Ext(name)
+	if ext == marker && name != marker {
+		return dir + strings.TrimSuffix(name, ext), true
+	}
+
+	stem := strings.TrimSuffix(name, ext)
+	if !strings.HasSuffix(stem, marker) {
+		return "", false
+	}
+	return dir + strings.TrimSuffix(stem, marker) + ext, true
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RuleSource is a rule of one .sops.yaml that contributes to the effective rule of a file
+type RuleSource struct {
+	// ConfigPath is the .sops.yaml holding the rule
+	ConfigPath string
+	// Override is set if the .sops.yaml doesn't inherit from its parents
+	Override bool
+	// Rule is the matching rule
+	Rule CreationRule
+}
+
+// EffectiveRule is the rule of a file after merging nested .sops.yaml files
+type EffectiveRule struct {
+	// Rule is the merged rule: the settings of the nearest matching rule with
+	// the recipients of all matching rules
+	Rule CreationRule
+	// Sources are the contributing rules, nearest first
+	Sources []RuleSource
+}
+
+// Nested checks if rules of a .sops.yaml other than configPath contribute to the effective rule
+// sops only reads the nearest .sops.yaml, so such files need a merged config.
+func (e EffectiveRule) Nested(configPath string) bool {
+	return len(e.Sources) > 1 || (len(e.Sources) == 1 && e.Sources[0].ConfigPath != configPath)
+}
+
+// SopsConfigChain returns the .sops.yaml files that apply to a file, nearest first
+// These are the files in the directories from the file up to the one of
+// configPath, which is always last, also if it doesn't exist.
+func SopsConfigChain(configPath string, filePath string) ([]string, error) {
+	root, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []string
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		candidate := filepath.Join(dir, ".sops.yaml")
+		if _, err := os.Stat(candidate); err == nil {
+			chain = append(chain, candidate)
+		}
+		dir = filepath.Dir(dir)
+	}
+	return append(chain, configPath), nil
+}
+
+// NestedRules finds the rules covering files below a root, including those of nested .sops.yaml files
+// The rules of the root are given, e.g. read from a source, the .sops.yaml
+// files of subdirectories are read from the working tree once.
+type NestedRules struct {
+	root       string
+	rootConfig *SopsConfig
+	configs    map[string]*SopsConfig
+}
+
+// NewNestedRules creates the rules of the .sops.yaml files below root
+func NewNestedRules(root string, rootConfig *SopsConfig) *NestedRules {
+	return &NestedRules{root: root, rootConfig: rootConfig, configs: make(map[string]*SopsConfig)}
+}
+
+// CoveringRule returns the nearest rule covering a file given relative to the root
+// The .sops.yaml files from the file's directory up to the root are searched
+// until one with override: true, like ResolveRule does. Catch-all rules don't
+// cover a file.
+func (n *NestedRules) CoveringRule(relPath string) (CreationRule, bool, error) {
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		sopsConfig, err := n.load(dir)
+		if err != nil {
+			return CreationRule{}, false, err
+		}
+		if sopsConfig == nil {
+			continue
+		}
+		if rule, found := CoveringRule(sopsConfig, strings.TrimPrefix(relPath, dir+"/")); found {
+			return rule, true, nil
+		}
+		if sopsConfig.Override {
+			return CreationRule{}, false, nil
+		}
+	}
+
+	rule, found := CoveringRule(n.rootConfig, relPath)
+	return rule, found, nil
+}
+
+// load returns the .sops.yaml of a directory relative to the root, or nil if it has none
+func (n *NestedRules) load(dir string) (*SopsConfig, error) {
+	if sopsConfig, ok := n.configs[dir]; ok {
+		return sopsConfig, nil
+	}
+
+	var sopsConfig *SopsConfig
+	configPath := filepath.Join(n.root, filepath.FromSlash(dir), ".sops.yaml")
+	if _, err := os.Stat(configPath); err == nil {
+		if sopsConfig, err = LoadSopsConfig(configPath); err != nil {
+			return nil, fmt.Errorf("%s: %w", configPath, err)
+		}
+	}
+	n.configs[dir] = sopsConfig
+	return sopsConfig, nil
+}
+
+// ResolveRule merges the rules of nested .sops.yaml files that match a file
+// A .sops.yaml in a subdirectory extends the recipients of its parents: the
+// matching rules of all files up to configPath are merged, nearest first,
+// until a file with override: true. The other settings, like encrypted_regex,
+// come from the nearest matching rule.
+func ResolveRule(configPath string, filePath string) (EffectiveRule, bool, error) {
+	chain, err := SopsConfigChain(configPath, filePath)
+	if err != nil {
+		return EffectiveRule{}, false, err
+	}
+
+	var effective EffectiveRule
+	for _, path := range chain {
+		sopsConfig, err := LoadSopsConfig(path)
+		if err != nil {
+			return EffectiveRule{}, false, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if rule, found := MatchingRule(sopsConfig, ConfigRelativePath(path, filePath)); found {
+			if len(effective.Sources) == 0 {
+				effective.Rule = rule
+				effective.Rule.Age = append(AgeRecipients(nil), rule.Age...)
+			} else {
+				effective.Rule.Age = UniqueRecipients(append(effective.Rule.Age, rule.Age...))
+			}
+			effective.Sources = append(effective.Sources, RuleSource{ConfigPath: path, Override: sopsConfig.Override, Rule: rule})
+		}
+		if sopsConfig.Override {
+			break
+		}
+	}
+
+	return effective, len(effective.Sources) > 0, nil
+}
+
+// MergedSopsConfig returns a .sops.yaml with only the effective rule, for passing to sops
+func (e EffectiveRule) MergedSopsConfig() *SopsConfig {
+	rule := e.Rule
+	rule.PathRegex = ".*"
+	return &SopsConfig{CreationRules: []CreationRule{rule}}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveRule(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, content string) {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	configPath := filepath.Join(root, ".sops.yaml")
+	write(".sops.yaml", "creation_rules:\n  - path_regex: ^clusters/\n    age: age1root\n    encrypted_regex: ^data$\n")
+	write("clusters/.sops.yaml", "creation_rules:\n  - path_regex: ^prod/\n    age: age1prod,age1root\n")
+	write("clusters/prod/team/.sops.yaml", "override: true\ncreation_rules:\n  - path_regex: .*\n    age: age1team\n")
+
+	// The nested rule extends the recipients of the root rule
+	effective, found, err := ResolveRule(configPath, filepath.Join(root, "clusters", "prod", "app.enc.yaml"))
+	if err != nil || !found {
+		t.Fatalf("ResolveRule() = %v, %v", found, err)
+	}
+	if got := effective.Rule.Age.String(); got != "age1prod,age1root" {
+		t.Errorf("Expected the merged recipients, got %s", got)
+	}
+	if effective.Rule.PathRegex != "^prod/" || effective.Rule.EncryptedRegex != "" {
+		t.Errorf("Expected the settings of the nearest rule, got %+v", effective.Rule)
+	}
+	if len(effective.Sources) != 2 || !effective.Nested(configPath) {
+		t.Errorf("Expected two nested sources, got %+v", effective.Sources)
+	}
+
+	// override stops the inheritance
+	effective, _, err = ResolveRule(configPath, filepath.Join(root, "clusters", "prod", "team", "app.enc.yaml"))
+	if err != nil {
+		t.Fatalf("ResolveRule failed: %v", err)
+	}
+	if got := effective.Rule.Age.String(); got != "age1team" || len(effective.Sources) != 1 {
+		t.Errorf("Expected only the recipients of the overriding config, got %s", got)
+	}
+
+	// A nested config without a matching rule falls through to its parents
+	effective, _, err = ResolveRule(configPath, filepath.Join(root, "clusters", "dev.enc.yaml"))
+	if err != nil {
+		t.Fatalf("ResolveRule failed: %v", err)
+	}
+	if got := effective.Rule.Age.String(); got != "age1root" || effective.Nested(configPath) {
+		t.Errorf("Expected the root rule only, got %s from %+v", got, effective.Sources)
+	}
+
+	// The merged config applies to the file under any path
+	merged := effective.MergedSopsConfig()
+	if len(merged.CreationRules) != 1 || merged.CreationRules[0].PathRegex != ".*" {
+		t.Errorf("Expected a single catch-all rule, got %+v", merged.CreationRules)
+	}
+
+	// override survives saving the rules
+	teamConfig := filepath.Join(root, "clusters", "prod", "team", ".sops.yaml")
+	loaded, err := LoadSopsConfig(teamConfig)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	if err := SaveSopsConfig(teamConfig, loaded); err != nil {
+		t.Fatalf("SaveSopsConfig failed: %v", err)
+	}
+	if data, _ := os.ReadFile(teamConfig); !strings.Contains(string(data), "override: true") {
+		t.Errorf("Expected override to be kept, got %s", data)
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFileName is the path of the recipient policy relative to the repository root
+const PolicyFileName = ".simple-sops/policy.yaml"
+
+// RecipientPolicy sets the recipients every rule must have
+// It prevents files only one person can decrypt, e.g. by requiring the
+// backup key and a second recipient.
+type RecipientPolicy struct {
+	// RequiredRecipients are Age recipients every rule must include
+	RequiredRecipients []string `yaml:"required_recipients,omitempty"`
+	// RecoveryRecipients are Age recipients of an escrowed recovery key, e.g.
+	// kept offline, that encrypt adds to every file and rule
+	RecoveryRecipients []string `yaml:"recovery_recipients,omitempty"`
+	// MinRecipients is the minimum number of recipients of a rule
+	MinRecipients int `yaml:"min_recipients,omitempty"`
+}
+
+// PolicyViolation is a rule that doesn't meet the recipient policy
+type PolicyViolation struct {
+	PathRegex string
+	Problems  []string
+}
+
+// MissingRecovery is an encrypted file the recovery recipients can't decrypt
+type MissingRecovery struct {
+	Path       string
+	Recipients []string
+}
+
+// LoadRecipientPolicy loads the recipient policy of the repository at root
+// The policy of the repository is combined with the one of the application
+// config: all required recipients apply, and the higher minimum.
+func LoadRecipientPolicy(root string, appPolicy RecipientPolicy) (RecipientPolicy, error) {
+	path := filepath.Join(root, filepath.FromSlash(PolicyFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return appPolicy, nil
+	}
+	if err != nil {
+		return RecipientPolicy{}, fmt.Errorf("failed to read %s: %w", PolicyFileName, err)
+	}
+
+	var repoPolicy RecipientPolicy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&repoPolicy); err != nil && !errors.Is(err, io.EOF) {
+		return RecipientPolicy{}, fmt.Errorf("failed to parse %s: %w", PolicyFileName, err)
+	}
+	if err := repoPolicy.Validate(); err != nil {
+		return RecipientPolicy{}, fmt.Errorf("invalid %s: %w", PolicyFileName, err)
+	}
+	return appPolicy.Merge(repoPolicy), nil
+}
+
+// Validate checks the minimum and that required recipients are Age recipients
+func (p RecipientPolicy) Validate() error {
+	if p.MinRecipients < 0 {
+		return fmt.Errorf("min_recipients must not be negative")
+	}
+	for _, recipient := range p.RequiredRecipients {
+		if !strings.HasPrefix(recipient, "age1") {
+			return fmt.Errorf("required_recipients: %s is not an Age recipient (age1...)", recipient)
+		}
+	}
+	for _, recipient := range p.RecoveryRecipients {
+		if !strings.HasPrefix(recipient, "age1") {
+			return fmt.Errorf("recovery_recipients: %s is not an Age recipient (age1...)", recipient)
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the policy requires nothing
+func (p RecipientPolicy) IsEmpty() bool {
+	return len(p.RequiredRecipients) == 0 && len(p.RecoveryRecipients) == 0 && p.MinRecipients == 0
+}
+
+// Merge combines two policies, requiring the recipients of both and the higher minimum
+func (p RecipientPolicy) Merge(other RecipientPolicy) RecipientPolicy {
+	return RecipientPolicy{
+		RequiredRecipients: UniqueRecipients(append(slices.Clone(p.RequiredRecipients), other.RequiredRecipients...)),
+		RecoveryRecipients: UniqueRecipients(append(slices.Clone(p.RecoveryRecipients), other.RecoveryRecipients...)),
+		MinRecipients:      max(p.MinRecipients, other.MinRecipients),
+	}
+}
+
+// WithRecovery adds the recovery recipients to a set of recipients
+func (p RecipientPolicy) WithRecovery(recipients []string) []string {
+	return UniqueRecipients(append(slices.Clone(recipients), p.RecoveryRecipients...))
+}
+
+// Check returns the problems of a set of recipients, none if it meets the policy
+func (p RecipientPolicy) Check(recipients []string) []string {
+	recipients = UniqueRecipients(recipients)
+	var problems []string
+	for _, required := range p.RequiredRecipients {
+		if !slices.Contains(recipients, required) {
+			problems = append(problems, fmt.Sprintf("missing required recipient %s", required))
+		}
+	}
+	for _, recovery := range p.RecoveryRecipients {
+		if !slices.Contains(recipients, recovery) {
+			problems = append(problems, fmt.Sprintf("missing recovery recipient %s", recovery))
+		}
+	}
+	if len(recipients) < p.MinRecipients {
+		problems = append(problems, fmt.Sprintf("%d recipient(s), at least %d required", len(recipients), p.MinRecipients))
+	}
+	return problems
+}
+
+// FindPolicyViolations returns the rules that don't meet the recipient policy
+// KMS keys count as recipients, so a rule can reach the minimum with them.
+func FindPolicyViolations(sopsConfig *SopsConfig, policy RecipientPolicy) []PolicyViolation {
+	var violations []PolicyViolation
+	for _, rule := range sopsConfig.CreationRules {
+		recipients := slices.Clone([]string(rule.Age))
+		if rule.KMS != "" {
+			recipients = append(recipients, strings.Split(rule.KMS, ",")...)
+		}
+		if problems := policy.Check(recipients); len(problems) > 0 {
+			violations = append(violations, PolicyViolation{PathRegex: rule.PathRegex, Problems: problems})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRecipientPolicy(t *testing.T) {
+	root := t.TempDir()
+	appPolicy := RecipientPolicy{RequiredRecipients: []string{"age1backup"}, MinRecipients: 3}
+
+	// Without a repository policy the one of the config applies
+	policy, err := LoadRecipientPolicy(root, appPolicy)
+	if err != nil || !reflect.DeepEqual(policy, appPolicy) {
+		t.Fatalf("LoadRecipientPolicy() = %+v, %v", policy, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, ".simple-sops"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, filepath.FromSlash(PolicyFileName))
+	if err := os.WriteFile(path, []byte("required_recipients:\n  - age1ops\n  - age1backup\nmin_recipients: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = LoadRecipientPolicy(root, appPolicy)
+	if err != nil {
+		t.Fatalf("LoadRecipientPolicy() error = %v", err)
+	}
+	want := RecipientPolicy{RequiredRecipients: []string{"age1backup", "age1ops"}, MinRecipients: 3}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("LoadRecipientPolicy() = %+v, want %+v", policy, want)
+	}
+
+	if err := os.WriteFile(path, []byte("required_recipients: [ops]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRecipientPolicy(root, RecipientPolicy{}); err == nil {
+		t.Error("LoadRecipientPolicy() accepted a required recipient that is not an Age recipient")
+	}
+}
+
+func TestFindPolicyViolations(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: "^ok/", Age: AgeRecipients{"age1alice", "age1backup"}},
+		{PathRegex: "^kms/", Age: AgeRecipients{"age1backup"}, KMS: "arn:aws:kms:eu-west-1:1:key/a"},
+		{PathRegex: "^single/", Age: AgeRecipients{"age1alice"}},
+	}}
+	policy := RecipientPolicy{RequiredRecipients: []string{"age1backup"}, MinRecipients: 2}
+
+	violations := FindPolicyViolations(sopsConfig, policy)
+	if len(violations) != 1 || violations[0].PathRegex != "^single/" {
+		t.Fatalf("FindPolicyViolations() = %+v, want only ^single/", violations)
+	}
+	if len(violations[0].Problems) != 2 {
+		t.Errorf("Problems = %v, want the missing backup key and the minimum", violations[0].Problems)
+	}
+
+	if got := FindPolicyViolations(sopsConfig, RecipientPolicy{}); len(got) != 0 {
+		t.Errorf("FindPolicyViolations() with an empty policy = %+v", got)
+	}
+}
+
+func TestRecoveryRecipients(t *testing.T) {
+	policy := RecipientPolicy{RecoveryRecipients: []string{"age1escrow"}}.Merge(RecipientPolicy{RecoveryRecipients: []string{"age1escrow", "age1vault"}})
+	if want := []string{"age1escrow", "age1vault"}; !reflect.DeepEqual(policy.RecoveryRecipients, want) {
+		t.Fatalf("Merge() recovery recipients = %v, want %v", policy.RecoveryRecipients, want)
+	}
+
+	recipients := policy.WithRecovery([]string{"age1alice", "age1escrow"})
+	if want := []string{"age1alice", "age1escrow", "age1vault"}; !reflect.DeepEqual(recipients, want) {
+		t.Errorf("WithRecovery() = %v, want %v", recipients, want)
+	}
+	if problems := policy.Check(recipients); len(problems) != 0 {
+		t.Errorf("Check() with the recovery recipients = %v", problems)
+	}
+	if problems := policy.Check([]string{"age1alice", "age1escrow"}); len(problems) != 1 {
+		t.Errorf("Check() = %v, want the missing age1vault", problems)
+	}
+
+	if err := (RecipientPolicy{RecoveryRecipients: []string{"escrow"}}).Validate(); err == nil {
+		t.Error("Validate() accepted a recovery recipient that is not an Age recipient")
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PresetSettings customize a file preset such as kubeconfig or talos
+type PresetSettings struct {
+	// Recipients are encrypted to in addition to your own key
+	Recipients []string `yaml:"recipients,omitempty"`
+	// EncryptedRegex replaces the preset's encrypted_regex
+	EncryptedRegex string `yaml:"encrypted_regex,omitempty"`
+}
+
+// PresetSettingsMap holds the settings of each preset by name
+type PresetSettingsMap map[string]PresetSettings
+
+// Validate checks that the encrypted_regex of every preset compiles
+func (m PresetSettingsMap) Validate() error {
+	for name, settings := range m {
+		if settings.EncryptedRegex == "" {
+			continue
+		}
+		if _, err := regexp.Compile(settings.EncryptedRegex); err != nil {
+			return fmt.Errorf("presets: invalid encrypted_regex for %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+)
+
+// RuleConflict is a creation rule that never applies, since sops uses the
+// first matching rule and an earlier rule matches first
+type RuleConflict struct {
+	Rule       CreationRule
+	ShadowedBy CreationRule
+}
+
+// FindConflicts returns the rules shadowed by an earlier rule
+// A file rule is shadowed by an earlier rule matching its path. Other rules
+// are shadowed by an earlier rule with the same path regex or by an earlier
+// catch-all rule.
+func FindConflicts(config *SopsConfig) []RuleConflict {
+	var conflicts []RuleConflict
+	for i, rule := range config.CreationRules {
+		for _, earlier := range config.CreationRules[:i] {
+			if shadows(earlier, rule) {
+				conflicts = append(conflicts, RuleConflict{Rule: rule, ShadowedBy: earlier})
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// ConflictFor returns the conflict of the rule written for a file, if it is shadowed
+func ConflictFor(config *SopsConfig, filename string) (RuleConflict, bool) {
+	for _, conflict := range FindConflicts(config) {
+		if ruleMatchesFile(conflict.Rule, filename) {
+			return conflict, true
+		}
+	}
+	return RuleConflict{}, false
+}
+
+// shadows checks if an earlier rule matches every path a later rule matches
+func shadows(earlier CreationRule, later CreationRule) bool {
+	if earlier.PathRegex == later.PathRegex {
+		return true
+	}
+
+	path, isFileRule := RulePath(later.PathRegex)
+	if !isFileRule {
+		return earlier.PathRegex == WildcardPattern
+	}
+
+	pattern, err := regexp.Compile(earlier.PathRegex)
+	return err == nil && pattern.MatchString(path)
+}
+
+// rulePriority orders file rules before patterns and the catch-all rule last
+func rulePriority(rule CreationRule) int {
+	if rule.PathRegex == WildcardPattern {
+		return 2
+	}
+	if _, isFileRule := RulePath(rule.PathRegex); isFileRule {
+		return 0
+	}
+	return 1
+}
+
+// ReorderRules moves specific rules before broader ones
+// File rules come first, then patterns in their current order and the
+// catch-all rule last. It returns the conflicts that were resolved.
+func ReorderRules(config *SopsConfig) []RuleConflict {
+	before := FindConflicts(config)
+
+	slices.SortStableFunc(config.CreationRules, func(a, b CreationRule) int {
+		return rulePriority(a) - rulePriority(b)
+	})
+
+	var resolved []RuleConflict
+	after := FindConflicts(config)
+	for _, conflict := range before {
+		if !slices.ContainsFunc(after, func(c RuleConflict) bool { return c.Rule.PathRegex == conflict.Rule.PathRegex }) {
+			resolved = append(resolved, conflict)
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectsFileName is the name of the project registry in the config directory
+const ProjectsFileName = "projects.yaml"
+
+// Projects are the repositories registered with project add
+// Commands run with --project <name> operate on the repository from anywhere.
+type Projects struct {
+	Projects map[string]*Project `yaml:"projects"`
+}
+
+// Project holds the defaults of one repository
+type Project struct {
+	// Path is the absolute path of the repository
+	Path string `yaml:"path"`
+	// Profile is the key profile used for the project
+	Profile string `yaml:"profile,omitempty"`
+	// SopsConfig is the .sops.yaml of the project, relative to Path
+	SopsConfig string `yaml:"sops_config,omitempty"`
+	// Patterns restrict the encrypted files found without file arguments,
+	// in gitignore syntax relative to Path
+	Patterns []string `yaml:"patterns,omitempty"`
+}
+
+var (
+	// sopsConfigOverride replaces the discovered .sops.yaml of the active project
+	sopsConfigOverride string
+
+	// filePatterns restrict FindEncryptedFiles to the files of the active project
+	filePatterns *IgnoreMatcher
+)
+
+// ProjectsFilePath returns the path of the project registry
+func ProjectsFilePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, ProjectsFileName), nil
+}
+
+// LoadProjects loads the project registry from a file
+// A missing file results in no projects.
+func LoadProjects(path string) (*Projects, error) {
+	projects := &Projects{Projects: map[string]*Project{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return projects, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(projects); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if projects.Projects == nil {
+		projects.Projects = map[string]*Project{}
+	}
+
+	for name, project := range projects.Projects {
+		if err := project.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: project %s: %w", path, name, err)
+		}
+	}
+	return projects, nil
+}
+
+// SaveProjects saves the project registry to a file
+func SaveProjects(path string, projects *Projects) error {
+	data, err := yaml.Marshal(projects)
+	if err != nil {
+		return fmt.Errorf("failed to serialize projects: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the path is absolute and the patterns are valid
+func (p *Project) Validate() error {
+	if !filepath.IsAbs(p.Path) {
+		return fmt.Errorf("path %q is not absolute", p.Path)
+	}
+	if filepath.IsAbs(p.SopsConfig) {
+		return fmt.Errorf("sops_config %q must be relative to the project path", p.SopsConfig)
+	}
+	if _, err := NewIgnoreMatcher(p.Path, p.Patterns...); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	return nil
+}
+
+// Names returns the names of the projects in order
+func (p *Projects) Names() []string {
+	names := make([]string, 0, len(p.Projects))
+	for name := range p.Projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActivateProject makes the commands of this process operate on a project
+// The working directory changes to the project, so file arguments are
+// relative to it. The project's profile overrides the configured one.
+func ActivateProject(projects *Projects, name string) (*Project, error) {
+	project, ok := projects.Projects[name]
+	if !ok {
+		return nil, fmt.Errorf("project %s not found, add it with 'simple-sops project add %s <path>'", name, name)
+	}
+
+	if err := os.Chdir(project.Path); err != nil {
+		return nil, fmt.Errorf("failed to change to project %s: %w", name, err)
+	}
+	if project.Profile != "" {
+		if err := os.Setenv(ProfileEnvVar, project.Profile); err != nil {
+			return nil, fmt.Errorf("failed to select profile %s: %w", project.Profile, err)
+		}
+	}
+	if project.SopsConfig != "" {
+		sopsConfigOverride = filepath.Join(project.Path, project.SopsConfig)
+	}
+	if len(project.Patterns) > 0 {
+		matcher, err := NewIgnoreMatcher(project.Path, project.Patterns...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of project %s: %w", name, err)
+		}
+		filePatterns = matcher
+	}
+
+	return project, nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ProjectsFileName)
+
+	projects, err := LoadProjects(path)
+	if err != nil || len(projects.Projects) != 0 {
+		t.Fatalf("Expected no projects for a missing file, got %v (%v)", projects, err)
+	}
+
+	projects.Projects["homelab"] = &Project{Path: "/src/homelab", Profile: "personal", Patterns: []string{"clusters/"}}
+	projects.Projects["infra"] = &Project{Path: "/src/infra"}
+	if err := SaveProjects(path, projects); err != nil {
+		t.Fatalf("SaveProjects failed: %v", err)
+	}
+
+	loaded, err := LoadProjects(path)
+	if err != nil {
+		t.Fatalf("LoadProjects failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, projects) {
+		t.Errorf("Expected %+v, got %+v", projects, loaded)
+	}
+	if names := loaded.Names(); !reflect.DeepEqual(names, []string{"homelab", "infra"}) {
+		t.Errorf("Expected sorted names, got %v", names)
+	}
+
+	// Relative paths can't be resolved from anywhere
+	if err := os.WriteFile(path, []byte("projects:\n  x:\n    path: src/x\n"), 0600); err != nil {
+		t.Fatalf("Failed to write projects: %v", err)
+	}
+	if _, err := LoadProjects(path); err == nil {
+		t.Error("Expected an error for a relative project path")
+	}
+}
+
+func TestActivateProject(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+	defer func() { sopsConfigOverride, filePatterns = "", nil }()
+	t.Setenv(ProfileEnvVar, "")
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	encrypted := "a: ENC[AES256_GCM,data:x]\nsops:\n  mac: x\n  version: 3.8.0\n"
+	for _, file := range []string{"clusters/prod.enc.yaml", "other.enc.yaml"} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(encrypted), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	projects := &Projects{Projects: map[string]*Project{
+		"infra": {Path: root, Profile: "work", SopsConfig: "clusters/.sops.yaml", Patterns: []string{"clusters/"}},
+	}}
+	if _, err := ActivateProject(projects, "missing"); err == nil {
+		t.Error("Expected an error for an unknown project")
+	}
+	if _, err := ActivateProject(projects, "infra"); err != nil {
+		t.Fatalf("ActivateProject failed: %v", err)
+	}
+
+	if cwd, _ := os.Getwd(); cwd != root {
+		t.Errorf("Expected working directory %s, got %s", root, cwd)
+	}
+	if profile := os.Getenv(ProfileEnvVar); profile != "work" {
+		t.Errorf("Expected profile work, got %q", profile)
+	}
+	if configPath, err := GetSopsConfigPath(); err != nil || configPath != filepath.Join(root, "clusters", ".sops.yaml") {
+		t.Errorf("Expected the project's .sops.yaml, got %s (%v)", configPath, err)
+	}
+
+	files, err := FindEncryptedFiles(root)
+	if err != nil {
+		t.Fatalf("FindEncryptedFiles failed: %v", err)
+	}
+	if want := []string{filepath.Join(root, "clusters", "prod.enc.yaml")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RotationsFileName is the path of the rotation times relative to the repository root
+const RotationsFileName = ".simple-sops/rotations.yaml"
+
+// Rotations are the times the data keys of encrypted files were last rotated
+// sops updates lastmodified on every edit, so rotate records its runs in a
+// committed file next to .sops.yaml.
+type Rotations struct {
+	// Files maps files relative to the repository root to their last rotation
+	Files map[string]time.Time `yaml:"files"`
+}
+
+// RotationPolicy describes how often keys and encrypted files should be rotated
+type RotationPolicy struct {
+	// LastRotated is when the key was last replaced
+	LastRotated time.Time `yaml:"last_rotated,omitempty"`
+	// MaxAge is the maximum age of a key or file before rotation is due (e.g. "90d", "720h")
+	MaxAge string `yaml:"max_age,omitempty"`
+}
+
+// IsZero reports whether the policy is unset
+func (p RotationPolicy) IsZero() bool {
+	return p.LastRotated.IsZero() && p.MaxAge == ""
+}
+
+// ParseMaxAge parses a max age like "90d", "2w" or any Go duration
+func ParseMaxAge(maxAge string) (time.Duration, error) {
+	maxAge = strings.TrimSpace(maxAge)
+	if maxAge == "" {
+		return 0, nil
+	}
+
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(maxAge, suffix) {
+			count, err := strconv.Atoi(strings.TrimSuffix(maxAge, suffix))
+			if err != nil {
+				return 0, fmt.Errorf("invalid max age %q: %w", maxAge, err)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(maxAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max age %q: %w", maxAge, err)
+	}
+
+	return duration, nil
+}
+
+// IsOverdue reports whether something last rotated at the given time exceeds the policy
+func (p RotationPolicy) IsOverdue(lastRotated time.Time, now time.Time) (bool, error) {
+	maxAge, err := ParseMaxAge(p.MaxAge)
+	if err != nil {
+		return false, err
+	}
+
+	if maxAge == 0 || lastRotated.IsZero() {
+		return false, nil
+	}
+
+	return now.Sub(lastRotated) > maxAge, nil
+}
+
+// KeyOverdue reports whether the key itself exceeds the policy
+func (p RotationPolicy) KeyOverdue(now time.Time) (bool, error) {
+	return p.IsOverdue(p.LastRotated, now)
+}
+
+// ActiveRotationPolicy returns the rotation policy of the active profile,
+// falling back to the global policy
+func (c *AppConfig) ActiveRotationPolicy() RotationPolicy {
+	policy := c.Rotation
+	if profile, ok := c.ActiveProfile(); ok {
+		if !profile.Rotation.LastRotated.IsZero() {
+			policy.LastRotated = profile.Rotation.LastRotated
+		}
+		if profile.Rotation.MaxAge != "" {
+			policy.MaxAge = profile.Rotation.MaxAge
+		}
+	}
+
+	return policy
+}
+
+// LoadRotations loads the rotation times of the repository at root
+// A missing file results in no rotation times.
+func LoadRotations(root string) (*Rotations, error) {
+	rotations := &Rotations{Files: make(map[string]time.Time)}
+	path := filepath.Join(root, filepath.FromSlash(RotationsFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rotations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", RotationsFileName, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rotations); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RotationsFileName, err)
+	}
+	if rotations.Files == nil {
+		rotations.Files = make(map[string]time.Time)
+	}
+	return rotations, nil
+}
+
+// SaveRotations saves the rotation times of the repository at root
+func SaveRotations(root string, rotations *Rotations) error {
+	path := filepath.Join(root, filepath.FromSlash(RotationsFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(rotations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", RotationsFileName, err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", RotationsFileName, err)
+	}
+	return nil
+}
+
+// LastRotated returns when the data key of a file was last rotated
+// Files rotate never ran on fall back to the lastmodified time of their
+// metadata, the latest time their data key may have been created.
+func (r *Rotations) LastRotated(relPath string, metadata *FileMetadata) time.Time {
+	if rotated, ok := r.Files[relPath]; ok {
+		return rotated
+	}
+	return metadata.LastModified
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotations(t *testing.T) {
+	root := t.TempDir()
+
+	// A missing file has no rotation times
+	rotations, err := LoadRotations(root)
+	if err != nil {
+		t.Fatalf("LoadRotations failed: %v", err)
+	}
+	if len(rotations.Files) != 0 {
+		t.Fatalf("Expected no rotation times, got %v", rotations.Files)
+	}
+
+	rotated := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	rotations.Files["secrets/db.enc.yaml"] = rotated
+	if err := SaveRotations(root, rotations); err != nil {
+		t.Fatalf("SaveRotations failed: %v", err)
+	}
+	loaded, err := LoadRotations(root)
+	if err != nil {
+		t.Fatalf("LoadRotations failed: %v", err)
+	}
+
+	// Edits update lastmodified, but not the recorded rotation
+	edited := &FileMetadata{LastModified: rotated.AddDate(0, 2, 0)}
+	if got := loaded.LastRotated("secrets/db.enc.yaml", edited); !got.Equal(rotated) {
+		t.Errorf("Expected last rotation %v, got %v", rotated, got)
+	}
+
+	// Files never rotated fall back to lastmodified
+	if got := loaded.LastRotated("secrets/api.enc.yaml", edited); !got.Equal(edited.LastModified) {
+		t.Errorf("Expected last rotation %v, got %v", edited.LastModified, got)
+	}
+}
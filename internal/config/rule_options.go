@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RuleOptions are advanced sops options of a creation rule
+// Options that are nil are left as they are in the rule.
+type RuleOptions struct {
+	// MacOnlyEncrypted computes the MAC over encrypted values only, so
+	// unencrypted values can be changed without sops
+	MacOnlyEncrypted *bool
+	// EncryptedCommentRegex encrypts comments matching the regex
+	EncryptedCommentRegex *string
+}
+
+// IsZero reports whether no option is set
+func (o RuleOptions) IsZero() bool {
+	return o.MacOnlyEncrypted == nil && o.EncryptedCommentRegex == nil
+}
+
+// Validate checks that the comment regex compiles
+func (o RuleOptions) Validate() error {
+	if o.EncryptedCommentRegex == nil || *o.EncryptedCommentRegex == "" {
+		return nil
+	}
+	if _, err := regexp.Compile(*o.EncryptedCommentRegex); err != nil {
+		return fmt.Errorf("invalid encrypted_comment_regex: %w", err)
+	}
+	return nil
+}
+
+// MinSopsVersion returns the oldest sops version supporting the options
+// Returns an empty string if every sops version does.
+func (o RuleOptions) MinSopsVersion() string {
+	switch {
+	case o.MacOnlyEncrypted != nil && *o.MacOnlyEncrypted:
+		return "3.9.0"
+	case o.EncryptedCommentRegex != nil && *o.EncryptedCommentRegex != "":
+		return "3.7.0"
+	default:
+		return ""
+	}
+}
+
+// SetRuleOptions sets the advanced options of the rule for a file
+// The file is given by its path relative to .sops.yaml. Options that are
+// nil keep their value.
+func SetRuleOptions(config *SopsConfig, filename string, options RuleOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	for i, rule := range config.CreationRules {
+		if ruleMatchesFile(rule, filename) {
+			if options.MacOnlyEncrypted != nil {
+				config.CreationRules[i].MacOnlyEncrypted = *options.MacOnlyEncrypted
+			}
+			if options.EncryptedCommentRegex != nil {
+				config.CreationRules[i].EncryptedCommentRegex = *options.EncryptedCommentRegex
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no rule found for %s", filename)
+}
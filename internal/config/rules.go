@@ -0,0 +1,87 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ruleAnchorPrefix starts the path regex of a file rule, so the rule matches
+// both absolute paths and paths relative to the directory holding .sops.yaml
+const ruleAnchorPrefix = "(^|/)"
+
+// RulePathRegex returns the path regex of the creation rule for a file
+// The path is relative to the directory holding .sops.yaml. The regex is
+// anchored, so a rule for config.yaml doesn't also match prod-config.yaml.
+func RulePathRegex(relPath string) string {
+	return ruleAnchorPrefix + regexp.QuoteMeta(filepath.ToSlash(relPath)) + "$"
+}
+
+// RulePath returns the file path a creation rule was written for
+// Returns false for rules with a real regular expression, such as the catch-all rule.
+func RulePath(pathRegex string) (string, bool) {
+	if IsLegacyRule(pathRegex) {
+		return pathRegex, true
+	}
+
+	if !strings.HasPrefix(pathRegex, ruleAnchorPrefix) || !strings.HasSuffix(pathRegex, "$") {
+		return "", false
+	}
+
+	quoted := strings.TrimSuffix(strings.TrimPrefix(pathRegex, ruleAnchorPrefix), "$")
+	path := unquoteMeta(quoted)
+	if regexp.QuoteMeta(path) != quoted {
+		return "", false
+	}
+
+	return path, true
+}
+
+// IsLegacyRule checks if a path regex is a plain, unanchored file path
+// Older versions wrote the file name as is, which sops matches anywhere in a
+// path and with "." matching any character.
+func IsLegacyRule(pathRegex string) bool {
+	return pathRegex != "" && !strings.ContainsAny(pathRegex, `\^$*+?()[]{}|`)
+}
+
+// unquoteMeta removes the escaping added by regexp.QuoteMeta
+func unquoteMeta(quoted string) string {
+	var path strings.Builder
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] == '\\' && i+1 < len(quoted) {
+			i++
+		}
+		path.WriteByte(quoted[i])
+	}
+	return path.String()
+}
+
+// ConfigRelativePath returns the path of a file relative to the directory holding .sops.yaml
+// Files outside that directory are identified by their file name.
+func ConfigRelativePath(configPath string, filePath string) string {
+	absFile, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Base(filePath)
+	}
+	absDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return filepath.Base(filePath)
+	}
+
+	rel, err := filepath.Rel(absDir, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(filePath)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// ruleMatchesFile checks if a rule was written for a file
+// The file is given by its path relative to .sops.yaml or by a path regex.
+// Legacy rules holding only the file name still match.
+func ruleMatchesFile(rule CreationRule, file string) bool {
+	if rule.PathRegex == file || rule.PathRegex == RulePathRegex(file) {
+		return true
+	}
+	return IsLegacyRule(rule.PathRegex) && rule.PathRegex == filepath.Base(file)
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRulePathRegex(t *testing.T) {
+	pattern := RulePathRegex("config/app.env")
+	if pattern != `(^|/)config/app\.env$` {
+		t.Errorf("Unexpected path regex: %s", pattern)
+	}
+
+	re := regexp.MustCompile(pattern)
+	for path, expected := range map[string]bool{
+		"config/app.env":           true,
+		"/repo/config/app.env":     true,
+		"prod-config/app.env":      false,
+		"config/app.env.bak":       false,
+		"config/appXenv":           false,
+		"other/config/app.env.old": false,
+	} {
+		if got := re.MatchString(path); got != expected {
+			t.Errorf("Match(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+
+	// The file path can be read back from the rule
+	for _, rulePath := range []string{"config/app.env", "a+b (1).yaml"} {
+		if got, ok := RulePath(RulePathRegex(rulePath)); !ok || got != rulePath {
+			t.Errorf("RulePath(RulePathRegex(%q)) = %q, %v", rulePath, got, ok)
+		}
+	}
+	if got, ok := RulePath("app.env"); !ok || got != "app.env" {
+		t.Errorf("Expected legacy rule to hold its file name, got %q, %v", got, ok)
+	}
+	if _, ok := RulePath(WildcardPattern); ok {
+		t.Error("Expected the catch-all rule not to be a file rule")
+	}
+}
+
+func TestLegacyRuleIsUpgraded(t *testing.T) {
+	config := &SopsConfig{CreationRules: []CreationRule{{PathRegex: "app.env", Age: AgeRecipients{"age123"}}}}
+
+	// Legacy rules holding the file name are found by the file's path
+	if _, found := GetCreationRule(config, "config/app.env"); !found {
+		t.Fatal("Expected legacy rule to be found")
+	}
+
+	if err := AddCreationRule(config, "config/app.env", "age456", "", WildcardPolicy{Mode: WildcardNone}); err != nil {
+		t.Fatalf("AddCreationRule failed: %v", err)
+	}
+	if len(config.CreationRules) != 1 {
+		t.Fatalf("Expected the legacy rule to be updated, got %d rules", len(config.CreationRules))
+	}
+	if rule := config.CreationRules[0]; rule.PathRegex != RulePathRegex("config/app.env") || rule.Age.String() != "age456" {
+		t.Errorf("Unexpected rule after update: %+v", rule)
+	}
+
+	if err := RemoveCreationRule(config, "config/app.env"); err != nil {
+		t.Fatalf("RemoveCreationRule failed: %v", err)
+	}
+	if len(config.CreationRules) != 0 {
+		t.Errorf("Expected no rules, got %d", len(config.CreationRules))
+	}
+}
+
+func TestCleanOrphanedRules(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "config"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "app.env"), []byte("A=1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	config := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("config/app.env")},
+		{PathRegex: RulePathRegex("config/gone.env")},
+		{PathRegex: `^secrets/.*\.yaml$`},
+		{PathRegex: WildcardPattern},
+	}}
+
+	removed, err := CleanOrphanedRules(config, root)
+	if err != nil {
+		t.Fatalf("CleanOrphanedRules failed: %v", err)
+	}
+	if removed != 1 || len(config.CreationRules) != 3 {
+		t.Errorf("Expected 1 orphaned rule, removed %d and kept %v", removed, config.CreationRules)
+	}
+	if _, found := GetCreationRule(config, "config/gone.env"); found {
+		t.Error("Expected the rule of the missing file to be removed")
+	}
+}
+
+func TestConfigRelativePath(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, ".sops.yaml")
+
+	if got := ConfigRelativePath(configPath, filepath.Join(root, "config", "app.env")); got != "config/app.env" {
+		t.Errorf("Expected config/app.env, got %s", got)
+	}
+	if got := ConfigRelativePath(configPath, filepath.Join(filepath.Dir(root), "elsewhere", "app.env")); got != "app.env" {
+		t.Errorf("Expected files outside the repository to use their name, got %s", got)
+	}
+}
+
+func TestCoveringRule(t *testing.T) {
+	config := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("k8s/secret.yaml")},
+		{PathRegex: `^secrets/.*\.json$`},
+		{PathRegex: WildcardPattern},
+	}}
+
+	tests := map[string]bool{
+		"k8s/secret.yaml":     true,
+		"secrets/prod.json":   true,
+		"k8s/deployment.yaml": false,
+		"README.md":           false,
+	}
+
+	for path, expected := range tests {
+		if _, covered := CoveringRule(config, path); covered != expected {
+			t.Errorf("CoveringRule(%q) = %v, expected %v", path, covered, expected)
+		}
+	}
+}
+
+func TestCompareRecipients(t *testing.T) {
+	config := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secret.yaml"), Age: AgeRecipients{"age1alice", "age1bob"}},
+		{PathRegex: `^secrets/.*\.json$`, Age: AgeRecipients{"age1alice"}},
+	}}
+
+	drift := CompareRecipients(config, "secret.yaml", []string{"age1bob", "age1alice"})
+	if drift.Drifted() {
+		t.Errorf("Expected no drift for the same recipients in another order, got %+v", drift)
+	}
+
+	drift = CompareRecipients(config, "secret.yaml", []string{"age1alice", "age1carol"})
+	if !reflect.DeepEqual(drift.Missing, []string{"age1bob"}) || !reflect.DeepEqual(drift.Extra, []string{"age1carol"}) {
+		t.Errorf("Expected age1bob missing and age1carol extra, got %+v", drift)
+	}
+	if drift.PathRegex != RulePathRegex("secret.yaml") {
+		t.Errorf("Expected the matching rule, got %q", drift.PathRegex)
+	}
+
+	drift = CompareRecipients(config, "other.yaml", []string{"age1alice"})
+	if !drift.Drifted() || drift.PathRegex != "" {
+		t.Errorf("Expected a file without a rule to drift, got %+v", drift)
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	config := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: `^secrets/.*`, Age: AgeRecipients{"age1"}},
+		{PathRegex: RulePathRegex("secrets/db.yaml"), Age: AgeRecipients{"age2"}},
+		{PathRegex: RulePathRegex("app.env"), Age: AgeRecipients{"age3"}},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1"}},
+		{PathRegex: `.*\.toml`, Age: AgeRecipients{"age4"}},
+	}}
+
+	conflicts := FindConflicts(config)
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].Rule.PathRegex != RulePathRegex("secrets/db.yaml") || conflicts[0].ShadowedBy.PathRegex != `^secrets/.*` {
+		t.Errorf("Unexpected conflict: %+v", conflicts[0])
+	}
+	if conflicts[1].Rule.PathRegex != `.*\.toml` || conflicts[1].ShadowedBy.PathRegex != WildcardPattern {
+		t.Errorf("Unexpected conflict: %+v", conflicts[1])
+	}
+
+	if _, found := ConflictFor(config, "secrets/db.yaml"); !found {
+		t.Error("Expected the rule for secrets/db.yaml to be shadowed")
+	}
+	if _, found := ConflictFor(config, "app.env"); found {
+		t.Error("Expected the rule for app.env not to be shadowed")
+	}
+
+	// Reordering puts file rules first and the catch-all rule last
+	resolved := ReorderRules(config)
+	if len(resolved) != 2 {
+		t.Errorf("Expected 2 resolved conflicts, got %+v", resolved)
+	}
+	var order []string
+	for _, rule := range config.CreationRules {
+		order = append(order, rule.PathRegex)
+	}
+	expected := []string{RulePathRegex("secrets/db.yaml"), RulePathRegex("app.env"), `^secrets/.*`, `.*\.toml`, WildcardPattern}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Unexpected order %v, expected %v", order, expected)
+	}
+	if conflicts := FindConflicts(config); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts after reordering, got %+v", conflicts)
+	}
+}
+
+func TestImportRules(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, ".sops.yaml")
+
+	files := map[string]string{
+		"secrets/db.yaml": "password: ENC[AES256_GCM,data:abc,type:str]\nsops:\n    age:\n        - recipient: age1a\n        - recipient: age1b\n    encrypted_regex: ^(password)$\n",
+		"app.env":         "TOKEN=ENC[AES256_GCM,data:abc,type:str]\nsops_age__list_0__map_recipient=age1c\n",
+		"covered.yaml":    "password: ENC[AES256_GCM,data:abc,type:str]\nsops:\n    age:\n        - recipient: age1d\n",
+		"kms.yaml":        "password: ENC[AES256_GCM,data:abc,type:str]\nsops:\n    kms:\n        - arn: arn:aws:kms\n    version: 3.8.1\n",
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("covered.yaml"), Age: AgeRecipients{"age1x"}},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1x"}},
+	}}
+
+	imported, err := ImportRules(sopsConfig, configPath, paths)
+	if err != nil {
+		t.Fatalf("ImportRules failed: %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("Expected 2 imported rules, got %+v", imported)
+	}
+
+	rule, found := CoveringRule(sopsConfig, "secrets/db.yaml")
+	if !found || rule.Age.String() != "age1a,age1b" || rule.EncryptedRegex != "^(password)$" {
+		t.Errorf("Unexpected rule for secrets/db.yaml: %+v", rule)
+	}
+	if rule, found := CoveringRule(sopsConfig, "app.env"); !found || rule.Age.String() != "age1c" {
+		t.Errorf("Unexpected rule for app.env: %+v", rule)
+	}
+
+	// The existing rule and the catch-all rule are left alone
+	if rule, _ := CoveringRule(sopsConfig, "covered.yaml"); rule.Age.String() != "age1x" {
+		t.Errorf("Expected the existing rule to be kept, got %+v", rule)
+	}
+	last := sopsConfig.CreationRules[len(sopsConfig.CreationRules)-1]
+	if last.PathRegex != WildcardPattern || last.Age.String() != "age1x" {
+		t.Errorf("Expected the catch-all rule to stay last and unchanged, got %+v", last)
+	}
+	if len(FindConflicts(sopsConfig)) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", FindConflicts(sopsConfig))
+	}
+}
+
+func TestNamingConvention(t *testing.T) {
+	var naming NamingConvention
+	for plain, encrypted := range map[string]string{
+		"secrets.yaml":          "secrets.enc.yaml",
+		"config/app.env":        "config/app.enc.env",
+		".env":                  ".enc.env",
+		".env.production":       ".env.enc.production",
+		"secrets":               "secrets.enc",
+		"k8s/db.secret.yaml":    "k8s/db.secret.enc.yaml",
+		"settings.example.json": "settings.example.enc.json",
+	} {
+		if got := naming.EncryptedName(plain); got != encrypted {
+			t.Errorf("EncryptedName(%q) = %q, expected %q", plain, got, encrypted)
+		}
+		if got, ok := naming.PlainName(encrypted); !ok || got != plain {
+			t.Errorf("PlainName(%q) = %q, %v, expected %q", encrypted, got, ok, plain)
+		}
+	}
+
+	if _, ok := naming.PlainName("secrets.yaml"); ok {
+		t.Error("Expected secrets.yaml not to follow the convention")
+	}
+
+	custom := NamingConvention{Infix: "sops"}
+	if got := custom.EncryptedName("secrets.yaml"); got != "secrets.sops.yaml" {
+		t.Errorf("Unexpected name with custom infix: %s", got)
+	}
+	if err := (NamingConvention{Infix: "a.b"}).Validate(); err == nil {
+		t.Error("Expected an infix with a dot to be rejected")
+	}
+}
+
+func TestAddRecipients(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secrets.yaml"), Age: AgeRecipients{"age1alice"}, EncryptedRegex: "^data$"},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1alice"}},
+	}}
+
+	added, err := AddRecipients(sopsConfig, "secrets.yaml", []string{"age1alice", "age1bob"})
+	if err != nil {
+		t.Fatalf("AddRecipients failed: %v", err)
+	}
+	if len(added) != 1 || added[0] != "age1bob" {
+		t.Errorf("Expected age1bob to be added, got %v", added)
+	}
+
+	rule, _ := GetCreationRule(sopsConfig, "secrets.yaml")
+	if rule.Age.String() != "age1alice,age1bob" || rule.EncryptedRegex != "^data$" {
+		t.Errorf("Unexpected rule after adding recipients: %+v", rule)
+	}
+	if sopsConfig.CreationRules[1].Age.String() != "age1alice" {
+		t.Errorf("Expected the catch-all rule to be left alone, got %s", sopsConfig.CreationRules[1].Age)
+	}
+
+	if _, err := AddRecipients(sopsConfig, "missing.yaml", []string{"age1bob"}); err == nil {
+		t.Error("Expected an error for a file without rule")
+	}
+}
+
+func TestReplaceRecipients(t *testing.T) {
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secrets.yaml"), Age: AgeRecipients{"age1old", "age1bob"}},
+		{PathRegex: RulePathRegex("other.yaml"), Age: AgeRecipients{"age1bob"}, KMS: "arn:aws:kms:eu-west-1:1:key/a"},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1old"}, KMS: "arn:aws:kms:eu-west-1:1:key/a"},
+	}}
+
+	changed := ReplaceRecipients(sopsConfig, []string{"age1old"}, []string{"age1new", "age1bob"}, []string{"arn:aws:kms:eu-west-1:1:key/b"})
+	if len(changed) != 2 {
+		t.Fatalf("Expected 2 changed rules, got %v", changed)
+	}
+
+	want := []CreationRule{
+		{PathRegex: RulePathRegex("secrets.yaml"), Age: AgeRecipients{"age1bob", "age1new"}, KMS: "arn:aws:kms:eu-west-1:1:key/b"},
+		{PathRegex: RulePathRegex("other.yaml"), Age: AgeRecipients{"age1bob"}, KMS: "arn:aws:kms:eu-west-1:1:key/a"},
+		{PathRegex: WildcardPattern, Age: AgeRecipients{"age1new", "age1bob"}, KMS: "arn:aws:kms:eu-west-1:1:key/a,arn:aws:kms:eu-west-1:1:key/b"},
+	}
+	for i, rule := range sopsConfig.CreationRules {
+		if rule.Age.String() != want[i].Age.String() || rule.KMS != want[i].KMS {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, want[i], rule)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// lockTimeout is how long Update waits for another process to release the lock
+	lockTimeout = 10 * time.Second
+
+	// lockStaleAge is the age after which a lock is considered left behind by a crashed process
+	lockStaleAge = time.Minute
+
+	// lockRetryInterval is how often a held lock is checked
+	lockRetryInterval = 20 * time.Millisecond
+)
+
+// RuleSet is the set of creation rules stored in a .sops.yaml file
+// Changes go through Update, which reloads the file under an exclusive lock
+// and saves it atomically, so concurrent simple-sops processes don't lose or
+// duplicate each other's rules.
+type RuleSet struct {
+	path string
+}
+
+// NewRuleSet returns the rule set stored at the given .sops.yaml path
+func NewRuleSet(configPath string) *RuleSet {
+	return &RuleSet{path: configPath}
+}
+
+// Path returns the path of the .sops.yaml file
+func (s *RuleSet) Path() string {
+	return s.path
+}
+
+// Load reads the current rules without locking
+// Use it for reading or previewing changes, never to save them again.
+func (s *RuleSet) Load() (*SopsConfig, error) {
+	return LoadSopsConfig(s.path)
+}
+
+// Preview applies a change to the current rules without saving it
+func (s *RuleSet) Preview(fn func(*SopsConfig) error) (*SopsConfig, error) {
+	sopsConfig, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(sopsConfig); err != nil {
+		return nil, err
+	}
+	return sopsConfig, nil
+}
+
+// Update applies a change to the rules as a transaction
+// The file is loaded under an exclusive lock, passed to fn and saved
+// atomically. Nothing is written if fn returns an error or leaves the rules
+// unchanged. The resulting config is returned.
+func (s *RuleSet) Update(fn func(*SopsConfig) error) (*SopsConfig, error) {
+	if err := checkWritable(s.path); err != nil {
+		return nil, err
+	}
+
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	sopsConfig, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := yaml.Marshal(sopsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+
+	if err := fn(sopsConfig); err != nil {
+		return nil, err
+	}
+
+	after, err := yaml.Marshal(sopsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+	if bytes.Equal(before, after) {
+		return sopsConfig, nil
+	}
+
+	if err := SaveSopsConfig(s.path, sopsConfig); err != nil {
+		return nil, err
+	}
+	signUpdatedConfig(s.path)
+	return sopsConfig, nil
+}
+
+// lockFile takes an exclusive lock by creating the lock file
+// A lock older than lockStaleAge is taken over. The returned function
+// releases the lock.
+func lockFile(lockPath string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock SOPS config: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStaleAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s, remove it if no other simple-sops process is running", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
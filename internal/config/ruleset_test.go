@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRuleSetUpdateConcurrent(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ".sops.yaml")
+	rules := NewRuleSet(configPath)
+
+	// Every update adds its own rule, none may be lost
+	const updates = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := NewRuleSet(configPath).Update(func(sopsConfig *SopsConfig) error {
+				return AddCreationRule(sopsConfig, fmt.Sprintf("file%d.yaml", i), "age1test", "", WildcardPolicy{Mode: WildcardNone})
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	sopsConfig, err := rules.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(sopsConfig.CreationRules) != updates {
+		t.Fatalf("Expected %d rules, got %d", updates, len(sopsConfig.CreationRules))
+	}
+
+	if _, err := os.Stat(configPath + ".lock"); !os.IsNotExist(err) {
+		t.Error("Expected the lock to be released")
+	}
+}
+
+func TestRuleSetUpdate(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ".sops.yaml")
+	rules := NewRuleSet(configPath)
+
+	// A failed change isn't saved
+	failure := errors.New("failure")
+	_, err := rules.Update(func(sopsConfig *SopsConfig) error {
+		if err := AddCreationRule(sopsConfig, "app.env", "age1test", "", WildcardPolicy{Mode: WildcardNone}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the change's error, got %v", err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Fatal("Expected no config to be written")
+	}
+
+	// A change without effect doesn't write the file
+	if _, err := rules.Update(func(*SopsConfig) error { return nil }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Fatal("Expected no config to be written for an unchanged rule set")
+	}
+
+	// Previewing a change doesn't save it
+	preview, err := rules.Preview(func(sopsConfig *SopsConfig) error {
+		return AddCreationRule(sopsConfig, "app.env", "age1test", "", WildcardPolicy{Mode: WildcardNone})
+	})
+	if err != nil || len(preview.CreationRules) != 1 {
+		t.Fatalf("Unexpected preview: %v, %v", preview, err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Fatal("Expected the preview not to write the config")
+	}
+}
+
+func TestRuleSetStaleLock(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ".sops.yaml")
+	lockPath := configPath + ".lock"
+
+	// A lock left behind by a crashed process is taken over
+	if err := os.WriteFile(lockPath, []byte("0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lock: %v", err)
+	}
+	old := time.Now().Add(-2 * lockStaleAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("Failed to age lock: %v", err)
+	}
+
+	if _, err := NewRuleSet(configPath).Update(func(sopsConfig *SopsConfig) error {
+		return AddCreationRule(sopsConfig, "app.env", "age1test", "", WildcardPolicy{Mode: WildcardNone})
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// A held lock times out
+	originalTimeout := lockTimeout
+	lockTimeout = 50 * time.Millisecond
+	defer func() { lockTimeout = originalTimeout }()
+
+	if err := os.WriteFile(lockPath, []byte("0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lock: %v", err)
+	}
+	if _, err := NewRuleSet(configPath).Update(func(*SopsConfig) error { return nil }); err == nil {
+		t.Error("Expected a held lock to time out")
+	}
+}
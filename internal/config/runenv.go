@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// envPatternRegex matches an environment variable name, optionally ending in * to match a prefix
+var envPatternRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\*?$|^\*$`)
+
+// RunEnvSettings control the environment of commands started by run
+type RunEnvSettings struct {
+	// Clean starts commands from a minimal environment instead of inheriting everything
+	Clean bool `yaml:"clean,omitempty"`
+	// Allow lists more variables a clean environment passes on, as NAME or PREFIX_*
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// Validate checks that the allowed variables are names or prefixes
+func (r RunEnvSettings) Validate() error {
+	for _, pattern := range r.Allow {
+		if !envPatternRegex.MatchString(pattern) {
+			return fmt.Errorf("run_env: invalid variable %q, expected a name like AWS_PROFILE or a prefix like AWS_*", pattern)
+		}
+	}
+	return nil
+}
+
+// ActiveRunEnv returns the run environment settings with those of the active profile
+// A profile can turn on a clean environment and allows its variables in
+// addition to the global ones.
+func (c *AppConfig) ActiveRunEnv() RunEnvSettings {
+	settings := RunEnvSettings{Clean: c.RunEnv.Clean, Allow: append([]string(nil), c.RunEnv.Allow...)}
+	if profile, ok := c.ActiveProfile(); ok {
+		settings.Clean = settings.Clean || profile.RunEnv.Clean
+		settings.Allow = append(settings.Allow, profile.RunEnv.Allow...)
+	}
+
+	return settings
+}
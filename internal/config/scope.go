@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ResolveScope returns a directory relative to the directory holding .sops.yaml
+// A scope confines commands to the sub-tree of a monorepo that one service
+// owns. The repository root itself is returned as "", meaning no scope.
+func ResolveScope(configPath string, dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid scope: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid scope: %s is not a directory", dir)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	absRoot, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", configPath, err)
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid scope: %s is outside of %s", dir, absRoot)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// InScope checks if a path relative to .sops.yaml is inside the scope
+func InScope(relPath string, scope string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return scope == "" || relPath == scope || strings.HasPrefix(relPath, scope+"/")
+}
+
+// ScopedWildcardPattern returns the path regex of the catch-all rule of a scope
+// Without a scope it is the catch-all rule of the whole repository.
+func ScopedWildcardPattern(scope string) string {
+	if scope == "" {
+		return WildcardPattern
+	}
+	return ruleAnchorPrefix + regexp.QuoteMeta(scope) + "/" + WildcardPattern
+}
+
+// IsWildcardRule checks if a path regex is the catch-all rule of the repository or of a scope
+func IsWildcardRule(pathRegex string) bool {
+	if pathRegex == WildcardPattern {
+		return true
+	}
+	if !strings.HasPrefix(pathRegex, ruleAnchorPrefix) || !strings.HasSuffix(pathRegex, "/"+WildcardPattern) {
+		return false
+	}
+	quoted := strings.TrimSuffix(strings.TrimPrefix(pathRegex, ruleAnchorPrefix), "/"+WildcardPattern)
+	return quoted != "" && regexp.QuoteMeta(unquoteMeta(quoted)) == quoted
+}
+
+// RuleInScope checks if a rule only applies to files inside the scope
+// File rules are in scope if their file is, and catch-all rules if they were
+// written for the scope or a directory below it. Other patterns can't be
+// attributed to a directory and are only in scope without a scope.
+func RuleInScope(pathRegex string, scope string) bool {
+	if scope == "" {
+		return true
+	}
+	if path, isFileRule := RulePath(pathRegex); isFileRule && !IsLegacyRule(pathRegex) {
+		return InScope(path, scope)
+	}
+	if IsWildcardRule(pathRegex) && pathRegex != WildcardPattern {
+		quoted := strings.TrimSuffix(strings.TrimPrefix(pathRegex, ruleAnchorPrefix), "/"+WildcardPattern)
+		return InScope(unquoteMeta(quoted), scope)
+	}
+	return false
+}
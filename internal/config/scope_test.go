@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveScope(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, ".sops.yaml")
+	if err := os.MkdirAll(filepath.Join(root, "services", "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir     string
+		want    string
+		wantErr bool
+	}{
+		{dir: "", want: ""},
+		{dir: root, want: ""},
+		{dir: filepath.Join(root, "services", "api"), want: "services/api"},
+		{dir: filepath.Join(root, "services", "missing"), wantErr: true},
+		{dir: filepath.Dir(root), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveScope(configPath, tt.dir)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveScope(%q) = %q, %v, want %q, error %v", tt.dir, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if !InScope("services/api/secret.yaml", "services/api") || InScope("services/api-v2/secret.yaml", "services/api") {
+		t.Error("Expected only files below the scope to be in scope")
+	}
+}
+
+func TestScopedWildcardRule(t *testing.T) {
+	config := &SopsConfig{}
+	if err := AddCreationRule(config, "a.env", "age123", "", WildcardPolicy{}); err != nil {
+		t.Fatalf("AddCreationRule failed: %v", err)
+	}
+	if err := AddCreationRule(config, "services/api/b.env", "age456", "", WildcardPolicy{Scope: "services/api"}); err != nil {
+		t.Fatalf("AddCreationRule failed: %v", err)
+	}
+
+	// The catch-all rule of the scope goes before the one of the repository
+	pattern := ScopedWildcardPattern("services/api")
+	last := len(config.CreationRules) - 1
+	if config.CreationRules[last].PathRegex != WildcardPattern || config.CreationRules[last-1].PathRegex != pattern {
+		t.Fatalf("Expected the scoped catch-all rule before the global one, got %+v", config.CreationRules)
+	}
+	if config.CreationRules[last].Age.String() != "age123" || config.CreationRules[last-1].Age.String() != "age456" {
+		t.Errorf("Expected each catch-all rule to keep its own recipients, got %+v", config.CreationRules)
+	}
+
+	// A file of the scope without its own rule isn't required to be encrypted
+	if rule, found := CoveringRule(config, "services/api/other.yaml"); found {
+		t.Errorf("Expected the scoped catch-all rule not to cover files, got %+v", rule)
+	}
+	if rule, found := MatchingRule(config, "services/api/other.yaml"); !found || rule.PathRegex != pattern {
+		t.Errorf("Expected the scoped catch-all rule to match, got %+v", rule)
+	}
+
+	tests := []struct {
+		pathRegex string
+		scope     string
+		want      bool
+	}{
+		{pathRegex: RulePathRegex("services/api/b.env"), scope: "services/api", want: true},
+		{pathRegex: RulePathRegex("a.env"), scope: "services/api", want: false},
+		{pathRegex: pattern, scope: "services/api", want: true},
+		{pathRegex: pattern, scope: "services", want: true},
+		{pathRegex: WildcardPattern, scope: "services/api", want: false},
+		{pathRegex: WildcardPattern, scope: "", want: true},
+		{pathRegex: `^k8s/`, scope: "services/api", want: false},
+	}
+	for _, tt := range tests {
+		if got := RuleInScope(tt.pathRegex, tt.scope); got != tt.want {
+			t.Errorf("RuleInScope(%q, %q) = %v, want %v", tt.pathRegex, tt.scope, got, tt.want)
+		}
+	}
+}
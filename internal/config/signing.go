@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SignatureSuffix is appended to the name of .sops.yaml to name its signature
+	SignatureSuffix = ".sig"
+
+	// SignatureNamespace keeps signatures of .sops.yaml from being valid for anything else
+	SignatureNamespace = "simple-sops-config"
+
+	// DefaultAllowedSignersFile lists the trusted signers, in the config directory
+	DefaultAllowedSignersFile = "allowed_signers"
+)
+
+// Use a variable for ssh-keygen to allow mocking in tests
+var sshKeygenCommand = exec.Command
+
+// ConfigSigning controls the signature of .sops.yaml
+// An attacker who can change .sops.yaml could add their recipient to the
+// rules and read every file encrypted afterwards. Signatures are made with SSH
+// keys and checked against a local allowed signers file, which the
+// repository can't change.
+type ConfigSigning struct {
+	// Key is the SSH key signing .sops.yaml, a private key or the public key of an agent key
+	Key string `yaml:"key,omitempty"`
+	// AllowedSigners is the allowed signers file of ssh-keygen, in the config directory by default
+	AllowedSigners string `yaml:"allowed_signers,omitempty"`
+}
+
+// SignatureStatus is the result of checking the signature of .sops.yaml
+type SignatureStatus int
+
+const (
+	// SignatureDisabled means no allowed signers are configured
+	SignatureDisabled SignatureStatus = iota
+	// SignatureMissing means .sops.yaml has no signature
+	SignatureMissing
+	// SignatureInvalid means .sops.yaml changed since it was signed or the signer isn't allowed
+	SignatureInvalid
+	// SignatureValid means an allowed signer signed the current .sops.yaml
+	SignatureValid
+)
+
+// SignaturePath returns the path of the signature of a .sops.yaml file
+func SignaturePath(configPath string) string {
+	return configPath + SignatureSuffix
+}
+
+// AllowedSignersPath returns the path of the allowed signers file
+func (s ConfigSigning) AllowedSignersPath() (string, error) {
+	if s.AllowedSigners != "" {
+		return expandHome(s.AllowedSigners)
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, DefaultAllowedSignersFile), nil
+}
+
+// LoadConfigSigning reads only the signing settings of the application config
+// Like LoadAliases, it doesn't validate or warn about the other settings.
+func LoadConfigSigning() (ConfigSigning, error) {
+	configPath, err := GetConfigFilePath()
+	if err != nil {
+		return ConfigSigning{}, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return ConfigSigning{}, nil
+	}
+	if err != nil {
+		return ConfigSigning{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var partial struct {
+		ConfigSigning ConfigSigning `yaml:"config_signing"`
+	}
+	if err := yaml.Unmarshal(data, &partial); err != nil {
+		return ConfigSigning{}, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	return partial.ConfigSigning, nil
+}
+
+// SignConfig signs a .sops.yaml file with an SSH key
+// The signature is written next to the file.
+func SignConfig(configPath string, key string) error {
+	keyPath, err := expandHome(key)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	cmd := sshKeygenCommand("ssh-keygen", "-Y", "sign", "-f", keyPath, "-n", SignatureNamespace)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	signature, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w\n%s", configPath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err := os.WriteFile(SignaturePath(configPath), signature, 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// CheckConfigSignature checks that an allowed signer signed the current .sops.yaml
+// It returns the status and, for valid signatures, the signer. Signatures
+// are only checked once the allowed signers file exists.
+func CheckConfigSignature(configPath string, signing ConfigSigning) (SignatureStatus, string, error) {
+	allowedSigners, err := signing.AllowedSignersPath()
+	if err != nil {
+		return SignatureDisabled, "", err
+	}
+	if _, err := os.Stat(allowedSigners); os.IsNotExist(err) {
+		return SignatureDisabled, "", nil
+	}
+
+	signaturePath := SignaturePath(configPath)
+	if _, err := os.Stat(signaturePath); os.IsNotExist(err) {
+		return SignatureMissing, "", nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return SignatureInvalid, "", fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	// Find who claims to have signed, then verify the content for them
+	output, err := sshKeygenCommand("ssh-keygen", "-Y", "find-principals", "-s", signaturePath, "-f", allowedSigners).Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return SignatureInvalid, "", fmt.Errorf("failed to run ssh-keygen: %w", err)
+	}
+	if err != nil {
+		return SignatureInvalid, "", nil
+	}
+	for _, principal := range strings.Fields(string(output)) {
+		cmd := sshKeygenCommand("ssh-keygen", "-Y", "verify", "-f", allowedSigners, "-I", principal, "-n", SignatureNamespace, "-s", signaturePath)
+		cmd.Stdin = bytes.NewReader(data)
+		if err := cmd.Run(); err == nil {
+			return SignatureValid, principal, nil
+		}
+	}
+	return SignatureInvalid, "", nil
+}
+
+// signUpdatedConfig signs .sops.yaml again after simple-sops changed it
+// Nothing is signed without a configured key.
+func signUpdatedConfig(configPath string) {
+	signing, err := LoadConfigSigning()
+	if err != nil || signing.Key == "" {
+		return
+	}
+	if err := SignConfig(configPath, signing.Key); err != nil {
+		logging.Warn("%v", err)
+		return
+	}
+	logging.Debug("Signed %s", configPath)
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
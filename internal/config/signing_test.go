@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// setupSigningKey creates an SSH key and an allowed signers file trusting it
+func setupSigningKey(t *testing.T) (string, ConfigSigning) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not available")
+	}
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "alice", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, output)
+	}
+	publicKey, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowedSigners := filepath.Join(dir, "allowed_signers")
+	if err := os.WriteFile(allowedSigners, append([]byte("alice@example.com "), publicKey...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return keyPath, ConfigSigning{Key: keyPath, AllowedSigners: allowedSigners}
+}
+
+func TestConfigSignature(t *testing.T) {
+	keyPath, signing := setupSigningKey(t)
+	configPath := filepath.Join(t.TempDir(), ".sops.yaml")
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: .*\n    age: age1team\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want SignatureStatus) {
+		t.Helper()
+		status, _, err := CheckConfigSignature(configPath, signing)
+		if err != nil || status != want {
+			t.Errorf("CheckConfigSignature() = %v, %v, want %v", status, err, want)
+		}
+	}
+
+	check(SignatureMissing)
+
+	if err := SignConfig(configPath, keyPath); err != nil {
+		t.Fatalf("SignConfig() error = %v", err)
+	}
+	status, signer, err := CheckConfigSignature(configPath, signing)
+	if err != nil || status != SignatureValid || signer != "alice@example.com" {
+		t.Errorf("CheckConfigSignature() = %v, %q, %v, want valid by alice@example.com", status, signer, err)
+	}
+
+	// An added recipient invalidates the signature
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: .*\n    age: age1team,age1attacker\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check(SignatureInvalid)
+
+	// Without allowed signers nothing is checked
+	signing.AllowedSigners = filepath.Join(t.TempDir(), "missing")
+	check(SignatureDisabled)
+}
+
+func TestRuleSetUpdateSigns(t *testing.T) {
+	keyPath, signing := setupSigningKey(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configDir, err := GetConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := "config_signing:\n  key: " + keyPath + "\n  allowed_signers: " + signing.AllowedSigners + "\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), ".sops.yaml")
+	_, err = NewRuleSet(configPath).Update(func(sopsConfig *SopsConfig) error {
+		return AddCreationRule(sopsConfig, "secrets.yaml", "age1team", "", WildcardPolicy{})
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	loaded, err := LoadConfigSigning()
+	if err != nil {
+		t.Fatalf("LoadConfigSigning() error = %v", err)
+	}
+	if status, _, err := CheckConfigSignature(configPath, loaded); err != nil || status != SignatureValid {
+		t.Errorf("CheckConfigSignature() = %v, %v after Update, want valid", status, err)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSimilarFileScan is the number of files SimilarFiles looks at before it stops
+// It keeps hints fast in large trees, e.g. with node_modules.
+const maxSimilarFileScan = 10000
+
+// SimilarFiles returns the files below root whose path is close to name, e.g. after a typo
+// Files with a similar name in another directory count as well. The closest
+// come first, at most limit of them. Paths listed in .sopsignore are skipped.
+func SimilarFiles(root string, name string, limit int) ([]string, error) {
+	ignore, err := LoadIgnoreFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(filepath.ToSlash(filepath.Clean(name)))
+	threshold := max(2, len(name)/3)
+
+	type candidate struct {
+		path     string
+		distance int
+	}
+	var candidates []candidate
+	scanned := 0
+	err = WalkFiles(root, ignore, func(file string) error {
+		if scanned++; scanned > maxSimilarFileScan {
+			return fs.SkipAll
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		lower := strings.ToLower(rel)
+		// A similar name in another directory is one step further away
+		distance := min(editDistance(name, lower), editDistance(path.Base(name), path.Base(lower))+1)
+		if distance <= threshold {
+			candidates = append(candidates, candidate{path: rel, distance: distance})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].path < candidates[j].path
+	})
+
+	var files []string
+	for _, c := range candidates {
+		if len(files) == limit {
+			break
+		}
+		files = append(files, c.path)
+	}
+	return files, nil
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
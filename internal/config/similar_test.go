@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSimilarFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"secret.yaml", "k8s/secrets.yaml", "secrets.enc.yaml", "vendor/secrets.yaml", "README.md"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("a: b\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("vendor/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := SimilarFiles(root, "secrets.yaml", 5)
+	if err != nil {
+		t.Fatalf("SimilarFiles() error = %v", err)
+	}
+	want := []string{"k8s/secrets.yaml", "secret.yaml", "secrets.enc.yaml"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("SimilarFiles() = %v, want %v", files, want)
+	}
+
+	if files, _ := SimilarFiles(root, "secrets.yaml", 1); len(files) != 1 {
+		t.Errorf("SimilarFiles() with a limit of 1 = %v", files)
+	}
+	if files, _ := SimilarFiles(root, "database.json", 5); len(files) != 0 {
+		t.Errorf("SimilarFiles() for an unrelated name = %v", files)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"secrets.yaml", "secrets.yaml", 0},
+		{"secrets.yaml", "secret.yaml", 1},
+		{"secrets.yml", "secrets.yaml", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"simple-sops/internal/exitcode"
 	"simple-sops/pkg/logging"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -13,20 +16,77 @@ import (
 
 // SopsConfig represents the structure of a .sops.yaml file
 type SopsConfig struct {
+	// Override stops a .sops.yaml in a subdirectory from inheriting the recipients of its parents
+	Override      bool           `yaml:"override,omitempty"`
 	CreationRules []CreationRule `yaml:"creation_rules"`
 }
 
 // CreationRule represents a rule in the .sops.yaml file
 type CreationRule struct {
-	PathRegex      string `yaml:"path_regex"`
-	Age            string `yaml:"age"`
-	EncryptedRegex string `yaml:"encrypted_regex,omitempty"`
+	PathRegex             string        `yaml:"path_regex"`
+	Age                   AgeRecipients `yaml:"age"`
+	KMS                   string        `yaml:"kms,omitempty"`
+	EncryptedRegex        string        `yaml:"encrypted_regex,omitempty"`
+	EncryptedCommentRegex string        `yaml:"encrypted_comment_regex,omitempty"`
+	MacOnlyEncrypted      bool          `yaml:"mac_only_encrypted,omitempty"`
+	Audiences             []string      `yaml:"audiences,omitempty"`
+}
+
+// AgeRecipients is the list of Age recipients of a creation rule
+// sops accepts a comma-separated string or a YAML list. The string form is
+// written, since every sops version understands it.
+type AgeRecipients []string
+
+// ParseRecipients parses a comma-separated list of recipients
+func ParseRecipients(recipients string) AgeRecipients {
+	return UniqueRecipients(strings.Split(recipients, ","))
+}
+
+// String returns the recipients as a comma-separated list
+func (r AgeRecipients) String() string {
+	return strings.Join(r, ",")
+}
+
+// UnmarshalYAML reads recipients from a comma-separated string or a list
+func (r *AgeRecipients) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*r = ParseRecipients(value.Value)
+	case yaml.SequenceNode:
+		var entries []string
+		if err := value.Decode(&entries); err != nil {
+			return err
+		}
+		*r = ParseRecipients(strings.Join(entries, ","))
+	default:
+		return fmt.Errorf("line %d: age recipients must be a string or a list", value.Line)
+	}
+
+	return nil
+}
+
+// MarshalYAML writes recipients as a comma-separated string
+func (r AgeRecipients) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
+// RepoRoot returns the repository root for the SOPS config at configPath
+// Files kept with the repository, like .sops-keys.yaml and the team registry,
+// are resolved from it.
+func RepoRoot(configPath string) string {
+	return filepath.Dir(configPath)
 }
 
 // GetSopsConfigPath returns the path to the .sops.yaml file
 // If in a Git repository, returns the path at the root of the repository
 // Otherwise, returns the path in the current directory
 func GetSopsConfigPath() (string, error) {
+	// The active project or --config can name the config
+	if sopsConfigOverride != "" {
+		logging.Debug("Using config path: %s", sopsConfigOverride)
+		return sopsConfigOverride, nil
+	}
+
 	// Check if we're in a Git repository
 	if isGitAvailable() {
 		cmd := exec.Command("git", "rev-parse", "--show-toplevel")
@@ -52,23 +112,22 @@ func GetSopsConfigPath() (string, error) {
 
 // LoadSopsConfig loads the .sops.yaml file
 func LoadSopsConfig(configPath string) (*SopsConfig, error) {
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Return empty config if file doesn't exist
+	// Read config file, or the rules of --config standing in for it
+	data, err := readSopsConfigData(configPath)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to read SOPS config file: %w", err))
+	}
+
+	// Return empty config if file doesn't exist
+	if data == nil {
 		return &SopsConfig{
 			CreationRules: []CreationRule{},
 		}, nil
 	}
 
-	// Read and parse config file
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SOPS config file: %w", err)
-	}
-
 	var config SopsConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse SOPS config file: %w", err)
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to parse SOPS config file: %w", err))
 	}
 
 	return &config, nil
@@ -76,6 +135,10 @@ func LoadSopsConfig(configPath string) (*SopsConfig, error) {
 
 // SaveSopsConfig saves the .sops.yaml file
 func SaveSopsConfig(configPath string, config *SopsConfig) error {
+	if err := checkWritable(configPath); err != nil {
+		return err
+	}
+
 	// Create parent directories if they don't exist
 	dir := filepath.Dir(configPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -88,8 +151,29 @@ func SaveSopsConfig(configPath string, config *SopsConfig) error {
 		return fmt.Errorf("failed to marshal SOPS config: %w", err)
 	}
 
-	// Write config file
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	// Write to a temporary file and rename it, so readers never see a partial config
+	temp, err := os.CreateTemp(dir, ".sops.yaml.*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write SOPS config file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(configPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write SOPS config file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write SOPS config file: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write SOPS config file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), configPath); err != nil {
 		return fmt.Errorf("failed to write SOPS config file: %w", err)
 	}
 
@@ -97,105 +181,138 @@ func SaveSopsConfig(configPath string, config *SopsConfig) error {
 }
 
 // AddCreationRule adds or updates a rule in the .sops.yaml file
-func AddCreationRule(config *SopsConfig, filename string, publicKey string, encryptedRegex string) error {
-	// Check if a rule for this file already exists
+// The catch-all rule is maintained according to the wildcard policy
+func AddCreationRule(config *SopsConfig, filename string, publicKey string, encryptedRegex string, wildcard WildcardPolicy) error {
+	return AddCreationRuleWithMultipleKeys(config, filename, []string{publicKey}, encryptedRegex, wildcard)
+}
+
+// RemoveCreationRule removes the rule for a file from the .sops.yaml file
+func RemoveCreationRule(config *SopsConfig, filename string) error {
 	for i, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
-			// Update existing rule
-			config.CreationRules[i].Age = publicKey
-			if encryptedRegex != "" {
-				config.CreationRules[i].EncryptedRegex = encryptedRegex
-			}
-			// Don't return yet, we still need to check for the wildcard rule
-			break
+		if ruleMatchesFile(rule, filename) {
+			// Remove rule
+			config.CreationRules = append(config.CreationRules[:i], config.CreationRules[i+1:]...)
+			return nil
 		}
 	}
 
-	// Create new rule if it doesn't exist
-	ruleExists := false
-	for _, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
-			ruleExists = true
-			break
-		}
-	}
+	return fmt.Errorf("no rule found for %s", filename)
+}
 
-	if !ruleExists {
-		// Create new rule
-		rule := CreationRule{
-			PathRegex: filename,
-			Age:       publicKey,
-		}
-		if encryptedRegex != "" {
-			rule.EncryptedRegex = encryptedRegex
+// AddRecipients adds recipients to the rule of a file and returns the ones that were new
+// Unlike AddCreationRuleWithMultipleKeys the existing recipients are kept and
+// the catch-all rule is left alone.
+func AddRecipients(config *SopsConfig, filename string, publicKeys []string) ([]string, error) {
+	for i, rule := range config.CreationRules {
+		if !ruleMatchesFile(rule, filename) {
+			continue
 		}
 
-		// Add the new rule at the beginning of the list
-		config.CreationRules = append([]CreationRule{rule}, config.CreationRules...)
+		var added []string
+		for _, pubKey := range UniqueRecipients(publicKeys) {
+			if !slices.Contains(rule.Age, pubKey) {
+				added = append(added, pubKey)
+			}
+		}
+		config.CreationRules[i].Age = append(slices.Clone(rule.Age), added...)
+		return added, nil
 	}
 
-	// Check if we already have a wildcard rule
-	wildcardPattern := `.*\.(ya?ml|json|ini|env)`
-	hasWildcard := false
+	return nil, fmt.Errorf("no rule found for %s, encrypt it first", filename)
+}
 
-	for _, rule := range config.CreationRules {
-		if rule.PathRegex == wildcardPattern {
-			hasWildcard = true
-			break
+// ReplaceRecipients replaces recipients in every rule that has one of them
+// The new Age recipients take the place of the old ones and the KMS keys are
+// added to the rule's kms list. It returns the path_regex of changed rules.
+func ReplaceRecipients(config *SopsConfig, from []string, to []string, kms []string) []string {
+	var changed []string
+	for i, rule := range config.CreationRules {
+		if !slices.ContainsFunc(rule.Age, func(recipient string) bool { return slices.Contains(from, recipient) }) {
+			continue
 		}
-	}
 
-	// Add the wildcard rule if it doesn't exist
-	if !hasWildcard {
-		wildcardRule := CreationRule{
-			PathRegex: wildcardPattern,
-			Age:       publicKey,
+		var recipients []string
+		for _, recipient := range rule.Age {
+			if !slices.Contains(from, recipient) {
+				recipients = append(recipients, recipient)
+			}
 		}
-		config.CreationRules = append(config.CreationRules, wildcardRule)
-	}
+		config.CreationRules[i].Age = AgeRecipients(UniqueRecipients(append(recipients, to...)))
 
-	return nil
+		if len(kms) > 0 {
+			keys := append(strings.Split(rule.KMS, ","), kms...)
+			config.CreationRules[i].KMS = strings.Join(UniqueRecipients(keys), ",")
+		}
+		changed = append(changed, rule.PathRegex)
+	}
+	return changed
 }
 
-// RemoveCreationRule removes a rule from the .sops.yaml file
-func RemoveCreationRule(config *SopsConfig, filename string) error {
+// SyncGroupRecipients applies the sync of a group to the rules encrypting to it
+// Rules with any of the group's previous keys get the added keys, and the
+// removed keys are taken out of every rule. It returns the path_regex of
+// changed rules, and fails if a rule would be left without recipients.
+func SyncGroupRecipients(config *SopsConfig, previous []string, sync GroupSync) ([]string, error) {
+	var changed []string
 	for i, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
-			// Remove rule
-			config.CreationRules = append(config.CreationRules[:i], config.CreationRules[i+1:]...)
-			return nil
+		recipients := slices.DeleteFunc(slices.Clone(rule.Age), func(recipient string) bool {
+			return slices.Contains(sync.RemovedKeys, recipient)
+		})
+		if slices.ContainsFunc(rule.Age, func(recipient string) bool { return slices.Contains(previous, recipient) }) {
+			recipients = UniqueRecipients(append(recipients, sync.AddedKeys...))
+		}
+		if slices.Equal(recipients, rule.Age) {
+			continue
 		}
+		if len(recipients) == 0 && rule.KMS == "" {
+			return nil, fmt.Errorf("rule %s would be left without recipients", rule.PathRegex)
+		}
+		config.CreationRules[i].Age = recipients
+		changed = append(changed, rule.PathRegex)
 	}
-
-	return fmt.Errorf("no rule found for %s", filename)
+	return changed, nil
 }
 
 // CleanOrphanedRules removes rules for files that no longer exist
-func CleanOrphanedRules(config *SopsConfig) (int, error) {
+// File paths are resolved relative to baseDir, the directory holding .sops.yaml.
+func CleanOrphanedRules(config *SopsConfig, baseDir string) (int, error) {
 	var cleanedRules []CreationRule
 	orphanedCount := 0
 
-	// Keep only rules for wildcard patterns and existing files
+	// Keep only rules for patterns and existing files
 	for _, rule := range config.CreationRules {
-		// Keep rules with wildcard patterns
-		if strings.Contains(rule.PathRegex, "*") || strings.Contains(rule.PathRegex, "?") {
+		path, isFileRule := RulePath(rule.PathRegex)
+		if !isFileRule {
 			cleanedRules = append(cleanedRules, rule)
 			continue
 		}
 
 		// Check if the file exists
-		if _, err := os.Stat(rule.PathRegex); os.IsNotExist(err) {
-			logging.Info("Removing orphaned rule for file: %s", rule.PathRegex)
-			orphanedCount++
-		} else {
+		if fileExists(filepath.Join(baseDir, filepath.FromSlash(path))) {
 			cleanedRules = append(cleanedRules, rule)
+			continue
 		}
+
+		// Legacy rules were relative to the working directory
+		if IsLegacyRule(rule.PathRegex) && fileExists(path) {
+			cleanedRules = append(cleanedRules, rule)
+			continue
+		}
+
+		logging.Info("Removing orphaned rule for file: %s", path)
+		orphanedCount++
 	}
 
 	config.CreationRules = cleanedRules
 	return orphanedCount, nil
 }
 
+// fileExists checks if a file exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // isGitAvailable checks if Git is available on the system
 func isGitAvailable() bool {
 	_, err := exec.LookPath("git")
@@ -203,9 +320,10 @@ func isGitAvailable() bool {
 }
 
 // GetCreationRule gets the rule for a specific file
+// The file is given by its path relative to .sops.yaml or by the rule's path regex.
 func GetCreationRule(config *SopsConfig, filename string) (CreationRule, bool) {
 	for _, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
+		if ruleMatchesFile(rule, filename) {
 			return rule, true
 		}
 	}
@@ -213,6 +331,31 @@ func GetCreationRule(config *SopsConfig, filename string) (CreationRule, bool) {
 	return CreationRule{}, false
 }
 
+// MatchingRule returns the rule sops applies to a file
+// As in sops, the first rule whose path regex matches the path relative to
+// .sops.yaml wins.
+func MatchingRule(config *SopsConfig, relPath string) (CreationRule, bool) {
+	relPath = filepath.ToSlash(relPath)
+	for _, rule := range config.CreationRules {
+		pattern, err := regexp.Compile(rule.PathRegex)
+		if err == nil && pattern.MatchString(relPath) {
+			return rule, true
+		}
+	}
+
+	return CreationRule{}, false
+}
+
+// CoveringRule returns the rule sops applies to a file, unless it is the catch-all rule
+// Files covered by a rule are expected to be encrypted.
+func CoveringRule(config *SopsConfig, relPath string) (CreationRule, bool) {
+	rule, found := MatchingRule(config, relPath)
+	if !found || IsWildcardRule(rule.PathRegex) {
+		return CreationRule{}, false
+	}
+	return rule, true
+}
+
 // IsFileEncrypted checks if a file is encrypted using SOPS
 func IsFileEncrypted(filePath string) bool {
 	// Read the first few KB of the file to check for SOPS markers
@@ -228,7 +371,16 @@ func IsFileEncrypted(filePath string) bool {
 		return false
 	}
 
-	content := string(buffer[:n])
+	return IsEncryptedData(buffer[:n])
+}
+
+// IsEncryptedData checks if file content is encrypted using SOPS
+// Only the first few KB are inspected, as in IsFileEncrypted.
+func IsEncryptedData(data []byte) bool {
+	if len(data) > 4096 {
+		data = data[:4096]
+	}
+	content := string(data)
 
 	// Check for common SOPS encryption markers
 	markers := []string{
@@ -248,25 +400,40 @@ func IsFileEncrypted(filePath string) bool {
 	return false
 }
 
+// UniqueRecipients returns the recipients without duplicates or empty entries, keeping their order
+func UniqueRecipients(recipients []string) []string {
+	var unique []string
+	for _, recipient := range recipients {
+		recipient = strings.TrimSpace(recipient)
+		if recipient != "" && !slices.Contains(unique, recipient) {
+			unique = append(unique, recipient)
+		}
+	}
+	return unique
+}
+
 // AddCreationRuleWithMultipleKeys adds or updates a rule in the .sops.yaml file with multiple keys
-func AddCreationRuleWithMultipleKeys(config *SopsConfig, filename string, publicKeys string, encryptedRegex string) error {
+// The file is given by its path relative to .sops.yaml. Repeated keys are only written once. The catch-all rule is maintained
+// according to the wildcard policy.
+func AddCreationRuleWithMultipleKeys(config *SopsConfig, filename string, publicKeys []string, encryptedRegex string, wildcard WildcardPolicy) error {
+	recipients := AgeRecipients(UniqueRecipients(publicKeys))
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients specified for %s", filename)
+	}
+	if err := wildcard.Validate(); err != nil {
+		return err
+	}
+
 	// Check if a rule for this file already exists
+	ruleExists := false
 	for i, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
-			// Update existing rule
-			config.CreationRules[i].Age = publicKeys
+		if ruleMatchesFile(rule, filename) {
+			// Update existing rule, upgrading legacy rules to an anchored path
+			config.CreationRules[i].PathRegex = RulePathRegex(filename)
+			config.CreationRules[i].Age = recipients
 			if encryptedRegex != "" {
 				config.CreationRules[i].EncryptedRegex = encryptedRegex
 			}
-			// Don't return yet, we still need to check for the wildcard rule
-			break
-		}
-	}
-
-	// Create new rule if it doesn't exist
-	ruleExists := false
-	for _, rule := range config.CreationRules {
-		if rule.PathRegex == filename {
 			ruleExists = true
 			break
 		}
@@ -275,8 +442,8 @@ func AddCreationRuleWithMultipleKeys(config *SopsConfig, filename string, public
 	if !ruleExists {
 		// Create new rule
 		rule := CreationRule{
-			PathRegex: filename,
-			Age:       publicKeys,
+			PathRegex: RulePathRegex(filename),
+			Age:       recipients,
 		}
 		if encryptedRegex != "" {
 			rule.EncryptedRegex = encryptedRegex
@@ -286,31 +453,7 @@ func AddCreationRuleWithMultipleKeys(config *SopsConfig, filename string, public
 		config.CreationRules = append([]CreationRule{rule}, config.CreationRules...)
 	}
 
-	// Check if we already have a wildcard rule
-	wildcardPattern := `.*\.(ya?ml|json|ini|env)`
-	hasWildcard := false
-
-	for _, rule := range config.CreationRules {
-		if rule.PathRegex == wildcardPattern {
-			hasWildcard = true
-			break
-		}
-	}
-
-	// Add the wildcard rule if it doesn't exist
-	if !hasWildcard {
-		// Extract the first key from the comma-separated list
-		firstKey := publicKeys
-		if idx := strings.Index(publicKeys, ","); idx > 0 {
-			firstKey = publicKeys[:idx]
-		}
-
-		wildcardRule := CreationRule{
-			PathRegex: wildcardPattern,
-			Age:       firstKey, // Use just the first key for the wildcard rule
-		}
-		config.CreationRules = append(config.CreationRules, wildcardRule)
-	}
+	applyWildcardRule(config, recipients, wildcard)
 
 	return nil
 }
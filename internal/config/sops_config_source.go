@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/exitcode"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSopsConfigSize limits the size of a .sops.yaml fetched over HTTPS
+const maxSopsConfigSize = 1 << 20
+
+// sopsConfigClient fetches .sops.yaml files over HTTPS (swappable for tests)
+var sopsConfigClient = &http.Client{Timeout: 30 * time.Second}
+
+// sopsConfigSource replaces the content of the discovered .sops.yaml, if set
+var sopsConfigSource *SopsConfigSource
+
+// SopsConfigSource is a .sops.yaml read from a canonical source instead of the working tree
+// It stands in for the discovered .sops.yaml, so rules still apply relative
+// to the repository, and can't be changed.
+type SopsConfigSource struct {
+	// Name is the URL or git object the rules were read from
+	Name string
+	// path is the .sops.yaml the source stands in for
+	path string
+	data []byte
+}
+
+// UseSopsConfig reads the rules from a path or an https:// URL instead of the discovered .sops.yaml
+func UseSopsConfig(location string) error {
+	switch {
+	case strings.HasPrefix(location, "https://"):
+		data, err := fetchSopsConfig(location)
+		if err != nil {
+			return err
+		}
+		return setSopsConfigSource(location, data)
+	case strings.HasPrefix(location, "http://"):
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("refusing to fetch rules over plain HTTP: %s", location))
+	}
+
+	path, err := filepath.Abs(location)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", location, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("SOPS config not found: %w", err))
+	}
+	sopsConfigOverride = path
+	return nil
+}
+
+// UseSopsConfigRef reads the rules from a git object like origin/main:.sops.yaml
+// Paths are relative to the root of the repository.
+func UseSopsConfigRef(ref string) error {
+	if !strings.Contains(ref, ":") {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid config ref %s, expected <ref>:<path> like origin/main:.sops.yaml", ref))
+	}
+	if !isGitAvailable() {
+		return fmt.Errorf("git is needed to read %s", ref)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "show", ref)
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to read %s: %s", ref, strings.TrimSpace(stderr.String())))
+	}
+	return setSopsConfigSource(ref, data)
+}
+
+// ActiveSopsConfigSource returns the source the rules are read from, if not the working tree
+func ActiveSopsConfigSource() (*SopsConfigSource, bool) {
+	return sopsConfigSource, sopsConfigSource != nil
+}
+
+// SopsConfigSourceFor returns the rules standing in for a .sops.yaml, if they come from a source
+func SopsConfigSourceFor(configPath string) ([]byte, bool) {
+	if sopsConfigSource == nil || configPath != sopsConfigSource.path {
+		return nil, false
+	}
+	return sopsConfigSource.data, true
+}
+
+// setSopsConfigSource makes the rules stand in for the discovered .sops.yaml
+func setSopsConfigSource(name string, data []byte) error {
+	var parsed SopsConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to parse SOPS config from %s: %w", name, err))
+	}
+
+	path, err := GetSopsConfigPath()
+	if err != nil {
+		return err
+	}
+	logging.Debug("Using the rules of %s for %s", name, path)
+	sopsConfigSource = &SopsConfigSource{Name: name, path: path, data: data}
+	return nil
+}
+
+// fetchSopsConfig downloads a .sops.yaml over HTTPS
+func fetchSopsConfig(url string) ([]byte, error) {
+	response, err := sopsConfigClient.Get(url)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to fetch %s: %w", url, err))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to fetch %s: %s", url, response.Status))
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSopsConfigSize+1))
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("failed to fetch %s: %w", url, err))
+	}
+	if len(data) > maxSopsConfigSize {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("%s is larger than %d bytes", url, maxSopsConfigSize))
+	}
+	return data, nil
+}
+
+// readSopsConfigData reads a .sops.yaml, or the source standing in for it
+// A missing file returns nil data without an error.
+func readSopsConfigData(configPath string) ([]byte, error) {
+	if sopsConfigSource != nil && configPath == sopsConfigSource.path {
+		return sopsConfigSource.data, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// checkWritable refuses to change a .sops.yaml whose rules come from a canonical source
+func checkWritable(configPath string) error {
+	if sopsConfigSource != nil && configPath == sopsConfigSource.path {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("the rules are read from %s and can't be changed", sopsConfigSource.Name))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUseSopsConfigRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(wd)
+	defer func() { sopsConfigSource = nil }()
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	configPath := filepath.Join(root, ".sops.yaml")
+	git("init", "-q")
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: canonical\\.yaml$\n    age: age1canonical\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .sops.yaml: %v", err)
+	}
+	git("add", ".sops.yaml")
+	git("commit", "-q", "-m", "rules")
+
+	// The working tree has local changes
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: local\\.yaml$\n    age: age1local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .sops.yaml: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	if err := UseSopsConfigRef("HEAD"); err == nil {
+		t.Error("Expected an error for a ref without a path")
+	}
+	if err := UseSopsConfigRef("HEAD:.sops.yaml"); err != nil {
+		t.Fatalf("UseSopsConfigRef failed: %v", err)
+	}
+
+	sopsConfig, err := LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	if len(sopsConfig.CreationRules) != 1 || sopsConfig.CreationRules[0].PathRegex != `canonical\.yaml$` {
+		t.Errorf("Expected the committed rules, got %+v", sopsConfig.CreationRules)
+	}
+	if data, ok := SopsConfigSourceFor(configPath); !ok || !strings.Contains(string(data), "age1canonical") {
+		t.Errorf("Expected the committed rules to stand in for %s, got %q", configPath, data)
+	}
+
+	// The rules of a ref can't be changed
+	if err := SaveSopsConfig(configPath, sopsConfig); err == nil || !strings.Contains(err.Error(), "HEAD:.sops.yaml") {
+		t.Errorf("Expected an error when saving the rules of a ref, got %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "age1local") {
+		t.Errorf("Expected the local .sops.yaml to be unchanged, got %s", data)
+	}
+}
+
+func TestUseSopsConfigURL(t *testing.T) {
+	defer func() { sopsConfigSource = nil }()
+	client := sopsConfigClient
+	defer func() { sopsConfigClient = client }()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.sops.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("creation_rules:\n  - path_regex: remote\\.yaml$\n    age: age1remote\n"))
+	}))
+	defer server.Close()
+	sopsConfigClient = server.Client()
+
+	if err := UseSopsConfig(server.URL + "/missing.yaml"); err == nil {
+		t.Error("Expected an error for a missing config")
+	}
+	if err := UseSopsConfig(strings.Replace(server.URL, "https://", "http://", 1) + "/.sops.yaml"); err == nil {
+		t.Error("Expected an error for plain HTTP")
+	}
+	if err := UseSopsConfig(server.URL + "/.sops.yaml"); err != nil {
+		t.Fatalf("UseSopsConfig failed: %v", err)
+	}
+
+	configPath, err := GetSopsConfigPath()
+	if err != nil {
+		t.Fatalf("GetSopsConfigPath failed: %v", err)
+	}
+	sopsConfig, err := LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	if len(sopsConfig.CreationRules) != 1 || sopsConfig.CreationRules[0].Age.String() != "age1remote" {
+		t.Errorf("Expected the fetched rules, got %+v", sopsConfig.CreationRules)
+	}
+}
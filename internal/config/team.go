@@ -0,0 +1,375 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TeamFileName is the path of the team key registry relative to the repository root
+const TeamFileName = ".simple-sops/keys.yaml"
+
+// Team is the committed registry of team members and groups
+// Recipients can be given by the name of a member or a group instead of a key,
+// so onboarding someone is a change adding one line.
+type Team struct {
+	Members []TeamMember        `yaml:"members"`
+	Groups  map[string][]string `yaml:"groups,omitempty"`
+	// Owners maps CODEOWNERS owners like @acme/payments to members or groups
+	Owners map[string]string `yaml:"owners,omitempty"`
+}
+
+// TeamMember is a person and their Age recipients
+type TeamMember struct {
+	Name string        `yaml:"name"`
+	Keys AgeRecipients `yaml:"keys"`
+	// Expires is the date from which the keys must no longer read secrets, as YYYY-MM-DD
+	Expires string `yaml:"expires,omitempty"`
+	// Offboarded marks a member who left, whose keys must no longer read secrets
+	Offboarded bool `yaml:"offboarded,omitempty"`
+}
+
+// RevokedRecipients are the recipients of an encrypted file owned by offboarded or expired members
+type RevokedRecipients struct {
+	Path string
+	// Members describe the owners and why their keys are revoked, e.g. "alice (offboarded)"
+	Members    []string
+	Recipients []string
+}
+
+// GroupSync is the result of syncing a group with an external team
+type GroupSync struct {
+	// Added and Removed are the members that joined or left the group
+	Added   []string
+	Removed []string
+	// AddedKeys are the keys the group gained, RemovedKeys the ones it lost
+	// that no member owns anymore
+	AddedKeys   []string
+	RemovedKeys []string
+}
+
+// IsEmpty reports whether the sync changed nothing
+func (s GroupSync) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.AddedKeys) == 0 && len(s.RemovedKeys) == 0
+}
+
+// UnregisteredRecipients are the recipients of a rule that no team member owns
+type UnregisteredRecipients struct {
+	PathRegex  string
+	Recipients []string
+}
+
+// LoadTeam loads the team key registry of the repository at root
+// A missing file results in an empty team.
+func LoadTeam(root string) (*Team, error) {
+	path := filepath.Join(root, filepath.FromSlash(TeamFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Team{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", TeamFileName, err)
+	}
+
+	var team Team
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&team); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", TeamFileName, err)
+	}
+
+	if err := team.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", TeamFileName, err)
+	}
+	return &team, nil
+}
+
+// SaveTeam writes the team key registry of the repository at root
+func SaveTeam(root string, team *Team) error {
+	if err := team.Validate(); err != nil {
+		return fmt.Errorf("invalid %s: %w", TeamFileName, err)
+	}
+	data, err := yaml.Marshal(team)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", TeamFileName, err)
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(TeamFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", TeamFileName, err)
+	}
+	return nil
+}
+
+// IsEmpty reports whether the registry lists no members
+func (t *Team) IsEmpty() bool {
+	return len(t.Members) == 0
+}
+
+// Validate checks that names are unique, members have Age keys and groups only list members
+func (t *Team) Validate() error {
+	names := make(map[string]bool)
+	for i, member := range t.Members {
+		if member.Name == "" {
+			return fmt.Errorf("members[%d]: name is required", i)
+		}
+		if names[member.Name] {
+			return fmt.Errorf("member %s is listed more than once", member.Name)
+		}
+		names[member.Name] = true
+
+		if len(member.Keys) == 0 {
+			return fmt.Errorf("member %s has no keys", member.Name)
+		}
+		if member.Expires != "" {
+			if _, err := time.Parse(ExpiryDateFormat, member.Expires); err != nil {
+				return fmt.Errorf("member %s: invalid expiry date %q, expected YYYY-MM-DD", member.Name, member.Expires)
+			}
+		}
+		for _, key := range member.Keys {
+			if !strings.HasPrefix(key, "age1") {
+				return fmt.Errorf("member %s: %s is not an Age recipient (age1...)", member.Name, key)
+			}
+		}
+	}
+
+	for group, members := range t.Groups {
+		if names[group] {
+			return fmt.Errorf("group %s has the name of a member", group)
+		}
+		for _, member := range members {
+			if !names[member] {
+				return fmt.Errorf("group %s: unknown member %s", group, member)
+			}
+		}
+	}
+
+	for owner, name := range t.Owners {
+		if !names[name] && !t.hasGroup(name) {
+			return fmt.Errorf("owner %s: unknown member or group %s", owner, name)
+		}
+	}
+	return nil
+}
+
+// Member returns the member with the given name
+func (t *Team) Member(name string) (TeamMember, bool) {
+	for _, member := range t.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return TeamMember{}, false
+}
+
+// Revoked returns why the member's keys must no longer read secrets, if they must not
+// Keys expire at the start of their expiry date.
+func (m TeamMember) Revoked(now time.Time) (string, bool) {
+	if m.Offboarded {
+		return "offboarded", true
+	}
+	if expires, err := time.Parse(ExpiryDateFormat, m.Expires); err == nil && !now.Before(expires) {
+		return "expired on " + m.Expires, true
+	}
+	return "", false
+}
+
+// FindRevoked returns the recipients of a file that belong to offboarded or expired members
+func (t *Team) FindRevoked(path string, recipients []string, now time.Time) (RevokedRecipients, bool) {
+	revoked := RevokedRecipients{Path: path}
+	for _, member := range t.Members {
+		reason, ok := member.Revoked(now)
+		if !ok {
+			continue
+		}
+		found := false
+		for _, key := range member.Keys {
+			if slices.Contains(recipients, key) {
+				revoked.Recipients = append(revoked.Recipients, key)
+				found = true
+			}
+		}
+		if found {
+			revoked.Members = append(revoked.Members, fmt.Sprintf("%s (%s)", member.Name, reason))
+		}
+	}
+	return revoked, len(revoked.Recipients) > 0
+}
+
+// Resolve returns the keys of the given members and groups
+// Age recipients are passed through, so names and keys can be mixed. Groups
+// leave out offboarded and expired members, naming one of them is an error.
+func (t *Team) Resolve(names []string) ([]string, error) {
+	return t.resolve(names, false)
+}
+
+// ResolveRevoked returns the keys of the given members and groups including revoked ones
+// It is used for keys that are taken away, e.g. those of a member who left.
+func (t *Team) ResolveRevoked(names []string) ([]string, error) {
+	return t.resolve(names, true)
+}
+
+// resolve returns the keys of members and groups, with the ones of revoked members if withRevoked
+func (t *Team) resolve(names []string, withRevoked bool) ([]string, error) {
+	now := time.Now()
+	var keys []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch {
+		case strings.HasPrefix(name, "age1"):
+			keys = append(keys, name)
+		case t.hasGroup(name):
+			for _, memberName := range t.Groups[name] {
+				member, _ := t.Member(memberName)
+				if _, revoked := member.Revoked(now); revoked && !withRevoked {
+					continue
+				}
+				keys = append(keys, member.Keys...)
+			}
+		default:
+			member, ok := t.Member(name)
+			if !ok {
+				return nil, fmt.Errorf("%s is neither a member nor a group in %s", name, TeamFileName)
+			}
+			if reason, revoked := member.Revoked(now); revoked && !withRevoked {
+				return nil, fmt.Errorf("%s is %s in %s, their keys must no longer read secrets", name, reason, TeamFileName)
+			}
+			keys = append(keys, member.Keys...)
+		}
+	}
+	return UniqueRecipients(keys), nil
+}
+
+// Owner returns the name of the member a key belongs to
+func (t *Team) Owner(key string) (string, bool) {
+	for _, member := range t.Members {
+		if slices.Contains(member.Keys, key) {
+			return member.Name, true
+		}
+	}
+	return "", false
+}
+
+// ForCodeOwners returns the members and groups of CODEOWNERS owners
+// Owners are looked up in the owners mapping first, otherwise a team like
+// @acme/payments maps to the group payments and a user like @alice to the
+// member alice. Owners without a match are skipped.
+func (t *Team) ForCodeOwners(owners []string) []string {
+	var names []string
+	for _, owner := range owners {
+		name, ok := t.Owners[owner]
+		if !ok {
+			name = strings.TrimPrefix(owner, "@")
+			if slash := strings.LastIndex(name, "/"); slash >= 0 {
+				name = name[slash+1:]
+			}
+			if _, isMember := t.Member(name); !isMember && !t.hasGroup(name) {
+				continue
+			}
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SyncGroup makes a group consist of the given members and their keys
+// Members get exactly the given keys. Members that left the group are removed
+// from the registry, unless they are in another group.
+func (t *Team) SyncGroup(group string, members map[string][]string) GroupSync {
+	var sync GroupSync
+	before, _ := t.Resolve([]string{group})
+	if !t.hasGroup(group) {
+		before = nil
+	}
+
+	names := slices.Sorted(maps.Keys(members))
+	for _, name := range names {
+		keys := AgeRecipients(UniqueRecipients(members[name]))
+		index := slices.IndexFunc(t.Members, func(member TeamMember) bool { return member.Name == name })
+		if index < 0 {
+			t.Members = append(t.Members, TeamMember{Name: name, Keys: keys})
+		} else {
+			t.Members[index].Keys = keys
+		}
+		if !slices.Contains(t.Groups[group], name) {
+			sync.Added = append(sync.Added, name)
+		}
+	}
+
+	for _, name := range t.Groups[group] {
+		if _, ok := members[name]; !ok {
+			sync.Removed = append(sync.Removed, name)
+		}
+	}
+	if t.Groups == nil {
+		t.Groups = make(map[string][]string)
+	}
+	t.Groups[group] = names
+
+	// Members that left are only kept for the other groups they are in
+	for _, name := range sync.Removed {
+		inOtherGroup := false
+		for other, otherMembers := range t.Groups {
+			if other != group && slices.Contains(otherMembers, name) {
+				inOtherGroup = true
+			}
+		}
+		if !inOtherGroup {
+			t.Members = slices.DeleteFunc(t.Members, func(member TeamMember) bool { return member.Name == name })
+		}
+	}
+
+	after, _ := t.Resolve([]string{group})
+	var remaining []string
+	for _, member := range t.Members {
+		remaining = append(remaining, member.Keys...)
+	}
+	for _, key := range after {
+		if !slices.Contains(before, key) {
+			sync.AddedKeys = append(sync.AddedKeys, key)
+		}
+	}
+	for _, key := range before {
+		if !slices.Contains(after, key) && !slices.Contains(remaining, key) {
+			sync.RemovedKeys = append(sync.RemovedKeys, key)
+		}
+	}
+	return sync
+}
+
+// FindUnregistered returns the rules with recipients no team member owns
+// Those are typically keys of people who left the team. Keys in exempt, like
+// the recovery recipients of the policy, belong to no one and aren't reported.
+func (t *Team) FindUnregistered(sopsConfig *SopsConfig, exempt []string) []UnregisteredRecipients {
+	var unregistered []UnregisteredRecipients
+	for _, rule := range sopsConfig.CreationRules {
+		var unknown []string
+		for _, key := range rule.Age {
+			if _, ok := t.Owner(key); !ok && !slices.Contains(exempt, key) {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			unregistered = append(unregistered, UnregisteredRecipients{PathRegex: rule.PathRegex, Recipients: unknown})
+		}
+	}
+	return unregistered
+}
+
+// hasGroup checks if a group with the given name exists
+func (t *Team) hasGroup(name string) bool {
+	_, ok := t.Groups[name]
+	return ok
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTeamFile(t *testing.T, root string, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(TeamFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTeamResolveSkipsRevokedMembers(t *testing.T) {
+	root := t.TempDir()
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+  - name: bob
+    keys: age1bob
+    offboarded: true
+  - name: carol
+    keys: age1carol
+    expires: 2000-01-01
+  - name: dave
+    keys: age1dave
+    expires: 2999-01-01
+groups:
+  backend: [alice, bob, carol, dave]
+`)
+
+	team, err := LoadTeam(root)
+	if err != nil {
+		t.Fatalf("LoadTeam failed: %v", err)
+	}
+
+	keys, err := team.Resolve([]string{"backend"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got := strings.Join(keys, ","); got != "age1alice,age1dave" {
+		t.Errorf("Resolve(backend) = %s, want age1alice,age1dave", got)
+	}
+
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := team.Resolve([]string{name}); err == nil {
+			t.Errorf("Expected the revoked member %s to be rejected", name)
+		}
+	}
+
+	keys, err = team.ResolveRevoked([]string{"bob", "backend"})
+	if err != nil {
+		t.Fatalf("ResolveRevoked failed: %v", err)
+	}
+	if got := strings.Join(keys, ","); got != "age1bob,age1alice,age1carol,age1dave" {
+		t.Errorf("ResolveRevoked = %s, want age1bob,age1alice,age1carol,age1dave", got)
+	}
+}
+
+func TestTeamResolve(t *testing.T) {
+	root := t.TempDir()
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+  - name: bob
+    keys: [age1bob, age1bobci]
+groups:
+  backend: [alice, bob]
+  empty: []
+`)
+
+	team, err := LoadTeam(root)
+	if err != nil {
+		t.Fatalf("LoadTeam failed: %v", err)
+	}
+
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"alice"}, "age1alice"},
+		{[]string{"backend"}, "age1alice,age1bob,age1bobci"},
+		{[]string{"alice", "backend", "age1carol"}, "age1alice,age1bob,age1bobci,age1carol"},
+		{[]string{"empty"}, ""},
+	}
+	for _, tt := range tests {
+		keys, err := team.Resolve(tt.names)
+		if err != nil {
+			t.Errorf("Resolve(%v) failed: %v", tt.names, err)
+			continue
+		}
+		if got := strings.Join(keys, ","); got != tt.want {
+			t.Errorf("Resolve(%v) = %s, want %s", tt.names, got, tt.want)
+		}
+	}
+
+	if _, err := team.Resolve([]string{"carol"}); err == nil {
+		t.Error("Expected an unknown name to be rejected")
+	}
+
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("secrets.yaml"), Age: AgeRecipients{"age1alice", "age1mallory"}},
+		{PathRegex: RulePathRegex("other.yaml"), Age: AgeRecipients{"age1bob"}},
+	}}
+	unregistered := team.FindUnregistered(sopsConfig, nil)
+	if len(unregistered) != 1 || unregistered[0].Recipients[0] != "age1mallory" {
+		t.Errorf("Expected age1mallory to be unregistered, got %+v", unregistered)
+	}
+	if unregistered := team.FindUnregistered(sopsConfig, []string{"age1mallory"}); len(unregistered) != 0 {
+		t.Errorf("Expected exempt keys to be skipped, got %+v", unregistered)
+	}
+}
+
+func TestLoadTeam(t *testing.T) {
+	// A missing registry is empty
+	team, err := LoadTeam(t.TempDir())
+	if err != nil || !team.IsEmpty() {
+		t.Fatalf("Expected an empty team, got %+v, %v", team, err)
+	}
+
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{"members:\n  - name: alice\n", "has no keys"},
+		{"members:\n  - name: alice\n    keys: ssh-ed25519\n", "not an Age recipient"},
+		{"members:\n  - name: alice\n    keys: age1a\n  - name: alice\n    keys: age1b\n", "more than once"},
+		{"members:\n  - name: alice\n    keys: age1a\ngroups:\n  ops: [bob]\n", "unknown member bob"},
+		{"members:\n  - name: alice\n    keys: age1a\ngroups:\n  alice: [alice]\n", "name of a member"},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeTeamFile(t, root, tt.content)
+		if _, err := LoadTeam(root); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadTeam(%q) error = %v, want %q", tt.content, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTeamSyncGroup(t *testing.T) {
+	team := &Team{
+		Members: []TeamMember{
+			{Name: "alice", Keys: AgeRecipients{"age1alice"}},
+			{Name: "bob", Keys: AgeRecipients{"age1bob"}},
+			{Name: "carol", Keys: AgeRecipients{"age1carol"}},
+		},
+		Groups: map[string][]string{
+			"infra": {"alice", "bob", "carol"},
+			"ops":   {"carol"},
+		},
+	}
+
+	// alice rotated her key, bob and carol left and dave joined
+	sync := team.SyncGroup("infra", map[string][]string{
+		"alice": {"age1alice2"},
+		"dave":  {"age1dave"},
+	})
+	if strings.Join(sync.Added, ",") != "dave" || strings.Join(sync.Removed, ",") != "bob,carol" {
+		t.Errorf("Expected dave to join and bob and carol to leave, got %+v", sync)
+	}
+	if strings.Join(sync.AddedKeys, ",") != "age1alice2,age1dave" {
+		t.Errorf("Expected the keys of alice and dave to be added, got %v", sync.AddedKeys)
+	}
+	// carol is still in ops, so her key stays
+	if strings.Join(sync.RemovedKeys, ",") != "age1alice,age1bob" {
+		t.Errorf("Expected the old keys of alice and bob to be removed, got %v", sync.RemovedKeys)
+	}
+	if _, ok := team.Member("bob"); ok {
+		t.Error("Expected bob to be removed from the registry")
+	}
+	if _, ok := team.Member("carol"); !ok {
+		t.Error("Expected carol to stay in the registry for ops")
+	}
+	if err := team.Validate(); err != nil {
+		t.Errorf("Expected a valid registry after the sync, got %v", err)
+	}
+
+	sopsConfig := &SopsConfig{CreationRules: []CreationRule{
+		{PathRegex: RulePathRegex("infra.yaml"), Age: AgeRecipients{"age1alice", "age1bob", "age1carol"}},
+		{PathRegex: RulePathRegex("bob.yaml"), Age: AgeRecipients{"age1bob"}, KMS: "arn:aws:kms:key"},
+		{PathRegex: RulePathRegex("other.yaml"), Age: AgeRecipients{"age1other"}},
+	}}
+	changed, err := SyncGroupRecipients(sopsConfig, []string{"age1alice", "age1bob", "age1carol"}, sync)
+	if err != nil {
+		t.Fatalf("SyncGroupRecipients failed: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("Expected 2 changed rules, got %v", changed)
+	}
+	if got := sopsConfig.CreationRules[0].Age.String(); got != "age1carol,age1alice2,age1dave" {
+		t.Errorf("Expected the rule of the group to get the new keys, got %s", got)
+	}
+	if got := sopsConfig.CreationRules[2].Age.String(); got != "age1other" {
+		t.Errorf("Expected other rules to be kept, got %s", got)
+	}
+
+	// A rule can't be left without recipients
+	sopsConfig = &SopsConfig{CreationRules: []CreationRule{{PathRegex: RulePathRegex("bob.yaml"), Age: AgeRecipients{"age1bob"}}}}
+	if _, err := SyncGroupRecipients(sopsConfig, nil, sync); err == nil {
+		t.Error("Expected an error for a rule without recipients")
+	}
+}
+
+func TestTeamFindRevoked(t *testing.T) {
+	root := t.TempDir()
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+  - name: bob
+    keys: [age1bob, age1bobci]
+    offboarded: true
+  - name: carol
+    keys: age1carol
+    expires: 2026-03-01
+`)
+
+	team, err := LoadTeam(root)
+	if err != nil {
+		t.Fatalf("LoadTeam failed: %v", err)
+	}
+
+	recipients := []string{"age1alice", "age1bobci", "age1carol"}
+	before := time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC)
+	revoked, found := team.FindRevoked("secret.yaml", recipients, before)
+	if !found || strings.Join(revoked.Members, ",") != "bob (offboarded)" || strings.Join(revoked.Recipients, ",") != "age1bobci" {
+		t.Errorf("Expected only bob to be revoked before carol's expiry, got %+v", revoked)
+	}
+
+	after := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	revoked, _ = team.FindRevoked("secret.yaml", recipients, after)
+	if strings.Join(revoked.Members, ",") != "bob (offboarded),carol (expired on 2026-03-01)" {
+		t.Errorf("Expected bob and carol to be revoked on carol's expiry date, got %+v", revoked)
+	}
+
+	if _, found := team.FindRevoked("secret.yaml", []string{"age1alice"}, after); found {
+		t.Error("Expected no revoked recipients for a file only alice can decrypt")
+	}
+
+	writeTeamFile(t, root, `members:
+  - name: alice
+    keys: age1alice
+    expires: next week
+`)
+	if _, err := LoadTeam(root); err == nil {
+		t.Error("Expected an error for an invalid expiry date")
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplatesDirName is the directory below the config directory holding secret file templates
+const TemplatesDirName = "templates"
+
+// templatePlaceholder matches placeholders like {{ DB_PASSWORD }}
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// GetTemplatesDir returns the directory holding secret file templates
+func GetTemplatesDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, TemplatesDirName), nil
+}
+
+// ListTemplates returns the names of the templates in dir
+func ListTemplates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// FindTemplate returns the path of a template, given by its file name with or without extension
+func FindTemplate(dir string, name string) (string, error) {
+	names, err := ListTemplates(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, candidate := range names {
+		if candidate == name {
+			return filepath.Join(dir, candidate), nil
+		}
+		if strings.TrimSuffix(candidate, filepath.Ext(candidate)) == name {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if len(names) == 0 {
+			return "", fmt.Errorf("template %s not found, %s has no templates", name, dir)
+		}
+		return "", fmt.Errorf("template %s not found in %s (available: %s)", name, dir, strings.Join(names, ", "))
+	case 1:
+		return filepath.Join(dir, matches[0]), nil
+	default:
+		return "", fmt.Errorf("template %s is ambiguous, use one of %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// TemplatePlaceholders returns the names of the placeholders of a template in order of appearance
+func TemplatePlaceholders(content string) []string {
+	var names []string
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// RenderTemplate replaces the placeholders of a template with their values
+// Values are quoted for the format of fileName, so a value like "a: b" or
+// "#secret" stays a single string instead of changing the structure of the
+// file. Placeholders without a value are rejected.
+func RenderTemplate(content string, values map[string]string, fileName string) (string, error) {
+	format := templateFormat(fileName)
+
+	var (
+		builder strings.Builder
+		missing []string
+		last    int
+	)
+	for _, match := range templatePlaceholder.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		name := content[match[2]:match[3]]
+		builder.WriteString(content[last:start])
+		last = end
+
+		value, ok := values[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			continue
+		}
+
+		// A placeholder between quotes is part of a string of the template
+		var quote byte
+		if start > 0 && end < len(content) && content[start-1] == content[end] && (content[end] == '"' || content[end] == '\'') {
+			quote = content[end]
+		}
+		quoted, err := quoteTemplateValue(value, format, quote)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		builder.WriteString(quoted)
+	}
+	builder.WriteString(content[last:])
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no value for %s", strings.Join(missing, ", "))
+	}
+	return builder.String(), nil
+}
+
+// templateFormat returns the format values of a template are quoted for
+// Formats other than YAML and JSON take values as they are.
+func templateFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
+
+// quoteTemplateValue quotes a value for a placeholder of a template
+// quote is the quote character around the placeholder, or 0 without one.
+func quoteTemplateValue(value string, format string, quote byte) (string, error) {
+	switch {
+	case quote == '"' && format != "":
+		// JSON escapes are valid in double-quoted YAML strings as well
+		quoted, err := marshalJSONString(value)
+		if err != nil {
+			return "", err
+		}
+		return quoted[1 : len(quoted)-1], nil
+	case quote == '\'' && format == "yaml":
+		if strings.Contains(value, "\n") {
+			return "", fmt.Errorf("line breaks can't be used between single quotes")
+		}
+		return strings.ReplaceAll(value, "'", "''"), nil
+	case quote != 0 || format == "":
+		if strings.Contains(value, "\n") {
+			return "", fmt.Errorf("line breaks are only supported in YAML and JSON files")
+		}
+		if quote != 0 && strings.IndexByte(value, quote) >= 0 {
+			return "", fmt.Errorf("the value contains the quote %c around its placeholder", quote)
+		}
+		return value, nil
+	case format == "yaml":
+		return quoteYAMLValue(value)
+	default:
+		// Numbers, booleans and null are kept, everything else becomes a string
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			switch decoded.(type) {
+			case float64, bool, nil:
+				return value, nil
+			}
+		}
+		return marshalJSONString(value)
+	}
+}
+
+// quoteYAMLValue returns a value as a YAML scalar, quoted if it would be read differently
+func quoteYAMLValue(value string) (string, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &node); err == nil && len(node.Content) == 1 {
+		scalar := node.Content[0]
+		if scalar.Kind == yaml.ScalarNode && scalar.Style == 0 && scalar.Value == value &&
+			scalar.HeadComment == "" && scalar.LineComment == "" && scalar.FootComment == "" {
+			return value, nil
+		}
+	}
+
+	// JSON strings are valid YAML and, unlike YAML's own quoting, never span lines
+	return marshalJSONString(value)
+}
+
+// marshalJSONString returns a value as a JSON string without escaping HTML characters
+func marshalJSONString(value string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	content := "user: {{ DB_USER }}\npassword: {{DB_PASSWORD}}\nurl: postgres://{{ DB_USER }}@db\n"
+
+	placeholders := TemplatePlaceholders(content)
+	if strings.Join(placeholders, ",") != "DB_USER,DB_PASSWORD" {
+		t.Errorf("TemplatePlaceholders = %v", placeholders)
+	}
+
+	rendered, err := RenderTemplate(content, map[string]string{"DB_USER": "app", "DB_PASSWORD": "s3cret"}, "db.yaml")
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if want := "user: app\npassword: s3cret\nurl: postgres://app@db\n"; rendered != want {
+		t.Errorf("RenderTemplate = %q, want %q", rendered, want)
+	}
+
+	if _, err := RenderTemplate(content, map[string]string{"DB_USER": "app"}, "db.yaml"); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD") {
+		t.Errorf("Expected an error for the missing DB_PASSWORD, got %v", err)
+	}
+}
+
+func TestRenderTemplateQuotesValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		value    string
+		fileName string
+		want     string
+	}{
+		{"plain YAML", "password: {{ X }}\n", "s3cret", "db.yaml", "password: s3cret\n"},
+		{"YAML number", "port: {{ X }}\n", "5432", "db.yaml", "port: 5432\n"},
+		{"YAML mapping", "password: {{ X }}\n", "a: b", "db.yaml", "password: \"a: b\"\n"},
+		{"YAML comment", "password: {{ X }}\n", "#secret", "db.yml", "password: \"#secret\"\n"},
+		{"YAML line break", "password: {{ X }}\n", "a\nb", "db.yaml", "password: \"a\\nb\"\n"},
+		{"YAML double quotes", "password: \"{{ X }}\"\n", `a"b`, "db.yaml", "password: \"a\\\"b\"\n"},
+		{"YAML single quotes", "password: '{{ X }}'\n", "it's", "db.yaml", "password: 'it''s'\n"},
+		{"JSON string", `{"password": "{{ X }}"}`, `a"b`, "db.json", `{"password": "a\"b"}`},
+		{"JSON unquoted", `{"password": {{ X }}}`, "s3cret", "db.json", `{"password": "s3cret"}`},
+		{"JSON number", `{"port": {{ X }}}`, "5432", "db.json", `{"port": 5432}`},
+		{"dotenv", "PASSWORD={{ X }}\n", "a b#c", "db.env", "PASSWORD=a b#c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderTemplate(tt.content, map[string]string{"X": tt.value}, tt.fileName)
+			if err != nil {
+				t.Fatalf("RenderTemplate failed: %v", err)
+			}
+			if rendered != tt.want {
+				t.Errorf("RenderTemplate = %q, want %q", rendered, tt.want)
+			}
+		})
+	}
+
+	if _, err := RenderTemplate("PASSWORD={{ X }}\n", map[string]string{"X": "a\nb"}, "db.env"); err == nil {
+		t.Error("Expected an error for a line break in a dotenv value, got nil")
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"database.yaml", "api.env", "api.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := FindTemplate(dir, "database")
+	if err != nil || path != filepath.Join(dir, "database.yaml") {
+		t.Errorf("FindTemplate(database) = %s, %v", path, err)
+	}
+	path, err = FindTemplate(dir, "api.env")
+	if err != nil || path != filepath.Join(dir, "api.env") {
+		t.Errorf("FindTemplate(api.env) = %s, %v", path, err)
+	}
+	if _, err := FindTemplate(dir, "api"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected api to be ambiguous, got %v", err)
+	}
+	if _, err := FindTemplate(dir, "missing"); err == nil || !strings.Contains(err.Error(), "available") {
+		t.Errorf("Expected the available templates to be listed, got %v", err)
+	}
+}
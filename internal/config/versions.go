@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/version"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionsFileName is the path of the pinned tool versions relative to the repository root
+const VersionsFileName = ".simple-sops/versions.yaml"
+
+// VersionPin pins the sops version a team uses for a repository
+// Files written by other sops versions may carry metadata older releases
+// can't read.
+type VersionPin struct {
+	// SopsVersion is a constraint on the sops version, e.g. ">=3.9, <4"
+	SopsVersion string `yaml:"sops_version,omitempty"`
+	// Fail refuses to run sops outside the constraint instead of warning
+	Fail bool `yaml:"fail,omitempty"`
+
+	// constraint is the parsed SopsVersion
+	constraint version.Constraint
+}
+
+// LoadVersionPin loads the pinned tool versions of the repository at root
+// It reports false if the repository pins no sops version.
+func LoadVersionPin(root string) (*VersionPin, bool, error) {
+	path := filepath.Join(root, filepath.FromSlash(VersionsFileName))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", VersionsFileName, err)
+	}
+
+	pin := &VersionPin{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(pin); err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", VersionsFileName, err)
+	}
+	if pin.SopsVersion == "" {
+		return nil, false, nil
+	}
+
+	if pin.constraint, err = version.ParseConstraint(pin.SopsVersion); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: sops_version: %w", VersionsFileName, err)
+	}
+	return pin, true, nil
+}
+
+// CheckSops checks the version sops reported against the pin
+// An error describes the mismatch. Versions that can't be parsed, e.g. of
+// development builds, are accepted.
+func (p *VersionPin) CheckSops(reported string) error {
+	satisfied, ok := p.constraint.Check(reported)
+	if !ok || satisfied {
+		return nil
+	}
+	return fmt.Errorf("%s requires sops %s, found %s", VersionsFileName, p.SopsVersion, reported)
+}
+
+// PinSopsVersion sets the sops version constraint of the repository at root
+// Other settings of the file, like fail, are kept.
+func PinSopsVersion(root string, constraint string) error {
+	if _, err := version.ParseConstraint(constraint); err != nil {
+		return fmt.Errorf("invalid sops version %q: %w", constraint, err)
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(VersionsFileName))
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", VersionsFileName, err)
+	}
+
+	pin := &VersionPin{}
+	if err := yaml.Unmarshal(data, pin); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", VersionsFileName, err)
+	}
+	pin.SopsVersion = constraint
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err = yaml.Marshal(pin)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", VersionsFileName, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", VersionsFileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadVersionPin(t *testing.T) {
+	root := t.TempDir()
+
+	// Without the file nothing is pinned
+	if _, enabled, err := LoadVersionPin(root); err != nil || enabled {
+		t.Fatalf("LoadVersionPin() = %v, %v, want disabled", enabled, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, ".simple-sops"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, filepath.FromSlash(VersionsFileName))
+	if err := os.WriteFile(path, []byte("sops_version: \">=3.9, <4\"\nfail: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pin, enabled, err := LoadVersionPin(root)
+	if err != nil || !enabled || !pin.Fail {
+		t.Fatalf("LoadVersionPin() = %+v, %v, %v", pin, enabled, err)
+	}
+	if err := pin.CheckSops("sops 3.9.1 (latest)"); err != nil {
+		t.Errorf("CheckSops(3.9.1) error = %v", err)
+	}
+	if err := pin.CheckSops("sops 3.8.1"); err == nil {
+		t.Error("CheckSops(3.8.1) accepted a version outside the constraint")
+	}
+	if err := pin.CheckSops("sops dev"); err != nil {
+		t.Errorf("CheckSops(dev) error = %v, unparsable versions are accepted", err)
+	}
+
+	if err := os.WriteFile(path, []byte("sops_version: \"~3.9\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadVersionPin(root); err == nil {
+		t.Error("LoadVersionPin() accepted an invalid constraint")
+	}
+}
+
+func TestPinSopsVersion(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, filepath.FromSlash(VersionsFileName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("sops_version: \"3.8\"\nfail: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+
+	if err := PinSopsVersion(root, ">=3.9, <4"); err != nil {
+		t.Fatalf("PinSopsVersion failed: %v", err)
+	}
+	pin, enabled, err := LoadVersionPin(root)
+	if err != nil || !enabled {
+		t.Fatalf("LoadVersionPin() = %v, %v, want enabled", enabled, err)
+	}
+	if pin.SopsVersion != ">=3.9, <4" || !pin.Fail {
+		t.Errorf("Expected the new constraint and fail to be kept, got %+v", pin)
+	}
+
+	if err := PinSopsVersion(root, ">>3"); err == nil {
+		t.Error("Expected an invalid constraint to be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WildcardPattern is the path regex of the catch-all creation rule
+const WildcardPattern = `.*\.(ya?ml|json|ini|env)`
+
+// WildcardMode selects which recipients the catch-all rule gets
+type WildcardMode string
+
+const (
+	// WildcardAll adds every recipient of encrypted files to the catch-all rule
+	WildcardAll WildcardMode = "all"
+	// WildcardDefault uses a designated set of recipients for the catch-all rule
+	WildcardDefault WildcardMode = "default"
+	// WildcardNone never creates or changes the catch-all rule
+	WildcardNone WildcardMode = "none"
+)
+
+// WildcardPolicy controls how the catch-all rule in .sops.yaml is maintained
+type WildcardPolicy struct {
+	// Mode is all, default or none; empty means all
+	Mode WildcardMode `yaml:"mode,omitempty"`
+	// Recipients are the designated recipients for the default mode
+	Recipients []string `yaml:"recipients,omitempty"`
+	// Scope confines the catch-all rule to a directory relative to .sops.yaml, set by --scope
+	Scope string `yaml:"-"`
+}
+
+// Validate checks that the policy is complete
+func (p WildcardPolicy) Validate() error {
+	switch p.Mode {
+	case "", WildcardAll, WildcardNone:
+		return nil
+	case WildcardDefault:
+		if len(UniqueRecipients(p.Recipients)) == 0 {
+			return fmt.Errorf("wildcard mode %s requires recipients", p.Mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported wildcard mode: %s (supported: all, default, none)", p.Mode)
+	}
+}
+
+// applyWildcardRule creates or updates the catch-all rule for recipients that were
+// just added to a file rule
+func applyWildcardRule(config *SopsConfig, recipients AgeRecipients, policy WildcardPolicy) {
+	var wanted AgeRecipients
+	switch policy.Mode {
+	case WildcardNone:
+		return
+	case WildcardDefault:
+		wanted = UniqueRecipients(policy.Recipients)
+	default:
+		wanted = recipients
+	}
+
+	pattern := ScopedWildcardPattern(policy.Scope)
+	for i, rule := range config.CreationRules {
+		if rule.PathRegex != pattern {
+			continue
+		}
+
+		if policy.Mode == WildcardDefault {
+			config.CreationRules[i].Age = wanted
+		} else {
+			// Keep existing recipients so other teammates can still read new files
+			config.CreationRules[i].Age = UniqueRecipients(append(slices.Clone(rule.Age), wanted...))
+		}
+		return
+	}
+
+	// The catch-all rule of a scope goes before the one of the repository, which would shadow it
+	rule := CreationRule{PathRegex: pattern, Age: slices.Clone(wanted)}
+	index := slices.IndexFunc(config.CreationRules, func(r CreationRule) bool { return r.PathRegex == WildcardPattern })
+	if policy.Scope == "" || index < 0 {
+		config.CreationRules = append(config.CreationRules, rule)
+		return
+	}
+	config.CreationRules = slices.Insert(config.CreationRules, index, rule)
+}
@@ -0,0 +1,159 @@
+// Package convert maps file formats sops can't read to JSON and back
+//
+// sops has no support for TOML or Java properties files. Such files are
+// encrypted as JSON holding a marker key with the original format, and are
+// converted back when they are decrypted or edited.
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// MarkerKey is the top-level key holding the original format of a converted file
+const MarkerKey = "simple_sops_format"
+
+const (
+	// FormatTOML is the format of .toml files
+	FormatTOML = "toml"
+	// FormatProperties is the format of Java .properties files
+	FormatProperties = "properties"
+)
+
+// FormatOf returns the format of a file that must be converted for sops
+// It returns an empty string for formats sops reads itself.
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".properties":
+		return FormatProperties
+	}
+	return ""
+}
+
+// IsConverted checks if data is the JSON form of a converted file
+// The marker key stays readable when the file is encrypted, since sops only
+// encrypts values.
+func IsConverted(data []byte) bool {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return false
+	}
+	_, ok := object[MarkerKey]
+	return ok
+}
+
+// ToJSON converts a file of the given format to its JSON form
+func ToJSON(format string, data []byte) ([]byte, error) {
+	switch format {
+	case FormatTOML:
+		var values map[string]interface{}
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse TOML: %w", err)
+		}
+		if _, ok := values[MarkerKey]; ok {
+			return nil, fmt.Errorf("key %s is reserved", MarkerKey)
+		}
+		values[MarkerKey] = format
+
+		encoded, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return append(encoded, '\n'), nil
+
+	case FormatProperties:
+		properties, err := ParseProperties(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// Build the object by hand to keep the order of the properties
+		var buffer bytes.Buffer
+		buffer.WriteString("{\n")
+		writeJSONField(&buffer, MarkerKey, format)
+		for _, property := range properties {
+			if property.Key == MarkerKey {
+				return nil, fmt.Errorf("key %s is reserved", MarkerKey)
+			}
+			buffer.WriteString(",\n")
+			writeJSONField(&buffer, property.Key, property.Value)
+		}
+		buffer.WriteString("\n}\n")
+		return buffer.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+// writeJSONField writes an indented "key": "value" pair
+func writeJSONField(buffer *bytes.Buffer, key string, value string) {
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, _ := json.Marshal(value)
+	buffer.WriteString("  ")
+	buffer.Write(encodedKey)
+	buffer.WriteString(": ")
+	buffer.Write(encodedValue)
+}
+
+// FromJSON converts the JSON form of a file back to its original format
+// The format is read from the marker key and returned with the data.
+func FromJSON(data []byte) ([]byte, string, error) {
+	// JSON is valid YAML, and YAML nodes keep the order of the keys
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, "", fmt.Errorf("expected a JSON object")
+	}
+
+	// Remove the marker before converting the remaining keys
+	root := document.Content[0]
+	format := ""
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == MarkerKey {
+			format = root.Content[i+1].Value
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+	}
+
+	switch format {
+	case FormatTOML:
+		var values map[string]interface{}
+		if err := root.Decode(&values); err != nil {
+			return nil, "", fmt.Errorf("failed to decode values: %w", err)
+		}
+
+		var buffer bytes.Buffer
+		encoder := toml.NewEncoder(&buffer)
+		encoder.Indent = ""
+		if err := encoder.Encode(values); err != nil {
+			return nil, "", fmt.Errorf("failed to encode TOML: %w", err)
+		}
+		return buffer.Bytes(), format, nil
+
+	case FormatProperties:
+		var properties []Property
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i+1].Kind != yaml.ScalarNode {
+				return nil, "", fmt.Errorf("property %s is not a single value", root.Content[i].Value)
+			}
+			properties = append(properties, Property{Key: root.Content[i].Value, Value: root.Content[i+1].Value})
+		}
+		return MarshalProperties(properties), format, nil
+
+	case "":
+		return nil, "", fmt.Errorf("missing %s marker", MarkerKey)
+	}
+
+	return nil, "", fmt.Errorf("unsupported format: %s", format)
+}
@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"config.toml":            FormatTOML,
+		"Cargo.TOML":             FormatTOML,
+		"app/prod.properties":    FormatProperties,
+		"secrets.yaml":           "",
+		"values.json":            "",
+		"properties.toml.backup": "",
+	}
+
+	for path, expected := range tests {
+		if got := FormatOf(path); got != expected {
+			t.Errorf("FormatOf(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	input := `title = "app"
+port = 8080
+ratio = 0.5
+enabled = true
+tags = ["a", "b"]
+
+[database]
+password = "s3cret"
+
+[[servers]]
+name = "alpha"
+`
+
+	converted, err := ToJSON(FormatTOML, []byte(input))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !IsConverted(converted) {
+		t.Errorf("Expected converted JSON to contain the marker, got:\n%s", converted)
+	}
+
+	output, format, err := FromJSON(converted)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if format != FormatTOML {
+		t.Errorf("Expected format %q, got %q", FormatTOML, format)
+	}
+
+	// Key order isn't kept, so compare the decoded values
+	for _, expected := range []string{`title = "app"`, "port = 8080", "ratio = 0.5", "enabled = true",
+		`tags = ["a", "b"]`, "[database]", `password = "s3cret"`, "[[servers]]", `name = "alpha"`} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(string(output), MarkerKey) {
+		t.Errorf("Expected marker to be removed, got:\n%s", output)
+	}
+}
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	input := `# Database settings
+db.url = jdbc:postgresql://localhost/app
+db.password:s3cret
+! another comment
+message Hello \
+    World
+path=C:\\temp
+unicode=caf\u00e9
+db.password=changed
+`
+
+	properties, err := ParseProperties([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseProperties failed: %v", err)
+	}
+
+	expected := []Property{
+		{Key: "db.url", Value: "jdbc:postgresql://localhost/app"},
+		{Key: "db.password", Value: "changed"},
+		{Key: "message", Value: "Hello World"},
+		{Key: "path", Value: `C:\temp`},
+		{Key: "unicode", Value: "café"},
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, properties)
+	}
+
+	converted, err := ToJSON(FormatProperties, []byte(input))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.HasPrefix(string(converted), "{\n  \"simple_sops_format\": \"properties\",\n  \"db.url\"") {
+		t.Errorf("Expected marker first and properties in order, got:\n%s", converted)
+	}
+
+	output, format, err := FromJSON(converted)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if format != FormatProperties {
+		t.Errorf("Expected format %q, got %q", FormatProperties, format)
+	}
+
+	reparsed, err := ParseProperties(output)
+	if err != nil {
+		t.Fatalf("ParseProperties of output failed: %v", err)
+	}
+	if !reflect.DeepEqual(reparsed, expected) {
+		t.Errorf("Round trip: expected %#v, got %#v", expected, reparsed)
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	if _, err := ToJSON(FormatTOML, []byte("simple_sops_format = \"x\"\n")); err == nil {
+		t.Error("Expected error for reserved key, got nil")
+	}
+	if _, err := ToJSON(FormatTOML, []byte("not toml")); err == nil {
+		t.Error("Expected error for invalid TOML, got nil")
+	}
+	if _, _, err := FromJSON([]byte(`{"key": "value"}`)); err == nil {
+		t.Error("Expected error for missing marker, got nil")
+	}
+	if IsConverted([]byte("key = \"value\"\n")) {
+		t.Error("Expected plain TOML not to be detected as converted")
+	}
+}
@@ -0,0 +1,167 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Property is a key and value of a Java properties file
+type Property struct {
+	Key   string
+	Value string
+}
+
+// ParseProperties parses a Java properties file
+// It supports # and ! comments, the =, : and whitespace separators, lines
+// continued with a trailing backslash and the escapes of the format.
+// Comments are dropped and a repeated key keeps its last value.
+func ParseProperties(data []byte) ([]Property, error) {
+	var properties []Property
+	index := make(map[string]int)
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// Join continuation lines, dropping the leading whitespace of each
+		for continuesLine(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		key, value := splitProperty(line)
+		key, err := unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		value, err = unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if existing, ok := index[key]; ok {
+			properties[existing].Value = value
+			continue
+		}
+		index[key] = len(properties)
+		properties = append(properties, Property{Key: key, Value: value})
+	}
+
+	return properties, nil
+}
+
+// continuesLine checks if a line ends with an unescaped backslash
+func continuesLine(line string) bool {
+	backslashes := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// splitProperty splits a line at the first unescaped separator
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			// Whitespace separates too, optionally followed by = or :
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			return line[:i], rest
+		}
+	}
+	return line, ""
+}
+
+// unescapeProperty resolves the escapes of a key or value
+func unescapeProperty(text string) (string, error) {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			builder.WriteByte(text[i])
+			continue
+		}
+
+		i++
+		switch text[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(text) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape: \\u%s", text[i+1:i+5])
+			}
+			builder.WriteRune(rune(code))
+			i += 4
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+	return builder.String(), nil
+}
+
+// MarshalProperties writes properties as a Java properties file
+// Non-ASCII characters are kept as UTF-8, which Java reads since version 9.
+func MarshalProperties(properties []Property) []byte {
+	var builder strings.Builder
+	for _, property := range properties {
+		builder.WriteString(escapeProperty(property.Key, true))
+		builder.WriteByte('=')
+		builder.WriteString(escapeProperty(property.Value, false))
+		builder.WriteByte('\n')
+	}
+	return []byte(builder.String())
+}
+
+// escapeProperty escapes a key or value for a properties file
+func escapeProperty(text string, isKey bool) string {
+	var builder strings.Builder
+	for i, r := range text {
+		switch r {
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\f':
+			builder.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if isKey || i == 0 {
+				builder.WriteByte('\\')
+			}
+			builder.WriteRune(r)
+		case ' ':
+			// Spaces separate keys and lead values
+			if isKey || i == 0 {
+				builder.WriteByte('\\')
+			}
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
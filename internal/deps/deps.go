@@ -0,0 +1,218 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// Tool is an external program simple-sops depends on
+type Tool struct {
+	// Name is the name of the project
+	Name string
+	// Binary is the executable looked up in PATH
+	Binary string
+	// Packages maps installer names to package names, missing if not packaged
+	Packages map[string]string
+	// URL is where the tool can be downloaded manually
+	URL string
+}
+
+var (
+	// Sops encrypts and decrypts the files
+	Sops = Tool{
+		Name:   "sops",
+		Binary: "sops",
+		Packages: map[string]string{
+			"brew":  "sops",
+			"nix":   "nixpkgs#sops",
+			"scoop": "sops",
+		},
+		URL: "https://github.com/getsops/sops/releases",
+	}
+
+	// Age generates the keys with age-keygen
+	Age = Tool{
+		Name:   "age",
+		Binary: "age-keygen",
+		Packages: map[string]string{
+			"brew":    "age",
+			"nix":     "nixpkgs#age",
+			"apt-get": "age",
+			"scoop":   "age",
+		},
+		URL: "https://github.com/FiloSottile/age/releases",
+	}
+
+	// AgeCLI reads and writes plain age files, it comes in the package of age-keygen
+	AgeCLI = Tool{
+		Name:   "age",
+		Binary: "age",
+		Packages: map[string]string{
+			"brew":    "age",
+			"nix":     "nixpkgs#age",
+			"apt-get": "age",
+			"scoop":   "age",
+		},
+		URL: "https://github.com/FiloSottile/age/releases",
+	}
+
+	// AgePluginYubikey keeps Age identities in YubiKey PIV slots
+	AgePluginYubikey = Tool{
+		Name:   "age-plugin-yubikey",
+		Binary: "age-plugin-yubikey",
+		Packages: map[string]string{
+			"brew": "age-plugin-yubikey",
+			"nix":  "nixpkgs#age-plugin-yubikey",
+		},
+		URL: "https://github.com/str4d/age-plugin-yubikey/releases",
+	}
+)
+
+// Installer is a package manager that can install missing tools
+type Installer struct {
+	Name string
+	// Command is the install command, the package names are appended
+	Command []string
+}
+
+// installers lists the package managers per platform in order of preference
+var installers = map[string][]Installer{
+	"darwin": {
+		{Name: "brew", Command: []string{"brew", "install"}},
+		{Name: "nix", Command: []string{"nix", "profile", "install"}},
+	},
+	"linux": {
+		{Name: "brew", Command: []string{"brew", "install"}},
+		{Name: "nix", Command: []string{"nix", "profile", "install"}},
+		{Name: "apt-get", Command: []string{"apt-get", "install", "-y"}},
+	},
+	"windows": {
+		{Name: "scoop", Command: []string{"scoop", "install"}},
+	},
+}
+
+var (
+	// Use variables for the lookups to allow mocking in tests
+	lookPath = exec.LookPath
+	goos     = runtime.GOOS
+	geteuid  = os.Geteuid
+
+	// execCommand runs the installer, replaced in tests
+	execCommand = exec.Command
+)
+
+// Missing returns the tools that are not installed
+func Missing(tools ...Tool) []Tool {
+	var missing []Tool
+	for _, tool := range tools {
+		if _, err := lookPath(tool.Binary); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+// DetectInstaller returns the first installed package manager of the platform
+func DetectInstaller() (Installer, bool) {
+	for _, installer := range installers[goos] {
+		if _, err := lookPath(installer.Command[0]); err == nil {
+			return installer, true
+		}
+	}
+	return Installer{}, false
+}
+
+// Hint returns how to install a tool on this platform
+func Hint(tool Tool) string {
+	if installer, ok := DetectInstaller(); ok {
+		if command, ok := installCommand(installer, []Tool{tool}); ok {
+			return strings.Join(command, " ")
+		}
+	}
+	return "download it from " + tool.URL
+}
+
+// installCommand returns the command installing the tools with an installer
+// ok is false if one of the tools isn't packaged for the installer.
+func installCommand(installer Installer, tools []Tool) ([]string, bool) {
+	command := append([]string{}, installer.Command...)
+	for _, tool := range tools {
+		pkg, ok := tool.Packages[installer.Name]
+		if !ok {
+			return nil, false
+		}
+		command = append(command, pkg)
+	}
+
+	// apt-get needs root
+	if installer.Name == "apt-get" && geteuid() != 0 {
+		command = append([]string{"sudo"}, command...)
+	}
+	return command, true
+}
+
+// Check returns an error listing the missing tools and how to install them
+// With install the missing tools are installed with the package manager of
+// the platform first.
+func Check(install bool, tools ...Tool) error {
+	missing := Missing(tools...)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if install {
+		if err := Install(missing); err != nil {
+			return err
+		}
+		if missing = Missing(missing...); len(missing) == 0 {
+			return nil
+		}
+	}
+
+	var lines []string
+	for _, tool := range missing {
+		lines = append(lines, fmt.Sprintf("%s is not installed (%s not found in PATH), install it with: %s", tool.Name, tool.Binary, Hint(tool)))
+	}
+	if !install {
+		lines = append(lines, "Pass --install-deps to install it with the package manager of your platform")
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// Install installs tools with the package manager of the platform
+func Install(tools []Tool) error {
+	installer, ok := DetectInstaller()
+	if !ok {
+		return fmt.Errorf("no supported package manager found, install %s manually", toolNames(tools))
+	}
+
+	command, ok := installCommand(installer, tools)
+	if !ok {
+		return fmt.Errorf("%s doesn't package %s, install it manually", installer.Name, toolNames(tools))
+	}
+
+	logging.Info("Installing %s: %s", toolNames(tools), strings.Join(command, " "))
+	cmd := execCommand(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to install %s: %w", toolNames(tools), err)
+	}
+
+	logging.Success("Installed %s", toolNames(tools))
+	return nil
+}
+
+// toolNames joins the names of tools for messages
+func toolNames(tools []Tool) string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return strings.Join(names, " and ")
+}
@@ -0,0 +1,95 @@
+package deps
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// mockPlatform makes only the given binaries installed on a platform
+func mockPlatform(t *testing.T, platform string, euid int, installed ...string) {
+	originalLookPath, originalGoos, originalGeteuid := lookPath, goos, geteuid
+	t.Cleanup(func() { lookPath, goos, geteuid = originalLookPath, originalGoos, originalGeteuid })
+
+	goos = platform
+	geteuid = func() int { return euid }
+	lookPath = func(file string) (string, error) {
+		for _, name := range installed {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		euid      int
+		installed []string
+		tool      Tool
+		want      string
+	}{
+		{"brew on macOS", "darwin", 501, []string{"brew", "nix"}, Sops, "brew install sops"},
+		{"nix on Linux", "linux", 1000, []string{"nix", "apt-get"}, Age, "nix profile install nixpkgs#age"},
+		{"apt-get as user", "linux", 1000, []string{"apt-get"}, Age, "sudo apt-get install -y age"},
+		{"apt-get as root", "linux", 0, []string{"apt-get"}, Age, "apt-get install -y age"},
+		{"not packaged", "linux", 1000, []string{"apt-get"}, Sops, "download it from " + Sops.URL},
+		{"scoop on Windows", "windows", -1, []string{"scoop"}, Sops, "scoop install sops"},
+		{"no package manager", "linux", 1000, nil, Age, "download it from " + Age.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPlatform(t, tt.goos, tt.euid, tt.installed...)
+			if got := Hint(tt.tool); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	mockPlatform(t, "darwin", 501, "brew", "age-keygen")
+
+	if err := Check(false, Age); err != nil {
+		t.Errorf("Expected no error for installed tools, got %v", err)
+	}
+
+	err := Check(false, Sops, Age)
+	if err == nil {
+		t.Fatal("Expected an error for missing sops")
+	}
+	for _, want := range []string{"sops is not installed", "brew install sops", "--install-deps"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "age is not installed") {
+		t.Errorf("Expected only missing tools in the error, got %q", err)
+	}
+}
+
+func TestCheckInstall(t *testing.T) {
+	mockPlatform(t, "darwin", 501, "brew")
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	var ran []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		ran = append([]string{name}, args...)
+		// Installing makes the binaries available
+		lookPath = func(file string) (string, error) { return "/usr/local/bin/" + file, nil }
+		return exec.Command("true")
+	}
+
+	if err := Check(true, Sops, Age); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if got := strings.Join(ran, " "); got != "brew install sops age" {
+		t.Errorf("Expected brew install sops age, got %q", got)
+	}
+}
@@ -0,0 +1,211 @@
+// Package dotenv parses and writes dotenv files
+//
+// Dotenv files in the wild use comments, export prefixes and quoted values
+// that span several lines. sops only understands plain KEY=value lines with
+// newlines escaped as \n, so files are converted between both forms.
+package dotenv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Entry is a line of a dotenv file: a variable or a comment
+type Entry struct {
+	// Key is the variable name, empty for comments
+	Key string
+	// Value is the unquoted value
+	Value string
+	// Comment is the text of a comment line without the leading #
+	Comment string
+}
+
+// IsComment checks if the entry is a comment line
+func (e Entry) IsComment() bool {
+	return e.Key == ""
+}
+
+// IsDotenvFile checks if a file name looks like a dotenv file
+// Besides the .env extension this covers names like .env.production, but not
+// files of other formats named after an environment, like app.env.json.
+func IsDotenvFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json", ".ini", ".toml", ".properties":
+		return false
+	}
+	return strings.HasSuffix(name, ".env") || strings.HasPrefix(name, ".env.")
+}
+
+// Parse parses a dotenv file
+// It supports comments, export prefixes, inline comments after unquoted
+// values, single-quoted literal values and double-quoted values with escapes.
+// Quoted values may span several lines.
+func Parse(data []byte) ([]Entry, error) {
+	var entries []Entry
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			entries = append(entries, Entry{Comment: strings.TrimPrefix(line, "#")})
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNumber)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			entries = append(entries, Entry{Key: key, Value: unquotedValue(rest)})
+			continue
+		}
+
+		// Quoted values continue until the closing quote, possibly on a later line
+		quote := rest[0]
+		value := rest[1:]
+		for {
+			end := closingQuote(value, quote)
+			if end >= 0 {
+				if trailing := strings.TrimSpace(value[end+1:]); trailing != "" && !strings.HasPrefix(trailing, "#") {
+					return nil, fmt.Errorf("line %d: unexpected text after closing quote", lineNumber)
+				}
+				value = value[:end]
+				break
+			}
+
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value for %s", lineNumber, key)
+			}
+			value += "\n" + lines[i]
+		}
+
+		if quote == '"' {
+			value = unescape(value)
+		}
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+
+	return entries, nil
+}
+
+// unquotedValue strips an inline comment and surrounding whitespace
+func unquotedValue(value string) string {
+	if index := strings.Index(value, " #"); index >= 0 {
+		value = value[:index]
+	}
+	return strings.TrimSpace(value)
+}
+
+// closingQuote returns the index of the closing quote, skipping escaped
+// quotes in double-quoted values
+func closingQuote(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescape resolves the escapes of a double-quoted value
+func unescape(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		case '"', '\\', '$', '`':
+			builder.WriteByte(value[i])
+		default:
+			builder.WriteByte('\\')
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
+
+// Quote quotes a value for dotenv files when needed
+func Quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\"'#$\\`") {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// Marshal writes entries as a dotenv file, quoting values where needed
+func Marshal(entries []Entry) []byte {
+	var builder strings.Builder
+	for _, entry := range entries {
+		if entry.IsComment() {
+			builder.WriteString("#" + entry.Comment + "\n")
+			continue
+		}
+		builder.WriteString(entry.Key + "=" + Quote(entry.Value) + "\n")
+	}
+	return []byte(builder.String())
+}
+
+// ParseSops parses a dotenv file in the form sops reads and writes
+// Values are taken literally, except for newlines escaped as \n.
+func ParseSops(data []byte) []Entry {
+	var entries []Entry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			entries = append(entries, Entry{Comment: strings.TrimPrefix(strings.TrimSpace(line), "#")})
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		entries = append(entries, Entry{
+			Key:   strings.TrimSpace(key),
+			Value: strings.ReplaceAll(value, `\n`, "\n"),
+		})
+	}
+	return entries
+}
+
+// MarshalSops writes entries in the form sops reads and writes
+func MarshalSops(entries []Entry) []byte {
+	var builder strings.Builder
+	for _, entry := range entries {
+		if entry.IsComment() {
+			builder.WriteString("#" + entry.Comment + "\n")
+			continue
+		}
+		builder.WriteString(entry.Key + "=" + strings.ReplaceAll(entry.Value, "\n", `\n`) + "\n")
+	}
+	return []byte(builder.String())
+}
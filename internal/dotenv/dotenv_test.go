@@ -0,0 +1,100 @@
+package dotenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`# Database settings
+export DB_HOST=localhost # inline comment
+DB_PASSWORD="p@ss \"word\""
+LITERAL='$HOME\n'
+CERT="-----BEGIN-----
+abc
+-----END-----"
+EMPTY=
+`)
+
+	entries, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []Entry{
+		{Comment: " Database settings"},
+		{Key: "DB_HOST", Value: "localhost"},
+		{Key: "DB_PASSWORD", Value: `p@ss "word"`},
+		{Key: "LITERAL", Value: `$HOME\n`},
+		{Key: "CERT", Value: "-----BEGIN-----\nabc\n-----END-----"},
+		{Key: "EMPTY", Value: ""},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, entries)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing equals":    "JUST_A_KEY\n",
+		"space in key":      "MY KEY=value\n",
+		"unterminated":      "KEY=\"value\nmore\n",
+		"text after quotes": "KEY=\"value\" trailing\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Comment: " comment"},
+		{Key: "PLAIN", Value: "value"},
+		{Key: "SPACES", Value: "hello world"},
+		{Key: "MULTILINE", Value: "line1\nline2"},
+		{Key: "SPECIAL", Value: `a"b$c\d`},
+	}
+
+	parsed, err := Parse(Marshal(entries))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, entries) {
+		t.Errorf("Marshal round trip: expected %#v, got %#v", entries, parsed)
+	}
+
+	sopsData := MarshalSops(entries)
+	if string(sopsData) != "# comment\nPLAIN=value\nSPACES=hello world\nMULTILINE=line1\\nline2\nSPECIAL=a\"b$c\\d\n" {
+		t.Errorf("Unexpected sops output: %q", sopsData)
+	}
+	if parsed := ParseSops(sopsData); !reflect.DeepEqual(parsed, entries) {
+		t.Errorf("Sops round trip: expected %#v, got %#v", entries, parsed)
+	}
+}
+
+func TestIsDotenvFile(t *testing.T) {
+	tests := map[string]bool{
+		".env":               true,
+		"app.env":            true,
+		".env.production":    true,
+		"config/.env.dev":    true,
+		"config/app.env.dev": false,
+		"secrets.yaml":       false,
+		"values.env.yaml":    false,
+		"foo.env.json":       false,
+		".env.toml":          false,
+		"config.json":        false,
+		"environment.txt":    false,
+	}
+
+	for path, expected := range tests {
+		if got := IsDotenvFile(path); got != expected {
+			t.Errorf("IsDotenvFile(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AgeExtension is the extension of files encrypted with the age CLI
+const AgeExtension = ".age"
+
+// DecryptAgeFile decrypts a file encrypted with the age CLI instead of sops
+// Binary and armored files both work, the key file is passed to age as its identity.
+func DecryptAgeFile(filePath string, keyFile string) ([]byte, error) {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input file not found: %s", filePath)
+	}
+
+	cmd := execCommand("age", "--decrypt", "--identity", keyFile, filePath)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("failed to decrypt %s with age: %s", filePath, message)
+		}
+		return nil, fmt.Errorf("failed to decrypt %s with age: %w", filePath, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// EncryptAgeFile encrypts plaintext with the age CLI into outputPath, readable without sops
+// The plaintext is passed on stdin, so it never touches the disk. Recipients
+// are checked against the trust store like those of sops files.
+func EncryptAgeFile(plaintext []byte, outputPath string, recipients []string, armor bool) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients specified")
+	}
+	if err := checkRecipientsTrusted(recipients); err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt"}
+	if armor {
+		args = append(args, "--armor")
+	}
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+	args = append(args, "--output", outputPath)
+
+	cmd := execCommand("age", args...)
+	cmd.Stdin = bytes.NewReader(plaintext)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("failed to encrypt %s with age: %s", outputPath, message)
+		}
+		return fmt.Errorf("failed to encrypt %s with age: %w", outputPath, err)
+	}
+	return nil
+}
+
+// AgePlainName returns the name of the plaintext of an age file, without the .age extension
+// Returns false if the file doesn't have the extension.
+func AgePlainName(filePath string) (string, bool) {
+	if len(filePath) <= len(AgeExtension) || !strings.EqualFold(filePath[len(filePath)-len(AgeExtension):], AgeExtension) {
+		return "", false
+	}
+	return filePath[:len(filePath)-len(AgeExtension)], true
+}
@@ -0,0 +1,71 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDecryptAgeFile(t *testing.T) {
+	keyPath, testFilePath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockExecOutput = nil
+	mockExecError = nil
+
+	agePath := testFilePath + AgeExtension
+	if err := os.WriteFile(agePath, []byte("age-encryption.org/v1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write age file: %v", err)
+	}
+	if _, err := DecryptAgeFile(agePath, keyPath); err != nil {
+		t.Fatalf("DecryptAgeFile failed: %v", err)
+	}
+	want := []string{"--decrypt", "--identity", keyPath, agePath}
+	if lastExecCommand.cmd != "age" || !slices.Equal(lastExecCommand.args, want) {
+		t.Errorf("Expected age %v, got %s %v", want, lastExecCommand.cmd, lastExecCommand.args)
+	}
+
+	if _, err := DecryptAgeFile(filepath.Join(filepath.Dir(agePath), "missing.age"), keyPath); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestEncryptAgeFile(t *testing.T) {
+	_, testFilePath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockExecOutput = nil
+	mockExecError = nil
+
+	outputPath := testFilePath + AgeExtension
+	if err := EncryptAgeFile([]byte("TEST=value\n"), outputPath, []string{"age1alice", "age1bob"}, true); err != nil {
+		t.Fatalf("EncryptAgeFile failed: %v", err)
+	}
+	want := []string{"--encrypt", "--armor", "--recipient", "age1alice", "--recipient", "age1bob", "--output", outputPath}
+	if lastExecCommand.cmd != "age" || !slices.Equal(lastExecCommand.args, want) {
+		t.Errorf("Expected age %v, got %s %v", want, lastExecCommand.cmd, lastExecCommand.args)
+	}
+
+	if err := EncryptAgeFile([]byte("TEST=value\n"), outputPath, nil, false); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestAgePlainName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"secrets/db.yaml.age", "secrets/db.yaml", true},
+		{"token.env.AGE", "token.env", true},
+		{"secrets.yaml", "", false},
+		{".age", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := AgePlainName(tt.path); got != tt.want || ok != tt.ok {
+			t.Errorf("AgePlainName(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
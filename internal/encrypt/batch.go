@@ -0,0 +1,96 @@
+package encrypt
+
+import (
+	"fmt"
+	"runtime"
+	"simple-sops/internal/exitcode"
+	"simple-sops/pkg/logging"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultJobs is the number of files bulk operations process at once
+var DefaultJobs = runtime.NumCPU()
+
+// failFast stops bulk operations at the first file that fails
+var failFast bool
+
+// SetFailFast sets whether bulk operations stop at the first file that fails
+// By default the remaining files are still processed.
+func SetFailFast(enabled bool) {
+	failFast = enabled
+}
+
+// FailFast reports whether bulk operations stop at the first file that fails
+func FailFast() bool {
+	return failFast
+}
+
+// BulkError returns the result of a bulk operation from the error of the last failed file
+// If other files succeeded, the error is marked as a partial failure.
+func BulkError(lastErr error, failed int, succeeded int) error {
+	if lastErr == nil {
+		return nil
+	}
+	if succeeded == 0 {
+		return lastErr
+	}
+	return exitcode.Wrap(exitcode.Partial, fmt.Errorf("%d file(s) failed: %w", failed, lastErr))
+}
+
+// forEachFile runs fn for every file, processing up to jobs files at once
+// Operations starting a sops process per file hide the process startup this
+// way, which dominates for many small files. Failures are logged with the
+// action that failed and the error of the last failed file is returned. In
+// dry-run mode files are processed one by one, so the printed plan stays in
+// order. With fail-fast no more files are started once one failed.
+func forEachFile(filePaths []string, jobs int, action string, fn func(string) error) error {
+	if jobs < 1 || dryRun {
+		jobs = 1
+	}
+
+	errs := make([]error, len(filePaths))
+	done := make([]bool, len(filePaths))
+	var stopped atomic.Bool
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(filePaths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				done[i] = true
+				if err := fn(filePaths[i]); err != nil {
+					logging.Error("Failed to %s %s: %v", action, filePaths[i], err)
+					logging.Record(filePaths[i], action, logging.ResultFailed)
+					errs[i] = err
+					if failFast {
+						stopped.Store(true)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range filePaths {
+		if stopped.Load() {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var lastErr error
+	failed, succeeded := 0, 0
+	for i, err := range errs {
+		switch {
+		case err != nil:
+			lastErr = err
+			failed++
+		case done[i]:
+			succeeded++
+		}
+	}
+	return BulkError(lastErr, failed, succeeded)
+}
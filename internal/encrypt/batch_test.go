@@ -0,0 +1,93 @@
+package encrypt
+
+import (
+	"errors"
+	"simple-sops/internal/exitcode"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachFile(t *testing.T) {
+	files := []string{"a.yaml", "b.yaml", "c.yaml", "d.yaml"}
+
+	var (
+		mu        sync.Mutex
+		processed = make(map[string]bool)
+		running   int
+		peak      int
+	)
+	errFailed := errors.New("failed")
+	err := forEachFile(files, 2, "rotate", func(file string) error {
+		mu.Lock()
+		processed[file] = true
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if file == "c.yaml" {
+			return errFailed
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected the error of c.yaml, got %v", err)
+	}
+	if code := exitcode.Code(err); code != exitcode.Partial {
+		t.Errorf("Expected exit code %d for a partial failure, got %d", exitcode.Partial, code)
+	}
+	if len(processed) != len(files) {
+		t.Errorf("Expected all files to be processed, got %v", processed)
+	}
+	if peak != 2 {
+		t.Errorf("Expected 2 files to be processed at once, got %d", peak)
+	}
+}
+
+func TestForEachFileDryRunSequential(t *testing.T) {
+	SetDryRun(true)
+	defer SetDryRun(false)
+
+	var order []string
+	err := forEachFile([]string{"a.yaml", "b.yaml", "c.yaml"}, 4, "rotate", func(file string) error {
+		order = append(order, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachFile failed: %v", err)
+	}
+
+	if len(order) != 3 || order[0] != "a.yaml" || order[1] != "b.yaml" || order[2] != "c.yaml" {
+		t.Errorf("Expected files in order, got %v", order)
+	}
+}
+
+func TestForEachFileFailFast(t *testing.T) {
+	SetFailFast(true)
+	defer SetFailFast(false)
+
+	var processed []string
+	errFailed := errors.New("failed")
+	err := forEachFile([]string{"a.yaml", "b.yaml", "c.yaml"}, 1, "rotate", func(file string) error {
+		processed = append(processed, file)
+		if file == "a.yaml" {
+			return errFailed
+		}
+		return nil
+	})
+
+	if len(processed) != 1 {
+		t.Errorf("Expected to stop after a.yaml, processed %v", processed)
+	}
+	// No file succeeded, so it's not a partial failure
+	if err != errFailed {
+		t.Errorf("Expected the error of a.yaml, got %v", err)
+	}
+}
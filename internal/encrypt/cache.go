@@ -0,0 +1,102 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"simple-sops/internal/metrics"
+	"sync"
+)
+
+// DecryptToBytes decrypts a file and returns the plaintext
+func DecryptToBytes(inputPath string, keyFile string) ([]byte, error) {
+	// Check if input file exists
+	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input file not found: %s", inputPath)
+	}
+
+	cmd := execCommand("sops", sopsArgs(inputPath, "--decrypt")...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, sopsFailed(fmt.Errorf("failed to decrypt file: %w", err))
+	}
+
+	return formatDecrypted(inputPath, stdout.Bytes())
+}
+
+// DecryptCache caches decrypted content in memory keyed by the hash of the
+// ciphertext and the key file, so repeated decryption of unchanged files skips
+// sops. Another key file decrypts again, so a key that can't read a file
+// isn't served a plaintext another key decrypted.
+type DecryptCache struct {
+	mu         sync.Mutex
+	entries    map[decryptCacheKey][]byte
+	order      []decryptCacheKey
+	maxEntries int
+
+	// decrypt performs the actual decryption (swappable for tests)
+	decrypt func(inputPath string, keyFile string) ([]byte, error)
+}
+
+// decryptCacheKey identifies a plaintext in the cache
+type decryptCacheKey struct {
+	hash    [sha256.Size]byte
+	keyFile string
+}
+
+// NewDecryptCache creates a cache holding at most maxEntries plaintexts
+func NewDecryptCache(maxEntries int) *DecryptCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+
+	return &DecryptCache{
+		entries:    make(map[decryptCacheKey][]byte),
+		maxEntries: maxEntries,
+		decrypt:    DecryptToBytes,
+	}
+}
+
+// Decrypt returns the plaintext of a file, using the cache when the
+// ciphertext is unchanged. The second return value reports a cache hit.
+func (c *DecryptCache) Decrypt(inputPath string, keyFile string) ([]byte, bool, error) {
+	ciphertext, err := os.ReadFile(inputPath)
+	if err != nil {
+		metrics.DecryptFailures.Inc()
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	key := decryptCacheKey{hash: sha256.Sum256(ciphertext), keyFile: keyFile}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if plaintext, ok := c.entries[key]; ok {
+		metrics.DecryptCacheHits.Inc()
+		return plaintext, true, nil
+	}
+
+	plaintext, err := c.decrypt(inputPath, keyFile)
+	if err != nil {
+		metrics.DecryptFailures.Inc()
+		return nil, false, err
+	}
+	metrics.Decrypts.Inc()
+
+	// Evict the oldest entry when full
+	if len(c.order) >= c.maxEntries {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = plaintext
+	c.order = append(c.order, key)
+
+	return plaintext, false, nil
+}
@@ -0,0 +1,65 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecryptCache(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "cache-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filePath := filepath.Join(tempDir, "secret.enc.yaml")
+	os.WriteFile(filePath, []byte("ciphertext-1"), 0644)
+
+	// Count decryptions instead of running sops
+	calls := 0
+	cache := NewDecryptCache(2)
+	cache.decrypt = func(inputPath string, keyFile string) ([]byte, error) {
+		calls++
+		content, _ := os.ReadFile(inputPath)
+		return append([]byte("plain:"), content...), nil
+	}
+
+	plaintext, hit, err := cache.Decrypt(filePath, "key.txt")
+	if err != nil || hit || string(plaintext) != "plain:ciphertext-1" {
+		t.Fatalf("Unexpected first decryption: %s, %v, %v", plaintext, hit, err)
+	}
+
+	// Unchanged ciphertext is served from the cache
+	_, hit, _ = cache.Decrypt(filePath, "key.txt")
+	if !hit || calls != 1 {
+		t.Errorf("Expected cache hit without decrypting, got hit=%v calls=%d", hit, calls)
+	}
+
+	// Another key decrypts again, e.g. after the config was reloaded
+	if _, hit, _ = cache.Decrypt(filePath, "other.txt"); hit || calls != 2 {
+		t.Errorf("Expected another key to decrypt again, got hit=%v calls=%d", hit, calls)
+	}
+
+	// Changed ciphertext is decrypted again
+	os.WriteFile(filePath, []byte("ciphertext-2"), 0644)
+	plaintext, hit, _ = cache.Decrypt(filePath, "key.txt")
+	if hit || calls != 3 || string(plaintext) != "plain:ciphertext-2" {
+		t.Errorf("Expected fresh decryption, got %s, hit=%v calls=%d", plaintext, hit, calls)
+	}
+
+	// Switching back to the first content is still cached
+	os.WriteFile(filePath, []byte("ciphertext-1"), 0644)
+	if _, hit, _ = cache.Decrypt(filePath, "other.txt"); !hit {
+		t.Error("Expected cache hit for previously seen ciphertext")
+	}
+
+	// The oldest entry is evicted once the cache is full
+	os.WriteFile(filePath, []byte("ciphertext-3"), 0644)
+	cache.Decrypt(filePath, "key.txt")
+	os.WriteFile(filePath, []byte("ciphertext-1"), 0644)
+	if _, hit, _ = cache.Decrypt(filePath, "key.txt"); hit {
+		t.Error("Expected evicted entry to be decrypted again")
+	}
+}
@@ -1,8 +1,193 @@
 package encrypt
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/convert"
+	"simple-sops/internal/dotenv"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/version"
+	"simple-sops/pkg/logging"
 )
 
 // Use a variable for exec.Command to allow mocking in tests
 var execCommand = exec.Command
+
+// Use a variable for the .sops.yaml lookup so tests never touch the real repository
+var getSopsConfigPath = config.GetSopsConfigPath
+
+// Use a variable for the rules of --config to allow mocking in tests
+var sopsConfigSourceFor = config.SopsConfigSourceFor
+
+// Use a variable for the sops version lookup to allow mocking in tests
+var sopsVersion = version.ToolVersion
+
+// sopsFailed marks an error of a sops run, so simple-sops exits with the sops exit code
+func sopsFailed(err error) error {
+	return exitcode.Wrap(exitcode.Sops, err)
+}
+
+// sopsArgs appends the file and its type to the arguments of a sops command
+// Dotenv files are passed with explicit types, since sops only recognizes the
+// .env extension and not names like .env.production. Converted files, e.g.
+// TOML, are stored as JSON, and kubeconfig and talosconfig files are YAML.
+func sopsArgs(filePath string, args ...string) []string {
+	if inputType := sopsInputType(filePath); inputType != "" {
+		args = append(args, "--input-type", inputType, "--output-type", inputType)
+	}
+	return append(args, filePath)
+}
+
+// sopsInputType returns the type sopsArgs passes for a file, or "" to let sops detect it
+func sopsInputType(filePath string) string {
+	switch {
+	case dotenv.IsDotenvFile(filePath):
+		return "dotenv"
+	case convert.FormatOf(filePath) != "":
+		return "json"
+	case isYAMLFileName(filePath):
+		return "yaml"
+	}
+	return ""
+}
+
+// wildcardPolicy controls the recipients of the catch-all rule in .sops.yaml
+var wildcardPolicy config.WildcardPolicy
+
+// SetWildcardPolicy sets how the catch-all rule in .sops.yaml is maintained
+func SetWildcardPolicy(policy config.WildcardPolicy) {
+	wildcardPolicy = policy
+}
+
+// recipientPolicy sets the recipients every file must be encrypted to
+var recipientPolicy config.RecipientPolicy
+
+// SetRecipientPolicy sets the recipient policy of the application config
+// The policy of the repository applies as well.
+func SetRecipientPolicy(policy config.RecipientPolicy) {
+	recipientPolicy = policy
+}
+
+// encryptedRegexDefaults give new rules an encrypted_regex based on the file
+var encryptedRegexDefaults config.EncryptedRegexDefaults
+
+// SetEncryptedRegexDefaults sets the encrypted_regex defaults for new rules
+func SetEncryptedRegexDefaults(defaults config.EncryptedRegexDefaults) {
+	encryptedRegexDefaults = defaults
+}
+
+// newRuleEncryptedRegex returns the default encrypted_regex for a file without a rule
+// Existing rules keep their encrypted_regex, which may have been set with set-keys,
+// unless an override is set.
+func newRuleEncryptedRegex(sopsConfig *config.SopsConfig, fileName string) string {
+	if encryptedRegexOverride != "" {
+		return encryptedRegexOverride
+	}
+	if _, exists := config.GetCreationRule(sopsConfig, fileName); exists {
+		return ""
+	}
+
+	encryptedRegex := encryptedRegexDefaults.For(fileName)
+	if encryptedRegex != "" {
+		logging.Debug("Using default encrypted_regex %s for %s", encryptedRegex, fileName)
+	}
+	return encryptedRegex
+}
+
+// sopsConfigFor returns the .sops.yaml to pass to sops for a file with the given effective rule
+// sops only reads a single .sops.yaml, so if nested .sops.yaml files
+// contribute to the rule, one with only the merged rule is written to a
+// temporary directory. The returned function removes it again.
+func sopsConfigFor(configPath string, effective config.EffectiveRule) (string, func(), error) {
+	if !effective.Nested(configPath) {
+		sourcePath, cleanup, err := sourceConfigFile(configPath)
+		if err != nil || sourcePath == "" {
+			return configPath, cleanup, err
+		}
+		return sourcePath, cleanup, nil
+	}
+
+	tempDir, err := keymgmt.CreateTempDir()
+	if err != nil {
+		return "", nil, err
+	}
+	mergedPath := filepath.Join(tempDir, ".sops.yaml")
+	if err := config.SaveSopsConfig(mergedPath, effective.MergedSopsConfig()); err != nil {
+		keymgmt.RemoveSecurely(tempDir)
+		return "", nil, err
+	}
+
+	logging.Debug("Using the rule merged from %d .sops.yaml file(s)", len(effective.Sources))
+	return mergedPath, func() { keymgmt.RemoveSecurely(tempDir) }, nil
+}
+
+// sourceConfigFile writes the rules of --config or --config-ref for sops
+// sops reads .sops.yaml itself and would otherwise apply the rules of the
+// working tree instead of the ones simple-sops planned with. The copy is
+// written next to .sops.yaml, so sops matches paths relative to the same
+// directory. Returns an empty path if the rules come from the working tree.
+func sourceConfigFile(configPath string) (string, func(), error) {
+	data, ok := sopsConfigSourceFor(configPath)
+	if !ok {
+		return "", func() {}, nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(configPath), ".sops.yaml.source-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write the rules for sops: %w", err)
+	}
+	path := file.Name()
+	keymgmt.RemoveOnExit(path)
+	cleanup := func() {
+		os.Remove(path)
+		keymgmt.KeepOnExit(path)
+	}
+
+	// CreateTemp creates the file with mode 0600
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write the rules for sops: %w", err)
+	}
+	return path, cleanup, nil
+}
+
+// encryptConfigArgs returns the --config arguments and recipients for encrypting a file
+// Rules of .sops.yaml files in subdirectories add their recipients and
+// settings, like encrypted_regex, so sops gets the merged rule. Otherwise
+// they are the ones of sourceConfigArgs. The returned function removes the
+// written config again.
+func encryptConfigArgs(configPath string, filePath string, recipients []string) ([]string, []string, func(), error) {
+	effective, _, err := config.ResolveRule(configPath, filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load SOPS config: %w", err)
+	}
+	if !effective.Nested(configPath) {
+		configArgs, cleanup, err := sourceConfigArgs(configPath)
+		return configArgs, recipients, cleanup, err
+	}
+
+	sopsConfigPath, cleanup, err := sopsConfigFor(configPath, effective)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	recipients = config.UniqueRecipients(append(append([]string{}, recipients...), effective.Rule.Age...))
+	return []string{"--config", sopsConfigPath}, recipients, cleanup, nil
+}
+
+// sourceConfigArgs returns the --config arguments for sops if the rules come from a source
+// The returned function removes the copy of the rules again.
+func sourceConfigArgs(configPath string) ([]string, func(), error) {
+	path, cleanup, err := sourceConfigFile(configPath)
+	if err != nil || path == "" {
+		return nil, cleanup, err
+	}
+	return []string{"--config", path}, cleanup, nil
+}
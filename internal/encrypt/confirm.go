@@ -0,0 +1,96 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// ANSI colors of added and removed lines
+const (
+	colorAdded   = "\033[32m"
+	colorRemoved = "\033[31m"
+	colorReset   = "\033[0m"
+)
+
+// confirmConfigChanges makes changes to .sops.yaml wait for confirmation
+var confirmConfigChanges bool
+
+// SetConfirmConfigChanges enables asking before .sops.yaml is changed
+// Commands enable it unless --yes is given. Without a terminal to answer
+// the question, the change is declined.
+func SetConfirmConfigChanges(enabled bool) {
+	confirmConfigChanges = enabled
+}
+
+// ConfirmConfigChange shows the diff of a change to .sops.yaml and asks whether to save it
+// It returns an error if the change is declined. Nothing is asked if
+// confirmation is disabled, in dry-run mode, or if the change keeps the rules.
+func ConfirmConfigChange(configPath string, fn func(*config.SopsConfig) error) error {
+	if !confirmConfigChanges || dryRun {
+		return nil
+	}
+
+	rules := config.NewRuleSet(configPath)
+	current, err := rules.Load()
+	if err != nil {
+		return err
+	}
+	before, err := yaml.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+
+	changed, err := rules.Preview(fn)
+	if err != nil {
+		return err
+	}
+	after, err := yaml.Marshal(changed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SOPS config: %w", err)
+	}
+
+	diff := config.DiffLines(string(before), string(after))
+	if diff == "" {
+		return nil
+	}
+
+	// In porcelain mode stdout only carries records, the diff goes with the prompt
+	out := os.Stdout
+	if logging.IsPorcelainEnabled {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Changes to %s:\n", configPath)
+	fmt.Fprint(out, colorDiff(config.TrimDiff(diff, diffContext), isTerminal(out) && os.Getenv("NO_COLOR") == ""))
+	if !logging.Confirm(fmt.Sprintf("Save these changes to %s?", configPath)) {
+		return fmt.Errorf("changes to %s declined, pass --yes to save them without asking", configPath)
+	}
+	return nil
+}
+
+// colorDiff colors added lines green and removed lines red
+func colorDiff(diff string, enabled bool) string {
+	if !enabled {
+		return diff
+	}
+
+	var colored strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			colored.WriteString(colorAdded + strings.TrimSuffix(line, "\n") + colorReset + "\n")
+		case strings.HasPrefix(line, "-"):
+			colored.WriteString(colorRemoved + strings.TrimSuffix(line, "\n") + colorReset + "\n")
+		default:
+			colored.WriteString(line)
+		}
+	}
+	return colored.String()
+}
@@ -0,0 +1,54 @@
+package encrypt
+
+import (
+	"os"
+	"simple-sops/pkg/logging"
+	"strings"
+	"testing"
+)
+
+func TestEncryptFileConfirmConfigChange(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	defer SetConfirmConfigChanges(false)
+
+	mockExecOutput = []byte("Encryption successful")
+	mockExecError = nil
+	SetConfirmConfigChanges(true)
+
+	// A declined change leaves .sops.yaml and the file alone
+	restore := logging.MockConfirm(false)
+	err := EncryptFile(testFilePath, keyPath, configPath)
+	restore()
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("Expected the declined change to fail with a hint to --yes, got %v", err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be written after declining", configPath)
+	}
+	if lastExecCommand.cmd != "" {
+		t.Errorf("Expected sops not to run after declining, got %s %v", lastExecCommand.cmd, lastExecCommand.args)
+	}
+
+	// A confirmed change is saved
+	restore = logging.MockConfirm(true)
+	defer restore()
+	if err := EncryptFile(testFilePath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		t.Errorf("Expected %s to be written after confirming: %v", configPath, err)
+	}
+}
+
+func TestColorDiff(t *testing.T) {
+	diff := " creation_rules:\n-  age: age1old\n+  age: age1new\n"
+	if got := colorDiff(diff, false); got != diff {
+		t.Errorf("Expected the diff unchanged without color, got %q", got)
+	}
+
+	want := " creation_rules:\n" + colorRemoved + "-  age: age1old" + colorReset + "\n" + colorAdded + "+  age: age1new" + colorReset + "\n"
+	if got := colorDiff(diff, true); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,211 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/convert"
+	"simple-sops/internal/dotenv"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// prepareFile rewrites a plaintext file in a form sops can encrypt
+// The returned function writes the original plaintext back, so a failed
+// encryption leaves the file as the user wrote it.
+func prepareFile(filePath string) (func(), error) {
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		// Nothing is rewritten, e.g. for planned copies in dry-run mode
+		original = nil
+	}
+	restore := func() {
+		if original == nil {
+			return
+		}
+		if data, err := os.ReadFile(filePath); err == nil && bytes.Equal(data, original) {
+			return
+		}
+		if err := os.WriteFile(filePath, original, 0600); err != nil {
+			logging.Error("Failed to restore %s: %v", filePath, err)
+		}
+	}
+
+	if err := normalizeDotenvFile(filePath); err != nil {
+		restore()
+		return nil, err
+	}
+	if err := convertFile(filePath); err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+// convertFile rewrites a plaintext file sops can't read, e.g. TOML, as JSON
+func convertFile(filePath string) error {
+	format := convert.FormatOf(filePath)
+	if format == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(sourcePath(filePath))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Already converted, e.g. by an interrupted encryption
+	if convert.IsConverted(data) {
+		return nil
+	}
+
+	converted, err := convert.ToJSON(format, data)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", filePath, err)
+	}
+
+	if dryRun {
+		PrintDryRun("Would convert %s from %s to JSON", filePath, format)
+		return nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := os.WriteFile(filePath, converted, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+
+	logging.Debug("Converted %s from %s to JSON", filePath, format)
+	return nil
+}
+
+// formatDecrypted converts the decrypted output of sops for a file
+// Dotenv output is turned into a regular dotenv file with quoted values and
+// converted files are turned back into their original format. Other formats
+// are returned unchanged.
+func formatDecrypted(filePath string, data []byte) ([]byte, error) {
+	if convert.FormatOf(filePath) != "" && convert.IsConverted(data) {
+		original, _, err := convert.FromJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s back: %w", filePath, err)
+		}
+		return original, nil
+	}
+
+	if dotenv.IsDotenvFile(filePath) {
+		return dotenv.Marshal(dotenv.ParseSops(data)), nil
+	}
+	return data, nil
+}
+
+// formatDecryptedFile converts a file decrypted in-place by sops
+func formatDecryptedFile(filePath string) error {
+	if convert.FormatOf(filePath) == "" && !dotenv.IsDotenvFile(filePath) {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	formatted, err := formatDecrypted(filePath, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, formatted, info.Mode().Perm())
+}
+
+// editConvertedFile edits a converted file in its original format
+// sops would open the JSON form, so the file is decrypted to a private
+// temporary file, opened in $EDITOR and encrypted again when it changed.
+func editConvertedFile(filePath string, keyFile string) error {
+	plaintext, err := DecryptToBytes(filePath, keyFile)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := keymgmt.CreateTempDir()
+	if err != nil {
+		return err
+	}
+	defer keymgmt.RemoveSecurely(tempDir)
+
+	// Keep the extension so the editor highlights the original format
+	tempPath := filepath.Join(tempDir, filepath.Base(filePath))
+	if err := os.WriteFile(tempPath, plaintext, 0600); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := execCommand(editor[0], append(editor[1:], tempPath)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error while editing the file: %w", err)
+	}
+
+	edited, err := os.ReadFile(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if bytes.Equal(edited, plaintext) {
+		logging.Info("File unchanged, nothing to save.")
+		return nil
+	}
+
+	converted, err := convert.ToJSON(convert.FormatOf(filePath), edited)
+	if err != nil {
+		return fmt.Errorf("failed to convert edited file: %w", err)
+	}
+
+	// The rules of --config apply, not the .sops.yaml of the working tree
+	var configArgs []string
+	if configPath, err := getSopsConfigPath(); err == nil {
+		args, cleanup, err := sourceConfigArgs(configPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		configArgs = args
+	}
+
+	// Restore the encrypted file if encryption fails, so no plaintext is left behind
+	encrypted, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := os.WriteFile(filePath, converted, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+
+	cmd = execCommand("sops", sopsArgs(filePath, append(configArgs, "--encrypt", "--in-place")...)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if restoreErr := os.WriteFile(filePath, encrypted, info.Mode().Perm()); restoreErr != nil {
+			logging.Error("Failed to restore %s: %v", filePath, restoreErr)
+		}
+		return sopsFailed(fmt.Errorf("failed to encrypt file: %s\n%s", err, string(output)))
+	}
+
+	logging.Success("File edited and saved successfully.")
+	logging.Record(filePath, "edit", logging.ResultOK)
+	return nil
+}
@@ -0,0 +1,87 @@
+package encrypt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/convert"
+	"strings"
+	"testing"
+)
+
+func TestEncryptConvertedFile(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	tomlPath := filepath.Join(filepath.Dir(testFilePath), "config.toml")
+	if err := os.WriteFile(tomlPath, []byte("[database]\npassword = \"s3cret\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	if err := EncryptFile(tomlPath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+
+	// sops must read the file as JSON
+	args := strings.Join(lastExecCommand.args, " ")
+	if !strings.Contains(args, "--input-type json --output-type json "+tomlPath) {
+		t.Errorf("Expected JSON types for TOML file, got: %s", args)
+	}
+
+	data, err := os.ReadFile(tomlPath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !convert.IsConverted(data) {
+		t.Errorf("Expected file to be converted to JSON before encryption, got:\n%s", data)
+	}
+}
+
+func TestEncryptConvertedFileFailure(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	original := "[database]\npassword = \"s3cret\"\n"
+	tomlPath := filepath.Join(filepath.Dir(testFilePath), "config.toml")
+	if err := os.WriteFile(tomlPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if err := EncryptFile(tomlPath, keyPath, configPath); err == nil {
+		t.Fatal("Expected error when sops fails, got nil")
+	}
+
+	// A failed encryption leaves the TOML file as it was
+	data, err := os.ReadFile(tomlPath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected the original TOML file to be restored, got:\n%s", data)
+	}
+}
+
+func TestFormatDecryptedConvertedFile(t *testing.T) {
+	converted, err := convert.ToJSON(convert.FormatTOML, []byte("[database]\npassword = \"s3cret\"\n"))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+
+	// sops prints the decrypted JSON form of the file
+	plaintext, err := formatDecrypted("config.toml", converted)
+	if err != nil {
+		t.Fatalf("formatDecrypted failed: %v", err)
+	}
+	if string(plaintext) != "[database]\npassword = \"s3cret\"\n" {
+		t.Errorf("Expected TOML output, got:\n%s", plaintext)
+	}
+
+	// Files that were never converted are returned unchanged
+	plain := []byte("key = \"value\"\n")
+	if output, err := formatDecrypted("config.toml", plain); err != nil || string(output) != string(plain) {
+		t.Errorf("Expected unconverted file unchanged, got %q (%v)", output, err)
+	}
+}
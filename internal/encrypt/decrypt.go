@@ -1,10 +1,13 @@
 package encrypt
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
+	"simple-sops/internal/convert"
 	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/timing"
 	"simple-sops/pkg/logging"
 )
 
@@ -27,28 +30,53 @@ func DecryptFile(filePath string, keyFile string, mode DecryptionMode) error {
 
 	// Set up the command
 	var cmd *exec.Cmd
+	var stdout bytes.Buffer
 	if mode == DecryptModeStdout {
 		logging.Debug("Decrypting %s to stdout...", filePath)
-		cmd = execCommand("sops", "--decrypt", filePath)
-		cmd.Stdout = os.Stdout
+		cmd = execCommand("sops", sopsArgs(filePath, "--decrypt")...)
+		cmd.Stdout = &stdout
 	} else {
 		logging.Info("Decrypting %s in-place...", filePath)
-		cmd = execCommand("sops", "--decrypt", "--in-place", filePath)
+		cmd = execCommand("sops", sopsArgs(filePath, "--decrypt", "--in-place")...)
 	}
 
 	// Set the SOPS_AGE_KEY_FILE environment variable
 	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
 	cmd.Stderr = os.Stderr
 
+	// Only in-place decryption touches the file
+	touchedFile := ""
+	if mode == DecryptModeInPlace {
+		touchedFile = filePath
+	}
+	if skipCommand(cmd, touchedFile) {
+		return nil
+	}
+
 	// Run the command
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to decrypt file: %w", err)
+	stopTiming := timing.Track(filePath, timing.SopsExec)
+	err := cmd.Run()
+	stopTiming()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to decrypt file: %w", err))
 	}
 
-	if mode == DecryptModeInPlace {
-		logging.Success("File decrypted successfully: %s", filePath)
+	if mode == DecryptModeStdout {
+		formatted, err := formatDecrypted(filePath, stdout.Bytes())
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(formatted)
+		return nil
 	}
 
+	recordDecrypted(filePath, "")
+	if err := formatDecryptedFile(filePath); err != nil {
+		return fmt.Errorf("failed to format decrypted file: %w", err)
+	}
+	logging.Success("File decrypted successfully: %s", filePath)
+	logging.Record(filePath, "decrypt", logging.ResultOK)
+
 	return nil
 }
 
@@ -90,15 +118,9 @@ func DecryptFiles(filePaths []string, keyFile string, useStdout bool, alwaysUseO
 	}
 
 	// Process each file
-	var decryptErr error
-	for _, filePath := range filePaths {
-		if err := DecryptFile(filePath, keyPath, mode); err != nil {
-			logging.Error("Failed to decrypt %s: %v", filePath, err)
-			decryptErr = err
-		}
-	}
-
-	return decryptErr
+	return forEachFile(filePaths, 1, "decrypt", func(filePath string) error {
+		return DecryptFile(filePath, keyPath, mode)
+	})
 }
 
 // EditFile opens an encrypted file for editing
@@ -119,20 +141,32 @@ func EditFile(filePath string, keyFile string, alwaysUseOnePassword bool) error
 		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
 	}
 
+	// Warn others who edit the file at the same time
+	release, err := acquireEditLock(filePath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Edit the file using SOPS
 	logging.Info("Opening %s for editing...", filePath)
+	if convert.FormatOf(filePath) != "" {
+		return editConvertedFile(filePath, keyPath)
+	}
 
-	cmd := execCommand("sops", filePath)
+	cmd := execCommand("sops", sopsArgs(filePath)...)
 	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyPath))
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error while editing the file: %w", err)
+		return sopsFailed(fmt.Errorf("error while editing the file: %w", err))
 	}
 
 	logging.Success("File edited and saved successfully.")
+	logging.Record(filePath, "edit", logging.ResultOK)
+	lintEditedFile(filePath, keyPath)
 	return nil
 }
 
@@ -144,26 +178,43 @@ func DecryptToFile(inputPath string, outputPath string, keyFile string) error {
 	}
 
 	// Set up the command
-	cmd := execCommand("sops", "--decrypt", inputPath)
+	cmd := execCommand("sops", sopsArgs(inputPath, "--decrypt")...)
+	if skipCommand(cmd, outputPath) {
+		return nil
+	}
 
-	// Create or truncate the output file
-	outputFile, err := os.Create(outputPath)
+	// Create or truncate the output file, readable only by the owner
+	outputFile, err := CreatePlaintextFile(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer outputFile.Close()
+	recordDecrypted(outputPath, inputPath)
 
-	cmd.Stdout = outputFile
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = os.Stderr
 
 	// Set the SOPS_AGE_KEY_FILE environment variable
 	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
 
 	// Run the command
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to decrypt file: %w", err)
+	stopTiming := timing.Track(inputPath, timing.SopsExec)
+	err = cmd.Run()
+	stopTiming()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to decrypt file: %w", err))
+	}
+
+	formatted, err := formatDecrypted(inputPath, stdout.Bytes())
+	if err != nil {
+		return err
+	}
+	if _, err := outputFile.Write(formatted); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
 	}
 
 	logging.Success("File decrypted successfully to: %s", outputPath)
+	logging.Record(inputPath, "decrypt", logging.ResultOK)
 	return nil
 }
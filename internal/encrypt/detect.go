@@ -0,0 +1,98 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the predefined encryption patterns
+const (
+	PatternAllValues  = "All values"
+	PatternKubernetes = "Kubernetes"
+	PatternTalos      = "Talos configuration"
+	PatternKubeconfig = "Kubeconfig"
+	PatternCommon     = "Common sensitive data"
+)
+
+// DetectedPattern is a predefined encryption pattern proposed for a file
+type DetectedPattern struct {
+	// Name is the name of the predefined pattern
+	Name string
+	// Reason describes what was detected
+	Reason string
+}
+
+// DetectEncryptionPattern sniffs a file and proposes a matching predefined pattern
+// Kubernetes manifests, kubeconfigs and Talos configs are recognized by their documents,
+// dotenv files by their extension. Returns false if nothing was recognized.
+func DetectEncryptionPattern(filePath string) (DetectedPattern, bool) {
+	if strings.EqualFold(filepath.Ext(filePath), ".env") {
+		return DetectedPattern{Name: PatternAllValues, Reason: "dotenv file"}, true
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return DetectedPattern{}, false
+	}
+
+	var kinds []string
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if !errors.Is(err, io.EOF) {
+				// Not YAML or JSON, or a document that isn't a mapping
+				return DetectedPattern{}, false
+			}
+			break
+		}
+		if doc == nil {
+			continue
+		}
+
+		if isTalosDocument(doc) {
+			return DetectedPattern{Name: PatternTalos, Reason: "Talos configuration"}, true
+		}
+
+		apiVersion, _ := doc["apiVersion"].(string)
+		kind, _ := doc["kind"].(string)
+		if _, hasUsers := doc["users"]; kind == "Config" && hasUsers {
+			return DetectedPattern{Name: PatternKubeconfig, Reason: "kubeconfig"}, true
+		}
+		if apiVersion != "" && kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	if len(kinds) == 0 {
+		return DetectedPattern{}, false
+	}
+
+	reason := "Kubernetes " + strings.Join(kinds, ", ")
+	return DetectedPattern{Name: PatternKubernetes, Reason: reason}, true
+}
+
+// isTalosDocument recognizes Talos machine configs, talosconfig files and secrets bundles
+func isTalosDocument(doc map[string]interface{}) bool {
+	_, hasMachine := doc["machine"]
+	_, hasCluster := doc["cluster"]
+	if version, _ := doc["version"].(string); version == "v1alpha1" && hasMachine && hasCluster {
+		return true
+	}
+
+	// talosctl gen secrets
+	if _, ok := doc["trustdinfo"]; ok {
+		return true
+	}
+
+	// talosconfig
+	_, hasContext := doc["context"]
+	_, hasContexts := doc["contexts"]
+	return hasContext && hasContexts
+}
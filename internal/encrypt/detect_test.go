@@ -0,0 +1,49 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectEncryptionPattern(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"secret.yaml", "apiVersion: v1\nkind: Secret\nstringData:\n  password: x\n", PatternKubernetes},
+		{"multi.yaml", "apiVersion: v1\nkind: ConfigMap\ndata: {}\n---\napiVersion: v1\nkind: Secret\ndata: {}\n", PatternKubernetes},
+		{"controlplane.yaml", "version: v1alpha1\nmachine:\n  type: controlplane\ncluster:\n  clusterName: demo\n", PatternTalos},
+		{"secrets.yaml", "cluster:\n  id: abc\ntrustdinfo:\n  token: x\n", PatternTalos},
+		{"talosconfig", "context: demo\ncontexts:\n  demo: {}\n", PatternTalos},
+		{"kubeconfig", "apiVersion: v1\nkind: Config\nclusters: []\nusers:\n- name: admin\n", PatternKubeconfig},
+		{"app.env", "TOKEN=x\n", PatternAllValues},
+		{"values.yaml", "replicas: 2\npassword: x\n", ""},
+		{"list.yaml", "- a\n- b\n", ""},
+		{"manifest.json", `{"apiVersion": "v1", "kind": "Secret", "data": {}}`, PatternKubernetes},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(tempDir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", tt.name, err)
+		}
+
+		detected, ok := DetectEncryptionPattern(path)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%s: expected no pattern, got %s", tt.name, detected.Name)
+			}
+			continue
+		}
+		if !ok || detected.Name != tt.expected {
+			t.Errorf("%s: expected %s, got %+v (%v)", tt.name, tt.expected, detected, ok)
+		}
+		if _, exists := PredefinedEncryptionPatterns()[detected.Name]; !exists {
+			t.Errorf("%s: %s is not a predefined pattern", tt.name, detected.Name)
+		}
+	}
+}
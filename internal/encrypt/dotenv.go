@@ -0,0 +1,51 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/dotenv"
+	"simple-sops/pkg/logging"
+)
+
+// normalizeDotenvFile rewrites a plaintext dotenv file in the form sops reads
+// sops takes every line literally, so export prefixes, quotes and values
+// spanning several lines would end up in the encrypted keys and values.
+func normalizeDotenvFile(filePath string) error {
+	if !dotenv.IsDotenvFile(filePath) || config.IsFileEncrypted(sourcePath(filePath)) {
+		return nil
+	}
+
+	data, err := os.ReadFile(sourcePath(filePath))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	entries, err := dotenv.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	// Only a missing final newline isn't worth rewriting the file
+	normalized := dotenv.MarshalSops(entries)
+	if bytes.Equal(bytes.TrimRight(normalized, "\n"), bytes.TrimRight(data, "\n")) {
+		return nil
+	}
+
+	if dryRun {
+		PrintDryRun("Would rewrite %s in the dotenv form sops reads", filePath)
+		return nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := os.WriteFile(filePath, normalized, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+
+	logging.Debug("Rewrote %s in the dotenv form sops reads", filePath)
+	return nil
+}
@@ -0,0 +1,124 @@
+package encrypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+var (
+	// dryRun prints the planned changes instead of making them
+	dryRun bool
+
+	// Use a variable for the dry-run output to allow capturing it in tests
+	dryRunOutput io.Writer = os.Stdout
+)
+
+// SetDryRun enables or disables dry-run mode
+// In dry-run mode no sops command is executed and no file is written. The
+// commands, .sops.yaml changes and touched files are printed instead.
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+}
+
+// IsDryRun reports whether dry-run mode is enabled
+func IsDryRun() bool {
+	return dryRun
+}
+
+// PrintDryRun prints a line of the dry-run plan
+// The plan is printed even in quiet mode, since it is the requested output.
+func PrintDryRun(format string, args ...interface{}) {
+	fmt.Fprintf(dryRunOutput, "[dry-run] "+format+"\n", args...)
+}
+
+// PrintConfigPreview prints the changes that saving the config would make
+func PrintConfigPreview(configPath string, sopsConfig *config.SopsConfig) error {
+	diff, err := config.PreviewSopsConfig(configPath, sopsConfig)
+	if err != nil {
+		return err
+	}
+
+	if diff == "" {
+		PrintDryRun("No changes to %s", configPath)
+		return nil
+	}
+
+	PrintDryRun("Would update %s:", configPath)
+	fmt.Fprint(dryRunOutput, diff)
+	return nil
+}
+
+// updateSopsConfig applies a change to the config, or prints it in dry-run mode
+// When confirmation is enabled, the change is only saved once confirmed.
+func updateSopsConfig(configPath string, fn func(*config.SopsConfig) error) (*config.SopsConfig, error) {
+	rules := config.NewRuleSet(configPath)
+	if !dryRun {
+		if err := ConfirmConfigChange(configPath, fn); err != nil {
+			return nil, err
+		}
+		return rules.Update(fn)
+	}
+
+	sopsConfig, err := rules.Preview(fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := PrintConfigPreview(configPath, sopsConfig); err != nil {
+		return nil, err
+	}
+	return sopsConfig, nil
+}
+
+// skipCommand prints the command and the file it would touch in dry-run mode
+// It returns true if the command must not be executed.
+func skipCommand(cmd *exec.Cmd, touchedFile string) bool {
+	if !dryRun {
+		return false
+	}
+
+	PrintDryRun("Would run: %s", formatCommand(cmd.Args))
+	if touchedFile != "" {
+		PrintDryRun("Would modify: %s", touchedFile)
+	}
+	logging.Debug("Skipped command in dry-run mode")
+	return true
+}
+
+// formatCommand formats command arguments so they can be pasted into a shell
+func formatCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+			quoted[i] = arg
+		} else {
+			quoted[i] = ShellQuote(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// printEncryptionPreview prints which values of a file would be encrypted in dry-run mode
+func printEncryptionPreview(filePath string, sopsConfig *config.SopsConfig, fileName string) {
+	if !dryRun {
+		return
+	}
+
+	rule, found := config.GetCreationRule(sopsConfig, fileName)
+	if !found || rule.EncryptedRegex == "" {
+		return
+	}
+
+	previews, err := PreviewEncryption(sourcePath(filePath), rule.EncryptedRegex)
+	if err != nil {
+		logging.Debug("No preview for %s: %v", filePath, err)
+		return
+	}
+
+	PrintDryRun("Values of %s matching %s:", filePath, rule.EncryptedRegex)
+	fmt.Fprint(dryRunOutput, FormatPreview(previews))
+}
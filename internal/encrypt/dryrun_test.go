@@ -0,0 +1,138 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRunEncrypt(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var output bytes.Buffer
+	dryRunOutput = &output
+	SetDryRun(true)
+
+	// sops fails if it is executed
+	mockExecError = errors.New("sops must not run")
+
+	if err := EncryptFiles([]string{testFilePath}, keyPath, false); err != nil {
+		t.Fatalf("EncryptFiles failed: %v", err)
+	}
+
+	// Nothing is written
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created in dry-run mode", configPath)
+	}
+	content, err := os.ReadFile(testFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if string(content) != "TEST=value" {
+		t.Errorf("Expected test file to be unchanged, got %q", content)
+	}
+
+	// The plan lists the config changes, the command and the touched file
+	plan := output.String()
+	for _, expected := range []string{
+		"Would update " + configPath,
+		"+    - path_regex: (^|/)test\\.env$",
+		"+      age: age123456789abcdef",
+		"--encrypt --age age123456789abcdef --in-place --input-type dotenv --output-type dotenv " + testFilePath,
+		"Would modify: " + testFilePath,
+	} {
+		if !strings.Contains(plan, expected) {
+			t.Errorf("Expected dry-run output to contain %q, got:\n%s", expected, plan)
+		}
+	}
+}
+
+func TestDryRunDecryptStdout(t *testing.T) {
+	keyPath, testFilePath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var output bytes.Buffer
+	dryRunOutput = &output
+	SetDryRun(true)
+
+	if err := DecryptFile(testFilePath, keyPath, DecryptModeStdout); err != nil {
+		t.Fatalf("DecryptFile failed: %v", err)
+	}
+
+	// Decrypting to stdout doesn't touch the file
+	plan := output.String()
+	if !strings.Contains(plan, "--decrypt --input-type dotenv --output-type dotenv "+testFilePath) {
+		t.Errorf("Expected the sops command in the dry-run output, got:\n%s", plan)
+	}
+	if strings.Contains(plan, "Would modify") {
+		t.Errorf("Expected no modified files, got:\n%s", plan)
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	got := formatCommand([]string{"sops", "--age", "age1,age2", "--in-place", "my file.env", "it's"})
+	expected := `sops --age age1,age2 --in-place 'my file.env' 'it'\''s'`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestDryRunEncryptToOutput(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var output bytes.Buffer
+	dryRunOutput = &output
+	SetDryRun(true)
+	mockExecError = errors.New("sops must not run")
+
+	outputPath := filepath.Join(filepath.Dir(testFilePath), "test.enc.env")
+	if err := CopyToOutput(testFilePath, outputPath); err != nil {
+		t.Fatalf("CopyToOutput failed: %v", err)
+	}
+	if err := EncryptFiles([]string{outputPath}, keyPath, false); err != nil {
+		t.Fatalf("EncryptFiles failed: %v", err)
+	}
+
+	// The copy is only planned, and the rule is for the encrypted name
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created in dry-run mode", outputPath)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created in dry-run mode", configPath)
+	}
+
+	plan := output.String()
+	for _, expected := range []string{
+		"Would copy " + testFilePath + " to " + outputPath,
+		"+    - path_regex: (^|/)test\\.enc\\.env$",
+		"Would modify: " + outputPath,
+	} {
+		if !strings.Contains(plan, expected) {
+			t.Errorf("Expected dry-run output to contain %q, got:\n%s", expected, plan)
+		}
+	}
+}
+
+func TestDryRunRotateRecipients(t *testing.T) {
+	keyPath, testFilePath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var output bytes.Buffer
+	dryRunOutput = &output
+	SetDryRun(true)
+	mockExecError = errors.New("sops must not run")
+
+	if err := RotateRecipients(testFilePath, keyPath, []string{"age1old"}, []string{"age1new"}, []string{"arn:aws:kms:eu-west-1:1:key/a"}); err != nil {
+		t.Fatalf("RotateRecipients failed: %v", err)
+	}
+
+	expected := "sops --rotate --in-place --rm-age age1old --add-age age1new --add-kms arn:aws:kms:eu-west-1:1:key/a --input-type dotenv --output-type dotenv " + testFilePath
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("Expected dry-run output to contain %q, got:\n%s", expected, output.String())
+	}
+}
@@ -0,0 +1,149 @@
+package encrypt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EditLockSuffix is appended to a file's name to name its lock
+const EditLockSuffix = ".lock"
+
+// staleEditLockAge is the age after which a lock is ignored, e.g. when an
+// editor on another host crashed
+const staleEditLockAge = 24 * time.Hour
+
+// editLocking makes edit record a lock next to the file
+var editLocking bool
+
+// SetEditLocking enables the advisory lock of edit
+func SetEditLocking(enabled bool) {
+	editLocking = enabled
+}
+
+// EditLock records who is editing an encrypted file
+// sops re-encrypts the whole file when saving, so concurrent edits can't be
+// merged and the last one wins. The lock only warns, it doesn't prevent edits.
+type EditLock struct {
+	User  string    `yaml:"user"`
+	Host  string    `yaml:"host"`
+	PID   int       `yaml:"pid"`
+	Since time.Time `yaml:"since"`
+}
+
+// EditLockPath returns the path of the lock of a file
+func EditLockPath(filePath string) string {
+	return filePath + EditLockSuffix
+}
+
+// ReadEditLock reads the lock of a file
+// It returns nil if the file isn't locked.
+func ReadEditLock(filePath string) (*EditLock, error) {
+	data, err := os.ReadFile(EditLockPath(filePath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock of %s: %w", filePath, err)
+	}
+
+	var lock EditLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock of %s: %w", filePath, err)
+	}
+	return &lock, nil
+}
+
+// Stale checks if the lock is left over from an edit that ended
+// A lock of this host is stale once its process is gone, a lock of another
+// host once it is older than a day.
+func (l *EditLock) Stale(now time.Time) bool {
+	if host, err := os.Hostname(); err == nil && host == l.Host {
+		return !keymgmt.ProcessAlive(l.PID)
+	}
+	return now.Sub(l.Since) > staleEditLockAge
+}
+
+// String describes the holder of the lock
+func (l *EditLock) String() string {
+	return fmt.Sprintf("%s@%s (pid %d) since %s", l.User, l.Host, l.PID, l.Since.Local().Format(time.DateTime))
+}
+
+// heldBy checks if two locks were recorded by the same edit
+func (l *EditLock) heldBy(other *EditLock) bool {
+	return l.User == other.User && l.Host == other.Host && l.PID == other.PID && l.Since.Equal(other.Since)
+}
+
+// newEditLock returns a lock held by this process
+func newEditLock() *EditLock {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	host, _ := os.Hostname()
+	return &EditLock{User: name, Host: host, PID: os.Getpid(), Since: time.Now().UTC().Truncate(time.Second)}
+}
+
+// acquireEditLock locks a file for editing and returns a function releasing it
+// If someone else holds the lock, it warns and asks whether to edit anyway.
+// Without locking enabled, nothing is recorded.
+func acquireEditLock(filePath string) (func(), error) {
+	if !editLocking {
+		return func() {}, nil
+	}
+
+	lock := newEditLock()
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize lock: %w", err)
+	}
+	lockPath := EditLockPath(filePath)
+
+	file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		existing, err := ReadEditLock(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && !existing.Stale(time.Now()) {
+			logging.Warn("%s is being edited by %s. Saving both edits will lose one of them, since sops re-encrypts the whole file.", filePath, existing)
+			if !logging.Confirm("Edit it anyway?") {
+				return nil, fmt.Errorf("%s is locked by %s", filePath, existing)
+			}
+		} else if existing != nil {
+			logging.Debug("Replacing the stale lock of %s held by %s", filePath, existing)
+		}
+		if err := os.WriteFile(lockPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", filePath, err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", filePath, err)
+	} else {
+		_, err := file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(lockPath)
+			return nil, fmt.Errorf("failed to lock %s: %w", filePath, err)
+		}
+	}
+
+	// Only remove the lock if nobody took it over in the meantime
+	return func() {
+		current, err := ReadEditLock(filePath)
+		if err != nil || current == nil || !current.heldBy(lock) {
+			return
+		}
+		if err := os.Remove(lockPath); err != nil {
+			logging.Warn("Failed to remove the lock of %s: %v", filePath, err)
+		}
+	}, nil
+}
@@ -0,0 +1,102 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"simple-sops/pkg/logging"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireEditLock(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	SetEditLocking(true)
+	defer SetEditLocking(false)
+
+	release, err := acquireEditLock(filePath)
+	if err != nil {
+		t.Fatalf("acquireEditLock() error = %v", err)
+	}
+	lock, err := ReadEditLock(filePath)
+	if err != nil || lock == nil {
+		t.Fatalf("ReadEditLock() = %v, %v, want the lock", lock, err)
+	}
+	if lock.PID != os.Getpid() {
+		t.Errorf("lock PID = %d, want %d", lock.PID, os.Getpid())
+	}
+
+	release()
+	if _, err := os.Stat(EditLockPath(filePath)); !os.IsNotExist(err) {
+		t.Errorf("lock still exists after release: %v", err)
+	}
+}
+
+func TestAcquireEditLockHeld(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	SetEditLocking(true)
+	defer SetEditLocking(false)
+
+	// A lock of another host that isn't stale yet
+	held := "user: alice\nhost: other-host.invalid\npid: 1\nsince: " + time.Now().UTC().Format(time.RFC3339) + "\n"
+	if err := os.WriteFile(EditLockPath(filePath), []byte(held), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restore := logging.MockConfirm(false)
+	_, err := acquireEditLock(filePath)
+	restore()
+	if err == nil || !strings.Contains(err.Error(), "alice@other-host.invalid") {
+		t.Fatalf("acquireEditLock() error = %v, want locked by alice", err)
+	}
+
+	// Editing anyway takes over the lock, which the other edit then keeps
+	restore = logging.MockConfirm(true)
+	release, err := acquireEditLock(filePath)
+	restore()
+	if err != nil {
+		t.Fatalf("acquireEditLock() error = %v", err)
+	}
+	if err := os.WriteFile(EditLockPath(filePath), []byte(held), 0644); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err := os.Stat(EditLockPath(filePath)); err != nil {
+		t.Errorf("release removed a lock held by someone else: %v", err)
+	}
+}
+
+func TestEditLockStale(t *testing.T) {
+	now := time.Now()
+	host, _ := os.Hostname()
+
+	tests := []struct {
+		name string
+		lock EditLock
+		want bool
+	}{
+		{"running here", EditLock{Host: host, PID: os.Getpid(), Since: now.Add(-48 * time.Hour)}, false},
+		{"recent elsewhere", EditLock{Host: "other-host.invalid", PID: 1, Since: now.Add(-time.Hour)}, false},
+		{"old elsewhere", EditLock{Host: "other-host.invalid", PID: 1, Since: now.Add(-25 * time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lock.Stale(now); got != tt.want {
+				t.Errorf("Stale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireEditLockDisabled(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+
+	release, err := acquireEditLock(filePath)
+	if err != nil {
+		t.Fatalf("acquireEditLock() error = %v", err)
+	}
+	release()
+	if _, err := os.Stat(EditLockPath(filePath)); !os.IsNotExist(err) {
+		t.Errorf("lock created with locking disabled: %v", err)
+	}
+}
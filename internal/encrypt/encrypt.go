@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"simple-sops/internal/config"
 	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/timing"
+	"simple-sops/internal/version"
 	"simple-sops/pkg/logging"
 	"strings"
 )
@@ -13,7 +15,7 @@ import (
 // EncryptFile encrypts a file using SOPS
 func EncryptFile(filePath string, keyFile string, configPath string) error {
 	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	if _, err := os.Stat(sourcePath(filePath)); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", filePath)
 	}
 
@@ -23,40 +25,80 @@ func EncryptFile(filePath string, keyFile string, configPath string) error {
 		return fmt.Errorf("failed to get public key: %w", err)
 	}
 
-	// Load or create SOPS config
-	sopsConfig, err := config.LoadSopsConfig(configPath)
+	// Check the recipient against the trust store
+	if err := checkRecipientsTrusted([]string{pubKey}); err != nil {
+		return err
+	}
+
+	// The recovery recipients of the policy can always decrypt the file
+	recipients, err := withRecoveryRecipients(configPath, []string{pubKey})
 	if err != nil {
-		return fmt.Errorf("failed to load SOPS config: %w", err)
+		return err
 	}
 
-	// Add or update rule for this file
-	fileName := filepath.Base(filePath)
-	if err := config.AddCreationRule(sopsConfig, fileName, pubKey, ""); err != nil {
-		return fmt.Errorf("failed to add rule to SOPS config: %w", err)
+	// Check the recipients against the policy
+	if err := checkRecipientPolicy(configPath, recipients); err != nil {
+		return err
 	}
 
-	// Save the updated config
-	if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
-		return fmt.Errorf("failed to save SOPS config: %w", err)
+	// Check for weak values before anything is changed
+	if err := lintFile(filePath, configPath); err != nil {
+		return err
 	}
 
+	// Add or update rule for this file
+	fileName := config.ConfigRelativePath(configPath, filePath)
+	stopTiming := timing.Track(filePath, timing.ConfigIO)
+	sopsConfig, err := updateSopsConfig(configPath, func(sopsConfig *config.SopsConfig) error {
+		encryptedRegex := newRuleEncryptedRegex(sopsConfig, fileName)
+		if err := config.AddCreationRuleWithMultipleKeys(sopsConfig, fileName, recipients, encryptedRegex, wildcardPolicy); err != nil {
+			return fmt.Errorf("failed to add rule to SOPS config: %w", err)
+		}
+		return nil
+	})
+	stopTiming()
+	if err != nil {
+		return fmt.Errorf("failed to update SOPS config: %w", err)
+	}
+	warnShadowedRule(sopsConfig, fileName)
+	printEncryptionPreview(filePath, sopsConfig, fileName)
+
 	// Encrypt the file
 	logging.Info("Encrypting %s...", filePath)
+	restore, err := prepareFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	configArgs, recipients, cleanup, err := encryptConfigArgs(configPath, filePath, recipients)
+	if err != nil {
+		restore()
+		return err
+	}
+	defer cleanup()
 
 	// Set the SOPS_AGE_KEY_FILE environment variable
-	cmd := execCommand("sops", "--encrypt", "--age", pubKey, "--in-place", filePath)
+	cmd := execCommand("sops", sopsArgs(filePath, append(configArgs, "--encrypt", "--age", strings.Join(recipients, ","), "--in-place")...)...)
 	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+	if skipCommand(cmd, filePath) {
+		return nil
+	}
 
+	stopTiming = timing.Track(filePath, timing.SopsExec)
 	output, err := cmd.CombinedOutput()
+	stopTiming()
 	if err != nil {
-		return fmt.Errorf("failed to encrypt file: %s\n%s", err, string(output))
+		restore()
+		return sopsFailed(fmt.Errorf("failed to encrypt file: %s\n%s", err, string(output)))
 	}
 
 	logging.Success("File encrypted successfully: %s", filePath)
+	logging.Record(filePath, "encrypt", logging.ResultOK)
 	return nil
 }
 
 // EncryptFilesWithMultipleKeys encrypts files with multiple keys
+// pubKeys are additional recipients that don't have a private key available
 func EncryptFilesWithMultipleKeys(filePaths []string, keyFiles []string, pubKeys []string,
 	alwaysUseOnePassword bool, opItems []keymgmt.OnePasswordItem,
 ) error {
@@ -64,57 +106,23 @@ func EncryptFilesWithMultipleKeys(filePaths []string, keyFiles []string, pubKeys
 		return fmt.Errorf("no files specified")
 	}
 
-	var keyPath string
-	var err error
-
-	// Create a temporary directory for the combined key
-	tempDir, err := os.MkdirTemp("", "simple-sops-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary directory: %w", err)
-	}
-	defer os.RemoveAll(tempDir)
-
-	// Set up the combined key file
-	combinedKeyPath := filepath.Join(tempDir, "combined-keys.txt")
-	combinedFile, err := os.Create(combinedKeyPath)
-	if err != nil {
-		return fmt.Errorf("failed to create combined key file: %w", err)
-	}
-	defer combinedFile.Close()
+	// Only public keys are needed to encrypt, so private keys are never
+	// combined on disk. They stay with their source for decryption.
+	var allPubKeys []string
 
-	// Track if we've added any keys
-	keysAdded := false
+	// Keep key material out of core dumps while it is in memory
+	keymgmt.DisableCoreDumps()
+	stopTiming := timing.Track("", timing.KeyFetch)
 
 	// First, add keys from 1Password if available
 	if len(opItems) > 0 {
 		logging.Debug("Getting keys from 1Password items...")
-		opKeyPath, opIsTemp, err := keymgmt.GetKeysFromOnePassword(opItems)
+		opPubKeys, err := keymgmt.GetPublicKeysFromOnePassword(opItems)
 		if err != nil {
 			logging.Error("Failed to get keys from 1Password: %v", err)
 		} else {
-			// Read 1Password key file and add to combined file
-			content, err := os.ReadFile(opKeyPath)
-			if err != nil {
-				logging.Error("Failed to read 1Password key file: %v", err)
-			} else {
-				if _, err := combinedFile.Write(content); err != nil {
-					logging.Error("Failed to write 1Password key to combined file: %v", err)
-				} else {
-					// Add newline if needed
-					if !strings.HasSuffix(string(content), "\n") {
-						combinedFile.WriteString("\n")
-					}
-					keysAdded = true
-					logging.Debug("Added keys from 1Password")
-				}
-			}
-
-			// Clean up temporary 1Password key file
-			if opIsTemp {
-				if tempDir := filepath.Dir(opKeyPath); strings.HasPrefix(filepath.Base(tempDir), "simple-sops-") {
-					os.RemoveAll(tempDir)
-				}
-			}
+			allPubKeys = append(allPubKeys, opPubKeys...)
+			logging.Debug("Added keys from 1Password")
 		}
 	}
 
@@ -122,154 +130,153 @@ func EncryptFilesWithMultipleKeys(filePaths []string, keyFiles []string, pubKeys
 	if len(keyFiles) > 0 {
 		logging.Debug("Adding keys from %d key files", len(keyFiles))
 		for _, kf := range keyFiles {
-			// Read the key file
-			expandedPath, err := keymgmt.ExpandPath(kf)
+			// Read the public keys, fetching the key from its source if needed
+			filePubKeys, err := keymgmt.GetPublicKeysFromKeySpec(kf)
 			if err != nil {
-				logging.Error("Failed to expand path %s: %v", kf, err)
+				logging.Error("Failed to get public keys from %s: %v", kf, err)
 				continue
 			}
 
-			content, err := os.ReadFile(expandedPath)
-			if err != nil {
-				logging.Error("Failed to read key file %s: %v", expandedPath, err)
-				continue
-			}
-
-			// Append to combined file
-			if _, err := combinedFile.Write(content); err != nil {
-				logging.Error("Failed to write key to combined file: %v", err)
-				continue
-			}
-
-			// Add newline if needed
-			if !strings.HasSuffix(string(content), "\n") {
-				combinedFile.WriteString("\n")
-			}
-
-			keysAdded = true
+			allPubKeys = append(allPubKeys, filePubKeys...)
 			logging.Debug("Added key from file: %s", kf)
 		}
 	}
 
 	// If no keys added yet and alwaysUseOnePassword is true, try to get default key
-	if !keysAdded && alwaysUseOnePassword {
+	if len(allPubKeys) == 0 && alwaysUseOnePassword {
 		logging.Debug("Attempting to get default key from 1Password")
-		defaultKeyPath, defaultIsTemp, err := keymgmt.EnsureAgeKey("", true, true)
+		defaultPubKeys, err := keymgmt.GetPublicKeysFromOnePassword([]keymgmt.OnePasswordItem{keymgmt.DefaultOnePasswordItem})
 		if err != nil {
 			return fmt.Errorf("failed to get any keys: %w", err)
 		}
 
-		// Read default key file and add to combined file
-		content, err := os.ReadFile(defaultKeyPath)
-		if err != nil {
-			logging.Error("Failed to read default key file: %v", err)
-		} else {
-			if _, err := combinedFile.Write(content); err != nil {
-				logging.Error("Failed to write default key to combined file: %v", err)
-			} else {
-				// Add newline if needed
-				if !strings.HasSuffix(string(content), "\n") {
-					combinedFile.WriteString("\n")
-				}
-				keysAdded = true
-				logging.Debug("Added default key")
-			}
-		}
-
-		// Clean up default key file if temporary
-		if defaultIsTemp {
-			if tempDir := filepath.Dir(defaultKeyPath); strings.HasPrefix(filepath.Base(tempDir), "simple-sops-") {
-				os.RemoveAll(tempDir)
-			}
-		}
+		allPubKeys = append(allPubKeys, defaultPubKeys...)
+		logging.Debug("Added default key")
 	}
 
 	// If still no keys, return error
-	if !keysAdded {
+	if len(allPubKeys) == 0 {
 		return fmt.Errorf("no valid keys found from any source")
 	}
+	logging.Debug("Collected %d public keys", len(allPubKeys))
+	stopTiming()
 
-	// Close the file to ensure all writes are flushed
-	combinedFile.Close()
-
-	// Set keyPath to the combined key file path
-	keyPath = combinedKeyPath
+	// Add explicitly provided recipients, e.g. from GitHub users. The same key
+	// may come from several sources, e.g. both a file and 1Password.
+	allPubKeys = config.UniqueRecipients(append(allPubKeys, pubKeys...))
 
-	// Get public keys from the combined key file
-	var allPubKeys []string
-	if len(pubKeys) > 0 {
-		// Public keys explicitly provided
-		allPubKeys = pubKeys
-	} else {
-		// Extract ALL public keys from the combined key file
-		extractedPubKeys, err := keymgmt.GetAllPublicKeysFromFile(keyPath)
-		if err != nil {
-			return fmt.Errorf("failed to get public keys: %w", err)
-		}
-		allPubKeys = extractedPubKeys
-		logging.Debug("Extracted %d public keys from combined key file", len(allPubKeys))
+	// Check the recipients against the trust store
+	if err := checkRecipientsTrusted(allPubKeys); err != nil {
+		return err
 	}
 
 	// Get the SOPS config path
-	configPath, err := config.GetSopsConfigPath()
+	configPath, err := getSopsConfigPath()
 	if err != nil {
 		return fmt.Errorf("failed to determine SOPS config path: %w", err)
 	}
 
+	// The recovery recipients of the policy can always decrypt the files
+	allPubKeys, err = withRecoveryRecipients(configPath, allPubKeys)
+	if err != nil {
+		return err
+	}
+
+	// Check the recipients against the policy
+	if err := checkRecipientPolicy(configPath, allPubKeys); err != nil {
+		return err
+	}
+
 	// Process each file
 	var encryptErr error
+	processed, succeeded := 0, 0
 	for _, filePath := range filePaths {
+		if failFast && encryptErr != nil {
+			break
+		}
+		processed++
+
 		// Check if file exists
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if _, err := os.Stat(sourcePath(filePath)); os.IsNotExist(err) {
 			logging.Error("File not found: %s", filePath)
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
 			encryptErr = err
 			continue
 		}
 
-		// Load or create SOPS config
-		sopsConfig, err := config.LoadSopsConfig(configPath)
-		if err != nil {
-			logging.Error("Failed to load SOPS config: %v", err)
+		// Check for weak values before anything is changed
+		if err := lintFile(filePath, configPath); err != nil {
+			logging.Error("%v", err)
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
 			encryptErr = err
 			continue
 		}
 
-		// Combine multiple public keys with commas
-		pubKeyStr := strings.Join(allPubKeys, ",")
-
 		// Add or update rule for this file
-		fileName := filepath.Base(filePath)
-		if err := config.AddCreationRuleWithMultipleKeys(sopsConfig, fileName, pubKeyStr, ""); err != nil {
-			logging.Error("Failed to add rule to SOPS config: %v", err)
+		fileName := config.ConfigRelativePath(configPath, filePath)
+		stopTiming := timing.Track(filePath, timing.ConfigIO)
+		sopsConfig, err := updateSopsConfig(configPath, func(sopsConfig *config.SopsConfig) error {
+			encryptedRegex := newRuleEncryptedRegex(sopsConfig, fileName)
+			if err := config.AddCreationRuleWithMultipleKeys(sopsConfig, fileName, allPubKeys, encryptedRegex, wildcardPolicy); err != nil {
+				return fmt.Errorf("failed to add rule to SOPS config: %w", err)
+			}
+			return nil
+		})
+		stopTiming()
+		if err != nil {
+			logging.Error("Failed to update SOPS config: %v", err)
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
 			encryptErr = err
 			continue
 		}
+		warnShadowedRule(sopsConfig, fileName)
+		printEncryptionPreview(filePath, sopsConfig, fileName)
 
-		// Save the updated config
-		if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
-			logging.Error("Failed to save SOPS config: %v", err)
+		// Encrypt the file
+		logging.Info("Encrypting %s with multiple keys...", filePath)
+		restore, err := prepareFile(filePath)
+		if err != nil {
+			logging.Error("Failed to prepare %s: %v", filePath, err)
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
 			encryptErr = err
 			continue
 		}
 
-		// Encrypt the file
-		logging.Info("Encrypting %s with multiple keys...", filePath)
+		configArgs, fileRecipients, cleanup, err := encryptConfigArgs(configPath, filePath, allPubKeys)
+		if err != nil {
+			restore()
+			logging.Error("%v", err)
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
+			encryptErr = err
+			continue
+		}
 
-		// Use multiple Age recipients (comma-separated)
-		cmd := execCommand("sops", "--encrypt", "--age", pubKeyStr, "--in-place", filePath)
-		cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyPath))
+		// Use multiple Age recipients (comma-separated), no private key is needed
+		cmd := execCommand("sops", sopsArgs(filePath, append(configArgs, "--encrypt", "--age", strings.Join(fileRecipients, ","), "--in-place")...)...)
+		if skipCommand(cmd, filePath) {
+			cleanup()
+			succeeded++
+			continue
+		}
 
+		stopTiming = timing.Track(filePath, timing.SopsExec)
 		output, err := cmd.CombinedOutput()
+		stopTiming()
+		cleanup()
 		if err != nil {
+			restore()
 			logging.Error("Failed to encrypt file %s: %s\n%s", filePath, err, string(output))
-			encryptErr = err
+			logging.Record(filePath, "encrypt", logging.ResultFailed)
+			encryptErr = sopsFailed(err)
 			continue
 		}
 
 		logging.Success("File encrypted successfully: %s", filePath)
+		logging.Record(filePath, "encrypt", logging.ResultOK)
+		succeeded++
 	}
 
-	return encryptErr
+	return BulkError(encryptErr, processed-succeeded, succeeded)
 }
 
 // EncryptFiles encrypts multiple files
@@ -290,43 +297,46 @@ func EncryptFiles(filePaths []string, keyFile string, alwaysUseOnePassword bool)
 	}
 
 	// Get the SOPS config path
-	configPath, err := config.GetSopsConfigPath()
+	configPath, err := getSopsConfigPath()
 	if err != nil {
 		return fmt.Errorf("failed to determine SOPS config path: %w", err)
 	}
 
-	// Process each file
-	var encryptErr error
-	for _, filePath := range filePaths {
-		if err := EncryptFile(filePath, keyPath, configPath); err != nil {
-			logging.Error("Failed to encrypt %s: %v", filePath, err)
-			encryptErr = err
-		}
-	}
-
-	return encryptErr
+	// Process each file, one at a time as the rules are updated in between
+	return forEachFile(filePaths, 1, "encrypt", func(filePath string) error {
+		return EncryptFile(filePath, keyPath, configPath)
+	})
 }
 
 // SetEncryptionKeys sets the encryption keys for a specific file
-func SetEncryptionKeys(filePath string, keyFile string, encryptedRegex string, alwaysUseOnePassword bool) error {
+// An empty encryptedRegex selects the predefined pattern detected for the file.
+// Advanced rule options are only changed if any is set.
+func SetEncryptionKeys(filePath string, keyFile string, encryptedRegex string, options config.RuleOptions, alwaysUseOnePassword bool) error {
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", filePath)
 	}
 
 	// Check file extension
-	ext := filepath.Ext(filePath)
-	supportedExts := []string{".yaml", ".yml", ".json", ".ini", ".env"}
-	isSupported := false
-	for _, supportedExt := range supportedExts {
-		if strings.EqualFold(ext, supportedExt) {
-			isSupported = true
-			break
+	if !config.IsSupportedFile(filePath) {
+		return fmt.Errorf("unsupported file type: %s", filepath.Ext(filePath))
+	}
+
+	// Without a pattern, use the one matching the file's content
+	if encryptedRegex == "" {
+		detected, ok := DetectEncryptionPattern(filePath)
+		if !ok {
+			return fmt.Errorf("no encryption pattern given and none detected for %s", filePath)
 		}
+		encryptedRegex = PredefinedEncryptionPatterns()[detected.Name]
+		logging.Info("Detected %s, using the %s pattern", detected.Reason, detected.Name)
 	}
 
-	if !isSupported {
-		return fmt.Errorf("unsupported file type: %s", ext)
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if err := checkSopsSupports(options); err != nil {
+		return err
 	}
 
 	// Ensure we have the key available
@@ -347,26 +357,37 @@ func SetEncryptionKeys(filePath string, keyFile string, encryptedRegex string, a
 	}
 
 	// Get the SOPS config path
-	configPath, err := config.GetSopsConfigPath()
+	configPath, err := getSopsConfigPath()
 	if err != nil {
 		return fmt.Errorf("failed to determine SOPS config path: %w", err)
 	}
 
-	// Load or create SOPS config
-	sopsConfig, err := config.LoadSopsConfig(configPath)
+	// The recovery recipients of the policy can always decrypt the file
+	recipients, err := withRecoveryRecipients(configPath, []string{pubKey})
 	if err != nil {
-		return fmt.Errorf("failed to load SOPS config: %w", err)
+		return err
 	}
 
 	// Add or update rule for this file
-	fileName := filepath.Base(filePath)
-	if err := config.AddCreationRule(sopsConfig, fileName, pubKey, encryptedRegex); err != nil {
-		return fmt.Errorf("failed to add rule to SOPS config: %w", err)
+	fileName := config.ConfigRelativePath(configPath, filePath)
+	stopTiming := timing.Track(filePath, timing.ConfigIO)
+	sopsConfig, err := updateSopsConfig(configPath, func(sopsConfig *config.SopsConfig) error {
+		if err := config.AddCreationRuleWithMultipleKeys(sopsConfig, fileName, recipients, encryptedRegex, wildcardPolicy); err != nil {
+			return fmt.Errorf("failed to add rule to SOPS config: %w", err)
+		}
+		if options.IsZero() {
+			return nil
+		}
+		return config.SetRuleOptions(sopsConfig, fileName, options)
+	})
+	stopTiming()
+	if err != nil {
+		return fmt.Errorf("failed to update SOPS config: %w", err)
 	}
+	warnShadowedRule(sopsConfig, fileName)
 
-	// Save the updated config
-	if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
-		return fmt.Errorf("failed to save SOPS config: %w", err)
+	if dryRun {
+		return nil
 	}
 
 	logging.Success("SOPS config updated for %s! Pattern: %s", fileName, encryptedRegex)
@@ -380,9 +401,36 @@ func SetEncryptionKeys(filePath string, keyFile string, encryptedRegex string, a
 // PredefinedEncryptionPatterns returns predefined encryption patterns
 func PredefinedEncryptionPatterns() map[string]string {
 	return map[string]string{
-		"All values":            ".*",
-		"Kubernetes":            "^(data|stringData|password|token|secret|key|cert|ca.crt|tls|ingress|backupTarget)",
-		"Talos configuration":   "^(secrets|privateKey|token|key|crt|cert|password|secret|kubeconfig|talosconfig)",
-		"Common sensitive data": "^(password|token|secret|key|auth|credential|private|apiKey|cert)",
+		PatternAllValues:  ".*",
+		PatternKubernetes: "^(data|stringData|password|token|secret|key|cert|ca.crt|tls|ingress|backupTarget)",
+		PatternTalos:      "^(secrets|privateKey|token|key|crt|cert|password|secret|kubeconfig|talosconfig)",
+		PatternKubeconfig: "^(client-key-data|client-certificate-data|token|password|client-secret|id-token|refresh-token)$",
+		PatternCommon:     "^(password|token|secret|key|auth|credential|private|apiKey|cert)",
 	}
 }
+
+// warnShadowedRule warns if the rule for a file never applies, since an
+// earlier rule in .sops.yaml matches the file first
+func warnShadowedRule(sopsConfig *config.SopsConfig, fileName string) {
+	if conflict, found := config.ConflictFor(sopsConfig, fileName); found {
+		logging.Warn("The rule for %s is shadowed by the earlier rule %s, sops uses its recipients instead. Run 'simple-sops config reorder' to fix the order.", fileName, conflict.ShadowedBy.PathRegex)
+	}
+}
+
+// checkSopsSupports fails if the installed sops is too old for the rule options
+// A missing sops or an unknown version is left to the sops command itself.
+func checkSopsSupports(options config.RuleOptions) error {
+	minimum := options.MinSopsVersion()
+	if minimum == "" {
+		return nil
+	}
+
+	reported, found := sopsVersion("sops")
+	if !found {
+		return nil
+	}
+	if atLeast, ok := version.AtLeast(reported, minimum); ok && !atLeast {
+		return fmt.Errorf("the rule options require sops %s or newer, found %s", minimum, reported)
+	}
+	return nil
+}
@@ -4,6 +4,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/version"
+	"strings"
 	"testing"
 )
 
@@ -81,10 +85,29 @@ func setupTestEnvironment(t *testing.T) (string, string, string, func()) {
 	// Create a SOPS config file
 	configPath := filepath.Join(tempDir, ".sops.yaml")
 
+	// Use a trust store inside the temp directory
+	trustStorePath = filepath.Join(tempDir, "trust.yaml")
+	registryPath = filepath.Join(tempDir, "decrypted.yaml")
+
+	// Never touch the .sops.yaml of the repository the tests run in
+	getSopsConfigPath = func() (string, error) { return configPath, nil }
+	sopsVersion = func(string) (string, bool) { return "", false }
+
 	// Return cleanup function
 	cleanup := func() {
 		// Restore original execCommand
 		execCommand = originalExecCommand
+		trustStorePath = keymgmt.DefaultTrustStoreFile
+		registryPath = DefaultRegistryFile
+		getSopsConfigPath = config.GetSopsConfigPath
+		sopsVersion = version.ToolVersion
+		strictRecipients = false
+		recipientPolicy = config.RecipientPolicy{}
+		encryptedRegexDefaults = nil
+		encryptedRegexOverride = ""
+		dryRun = false
+		dryRunOutput = os.Stdout
+		plannedCopies = map[string]string{}
 		os.RemoveAll(tempDir)
 
 		// Reset mock state
@@ -136,4 +159,207 @@ func TestEncryptFile(t *testing.T) {
 	}
 }
 
+func TestEncryptFileStrictRecipients(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockExecOutput = []byte("Encryption successful")
+	mockExecError = nil
+
+	// Strict mode should reject a recipient that is not in the trust store
+	SetStrictRecipients(true)
+	if err := EncryptFile(testFilePath, keyPath, configPath); err == nil {
+		t.Fatal("Expected error for untrusted recipient in strict mode, got nil")
+	}
+	if lastExecCommand.cmd != "" {
+		t.Errorf("sops should not run for untrusted recipients, got '%s'", lastExecCommand.cmd)
+	}
+
+	// Trusting the key should allow encryption
+	store := &keymgmt.TrustStore{}
+	store.Add("age123456789abcdef", "test key")
+	if err := keymgmt.SaveTrustStore(trustStorePath, store); err != nil {
+		t.Fatalf("Failed to save trust store: %v", err)
+	}
+	if err := EncryptFile(testFilePath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed with trusted recipient: %v", err)
+	}
+}
+
+func TestEncryptFileRecipientPolicy(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockExecOutput = []byte("Encryption successful")
+	mockExecError = nil
+
+	// A single recipient violates a policy requiring the backup key
+	SetRecipientPolicy(config.RecipientPolicy{RequiredRecipients: []string{"age1backup"}})
+	if err := EncryptFile(testFilePath, keyPath, configPath); err == nil {
+		t.Fatal("Expected error for a recipient policy violation, got nil")
+	}
+	if lastExecCommand.cmd != "" {
+		t.Errorf("sops should not run on policy violations, got '%s'", lastExecCommand.cmd)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf(".sops.yaml should not be written on policy violations")
+	}
+
+	// Encrypting to the backup key as well meets the policy
+	SetRecipientPolicy(config.RecipientPolicy{RequiredRecipients: []string{"age1backup"}, MinRecipients: 2})
+	if err := EncryptFilesWithMultipleKeys([]string{testFilePath}, []string{keyPath}, []string{"age1backup"}, false, nil); err != nil {
+		t.Fatalf("EncryptFilesWithMultipleKeys failed with a policy-compliant recipient set: %v", err)
+	}
+}
+
+func TestEncryptFileEncryptedRegexDefaults(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	SetEncryptedRegexDefaults(config.EncryptedRegexDefaults{
+		{Match: ".yaml", EncryptedRegex: "^(data|stringData)$"},
+		{Match: ".env", EncryptedRegex: ".*"},
+	})
+
+	// New rules get the default for their file type
+	if err := EncryptFile(testFilePath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	rule, found := config.GetCreationRule(sopsConfig, "test.env")
+	if !found || rule.EncryptedRegex != ".*" {
+		t.Fatalf("Expected rule with encrypted_regex '.*', got %+v", rule)
+	}
+
+	// Existing rules keep their encrypted_regex
+	sopsConfig.CreationRules[0].EncryptedRegex = "^password$"
+	if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
+		t.Fatalf("SaveSopsConfig failed: %v", err)
+	}
+	if err := EncryptFile(testFilePath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	sopsConfig, err = config.LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	if rule, _ := config.GetCreationRule(sopsConfig, "test.env"); rule.EncryptedRegex != "^password$" {
+		t.Errorf("Expected existing encrypted_regex to be kept, got '%s'", rule.EncryptedRegex)
+	}
+}
+
+func TestSetEncryptionKeysRuleOptions(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	macOnlyEncrypted := true
+	options := config.RuleOptions{MacOnlyEncrypted: &macOnlyEncrypted}
+
+	// An old sops is rejected before the rule is changed
+	sopsVersion = func(string) (string, bool) { return "sops 3.8.1", true }
+	if err := SetEncryptionKeys(testFilePath, keyPath, ".*", options, false); err == nil {
+		t.Fatal("Expected mac_only_encrypted to be rejected for sops 3.8.1")
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Fatal("Expected no config to be written")
+	}
+
+	sopsVersion = func(string) (string, bool) { return "sops 3.9.4", true }
+	if err := SetEncryptionKeys(testFilePath, keyPath, ".*", options, false); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+
+	// Setting keys without options keeps them
+	if err := SetEncryptionKeys(testFilePath, keyPath, "^TEST$", config.RuleOptions{}, false); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	rule, found := config.GetCreationRule(sopsConfig, "test.env")
+	if !found || !rule.MacOnlyEncrypted || rule.EncryptedRegex != "^TEST$" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+}
+
 // Additional tests for other encrypt package functions will be implemented here
+
+func TestEncryptFileNestedRule(t *testing.T) {
+	keyPath, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	rootConfig := "creation_rules:\n  - path_regex: .*\n    age: age1alice\n"
+	if err := os.WriteFile(configPath, []byte(rootConfig), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+	apps := filepath.Join(filepath.Dir(configPath), "apps")
+	if err := os.MkdirAll(apps, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", apps, err)
+	}
+	nestedConfig := "creation_rules:\n  - path_regex: (^|/)secret\\.yaml$\n    age: age1bob\n"
+	if err := os.WriteFile(filepath.Join(apps, ".sops.yaml"), []byte(nestedConfig), 0644); err != nil {
+		t.Fatalf("Failed to write nested SOPS config: %v", err)
+	}
+	secret := filepath.Join(apps, "secret.yaml")
+	if err := os.WriteFile(secret, []byte("password: plain\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", secret, err)
+	}
+
+	if err := EncryptFile(secret, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+
+	// sops gets the merged rule and the recipients of the nested rule
+	args := strings.Join(lastExecCommand.args, " ")
+	if !strings.Contains(args, "--config ") || strings.Contains(args, "--config "+configPath) {
+		t.Errorf("Expected sops to get the merged config, got %v", lastExecCommand.args)
+	}
+	if !strings.Contains(args, "age1bob") {
+		t.Errorf("Expected the recipient of the nested rule, got %v", lastExecCommand.args)
+	}
+}
+
+func TestEncryptFileWithSourceConfig(t *testing.T) {
+	keyPath, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+	canonical := "creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1canonical\n"
+	sopsConfigSourceFor = func(path string) ([]byte, bool) {
+		return []byte(canonical), path == configPath
+	}
+	defer func() { sopsConfigSourceFor = config.SopsConfigSourceFor }()
+
+	// sops gets a copy of the rules of --config instead of the local .sops.yaml
+	var passed string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		lastExecCommand.cmd = command
+		lastExecCommand.args = args
+		if len(args) > 1 && args[0] == "--config" {
+			data, _ := os.ReadFile(args[1])
+			passed = string(data)
+		}
+		return exec.Command("true")
+	}
+
+	secrets := filepath.Join(filepath.Dir(configPath), "secrets.yaml")
+	if err := os.WriteFile(secrets, []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", secrets, err)
+	}
+	if err := EncryptFile(secrets, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	if lastExecCommand.args[0] != "--config" || lastExecCommand.args[1] == configPath || passed != canonical {
+		t.Errorf("Expected sops to get the canonical rules, got %v with:\n%s", lastExecCommand.args, passed)
+	}
+	if _, err := os.Stat(lastExecCommand.args[1]); !os.IsNotExist(err) {
+		t.Errorf("Expected the copy of the rules to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"simple-sops/internal/dotenv"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// EnvFormat represents an output format for decrypted environment variables
+type EnvFormat string
+
+const (
+	// EnvFormatShell emits export KEY='value' lines for eval
+	EnvFormatShell EnvFormat = "shell"
+	// EnvFormatGitHubEnv emits lines suitable for appending to $GITHUB_ENV
+	EnvFormatGitHubEnv EnvFormat = "github-env"
+	// EnvFormatDotenv emits KEY=value lines with dotenv quoting
+	EnvFormatDotenv EnvFormat = "dotenv"
+)
+
+// EnvFormats lists the supported environment output formats
+var EnvFormats = []EnvFormat{EnvFormatShell, EnvFormatGitHubEnv, EnvFormatDotenv}
+
+// EnvVar represents a single decrypted environment variable
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// envKeyPattern matches valid environment variable names
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseEnvFormat validates a format name
+func ParseEnvFormat(format string) (EnvFormat, error) {
+	for _, f := range EnvFormats {
+		if string(f) == format {
+			return f, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported format: %s (supported: shell, github-env, dotenv)", format)
+}
+
+// DecryptToEnv decrypts a file and returns its values as environment variables
+func DecryptToEnv(filePath string, keyFile string) ([]EnvVar, error) {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	logging.Debug("Decrypting %s as environment variables...", filePath)
+
+	args := []string{"--decrypt", "--output-type", "dotenv"}
+	if dotenv.IsDotenvFile(filePath) {
+		args = append(args, "--input-type", "dotenv")
+	}
+	cmd := execCommand("sops", append(args, filePath)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+	cmd.Stderr = os.Stderr
+	if skipCommand(cmd, "") {
+		return nil, nil
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, sopsFailed(fmt.Errorf("failed to decrypt file: %w", err))
+	}
+
+	return parseSopsDotenv(output), nil
+}
+
+// parseSopsDotenv parses the dotenv output of sops, which escapes newlines as \n
+func parseSopsDotenv(data []byte) []EnvVar {
+	var vars []EnvVar
+	for _, entry := range dotenv.ParseSops(data) {
+		if !entry.IsComment() {
+			vars = append(vars, EnvVar{Key: entry.Key, Value: entry.Value})
+		}
+	}
+	return vars
+}
+
+// FormatEnv renders environment variables in the given format
+func FormatEnv(vars []EnvVar, format EnvFormat) (string, error) {
+	var builder strings.Builder
+
+	for _, v := range vars {
+		if !envKeyPattern.MatchString(v.Key) {
+			logging.Warn("Skipping %s: not a valid environment variable name", v.Key)
+			continue
+		}
+
+		switch format {
+		case EnvFormatShell:
+			fmt.Fprintf(&builder, "export %s=%s\n", v.Key, ShellQuote(v.Value))
+		case EnvFormatGitHubEnv:
+			if strings.Contains(v.Value, "\n") {
+				delimiter, err := heredocDelimiter(v.Value)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&builder, "%s<<%s\n%s\n%s\n", v.Key, delimiter, v.Value, delimiter)
+			} else {
+				fmt.Fprintf(&builder, "%s=%s\n", v.Key, v.Value)
+			}
+		case EnvFormatDotenv:
+			fmt.Fprintf(&builder, "%s=%s\n", v.Key, dotenv.Quote(v.Value))
+		default:
+			return "", fmt.Errorf("unsupported format: %s", format)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// ShellQuote quotes a value for POSIX shells using single quotes
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// heredocDelimiter returns a random delimiter that does not occur in the value
+func heredocDelimiter(value string) (string, error) {
+	for {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate delimiter: %w", err)
+		}
+
+		delimiter := "EOF_" + hex.EncodeToString(buf)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
+
+// DecryptFilesAsEnv decrypts multiple files and prints their values in an environment format
+func DecryptFilesAsEnv(filePaths []string, keyFile string, format EnvFormat, alwaysUseOnePassword bool) error {
+	if len(filePaths) == 0 {
+		return fmt.Errorf("no files specified")
+	}
+
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	// Process each file
+	var decryptErr error
+	failed, succeeded := 0, 0
+	for _, filePath := range filePaths {
+		if failFast && decryptErr != nil {
+			break
+		}
+
+		vars, err := DecryptToEnv(filePath, keyPath)
+		if err != nil {
+			logging.Error("Failed to decrypt %s: %v", filePath, err)
+			decryptErr = err
+			failed++
+			continue
+		}
+
+		output, err := FormatEnv(vars, format)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, output)
+		succeeded++
+	}
+
+	return BulkError(decryptErr, failed, succeeded)
+}
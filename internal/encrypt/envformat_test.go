@@ -0,0 +1,87 @@
+package encrypt
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseSopsDotenv(t *testing.T) {
+	vars := parseSopsDotenv([]byte("# comment\nTOKEN=abc=def\nCERT=line1\\nline2\n\n"))
+	if len(vars) != 2 {
+		t.Fatalf("Expected 2 vars, got %d", len(vars))
+	}
+	if vars[0].Key != "TOKEN" || vars[0].Value != "abc=def" {
+		t.Errorf("Unexpected first var: %+v", vars[0])
+	}
+	if vars[1].Value != "line1\nline2" {
+		t.Errorf("Expected newline to be unescaped, got %q", vars[1].Value)
+	}
+}
+
+func TestFormatEnv(t *testing.T) {
+	vars := []EnvVar{
+		{Key: "PLAIN", Value: "value"},
+		{Key: "QUOTED", Value: "it's here"},
+		{Key: "not-valid", Value: "skipped"},
+	}
+
+	output, err := FormatEnv(vars, EnvFormatShell)
+	if err != nil {
+		t.Fatalf("FormatEnv failed for shell: %v", err)
+	}
+	expected := "export PLAIN='value'\nexport QUOTED='it'\\''s here'\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	output, err = FormatEnv(vars, EnvFormatDotenv)
+	if err != nil {
+		t.Fatalf("FormatEnv failed for dotenv: %v", err)
+	}
+	expected = "PLAIN=value\nQUOTED=\"it's here\"\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	// Multi-line values use a heredoc delimiter in github-env format
+	output, err = FormatEnv([]EnvVar{{Key: "CERT", Value: "line1\nline2"}}, EnvFormatGitHubEnv)
+	if err != nil {
+		t.Fatalf("FormatEnv failed for github-env: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "CERT<<EOF_") || lines[3] != strings.TrimPrefix(lines[0], "CERT<<") {
+		t.Errorf("Unexpected github-env output: %q", output)
+	}
+
+	if _, err := ParseEnvFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format, got nil")
+	}
+}
+
+func TestEncryptDotenvFileFailure(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	original := "export API_KEY='s3cret'\nCERT=\"line1\nline2\"\n"
+	if err := os.WriteFile(testFilePath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if err := EncryptFile(testFilePath, keyPath, configPath); err == nil {
+		t.Fatal("Expected error when sops fails, got nil")
+	}
+
+	// A failed encryption keeps the export prefix and quoting
+	data, err := os.ReadFile(testFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected the original dotenv file to be restored, got:\n%s", data)
+	}
+}
@@ -0,0 +1,70 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// Operations hooks can be configured for
+const (
+	OperationDecrypt = "decrypt"
+	OperationRotate  = "rotate"
+)
+
+// WithHooks runs an operation between its pre and post hooks
+// A failing pre hook aborts the operation. Post hooks learn from
+// SIMPLE_SOPS_HOOK_STATUS whether the operation succeeded, and their failures
+// are only reported. In dry-run mode the hooks are printed instead.
+func WithHooks(hooks config.HookSettings, operation string, files []string, fn func() error) error {
+	if err := runHooks(hooks.Commands(operation, config.HookPre), operation, config.HookPre, files, nil); err != nil {
+		return fmt.Errorf("%s aborted by a pre_%s hook: %w", operation, operation, err)
+	}
+
+	opErr := fn()
+
+	if err := runHooks(hooks.Commands(operation, config.HookPost), operation, config.HookPost, files, opErr); err != nil {
+		logging.Warn("post_%s hook failed: %v", operation, err)
+	}
+
+	return opErr
+}
+
+// runHooks runs hook commands one after the other, stopping at the first failure
+// Hooks get the operation, stage and files in their environment. Their output
+// goes to stderr, so it doesn't mix with decrypted output.
+func runHooks(commands []string, operation string, stage string, files []string, opErr error) error {
+	env := append(os.Environ(),
+		"SIMPLE_SOPS_HOOK_OPERATION="+operation,
+		"SIMPLE_SOPS_HOOK_STAGE="+stage,
+		"SIMPLE_SOPS_HOOK_FILES="+strings.Join(files, "\n"),
+	)
+	if stage == config.HookPost {
+		status := "success"
+		if opErr != nil {
+			status = "failure"
+			env = append(env, "SIMPLE_SOPS_HOOK_ERROR="+opErr.Error())
+		}
+		env = append(env, "SIMPLE_SOPS_HOOK_STATUS="+status)
+	}
+
+	for _, command := range commands {
+		cmd := execCommand("sh", "-c", command)
+		cmd.Env = env
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if skipCommand(cmd, "") {
+			continue
+		}
+
+		logging.Debug("Running %s_%s hook: %s", stage, operation, command)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package encrypt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestWithHooks(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "hooks.log")
+	record := `echo "$SIMPLE_SOPS_HOOK_STAGE $SIMPLE_SOPS_HOOK_OPERATION $SIMPLE_SOPS_HOOK_FILES $SIMPLE_SOPS_HOOK_STATUS" >> ` + logPath
+	hooks := config.HookSettings{
+		PreDecrypt:  []string{record},
+		PostDecrypt: []string{record, "exit 1"},
+	}
+
+	errFailed := errors.New("failed")
+	err := WithHooks(hooks, OperationDecrypt, []string{"secrets.yaml"}, func() error { return errFailed })
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Expected the error of the operation, got %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pre decrypt secrets.yaml \npost decrypt secrets.yaml failure\n"
+	if string(data) != want {
+		t.Errorf("Hooks recorded %q, want %q", string(data), want)
+	}
+}
+
+func TestWithHooksPreHookAborts(t *testing.T) {
+	hooks := config.HookSettings{PreRotate: []string{`test -n "$TICKET_ID"`}}
+	t.Setenv("TICKET_ID", "")
+
+	ran := false
+	err := WithHooks(hooks, OperationRotate, []string{"secrets.yaml"}, func() error {
+		ran = true
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "aborted by a pre_rotate hook") {
+		t.Errorf("Expected the pre hook to abort, got %v", err)
+	}
+	if ran {
+		t.Error("Expected the operation not to run")
+	}
+
+	// Hooks of other operations don't apply
+	if err := WithHooks(hooks, OperationDecrypt, nil, func() error { return nil }); err != nil {
+		t.Errorf("Expected no decrypt hooks to run, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/lint"
+	"simple-sops/pkg/logging"
+)
+
+// lintFile checks the plaintext of a file for weak values before it's encrypted
+// Only repositories with a .simple-sops/lint.yaml are checked. Weak values
+// abort the encryption if the settings ask to fail, and are reported otherwise.
+func lintFile(filePath string, configPath string) error {
+	settings, enabled, err := config.LoadLintSettings(filepath.Dir(configPath))
+	if err != nil || !enabled {
+		return err
+	}
+
+	content, err := os.ReadFile(sourcePath(filePath))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	findings, err := lint.CheckFile(sourcePath(filePath), content, settings)
+	if err != nil {
+		return err
+	}
+	reportFindings(filePath, findings)
+
+	if settings.Fail && len(findings) > 0 {
+		return fmt.Errorf("%s has weak values, replace them or add their paths to ignore in %s", filePath, config.LintFileName)
+	}
+	return nil
+}
+
+// lintEditedFile checks the values of a file after it was edited
+// The file is already saved, so weak values are only reported.
+func lintEditedFile(filePath string, keyFile string) {
+	configPath, err := getSopsConfigPath()
+	if err != nil {
+		return
+	}
+	settings, enabled, err := config.LoadLintSettings(filepath.Dir(configPath))
+	if err != nil {
+		logging.Warn("Skipping the lint of %s: %v", filePath, err)
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	plaintext, err := DecryptToBytes(filePath, keyFile)
+	if err != nil {
+		logging.Warn("Skipping the lint of %s: %v", filePath, err)
+		return
+	}
+	findings, err := lint.CheckFile(filePath, plaintext, settings)
+	keymgmt.Wipe(plaintext)
+	if err != nil {
+		logging.Warn("Skipping the lint of %s: %v", filePath, err)
+		return
+	}
+	reportFindings(filePath, findings)
+}
+
+// reportFindings warns about the weak values of a file
+func reportFindings(filePath string, findings []lint.Finding) {
+	for _, finding := range findings {
+		logging.Warn("%s: %s %s", filePath, finding.Path, finding.Reason)
+	}
+}
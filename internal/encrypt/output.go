@@ -0,0 +1,105 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+)
+
+// plannedCopies maps outputs to the files they would be copied from in dry-run mode
+var plannedCopies = map[string]string{}
+
+// CopyToOutput copies a plaintext file to the path it is encrypted at, so the
+// plaintext is kept next to its encrypted counterpart
+// In dry-run mode the copy is only planned and reading the output reads the
+// plaintext file instead.
+func CopyToOutput(inputPath string, outputPath string) error {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return fmt.Errorf("file not found: %s", inputPath)
+	}
+	if config.IsFileEncrypted(inputPath) {
+		return fmt.Errorf("%s is already encrypted", inputPath)
+	}
+
+	if dryRun {
+		PrintDryRun("Would copy %s to %s", inputPath, outputPath)
+		plannedCopies[outputPath] = inputPath
+		return nil
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := os.WriteFile(outputPath, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+// sourcePath returns the file holding the content of a file
+// This is the plaintext file for outputs only planned in dry-run mode.
+func sourcePath(filePath string) string {
+	if source, ok := plannedCopies[filePath]; ok {
+		return source
+	}
+	return filePath
+}
+
+// plaintextFileMode is the mode of files holding decrypted content
+const plaintextFileMode = 0600
+
+// CreatePlaintextFile creates or truncates a file for decrypted content
+// Only the owner can read it, also if the file existed with other permissions.
+func CreatePlaintextFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, plaintextFileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(plaintextFileMode); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// WritePlaintextFile writes decrypted content to a file only the owner can read
+func WritePlaintextFile(path string, data []byte) error {
+	file, err := CreatePlaintextFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// CheckOutputDir refuses plaintext outputs in directories other users can write to
+// Other users could replace the file in world-writable directories like /tmp,
+// sticky or not. In directories they can only read, the file itself stays
+// private, so they are only warned about.
+func CheckOutputDir(outputPath string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	dir := filepath.Dir(outputPath)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check output directory: %w", err)
+	}
+	perm := info.Mode().Perm()
+	if perm&0002 != 0 {
+		return fmt.Errorf("%s is writable by other users (%s), choose another output path or pass --insecure-output", dir, perm)
+	}
+	if perm&0004 != 0 {
+		logging.Warn("%s is readable by other users (%s), they can see the name of the decrypted file", dir, perm)
+	}
+	return nil
+}
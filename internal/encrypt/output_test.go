@@ -0,0 +1,67 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWritePlaintextFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+
+	// Existing files lose their wider permissions
+	path := filepath.Join(t.TempDir(), "plain.yaml")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePlaintextFile(path, []byte("password: s3cret\n")); err != nil {
+		t.Fatalf("WritePlaintextFile failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %s", info.Mode().Perm())
+	}
+	if content, _ := os.ReadFile(path); string(content) != "password: s3cret\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
+
+func TestCheckOutputDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes are not checked on Windows")
+	}
+
+	dir := t.TempDir()
+	for mode, allowed := range map[os.FileMode]bool{0700: true, 0750: true, 0755: true, 0704: true, 0777: false, 0703: false, 0722: false} {
+		if err := os.Chmod(dir, mode); err != nil {
+			t.Fatal(err)
+		}
+		err := CheckOutputDir(filepath.Join(dir, "plain.yaml"))
+		if allowed && err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", mode, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("Expected %s to be refused", mode)
+		}
+	}
+
+	// Sticky world-writable directories like /tmp are refused too
+	if err := os.Chmod(dir, 0777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckOutputDir(filepath.Join(dir, "plain.yaml")); err == nil {
+		t.Error("Expected a sticky world-writable directory to be refused")
+	}
+	os.Chmod(dir, 0700)
+
+	if err := CheckOutputDir(filepath.Join(dir, "missing", "plain.yaml")); err == nil {
+		t.Error("Expected error for a missing directory")
+	}
+}
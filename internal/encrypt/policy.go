@@ -0,0 +1,37 @@
+package encrypt
+
+import (
+	"fmt"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// withRecoveryRecipients adds the recovery recipients of the policy to the recipients of a file
+// They are added after the trust check, since the policy vouches for them.
+func withRecoveryRecipients(configPath string, recipients []string) ([]string, error) {
+	policy, err := config.LoadRecipientPolicy(filepath.Dir(configPath), recipientPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.RecoveryRecipients) > 0 {
+		logging.Debug("Adding the recovery recipients %s", strings.Join(policy.RecoveryRecipients, ", "))
+	}
+	return policy.WithRecovery(recipients), nil
+}
+
+// checkRecipientPolicy fails if a file would be encrypted to recipients the policy rejects
+// The policy of the application config applies together with the one in
+// .simple-sops/policy.yaml next to .sops.yaml.
+func checkRecipientPolicy(configPath string, recipients []string) error {
+	policy, err := config.LoadRecipientPolicy(filepath.Dir(configPath), recipientPolicy)
+	if err != nil || policy.IsEmpty() {
+		return err
+	}
+
+	if problems := policy.Check(recipients); len(problems) > 0 {
+		return fmt.Errorf("recipients violate the recipient policy: %s, encrypt to more recipients", strings.Join(problems, ", "))
+	}
+	return nil
+}
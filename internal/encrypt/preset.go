@@ -0,0 +1,104 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/pkg/logging"
+	"sort"
+	"strings"
+)
+
+// Names of the file presets
+const (
+	PresetKubeconfig = "kubeconfig"
+	PresetTalos      = "talos"
+)
+
+// Preset bundles the encryption settings for a well-known kind of file
+type Preset struct {
+	// Name is the name of the preset and its command
+	Name string
+	// Pattern is the predefined encryption pattern of the preset
+	Pattern string
+	// DefaultFiles are encrypted when no file is given, if they exist
+	DefaultFiles []string
+}
+
+// presets are the available file presets by name
+var presets = map[string]Preset{
+	PresetKubeconfig: {
+		Name:         PresetKubeconfig,
+		Pattern:      PatternKubeconfig,
+		DefaultFiles: []string{"kubeconfig"},
+	},
+	PresetTalos: {
+		Name:    PresetTalos,
+		Pattern: PatternTalos,
+		// The files written by talosctl gen config and gen secrets
+		DefaultFiles: []string{"talosconfig", "controlplane.yaml", "worker.yaml", "secrets.yaml"},
+	},
+}
+
+// GetPreset returns the preset with the given name
+func GetPreset(name string) (Preset, bool) {
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// PresetNames returns the names of all presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EncryptedRegex returns the encrypted_regex of the preset, unless the settings override it
+func (p Preset) EncryptedRegex(settings config.PresetSettings) string {
+	if settings.EncryptedRegex != "" {
+		return settings.EncryptedRegex
+	}
+	return PredefinedEncryptionPatterns()[p.Pattern]
+}
+
+// FindDefaultFiles returns the default files of the preset that exist in dir
+// and are not encrypted yet
+func (p Preset) FindDefaultFiles(dir string) []string {
+	var files []string
+	for _, name := range p.DefaultFiles {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if config.IsFileEncrypted(path) {
+			logging.Debug("Skipping %s, it is already encrypted", path)
+			continue
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+// isYAMLFileName checks if a file without extension is YAML by its conventional name
+// kubectl and talosctl write kubeconfig and talosconfig files without an extension,
+// which sops would encrypt as a binary blob.
+func isYAMLFileName(path string) bool {
+	switch strings.ToLower(filepath.Base(path)) {
+	case "kubeconfig", "talosconfig":
+		return true
+	}
+	return false
+}
+
+// encryptedRegexOverride replaces the encrypted_regex of new and existing rules
+var encryptedRegexOverride string
+
+// SetEncryptedRegexOverride sets an encrypted_regex applied to every encrypted file
+// Presets use it to bring existing rules in line with their pattern.
+func SetEncryptedRegexOverride(encryptedRegex string) {
+	encryptedRegexOverride = encryptedRegex
+}
@@ -0,0 +1,78 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestEncryptFileWithPreset(t *testing.T) {
+	keyPath, testFilePath, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	kubeconfigPath := filepath.Join(filepath.Dir(testFilePath), "kubeconfig")
+	if err := os.WriteFile(kubeconfigPath, []byte("apiVersion: v1\nkind: Config\nusers: []\n"), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	// An existing rule with another encrypted_regex is brought in line with the preset
+	sopsConfig := &config.SopsConfig{CreationRules: []config.CreationRule{
+		{PathRegex: config.RulePathRegex("kubeconfig"), Age: config.AgeRecipients{"age1existing"}, EncryptedRegex: "^data$"},
+	}}
+	if err := config.SaveSopsConfig(configPath, sopsConfig); err != nil {
+		t.Fatalf("SaveSopsConfig failed: %v", err)
+	}
+
+	preset, ok := GetPreset(PresetKubeconfig)
+	if !ok {
+		t.Fatal("Expected kubeconfig preset")
+	}
+	SetEncryptedRegexOverride(preset.EncryptedRegex(config.PresetSettings{}))
+
+	if err := EncryptFile(kubeconfigPath, keyPath, configPath); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+
+	sopsConfig, err := config.LoadSopsConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadSopsConfig failed: %v", err)
+	}
+	rule, _ := config.GetCreationRule(sopsConfig, "kubeconfig")
+	if rule.EncryptedRegex != PredefinedEncryptionPatterns()[PatternKubeconfig] {
+		t.Errorf("Expected the kubeconfig pattern, got '%s'", rule.EncryptedRegex)
+	}
+
+	// Files without extension must not be encrypted as binary
+	if args := strings.Join(lastExecCommand.args, " "); !strings.Contains(args, "--input-type yaml --output-type yaml") {
+		t.Errorf("Expected YAML types for kubeconfig, got: %s", args)
+	}
+}
+
+func TestPresetFindDefaultFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"talosconfig":       "context: demo\ncontexts: {}\n",
+		"controlplane.yaml": "version: v1alpha1\n",
+		"secrets.yaml":      "sops:\n  mac: ENC[AES256_GCM,data:x]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	preset, _ := GetPreset(PresetTalos)
+	files := preset.FindDefaultFiles(dir)
+
+	// worker.yaml doesn't exist and secrets.yaml is already encrypted
+	expected := []string{filepath.Join(dir, "talosconfig"), filepath.Join(dir, "controlplane.yaml")}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	// Settings override the predefined pattern
+	if regex := preset.EncryptedRegex(config.PresetSettings{EncryptedRegex: "^key$"}); regex != "^key$" {
+		t.Errorf("Expected overridden encrypted_regex, got '%s'", regex)
+	}
+}
@@ -0,0 +1,185 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"simple-sops/internal/dotenv"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValuePreview is a value of a document and whether sops would encrypt it
+type ValuePreview struct {
+	// Path is the sops tree path of the value, e.g. ["data"]["password"]
+	Path string
+	// Encrypted is true if the value matches the encrypted_regex
+	Encrypted bool
+}
+
+// DocumentPreview lists the values of one document of a file
+type DocumentPreview struct {
+	// Index is the position of the document in the file, starting at 0
+	Index int
+	// Kind is the Kubernetes kind of the document, if any
+	Kind string
+	// Values are the leaf values of the document
+	Values []ValuePreview
+}
+
+// decodeYAMLDocuments decodes every document of a YAML or JSON file
+// Kubernetes files often hold several documents separated by "---".
+func decodeYAMLDocuments(data []byte) ([]*yaml.Node, error) {
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return documents, nil
+			}
+			return nil, err
+		}
+		documents = append(documents, &doc)
+	}
+}
+
+// CountDocuments returns the number of YAML documents in a file
+// Files that aren't YAML or JSON count as a single document.
+func CountDocuments(filePath string) int {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext != ".yaml" && ext != ".yml" {
+		return 1
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 1
+	}
+
+	documents, err := decodeYAMLDocuments(data)
+	if err != nil || len(documents) == 0 {
+		return 1
+	}
+	return len(documents)
+}
+
+// PreviewEncryption shows which values of each document sops would encrypt
+// As in sops, a key matching the encrypted_regex encrypts everything below it,
+// and the regex is applied to every document of the file.
+func PreviewEncryption(filePath string, encryptedRegex string) ([]DocumentPreview, error) {
+	isDotenv := dotenv.IsDotenvFile(filePath)
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if !isDotenv && ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return nil, fmt.Errorf("preview is only supported for YAML, JSON and dotenv files")
+	}
+
+	pattern, err := regexp.Compile(encryptedRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted_regex: %w", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Dotenv files are a single document of top-level keys
+	if isDotenv {
+		entries, err := dotenv.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+
+		preview := DocumentPreview{}
+		for _, entry := range entries {
+			if !entry.IsComment() {
+				preview.Values = append(preview.Values, ValuePreview{
+					Path:      "[" + strconv.Quote(entry.Key) + "]",
+					Encrypted: pattern.MatchString(entry.Key),
+				})
+			}
+		}
+		return []DocumentPreview{preview}, nil
+	}
+
+	documents, err := decodeYAMLDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	var previews []DocumentPreview
+	for i, doc := range documents {
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+
+		preview := DocumentPreview{Index: i, Kind: documentKind(root)}
+		collectValues(root, "", false, pattern, &preview.Values)
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
+
+// documentKind returns the Kubernetes kind of a document
+func documentKind(root *yaml.Node) string {
+	if root.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "kind" && root.Content[i+1].Kind == yaml.ScalarNode {
+			return root.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// collectValues walks a node and records its leaf values
+func collectValues(node *yaml.Node, path string, encrypted bool, pattern *regexp.Regexp, values *[]ValuePreview) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			collectValues(node.Content[i+1], path+"["+strconv.Quote(key)+"]", encrypted || pattern.MatchString(key), pattern, values)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			collectValues(item, fmt.Sprintf("%s[%d]", path, i), encrypted, pattern, values)
+		}
+	case yaml.AliasNode:
+		collectValues(node.Alias, path, encrypted, pattern, values)
+	default:
+		*values = append(*values, ValuePreview{Path: path, Encrypted: encrypted})
+	}
+}
+
+// FormatPreview formats the preview of a file for display
+func FormatPreview(previews []DocumentPreview) string {
+	var builder strings.Builder
+	for _, preview := range previews {
+		if len(previews) > 1 {
+			fmt.Fprintf(&builder, "Document %d", preview.Index+1)
+			if preview.Kind != "" {
+				fmt.Fprintf(&builder, " (%s)", preview.Kind)
+			}
+			builder.WriteString(":\n")
+		}
+
+		for _, value := range preview.Values {
+			marker := "  plain     "
+			if value.Encrypted {
+				marker = "  encrypted "
+			}
+			builder.WriteString(marker + value.Path + "\n")
+		}
+	}
+	return builder.String()
+}
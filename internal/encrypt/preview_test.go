@@ -0,0 +1,78 @@
+package encrypt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const multiDocManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  mode: production
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+stringData:
+  password: hunter2
+  certs:
+    - a
+    - b
+`
+
+func TestPreviewEncryptionMultiDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte(multiDocManifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	if documents := CountDocuments(path); documents != 2 {
+		t.Errorf("Expected 2 documents, got %d", documents)
+	}
+
+	previews, err := PreviewEncryption(path, "^(data|stringData)$")
+	if err != nil {
+		t.Fatalf("PreviewEncryption failed: %v", err)
+	}
+	if len(previews) != 2 {
+		t.Fatalf("Expected 2 document previews, got %d", len(previews))
+	}
+	if previews[0].Kind != "ConfigMap" || previews[1].Kind != "Secret" {
+		t.Errorf("Unexpected kinds: %s, %s", previews[0].Kind, previews[1].Kind)
+	}
+
+	// The regex is applied to every document, and matching keys encrypt everything below them
+	encrypted := map[string]bool{}
+	for _, preview := range previews {
+		for _, value := range preview.Values {
+			if value.Encrypted {
+				encrypted[value.Path] = true
+			}
+		}
+	}
+	for _, path := range []string{`["data"]["mode"]`, `["stringData"]["password"]`, `["stringData"]["certs"][1]`} {
+		if !encrypted[path] {
+			t.Errorf("Expected %s to be encrypted", path)
+		}
+	}
+	if encrypted[`["metadata"]["name"]`] || encrypted[`["kind"]`] {
+		t.Errorf("Expected metadata to stay plain, got %v", encrypted)
+	}
+
+	formatted := FormatPreview(previews)
+	for _, expected := range []string{"Document 2 (Secret):", `  encrypted ["stringData"]["password"]`, `  plain     ["kind"]`} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", expected, formatted)
+		}
+	}
+
+	// Unsupported formats are rejected
+	if _, err := PreviewEncryption(filepath.Join(t.TempDir(), "app.ini"), ".*"); err == nil {
+		t.Error("Expected error for ini file, got nil")
+	}
+}
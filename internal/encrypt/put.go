@@ -0,0 +1,234 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/timing"
+	"simple-sops/internal/version"
+	"simple-sops/pkg/logging"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setValueStdinVersion is the oldest sops version reading the value of set from stdin
+const setValueStdinVersion = "3.11.0"
+
+// NoDocument selects no document of a file, which is only valid for files with one
+// Documents are numbered from 1, like in the encryption preview.
+const NoDocument = 0
+
+// valuePathPattern matches sops tree paths like ["tls"]["key"] or ["items"][0]
+var valuePathPattern = regexp.MustCompile(`^(\[("([^"\\]|\\.)*"|\d+)\])+$`)
+
+// valuePathSegment matches one key or index of a sops tree path
+var valuePathSegment = regexp.MustCompile(`\[("(?:[^"\\]|\\.)*"|\d+)\]`)
+
+// ValidateValuePath checks that a path uses the sops ["key"][0] syntax
+func ValidateValuePath(valuePath string) error {
+	if !valuePathPattern.MatchString(valuePath) {
+		return fmt.Errorf(`invalid path %q, expected a sops tree path like '["tls"]["key"]'`, valuePath)
+	}
+	return nil
+}
+
+// encodeSetValue encodes a value for sops set
+// Plain values are encoded as JSON strings, raw JSON values are validated and passed through
+func encodeSetValue(valuePath string, value []byte, rawJSON bool) ([]byte, error) {
+	if err := ValidateValuePath(valuePath); err != nil {
+		return nil, err
+	}
+
+	var encoded []byte
+	if rawJSON {
+		var parsed interface{}
+		if err := json.Unmarshal(value, &parsed); err != nil {
+			return nil, fmt.Errorf("value is not valid JSON: %w", err)
+		}
+		var err error
+		if encoded, err = json.Marshal(parsed); err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+	} else {
+		var err error
+		if encoded, err = json.Marshal(string(value)); err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+	}
+
+	return encoded, nil
+}
+
+// checkSopsReadsSetValue fails if the installed sops can't read the value of set from stdin
+// The value is never put on the command line, where other users could see it.
+// A missing sops or an unknown version is left to the sops command itself.
+func checkSopsReadsSetValue() error {
+	reported, found := sopsVersion("sops")
+	if !found {
+		return nil
+	}
+	if atLeast, ok := version.AtLeast(reported, setValueStdinVersion); ok && !atLeast {
+		return fmt.Errorf("setting values needs sops %s or newer to pass the value on stdin, found %s", setValueStdinVersion, reported)
+	}
+	return nil
+}
+
+// checkDocument checks the document selected in a file and returns the number of documents
+// A file with several documents needs one to be selected.
+func checkDocument(filePath string, document int) (int, error) {
+	documents := CountDocuments(filePath)
+	switch {
+	case document == NoDocument && documents > 1:
+		return documents, fmt.Errorf("%s has %d documents, select one with --document", filePath, documents)
+	case document < 0 || document > documents:
+		return documents, fmt.Errorf("%s has %d document(s), there is no document %d", filePath, documents, document)
+	}
+	return documents, nil
+}
+
+// PutValue inserts or updates a single value in an encrypted file
+// document selects the document of a multi-document YAML file, starting at 1.
+func PutValue(filePath string, keyFile string, valuePath string, value []byte, rawJSON bool, document int, alwaysUseOnePassword bool) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	encoded, err := encodeSetValue(valuePath, value, rawJSON)
+	if err != nil {
+		return err
+	}
+	defer clear(encoded)
+
+	// sops only sets values in the first document of a file
+	if _, err := checkDocument(filePath, document); err != nil {
+		return err
+	}
+	if document > 1 {
+		return fmt.Errorf("sops can only set values in the first document of %s, use edit for document %d", filePath, document)
+	}
+	if err := checkSopsReadsSetValue(); err != nil {
+		return err
+	}
+
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	logging.Debug("Setting %s in %s...", valuePath, filePath)
+
+	// The value is passed on stdin, so it doesn't show up in the process list
+	cmd := execCommand("sops", append(sopsArgs(filePath, "set", "--value-stdin"), valuePath)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyPath))
+	cmd.Stdin = bytes.NewReader(encoded)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to set value: %s\n%s", err, string(output)))
+	}
+
+	logging.Success("Updated %s in %s", valuePath, filePath)
+	logging.Record(filePath, "put", logging.ResultOK)
+	return nil
+}
+
+// ExtractValue decrypts a single value of an encrypted file
+// Strings are returned as they are, structured values in the format of the
+// file. document selects the document of a multi-document YAML file, starting
+// at 1.
+func ExtractValue(filePath string, keyFile string, valuePath string, document int) ([]byte, error) {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	if err := ValidateValuePath(valuePath); err != nil {
+		return nil, err
+	}
+
+	// sops only extracts values from the first document, so others are decrypted in memory
+	documents, err := checkDocument(filePath, document)
+	if err != nil {
+		return nil, err
+	}
+	if documents > 1 {
+		plaintext, err := DecryptToBytes(filePath, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return documentValue(plaintext, document, valuePath)
+	}
+
+	logging.Debug("Extracting %s from %s...", valuePath, filePath)
+
+	cmd := execCommand("sops", sopsArgs(filePath, "--decrypt", "--extract", valuePath)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	stopTiming := timing.Track(filePath, timing.SopsExec)
+	err = cmd.Run()
+	stopTiming()
+	if err != nil {
+		return nil, sopsFailed(fmt.Errorf("failed to extract %s: %s\n%s", valuePath, err, stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// documentValue returns a value of one document of a decrypted YAML file
+// Scalars are returned as they are, structured values as YAML.
+func documentValue(data []byte, document int, valuePath string) ([]byte, error) {
+	documents, err := decodeYAMLDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted file: %w", err)
+	}
+	if document < 1 || document > len(documents) || len(documents[document-1].Content) == 0 {
+		return nil, fmt.Errorf("there is no document %d", document)
+	}
+
+	node := documents[document-1].Content[0]
+	for _, match := range valuePathSegment.FindAllStringSubmatch(valuePath, -1) {
+		segment := match[1]
+		var next *yaml.Node
+		switch {
+		case node.Kind == yaml.MappingNode && segment[0] == '"':
+			key, err := strconv.Unquote(segment)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s in %s: %w", segment, valuePath, err)
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case node.Kind == yaml.SequenceNode && segment[0] != '"':
+			if index, err := strconv.Atoi(segment); err == nil && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%s not found in document %d", valuePath, document)
+		}
+		node = next
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return []byte(node.Value), nil
+	}
+	return yaml.Marshal(node)
+}
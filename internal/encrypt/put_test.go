@@ -0,0 +1,138 @@
+package encrypt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncodeSetValue(t *testing.T) {
+	// Multi-line values are encoded as JSON strings
+	encoded, err := encodeSetValue(`["tls"]["key"]`, []byte("-----BEGIN KEY-----\nabc\n-----END KEY-----\n"), false)
+	if err != nil {
+		t.Fatalf("encodeSetValue failed: %v", err)
+	}
+	expected := `"-----BEGIN KEY-----\nabc\n-----END KEY-----\n"`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+
+	// Raw JSON is compacted and passed through
+	encoded, err = encodeSetValue(`["items"][0]`, []byte("{\n  \"a\": 1\n}"), true)
+	if err != nil {
+		t.Fatalf("encodeSetValue failed for JSON: %v", err)
+	}
+	if string(encoded) != `{"a":1}` {
+		t.Errorf("Unexpected JSON value: %s", encoded)
+	}
+
+	if _, err := encodeSetValue(`["a"]`, []byte("{invalid"), true); err == nil {
+		t.Error("Expected error for invalid JSON, got nil")
+	}
+
+	for _, path := range []string{"tls.key", `["tls"`, "", `[tls]`} {
+		if err := ValidateValuePath(path); err == nil {
+			t.Errorf("Expected error for invalid path %q", path)
+		}
+	}
+}
+
+func TestDocumentValue(t *testing.T) {
+	data := []byte("kind: ConfigMap\ndata:\n  mode: dev\n---\nkind: Secret\nstringData:\n  password: s3cret\n  hosts:\n    - a.example.com\n    - b.example.com\n")
+
+	tests := []struct {
+		document  int
+		valuePath string
+		want      string
+	}{
+		{1, `["data"]["mode"]`, "dev"},
+		{2, `["stringData"]["password"]`, "s3cret"},
+		{2, `["stringData"]["hosts"][1]`, "b.example.com"},
+		{2, `["stringData"]["hosts"]`, "- a.example.com\n- b.example.com\n"},
+	}
+	for _, tt := range tests {
+		got, err := documentValue(data, tt.document, tt.valuePath)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("documentValue(%d, %s) = %q, %v, want %q", tt.document, tt.valuePath, got, err, tt.want)
+		}
+	}
+
+	for _, missing := range []string{`["stringData"]["user"]`, `["stringData"]["hosts"][2]`, `["kind"]["name"]`} {
+		if _, err := documentValue(data, 2, missing); err == nil {
+			t.Errorf("Expected an error for %s, got nil", missing)
+		}
+	}
+}
+
+func TestMultiDocumentNeedsDocument(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.yaml")
+	if err := os.WriteFile(filePath, []byte("a: 1\n---\nb: 2\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", filePath, err)
+	}
+
+	// Both commands refuse to guess the document
+	if _, err := ExtractValue(filePath, "", `["b"]`, NoDocument); err == nil || !strings.Contains(err.Error(), "--document") {
+		t.Errorf("Expected ExtractValue to ask for --document, got %v", err)
+	}
+	if err := PutValue(filePath, "", `["b"]`, []byte("3"), false, NoDocument, false); err == nil || !strings.Contains(err.Error(), "--document") {
+		t.Errorf("Expected PutValue to ask for --document, got %v", err)
+	}
+	if _, err := ExtractValue(filePath, "", `["b"]`, 3); err == nil {
+		t.Error("Expected ExtractValue to reject a missing document")
+	}
+	if err := PutValue(filePath, "", `["b"]`, []byte("3"), false, 2, false); err == nil || !strings.Contains(err.Error(), "first document") {
+		t.Errorf("Expected PutValue to refuse documents other than the first, got %v", err)
+	}
+}
+
+func TestPutValueKeepsValueOffCommandLine(t *testing.T) {
+	keyPath, _, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	filePath := filepath.Join(filepath.Dir(keyPath), "secrets.yaml")
+	if err := os.WriteFile(filePath, []byte("db:\n    password: ENC[AES256_GCM,data:abc]\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", filePath, err)
+	}
+
+	// The fake sops saves what it reads from stdin
+	stdinPath := filepath.Join(filepath.Dir(keyPath), "stdin")
+	var args []string
+	execCommand = func(command string, arguments ...string) *exec.Cmd {
+		args = arguments
+		return exec.Command("sh", "-c", `cat > "$0"`, stdinPath)
+	}
+
+	secret := "hunter2-s3cret"
+	if err := PutValue(filePath, keyPath, `["db"]["password"]`, []byte(secret), false, NoDocument, false); err != nil {
+		t.Fatalf("PutValue failed: %v", err)
+	}
+
+	for _, arg := range args {
+		if strings.Contains(arg, secret) {
+			t.Errorf("The value is on the sops command line: %v", args)
+		}
+	}
+	if !slices.Contains(args, "--value-stdin") || args[len(args)-1] != `["db"]["password"]` {
+		t.Errorf("Expected sops set --value-stdin with the path last, got %v", args)
+	}
+	stdin, err := os.ReadFile(stdinPath)
+	if err != nil {
+		t.Fatalf("Failed to read the stdin of sops: %v", err)
+	}
+	if string(stdin) != `"`+secret+`"` {
+		t.Errorf("Expected the JSON-encoded value on stdin, got %q", stdin)
+	}
+
+	// sops before 3.11.0 can't read the value from stdin, so it isn't run at all
+	sopsVersion = func(string) (string, bool) { return "sops 3.10.2", true }
+	args = nil
+	if err := PutValue(filePath, keyPath, `["db"]["password"]`, []byte(secret), false, NoDocument, false); err == nil || !strings.Contains(err.Error(), "3.11.0") {
+		t.Errorf("Expected PutValue to require sops 3.11.0, got %v", err)
+	}
+	if args != nil {
+		t.Errorf("Expected sops not to run, got %v", args)
+	}
+}
@@ -0,0 +1,79 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"simple-sops/internal/config"
+	"simple-sops/internal/timing"
+	"simple-sops/pkg/logging"
+)
+
+// ReencryptFile encrypts a file that was decrypted in-place with its .sops.yaml rule
+// Unlike EncryptFile, the rule is not changed: sops applies the recipients and
+// encrypted_regex the file was encrypted with before.
+func ReencryptFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if config.IsEncryptedData(data) {
+		return fmt.Errorf("%s is already encrypted", filePath)
+	}
+
+	configPath, err := getSopsConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	stopTiming := timing.Track(filePath, timing.ConfigIO)
+	effective, found, err := config.ResolveRule(configPath, filePath)
+	stopTiming()
+	if err != nil {
+		return fmt.Errorf("failed to load SOPS config: %w", err)
+	}
+
+	if !found {
+		return fmt.Errorf("no rule in %s matches %s, use encrypt instead", configPath, filePath)
+	}
+	rule := effective.Rule
+	if config.IsWildcardRule(rule.PathRegex) {
+		logging.Warn("Only the catch-all rule matches %s, its recipients are used", filePath)
+	}
+
+	sopsConfigPath, cleanup, err := sopsConfigFor(configPath, effective)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	logging.Info("Encrypting %s with rule %s...", filePath, rule.PathRegex)
+	cmd := execCommand("sops", sopsArgs(filePath, "--config", sopsConfigPath, "--encrypt", "--in-place")...)
+	if skipCommand(cmd, filePath) {
+		return nil
+	}
+
+	restore, err := prepareFile(filePath)
+	if err != nil {
+		return err
+	}
+	stopTiming = timing.Track(filePath, timing.SopsExec)
+	output, err := cmd.CombinedOutput()
+	stopTiming()
+	if err != nil {
+		restore()
+		return sopsFailed(fmt.Errorf("failed to encrypt file: %s\n%s", err, string(output)))
+	}
+
+	ForgetDecrypted(filePath)
+	logging.Success("File encrypted successfully: %s", filePath)
+	logging.Record(filePath, "encrypt", logging.ResultOK)
+	return nil
+}
+
+// ReencryptFiles encrypts multiple files that were decrypted in-place, up to jobs files at once
+func ReencryptFiles(filePaths []string, jobs int) error {
+	if len(filePaths) == 0 {
+		return fmt.Errorf("no files specified")
+	}
+
+	return forEachFile(filePaths, jobs, "encrypt", ReencryptFile)
+}
@@ -0,0 +1,96 @@
+package encrypt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"slices"
+	"testing"
+)
+
+func TestReencryptFile(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	sopsConfig := "creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1alice,age1bob\n    encrypted_regex: ^password$\n"
+	if err := os.WriteFile(configPath, []byte(sopsConfig), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+
+	dir := filepath.Dir(configPath)
+	secrets := filepath.Join(dir, "secrets.yaml")
+	other := filepath.Join(dir, "other.yaml")
+	for _, path := range []string{secrets, other} {
+		if err := os.WriteFile(path, []byte("password: s3cret\n"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	if err := ReencryptFile(secrets); err != nil {
+		t.Fatalf("ReencryptFile failed: %v", err)
+	}
+
+	// sops applies the rule, no recipients are passed
+	expected := []string{"--config", configPath, "--encrypt", "--in-place", secrets}
+	if lastExecCommand.cmd != "sops" || !slices.Equal(lastExecCommand.args, expected) {
+		t.Errorf("Expected sops %v, got %s %v", expected, lastExecCommand.cmd, lastExecCommand.args)
+	}
+
+	// The rule is left alone
+	data, err := os.ReadFile(configPath)
+	if err != nil || string(data) != sopsConfig {
+		t.Errorf("Expected .sops.yaml to be unchanged, got:\n%s", data)
+	}
+
+	if err := ReencryptFile(other); err == nil {
+		t.Error("Expected error for a file without a rule, got nil")
+	}
+
+	if err := os.WriteFile(secrets, []byte("password: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.9.0\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", secrets, err)
+	}
+	if err := ReencryptFile(secrets); err == nil {
+		t.Error("Expected error for an encrypted file, got nil")
+	}
+}
+
+func TestReencryptFileWithSourceConfig(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+	canonical := "creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1canonical\n"
+	sopsConfigSourceFor = func(path string) ([]byte, bool) {
+		return []byte(canonical), path == configPath
+	}
+	defer func() { sopsConfigSourceFor = config.SopsConfigSourceFor }()
+
+	// sops gets a copy of the canonical rules instead of the local .sops.yaml
+	var passed string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		lastExecCommand.cmd = command
+		lastExecCommand.args = args
+		if len(args) > 1 && args[0] == "--config" {
+			data, _ := os.ReadFile(args[1])
+			passed = string(data)
+		}
+		return exec.Command("true")
+	}
+
+	secrets := filepath.Join(filepath.Dir(configPath), "secrets.yaml")
+	if err := os.WriteFile(secrets, []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", secrets, err)
+	}
+	if err := ReencryptFile(secrets); err != nil {
+		t.Fatalf("ReencryptFile failed: %v", err)
+	}
+	if lastExecCommand.args[1] == configPath || passed != canonical {
+		t.Errorf("Expected sops to get the canonical rules, got %v with:\n%s", lastExecCommand.args, passed)
+	}
+	if _, err := os.Stat(lastExecCommand.args[1]); !os.IsNotExist(err) {
+		t.Errorf("Expected the copy of the rules to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultRegistryFile is the default path for the registry of decrypted files
+	DefaultRegistryFile = "~/.config/simple-sops/decrypted.yaml"
+)
+
+// Use a variable for the registry path to allow overriding it in tests
+var registryPath = DefaultRegistryFile
+
+// registryMu serializes updates of the registry by concurrent bulk operations
+var registryMu sync.Mutex
+
+// DecryptedFile is a plaintext file written by simple-sops
+type DecryptedFile struct {
+	// Path is the absolute path of the plaintext file
+	Path string `yaml:"path"`
+	// Source is the encrypted file a copy was decrypted from, empty for files decrypted in-place
+	Source string `yaml:"source,omitempty"`
+	// DecryptedAt is when the file was decrypted
+	DecryptedAt time.Time `yaml:"decrypted_at"`
+}
+
+// DecryptedRegistry is the local list of plaintext files written by simple-sops
+type DecryptedRegistry struct {
+	Files []DecryptedFile `yaml:"files"`
+}
+
+// LoadDecryptedRegistry loads the registry from a file
+// A missing file results in an empty registry
+func LoadDecryptedRegistry(path string) (*DecryptedRegistry, error) {
+	expandedPath, err := keymgmt.ExpandPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	data, err := os.ReadFile(expandedPath)
+	if os.IsNotExist(err) {
+		return &DecryptedRegistry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry of decrypted files: %w", err)
+	}
+
+	var registry DecryptedRegistry
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse registry of decrypted files: %w", err)
+	}
+
+	return &registry, nil
+}
+
+// SaveDecryptedRegistry saves the registry to a file
+func SaveDecryptedRegistry(path string, registry *DecryptedRegistry) error {
+	expandedPath, err := keymgmt.ExpandPath(path)
+	if err != nil {
+		return fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(expandedPath), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(registry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry of decrypted files: %w", err)
+	}
+
+	if err := os.WriteFile(expandedPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write registry of decrypted files: %w", err)
+	}
+
+	return nil
+}
+
+// Add records a decrypted file, replacing an earlier record of the same path
+func (r *DecryptedRegistry) Add(file DecryptedFile) {
+	r.Remove(file.Path)
+	r.Files = append(r.Files, file)
+}
+
+// Remove forgets a decrypted file
+func (r *DecryptedRegistry) Remove(path string) {
+	files := r.Files[:0]
+	for _, file := range r.Files {
+		if file.Path != path {
+			files = append(files, file)
+		}
+	}
+	r.Files = files
+}
+
+// Under returns the decrypted files below a directory
+func (r *DecryptedRegistry) Under(root string) []DecryptedFile {
+	var files []DecryptedFile
+	for _, file := range r.Files {
+		rel, err := filepath.Rel(root, file.Path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// recordDecrypted adds a plaintext file to the registry
+// Files in simple-sops temporary directories are removed by simple-sops itself
+// and not recorded. Failures are only logged, they don't fail the decryption.
+func recordDecrypted(path string, source string) {
+	absPath, err := filepath.Abs(path)
+	if err != nil || keymgmt.IsInTempDir(absPath) {
+		return
+	}
+	if source != "" {
+		if absSource, err := filepath.Abs(source); err == nil {
+			source = absSource
+		}
+	}
+
+	updateRegistry(func(registry *DecryptedRegistry) {
+		registry.Add(DecryptedFile{Path: absPath, Source: source, DecryptedAt: time.Now().UTC()})
+	})
+}
+
+// ForgetDecrypted removes a plaintext file from the registry, e.g. after deleting it
+func ForgetDecrypted(path string) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	updateRegistry(func(registry *DecryptedRegistry) {
+		registry.Remove(absPath)
+	})
+}
+
+// updateRegistry loads, changes and saves the registry, logging failures
+// Updates are serialized, so files processed at once don't overwrite each other's records.
+func updateRegistry(update func(registry *DecryptedRegistry)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry, err := LoadDecryptedRegistry(registryPath)
+	if err != nil {
+		logging.Debug("Failed to load registry of decrypted files: %v", err)
+		return
+	}
+	update(registry)
+	if err := SaveDecryptedRegistry(registryPath, registry); err != nil {
+		logging.Debug("Failed to save registry of decrypted files: %v", err)
+	}
+}
+
+// CleanDecrypted re-encrypts or deletes the registered plaintext files below root
+// Files decrypted in-place are encrypted again with the recipients of their
+// .sops.yaml rule, decrypted copies are overwritten and deleted. Files that no
+// longer exist or are encrypted already are dropped from the registry. An
+// empty root cleans every registered file. Returns the files that were left
+// in plaintext or would be cleaned in dry-run mode.
+func CleanDecrypted(root string) ([]DecryptedFile, error) {
+	registry, err := LoadDecryptedRegistry(registryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := registry.Files
+	if root != "" {
+		files = registry.Under(root)
+	}
+
+	var remaining []DecryptedFile
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		switch {
+		case os.IsNotExist(err), err == nil && config.IsEncryptedData(data):
+			logging.Debug("Dropping %s from the registry of decrypted files", file.Path)
+		case err != nil:
+			logging.Error("Failed to read %s: %v", file.Path, err)
+			remaining = append(remaining, file)
+			continue
+		case dryRun && file.Source == "":
+			PrintDryRun("Would encrypt: %s", file.Path)
+			remaining = append(remaining, file)
+			continue
+		case dryRun:
+			PrintDryRun("Would remove: %s (decrypted from %s)", file.Path, file.Source)
+			remaining = append(remaining, file)
+			continue
+		case file.Source == "":
+			if err := ReencryptFile(file.Path); err != nil {
+				logging.Error("Failed to encrypt %s: %v", file.Path, err)
+				logging.Record(file.Path, "encrypt", logging.ResultFailed)
+				remaining = append(remaining, file)
+				continue
+			}
+		default:
+			if err := keymgmt.RemoveSecurely(file.Path); err != nil {
+				logging.Error("Failed to remove %s: %v", file.Path, err)
+				logging.Record(file.Path, "remove", logging.ResultFailed)
+				remaining = append(remaining, file)
+				continue
+			}
+			logging.Success("Removed decrypted copy %s", file.Path)
+			logging.Record(file.Path, "remove", logging.ResultOK)
+		}
+		registry.Remove(file.Path)
+	}
+
+	if dryRun {
+		return remaining, nil
+	}
+	if err := SaveDecryptedRegistry(registryPath, registry); err != nil {
+		return remaining, err
+	}
+	return remaining, nil
+}
@@ -0,0 +1,135 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecryptedRegistry(t *testing.T) {
+	_, _, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	recordDecrypted(filepath.Join(repo, "secrets.yaml"), "")
+	recordDecrypted(filepath.Join(repo, "plain.env"), filepath.Join(repo, "secrets.env"))
+	recordDecrypted("/elsewhere/config.yaml", "")
+	recordDecrypted(filepath.Join(repo, "secrets.yaml"), "")
+
+	// Files in simple-sops temporary directories are not recorded
+	recordDecrypted(filepath.Join(os.TempDir(), "simple-sops-123", "secrets.yaml.plain"), "")
+
+	registry, err := LoadDecryptedRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("LoadDecryptedRegistry failed: %v", err)
+	}
+	if len(registry.Files) != 3 {
+		t.Fatalf("Expected 3 recorded files, got %+v", registry.Files)
+	}
+
+	under := registry.Under(repo)
+	if len(under) != 2 || under[0].Path != filepath.Join(repo, "plain.env") || under[0].Source != filepath.Join(repo, "secrets.env") {
+		t.Errorf("Expected the 2 files of the repository, decrypted copy first, got %+v", under)
+	}
+
+	ForgetDecrypted(filepath.Join(repo, "plain.env"))
+	registry, _ = LoadDecryptedRegistry(registryPath)
+	if len(registry.Under(repo)) != 1 {
+		t.Errorf("Expected the forgotten file to be removed, got %+v", registry.Files)
+	}
+}
+
+func TestDecryptedRegistryConcurrent(t *testing.T) {
+	_, _, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	var paths []string
+	for i := range 50 {
+		paths = append(paths, filepath.Join(repo, fmt.Sprintf("secrets%d.yaml", i)))
+	}
+	err := forEachFile(paths, 8, "record", func(path string) error {
+		recordDecrypted(path, "")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachFile failed: %v", err)
+	}
+
+	registry, err := LoadDecryptedRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("LoadDecryptedRegistry failed: %v", err)
+	}
+	if len(registry.Files) != len(paths) {
+		t.Errorf("Expected %d recorded files, got %d", len(paths), len(registry.Files))
+	}
+}
+
+func TestCleanDecrypted(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := filepath.Dir(configPath)
+	if err := os.WriteFile(configPath, []byte("creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+	inPlace := filepath.Join(repo, "secrets.yaml")
+	decryptedCopy := filepath.Join(repo, "plain.env")
+	encrypted := filepath.Join(repo, "encrypted.yaml")
+	for path, content := range map[string]string{
+		inPlace:       "password: s3cret\n",
+		decryptedCopy: "PASSWORD=s3cret\n",
+		encrypted:     "password: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.9.0\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	recordDecrypted(inPlace, "")
+	recordDecrypted(decryptedCopy, filepath.Join(repo, "secrets.env"))
+	recordDecrypted(encrypted, "")
+	recordDecrypted(filepath.Join(repo, "deleted.yaml"), "")
+
+	// A dry run lists what would be cleaned
+	var output bytes.Buffer
+	dryRunOutput = &output
+	SetDryRun(true)
+	remaining, err := CleanDecrypted(repo)
+	if err != nil {
+		t.Fatalf("CleanDecrypted failed: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Expected 2 files to clean, got %+v", remaining)
+	}
+	for _, expected := range []string{"Would encrypt: " + inPlace, "Would remove: " + decryptedCopy} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected dry run output to contain %q, got:\n%s", expected, output.String())
+		}
+	}
+	if _, err := os.Stat(decryptedCopy); err != nil {
+		t.Errorf("Expected dry run to keep %s", decryptedCopy)
+	}
+	SetDryRun(false)
+
+	remaining, err = CleanDecrypted(repo)
+	if err != nil {
+		t.Fatalf("CleanDecrypted failed: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("Expected all files to be cleaned, got %+v", remaining)
+	}
+	if lastExecCommand.cmd != "sops" || !strings.Contains(strings.Join(lastExecCommand.args, " "), "--encrypt --in-place") {
+		t.Errorf("Expected sops to encrypt the file in-place, got %v", lastExecCommand)
+	}
+	if _, err := os.Stat(decryptedCopy); !os.IsNotExist(err) {
+		t.Errorf("Expected the decrypted copy to be removed")
+	}
+
+	registry, _ := LoadDecryptedRegistry(registryPath)
+	if len(registry.Files) != 0 {
+		t.Errorf("Expected an empty registry, got %+v", registry.Files)
+	}
+}
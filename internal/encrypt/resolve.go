@@ -0,0 +1,241 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/git"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// conflictSides name the versions of a conflicted file, like git mergetool
+var conflictSides = []string{"LOCAL", "BASE", "REMOTE"}
+
+// ResolveConflict resolves a merge conflict in an encrypted file on the plaintext
+// Both sides and the common ancestor are read from the git index and
+// decrypted, then merged with git merge-file. Remaining conflicts are
+// resolved with tool, or in $EDITOR without one. The resolution is encrypted
+// with the file's rule in .sops.yaml. tool runs in a shell with $LOCAL, $BASE,
+// $REMOTE and $MERGED set to the plaintext files, e.g. "vimdiff $LOCAL $MERGED $REMOTE".
+func ResolveConflict(filePath string, keyFile string, tool string, alwaysUseOnePassword bool) error {
+	conflicted, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if !git.HasConflictMarkers(conflicted) {
+		return fmt.Errorf("%s has no conflict markers", filePath)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	root, relPath, err := repoRelativePath(filePath)
+	if err != nil {
+		return err
+	}
+	stages, err := git.ReadConflictStages(root, relPath)
+	if err != nil {
+		return err
+	}
+
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	tempDir, err := keymgmt.CreateTempDir()
+	if err != nil {
+		return err
+	}
+	defer keymgmt.RemoveSecurely(tempDir)
+
+	// Keep the file name in every version, so sops and editors detect the format
+	paths := make(map[string]string)
+	for i, encrypted := range [][]byte{stages.Ours, stages.Base, stages.Theirs} {
+		side := conflictSides[i]
+		plaintextPath, err := decryptConflictSide(tempDir, side, filePath, encrypted, keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the %s version: %w", side, err)
+		}
+		paths[side] = plaintextPath
+	}
+
+	merged, conflicts, err := git.MergeFiles(paths["LOCAL"], paths["BASE"], paths["REMOTE"])
+	if err != nil {
+		return err
+	}
+	paths["MERGED"] = filepath.Join(tempDir, "MERGED", filepath.Base(filePath))
+	if err := os.MkdirAll(filepath.Dir(paths["MERGED"]), 0700); err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := WritePlaintextFile(paths["MERGED"], merged); err != nil {
+		return fmt.Errorf("failed to write merged file: %w", err)
+	}
+
+	if conflicts == 0 && tool == "" {
+		logging.Info("The decrypted versions of %s merged without conflicts.", filePath)
+	} else {
+		logging.Info("Resolving %d conflicts in %s...", conflicts, filePath)
+		if err := runMergeTool(tool, paths); err != nil {
+			return err
+		}
+	}
+
+	resolution, err := os.ReadFile(paths["MERGED"])
+	if err != nil {
+		return fmt.Errorf("failed to read merged file: %w", err)
+	}
+	if git.HasConflictMarkers(resolution) {
+		return fmt.Errorf("the resolution still has conflict markers, %s is unchanged", filePath)
+	}
+
+	// The conflicted file is only replaced once the resolution is encrypted
+	if err := encryptResolution(filePath, paths["MERGED"], tempDir, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	logging.Success("Resolved %s. Run 'git add %s' to mark it resolved.", filePath, filePath)
+	logging.Record(filePath, "resolve", logging.ResultOK)
+	return nil
+}
+
+// repoRelativePath returns the repository root of a file and its path relative to it
+func repoRelativePath(filePath string) (string, string, error) {
+	root, ok := git.RepoRoot(filePath)
+	if !ok {
+		return "", "", fmt.Errorf("%s is not in a git repository", filePath)
+	}
+
+	// git prints the root with symlinks resolved
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve %s: %w", filePath, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", "", fmt.Errorf("%s is outside of the repository %s", filePath, root)
+	}
+	return root, filepath.ToSlash(relPath), nil
+}
+
+// encryptResolution encrypts the resolved plaintext and replaces the conflicted file with it
+// The plaintext never leaves the temporary directory: sops writes the
+// encrypted file to stdout, which is written next to filePath and renamed
+// over it, so filePath keeps the conflict markers if encryption fails.
+func encryptResolution(filePath string, plaintextPath string, tempDir string, mode os.FileMode) error {
+	configPath, err := getSopsConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+	effective, found, err := config.ResolveRule(configPath, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to load SOPS config: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("no rule in %s matches %s, %s is unchanged", configPath, filePath, filePath)
+	}
+
+	// The plaintext isn't at the path of the rule, so sops gets only the rule
+	mergedConfigPath := filepath.Join(tempDir, ".sops.yaml")
+	if err := config.SaveSopsConfig(mergedConfigPath, effective.MergedSopsConfig()); err != nil {
+		return err
+	}
+
+	logging.Info("Encrypting %s with rule %s...", filePath, effective.Rule.PathRegex)
+	cmd := execCommand("sops", sopsArgs(plaintextPath, "--config", mergedConfigPath, "--encrypt")...)
+	if skipCommand(cmd, filePath) {
+		return nil
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	encrypted, err := cmd.Output()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to encrypt the resolution, %s is unchanged: %s\n%s", filePath, err, stderr.String()))
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(encrypted); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	if err := os.Rename(temp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// decryptConflictSide decrypts one version of a conflicted file into the temporary directory
+// A missing version, e.g. the base of a file added on both sides, is empty.
+func decryptConflictSide(tempDir string, side string, filePath string, encrypted []byte, keyPath string) (string, error) {
+	name := filepath.Base(filePath)
+	plaintextPath := filepath.Join(tempDir, side, name)
+	if err := os.MkdirAll(filepath.Dir(plaintextPath), 0700); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if encrypted == nil {
+		return plaintextPath, WritePlaintextFile(plaintextPath, nil)
+	}
+
+	encryptedPath := filepath.Join(tempDir, side, "encrypted", name)
+	if err := os.MkdirAll(filepath.Dir(encryptedPath), 0700); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := os.WriteFile(encryptedPath, encrypted, 0600); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	plaintext, err := DecryptToBytes(encryptedPath, keyPath)
+	if err != nil {
+		return "", err
+	}
+	return plaintextPath, WritePlaintextFile(plaintextPath, plaintext)
+}
+
+// runMergeTool resolves the conflicts in the merged file with a merge tool or $EDITOR
+func runMergeTool(tool string, paths map[string]string) error {
+	var args []string
+	if tool != "" {
+		args = []string{"sh", "-c", tool}
+	} else {
+		args = strings.Fields(os.Getenv("EDITOR"))
+		if len(args) == 0 {
+			args = []string{"vi"}
+		}
+		args = append(args, paths["MERGED"])
+	}
+
+	cmd := execCommand(args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	for side, path := range paths {
+		cmd.Env = append(cmd.Env, side+"="+path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("merge tool failed: %w", err)
+	}
+	return nil
+}
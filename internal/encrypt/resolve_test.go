@@ -0,0 +1,74 @@
+package encrypt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptResolution(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	sopsConfig := "creation_rules:\n  - path_regex: (^|/)secrets\\.yaml$\n    age: age1alice\n"
+	if err := os.WriteFile(configPath, []byte(sopsConfig), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+
+	dir := filepath.Dir(configPath)
+	secrets := filepath.Join(dir, "secrets.yaml")
+	conflicted := "<<<<<<< ours\npassword: ENC[a]\n=======\npassword: ENC[b]\n>>>>>>> theirs\n"
+	if err := os.WriteFile(secrets, []byte(conflicted), 0640); err != nil {
+		t.Fatalf("Failed to write %s: %v", secrets, err)
+	}
+
+	tempDir := t.TempDir()
+	merged := filepath.Join(tempDir, "secrets.yaml")
+	if err := os.WriteFile(merged, []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", merged, err)
+	}
+
+	// A failed encryption leaves the conflicted file alone
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if err := encryptResolution(secrets, merged, tempDir, 0640); err == nil {
+		t.Fatal("Expected error when sops fails, got nil")
+	}
+	data, err := os.ReadFile(secrets)
+	if err != nil || string(data) != conflicted {
+		t.Errorf("Expected %s to be unchanged, got:\n%s", secrets, data)
+	}
+
+	encrypted := "password: ENC[c]\nsops:\n  version: 3.9.0\n"
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		lastExecCommand = mockExecCommand{cmd: command, args: args}
+		return exec.Command("printf", "%s", encrypted)
+	}
+	if err := encryptResolution(secrets, merged, tempDir, 0640); err != nil {
+		t.Fatalf("encryptResolution failed: %v", err)
+	}
+	data, err = os.ReadFile(secrets)
+	if err != nil || string(data) != encrypted {
+		t.Errorf("Expected %s to hold the encrypted resolution, got:\n%s", secrets, data)
+	}
+	info, err := os.Stat(secrets)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected %s to keep mode 0640, got %v", secrets, info.Mode().Perm())
+	}
+
+	// sops encrypts the plaintext in the temporary directory, never next to the file
+	if args := lastExecCommand.args; args[len(args)-1] != merged {
+		t.Errorf("Expected sops to encrypt %s, got %v", merged, args)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".tmp" {
+			t.Errorf("Expected no temporary file to be left behind, found %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/timing"
+	"simple-sops/pkg/logging"
+	"sync"
+	"time"
+)
+
+// rotationsMu serializes updates of the rotation times by files rotated at once
+var rotationsMu sync.Mutex
+
+// RotateFile rotates the data key of an encrypted file
+func RotateFile(filePath string, keyFile string) error {
+	var rotator *libraryRotator
+	if !dryRun {
+		var err error
+		if rotator, err = newLibraryRotator(keyFile); err != nil {
+			return err
+		}
+	}
+	return rotateFile(rotator, filePath)
+}
+
+// rotateFile rotates the data key of an encrypted file with rotator
+// In dry-run mode the equivalent sops command is printed instead.
+func rotateFile(rotator *libraryRotator, filePath string) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	logging.Info("Rotating %s...", filePath)
+
+	if skipCommand(exec.Command("sops", sopsArgs(filePath, "--rotate", "--in-place")...), filePath) {
+		return nil
+	}
+
+	stopTiming := timing.Track(filePath, timing.SopsExec)
+	err := rotator.Rotate(filePath)
+	stopTiming()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to rotate file: %w", err))
+	}
+
+	recordRotation(filePath)
+	logging.Success("File rotated successfully: %s", filePath)
+	logging.Record(filePath, "rotate", logging.ResultOK)
+	return nil
+}
+
+// RotateFiles rotates the data keys of multiple files, up to jobs files at once
+// The files are rotated with the sops library in this process, so no sops
+// process is started per file.
+func RotateFiles(filePaths []string, keyFile string, alwaysUseOnePassword bool, jobs int) error {
+	if len(filePaths) == 0 {
+		return fmt.Errorf("no files specified")
+	}
+
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	var rotator *libraryRotator
+	if !dryRun {
+		if rotator, err = newLibraryRotator(keyPath); err != nil {
+			return err
+		}
+	}
+
+	// Process each file
+	return forEachFile(filePaths, jobs, "rotate", func(filePath string) error {
+		return rotateFile(rotator, filePath)
+	})
+}
+
+// UpdateKeys applies the recipients of the .sops.yaml rule to an encrypted file
+// The data key is kept, so removed recipients can still read the values they
+// saw before. Rotate the file afterwards to prevent that.
+func UpdateKeys(filePath string, keyFile string) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	configPath, err := getSopsConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine SOPS config path: %w", err)
+	}
+
+	effective, _, err := config.ResolveRule(configPath, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to load SOPS config: %w", err)
+	}
+	sopsConfigPath, cleanup, err := sopsConfigFor(configPath, effective)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	configPath = sopsConfigPath
+
+	logging.Info("Updating the recipients of %s...", filePath)
+
+	cmd := execCommand("sops", "--config", configPath, "updatekeys", "--yes", filePath)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+	if skipCommand(cmd, filePath) {
+		return nil
+	}
+
+	stopTiming := timing.Track(filePath, timing.SopsExec)
+	output, err := cmd.CombinedOutput()
+	stopTiming()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to update keys: %s\n%s", err, string(output)))
+	}
+
+	logging.Success("Recipients updated: %s", filePath)
+	logging.Record(filePath, "update-keys", logging.ResultOK)
+	return nil
+}
+
+// RotateRecipients rotates the data key of a file while changing its recipients
+// The old recipients are removed and the new Age recipients and KMS keys added
+// in one sops run, so it works even if keyFile holds one of the old keys.
+func RotateRecipients(filePath string, keyFile string, remove []string, add []string, kms []string) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	logging.Info("Rotating %s to new recipients...", filePath)
+
+	args := []string{"--rotate", "--in-place"}
+	for _, recipient := range remove {
+		args = append(args, "--rm-age", recipient)
+	}
+	for _, recipient := range add {
+		args = append(args, "--add-age", recipient)
+	}
+	for _, key := range kms {
+		args = append(args, "--add-kms", key)
+	}
+
+	cmd := execCommand("sops", sopsArgs(filePath, args...)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", keyFile))
+	if skipCommand(cmd, filePath) {
+		return nil
+	}
+
+	stopTiming := timing.Track(filePath, timing.SopsExec)
+	output, err := cmd.CombinedOutput()
+	stopTiming()
+	if err != nil {
+		return sopsFailed(fmt.Errorf("failed to rotate file: %s\n%s", err, string(output)))
+	}
+
+	recordRotation(filePath)
+	logging.Success("File rotated to new recipients: %s", filePath)
+	logging.Record(filePath, "rotate", logging.ResultOK)
+	return nil
+}
+
+// recordRotation records the rotation of a file for status and rotate --due
+// Failures are only logged, they don't fail the rotation.
+func recordRotation(filePath string) {
+	configPath, err := getSopsConfigPath()
+	if err != nil {
+		logging.Debug("Failed to determine SOPS config path: %v", err)
+		return
+	}
+	root := filepath.Dir(configPath)
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return
+	}
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return
+	}
+
+	rotationsMu.Lock()
+	defer rotationsMu.Unlock()
+
+	rotations, err := config.LoadRotations(root)
+	if err != nil {
+		logging.Warn("Failed to record the rotation of %s: %v", filePath, err)
+		return
+	}
+	rotations.Files[filepath.ToSlash(relPath)] = time.Now().UTC().Truncate(time.Second)
+	if err := config.SaveRotations(root, rotations); err != nil {
+		logging.Warn("Failed to record the rotation of %s: %v", filePath, err)
+	}
+}
@@ -0,0 +1,41 @@
+package encrypt
+
+import (
+	"path/filepath"
+	"simple-sops/internal/config"
+	"testing"
+)
+
+func TestRotateFileRecordsRotation(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	root := filepath.Dir(configPath)
+	keyPath, recipient := writeAgeKey(t, t.TempDir())
+	testFilePath := filepath.Join(root, "secrets.yaml")
+	writeEncryptedYAML(t, testFilePath, "password: s3cret\n", recipient)
+
+	// A dry run rotates nothing
+	dryRun = true
+	if err := RotateFile(testFilePath, keyPath); err != nil {
+		t.Fatalf("RotateFile failed: %v", err)
+	}
+	rotations, err := config.LoadRotations(root)
+	if err != nil {
+		t.Fatalf("LoadRotations failed: %v", err)
+	}
+	if len(rotations.Files) != 0 {
+		t.Errorf("Expected no rotation to be recorded in a dry run, got %v", rotations.Files)
+	}
+
+	dryRun = false
+	if err := RotateFile(testFilePath, keyPath); err != nil {
+		t.Fatalf("RotateFile failed: %v", err)
+	}
+	if rotations, err = config.LoadRotations(root); err != nil {
+		t.Fatalf("LoadRotations failed: %v", err)
+	}
+	if rotated, ok := rotations.Files[filepath.Base(testFilePath)]; !ok || rotated.IsZero() {
+		t.Errorf("Expected the rotation of %s to be recorded, got %v", testFilePath, rotations.Files)
+	}
+}
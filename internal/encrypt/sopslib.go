@@ -0,0 +1,140 @@
+package encrypt
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	sopsconfig "github.com/getsops/sops/v3/config"
+	"github.com/getsops/sops/v3/keyservice"
+)
+
+// libraryRotator rotates the data keys of files with the sops library instead of the sops command
+// Starting sops dominates the time spent on small files, so bulk rotations
+// share one rotator: the Age identities are parsed once and no process is
+// started per file. The result is written like sops --rotate --in-place.
+type libraryRotator struct {
+	keyServices []keyservice.KeyServiceClient
+	stores      *sopsconfig.StoresConfig
+}
+
+// newLibraryRotator creates a rotator decrypting Age keys with the identities in keyFile
+// Other key types, like KMS or PGP, are handled like the sops command does.
+func newLibraryRotator(keyFile string) (*libraryRotator, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	defer clear(data)
+
+	var identities sopsage.ParsedIdentities
+	if err := identities.Import(string(data)); err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", keyFile, err)
+	}
+
+	stores, err := loadStoresConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	server := ageKeyServer{identities: identities}
+	return &libraryRotator{
+		keyServices: []keyservice.KeyServiceClient{keyservice.NewCustomLocalClient(server)},
+		stores:      stores,
+	}, nil
+}
+
+// loadStoresConfig reads the stores settings, like the indentation, of the .sops.yaml sops would use
+// The rules of --config or --config-ref stand in for it, as they do when
+// sops is run.
+func loadStoresConfig() (*sopsconfig.StoresConfig, error) {
+	if configPath, err := getSopsConfigPath(); err == nil {
+		sourcePath, cleanup, err := sourceConfigFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		if sourcePath != "" {
+			return sopsconfig.LoadStoresConfig(sourcePath)
+		}
+	}
+
+	configPath, err := sopsconfig.FindConfigFile(".")
+	if err != nil {
+		// Like for sops, the config is optional
+		return sopsconfig.NewStoresConfig(), nil
+	}
+	stores, err := sopsconfig.LoadStoresConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", configPath, err)
+	}
+	return stores, nil
+}
+
+// Rotate gives a file a new data key, encrypted to the recipients it has
+func (r *libraryRotator) Rotate(filePath string) error {
+	store := common.StoreForFormat(formats.FormatForPathOrString(filePath, sopsInputType(filePath)), r.stores)
+	cipher := aes.NewCipher()
+
+	tree, err := common.LoadEncryptedFileWithBugFixes(common.GenericDecryptOpts{
+		Cipher:      cipher,
+		InputStore:  store,
+		InputPath:   filePath,
+		KeyServices: r.keyServices,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := common.DecryptTree(common.DecryptTreeOpts{
+		Cipher:      cipher,
+		Tree:        tree,
+		KeyServices: r.keyServices,
+	}); err != nil {
+		return err
+	}
+
+	dataKey, errs := tree.GenerateDataKeyWithKeyServices(r.keyServices)
+	if len(errs) > 0 {
+		return fmt.Errorf("could not generate data key: %v", errs)
+	}
+	defer clear(dataKey)
+	if err := common.EncryptTree(common.EncryptTreeOpts{DataKey: dataKey, Tree: tree, Cipher: cipher}); err != nil {
+		return err
+	}
+
+	encrypted, err := store.EmitEncryptedFile(*tree)
+	if err != nil {
+		return fmt.Errorf("could not marshal tree: %w", err)
+	}
+	// Like sops, an existing file keeps its permissions
+	return os.WriteFile(filePath, encrypted, 0600)
+}
+
+// ageKeyServer decrypts Age keys with parsed identities and other keys like the sops key service
+// The sops key service reads the identities from SOPS_AGE_KEY_FILE for every
+// key, which would need the process environment changed while files are
+// rotated at once.
+type ageKeyServer struct {
+	keyservice.Server
+	identities sopsage.ParsedIdentities
+}
+
+// Decrypt decrypts a data key
+func (s ageKeyServer) Decrypt(ctx context.Context, req *keyservice.DecryptRequest) (*keyservice.DecryptResponse, error) {
+	ageKey, ok := req.Key.KeyType.(*keyservice.Key_AgeKey)
+	if !ok {
+		return s.Server.Decrypt(ctx, req)
+	}
+
+	key := sopsage.MasterKey{Recipient: ageKey.AgeKey.Recipient, EncryptedKey: string(req.Ciphertext)}
+	s.identities.ApplyToMasterKey(&key)
+	plaintext, err := key.Decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return &keyservice.DecryptResponse{Plaintext: plaintext}, nil
+}
@@ -0,0 +1,209 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	sopsconfig "github.com/getsops/sops/v3/config"
+	"github.com/getsops/sops/v3/keys"
+	"github.com/getsops/sops/v3/stores/yaml"
+)
+
+// writeAgeKey writes a new Age identity to dir and returns its path and recipient
+func writeAgeKey(t testing.TB, dir string) (string, string) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate identity: %v", err)
+	}
+	keyPath := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(keyPath, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	return keyPath, identity.Recipient().String()
+}
+
+// writeEncryptedYAML encrypts plaintext to recipient with the sops library, like sops --encrypt does
+func writeEncryptedYAML(t testing.TB, filePath string, plaintext string, recipient string) {
+	store := yaml.NewStore(&sopsconfig.NewStoresConfig().YAML)
+	branches, err := store.LoadPlainFile([]byte(plaintext))
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", filePath, err)
+	}
+	masterKey, err := sopsage.MasterKeyFromRecipient(recipient)
+	if err != nil {
+		t.Fatalf("Invalid recipient: %v", err)
+	}
+
+	tree := sops.Tree{
+		Branches: branches,
+		Metadata: sops.Metadata{KeyGroups: []sops.KeyGroup{{keys.MasterKey(masterKey)}}, Version: "3.12.2"},
+		FilePath: filePath,
+	}
+	dataKey, errs := tree.GenerateDataKey()
+	if len(errs) > 0 {
+		t.Fatalf("Failed to generate data key: %v", errs)
+	}
+	if err := common.EncryptTree(common.EncryptTreeOpts{DataKey: dataKey, Tree: &tree, Cipher: aes.NewCipher()}); err != nil {
+		t.Fatalf("Failed to encrypt %s: %v", filePath, err)
+	}
+	encrypted, err := store.EmitEncryptedFile(tree)
+	if err != nil {
+		t.Fatalf("Failed to emit %s: %v", filePath, err)
+	}
+	if err := os.WriteFile(filePath, encrypted, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", filePath, err)
+	}
+}
+
+// decryptYAML decrypts a file with the sops library and returns its data key and plaintext
+func decryptYAML(t testing.TB, rotator *libraryRotator, filePath string) ([]byte, string) {
+	store := yaml.NewStore(&sopsconfig.NewStoresConfig().YAML)
+	tree, err := common.LoadEncryptedFile(store, filePath)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", filePath, err)
+	}
+	dataKey, err := common.DecryptTree(common.DecryptTreeOpts{Cipher: aes.NewCipher(), Tree: tree, KeyServices: rotator.keyServices})
+	if err != nil {
+		t.Fatalf("Failed to decrypt %s: %v", filePath, err)
+	}
+	plaintext, err := store.EmitPlainFile(tree.Branches)
+	if err != nil {
+		t.Fatalf("Failed to emit %s: %v", filePath, err)
+	}
+	return dataKey, string(plaintext)
+}
+
+func TestLibraryRotatorRotate(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, recipient := writeAgeKey(t, dir)
+	filePath := filepath.Join(dir, "secrets.yaml")
+	writeEncryptedYAML(t, filePath, "db:\n    password: s3cret\n", recipient)
+
+	rotator, err := newLibraryRotator(keyPath)
+	if err != nil {
+		t.Fatalf("newLibraryRotator failed: %v", err)
+	}
+	oldKey, _ := decryptYAML(t, rotator, filePath)
+
+	if err := rotator.Rotate(filePath); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	// The file has a new data key and the same values
+	newKey, plaintext := decryptYAML(t, rotator, filePath)
+	if string(newKey) == string(oldKey) {
+		t.Error("Expected a new data key")
+	}
+	if plaintext != "db:\n    password: s3cret\n" {
+		t.Errorf("Expected the values to be kept, got:\n%s", plaintext)
+	}
+
+	// Files the key can't decrypt are left alone
+	_, otherRecipient := writeAgeKey(t, t.TempDir())
+	otherPath := filepath.Join(dir, "other.yaml")
+	writeEncryptedYAML(t, otherPath, "a: b\n", otherRecipient)
+	before, _ := os.ReadFile(otherPath)
+	if err := rotator.Rotate(otherPath); err == nil {
+		t.Error("Expected an error for a file encrypted to another key")
+	}
+	if after, _ := os.ReadFile(otherPath); string(after) != string(before) {
+		t.Error("Expected the file encrypted to another key to be unchanged")
+	}
+}
+
+// benchmarkFiles writes count small encrypted files and returns the key and their paths
+func benchmarkFiles(b *testing.B, count int) (string, []string) {
+	dir := b.TempDir()
+	keyPath, recipient := writeAgeKey(b, dir)
+	files := make([]string, count)
+	for i := range files {
+		files[i] = filepath.Join(dir, fmt.Sprintf("secret%d.yaml", i))
+		writeEncryptedYAML(b, files[i], fmt.Sprintf("password: s3cret%d\n", i), recipient)
+	}
+	return keyPath, files
+}
+
+// BenchmarkRotateLibrary rotates 100 small files in this process, like rotate --all does
+func BenchmarkRotateLibrary(b *testing.B) {
+	keyPath, files := benchmarkFiles(b, 100)
+	b.ResetTimer()
+	for range b.N {
+		rotator, err := newLibraryRotator(keyPath)
+		if err != nil {
+			b.Fatalf("newLibraryRotator failed: %v", err)
+		}
+		for _, file := range files {
+			if err := rotator.Rotate(file); err != nil {
+				b.Fatalf("Rotate failed: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkRotateSopsCommand rotates 100 small files with one sops process per file, as before
+func BenchmarkRotateSopsCommand(b *testing.B) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		b.Skip("sops is not installed")
+	}
+	keyPath, files := benchmarkFiles(b, 100)
+	b.ResetTimer()
+	for range b.N {
+		for _, file := range files {
+			cmd := exec.Command("sops", sopsArgs(file, "--rotate", "--in-place")...)
+			cmd.Env = append(os.Environ(), "SOPS_AGE_KEY_FILE="+keyPath)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				b.Fatalf("sops failed: %v\n%s", err, output)
+			}
+		}
+	}
+}
+
+func TestLibraryRotatorSourceConfig(t *testing.T) {
+	_, _, configPath, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// The rules of --config set the indentation instead of the local .sops.yaml
+	if err := os.WriteFile(configPath, []byte("stores:\n  yaml:\n    indent: 4\n"), 0644); err != nil {
+		t.Fatalf("Failed to write SOPS config: %v", err)
+	}
+	sopsConfigSourceFor = func(path string) ([]byte, bool) {
+		return []byte("stores:\n  yaml:\n    indent: 2\n"), path == configPath
+	}
+	defer func() { sopsConfigSourceFor = config.SopsConfigSourceFor }()
+
+	dir := filepath.Dir(configPath)
+	keyPath, recipient := writeAgeKey(t, t.TempDir())
+	filePath := filepath.Join(dir, "secrets.yaml")
+	writeEncryptedYAML(t, filePath, "db:\n    password: s3cret\n", recipient)
+
+	rotator, err := newLibraryRotator(keyPath)
+	if err != nil {
+		t.Fatalf("newLibraryRotator failed: %v", err)
+	}
+	if err := rotator.Rotate(filePath); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", filePath, err)
+	}
+	if !strings.HasPrefix(string(data), "db:\n  password: ENC[") {
+		t.Errorf("Expected the indentation of the --config rules, got:\n%s", data)
+	}
+
+	// The copy of the rules is removed again
+	copies, _ := filepath.Glob(filepath.Join(dir, ".sops.yaml.source-*"))
+	if len(copies) != 0 {
+		t.Errorf("Expected the copy of the rules to be removed, got %v", copies)
+	}
+}
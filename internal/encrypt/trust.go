@@ -0,0 +1,55 @@
+package encrypt
+
+import (
+	"fmt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+var (
+	// strictRecipients makes encryption fail for recipients missing from the trust store
+	strictRecipients bool
+
+	// Use a variable for the trust store path to allow overriding in tests
+	trustStorePath = keymgmt.DefaultTrustStoreFile
+)
+
+// SetStrictRecipients enables or disables failing on untrusted recipients
+func SetStrictRecipients(strict bool) {
+	strictRecipients = strict
+}
+
+// checkRecipientsTrusted warns about (or, in strict mode, rejects) recipients
+// that are not listed in the local trust store
+func checkRecipientsTrusted(pubKeys []string) error {
+	store, err := keymgmt.LoadTrustStore(trustStorePath)
+	if err != nil {
+		if strictRecipients {
+			return fmt.Errorf("failed to load trust store: %w", err)
+		}
+		logging.Debug("Failed to load trust store: %v", err)
+		return nil
+	}
+
+	unknown := store.UnknownKeys(pubKeys)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	if strictRecipients {
+		return fmt.Errorf("untrusted recipients: %s (add them with 'simple-sops trust add')", strings.Join(unknown, ", "))
+	}
+
+	// Without any trusted keys every recipient would be unknown, so only warn once the store is in use
+	if len(store.Keys) == 0 {
+		logging.Debug("Trust store is empty, skipping recipient checks")
+		return nil
+	}
+
+	for _, pubKey := range unknown {
+		logging.Warn("Recipient %s is not in the trust store", pubKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/pkg/logging"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Use a variable for the pager lookup to allow mocking in tests
+var lookPath = exec.LookPath
+
+// pagerCommand returns the pager to view decrypted files with
+// $PAGER is used if set, otherwise less or more.
+func pagerCommand() ([]string, error) {
+	if pager := strings.Fields(os.Getenv("PAGER")); len(pager) > 0 {
+		return pager, nil
+	}
+	for _, pager := range []string{"less", "more"} {
+		if _, err := lookPath(pager); err == nil {
+			return []string{pager}, nil
+		}
+	}
+	return nil, fmt.Errorf("no pager found, install less or set $PAGER")
+}
+
+// isTerminal checks if a file is a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ViewFile shows a decrypted file in a pager without writing it to disk
+// The plaintext only exists in memory and is passed to the pager through a
+// pipe. less is started in secure mode, so it can't save the content to a
+// log file or open an editor. After the timeout the pager is closed.
+func ViewFile(filePath string, keyFile string, alwaysUseOnePassword bool, timeout time.Duration) error {
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	// A redirected view would write the plaintext to wherever stdout goes
+	if !isTerminal(os.Stdout) {
+		return fmt.Errorf("view only writes to a terminal, use 'decrypt --stdout' to pipe decrypted content")
+	}
+
+	pager, err := pagerCommand()
+	if err != nil {
+		return err
+	}
+
+	// Ensure we have the key available
+	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
+	if err != nil {
+		return err
+	}
+
+	// Clean up the key if it's temporary
+	if isTemp {
+		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
+	}
+
+	plaintext, err := DecryptToBytes(filePath, keyPath)
+	if err != nil {
+		return err
+	}
+	defer keymgmt.Wipe(plaintext)
+
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "LESSSECURE=1", "LESSHISTFILE=-")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start pager: %w", err)
+	}
+
+	// Close the view when the timeout expires, e.g. when the terminal was left open
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			cmd.Process.Signal(terminateSignal)
+		})
+		defer timer.Stop()
+	}
+
+	err = cmd.Wait()
+	if timedOut.Load() {
+		logging.Info("Closed the view of %s after %s.", filePath, timeout)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("pager failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package encrypt
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+
+	installed := map[string]bool{"more": true}
+	lookPath = func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	// $PAGER takes precedence, with its arguments
+	t.Setenv("PAGER", "less -S")
+	if pager, err := pagerCommand(); err != nil || !reflect.DeepEqual(pager, []string{"less", "-S"}) {
+		t.Errorf("Expected $PAGER, got %v, %v", pager, err)
+	}
+
+	// Otherwise less is preferred over more
+	t.Setenv("PAGER", "")
+	if pager, err := pagerCommand(); err != nil || !reflect.DeepEqual(pager, []string{"more"}) {
+		t.Errorf("Expected more, got %v, %v", pager, err)
+	}
+	installed["less"] = true
+	if pager, err := pagerCommand(); err != nil || !reflect.DeepEqual(pager, []string{"less"}) {
+		t.Errorf("Expected less, got %v, %v", pager, err)
+	}
+
+	installed = map[string]bool{}
+	if _, err := pagerCommand(); err == nil {
+		t.Error("Expected error without a pager")
+	}
+}
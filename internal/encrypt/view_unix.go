@@ -0,0 +1,8 @@
+//go:build !windows
+
+package encrypt
+
+import "syscall"
+
+// terminateSignal asks the pager to quit and restore the terminal
+var terminateSignal = syscall.SIGTERM
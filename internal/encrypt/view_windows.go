@@ -0,0 +1,8 @@
+//go:build windows
+
+package encrypt
+
+import "os"
+
+// terminateSignal ends the pager, Windows cannot deliver other signals
+var terminateSignal = os.Kill
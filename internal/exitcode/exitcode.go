@@ -0,0 +1,61 @@
+package exitcode
+
+import "errors"
+
+// Exit codes of simple-sops
+// Scripts can rely on them, so existing codes must never change meaning.
+const (
+	// OK means the command succeeded
+	OK = 0
+	// General is any error without a more specific code, e.g. invalid arguments
+	General = 1
+	// Config means the application config or .sops.yaml is missing or invalid
+	Config = 2
+	// Key means no Age key is available, e.g. the key file or 1Password item is missing
+	Key = 3
+	// Sops means sops failed, e.g. because the key can't decrypt the file
+	Sops = 4
+	// Verification means a check found problems, e.g. plaintext covered by a rule
+	Verification = 5
+	// Partial means a bulk operation failed for some files and succeeded for others
+	Partial = 6
+)
+
+// Error is an error with the exit code it should end simple-sops with
+type Error struct {
+	Code int
+	Err  error
+}
+
+// Error returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap attaches an exit code to an error
+// nil stays nil, so results can be wrapped without checking them first.
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Code returns the exit code for an error
+// The outermost code wins, so a partial failure of sops runs is reported as
+// partial. Errors without a code exit with General.
+func Code(err error) int {
+	if err == nil {
+		return OK
+	}
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return General
+}
@@ -0,0 +1,34 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	sopsErr := Wrap(Sops, errors.New("sops failed"))
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"nil", nil, OK},
+		{"plain", errors.New("invalid argument"), General},
+		{"wrapped", fmt.Errorf("failed to decrypt: %w", sopsErr), Sops},
+		{"outermost wins", Wrap(Partial, fmt.Errorf("1 of 3 files failed: %w", sopsErr)), Partial},
+	}
+	for _, test := range tests {
+		if code := Code(test.err); code != test.code {
+			t.Errorf("%s: Code() = %d, want %d", test.name, code, test.code)
+		}
+	}
+
+	if Wrap(Config, nil) != nil {
+		t.Error("Wrap(nil) should stay nil")
+	}
+	if sopsErr.Error() != "sops failed" {
+		t.Errorf("Error() = %q, want the wrapped message", sopsErr.Error())
+	}
+}
@@ -0,0 +1,97 @@
+package generate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// passwordAlphabet holds the characters of generated passwords
+// Characters with a meaning in YAML, dotenv or shells are left out, so the
+// passwords can be written into any file format without quoting.
+const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+// Default lengths when a spec has none
+const (
+	defaultPasswordLength = 32
+	defaultHexLength      = 64
+)
+
+// maxLength limits generated values to a sensible size
+const maxLength = 4096
+
+// Value generates a random value from a spec
+// Supported specs are password[:length], hex[:length] and uuid. Lengths count
+// characters, so hex:64 is 32 random bytes.
+func Value(spec string) (string, error) {
+	kind, lengthSpec, hasLength := strings.Cut(spec, ":")
+
+	length := 0
+	if hasLength {
+		var err error
+		length, err = strconv.Atoi(lengthSpec)
+		if err != nil || length < 1 || length > maxLength {
+			return "", fmt.Errorf("invalid length in %s, expected 1 to %d", spec, maxLength)
+		}
+	}
+
+	switch kind {
+	case "password":
+		if length == 0 {
+			length = defaultPasswordLength
+		}
+		return Password(length)
+	case "hex":
+		if length == 0 {
+			length = defaultHexLength
+		}
+		return Hex(length)
+	case "uuid":
+		if hasLength {
+			return "", fmt.Errorf("uuid takes no length")
+		}
+		return UUID()
+	default:
+		return "", fmt.Errorf("unsupported value spec %s (supported: password[:length], hex[:length], uuid)", spec)
+	}
+}
+
+// Password returns a random password of the given length
+func Password(length int) (string, error) {
+	alphabetSize := big.NewInt(int64(len(passwordAlphabet)))
+
+	var password strings.Builder
+	for range length {
+		index, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password.WriteByte(passwordAlphabet[index.Int64()])
+	}
+	return password.String(), nil
+}
+
+// Hex returns a random hexadecimal string of the given length
+func Hex(length int) (string, error) {
+	data := make([]byte, (length+1)/2)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate hex value: %w", err)
+	}
+	return hex.EncodeToString(data)[:length], nil
+}
+
+// UUID returns a random version 4 UUID
+func UUID() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	data[6] = (data[6] & 0x0f) | 0x40 // Version 4
+	data[8] = (data[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16]), nil
+}
@@ -0,0 +1,45 @@
+package generate
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		spec    string
+		pattern string
+	}{
+		{"password", `^[A-Za-z0-9_.-]{32}$`},
+		{"password:12", `^[A-Za-z0-9_.-]{12}$`},
+		{"hex", `^[0-9a-f]{64}$`},
+		{"hex:7", `^[0-9a-f]{7}$`},
+		{"uuid", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+	}
+
+	for _, tt := range tests {
+		value, err := Value(tt.spec)
+		if err != nil {
+			t.Errorf("Value(%s) failed: %v", tt.spec, err)
+			continue
+		}
+		if !regexp.MustCompile(tt.pattern).MatchString(value) {
+			t.Errorf("Value(%s) = %q, doesn't match %s", tt.spec, value, tt.pattern)
+		}
+	}
+
+	// Values are random
+	first, _ := Value("password")
+	second, _ := Value("password")
+	if first == second {
+		t.Error("Expected two generated passwords to differ")
+	}
+}
+
+func TestValueInvalid(t *testing.T) {
+	for _, spec := range []string{"pin", "password:0", "password:x", "hex:99999", "uuid:36"} {
+		if _, err := Value(spec); err == nil {
+			t.Errorf("Expected Value(%s) to fail", spec)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ConflictStages are the versions of a conflicted file in the index
+type ConflictStages struct {
+	// Base is the common ancestor, nil if both sides added the file
+	Base []byte
+	// Ours is the version of the checked out branch
+	Ours []byte
+	// Theirs is the version being merged in
+	Theirs []byte
+}
+
+// HasConflictMarkers checks if content contains the markers of an unresolved merge
+func HasConflictMarkers(data []byte) bool {
+	var start, end bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<<")):
+			start = true
+		case bytes.HasPrefix(line, []byte(">>>>>>>")) && start:
+			end = true
+		}
+	}
+	return start && end
+}
+
+// ReadConflictStages reads the versions of a conflicted file from the index
+// relPath is relative to the repository root.
+func ReadConflictStages(root string, relPath string) (*ConflictStages, error) {
+	ours, err := showObject(root, ":2:"+relPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s has no unmerged versions in the index, is a merge in progress?", relPath)
+	}
+	theirs, err := showObject(root, ":3:"+relPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s was deleted on one side, resolve it with git rm or git add", relPath)
+	}
+
+	stages := &ConflictStages{Ours: ours, Theirs: theirs}
+	if base, err := showObject(root, ":1:"+relPath); err == nil {
+		stages.Base = base
+	}
+	return stages, nil
+}
+
+// MergeFiles merges the changes from base to theirs into ours with git merge-file
+// It returns the merged content with conflict markers labeled like git does,
+// and the number of conflicts.
+func MergeFiles(oursPath string, basePath string, theirsPath string) ([]byte, int, error) {
+	cmd := exec.Command("git", "merge-file", "-p", "-L", "ours", "-L", "base", "-L", "theirs", oursPath, basePath, theirsPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+
+	// The exit code is the number of conflicts, negative on errors
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return output, exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("git merge-file failed: %w\n%s", err, stderr.String())
+	}
+	return output, 0, nil
+}
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"conflict", "a: 1\n<<<<<<< HEAD\nb: 2\n=======\nb: 3\n>>>>>>> feature\n", true},
+		{"no conflict", "a: 1\nb: 2\n", false},
+		{"only a start marker", "<<<<<<< HEAD\nb: 2\n", false},
+		{"marker inside a line", "note: use <<<<<<< and >>>>>>> sparingly\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasConflictMarkers([]byte(tt.data)); got != tt.want {
+				t.Errorf("HasConflictMarkers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConflictStages(t *testing.T) {
+	root, _ := setupRepo(t)
+	commitFile(t, root, "secret.yaml", "a: 1\n")
+	runGit(t, root, "branch", "-M", "main")
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+	commitFile(t, root, "secret.yaml", "a: 2\n")
+	runGit(t, root, "checkout", "-q", "main")
+	commitFile(t, root, "secret.yaml", "a: 3\n")
+
+	// The merge fails with a conflict
+	cmd := exec.Command("git", "-C", root, "-c", "user.name=Test", "-c", "user.email=test@example.com", "merge", "-q", "feature")
+	if err := cmd.Run(); err == nil {
+		t.Fatal("merge succeeded, want a conflict")
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "secret.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasConflictMarkers(data) {
+		t.Fatalf("secret.yaml has no conflict markers:\n%s", data)
+	}
+
+	stages, err := ReadConflictStages(root, "secret.yaml")
+	if err != nil {
+		t.Fatalf("ReadConflictStages() error = %v", err)
+	}
+	if string(stages.Base) != "a: 1\n" || string(stages.Ours) != "a: 3\n" || string(stages.Theirs) != "a: 2\n" {
+		t.Errorf("ReadConflictStages() = base %q, ours %q, theirs %q", stages.Base, stages.Ours, stages.Theirs)
+	}
+
+	if _, err := ReadConflictStages(root, "missing.yaml"); err == nil {
+		t.Error("ReadConflictStages() of a file without conflict succeeded")
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("base", "a: 1\nb: 1\nc: 1\nd: 1\ne: 1\n")
+
+	// Changes to different lines merge cleanly
+	ours := write("ours", "a: 2\nb: 1\nc: 1\nd: 1\ne: 1\n")
+	theirs := write("theirs", "a: 1\nb: 1\nc: 1\nd: 1\ne: 2\n")
+	merged, conflicts, err := MergeFiles(ours, base, theirs)
+	if err != nil {
+		t.Fatalf("MergeFiles() error = %v", err)
+	}
+	if conflicts != 0 || string(merged) != "a: 2\nb: 1\nc: 1\nd: 1\ne: 2\n" {
+		t.Errorf("MergeFiles() = %q, %d conflicts", merged, conflicts)
+	}
+
+	// Changes to the same line conflict
+	theirs = write("theirs", "a: 3\nb: 1\nc: 1\nd: 1\ne: 1\n")
+	merged, conflicts, err = MergeFiles(ours, base, theirs)
+	if err != nil {
+		t.Fatalf("MergeFiles() error = %v", err)
+	}
+	if conflicts != 1 || !strings.Contains(string(merged), "<<<<<<< ours") || !HasConflictMarkers(merged) {
+		t.Errorf("MergeFiles() = %q, %d conflicts, want 1 conflict", merged, conflicts)
+	}
+}
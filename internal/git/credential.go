@@ -0,0 +1,143 @@
+// Package git integrates simple-sops with git
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credential holds the attributes of git's credential protocol
+type Credential struct {
+	// Protocol is the protocol of the remote, e.g. https
+	Protocol string `yaml:"protocol,omitempty"`
+	// Host is the host of the remote, including the port if any
+	Host string `yaml:"host"`
+	// Path is the repository path; git only sends it with credential.useHttpPath
+	Path string `yaml:"path,omitempty"`
+	// Username is the user to authenticate as
+	Username string `yaml:"username,omitempty"`
+	// Password is the password or token
+	Password string `yaml:"password,omitempty"`
+}
+
+// CredentialFile is the layout of the encrypted credential file
+type CredentialFile struct {
+	Credentials []Credential `yaml:"credentials"`
+}
+
+// ParseCredentialFile parses the decrypted credential file, in YAML or JSON
+func ParseCredentialFile(data []byte) ([]Credential, error) {
+	var file CredentialFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credential file: %w", err)
+	}
+
+	for i, credential := range file.Credentials {
+		if credential.Host == "" {
+			return nil, fmt.Errorf("credential %d has no host", i+1)
+		}
+	}
+	return file.Credentials, nil
+}
+
+// ReadCredentialRequest reads the attributes git sends to a credential helper
+// The request ends with an empty line or the end of the input. A url
+// attribute is split into protocol, host and path.
+func ReadCredentialRequest(r io.Reader) (Credential, error) {
+	var request Credential
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return Credential{}, fmt.Errorf("invalid credential attribute: %s", line)
+		}
+
+		switch key {
+		case "protocol":
+			request.Protocol = value
+		case "host":
+			request.Host = value
+		case "path":
+			request.Path = value
+		case "username":
+			request.Username = value
+		case "password":
+			request.Password = value
+		case "url":
+			parsed, err := url.Parse(value)
+			if err != nil {
+				return Credential{}, fmt.Errorf("invalid credential url: %w", err)
+			}
+			request.Protocol = parsed.Scheme
+			request.Host = parsed.Host
+			request.Path = strings.TrimPrefix(parsed.Path, "/")
+			if parsed.User != nil {
+				request.Username = parsed.User.Username()
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Credential{}, fmt.Errorf("failed to read credential request: %w", err)
+	}
+	return request, nil
+}
+
+// FindCredential returns the first credential matching a request
+// Empty fields of a credential match any value, and a credential's path
+// matches the request's path and every path below it.
+func FindCredential(credentials []Credential, request Credential) (Credential, bool) {
+	for _, credential := range credentials {
+		if !strings.EqualFold(credential.Host, request.Host) {
+			continue
+		}
+		if credential.Protocol != "" && credential.Protocol != request.Protocol {
+			continue
+		}
+		if credential.Username != "" && request.Username != "" && credential.Username != request.Username {
+			continue
+		}
+		if credential.Path != "" && !pathMatches(credential.Path, request.Path) {
+			continue
+		}
+		return credential, true
+	}
+	return Credential{}, false
+}
+
+// pathMatches checks if a request path is the credential's path or below it
+func pathMatches(credentialPath string, requestPath string) bool {
+	credentialPath = strings.Trim(credentialPath, "/")
+	requestPath = strings.Trim(requestPath, "/")
+	return requestPath == credentialPath || strings.HasPrefix(requestPath, credentialPath+"/")
+}
+
+// WriteCredential writes the username and password of a credential for git
+func WriteCredential(w io.Writer, credential Credential) error {
+	for _, value := range []string{credential.Username, credential.Password} {
+		if strings.ContainsAny(value, "\n\x00") {
+			return fmt.Errorf("credential for %s contains a newline or NUL character", credential.Host)
+		}
+	}
+
+	if credential.Username != "" {
+		if _, err := fmt.Fprintf(w, "username=%s\n", credential.Username); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "password=%s\n", credential.Password); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCredentialRequest(t *testing.T) {
+	input := "protocol=https\nhost=github.com\nusername=alice\n\nignored=after blank line\n"
+
+	request, err := ReadCredentialRequest(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCredentialRequest failed: %v", err)
+	}
+	expected := Credential{Protocol: "https", Host: "github.com", Username: "alice"}
+	if request != expected {
+		t.Errorf("Expected %+v, got %+v", expected, request)
+	}
+
+	// A url attribute is split into its parts
+	request, err = ReadCredentialRequest(strings.NewReader("url=https://bob@git.example.com:8443/team/repo.git\n"))
+	if err != nil {
+		t.Fatalf("ReadCredentialRequest failed: %v", err)
+	}
+	expected = Credential{Protocol: "https", Host: "git.example.com:8443", Path: "team/repo.git", Username: "bob"}
+	if request != expected {
+		t.Errorf("Expected %+v, got %+v", expected, request)
+	}
+
+	if _, err := ReadCredentialRequest(strings.NewReader("not an attribute\n")); err == nil {
+		t.Error("Expected error for invalid attribute, got nil")
+	}
+}
+
+func TestFindCredential(t *testing.T) {
+	credentials, err := ParseCredentialFile([]byte(`credentials:
+  - host: github.com
+    path: team/private
+    password: team-token
+  - host: github.com
+    protocol: https
+    username: alice
+    password: alice-token
+  - host: gitlab.com
+    protocol: ssh
+    password: never
+`))
+	if err != nil {
+		t.Fatalf("ParseCredentialFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  Credential
+		password string
+	}{
+		{"path match", Credential{Protocol: "https", Host: "github.com", Path: "team/private/repo.git"}, "team-token"},
+		{"host match", Credential{Protocol: "https", Host: "GitHub.com"}, "alice-token"},
+		{"username mismatch", Credential{Protocol: "https", Host: "github.com", Username: "bob"}, ""},
+		{"protocol mismatch", Credential{Protocol: "https", Host: "gitlab.com"}, ""},
+		{"unknown host", Credential{Protocol: "https", Host: "example.com"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential, found := FindCredential(credentials, tt.request)
+			if found != (tt.password != "") || credential.Password != tt.password {
+				t.Errorf("Expected password %q, got %q (found: %v)", tt.password, credential.Password, found)
+			}
+		})
+	}
+
+	if _, err := ParseCredentialFile([]byte("credentials:\n  - password: x\n")); err == nil {
+		t.Error("Expected error for credential without host, got nil")
+	}
+}
+
+func TestWriteCredential(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteCredential(&buffer, Credential{Host: "github.com", Username: "alice", Password: "token"}); err != nil {
+		t.Fatalf("WriteCredential failed: %v", err)
+	}
+	if buffer.String() != "username=alice\npassword=token\n" {
+		t.Errorf("Unexpected output: %q", buffer.String())
+	}
+
+	if err := WriteCredential(&buffer, Credential{Host: "github.com", Password: "a\nb"}); err == nil {
+		t.Error("Expected error for newline in password, got nil")
+	}
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreSection heads the .gitignore entries added for decrypted files
+const gitignoreSection = "# Decrypted files written by simple-sops"
+
+// RepoRoot returns the root of the repository holding a path
+// Returns false if the path is not inside a git repository.
+func RepoRoot(path string) (string, bool) {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(output)), true
+}
+
+// IsIgnored checks if git ignores a path, e.g. through .gitignore
+func IsIgnored(root string, path string) bool {
+	return exec.Command("git", "-C", root, "check-ignore", "-q", "--", path).Run() == nil
+}
+
+// IsTracked checks if a path is tracked by git
+func IsTracked(root string, path string) bool {
+	return exec.Command("git", "-C", root, "ls-files", "--error-unmatch", "--", path).Run() == nil
+}
+
+// AddToGitignore adds a file to the .gitignore at the repository root
+// The entry is anchored to the root and listed in a section for decrypted files.
+func AddToGitignore(root string, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	// git reports the root with symlinks resolved
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+		absPath = filepath.Join(dir, filepath.Base(absPath))
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is outside of the repository %s", path, root)
+	}
+	entry := "/" + filepath.ToSlash(rel)
+
+	gitignorePath := filepath.Join(root, ".gitignore")
+	data, err := os.ReadFile(gitignorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", gitignorePath, err)
+	}
+
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if !strings.Contains(content, gitignoreSection+"\n") {
+		if content != "" {
+			content += "\n"
+		}
+		content += gitignoreSection + "\n"
+	}
+	content += entry + "\n"
+
+	if err := os.WriteFile(gitignorePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", gitignorePath, err)
+	}
+	return nil
+}
+
+// DecryptedEntries returns the paths listed in the .gitignore section for decrypted files
+// The paths are relative to the repository root.
+func DecryptedEntries(root string) ([]string, error) {
+	file, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	defer file.Close()
+
+	var entries []string
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == gitignoreSection:
+			inSection = true
+		case line == "" || strings.HasPrefix(line, "#"):
+			// A blank line or another comment ends the section
+			inSection = false
+		case inSection:
+			entries = append(entries, strings.TrimPrefix(line, "/"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddToGitignore(t *testing.T) {
+	root, _ := setupRepo(t)
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+
+	plainPath := filepath.Join(root, "config", "plain.yaml")
+	if IsIgnored(root, plainPath) {
+		t.Fatal("Expected plain.yaml not to be ignored yet")
+	}
+
+	for _, name := range []string{"config/plain.yaml", ".env.decrypted"} {
+		if err := AddToGitignore(root, filepath.Join(root, name)); err != nil {
+			t.Fatalf("AddToGitignore failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		t.Fatalf("Failed to read .gitignore: %v", err)
+	}
+	expected := "node_modules/\n\n" + gitignoreSection + "\n/config/plain.yaml\n/.env.decrypted\n"
+	if string(data) != expected {
+		t.Errorf("Expected .gitignore:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if !IsIgnored(root, plainPath) {
+		t.Error("Expected plain.yaml to be ignored")
+	}
+
+	entries, err := DecryptedEntries(root)
+	if err != nil {
+		t.Fatalf("DecryptedEntries failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0] != "config/plain.yaml" || entries[1] != ".env.decrypted" {
+		t.Errorf("Unexpected entries: %v", entries)
+	}
+
+	if err := AddToGitignore(root, filepath.Join(filepath.Dir(root), "outside.yaml")); err == nil {
+		t.Error("Expected error for path outside of the repository, got nil")
+	}
+}
+
+func TestIsTracked(t *testing.T) {
+	root, _ := setupRepo(t)
+	commitFile(t, root, "plain.yaml", "password: plain\n")
+
+	if !IsTracked(root, "plain.yaml") {
+		t.Error("Expected plain.yaml to be tracked")
+	}
+	if IsTracked(root, "other.yaml") {
+		t.Error("Expected other.yaml not to be tracked")
+	}
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// hookMarker identifies hooks written by simple-sops, so they can be replaced
+const hookMarker = "# Installed by simple-sops"
+
+// hookScripts are the scripts of the supported hooks
+var hookScripts = map[string]string{
+	"pre-commit": "exec simple-sops verify --staged",
+	"pre-push":   `exec simple-sops git pre-push "$@"`,
+}
+
+// HooksDir returns the hooks directory of a repository, respecting core.hooksPath
+func HooksDir(root string) (string, error) {
+	output, err := exec.Command("git", "-C", root, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the hooks directory of %s: %w", root, err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
+
+// InstallHook writes a hook running simple-sops and returns its path
+// Hooks not written by simple-sops are only replaced with force.
+func InstallHook(root string, name string, force bool) (string, error) {
+	script, ok := hookScripts[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported hook: %s", name)
+	}
+
+	dir, err := HooksDir(root)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, name)
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !force {
+		return "", fmt.Errorf("%s already exists and was not installed by simple-sops, use --force to replace it", path)
+	}
+
+	content := "#!/bin/sh\n" + hookMarker + "\n" + script + "\n"
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
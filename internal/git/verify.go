@@ -0,0 +1,243 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"slices"
+	"strings"
+)
+
+// zeroCommit is the object name git uses for a missing ref in push updates
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// Violation is a file covered by a creation rule that isn't encrypted
+type Violation struct {
+	// Path is the path of the file relative to the repository root
+	Path string
+	// Commit is the commit holding the plaintext, empty for the working tree or index
+	Commit string
+	// PathRegex is the path regex of the covering rule
+	PathRegex string
+}
+
+// FileReader reads a file, given by its path relative to the repository root
+// A missing file is reported with an error wrapping fs.ErrNotExist.
+type FileReader func(relPath string) ([]byte, error)
+
+// WorkTreeReader reads files from the working tree
+func WorkTreeReader(root string) FileReader {
+	return func(relPath string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(root, filepath.FromSlash(relPath)))
+	}
+}
+
+// StagedReader reads files from the index
+func StagedReader(root string) FileReader {
+	return func(relPath string) ([]byte, error) {
+		return showObject(root, ":"+relPath)
+	}
+}
+
+// CommitReader reads files from a commit
+func CommitReader(root string, commit string) FileReader {
+	return func(relPath string) ([]byte, error) {
+		return showObject(root, commit+":"+relPath)
+	}
+}
+
+// showObject returns the content of a git object like HEAD:config.yaml
+func showObject(root string, object string) ([]byte, error) {
+	output, err := exec.Command("git", "-C", root, "show", object).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", object, fs.ErrNotExist)
+	}
+	return output, nil
+}
+
+// Verify checks that the files covered by a creation rule are encrypted
+// Rules of nested .sops.yaml files in the working tree are applied as well.
+// Files that are ignored or don't exist are skipped.
+func Verify(sopsConfig *config.SopsConfig, root string, ignore *config.IgnoreMatcher, paths []string, read FileReader) ([]Violation, error) {
+	rules := config.NewNestedRules(root, sopsConfig)
+	var violations []Violation
+	for _, path := range paths {
+		rule, covered, err := rules.CoveringRule(path)
+		if err != nil {
+			return nil, err
+		}
+		if !covered || ignore.Ignored(filepath.Join(root, filepath.FromSlash(path)), false) {
+			continue
+		}
+
+		data, err := read(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if !config.IsEncryptedData(data) {
+			violations = append(violations, Violation{Path: path, PathRegex: rule.PathRegex})
+		}
+	}
+	return violations, nil
+}
+
+// StagedFiles returns the added, copied, modified and renamed files of the index
+func StagedFiles(root string) ([]string, error) {
+	output, err := exec.Command("git", "-C", root, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+	return splitNul(output), nil
+}
+
+// TrackedFiles returns the files tracked by git
+func TrackedFiles(root string) ([]string, error) {
+	output, err := exec.Command("git", "-C", root, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked files: %w", err)
+	}
+	return splitNul(output), nil
+}
+
+// splitNul splits the NUL-separated output of a git command
+func splitNul(output []byte) []string {
+	var paths []string
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// PushUpdate is a ref update git passes to the pre-push hook
+type PushUpdate struct {
+	LocalRef   string
+	LocalSHA   string
+	RemoteRef  string
+	RemoteSHA  string
+	NewRef     bool
+	DeletedRef bool
+}
+
+// ReadPushUpdates parses the lines git writes to the pre-push hook's stdin
+func ReadPushUpdates(r io.Reader) ([]PushUpdate, error) {
+	var updates []PushUpdate
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid push update: %s", scanner.Text())
+		}
+
+		updates = append(updates, PushUpdate{
+			LocalRef:   fields[0],
+			LocalSHA:   fields[1],
+			RemoteRef:  fields[2],
+			RemoteSHA:  fields[3],
+			NewRef:     fields[3] == zeroCommit,
+			DeletedRef: fields[1] == zeroCommit,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read push updates: %w", err)
+	}
+	return updates, nil
+}
+
+// CommitFiles are the files a commit changed
+type CommitFiles struct {
+	Commit string
+	Paths  []string
+}
+
+// PushedFiles returns the files changed by each commit a push update sends
+// For a new branch these are the commits not on any remote yet. Merge
+// commits are compared with each of their parents, so conflict resolutions
+// are checked too.
+func PushedFiles(root string, update PushUpdate) ([]CommitFiles, error) {
+	if update.DeletedRef {
+		return nil, nil
+	}
+
+	revisions := []string{update.LocalSHA, "--not", "--remotes"}
+	if !update.NewRef && objectExists(root, update.RemoteSHA) {
+		revisions = []string{update.RemoteSHA + ".." + update.LocalSHA}
+	}
+
+	args := append([]string{"-C", root, "log", "-m", "-z", "--format=%x01%H", "--name-only", "--diff-filter=ACMR"}, revisions...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pushed commits: %w", err)
+	}
+
+	// Every commit starts with \x01<hash>, followed by its NUL-separated files.
+	// With -m, merge commits appear once per parent.
+	var commits []CommitFiles
+	index := make(map[string]int)
+	for _, entry := range strings.Split(string(output), "\x01") {
+		lines := splitNul([]byte(entry))
+		if len(lines) == 0 {
+			continue
+		}
+
+		commit := strings.TrimSpace(lines[0])
+		i, ok := index[commit]
+		if !ok {
+			i = len(commits)
+			index[commit] = i
+			commits = append(commits, CommitFiles{Commit: commit})
+		}
+
+		for _, path := range lines[1:] {
+			path = strings.TrimSpace(path)
+			if path != "" && !slices.Contains(commits[i].Paths, path) {
+				commits[i].Paths = append(commits[i].Paths, path)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// objectExists checks if the repository has an object, e.g. a remote commit
+func objectExists(root string, object string) bool {
+	return exec.Command("git", "-C", root, "cat-file", "-e", object+"^{commit}").Run() == nil
+}
+
+// VerifyPush checks every commit of a push for plaintext files covered by a rule
+func VerifyPush(sopsConfig *config.SopsConfig, root string, ignore *config.IgnoreMatcher, updates []PushUpdate) ([]Violation, error) {
+	var violations []Violation
+	for _, update := range updates {
+		commits, err := PushedFiles(root, update)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commit := range commits {
+			commitViolations, err := Verify(sopsConfig, root, ignore, commit.Paths, CommitReader(root, commit.Commit))
+			if err != nil {
+				return nil, err
+			}
+			for _, violation := range commitViolations {
+				violation.Commit = commit.Commit
+				violations = append(violations, violation)
+			}
+		}
+	}
+	return violations, nil
+}
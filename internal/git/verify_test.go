@@ -0,0 +1,180 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"strings"
+	"testing"
+)
+
+// setupRepo creates a repository with a rule for secret.yaml
+func setupRepo(t *testing.T) (string, *config.SopsConfig) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	sopsConfig := &config.SopsConfig{CreationRules: []config.CreationRule{
+		{PathRegex: config.RulePathRegex("secret.yaml")},
+		{PathRegex: config.WildcardPattern},
+	}}
+	return root, sopsConfig
+}
+
+// runGit runs git in a repository and returns its trimmed output
+func runGit(t *testing.T, root string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes and commits a file
+func commitFile(t *testing.T, root string, name string, content string) string {
+	if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	runGit(t, root, "add", name)
+	runGit(t, root, "commit", "-q", "-m", "update "+name)
+	return runGit(t, root, "rev-parse", "HEAD")
+}
+
+func TestVerifyStaged(t *testing.T) {
+	root, sopsConfig := setupRepo(t)
+
+	for name, content := range map[string]string{
+		"secret.yaml": "password: plain\n",
+		"values.yaml": "replicas: 2\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	runGit(t, root, "add", ".")
+
+	staged, err := StagedFiles(root)
+	if err != nil {
+		t.Fatalf("StagedFiles failed: %v", err)
+	}
+
+	// Only files covered by a rule other than the catch-all rule must be encrypted
+	violations, err := Verify(sopsConfig, root, nil, staged, StagedReader(root))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Path != "secret.yaml" {
+		t.Errorf("Expected a violation for secret.yaml, got %+v", violations)
+	}
+
+	// Ignored files are skipped
+	ignore, err := config.NewIgnoreMatcher(root, "secret.yaml")
+	if err != nil {
+		t.Fatalf("NewIgnoreMatcher failed: %v", err)
+	}
+	if violations, _ := Verify(sopsConfig, root, ignore, staged, StagedReader(root)); len(violations) != 0 {
+		t.Errorf("Expected no violations for ignored file, got %+v", violations)
+	}
+}
+
+func TestVerifyNestedRules(t *testing.T) {
+	root, sopsConfig := setupRepo(t)
+
+	nested := filepath.Join(root, "apps", "api")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", nested, err)
+	}
+	nestedConfig := "creation_rules:\n  - path_regex: (^|/)credentials\\.json$\n    age: age1api\n"
+	if err := os.WriteFile(filepath.Join(nested, ".sops.yaml"), []byte(nestedConfig), 0644); err != nil {
+		t.Fatalf("Failed to write nested .sops.yaml: %v", err)
+	}
+	for _, name := range []string{"apps/api/credentials.json", "apps/credentials.json"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte("{\"token\": \"plain\"}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// Only the file below the nested .sops.yaml is covered by its rule
+	paths := []string{"apps/api/.sops.yaml", "apps/api/credentials.json", "apps/credentials.json"}
+	violations, err := Verify(sopsConfig, root, nil, paths, WorkTreeReader(root))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Path != "apps/api/credentials.json" {
+		t.Errorf("Expected a violation for apps/api/credentials.json, got %+v", violations)
+	}
+}
+
+func TestVerifyPush(t *testing.T) {
+	root, sopsConfig := setupRepo(t)
+
+	base := commitFile(t, root, "secret.yaml", "password: ENC[AES256_GCM,data:abc]\nsops:\n  version: 3.9.0\n")
+
+	// A later commit re-encrypts the file, but an earlier one leaked plaintext
+	leaked := commitFile(t, root, "secret.yaml", "password: plain\n")
+	head := commitFile(t, root, "secret.yaml", "password: ENC[AES256_GCM,data:def]\nsops:\n  version: 3.9.0\n")
+
+	updates, err := ReadPushUpdates(strings.NewReader("refs/heads/main " + head + " refs/heads/main " + base + "\n"))
+	if err != nil {
+		t.Fatalf("ReadPushUpdates failed: %v", err)
+	}
+
+	violations, err := VerifyPush(sopsConfig, root, nil, updates)
+	if err != nil {
+		t.Fatalf("VerifyPush failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Commit != leaked || violations[0].Path != "secret.yaml" {
+		t.Errorf("Expected a violation in commit %s, got %+v", leaked, violations)
+	}
+
+	// A new branch checks the commits not on any remote, here all of them
+	updates, _ = ReadPushUpdates(strings.NewReader("refs/heads/feature " + head + " refs/heads/feature " + zeroCommit + "\n"))
+	violations, err = VerifyPush(sopsConfig, root, nil, updates)
+	if err != nil {
+		t.Fatalf("VerifyPush failed: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Errorf("Expected one violation for the new branch, got %+v", violations)
+	}
+
+	// Deleting a branch pushes nothing
+	updates, _ = ReadPushUpdates(strings.NewReader("(delete) " + zeroCommit + " refs/heads/old " + head + "\n"))
+	if violations, err := VerifyPush(sopsConfig, root, nil, updates); err != nil || len(violations) != 0 {
+		t.Errorf("Expected no violations for a deleted branch, got %+v (%v)", violations, err)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	root, _ := setupRepo(t)
+
+	path, err := InstallHook(root, "pre-push", false)
+	if err != nil {
+		t.Fatalf("InstallHook failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read hook: %v", err)
+	}
+	if !strings.Contains(string(content), "simple-sops git pre-push") {
+		t.Errorf("Unexpected hook content:\n%s", content)
+	}
+
+	// Our own hook can be replaced, a foreign one only with force
+	if _, err := InstallHook(root, "pre-push", false); err != nil {
+		t.Errorf("Expected reinstalling to succeed, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nrun-linter\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	if _, err := InstallHook(root, "pre-push", false); err == nil {
+		t.Error("Expected error for foreign hook, got nil")
+	}
+	if _, err := InstallHook(root, "pre-push", true); err != nil {
+		t.Errorf("Expected force to replace the hook, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package helptopic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Topic is a task-oriented guide shown with 'simple-sops help <name>'
+type Topic struct {
+	Name     string
+	Short    string
+	Intro    string
+	Sections []Section
+}
+
+// Section is a task of a guide with the commands that do it
+type Section struct {
+	Title string
+	Text  string
+	Steps []Step
+}
+
+// Step is a command line of a section
+// Command is the path of the command, like "kubeconfig encrypt", and Args
+// holds its flags and arguments. Flags are looked up on the command, so a
+// guide can't refer to a flag that doesn't exist.
+type Step struct {
+	Comment string
+	Command string
+	Args    []string
+	// Pipe is a shell command the output is piped into, e.g. kubectl apply -f -
+	Pipe string
+}
+
+// Markdown generates the guide from the commands and flags of root
+// Every flag used in a section is listed with its description from the
+// command. Returns an error if a step uses a command or flag that doesn't exist.
+func (t Topic) Markdown(root *cobra.Command) (string, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n\n%s\n", t.Short, t.Intro)
+
+	for _, section := range t.Sections {
+		fmt.Fprintf(&builder, "\n## %s\n", section.Title)
+		if section.Text != "" {
+			fmt.Fprintf(&builder, "\n%s\n", section.Text)
+		}
+		if len(section.Steps) == 0 {
+			continue
+		}
+
+		var flags []*pflag.Flag
+		seen := make(map[string]bool)
+		builder.WriteString("\n```sh\n")
+		for i, step := range section.Steps {
+			cmd, err := findCommand(root, step.Command)
+			if err != nil {
+				return "", fmt.Errorf("topic %s: %w", t.Name, err)
+			}
+			for _, arg := range step.Args {
+				// Everything after -- belongs to the command that is run
+				if arg == "--" {
+					break
+				}
+				if !strings.HasPrefix(arg, "-") {
+					continue
+				}
+				flag, err := findFlag(cmd, arg)
+				if err != nil {
+					return "", fmt.Errorf("topic %s: %w", t.Name, err)
+				}
+				if !seen[flag.Name] {
+					seen[flag.Name] = true
+					flags = append(flags, flag)
+				}
+			}
+
+			if step.Comment != "" {
+				if i > 0 {
+					builder.WriteString("\n")
+				}
+				fmt.Fprintf(&builder, "# %s\n", step.Comment)
+			}
+			line := append([]string{root.Name(), step.Command}, step.Args...)
+			if step.Pipe != "" {
+				line = append(line, "|", step.Pipe)
+			}
+			builder.WriteString(strings.Join(line, " ") + "\n")
+		}
+		builder.WriteString("```\n")
+
+		if len(flags) > 0 {
+			builder.WriteString("\n")
+			for _, flag := range flags {
+				fmt.Fprintf(&builder, "- %s: %s\n", flagName(flag), flag.Usage)
+			}
+		}
+	}
+	return builder.String(), nil
+}
+
+// Render formats the markdown of a guide for the terminal
+// The colors suit the background of the terminal, lines wrap at width.
+func Render(markdown string, width int) (string, error) {
+	renderer, err := glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(width))
+	if err != nil {
+		return "", fmt.Errorf("failed to create renderer: %w", err)
+	}
+	return renderer.Render(markdown)
+}
+
+// findCommand returns the command at a path like "kubeconfig encrypt"
+func findCommand(root *cobra.Command, path string) (*cobra.Command, error) {
+	cmd, rest, err := root.Find(strings.Fields(path))
+	if err != nil || len(rest) > 0 || cmd == root {
+		return nil, fmt.Errorf("unknown command %q", path)
+	}
+	return cmd, nil
+}
+
+// findFlag returns the flag of an argument like --key-file, --to=alice or -k
+func findFlag(cmd *cobra.Command, arg string) (*pflag.Flag, error) {
+	var flag *pflag.Flag
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		name, _, _ = strings.Cut(name, "=")
+		flag = cmd.Flag(name)
+	} else if shorthand := strings.TrimPrefix(arg, "-"); len(shorthand) == 1 {
+		flag = cmd.Flags().ShorthandLookup(shorthand)
+		if flag == nil {
+			flag = cmd.InheritedFlags().ShorthandLookup(shorthand)
+		}
+	}
+	if flag == nil {
+		return nil, fmt.Errorf("unknown flag %s of %s", arg, cmd.CommandPath())
+	}
+	return flag, nil
+}
+
+// flagName formats the name of a flag with its shorthand, like `-k, --key-file`
+func flagName(flag *pflag.Flag) string {
+	if flag.Shorthand != "" {
+		return fmt.Sprintf("`-%s, --%s`", flag.Shorthand, flag.Name)
+	}
+	return fmt.Sprintf("`--%s`", flag.Name)
+}
@@ -0,0 +1,86 @@
+package helptopic_test
+
+import (
+	"simple-sops/internal/cli"
+	"simple-sops/internal/helptopic"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newRoot returns a root command with all the commands of the CLI
+func newRoot() *cobra.Command {
+	rootCmd := &cobra.Command{Use: "simple-sops"}
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Minimal output")
+	cli.RegisterCommands(rootCmd)
+	return rootCmd
+}
+
+func TestTopicsMatchCommands(t *testing.T) {
+	rootCmd := newRoot()
+	for _, topic := range helptopic.Topics {
+		markdown, err := topic.Markdown(rootCmd)
+		if err != nil {
+			t.Errorf("Topic %s is out of sync with the commands: %v", topic.Name, err)
+			continue
+		}
+		if !strings.HasPrefix(markdown, "# "+topic.Short) {
+			t.Errorf("Expected topic %s to start with its title, got %q", topic.Name, markdown)
+		}
+		if _, err := helptopic.Render(markdown, 80); err != nil {
+			t.Errorf("Failed to render topic %s: %v", topic.Name, err)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	rootCmd := newRoot()
+	topic := helptopic.Topic{
+		Name:  "test",
+		Short: "Test",
+		Intro: "Intro",
+		Sections: []helptopic.Section{{
+			Title: "Decrypt",
+			Steps: []helptopic.Step{
+				{Comment: "To stdout", Command: "decrypt", Args: []string{"-q", "--stdout", "secrets.yaml"}, Pipe: "kubectl apply -f -"},
+				{Command: "run", Args: []string{"-k", "key.txt", "secrets.yaml", "--", "curl", "--fail"}},
+			},
+		}},
+	}
+
+	markdown, err := topic.Markdown(rootCmd)
+	if err != nil {
+		t.Fatalf("Markdown failed: %v", err)
+	}
+	for _, want := range []string{
+		"## Decrypt",
+		"# To stdout\nsimple-sops decrypt -q --stdout secrets.yaml | kubectl apply -f -\n",
+		"simple-sops run -k key.txt secrets.yaml -- curl --fail\n",
+		"- `-q, --quiet`: Minimal output\n",
+		"- `--stdout`: Output to stdout instead of files\n",
+		"- `-k, --key-file`: ",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "`--fail`") {
+		t.Errorf("Expected flags after -- not to be listed, got:\n%s", markdown)
+	}
+	if strings.Count(markdown, "--key-file`") != 1 {
+		t.Errorf("Expected each flag to be listed once, got:\n%s", markdown)
+	}
+
+	for name, step := range map[string]helptopic.Step{
+		"unknown command":    {Command: "bogus"},
+		"unknown subcommand": {Command: "kubeconfig bogus"},
+		"unknown flag":       {Command: "decrypt", Args: []string{"--bogus"}},
+		"unknown shorthand":  {Command: "decrypt", Args: []string{"-z"}},
+	} {
+		topic.Sections[0].Steps = []helptopic.Step{step}
+		if _, err := topic.Markdown(rootCmd); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package helptopic
+
+// Topics are the guides shown with 'simple-sops help <name>'
+var Topics = []Topic{
+	{
+		Name:  "keys",
+		Short: "Creating, sharing and rotating Age keys",
+		Intro: `simple-sops encrypts files to Age recipients. Your key file holds the secret
+identity that decrypts them, and its public key (age1...) is the recipient
+others encrypt to. Keep the key file private and back it up: files can't be
+decrypted without it.`,
+		Sections: []Section{
+			{
+				Title: "Create a key",
+				Text: `The key is written to the key file of the config, ~/.config/simple-sops/key.txt
+by default. Identities on a YubiKey never leave the device.`,
+				Steps: []Step{
+					{Command: "gen-key"},
+					{Comment: "A second key for work", Command: "gen-key", Args: []string{"-k", "~/.config/simple-sops/work.txt"}},
+					{Comment: "An identity on a YubiKey that needs a touch", Command: "gen-key", Args: []string{"--piv", "--slot", "2", "--touch-policy", "cached"}},
+				},
+			},
+			{
+				Title: "Back up a key",
+				Text:  `Print the secret keys, e.g. to store them in a password manager or on paper.`,
+				Steps: []Step{
+					{Command: "key backup", Args: []string{"--qr"}},
+				},
+			},
+			{
+				Title: "Use another key",
+				Text: `Commands use the key file of the config unless one is given. SIMPLE_SOPS_KEY_FILE
+and SOPS_AGE_KEY_FILE select it for a whole shell, and keys in ssh-agent work
+as Age identities as well.`,
+				Steps: []Step{
+					{Command: "decrypt", Args: []string{"-k", "~/.config/simple-sops/work.txt", "secrets.yaml"}},
+					{Comment: "List ssh-agent keys usable as Age identities", Command: "ssh-keys"},
+				},
+			},
+			{
+				Title: "Share files with others",
+				Text: `Add the public keys of others to the trust store with a label, then encrypt
+files to them. Members and groups of .simple-sops/keys.yaml can be given by name.`,
+				Steps: []Step{
+					{Command: "trust add", Args: []string{"age1...", `"Alice (laptop)"`}},
+					{Command: "add-recipient", Args: []string{"--to", "alice", "secrets.yaml"}},
+					{Comment: "Encrypt a new file to a group", Command: "encrypt", Args: []string{"--to", "backend", "--strict", "secrets.yaml"}},
+				},
+			},
+			{
+				Title: "Rotate keys",
+				Text: `Rotating replaces the data key of files, e.g. after someone left the team.
+The rotation policy in the config tells when files are due.`,
+				Steps: []Step{
+					{Command: "rotate", Args: []string{"--due", "--dry-run"}},
+					{Command: "rotate", Args: []string{"--due"}},
+				},
+			},
+		},
+	},
+	{
+		Name:  "onepassword",
+		Short: "Keeping the Age key in 1Password",
+		Intro: `With the key in 1Password it never has to sit in a file on your disk. Commands
+fetch it with the op CLI when they need it and remove the temporary key file
+when they are done. Sign in with 'op signin' first.`,
+		Sections: []Section{
+			{
+				Title: "Store the key",
+				Text: `Create an item named SOPS_AGE_KEY_FILE in your Personal vault and add the content
+of your key file as a text field named text. Another item is set in the config
+or with SIMPLE_SOPS_OP_ITEM:
+
+    onepassword_enabled: true
+    always_use_onepassword: true
+    onepassword_item: op://Personal/SOPS_AGE_KEY_FILE/text`,
+			},
+			{
+				Title: "Load the key for a shell session",
+				Text: `Every command fetches the key again, which means one prompt per command. With
+the shell-init functions loaded, get-key sets SOPS_AGE_KEY_FILE in your shell,
+the following commands use it and clear-key removes it. A key that is still
+loaded is removed when the shell exits. Load them in ~/.bashrc or ~/.zshrc with
+eval "$(simple-sops shell-init bash)".`,
+				Steps: []Step{
+					{Command: "get-key"},
+					{Command: "edit", Args: []string{"secrets.yaml"}},
+					{Command: "clear-key"},
+				},
+			},
+			{
+				Title: "Work on several values at once",
+				Text:  `repl fetches the key once and runs get, set, ls and edit until you exit.`,
+				Steps: []Step{
+					{Command: "repl"},
+					{Comment: "In scripts", Command: "get-key", Args: []string{"--print-path"}},
+				},
+			},
+			{
+				Title: "Encrypt to the keys of several items",
+				Text:  `The public keys of the items are added to the rule of the file in .sops.yaml.`,
+				Steps: []Step{
+					{Command: "encrypt", Args: []string{"--op-items", "alice-age-key,bob-age-key", "--op-vaults", "Personal,Shared", "secrets.yaml"}},
+				},
+			},
+			{
+				Title: "Troubleshooting",
+				Text:  `doctor checks that the op CLI is installed, along with the rest of the setup.`,
+				Steps: []Step{
+					{Command: "doctor"},
+				},
+			},
+		},
+	},
+	{
+		Name:  "kubernetes",
+		Short: "Secrets, kubeconfigs and Talos configs for Kubernetes",
+		Intro: `Kubernetes manifests stay readable when only their secret values are encrypted:
+set-keys proposes the Kubernetes pattern for files holding a Secret, which
+encrypts data, stringData, password, ingress and token fields.`,
+		Sections: []Section{
+			{
+				Title: "Encrypt a Secret manifest",
+				Text: `Files with several documents separated by --- are supported, the pattern
+applies to every document.`,
+				Steps: []Step{
+					{Command: "set-keys", Args: []string{"secret.yaml"}},
+					{Command: "encrypt", Args: []string{"--dry-run", "secret.yaml"}},
+					{Command: "encrypt", Args: []string{"secret.yaml"}},
+				},
+			},
+			{
+				Title: "Apply it to a cluster",
+				Text:  `The decrypted manifest is piped to kubectl and never written to disk.`,
+				Steps: []Step{
+					{Command: "decrypt", Args: []string{"--stdout", "secret.yaml"}, Pipe: "kubectl apply -f -"},
+				},
+			},
+			{
+				Title: "Encrypt kubeconfigs and Talos configs",
+				Text: `The presets set the matching pattern on the rule of each file. Without arguments
+the files kubectl and talosctl write in the current directory are encrypted.`,
+				Steps: []Step{
+					{Command: "kubeconfig encrypt"},
+					{Command: "kubeconfig encrypt", Args: []string{"--recipient", "age1...", "clusters/prod/kubeconfig"}},
+					{Command: "talos encrypt"},
+				},
+			},
+			{
+				Title: "Use an encrypted kubeconfig",
+				Text:  `The decrypted file replaces {} and is removed when the command exits.`,
+				Steps: []Step{
+					{Command: "run", Args: []string{"clusters/prod/kubeconfig", "-s", "'kubectl --kubeconfig {} get pods'"}},
+				},
+			},
+			{
+				Title: "Check secrets in a container",
+				Text:  `Run preflight in the entrypoint so a pod fails early when the key can't decrypt.`,
+				Steps: []Step{
+					{Command: "preflight", Args: []string{"--canary", "/secrets/app.enc.yaml", "--format", "json"}},
+				},
+			},
+		},
+	},
+}
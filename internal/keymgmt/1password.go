@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"simple-sops/internal/exitcode"
+	"simple-sops/internal/metrics"
+	"simple-sops/internal/timing"
 	"simple-sops/pkg/logging"
+	"slices"
 	"strings"
+	"time"
 )
 
 // OnePasswordItem represents a key stored in 1Password
@@ -29,11 +34,42 @@ var DefaultOnePasswordItem = OnePasswordItem{
 // For backward compatibility with existing code
 var DefaultOnePasswordConfig = DefaultOnePasswordItem
 
+// SetDefaultOnePasswordItem replaces the item the key is fetched from by default
+func SetDefaultOnePasswordItem(item OnePasswordItem) {
+	DefaultOnePasswordItem = item
+	DefaultOnePasswordConfig = item
+}
+
+// ParseOnePasswordItem parses an item name or a secret reference like op://vault/item/field
+// Parts missing from the reference keep the values of the default item.
+func ParseOnePasswordItem(ref string) (OnePasswordItem, error) {
+	item := DefaultOnePasswordItem
+	path, isRef := strings.CutPrefix(ref, "op://")
+	if !isRef {
+		if ref == "" {
+			return OnePasswordItem{}, fmt.Errorf("empty 1Password item")
+		}
+		item.ItemName = ref
+		return item, nil
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return OnePasswordItem{}, fmt.Errorf("invalid 1Password reference %s, expected op://vault/item or op://vault/item/field", ref)
+	}
+	item.VaultName = parts[0]
+	item.ItemName = parts[1]
+	if len(parts) == 3 {
+		item.FieldLabel = parts[2]
+	}
+	return item, nil
+}
+
 // 1Password JSON structures
 type opItemResponse struct {
 	Fields []struct {
-		Label string `json:"label"`
-		Value string `json:"value"`
+		Label string          `json:"label"`
+		Value json.RawMessage `json:"value"`
 	} `json:"fields"`
 }
 
@@ -51,9 +87,10 @@ func GetKeyFromOnePassword(item OnePasswordItem) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer keyContent.Destroy()
 
 	// Create a temporary file for the key
-	return CreateTempAgeKeyFile(keyContent)
+	return createTempKeyFile(keyContent.Bytes())
 }
 
 // GetKeysFromOnePassword retrieves multiple Age keys from 1Password items and combines them into a single temporary file
@@ -66,13 +103,13 @@ func GetKeysFromOnePassword(items []OnePasswordItem) (string, bool, error) {
 	}
 
 	// Create a temporary directory for the keys
-	tempDir, err := os.MkdirTemp("", "simple-sops-*")
+	tempDir, err := CreateTempDir()
 	if err != nil {
-		return "", false, fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", false, err
 	}
 
 	// Create a combined key file
-	tempKeyFile := filepath.Join(tempDir, "age-keys.txt")
+	tempKeyFile := filepath.Join(tempDir, combinedTempKeyFileName)
 	keyFile, err := os.Create(tempKeyFile)
 	if err != nil {
 		os.RemoveAll(tempDir)
@@ -80,7 +117,9 @@ func GetKeysFromOnePassword(items []OnePasswordItem) (string, bool, error) {
 	}
 	defer keyFile.Close()
 
-	// Fetch each key and append to the combined file
+	// Fetch each key and append to the combined file, skipping keys that
+	// are stored in more than one item
+	seen := make(map[string]bool)
 	for _, item := range items {
 		logging.Debug("Fetching key from item: %s in vault: %s", item.ItemName, item.VaultName)
 
@@ -91,50 +130,96 @@ func GetKeysFromOnePassword(items []OnePasswordItem) (string, bool, error) {
 			continue
 		}
 
-		// Write the key to the combined file with a newline if needed
-		if !strings.HasSuffix(keyContent, "\n") {
-			keyContent += "\n"
-		}
-		if _, err := keyFile.WriteString(keyContent); err != nil {
+		// Write the new identities to the combined file
+		written, err := writeUniqueIdentities(keyFile, keyContent.Bytes(), seen)
+		keyContent.Destroy()
+		if err != nil {
 			os.RemoveAll(tempDir)
 			return "", false, fmt.Errorf("failed to write key to temporary file: %w", err)
 		}
 
-		logging.Debug("Successfully added key from item: %s", item.ItemName)
+		logging.Debug("Added %d new keys from item: %s", written, item.ItemName)
 	}
 
 	return tempKeyFile, true, nil
 }
 
+// GetPublicKeysFromOnePassword retrieves the public keys of 1Password items
+// The private keys only live in locked memory and are never written to disk
+func GetPublicKeysFromOnePassword(items []OnePasswordItem) ([]string, error) {
+	logging.Debug("Fetching public keys from 1Password...")
+
+	// Check if 1Password CLI is available
+	if err := checkOnePasswordCLI(); err != nil {
+		return nil, err
+	}
+
+	var pubKeys []string
+	for _, item := range items {
+		keyContent, err := getKeyContentFromOnePassword(item)
+		if err != nil {
+			logging.Debug("Failed to get key from 1Password item %s: %v", item.ItemName, err)
+			continue
+		}
+
+		itemPubKeys := publicKeysFromContent(keyContent.Bytes())
+		keyContent.Destroy()
+		if len(itemPubKeys) == 0 {
+			logging.Debug("No public key found in 1Password item %s", item.ItemName)
+			continue
+		}
+
+		pubKeys = append(pubKeys, itemPubKeys...)
+		logging.Debug("Got %d public keys from item: %s", len(itemPubKeys), item.ItemName)
+	}
+
+	if len(pubKeys) == 0 {
+		return nil, fmt.Errorf("no public keys found in 1Password items")
+	}
+
+	return pubKeys, nil
+}
+
 // getKeyContentFromOnePassword retrieves the key content from a 1Password item
-func getKeyContentFromOnePassword(item OnePasswordItem) (string, error) {
+// The key is returned in a locked buffer that the caller must destroy
+func getKeyContentFromOnePassword(item OnePasswordItem) (*LockedBuffer, error) {
 	// Get the key from 1Password
 	cmd := execCommand("op", "item", "get", item.ItemName, "--vault", item.VaultName, "--format", "json")
 	output, err := cmd.Output()
+	defer Wipe(output)
 	if err != nil {
-		return "", fmt.Errorf("failed to get key from 1Password: %w", err)
+		return nil, fmt.Errorf("failed to get key from 1Password: %w", err)
 	}
 
-	// Parse the JSON response
+	// Parse the JSON response, keeping field values raw so they can be wiped
 	var response opItemResponse
 	if err := json.Unmarshal(output, &response); err != nil {
-		return "", fmt.Errorf("failed to parse 1Password response: %w", err)
+		return nil, fmt.Errorf("failed to parse 1Password response: %w", err)
 	}
+	defer func() {
+		for _, field := range response.Fields {
+			Wipe(field.Value)
+		}
+	}()
 
 	// Find the field with the key
-	var keyContent string
 	for _, field := range response.Fields {
-		if field.Label == item.FieldLabel {
-			keyContent = field.Value
-			break
+		if field.Label != item.FieldLabel || len(field.Value) == 0 || field.Value[0] != '"' {
+			continue
 		}
-	}
 
-	if keyContent == "" {
-		return "", fmt.Errorf("no field with label '%s' found in 1Password item", item.FieldLabel)
+		keyContent, err := decodeJSONString(field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse 1Password response: %w", err)
+		}
+		if len(keyContent.Bytes()) == 0 {
+			keyContent.Destroy()
+			break
+		}
+		return keyContent, nil
 	}
 
-	return keyContent, nil
+	return nil, fmt.Errorf("no field with label '%s' found in 1Password item", item.FieldLabel)
 }
 
 // checkOnePasswordCLI checks if the 1Password CLI is available
@@ -150,6 +235,31 @@ func checkOnePasswordCLI() error {
 // EnsureAgeKey makes sure an Age key is available, either from a file or from 1Password
 // Now supports multiple 1Password items through the opItems parameter
 func EnsureAgeKey(keyFile string, useOnePassword bool, alwaysUseOnePassword bool, opItems ...OnePasswordItem) (string, bool, error) {
+	defer timing.Track("", timing.KeyFetch)()
+	defer metrics.ObserveKeyFetch(time.Now())
+
+	keyPath, isTemp, err := ensureAgeKey(keyFile, useOnePassword, alwaysUseOnePassword, opItems)
+	return keyPath, isTemp, exitcode.Wrap(exitcode.Key, err)
+}
+
+// ensureAgeKey looks up the Age key in the order of its sources
+func ensureAgeKey(keyFile string, useOnePassword bool, alwaysUseOnePassword bool, opItems []OnePasswordItem) (string, bool, error) {
+	// An identity streamed over stdin replaces every configured key
+	if stdinIdentity != nil {
+		logging.Debug("Using the Age identity read from stdin")
+		tempKeyFile, err := createTempKeyFile(stdinIdentity.Bytes())
+		if err != nil {
+			return "", false, err
+		}
+		return tempKeyFile, true, nil
+	}
+
+	// Keys from an explicit source like ssh-agent: take precedence
+	if IsKeySource(keyFile) {
+		logging.Debug("Fetching Age key from %s", keyFile)
+		return ResolveKeyFile(keyFile)
+	}
+
 	// If AlwaysUseOnePassword is true, we always try to get the key from 1Password first
 	if alwaysUseOnePassword && useOnePassword {
 		// Check if we have multiple items specified
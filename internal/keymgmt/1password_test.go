@@ -281,3 +281,53 @@ func TestOnePasswordCliNotFound(t *testing.T) {
 		t.Errorf("Expected GetKeyFromOnePassword to fail with CLI not found")
 	}
 }
+
+func TestGetPublicKeysFromOnePassword(t *testing.T) {
+	cleanup := setupOpTest(t)
+	defer cleanup()
+
+	pubKeys, err := GetPublicKeysFromOnePassword([]OnePasswordItem{
+		{ItemName: "test-item1", VaultName: "test-vault", FieldLabel: "text"},
+		{ItemName: "test-item2", VaultName: "test-vault", FieldLabel: "missing"},
+	})
+	if err != nil {
+		t.Fatalf("GetPublicKeysFromOnePassword failed: %v", err)
+	}
+
+	if len(pubKeys) != 1 || pubKeys[0] != "age123" {
+		t.Errorf("Expected [age123], got %v", pubKeys)
+	}
+
+	// Items without a key field are an error
+	if _, err := GetPublicKeysFromOnePassword([]OnePasswordItem{{ItemName: "test-item", VaultName: "test-vault", FieldLabel: "missing"}}); err == nil {
+		t.Error("Expected error when no item has a key, got nil")
+	}
+}
+
+func TestParseOnePasswordItem(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    OnePasswordItem
+		wantErr bool
+	}{
+		{ref: "ci-sops-key", want: OnePasswordItem{ItemName: "ci-sops-key", VaultName: "Personal", FieldLabel: "text"}},
+		{ref: "op://CI/sops", want: OnePasswordItem{ItemName: "sops", VaultName: "CI", FieldLabel: "text"}},
+		{ref: "op://CI/sops/key", want: OnePasswordItem{ItemName: "sops", VaultName: "CI", FieldLabel: "key"}},
+		{ref: "op://CI", wantErr: true},
+		{ref: "op://CI//key", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOnePasswordItem(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseOnePasswordItem(%q) expected an error", tt.ref)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseOnePasswordItem(%q) = %+v, %v, want %+v", tt.ref, got, err, tt.want)
+		}
+	}
+}
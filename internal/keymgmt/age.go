@@ -2,14 +2,15 @@ package keymgmt
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"simple-sops/pkg/logging"
 	"strings"
-	"syscall"
 )
 
 const (
@@ -147,15 +148,20 @@ func LoadAgeKey(keyFile string) (string, error) {
 
 // CreateTempAgeKeyFile creates a temporary file with an Age key and returns the path
 func CreateTempAgeKeyFile(keyContent string) (string, error) {
+	return createTempKeyFile([]byte(keyContent))
+}
+
+// createTempKeyFile writes key material to a new temporary key file
+func createTempKeyFile(keyContent []byte) (string, error) {
 	// Create a temporary directory
-	tempDir, err := os.MkdirTemp("", "simple-sops-*")
+	tempDir, err := CreateTempDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", err
 	}
 
 	// Create key file
-	tempKeyFile := filepath.Join(tempDir, "age-key.txt")
-	if err := os.WriteFile(tempKeyFile, []byte(keyContent), 0600); err != nil {
+	tempKeyFile := filepath.Join(tempDir, tempKeyFileName)
+	if err := os.WriteFile(tempKeyFile, keyContent, 0600); err != nil {
 		os.RemoveAll(tempDir) // Clean up if we can't write
 		return "", fmt.Errorf("failed to write temporary key file: %w", err)
 	}
@@ -165,35 +171,20 @@ func CreateTempAgeKeyFile(keyContent string) (string, error) {
 
 // CleanupTempAgeKeyFile removes a temporary Age key file and its directory
 func CleanupTempAgeKeyFile(keyFile string) error {
-	// Get the directory containing the key file
-	dir := filepath.Dir(keyFile)
-
 	// Only remove if it looks like our temp directory
-	if strings.HasPrefix(filepath.Base(dir), "simple-sops-") {
-		return os.RemoveAll(dir)
+	if IsTempKeyFile(keyFile) {
+		return RemoveSecurely(filepath.Dir(keyFile))
 	}
 
 	return fmt.Errorf("not a simple-sops temporary directory")
 }
 
-// RegisterCleanupOnExit registers a cleanup function for when the process exits
-func RegisterCleanupOnExit(keyFile string) {
-	// Go doesn't have a direct equivalent to Fish's --on-process-exit
-	// You can use signal handling instead
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		CleanupTempAgeKeyFile(keyFile)
-		os.Exit(1)
-	}()
-}
-
 // ExpandPath is just a renamed version of the internal expandPath function
 func ExpandPath(path string) (string, error) {
 	return expandPath(path)
 }
 
+// GetAllPublicKeysFromFile returns every public key listed in an Age key file
 func GetAllPublicKeysFromFile(keyFile string) ([]string, error) {
 	expandedPath, err := expandPath(keyFile)
 	if err != nil {
@@ -204,22 +195,117 @@ func GetAllPublicKeysFromFile(keyFile string) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read key file: %w", err)
 	}
+	defer Wipe(content)
+
+	pubKeys := publicKeysFromContent(content)
+	if len(pubKeys) == 0 {
+		return nil, fmt.Errorf("no public keys found in key file")
+	}
+
+	return pubKeys, nil
+}
+
+// GetSecretKeysFromFile returns every secret key of an Age key file, e.g. to back them up
+func GetSecretKeysFromFile(keyFile string) ([]string, error) {
+	expandedPath, err := expandPath(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	content, err := os.ReadFile(expandedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	defer Wipe(content)
+
+	var secretKeys []string
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("AGE-SECRET-KEY-")) {
+			secretKeys = append(secretKeys, string(line))
+		}
+	}
+	if len(secretKeys) == 0 {
+		return nil, fmt.Errorf("no secret keys found in key file")
+	}
+
+	return secretKeys, nil
+}
 
-	lines := strings.Split(string(content), "\n")
+// GetPublicKeysFromKeySpec returns the public keys of a key file or key source
+// without keeping the private keys around. Temporary key files are removed.
+func GetPublicKeysFromKeySpec(spec string) ([]string, error) {
+	keyPath, isTemp, err := ResolveKeyFile(spec)
+	if err != nil {
+		return nil, err
+	}
+	if isTemp {
+		defer CleanupTempAgeKeyFile(keyPath)
+	}
+
+	return GetAllPublicKeysFromFile(keyPath)
+}
+
+// publicKeysFromContent extracts the "# public key:" comments from key file content
+// Only the public keys are copied out of the content, so it can be wiped afterwards
+func publicKeysFromContent(content []byte) []string {
 	var pubKeys []string
 
-	for _, line := range lines {
-		if strings.HasPrefix(line, "# public key:") {
-			pubKey := strings.TrimSpace(strings.TrimPrefix(line, "# public key:"))
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("# public key:")) {
+			pubKey := strings.TrimSpace(string(bytes.TrimPrefix(line, []byte("# public key:"))))
 			if pubKey != "" {
 				pubKeys = append(pubKeys, pubKey)
 			}
 		}
 	}
 
-	if len(pubKeys) == 0 {
-		return nil, fmt.Errorf("no public keys found in key file")
+	return pubKeys
+}
+
+// writeUniqueIdentities writes the identities in key file content to w, skipping
+// identities already in seen. Identities are compared by their public key, or by
+// a hash of the secret key when the public key comment is missing. Returns the
+// number of identities written.
+func writeUniqueIdentities(w io.Writer, content []byte, seen map[string]bool) (int, error) {
+	written := 0
+	var block [][]byte
+	var pubKey string
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		block = append(block, line)
+
+		if bytes.HasPrefix(line, []byte("# public key:")) {
+			pubKey = strings.TrimSpace(string(bytes.TrimPrefix(line, []byte("# public key:"))))
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("AGE-SECRET-KEY-")) {
+			continue
+		}
+
+		// The secret key ends an identity block
+		id := pubKey
+		if id == "" {
+			sum := sha256.Sum256(bytes.TrimSpace(line))
+			id = "secret:" + hex.EncodeToString(sum[:])
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			for _, blockLine := range block {
+				if _, err := w.Write(blockLine); err != nil {
+					return written, err
+				}
+				if _, err := w.Write([]byte("\n")); err != nil {
+					return written, err
+				}
+			}
+			written++
+		}
+
+		block = block[:0]
+		pubKey = ""
 	}
 
-	return pubKeys, nil
+	return written, nil
 }
@@ -2,8 +2,10 @@
 package keymgmt
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -117,6 +119,68 @@ func TestGetAllPublicKeysFromFile(t *testing.T) {
 	}
 }
 
+func TestGetSecretKeysFromFile(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keyPath, []byte(mockKeyContent+"\n"+mockKeyContent2), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	secretKeys, err := GetSecretKeysFromFile(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to get secret keys: %v", err)
+	}
+	expected := []string{"AGE-SECRET-KEY-123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ", "AGE-SECRET-KEY-ABCDEFGHIJKLMNOPQRSTUVWXYZ123456789"}
+	if strings.Join(secretKeys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, secretKeys)
+	}
+
+	// Files with only public keys are rejected
+	if err := os.WriteFile(keyPath, []byte("# public key: age123\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if _, err := GetSecretKeysFromFile(keyPath); err == nil {
+		t.Error("Expected error for a file without secret keys")
+	}
+}
+
+func TestGetPublicKeysFromKeySpec(t *testing.T) {
+	// Plain key files are read in place
+	keyPath := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(keyPath, []byte(mockKeyContent), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	pubKeys, err := GetPublicKeysFromKeySpec(keyPath)
+	if err != nil {
+		t.Fatalf("GetPublicKeysFromKeySpec failed: %v", err)
+	}
+	if len(pubKeys) != 1 || pubKeys[0] != "age123" {
+		t.Errorf("Expected [age123], got %v", pubKeys)
+	}
+	if _, err := os.Stat(keyPath); err != nil {
+		t.Error("Plain key files must not be removed")
+	}
+
+	// Temporary key files from key sources are removed
+	var tempKeyFile string
+	keySources["test"] = func(ref string) (string, error) {
+		tempKeyFile, err = CreateTempAgeKeyFile(mockKeyContent2)
+		return tempKeyFile, err
+	}
+	defer delete(keySources, "test")
+
+	pubKeys, err = GetPublicKeysFromKeySpec("test:key")
+	if err != nil {
+		t.Fatalf("GetPublicKeysFromKeySpec failed for key source: %v", err)
+	}
+	if len(pubKeys) != 1 || pubKeys[0] != "age456" {
+		t.Errorf("Expected [age456], got %v", pubKeys)
+	}
+	if _, err := os.Stat(tempKeyFile); !os.IsNotExist(err) {
+		t.Error("Temporary key file should be removed")
+	}
+}
+
 // Mock implementation of exec.Command for testing
 type MockCmd struct {
 	expectedCmd  string
@@ -126,3 +190,34 @@ type MockCmd struct {
 }
 
 // Tests for 1Password integration with mocks will be implemented here
+
+func TestWriteUniqueIdentities(t *testing.T) {
+	seen := make(map[string]bool)
+	var combined bytes.Buffer
+
+	// The same key from two sources is only written once
+	written, err := writeUniqueIdentities(&combined, []byte(mockKeyContent+mockKeyContent2), seen)
+	if err != nil || written != 2 {
+		t.Fatalf("Expected 2 identities written, got %d (%v)", written, err)
+	}
+	written, err = writeUniqueIdentities(&combined, []byte(mockKeyContent2), seen)
+	if err != nil || written != 0 {
+		t.Errorf("Expected duplicate identity to be skipped, got %d written (%v)", written, err)
+	}
+
+	// Keys without a public key comment are compared by the secret key
+	bare := "AGE-SECRET-KEY-BARE\n"
+	if written, _ := writeUniqueIdentities(&combined, []byte(bare), seen); written != 1 {
+		t.Errorf("Expected bare identity to be written, got %d", written)
+	}
+	if written, _ := writeUniqueIdentities(&combined, []byte(bare), seen); written != 0 {
+		t.Errorf("Expected duplicate bare identity to be skipped, got %d", written)
+	}
+
+	if count := strings.Count(combined.String(), "AGE-SECRET-KEY-"); count != 3 {
+		t.Errorf("Expected 3 identities in combined content, got %d:\n%s", count, combined.String())
+	}
+	if pubKeys := publicKeysFromContent(combined.Bytes()); len(pubKeys) != 2 {
+		t.Errorf("Expected 2 public keys in combined content, got %v", pubKeys)
+	}
+}
@@ -0,0 +1,104 @@
+package keymgmt
+
+import (
+	"os"
+	"os/signal"
+	"simple-sops/pkg/logging"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// exitPaths are the temporary keys and decrypted files to remove when the process exits
+var (
+	exitPathsMu sync.Mutex
+	exitPaths   = make(map[string]bool)
+)
+
+// exitSignalsSuspended counts the callers handling exit signals themselves
+var exitSignalsSuspended atomic.Int32
+
+// interruptSuspended counts the callers handling only os.Interrupt themselves
+var interruptSuspended atomic.Int32
+
+// RemoveOnExit removes a file or directory when the process exits, unless it was removed before
+// New temporary directories are registered, so they don't outlive an
+// interrupted process.
+func RemoveOnExit(path string) {
+	exitPathsMu.Lock()
+	defer exitPathsMu.Unlock()
+	exitPaths[path] = true
+}
+
+// KeepOnExit stops removing a path when the process exits
+// get-key calls it once the key was handed to the shell.
+func KeepOnExit(path string) {
+	exitPathsMu.Lock()
+	defer exitPathsMu.Unlock()
+	delete(exitPaths, path)
+}
+
+// CleanupOnExit securely removes the paths registered with RemoveOnExit
+// Deferred cleanups are skipped by os.Exit and signals, so main calls this
+// before exiting.
+func CleanupOnExit() {
+	exitPathsMu.Lock()
+	paths := exitPaths
+	exitPaths = make(map[string]bool)
+	exitPathsMu.Unlock()
+
+	for path := range paths {
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		if err := RemoveSecurely(path); err != nil {
+			logging.Debug("Failed to remove %s: %v", path, err)
+		} else {
+			logging.Debug("Removed %s on exit", path)
+		}
+	}
+}
+
+// HandleExitSignals cleans up and exits when the process is interrupted or terminated
+// On Windows, Go delivers Ctrl+C and Ctrl+Break as os.Interrupt and closing
+// the console, logging off or shutting down as SIGTERM, so these console
+// events are covered too. The exit code is 128 plus the signal number.
+func HandleExitSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, exitSignals...)
+	go func() {
+		for sig := range signals {
+			if exitSignalsSuspended.Load() > 0 || (sig == os.Interrupt && interruptSuspended.Load() > 0) {
+				continue
+			}
+			logging.Debug("Received signal %v, cleaning up", sig)
+			CleanupOnExit()
+			code := 1
+			if number, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(number)
+			}
+			os.Exit(code)
+		}
+	}()
+}
+
+// SuspendExitSignals lets the caller handle exit signals until the returned function is called
+// run forwards them to its command and cleans up once the command exited.
+func SuspendExitSignals() func() {
+	exitSignalsSuspended.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { exitSignalsSuspended.Add(-1) })
+	}
+}
+
+// SuspendInterrupt lets the caller handle os.Interrupt until the returned function is called
+// Other exit signals still clean up and exit. The repl uses it to cancel the
+// current line on Ctrl+C instead of ending the session.
+func SuspendInterrupt() func() {
+	interruptSuspended.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { interruptSuspended.Add(-1) })
+	}
+}
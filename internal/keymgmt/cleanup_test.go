@@ -0,0 +1,52 @@
+package keymgmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanupOnExit(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	// A temporary directory that was left behind
+	leftDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(leftDir, "secrets.yaml.plain"), []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write plaintext: %v", err)
+	}
+
+	// One removed by its deferred cleanup and one handed over
+	removedDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+	if err := RemoveSecurely(removedDir); err != nil {
+		t.Fatalf("RemoveSecurely failed: %v", err)
+	}
+	keptDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+	KeepOnExit(keptDir)
+
+	// A plaintext output outside the temporary directories
+	output := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(output, []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	RemoveOnExit(output)
+
+	CleanupOnExit()
+
+	for _, path := range []string{leftDir, output} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(keptDir); err != nil {
+		t.Errorf("Expected %s to be kept: %v", keptDir, err)
+	}
+}
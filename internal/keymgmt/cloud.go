@@ -0,0 +1,79 @@
+package keymgmt
+
+import (
+	"bytes"
+	"fmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// GetKeyFromDoppler retrieves an Age key from a Doppler secret and saves it to a temporary file
+// The reference has the form [project/config/]NAME; without a project and config
+// the ones set up for the current directory with 'doppler setup' are used
+func GetKeyFromDoppler(ref string) (string, error) {
+	args := []string{"secrets", "get"}
+
+	parts := strings.Split(ref, "/")
+	switch len(parts) {
+	case 1:
+		args = append(args, parts[0])
+	case 3:
+		args = append(args, parts[2], "--project", parts[0], "--config", parts[1])
+	default:
+		return "", fmt.Errorf("invalid Doppler reference %q, use doppler:[project/config/]NAME", ref)
+	}
+	if args[2] == "" {
+		return "", fmt.Errorf("no Doppler secret specified, use doppler:[project/config/]NAME")
+	}
+	args = append(args, "--plain")
+
+	logging.Debug("Fetching SOPS key from Doppler secret %s...", ref)
+	return getKeyFromCLI("doppler", "Doppler", args...)
+}
+
+// GetKeyFromAWSSecretsManager retrieves an Age key from AWS Secrets Manager and saves it to a temporary file
+// The reference is the secret name or ARN; the region and credentials come from the AWS CLI configuration
+func GetKeyFromAWSSecretsManager(ref string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("no secret specified, use aws-sm:<secret-id>")
+	}
+
+	logging.Debug("Fetching SOPS key from AWS Secrets Manager secret %s...", ref)
+	return getKeyFromCLI("aws", "AWS Secrets Manager",
+		"secretsmanager", "get-secret-value", "--secret-id", ref,
+		"--query", "SecretString", "--output", "text")
+}
+
+// GetKeyFromAWSParameterStore retrieves an Age key from AWS SSM Parameter Store and saves it to a temporary file
+// The reference is the parameter name; SecureString parameters are decrypted
+func GetKeyFromAWSParameterStore(ref string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("no parameter specified, use aws-ssm:<parameter-name>")
+	}
+
+	logging.Debug("Fetching SOPS key from AWS SSM parameter %s...", ref)
+	return getKeyFromCLI("aws", "AWS SSM Parameter Store",
+		"ssm", "get-parameter", "--name", ref, "--with-decryption",
+		"--query", "Parameter.Value", "--output", "text")
+}
+
+// getKeyFromCLI runs a secret manager CLI and saves the Age key it prints to a temporary file
+func getKeyFromCLI(command string, provider string, args ...string) (string, error) {
+	if _, err := lookPathFunc(command); err != nil {
+		return "", fmt.Errorf("%s CLI (%s) not found in PATH. Please install it and try again", provider, command)
+	}
+
+	cmd := execCommand(command, args...)
+	output, err := cmd.Output()
+	defer Wipe(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key from %s: %w", provider, err)
+	}
+
+	if !bytes.Contains(output, []byte("AGE-SECRET-KEY-")) {
+		return "", fmt.Errorf("secret from %s does not contain an Age key", provider)
+	}
+
+	// Create a temporary file for the key
+	return createTempKeyFile(output)
+}
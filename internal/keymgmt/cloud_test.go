@@ -0,0 +1,70 @@
+package keymgmt
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// mockCloudCommand fakes secret manager CLIs, echoing the arguments in the key comment
+func mockCloudCommand(command string, args ...string) *exec.Cmd {
+	response := "# args: " + command + " " + strings.Join(args, " ") + "\n# public key: age123\nAGE-SECRET-KEY-123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ\n"
+
+	cs := []string{"-test.run=TestOpHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "OP_TEST_RESPONSE=" + response}
+	return cmd
+}
+
+func TestCloudKeySources(t *testing.T) {
+	execCommand = mockCloudCommand
+	lookPathFunc = func(file string) (string, error) { return "/usr/local/bin/" + file, nil }
+	defer func() {
+		execCommand = originalExecCommand
+		lookPathFunc = originalLookPath
+	}()
+
+	tests := []struct {
+		spec     string
+		wantArgs string
+	}{
+		{spec: "doppler:SOPS_AGE_KEY", wantArgs: "doppler secrets get SOPS_AGE_KEY --plain"},
+		{spec: "doppler:infra/ci/SOPS_AGE_KEY", wantArgs: "doppler secrets get SOPS_AGE_KEY --project infra --config ci --plain"},
+		{spec: "aws-sm:arn:aws:secretsmanager:eu-west-1:123:secret:age", wantArgs: "aws secretsmanager get-secret-value --secret-id arn:aws:secretsmanager:eu-west-1:123:secret:age"},
+		{spec: "aws-ssm:/ci/age-key", wantArgs: "aws ssm get-parameter --name /ci/age-key --with-decryption"},
+	}
+
+	for _, tt := range tests {
+		keyPath, isTemp, err := ResolveKeyFile(tt.spec)
+		if err != nil {
+			t.Errorf("ResolveKeyFile(%s) failed: %v", tt.spec, err)
+			continue
+		}
+		if !isTemp {
+			t.Errorf("ResolveKeyFile(%s): expected a temporary key file", tt.spec)
+		}
+
+		content, err := os.ReadFile(keyPath)
+		CleanupTempAgeKeyFile(keyPath)
+		if err != nil {
+			t.Fatalf("Failed to read temp key file: %v", err)
+		}
+		if !strings.Contains(string(content), "# args: "+tt.wantArgs) {
+			t.Errorf("ResolveKeyFile(%s): expected args %q, got:\n%s", tt.spec, tt.wantArgs, content)
+		}
+	}
+
+	for _, spec := range []string{"doppler:", "doppler:a/b", "aws-sm:", "aws-ssm:"} {
+		if _, _, err := ResolveKeyFile(spec); err == nil {
+			t.Errorf("ResolveKeyFile(%s): expected error, got nil", spec)
+		}
+	}
+
+	// Missing CLIs are reported
+	lookPathFunc = func(file string) (string, error) { return "", exec.ErrNotFound }
+	if _, err := GetKeyFromDoppler("SOPS_AGE_KEY"); err == nil || !strings.Contains(err.Error(), "doppler") {
+		t.Errorf("Expected missing CLI error, got %v", err)
+	}
+}
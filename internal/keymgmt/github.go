@@ -0,0 +1,231 @@
+package keymgmt
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+)
+
+// githubKeysURL is the URL template for a user's public SSH keys, swappable for testing
+var githubKeysURL = "https://github.com/%s.keys"
+
+// githubAPIURL is the base URL of the GitHub API, swappable for testing
+var githubAPIURL = "https://api.github.com"
+
+// githubTeamPageSize is the number of team members requested per page
+const githubTeamPageSize = 100
+
+// curve25519P is the field prime 2^255 - 19 shared by Ed25519 and X25519
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// GitHubRecipient represents an Age recipient converted from a GitHub user's SSH key
+type GitHubRecipient struct {
+	// User is the GitHub user name
+	User string
+	// Recipient is the Age recipient (age1...)
+	Recipient string
+	// Comment is the comment of the SSH key, if any
+	Comment string
+}
+
+// SSHPublicKeyToAge converts an ssh-ed25519 public key line to an Age recipient.
+// The Edwards point is mapped to its Montgomery form, the same conversion used by
+// ssh-to-age, so the matching identity can be derived from the SSH private key.
+func SSHPublicKeyToAge(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("invalid SSH public key")
+	}
+	if fields[0] != "ssh-ed25519" {
+		return "", fmt.Errorf("unsupported key type %s, only ssh-ed25519 keys can be converted", fields[0])
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode SSH public key: %w", err)
+	}
+
+	keyType, rest, err := readSSHString(blob)
+	if err != nil {
+		return "", err
+	}
+	if string(keyType) != "ssh-ed25519" {
+		return "", fmt.Errorf("SSH key type mismatch: %s", keyType)
+	}
+	edKey, _, err := readSSHString(rest)
+	if err != nil {
+		return "", err
+	}
+	if len(edKey) != 32 {
+		return "", fmt.Errorf("invalid ed25519 public key length %d", len(edKey))
+	}
+
+	montgomery, err := edwardsToMontgomery(edKey)
+	if err != nil {
+		return "", err
+	}
+
+	return bech32Encode("age", montgomery)
+}
+
+// edwardsToMontgomery converts an Ed25519 public key to an X25519 public key
+// using u = (1 + y) / (1 - y) mod p
+func edwardsToMontgomery(edKey []byte) ([]byte, error) {
+	// The key is little-endian y with the sign of x in the top bit
+	yBytes := make([]byte, 32)
+	for i := range edKey {
+		yBytes[31-i] = edKey[i]
+	}
+	yBytes[0] &= 0x7f
+	y := new(big.Int).SetBytes(yBytes)
+
+	denominator := new(big.Int).Sub(big.NewInt(1), y)
+	denominator.Mod(denominator, curve25519P)
+	if denominator.Sign() == 0 {
+		return nil, fmt.Errorf("invalid ed25519 public key")
+	}
+	denominator.ModInverse(denominator, curve25519P)
+
+	u := new(big.Int).Add(big.NewInt(1), y)
+	u.Mul(u, denominator)
+	u.Mod(u, curve25519P)
+
+	uBytes := u.FillBytes(make([]byte, 32))
+	result := make([]byte, 32)
+	for i := range uBytes {
+		result[31-i] = uBytes[i]
+	}
+
+	return result, nil
+}
+
+// FetchGitHubRecipients downloads a user's public SSH keys from GitHub and
+// converts the ed25519 keys to Age recipients. Other key types are skipped.
+func FetchGitHubRecipients(user string) ([]GitHubRecipient, error) {
+	if user == "" || strings.ContainsAny(user, "/?#") {
+		return nil, fmt.Errorf("invalid GitHub user name: %q", user)
+	}
+
+	return fetchSSHRecipients("GitHub", fmt.Sprintf(githubKeysURL, user), user)
+}
+
+// fetchSSHRecipients downloads the public SSH keys of a user of service from
+// url, a list of keys in authorized_keys format, and converts the ed25519 keys
+func fetchSSHRecipients(service string, url string, user string) ([]GitHubRecipient, error) {
+	logging.Debug("Fetching SSH keys from %s", url)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch keys for %s user %s: %w", service, user, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s user %s not found", service, user)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch keys for %s user %s: %s", service, user, resp.Status)
+	}
+
+	var recipients []GitHubRecipient
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1024*1024))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		recipient, err := SSHPublicKeyToAge(line)
+		if err != nil {
+			logging.Debug("Skipping key of %s user %s: %v", service, user, err)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		recipients = append(recipients, GitHubRecipient{
+			User:      user,
+			Recipient: recipient,
+			Comment:   strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read keys for %s user %s: %w", service, user, err)
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%s user %s has no ed25519 SSH keys", service, user)
+	}
+
+	return recipients, nil
+}
+
+// FetchGitHubTeamMembers returns the logins of the members of a GitHub team given as org/team
+// Team members are only visible to members of the organization, so a token
+// with the read:org scope is taken from GITHUB_TOKEN or GH_TOKEN.
+func FetchGitHubTeamMembers(team string) ([]string, error) {
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok || org == "" || slug == "" || strings.ContainsAny(slug, "/?#") {
+		return nil, fmt.Errorf("invalid GitHub team: %q, expected org/team", team)
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("listing the members of %s needs a token with read:org in GITHUB_TOKEN or GH_TOKEN", team)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	var logins []string
+	for page := 1; ; page++ {
+		pageURL := fmt.Sprintf("%s/orgs/%s/teams/%s/members?per_page=%d&page=%d", githubAPIURL, url.PathEscape(org), url.PathEscape(slug), githubTeamPageSize, page)
+		logging.Debug("Fetching team members from %s", pageURL)
+
+		req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the members of %s: %w", team, err)
+		}
+		var members []struct {
+			Login string `json:"login"`
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			err = fmt.Errorf("GitHub team %s not found or not visible with the token", team)
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("failed to fetch the members of %s: %s", team, resp.Status)
+		default:
+			if decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 10*1024*1024)).Decode(&members); decodeErr != nil {
+				err = fmt.Errorf("failed to read the members of %s: %w", team, decodeErr)
+			}
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			logins = append(logins, member.Login)
+		}
+		if len(members) < githubTeamPageSize {
+			return logins, nil
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package keymgmt
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sshEd25519Line formats an ed25519 public key as an authorized_keys line
+func sshEd25519Line(pub ed25519.PublicKey) string {
+	blob := appendSSHString(nil, []byte("ssh-ed25519"))
+	blob = appendSSHString(blob, pub)
+	return "ssh-ed25519 " + base64.StdEncoding.EncodeToString(blob)
+}
+
+// expectedAgeRecipient derives the Age recipient the way ssh-to-age converts the private key
+func expectedAgeRecipient(t *testing.T, priv ed25519.PrivateKey) string {
+	t.Helper()
+	h := sha512.Sum512(priv.Seed())
+	x25519Key, err := ecdh.X25519().NewPrivateKey(h[:32])
+	if err != nil {
+		t.Fatalf("Failed to create X25519 key: %v", err)
+	}
+	recipient, err := bech32Encode("age", x25519Key.PublicKey().Bytes())
+	if err != nil {
+		t.Fatalf("Failed to encode recipient: %v", err)
+	}
+	return recipient
+}
+
+func TestSSHPublicKeyToAge(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+
+		recipient, err := SSHPublicKeyToAge(sshEd25519Line(pub) + " alice@laptop")
+		if err != nil {
+			t.Fatalf("SSHPublicKeyToAge failed: %v", err)
+		}
+
+		if expected := expectedAgeRecipient(t, priv); recipient != expected {
+			t.Errorf("Expected recipient %s, got %s", expected, recipient)
+		}
+	}
+
+	if _, err := SSHPublicKeyToAge("ssh-rsa AAAAB3NzaC1yc2E"); err == nil {
+		t.Error("Expected error for RSA key, got nil")
+	}
+	if _, err := SSHPublicKeyToAge("ssh-ed25519 !!!"); err == nil {
+		t.Error("Expected error for invalid base64, got nil")
+	}
+}
+
+func TestFetchGitHubRecipients(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alice.keys":
+			fmt.Fprintln(w, "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ")
+			fmt.Fprintln(w, sshEd25519Line(pub))
+		case "/bob.keys":
+			fmt.Fprintln(w, "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	originalURL := githubKeysURL
+	githubKeysURL = server.URL + "/%s.keys"
+	defer func() { githubKeysURL = originalURL }()
+
+	recipients, err := FetchGitHubRecipients("alice")
+	if err != nil {
+		t.Fatalf("FetchGitHubRecipients failed: %v", err)
+	}
+	if len(recipients) != 1 {
+		t.Fatalf("Expected 1 recipient, got %d", len(recipients))
+	}
+	if expected := expectedAgeRecipient(t, priv); recipients[0].Recipient != expected {
+		t.Errorf("Expected recipient %s, got %s", expected, recipients[0].Recipient)
+	}
+
+	if _, err := FetchGitHubRecipients("bob"); err == nil {
+		t.Error("Expected error for user without ed25519 keys, got nil")
+	}
+	if _, err := FetchGitHubRecipients("nobody"); err == nil {
+		t.Error("Expected error for unknown user, got nil")
+	}
+	if _, err := FetchGitHubRecipients("../etc"); err == nil {
+		t.Error("Expected error for invalid user name, got nil")
+	}
+}
+
+func TestFetchGitHubTeamMembers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/orgs/acme/teams/infra/members" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first page is full, so the second one is requested too
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, "[")
+			for i := 0; i < githubTeamPageSize; i++ {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"login": "user%d"}`, i)
+			}
+			fmt.Fprint(w, "]")
+			return
+		}
+		fmt.Fprint(w, `[{"login": "alice"}]`)
+	}))
+	defer server.Close()
+
+	originalURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = originalURL }()
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	if _, err := FetchGitHubTeamMembers("acme/infra"); err == nil {
+		t.Error("Expected an error without a token, got nil")
+	}
+
+	t.Setenv("GH_TOKEN", "token")
+	logins, err := FetchGitHubTeamMembers("acme/infra")
+	if err != nil {
+		t.Fatalf("FetchGitHubTeamMembers failed: %v", err)
+	}
+	if len(logins) != githubTeamPageSize+1 || logins[len(logins)-1] != "alice" {
+		t.Errorf("Expected the members of both pages, got %d ending with %q", len(logins), logins[len(logins)-1])
+	}
+
+	if _, err := FetchGitHubTeamMembers("acme/missing"); err == nil {
+		t.Error("Expected error for unknown team, got nil")
+	}
+	if _, err := FetchGitHubTeamMembers("infra"); err == nil {
+		t.Error("Expected error for a team without organization, got nil")
+	}
+}
@@ -0,0 +1,94 @@
+package keymgmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+)
+
+// defaultGitLabURL is the GitLab instance used unless GITLAB_URL is set
+const defaultGitLabURL = "https://gitlab.com"
+
+// gitlabPageSize is the number of group members requested per page
+const gitlabPageSize = 100
+
+// gitlabURL returns the base URL of the GitLab instance, from GITLAB_URL for self-hosted ones
+func gitlabURL() string {
+	if base := os.Getenv("GITLAB_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return defaultGitLabURL
+}
+
+// FetchGitLabRecipients downloads a user's public SSH keys from GitLab and
+// converts the ed25519 keys to Age recipients. Other key types are skipped.
+func FetchGitLabRecipients(user string) ([]GitHubRecipient, error) {
+	if user == "" || strings.ContainsAny(user, "/?#") {
+		return nil, fmt.Errorf("invalid GitLab user name: %q", user)
+	}
+
+	return fetchSSHRecipients("GitLab", fmt.Sprintf("%s/%s.keys", gitlabURL(), user), user)
+}
+
+// FetchGitLabGroupMembers returns the user names of the direct members of a GitLab group given by its path
+// The token is taken from GITLAB_TOKEN and needs the read_api scope.
+func FetchGitLabGroupMembers(group string) ([]string, error) {
+	group = strings.Trim(group, "/")
+	if group == "" || strings.ContainsAny(group, "?#") {
+		return nil, fmt.Errorf("invalid GitLab group: %q", group)
+	}
+
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("listing the members of %s needs a token with read_api in GITLAB_TOKEN", group)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	var usernames []string
+	for page := 1; ; page++ {
+		// The group path is a single, escaped path segment of the API
+		pageURL := fmt.Sprintf("%s/api/v4/groups/%s/members?per_page=%d&page=%d", gitlabURL(), url.PathEscape(group), gitlabPageSize, page)
+		logging.Debug("Fetching group members from %s", pageURL)
+
+		req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("PRIVATE-TOKEN", token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the members of %s: %w", group, err)
+		}
+		var members []struct {
+			Username string `json:"username"`
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			err = fmt.Errorf("GitLab group %s not found or not visible with the token", group)
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("failed to fetch the members of %s: %s", group, resp.Status)
+		default:
+			if decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 10*1024*1024)).Decode(&members); decodeErr != nil {
+				err = fmt.Errorf("failed to read the members of %s: %w", group, decodeErr)
+			}
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			usernames = append(usernames, member.Username)
+		}
+		if len(members) < gitlabPageSize {
+			return usernames, nil
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package keymgmt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchGitLabRecipients(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alice.keys" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, sshEd25519Line(pub))
+	}))
+	defer server.Close()
+
+	t.Setenv("GITLAB_URL", server.URL+"/")
+
+	recipients, err := FetchGitLabRecipients("alice")
+	if err != nil {
+		t.Fatalf("FetchGitLabRecipients failed: %v", err)
+	}
+	if expected := expectedAgeRecipient(t, priv); len(recipients) != 1 || recipients[0].Recipient != expected {
+		t.Errorf("Expected recipient %s, got %v", expected, recipients)
+	}
+
+	if _, err := FetchGitLabRecipients("nobody"); err == nil {
+		t.Error("Expected error for unknown user, got nil")
+	}
+}
+
+func TestFetchGitLabGroupMembers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// Subgroups are addressed by their escaped full path
+		if r.URL.EscapedPath() != "/api/v4/groups/acme%2Finfra/members" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first page is full, so the second one is requested too
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, "[")
+			for i := 0; i < gitlabPageSize; i++ {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"username": "user%d"}`, i)
+			}
+			fmt.Fprint(w, "]")
+			return
+		}
+		fmt.Fprint(w, `[{"username": "alice"}]`)
+	}))
+	defer server.Close()
+
+	t.Setenv("GITLAB_URL", server.URL)
+
+	t.Setenv("GITLAB_TOKEN", "")
+	if _, err := FetchGitLabGroupMembers("acme/infra"); err == nil {
+		t.Error("Expected an error without a token, got nil")
+	}
+
+	t.Setenv("GITLAB_TOKEN", "token")
+	usernames, err := FetchGitLabGroupMembers("acme/infra")
+	if err != nil {
+		t.Fatalf("FetchGitLabGroupMembers failed: %v", err)
+	}
+	if len(usernames) != gitlabPageSize+1 || usernames[len(usernames)-1] != "alice" {
+		t.Errorf("Expected the members of both pages, got %d ending with %q", len(usernames), usernames[len(usernames)-1])
+	}
+
+	if _, err := FetchGitLabGroupMembers("acme/missing"); err == nil {
+		t.Error("Expected error for unknown group, got nil")
+	}
+}
@@ -0,0 +1,82 @@
+package keymgmt
+
+import (
+	"bytes"
+	"fmt"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// GopassItem represents a key stored in gopass
+type GopassItem struct {
+	// Store is the mount the secret lives in, empty for the root store
+	Store string
+	// Path is the path of the secret within the store
+	Path string
+}
+
+// ParseGopassRef parses a gopass reference of the form [store:]path
+func ParseGopassRef(ref string) (GopassItem, error) {
+	var item GopassItem
+	if store, path, found := strings.Cut(ref, ":"); found {
+		item = GopassItem{Store: strings.Trim(store, "/"), Path: path}
+	} else {
+		item = GopassItem{Path: ref}
+	}
+
+	item.Path = strings.Trim(item.Path, "/")
+	if item.Path == "" {
+		return GopassItem{}, fmt.Errorf("no gopass secret specified, use gopass:[store:]path")
+	}
+
+	return item, nil
+}
+
+// SecretPath returns the full gopass path including the mount prefix
+func (i GopassItem) SecretPath() string {
+	if i.Store == "" {
+		return i.Path
+	}
+	return i.Store + "/" + i.Path
+}
+
+// GetKeyFromGopass retrieves an Age key from gopass and saves it to a temporary file
+// The reference has the form [store:]path, e.g. "team:sops/age-key"
+func GetKeyFromGopass(ref string) (string, error) {
+	item, err := ParseGopassRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	logging.Debug("Fetching SOPS key from gopass secret %s...", item.SecretPath())
+
+	// Check if gopass is available
+	if err := checkGopassCLI(); err != nil {
+		return "", err
+	}
+
+	// Get the whole secret, age key files span multiple lines
+	cmd := execCommand("gopass", "show", "--noparsing", item.SecretPath())
+	output, err := cmd.Output()
+	defer Wipe(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key from gopass: %w", err)
+	}
+
+	if !bytes.Contains(output, []byte("AGE-SECRET-KEY-")) {
+		return "", fmt.Errorf("gopass secret %s does not contain an Age key", item.SecretPath())
+	}
+
+	// Create a temporary file for the key
+	return createTempKeyFile(output)
+}
+
+// checkGopassCLI checks if the gopass CLI is available
+func checkGopassCLI() error {
+	_, err := lookPathFunc("gopass")
+	if err != nil {
+		return fmt.Errorf("gopass not found in PATH. Please install it and try again")
+	}
+
+	return nil
+}
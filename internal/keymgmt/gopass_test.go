@@ -0,0 +1,90 @@
+package keymgmt
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// mockGopassCommand fakes 'gopass show', echoing the requested secret in the key comment
+func mockGopassCommand(command string, args ...string) *exec.Cmd {
+	if command != "gopass" {
+		return originalExecCommand(command, args...)
+	}
+
+	secret := args[len(args)-1]
+	response := "# secret: " + secret + "\n# public key: age123\nAGE-SECRET-KEY-123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ\n"
+	if secret == "empty" {
+		response = "not a key\n"
+	}
+
+	cs := []string{"-test.run=TestOpHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "OP_TEST_RESPONSE=" + response}
+	return cmd
+}
+
+func TestParseGopassRef(t *testing.T) {
+	tests := []struct {
+		ref        string
+		secretPath string
+		wantErr    bool
+	}{
+		{ref: "sops/age-key", secretPath: "sops/age-key"},
+		{ref: "team:sops/age-key", secretPath: "team/sops/age-key"},
+		{ref: "team/:/sops/age-key", secretPath: "team/sops/age-key"},
+		{ref: "", wantErr: true},
+		{ref: "team:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		item, err := ParseGopassRef(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseGopassRef(%q): expected error, got nil", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseGopassRef(%q) failed: %v", tt.ref, err)
+			continue
+		}
+		if item.SecretPath() != tt.secretPath {
+			t.Errorf("ParseGopassRef(%q): expected %s, got %s", tt.ref, tt.secretPath, item.SecretPath())
+		}
+	}
+}
+
+func TestGetKeyFromGopass(t *testing.T) {
+	execCommand = mockGopassCommand
+	lookPathFunc = func(file string) (string, error) { return "/usr/local/bin/" + file, nil }
+	defer func() {
+		execCommand = originalExecCommand
+		lookPathFunc = originalLookPath
+	}()
+
+	// Key sources resolve gopass references to temporary key files
+	keyPath, isTemp, err := ResolveKeyFile("gopass:team:sops/age-key")
+	if err != nil {
+		t.Fatalf("ResolveKeyFile failed: %v", err)
+	}
+	defer CleanupTempAgeKeyFile(keyPath)
+
+	if !isTemp {
+		t.Error("Keys from gopass should be temporary")
+	}
+
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to read temp key file: %v", err)
+	}
+	if !strings.Contains(string(content), "# secret: team/sops/age-key") {
+		t.Errorf("Expected secret from the team store, got:\n%s", content)
+	}
+
+	if _, err := GetKeyFromGopass("empty"); err == nil {
+		t.Error("Expected error for secret without an Age key, got nil")
+	}
+}
@@ -0,0 +1,53 @@
+package keymgmt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultLabelsFile is the default path for local recipient labels
+	DefaultLabelsFile = "~/.config/simple-sops/labels.yaml"
+
+	// RepoLabelsFile is the name of the labels file committed next to .sops.yaml
+	RepoLabelsFile = ".sops-keys.yaml"
+)
+
+// LoadKeyLabels loads the labels shown for recipients
+// Labels are read from the trust store, the local labels file and the labels
+// file committed in repoDir, in that order of precedence. The labels files use
+// the format of the trust store, but labelling a key doesn't trust it, so the
+// result is only meant for describing keys.
+func LoadKeyLabels(repoDir string) (*TrustStore, error) {
+	labels := &TrustStore{Keys: []TrustedKey{}}
+
+	sources := []string{DefaultTrustStoreFile, DefaultLabelsFile}
+	if repoDir != "" {
+		sources = append(sources, filepath.Join(repoDir, RepoLabelsFile))
+	}
+
+	for _, source := range sources {
+		store, err := LoadTrustStore(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load labels from %s: %w", source, err)
+		}
+
+		for _, key := range store.Keys {
+			if existing, ok := labels.Lookup(key.PublicKey); ok && existing.Label != "" {
+				continue
+			}
+			labels.Add(key.PublicKey, key.Label)
+		}
+	}
+
+	return labels, nil
+}
+
+// ValidateRecipient checks that a public key looks like an Age recipient
+func ValidateRecipient(publicKey string) error {
+	if !strings.HasPrefix(strings.TrimSpace(publicKey), "age1") {
+		return fmt.Errorf("%s is not an Age recipient (age1...)", publicKey)
+	}
+	return nil
+}
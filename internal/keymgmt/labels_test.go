@@ -0,0 +1,58 @@
+package keymgmt
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKeyLabels(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoDir := t.TempDir()
+
+	trusted := &TrustStore{Keys: []TrustedKey{{PublicKey: "age1alice", Label: "Alice (trusted)"}}}
+	if err := SaveTrustStore(DefaultTrustStoreFile, trusted); err != nil {
+		t.Fatal(err)
+	}
+	local := &TrustStore{Keys: []TrustedKey{
+		{PublicKey: "age1alice", Label: "Alice (local)"},
+		{PublicKey: "age1bob", Label: "Bob (local)"},
+	}}
+	if err := SaveTrustStore(DefaultLabelsFile, local); err != nil {
+		t.Fatal(err)
+	}
+	committed := &TrustStore{Keys: []TrustedKey{
+		{PublicKey: "age1bob", Label: "Bob (repo)"},
+		{PublicKey: "age1ci", Label: "CI"},
+	}}
+	if err := SaveTrustStore(filepath.Join(repoDir, RepoLabelsFile), committed); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := LoadKeyLabels(repoDir)
+	if err != nil {
+		t.Fatalf("LoadKeyLabels failed: %v", err)
+	}
+
+	want := map[string]string{
+		"age1alice": "Alice (trusted)",
+		"age1bob":   "Bob (local)",
+		"age1ci":    "CI",
+	}
+	if len(labels.Keys) != len(want) {
+		t.Errorf("Expected %d labels, got %+v", len(want), labels.Keys)
+	}
+	for pubKey, label := range want {
+		if key, ok := labels.Lookup(pubKey); !ok || key.Label != label {
+			t.Errorf("Expected %s to be labelled %q, got %q", pubKey, label, key.Label)
+		}
+	}
+}
+
+func TestValidateRecipient(t *testing.T) {
+	if err := ValidateRecipient("age1qyqszqgpqyqszqgp"); err != nil {
+		t.Errorf("Expected an Age recipient to be valid, got %v", err)
+	}
+	if err := ValidateRecipient("ssh-ed25519 AAAA"); err == nil {
+		t.Error("Expected an SSH key to be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package keymgmt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-sops/pkg/logging"
+	"strings"
+)
+
+// PIV slots age-plugin-yubikey can use, the retired key management slots
+const (
+	MinPIVSlot = 1
+	MaxPIVSlot = 20
+)
+
+// PIVOptions configure an identity generated on a YubiKey
+type PIVOptions struct {
+	// Slot is the retired PIV slot, 1 to 20
+	Slot int
+	// Serial selects the YubiKey if several are plugged in
+	Serial string
+	// Name labels the identity on the YubiKey
+	Name string
+	// PINPolicy is always, once or never
+	PINPolicy string
+	// TouchPolicy is always, cached or never
+	TouchPolicy string
+}
+
+// Validate checks the slot and the policies
+func (o PIVOptions) Validate() error {
+	if o.Slot < MinPIVSlot || o.Slot > MaxPIVSlot {
+		return fmt.Errorf("invalid PIV slot %d, use %d to %d", o.Slot, MinPIVSlot, MaxPIVSlot)
+	}
+	if o.PINPolicy != "" && o.PINPolicy != "always" && o.PINPolicy != "once" && o.PINPolicy != "never" {
+		return fmt.Errorf("invalid PIN policy %q, use always, once or never", o.PINPolicy)
+	}
+	if o.TouchPolicy != "" && o.TouchPolicy != "always" && o.TouchPolicy != "cached" && o.TouchPolicy != "never" {
+		return fmt.Errorf("invalid touch policy %q, use always, cached or never", o.TouchPolicy)
+	}
+	return nil
+}
+
+// args returns the arguments of age-plugin-yubikey --generate
+func (o PIVOptions) args() []string {
+	args := []string{"--generate", "--slot", fmt.Sprint(o.Slot)}
+	if o.Serial != "" {
+		args = append(args, "--serial", o.Serial)
+	}
+	if o.Name != "" {
+		args = append(args, "--name", o.Name)
+	}
+	if o.PINPolicy != "" {
+		args = append(args, "--pin-policy", o.PINPolicy)
+	}
+	if o.TouchPolicy != "" {
+		args = append(args, "--touch-policy", o.TouchPolicy)
+	}
+	return args
+}
+
+// GeneratePIVKey generates an Age identity on a YubiKey and saves its stub to a file
+// The private key never leaves the YubiKey, the file only references the slot.
+// A "# public key:" line is added, so the recipient can be read like the one
+// of a regular key file. An existing file is only replaced if overwrite is set,
+// and only once the new identity was generated. Returns the recipient.
+func GeneratePIVKey(keyFile string, options PIVOptions, overwrite bool) (string, error) {
+	if err := options.Validate(); err != nil {
+		return "", err
+	}
+
+	expandedPath, err := expandPath(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand path: %w", err)
+	}
+	if _, err := os.Stat(expandedPath); err == nil && !overwrite {
+		return "", fmt.Errorf("key file already exists at %s", expandedPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(expandedPath), 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", filepath.Dir(expandedPath), err)
+	}
+
+	// The plugin asks for the PIN and a touch on the terminal
+	logging.Info("Generating an Age identity in slot %d of the YubiKey...", options.Slot)
+	cmd := execCommand("age-plugin-yubikey", options.args()...)
+	var output bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("age-plugin-yubikey failed: %w", err)
+	}
+
+	recipient, identity, err := parsePIVIdentity(output.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	// Write next to the old file and rename, so it is kept if saving fails
+	content := fmt.Sprintf("# public key: %s\n%s", recipient, identity)
+	if err := writeFileReplacing(expandedPath, []byte(content)); err != nil {
+		return "", fmt.Errorf("failed to save key to file: %w", err)
+	}
+
+	logging.Success("Generated an Age identity on the YubiKey and saved its reference to %s", expandedPath)
+	logging.Info("Public key: %s", recipient)
+	return recipient, nil
+}
+
+// writeFileReplacing writes a private file through a temporary file in the same directory
+func writeFileReplacing(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// parsePIVIdentity extracts the recipient and the identity file of age-plugin-yubikey
+func parsePIVIdentity(output []byte) (string, string, error) {
+	var recipient string
+	var identity strings.Builder
+	found := false
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if value, ok := strings.CutPrefix(trimmed, "Recipient:"); ok && strings.HasPrefix(line, "#") {
+			recipient = strings.TrimSpace(value)
+		}
+		if strings.HasPrefix(line, "AGE-PLUGIN-YUBIKEY-") {
+			found = true
+		}
+		identity.WriteString(line + "\n")
+	}
+
+	if !found {
+		return "", "", fmt.Errorf("age-plugin-yubikey printed no identity")
+	}
+	if !strings.HasPrefix(recipient, "age1yubikey1") {
+		return "", "", fmt.Errorf("age-plugin-yubikey printed no recipient")
+	}
+	return recipient, identity.String(), nil
+}
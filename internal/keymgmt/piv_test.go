@@ -0,0 +1,95 @@
+package keymgmt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pivOutput is an identity printed by age-plugin-yubikey --generate
+const pivOutput = `#       Serial: 12345678, Slot: 2
+#         Name: age identity 1a2b3c4d
+#      Created: Fri, 16 Oct 2026 10:00:00 +0000
+#   PIN policy: Once   (A PIN is required once per session, if set)
+# Touch policy: Cached (A physical touch is required for decryption, and is cached for 15 seconds)
+#    Recipient: age1yubikey1qexample
+AGE-PLUGIN-YUBIKEY-1EXAMPLE
+`
+
+func TestParsePIVIdentity(t *testing.T) {
+	recipient, identity, err := parsePIVIdentity([]byte(pivOutput))
+	if err != nil {
+		t.Fatalf("parsePIVIdentity() error = %v", err)
+	}
+	if recipient != "age1yubikey1qexample" {
+		t.Errorf("recipient = %q, want age1yubikey1qexample", recipient)
+	}
+	if identity != pivOutput {
+		t.Errorf("identity = %q, want the whole output", identity)
+	}
+
+	if _, _, err := parsePIVIdentity([]byte("#    Recipient: age1yubikey1qexample\n")); err == nil {
+		t.Error("parsePIVIdentity() accepted output without an identity")
+	}
+}
+
+func TestPIVOptions(t *testing.T) {
+	options := PIVOptions{Slot: 2, Name: "laptop", TouchPolicy: "cached"}
+	want := []string{"--generate", "--slot", "2", "--name", "laptop", "--touch-policy", "cached"}
+	if got := options.args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %v, want %v", got, want)
+	}
+
+	for _, invalid := range []PIVOptions{{Slot: 0}, {Slot: 21}, {Slot: 1, PINPolicy: "sometimes"}, {Slot: 1, TouchPolicy: "once"}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestGeneratePIVKey(t *testing.T) {
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := append([]string{"-test.run=TestOpHelperProcess", "--", command}, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "OP_TEST_RESPONSE=" + pivOutput}
+		return cmd
+	}
+	defer func() { execCommand = originalExecCommand }()
+
+	keyFile := filepath.Join(t.TempDir(), "yubikey.txt")
+	recipient, err := GeneratePIVKey(keyFile, PIVOptions{Slot: 2}, false)
+	if err != nil {
+		t.Fatalf("GeneratePIVKey() error = %v", err)
+	}
+	if recipient != "age1yubikey1qexample" {
+		t.Errorf("recipient = %q, want age1yubikey1qexample", recipient)
+	}
+
+	// The recipient can be read like the one of a regular key file
+	pubKey, err := GetPublicKeyFromFile(keyFile)
+	if err != nil || pubKey != recipient {
+		t.Errorf("GetPublicKeyFromFile() = %q, %v, want %s", pubKey, err, recipient)
+	}
+	content, _ := os.ReadFile(keyFile)
+	if !strings.Contains(string(content), "AGE-PLUGIN-YUBIKEY-1EXAMPLE") {
+		t.Errorf("key file doesn't reference the identity:\n%s", content)
+	}
+
+	if _, err := GeneratePIVKey(keyFile, PIVOptions{Slot: 2}, false); err == nil {
+		t.Error("GeneratePIVKey() overwrote an existing key file")
+	}
+
+	// A failed generation keeps the existing key file
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 1")
+	}
+	if _, err := GeneratePIVKey(keyFile, PIVOptions{Slot: 2}, true); err == nil {
+		t.Fatal("GeneratePIVKey() succeeded although age-plugin-yubikey failed")
+	}
+	if kept, _ := os.ReadFile(keyFile); string(kept) != string(content) {
+		t.Errorf("key file changed after a failed generation:\n%s", kept)
+	}
+}
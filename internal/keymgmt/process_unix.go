@@ -0,0 +1,32 @@
+//go:build !windows
+
+package keymgmt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// exitSignals make HandleExitSignals clean up and exit
+var exitSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// ProcessAlive checks if a process is running
+// A process owned by another user can't be signaled, but exists.
+func ProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// checkOwner checks if a file is owned by the current user
+func checkOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("owned by user %d instead of %d", stat.Uid, os.Getuid())
+	}
+	return nil
+}
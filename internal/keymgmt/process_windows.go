@@ -0,0 +1,40 @@
+//go:build windows
+
+package keymgmt
+
+import (
+	"os"
+	"syscall"
+)
+
+// processQueryLimitedInformation is the access right needed to open any process
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code of a process that hasn't exited
+const stillActive = 259
+
+// exitSignals make HandleExitSignals clean up and exit
+// Go delivers the console events Ctrl+C and Ctrl+Break as os.Interrupt, and
+// closing the console, logging off and shutting down as SIGTERM.
+var exitSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// ProcessAlive checks if a process is running
+func ProcessAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
+
+// checkOwner checks if a file is owned by the current user
+// Directories in the user profile are protected by ACLs, so there is nothing to check.
+func checkOwner(info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,179 @@
+package keymgmt
+
+import (
+	"fmt"
+	"simple-sops/pkg/logging"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// disableCoreDumpsOnce makes sure core dumps are only disabled once per process
+var disableCoreDumpsOnce sync.Once
+
+// LockedBuffer holds key material in memory that is locked against swapping
+// and wiped when the buffer is destroyed
+type LockedBuffer struct {
+	// memory is the whole locked allocation, data the part in use
+	memory []byte
+	data   []byte
+	locked bool
+}
+
+// NewLockedBuffer allocates a locked buffer of the given size. Locking is best
+// effort: if the memory limit is too low, the buffer is still wiped on Destroy.
+// Core dumps are disabled for the rest of the process once key material is held.
+func NewLockedBuffer(size int) *LockedBuffer {
+	DisableCoreDumps()
+
+	memory := make([]byte, size)
+	buf := &LockedBuffer{memory: memory, data: memory}
+	if size > 0 {
+		if err := lockMemory(buf.memory); err != nil {
+			logging.Debug("Failed to lock key memory: %v", err)
+		} else {
+			buf.locked = true
+		}
+	}
+
+	return buf
+}
+
+// LockedBufferFrom moves data into a locked buffer and wipes the source
+func LockedBufferFrom(data []byte) *LockedBuffer {
+	buf := NewLockedBuffer(len(data))
+	copy(buf.data, data)
+	Wipe(data)
+	return buf
+}
+
+// Bytes returns the buffer contents, which are only valid until Destroy
+func (b *LockedBuffer) Bytes() []byte {
+	return b.data
+}
+
+// Destroy wipes and unlocks the buffer
+func (b *LockedBuffer) Destroy() {
+	Wipe(b.memory)
+	if b.locked {
+		if err := unlockMemory(b.memory); err != nil {
+			logging.Debug("Failed to unlock key memory: %v", err)
+		}
+		b.locked = false
+	}
+	b.memory = nil
+	b.data = nil
+}
+
+// Wipe overwrites data with zeros
+func Wipe(data []byte) {
+	clear(data)
+}
+
+// DisableCoreDumps prevents key material from ending up in core dumps
+func DisableCoreDumps() {
+	disableCoreDumpsOnce.Do(func() {
+		if err := disableCoreDumps(); err != nil {
+			logging.Debug("Failed to disable core dumps: %v", err)
+		}
+	})
+}
+
+// decodeJSONString decodes a raw JSON string literal into a locked buffer,
+// so the decoded key never exists as an immutable Go string
+func decodeJSONString(raw []byte) (*LockedBuffer, error) {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return nil, fmt.Errorf("invalid JSON string")
+	}
+	raw = raw[1 : len(raw)-1]
+
+	// The decoded value is never longer than the escaped one
+	buf := NewLockedBuffer(len(raw))
+	n := 0
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' {
+			buf.data[n] = c
+			n++
+			continue
+		}
+
+		i++
+		if i >= len(raw) {
+			buf.Destroy()
+			return nil, fmt.Errorf("invalid escape in JSON string")
+		}
+
+		switch raw[i] {
+		case '"', '\\', '/':
+			buf.data[n] = raw[i]
+		case 'b':
+			buf.data[n] = '\b'
+		case 'f':
+			buf.data[n] = '\f'
+		case 'n':
+			buf.data[n] = '\n'
+		case 'r':
+			buf.data[n] = '\r'
+		case 't':
+			buf.data[n] = '\t'
+		case 'u':
+			r, size, ok := decodeJSONEscape(raw[i-1:])
+			if !ok {
+				buf.Destroy()
+				return nil, fmt.Errorf("invalid unicode escape in JSON string")
+			}
+			n += utf8.EncodeRune(buf.data[n:], r)
+			i += size - 2
+			continue
+		default:
+			buf.Destroy()
+			return nil, fmt.Errorf("invalid escape in JSON string")
+		}
+		n++
+	}
+
+	buf.data = buf.data[:n]
+	return buf, nil
+}
+
+// decodeJSONEscape decodes a \uXXXX escape, including surrogate pairs, and
+// returns the rune and the number of bytes consumed
+func decodeJSONEscape(s []byte) (rune, int, bool) {
+	r, ok := parseHex4(s)
+	if !ok {
+		return 0, 0, false
+	}
+	if utf16.IsSurrogate(r) {
+		if r2, ok := parseHex4(s[6:]); ok {
+			if decoded := utf16.DecodeRune(r, r2); decoded != utf8.RuneError {
+				return decoded, 12, true
+			}
+		}
+		return utf8.RuneError, 6, true
+	}
+	return r, 6, true
+}
+
+// parseHex4 parses the four hex digits of a \uXXXX escape
+func parseHex4(s []byte) (rune, bool) {
+	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+		return 0, false
+	}
+
+	var r rune
+	for _, c := range s[2:6] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package keymgmt
+
+import "syscall"
+
+// prSetDumpable is the prctl option controlling core dumps and ptrace access
+const prSetDumpable = 4
+
+// disableCoreDumps marks the process as not dumpable. Unlike RLIMIT_CORE this
+// is reset on exec, so commands started by run can still dump core.
+func disableCoreDumps() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetDumpable, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
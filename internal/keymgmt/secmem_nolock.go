@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package keymgmt
+
+// lockMemory is a no-op on platforms without mlock, the buffer is still wiped
+func lockMemory(data []byte) error {
+	return nil
+}
+
+// unlockMemory is a no-op like lockMemory
+func unlockMemory(data []byte) error {
+	return nil
+}
+
+// disableCoreDumps is a no-op on platforms without core dumps
+func disableCoreDumps() error {
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build unix && !linux
+
+package keymgmt
+
+import "syscall"
+
+// disableCoreDumps sets the core file size limit to zero
+func disableCoreDumps() error {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &limit); err != nil {
+		return err
+	}
+	limit.Cur = 0
+	return syscall.Setrlimit(syscall.RLIMIT_CORE, &limit)
+}
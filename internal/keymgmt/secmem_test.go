@@ -0,0 +1,63 @@
+package keymgmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLockedBuffer(t *testing.T) {
+	source := []byte("AGE-SECRET-KEY-123")
+	buf := LockedBufferFrom(source)
+
+	if string(buf.Bytes()) != "AGE-SECRET-KEY-123" {
+		t.Errorf("Unexpected buffer content: %q", buf.Bytes())
+	}
+	if !bytes.Equal(source, make([]byte, len(source))) {
+		t.Error("Source should be wiped after moving it into the buffer")
+	}
+
+	memory := buf.Bytes()
+	buf.Destroy()
+	if !bytes.Equal(memory, make([]byte, len(memory))) {
+		t.Error("Buffer should be wiped on Destroy")
+	}
+	if buf.Bytes() != nil {
+		t.Error("Destroyed buffer should be empty")
+	}
+}
+
+func TestDecodeJSONString(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: `"plain"`, want: "plain"},
+		{raw: `"# public key: age1\nAGE-SECRET-KEY-1"`, want: "# public key: age1\nAGE-SECRET-KEY-1"},
+		{raw: `"quote \" slash \/ backslash \\ tab \t"`, want: "quote \" slash / backslash \\ tab \t"},
+		{raw: `"é😀"`, want: "é😀"},
+		{raw: `"\u00e9\ud83d\ude00"`, want: "é😀"},
+		{raw: `""`, want: ""},
+		{raw: `plain`, wantErr: true},
+		{raw: `"bad \x"`, wantErr: true},
+		{raw: `"bad \u12"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		buf, err := decodeJSONString([]byte(tt.raw))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("decodeJSONString(%s): expected error, got nil", tt.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("decodeJSONString(%s) failed: %v", tt.raw, err)
+			continue
+		}
+		if string(buf.Bytes()) != tt.want {
+			t.Errorf("decodeJSONString(%s): expected %q, got %q", tt.raw, tt.want, buf.Bytes())
+		}
+		buf.Destroy()
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package keymgmt
+
+import "golang.org/x/sys/unix"
+
+// lockMemory prevents the memory from being swapped to disk
+func lockMemory(data []byte) error {
+	return unix.Mlock(data)
+}
+
+// unlockMemory releases a lock taken by lockMemory
+func unlockMemory(data []byte) error {
+	return unix.Munlock(data)
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package keymgmt
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// lockMemory prevents the memory from being paged to disk
+func lockMemory(data []byte) error {
+	return syscall.VirtualLock(uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+}
+
+// unlockMemory releases a lock taken by lockMemory
+func unlockMemory(data []byte) error {
+	return syscall.VirtualUnlock(uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+}
+
+// disableCoreDumps is a no-op on Windows, which doesn't write core dumps by default
+func disableCoreDumps() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package keymgmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keySources maps key file prefixes (e.g. "ssh-agent:") to providers that
+// fetch the key at runtime and return the path of a temporary key file
+var keySources = map[string]func(ref string) (string, error){
+	"ssh-agent": GetKeyFromSSHAgent,
+	"gopass":    GetKeyFromGopass,
+	"doppler":   GetKeyFromDoppler,
+	"aws-sm":    GetKeyFromAWSSecretsManager,
+	"aws-ssm":   GetKeyFromAWSParameterStore,
+}
+
+// splitKeySource splits a key file spec like "ssh-agent:comment" into its provider and reference
+func splitKeySource(spec string) (func(ref string) (string, error), string, bool) {
+	name, ref, found := strings.Cut(spec, ":")
+	if !found {
+		return nil, "", false
+	}
+
+	source, ok := keySources[name]
+	return source, ref, ok
+}
+
+// IsKeySource reports whether a key file spec refers to a runtime key source instead of a file
+func IsKeySource(spec string) bool {
+	_, _, ok := splitKeySource(spec)
+	return ok
+}
+
+// ResolveKeyFile returns the path of a key file, fetching the key from its
+// source if needed. The boolean reports whether the file is temporary.
+func ResolveKeyFile(spec string) (string, bool, error) {
+	if source, ref, ok := splitKeySource(spec); ok {
+		tempKeyFile, err := source(ref)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get key from %s: %w", spec, err)
+		}
+		return tempKeyFile, true, nil
+	}
+
+	expandedPath, err := expandPath(spec)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	return expandedPath, false, nil
+}
@@ -0,0 +1,259 @@
+package keymgmt
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+)
+
+// ssh-agent protocol message types
+const (
+	agentFailure           = 5
+	agentRequestIdentities = 11
+	agentIdentitiesAnswer  = 12
+	agentSignRequest       = 13
+	agentSignResponse      = 14
+)
+
+// agentDerivationContext separates signatures made for simple-sops from any other use of the key
+const agentDerivationContext = "simple-sops age identity derivation v1"
+
+// AgentKey represents a public key held by the SSH agent
+type AgentKey struct {
+	// Type is the SSH key type, e.g. ssh-ed25519
+	Type string
+	// Blob is the SSH wire encoding of the public key
+	Blob []byte
+	// Comment is the key comment, usually user@host or the file name
+	Comment string
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key as shown by ssh-add -l
+func (k AgentKey) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// dialAgent connects to the agent from SSH_AUTH_SOCK
+func dialAgent() (net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set, is ssh-agent running?")
+	}
+
+	conn, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+
+	return conn, nil
+}
+
+// agentRequest sends a message to the agent and returns the reply
+func agentRequest(conn net.Conn, msgType byte, payload []byte) (byte, []byte, error) {
+	request := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(request, uint32(1+len(payload)))
+	request[4] = msgType
+	copy(request[5:], payload)
+
+	if _, err := conn.Write(request); err != nil {
+		return 0, nil, fmt.Errorf("failed to write to ssh-agent: %w", err)
+	}
+
+	var length uint32
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from ssh-agent: %w", err)
+	}
+	if length == 0 || length > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid ssh-agent response length %d", length)
+	}
+
+	reply := make([]byte, length)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from ssh-agent: %w", err)
+	}
+
+	return reply[0], reply[1:], nil
+}
+
+// readSSHString reads a length-prefixed string from SSH wire data
+func readSSHString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("truncated ssh-agent message")
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) < length {
+		return nil, nil, fmt.Errorf("truncated ssh-agent message")
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+// appendSSHString appends a length-prefixed string in SSH wire format
+func appendSSHString(buf []byte, value []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+	return append(buf, value...)
+}
+
+// ListAgentKeys returns the keys held by the SSH agent
+func ListAgentKeys() ([]AgentKey, error) {
+	conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	msgType, reply, err := agentRequest(conn, agentRequestIdentities, nil)
+	if err != nil {
+		return nil, err
+	}
+	if msgType != agentIdentitiesAnswer || len(reply) < 4 {
+		return nil, fmt.Errorf("unexpected ssh-agent response type %d", msgType)
+	}
+
+	count := binary.BigEndian.Uint32(reply)
+	rest := reply[4:]
+	var keys []AgentKey
+	for i := uint32(0); i < count; i++ {
+		var blob, comment []byte
+		if blob, rest, err = readSSHString(rest); err != nil {
+			return nil, err
+		}
+		if comment, rest, err = readSSHString(rest); err != nil {
+			return nil, err
+		}
+
+		keyType, _, err := readSSHString(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, AgentKey{Type: string(keyType), Blob: blob, Comment: string(comment)})
+	}
+
+	return keys, nil
+}
+
+// signWithAgent asks the agent to sign data with a key
+func signWithAgent(key AgentKey, data []byte) ([]byte, error) {
+	conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	payload := appendSSHString(nil, key.Blob)
+	payload = appendSSHString(payload, data)
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+
+	msgType, reply, err := agentRequest(conn, agentSignRequest, payload)
+	if err != nil {
+		return nil, err
+	}
+	if msgType == agentFailure {
+		return nil, fmt.Errorf("ssh-agent refused to sign with %s", key.Comment)
+	}
+	if msgType != agentSignResponse {
+		return nil, fmt.Errorf("unexpected ssh-agent response type %d", msgType)
+	}
+
+	signatureBlob, _, err := readSSHString(reply)
+	if err != nil {
+		return nil, err
+	}
+	_, rest, err := readSSHString(signatureBlob)
+	if err != nil {
+		return nil, err
+	}
+	signature, _, err := readSSHString(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// DeriveAgeKeyFromAgent derives an Age identity from an ed25519 key in the SSH agent.
+// Ed25519 signatures are deterministic, so signing a fixed challenge always yields the
+// same secret, which is hashed into an X25519 private key. The SSH private key never
+// leaves the agent. Returns the identity (AGE-SECRET-KEY-1...) and recipient (age1...).
+func DeriveAgeKeyFromAgent(key AgentKey) (string, string, error) {
+	if key.Type != "ssh-ed25519" {
+		return "", "", fmt.Errorf("unsupported key type %s, only ssh-ed25519 keys can be used", key.Type)
+	}
+
+	challenge := append([]byte(agentDerivationContext), key.Blob...)
+	signature, err := signWithAgent(key, challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	seed := sha256.Sum256(append([]byte(agentDerivationContext), signature...))
+	privateKey, err := ecdh.X25519().NewPrivateKey(seed[:])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to derive X25519 key: %w", err)
+	}
+
+	identity, err := bech32Encode("AGE-SECRET-KEY-", privateKey.Bytes())
+	if err != nil {
+		return "", "", err
+	}
+	recipient, err := bech32Encode("age", privateKey.PublicKey().Bytes())
+	if err != nil {
+		return "", "", err
+	}
+
+	return strings.ToUpper(identity), recipient, nil
+}
+
+// selectAgentKey picks the agent key matching a comment or fingerprint, or the first ed25519 key
+func selectAgentKey(keys []AgentKey, selector string) (AgentKey, error) {
+	for _, key := range keys {
+		if key.Type != "ssh-ed25519" {
+			continue
+		}
+		if selector == "" || key.Comment == selector || key.Fingerprint() == selector {
+			return key, nil
+		}
+	}
+
+	if selector != "" {
+		return AgentKey{}, fmt.Errorf("no ed25519 key matching %s found in ssh-agent", selector)
+	}
+	return AgentKey{}, fmt.Errorf("no ed25519 key found in ssh-agent")
+}
+
+// GetKeyFromSSHAgent derives an Age key from the SSH agent and saves it to a temporary file
+// The selector matches a key comment or fingerprint; empty selects the first ed25519 key
+func GetKeyFromSSHAgent(selector string) (string, error) {
+	keys, err := ListAgentKeys()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := selectAgentKey(keys, selector)
+	if err != nil {
+		return "", err
+	}
+
+	logging.Debug("Deriving Age key from ssh-agent key %s (%s)", key.Comment, key.Fingerprint())
+	identity, recipient, err := DeriveAgeKeyFromAgent(key)
+	if err != nil {
+		return "", err
+	}
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "# derived from ssh-agent key: %s %s\n", key.Fingerprint(), key.Comment)
+	fmt.Fprintf(&content, "# public key: %s\n", recipient)
+	fmt.Fprintf(&content, "%s\n", identity)
+
+	return CreateTempAgeKeyFile(content.String())
+}
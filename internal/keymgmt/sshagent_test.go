@@ -0,0 +1,138 @@
+package keymgmt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// startFakeAgent serves a minimal ssh-agent holding one ed25519 key
+func startFakeAgent(t *testing.T) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	blob := appendSSHString(nil, []byte("ssh-ed25519"))
+	blob = appendSSHString(blob, privateKey.Public().(ed25519.PublicKey))
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets not available: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	reply := func(conn net.Conn, msgType byte, payload []byte) {
+		msg := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)))
+		msg = append(msg, msgType)
+		conn.Write(append(msg, payload...))
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var length uint32
+				if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+					return
+				}
+				request := make([]byte, length)
+				io.ReadFull(conn, request)
+
+				switch request[0] {
+				case agentRequestIdentities:
+					payload := binary.BigEndian.AppendUint32(nil, 1)
+					payload = appendSSHString(payload, blob)
+					payload = appendSSHString(payload, []byte("test@host"))
+					reply(conn, agentIdentitiesAnswer, payload)
+				case agentSignRequest:
+					_, rest, _ := readSSHString(request[1:])
+					data, _, _ := readSSHString(rest)
+					signature := appendSSHString(nil, []byte("ssh-ed25519"))
+					signature = appendSSHString(signature, ed25519.Sign(privateKey, data))
+					reply(conn, agentSignResponse, appendSSHString(nil, signature))
+				default:
+					reply(conn, agentFailure, nil)
+				}
+			}(conn)
+		}
+	}()
+}
+
+func TestBech32Encode(t *testing.T) {
+	// Test vectors from BIP 173
+	encoded, err := bech32Encode("a", nil)
+	if err != nil || encoded != "a12uel5l" {
+		t.Errorf("Expected a12uel5l, got %s (%v)", encoded, err)
+	}
+
+	data := []byte{0x00, 0x44, 0x32, 0x14, 0xc7, 0x42, 0x54, 0xb6, 0x35, 0xcf, 0x84, 0x65, 0x3a, 0x56, 0xd7, 0xc6, 0x75, 0xbe, 0x77, 0xdf}
+	encoded, err = bech32Encode("abcdef", data)
+	if err != nil || encoded != "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw" {
+		t.Errorf("Expected abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw, got %s (%v)", encoded, err)
+	}
+}
+
+func TestGetKeyFromSSHAgent(t *testing.T) {
+	startFakeAgent(t)
+
+	keys, err := ListAgentKeys()
+	if err != nil {
+		t.Fatalf("ListAgentKeys failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Type != "ssh-ed25519" || keys[0].Comment != "test@host" {
+		t.Fatalf("Unexpected agent keys: %+v", keys)
+	}
+
+	// Derivation is deterministic
+	identity, recipient, err := DeriveAgeKeyFromAgent(keys[0])
+	if err != nil {
+		t.Fatalf("DeriveAgeKeyFromAgent failed: %v", err)
+	}
+	identity2, recipient2, _ := DeriveAgeKeyFromAgent(keys[0])
+	if identity != identity2 || recipient != recipient2 {
+		t.Error("Derived keys should be stable across calls")
+	}
+	if !strings.HasPrefix(identity, "AGE-SECRET-KEY-1") || len(identity) != 74 {
+		t.Errorf("Unexpected identity format: %s", identity)
+	}
+	if !strings.HasPrefix(recipient, "age1") || len(recipient) != 62 {
+		t.Errorf("Unexpected recipient format: %s", recipient)
+	}
+
+	// The key source produces a usable temporary key file
+	keyPath, isTemp, err := ResolveKeyFile("ssh-agent:test@host")
+	if err != nil {
+		t.Fatalf("ResolveKeyFile failed: %v", err)
+	}
+	defer CleanupTempAgeKeyFile(keyPath)
+	if !isTemp {
+		t.Error("Keys from ssh-agent should be temporary")
+	}
+	pubKey, err := GetPublicKeyFromFile(keyPath)
+	if err != nil || pubKey != recipient {
+		t.Errorf("Expected public key %s, got %s (%v)", recipient, pubKey, err)
+	}
+
+	if _, _, err := ResolveKeyFile("ssh-agent:unknown"); err == nil {
+		t.Error("Expected error for unknown agent key, got nil")
+	}
+
+	// Plain paths are not key sources
+	if IsKeySource(filepath.Join(os.TempDir(), "key.txt")) {
+		t.Error("Plain paths should not be treated as key sources")
+	}
+}
@@ -0,0 +1,96 @@
+package keymgmt
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tempKeyDirPrefix starts the name of the directories holding temporary keys
+const tempKeyDirPrefix = "simple-sops-"
+
+// tempKeyFileName is the name of temporary key files
+const tempKeyFileName = "age-key.txt"
+
+// combinedTempKeyFileName is the name of temporary files combining several keys
+const combinedTempKeyFileName = "age-keys.txt"
+
+// KeyState describes the key SOPS_AGE_KEY_FILE points to
+type KeyState string
+
+const (
+	// KeyNone means SOPS_AGE_KEY_FILE is not set
+	KeyNone KeyState = "none"
+	// KeyPersistent means SOPS_AGE_KEY_FILE points to a regular key file
+	KeyPersistent KeyState = "file"
+	// KeyTemporary means SOPS_AGE_KEY_FILE points to a key written by get-key
+	KeyTemporary KeyState = "temp"
+	// KeyMissing means SOPS_AGE_KEY_FILE points to a file that doesn't exist
+	KeyMissing KeyState = "missing"
+)
+
+// KeyStatus describes the loaded key and the temporary keys on disk
+type KeyStatus struct {
+	State KeyState
+	// Path is the value of SOPS_AGE_KEY_FILE
+	Path string
+	// Age is how long a temporary key has existed
+	Age time.Duration
+	// TempKeys is the number of temporary keys in the temp directory, including the loaded one
+	TempKeys int
+}
+
+// IsTempKeyFile checks if a key file was written to a simple-sops temporary directory
+func IsTempKeyFile(keyFile string) bool {
+	return IsInTempDir(keyFile)
+}
+
+// TempKeyFiles returns the temporary key files left in the temp directory
+func TempKeyFiles() ([]string, error) {
+	dirs, err := TempDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyFiles []string
+	for _, dir := range dirs {
+		for _, name := range []string{tempKeyFileName, combinedTempKeyFileName} {
+			if path := filepath.Join(dir, name); isRegularFile(path) {
+				keyFiles = append(keyFiles, path)
+			}
+		}
+	}
+	return keyFiles, nil
+}
+
+// isRegularFile checks if a path is an existing regular file
+func isRegularFile(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// CurrentKeyStatus returns the state of the key in SOPS_AGE_KEY_FILE
+func CurrentKeyStatus(now time.Time) KeyStatus {
+	status := KeyStatus{State: KeyNone, Path: os.Getenv("SOPS_AGE_KEY_FILE")}
+
+	if tempKeys, err := TempKeyFiles(); err == nil {
+		status.TempKeys = len(tempKeys)
+	}
+
+	if status.Path == "" {
+		return status
+	}
+
+	info, err := os.Stat(status.Path)
+	switch {
+	case err != nil:
+		status.State = KeyMissing
+	case IsTempKeyFile(status.Path):
+		status.State = KeyTemporary
+		status.Age = now.Sub(info.ModTime())
+	default:
+		status.State = KeyPersistent
+	}
+
+	return status
+}
@@ -0,0 +1,56 @@
+package keymgmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCurrentKeyStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	t.Setenv("XDG_RUNTIME_DIR", tempDir)
+
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	if status := CurrentKeyStatus(time.Now()); status.State != KeyNone || status.TempKeys != 0 {
+		t.Errorf("Expected no key, got %+v", status)
+	}
+
+	// A key loaded by get-key
+	tempKey, err := CreateTempAgeKeyFile(mockKeyContent)
+	if err != nil {
+		t.Fatalf("Failed to create temporary key: %v", err)
+	}
+	if _, err := CreateTempAgeKeyFile(mockKeyContent2); err != nil {
+		t.Fatalf("Failed to create temporary key: %v", err)
+	}
+	t.Setenv("SOPS_AGE_KEY_FILE", tempKey)
+
+	status := CurrentKeyStatus(time.Now().Add(10 * time.Minute))
+	if status.State != KeyTemporary || status.Path != tempKey || status.TempKeys != 2 {
+		t.Errorf("Expected a temporary key and 2 temporary keys on disk, got %+v", status)
+	}
+	if status.Age < 9*time.Minute || status.Age > 11*time.Minute {
+		t.Errorf("Expected the key to be about 10 minutes old, got %s", status.Age)
+	}
+
+	// A regular key file
+	keyFile := filepath.Join(tempDir, "key.txt")
+	if err := os.WriteFile(keyFile, []byte(mockKeyContent), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	t.Setenv("SOPS_AGE_KEY_FILE", keyFile)
+	if status := CurrentKeyStatus(time.Now()); status.State != KeyPersistent || status.Age != 0 {
+		t.Errorf("Expected a persistent key, got %+v", status)
+	}
+
+	// A cleared key still referenced by the environment
+	if err := CleanupTempAgeKeyFile(tempKey); err != nil {
+		t.Fatalf("Failed to clean up temporary key: %v", err)
+	}
+	t.Setenv("SOPS_AGE_KEY_FILE", tempKey)
+	if status := CurrentKeyStatus(time.Now()); status.State != KeyMissing || status.TempKeys != 1 {
+		t.Errorf("Expected a missing key and 1 temporary key on disk, got %+v", status)
+	}
+}
@@ -0,0 +1,55 @@
+package keymgmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxStdinIdentitySize limits the identity read from stdin
+const maxStdinIdentitySize = 64 * 1024
+
+// stdinIdentity holds the identity read with --identity-stdin
+var stdinIdentity *LockedBuffer
+
+// ReadStdinIdentity reads Age identities from r and uses them for every key lookup
+// It is meant for stdin, so wrapper scripts can pass the key without files or
+// environment variables. The identity is read straight into locked memory and
+// only written to a private temporary key file while sops runs.
+func ReadStdinIdentity(r io.Reader) error {
+	buffer := NewLockedBuffer(maxStdinIdentitySize + 1)
+	n, err := io.ReadFull(r, buffer.Bytes())
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		buffer.Destroy()
+		return fmt.Errorf("failed to read the identity from stdin: %w", err)
+	}
+	if n > maxStdinIdentitySize {
+		buffer.Destroy()
+		return fmt.Errorf("the identity on stdin is larger than %d bytes", maxStdinIdentitySize)
+	}
+	buffer.data = buffer.data[:n]
+
+	if !bytes.Contains(buffer.Bytes(), []byte("AGE-SECRET-KEY-")) {
+		buffer.Destroy()
+		return fmt.Errorf("no Age identity (AGE-SECRET-KEY-...) found on stdin")
+	}
+
+	ClearStdinIdentity()
+	stdinIdentity = buffer
+	return nil
+}
+
+// HasStdinIdentity reports whether the identity was read from stdin
+// Stdin is consumed then, so commands can't read other input from it.
+func HasStdinIdentity() bool {
+	return stdinIdentity != nil
+}
+
+// ClearStdinIdentity wipes the identity read from stdin
+func ClearStdinIdentity() {
+	if stdinIdentity != nil {
+		stdinIdentity.Destroy()
+		stdinIdentity = nil
+	}
+}
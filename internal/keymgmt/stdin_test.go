@@ -0,0 +1,58 @@
+package keymgmt
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadStdinIdentity(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	defer ClearStdinIdentity()
+
+	identity := "# public key: age1example\nAGE-SECRET-KEY-1EXAMPLE\n"
+	if err := ReadStdinIdentity(strings.NewReader(identity)); err != nil {
+		t.Fatalf("ReadStdinIdentity() error = %v", err)
+	}
+	if !HasStdinIdentity() {
+		t.Fatal("HasStdinIdentity() = false after reading an identity")
+	}
+
+	// The identity replaces the configured key file
+	keyPath, isTemp, err := EnsureAgeKey("/nonexistent/key.txt", false, false)
+	if err != nil {
+		t.Fatalf("EnsureAgeKey() error = %v", err)
+	}
+	defer CleanupTempAgeKeyFile(keyPath)
+	if !isTemp {
+		t.Error("EnsureAgeKey() returned the identity as a permanent key file")
+	}
+	content, err := os.ReadFile(keyPath)
+	if err != nil || string(content) != identity {
+		t.Errorf("key file = %q, %v, want the identity", content, err)
+	}
+
+	ClearStdinIdentity()
+	if HasStdinIdentity() {
+		t.Error("HasStdinIdentity() = true after clearing the identity")
+	}
+}
+
+func TestReadStdinIdentityInvalid(t *testing.T) {
+	defer ClearStdinIdentity()
+
+	for name, input := range map[string]string{
+		"empty":     "",
+		"no key":    "hunter2\n",
+		"too large": "AGE-SECRET-KEY-1EXAMPLE\n" + strings.Repeat("#", maxStdinIdentitySize),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := ReadStdinIdentity(strings.NewReader(input)); err == nil {
+				t.Error("ReadStdinIdentity() succeeded, want an error")
+			}
+			if HasStdinIdentity() {
+				t.Error("HasStdinIdentity() = true after an invalid identity")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package keymgmt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"simple-sops/pkg/logging"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTempMaxAge is how old an orphaned temporary directory must be before it is removed
+const DefaultTempMaxAge = time.Hour
+
+// tempOwnerFileName holds the PID of the process a temporary directory belongs to
+const tempOwnerFileName = ".owner"
+
+// tempDirOwner is the PID recorded in new temporary directories, 0 for the current process
+var tempDirOwner int
+
+// SetTempDirOwner sets the process new temporary directories belong to
+// get-key hands its key to the calling shell, so the directory must outlive
+// the simple-sops process.
+func SetTempDirOwner(pid int) {
+	tempDirOwner = pid
+}
+
+// CreateTempDir creates a private temporary directory for keys or decrypted files
+// The directory records its owner, so gc can tell orphaned directories from
+// ones still in use.
+func CreateTempDir() (string, error) {
+	root, err := TempRoot()
+	if err != nil {
+		return "", err
+	}
+
+	tempDir, err := os.MkdirTemp(root, tempKeyDirPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	owner := tempDirOwner
+	if owner == 0 {
+		owner = os.Getpid()
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, tempOwnerFileName), []byte(strconv.Itoa(owner)), 0600); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("failed to write temporary directory owner: %w", err)
+	}
+
+	RemoveOnExit(tempDir)
+	return tempDir, nil
+}
+
+// TempRoot returns the private directory of the current user for temporary directories
+// $XDG_RUNTIME_DIR is preferred since it is usually kept in memory, then the
+// user cache directory. The directory must be owned by the user and is
+// restricted to mode 0700, so other users can't race for its content.
+func TempRoot() (string, error) {
+	root := tempRootPath()
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory %s: %w", root, err)
+	}
+
+	info, err := os.Lstat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat temporary directory %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("temporary directory %s is not a directory", root)
+	}
+	if err := checkOwner(info); err != nil {
+		return "", fmt.Errorf("refusing to use temporary directory %s: %w", root, err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(root, 0700); err != nil {
+			return "", fmt.Errorf("failed to restrict permissions of %s: %w", root, err)
+		}
+	}
+
+	return root, nil
+}
+
+// tempRootPath returns the path of the private temporary directory without creating it
+func tempRootPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "simple-sops")
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "simple-sops", "tmp")
+	}
+	// The dot keeps the directory out of the legacy simple-sops-* glob
+	return filepath.Join(os.TempDir(), fmt.Sprintf("simple-sops.%d", os.Getuid()))
+}
+
+// IsInTempDir checks if a file is directly inside a simple-sops temporary directory
+func IsInTempDir(path string) bool {
+	return strings.HasPrefix(filepath.Base(filepath.Dir(path)), tempKeyDirPrefix)
+}
+
+// TempDirs returns the simple-sops temporary directories of the current user
+// Older versions wrote them to the shared temp directory, where only the ones
+// owned by the user are returned.
+func TempDirs() ([]string, error) {
+	var dirs []string
+	for _, parent := range []string{tempRootPath(), os.TempDir()} {
+		matches, err := filepath.Glob(filepath.Join(parent, tempKeyDirPrefix+"*"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if info, err := os.Lstat(match); err == nil && info.IsDir() && checkOwner(info) == nil {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// IsOrphanedTempDir checks if a temporary directory is older than maxAge and
+// its owner is no longer running
+// Directories written by versions without an owner file only need to be old enough.
+func IsOrphanedTempDir(dir string, maxAge time.Duration, now time.Time) bool {
+	info, err := os.Lstat(dir)
+	if err != nil || now.Sub(info.ModTime()) < maxAge {
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, tempOwnerFileName))
+	if err != nil {
+		return true
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return true
+	}
+	return !ProcessAlive(pid)
+}
+
+// SweepTempDirs removes orphaned temporary directories and returns their paths
+// With dryRun the directories are only returned.
+func SweepTempDirs(maxAge time.Duration, dryRun bool) ([]string, error) {
+	dirs, err := TempDirs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list temporary directories: %w", err)
+	}
+
+	now := time.Now()
+	var removed []string
+	for _, dir := range dirs {
+		if !IsOrphanedTempDir(dir, maxAge, now) {
+			continue
+		}
+		if !dryRun {
+			if err := RemoveSecurely(dir); err != nil {
+				logging.Debug("Failed to remove %s: %v", dir, err)
+				continue
+			}
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// RemoveSecurely overwrites a file, or the files in a directory, with zeros and removes it
+// On copy-on-write filesystems and SSDs the old blocks may survive, so this
+// only narrows the window in which plaintext can be recovered.
+func RemoveSecurely(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		return overwriteFile(path)
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	KeepOnExit(dir)
+	return nil
+}
+
+// overwriteFile replaces the content of a file with zeros of the same length
+func overwriteFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if _, err := file.Write(make([]byte, info.Size())); err != nil {
+		return fmt.Errorf("failed to overwrite %s: %w", path, err)
+	}
+	return file.Sync()
+}
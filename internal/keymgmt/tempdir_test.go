@@ -0,0 +1,132 @@
+package keymgmt
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSweepTempDirs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	defer SetTempDirOwner(0)
+
+	// A directory of this process, which is still running
+	ownDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+
+	// A directory of a process that exited
+	exitedPID := exitedProcess(t)
+	SetTempDirOwner(exitedPID)
+	orphanedDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+	SetTempDirOwner(0)
+	if err := os.WriteFile(filepath.Join(orphanedDir, "secrets.yaml.plain"), []byte("password: s3cret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write plaintext: %v", err)
+	}
+
+	// A directory of an older version without an owner
+	legacyDir := filepath.Join(os.TempDir(), "simple-sops-legacy")
+	if err := os.Mkdir(legacyDir, 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ownDir, tempOwnerFileName))
+	if err != nil || string(data) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected owner %d, got %q (%v)", os.Getpid(), data, err)
+	}
+
+	// Nothing is old enough yet
+	if removed, err := SweepTempDirs(time.Hour, false); err != nil || len(removed) != 0 {
+		t.Errorf("Expected no directories to be removed, got %v (%v)", removed, err)
+	}
+
+	// Age the directories
+	old := time.Now().Add(-2 * time.Hour)
+	for _, dir := range []string{ownDir, orphanedDir, legacyDir} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatalf("Failed to age %s: %v", dir, err)
+		}
+	}
+
+	removed, err := SweepTempDirs(time.Hour, true)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Expected 2 orphaned directories in a dry run, got %v (%v)", removed, err)
+	}
+	if _, err := os.Stat(orphanedDir); err != nil {
+		t.Errorf("Expected dry run to keep %s", orphanedDir)
+	}
+
+	if _, err := SweepTempDirs(time.Hour, false); err != nil {
+		t.Fatalf("SweepTempDirs failed: %v", err)
+	}
+	for dir, kept := range map[string]bool{ownDir: true, orphanedDir: false, legacyDir: false} {
+		if _, err := os.Stat(dir); (err == nil) != kept {
+			t.Errorf("Expected %s kept = %v, got stat error %v", dir, kept, err)
+		}
+	}
+}
+
+func TestTempRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	// A root created with loose permissions is restricted
+	root := filepath.Join(runtimeDir, "simple-sops")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if got, err := TempRoot(); err != nil || got != root {
+		t.Fatalf("Expected %s, got %s (%v)", root, got, err)
+	}
+	if info, err := os.Stat(root); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected mode 0700, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	tempDir, err := CreateTempDir()
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+	if filepath.Dir(tempDir) != root || !IsInTempDir(filepath.Join(tempDir, tempKeyFileName)) {
+		t.Errorf("Expected a temporary directory in %s, got %s", root, tempDir)
+	}
+
+	// A symlink planted in place of the root is refused
+	otherDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", otherDir)
+	if err := os.Symlink(t.TempDir(), filepath.Join(otherDir, "simple-sops")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if _, err := TempRoot(); err == nil {
+		t.Error("Expected a symlinked root to be refused")
+	}
+}
+
+// exitedProcess returns the PID of a process that has exited
+func exitedProcess(t *testing.T) int {
+	t.Helper()
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Failed to find test binary: %v", err)
+	}
+	process, err := os.StartProcess(executable, []string{executable, "-test.run=^$"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	if _, err := process.Wait(); err != nil {
+		t.Fatalf("Failed to wait for process: %v", err)
+	}
+	return process.Pid
+}
@@ -0,0 +1,179 @@
+package keymgmt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultTrustStoreFile is the default path for the recipient trust store
+	DefaultTrustStoreFile = "~/.config/simple-sops/trust.yaml"
+)
+
+// TrustedKey represents a known public key and who it belongs to
+type TrustedKey struct {
+	// PublicKey is the Age recipient (age1...)
+	PublicKey string `yaml:"public_key"`
+	// Label describes the owner of the key
+	Label string `yaml:"label"`
+}
+
+// TrustStore is the local list of known recipients
+type TrustStore struct {
+	Keys []TrustedKey `yaml:"keys"`
+}
+
+// LoadTrustStore loads the trust store from a file
+// A missing file results in an empty trust store
+func LoadTrustStore(path string) (*TrustStore, error) {
+	expandedPath, err := expandPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	data, err := os.ReadFile(expandedPath)
+	if os.IsNotExist(err) {
+		return &TrustStore{Keys: []TrustedKey{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+
+	var store TrustStore
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse trust store: %w", err)
+	}
+
+	return &store, nil
+}
+
+// SaveTrustStore saves the trust store to a file
+func SaveTrustStore(path string, store *TrustStore) error {
+	expandedPath, err := expandPath(path)
+	if err != nil {
+		return fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(expandedPath), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trust store: %w", err)
+	}
+
+	if err := os.WriteFile(expandedPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write trust store: %w", err)
+	}
+
+	return nil
+}
+
+// Add adds a key to the trust store or updates its label if it is already known
+func (s *TrustStore) Add(publicKey string, label string) {
+	publicKey = strings.TrimSpace(publicKey)
+	for i, key := range s.Keys {
+		if key.PublicKey == publicKey {
+			s.Keys[i].Label = label
+			return
+		}
+	}
+
+	s.Keys = append(s.Keys, TrustedKey{PublicKey: publicKey, Label: label})
+}
+
+// Remove removes a key from the trust store
+func (s *TrustStore) Remove(publicKey string) error {
+	for i, key := range s.Keys {
+		if key.PublicKey == publicKey {
+			s.Keys = append(s.Keys[:i], s.Keys[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("key %s is not in the trust store", publicKey)
+}
+
+// Lookup returns the trusted key entry for a public key
+func (s *TrustStore) Lookup(publicKey string) (TrustedKey, bool) {
+	publicKey = strings.TrimSpace(publicKey)
+	for _, key := range s.Keys {
+		if key.PublicKey == publicKey {
+			return key, true
+		}
+	}
+
+	return TrustedKey{}, false
+}
+
+// UnknownKeys returns the public keys that are not in the trust store
+func (s *TrustStore) UnknownKeys(publicKeys []string) []string {
+	var unknown []string
+	for _, pubKey := range publicKeys {
+		if _, ok := s.Lookup(pubKey); !ok {
+			unknown = append(unknown, strings.TrimSpace(pubKey))
+		}
+	}
+
+	return unknown
+}
+
+// Fingerprint shortens a public key to its start and end
+// That is enough to tell keys apart at a glance and to match them against the
+// full key.
+func Fingerprint(publicKey string) string {
+	publicKey = strings.TrimSpace(publicKey)
+	if len(publicKey) <= 20 {
+		return publicKey
+	}
+	return publicKey[:10] + "..." + publicKey[len(publicKey)-6:]
+}
+
+// Describe returns the label and fingerprint of a public key
+// Keys missing from the trust store are shown by their fingerprint only.
+func (s *TrustStore) Describe(publicKey string) string {
+	if key, ok := s.Lookup(publicKey); ok && key.Label != "" {
+		return fmt.Sprintf("%s (%s)", key.Label, Fingerprint(publicKey))
+	}
+	return Fingerprint(publicKey)
+}
+
+// DescribeAll returns the descriptions of several public keys as a comma-separated list
+func (s *TrustStore) DescribeAll(publicKeys []string) string {
+	descriptions := make([]string, len(publicKeys))
+	for i, pubKey := range publicKeys {
+		descriptions[i] = s.Describe(pubKey)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// Resolve returns the trusted key a reference denotes
+// The reference is a full public key, a fingerprint or a label. A label or
+// fingerprint shared by several keys is rejected.
+func (s *TrustStore) Resolve(ref string) (TrustedKey, error) {
+	ref = strings.TrimSpace(ref)
+	if key, ok := s.Lookup(ref); ok {
+		return key, nil
+	}
+
+	var matches []TrustedKey
+	for _, key := range s.Keys {
+		if key.Label == ref || Fingerprint(key.PublicKey) == ref {
+			matches = append(matches, key)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return TrustedKey{}, fmt.Errorf("no key in the trust store matches %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return TrustedKey{}, fmt.Errorf("%s matches %d keys in the trust store, use the public key", ref, len(matches))
+	}
+}
@@ -0,0 +1,98 @@
+package keymgmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrustStore(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "trust-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	storePath := filepath.Join(tempDir, "trust.yaml")
+
+	// Loading a missing store returns an empty store
+	store, err := LoadTrustStore(storePath)
+	if err != nil {
+		t.Fatalf("LoadTrustStore should not fail for missing file: %v", err)
+	}
+	if len(store.Keys) != 0 {
+		t.Errorf("Expected empty trust store, got %d keys", len(store.Keys))
+	}
+
+	// Add keys and update a label
+	store.Add("age123", "Alice")
+	store.Add("age456", "Bob")
+	store.Add("age123", "Alice (laptop)")
+	if len(store.Keys) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(store.Keys))
+	}
+
+	if err := SaveTrustStore(storePath, store); err != nil {
+		t.Fatalf("SaveTrustStore failed: %v", err)
+	}
+
+	// Reload and check contents
+	store, err = LoadTrustStore(storePath)
+	if err != nil {
+		t.Fatalf("LoadTrustStore failed: %v", err)
+	}
+	key, ok := store.Lookup("age123")
+	if !ok || key.Label != "Alice (laptop)" {
+		t.Errorf("Expected label 'Alice (laptop)', got '%s'", key.Label)
+	}
+
+	unknown := store.UnknownKeys([]string{"age123", "age789"})
+	if len(unknown) != 1 || unknown[0] != "age789" {
+		t.Errorf("Expected [age789] unknown, got %v", unknown)
+	}
+
+	// Remove keys
+	if err := store.Remove("age456"); err != nil {
+		t.Errorf("Remove failed: %v", err)
+	}
+	if err := store.Remove("age456"); err == nil {
+		t.Error("Expected error removing unknown key, got nil")
+	}
+}
+
+func TestTrustStoreDescribe(t *testing.T) {
+	alice := "age1qyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqs3290gq"
+	bob := "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj"
+	store := &TrustStore{Keys: []TrustedKey{
+		{PublicKey: alice, Label: "Alice"},
+		{PublicKey: bob, Label: "Team"},
+		{PublicKey: "age1other", Label: "Team"},
+	}}
+
+	if got := Fingerprint(alice); got != "age1qyqszq...3290gq" {
+		t.Errorf("Fingerprint(alice) = %q", got)
+	}
+	if got := Fingerprint("age123"); got != "age123" {
+		t.Errorf("Expected short keys to be kept, got %q", got)
+	}
+
+	unknown := "age1lggyhqrw2nlhcxprm67z43rta597azn8gknawjehu9d9dl0jq3yqqvfafg"
+	if got := store.DescribeAll([]string{alice, unknown}); got != "Alice (age1qyqszq...3290gq), age1lggyhq...qvfafg" {
+		t.Errorf("DescribeAll = %q", got)
+	}
+
+	// Keys are resolved by key, label or fingerprint
+	for _, ref := range []string{alice, "Alice", "age1qyqszq...3290gq"} {
+		key, err := store.Resolve(ref)
+		if err != nil || key.PublicKey != alice {
+			t.Errorf("Resolve(%q) = %v, %v, want Alice's key", ref, key, err)
+		}
+	}
+	if _, err := store.Resolve("Team"); err == nil {
+		t.Error("Expected an ambiguous label to be rejected")
+	}
+	if _, err := store.Resolve("Carol"); err == nil {
+		t.Error("Expected an unknown label to be rejected")
+	}
+}
@@ -0,0 +1,170 @@
+// Package lint flags weak secret values like short or well-known passwords
+// before they are encrypted
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"simple-sops/internal/config"
+	"simple-sops/internal/dotenv"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding is a weak value
+type Finding struct {
+	// Path is the sops tree path of the value
+	Path string
+	// Reason describes why the value is weak
+	Reason string
+}
+
+// commonWords are the bases of passwords found in breach corpora
+// They are matched case-insensitively and with trailing digits and symbols,
+// which covers variations like Password1 or Summer2024!.
+var commonWords = []string{
+	"password", "passwort", "passw0rd", "p@ssw0rd", "welcome", "letmein", "qwerty",
+	"qwertz", "azerty", "admin", "administrator", "root", "toor", "login",
+	"dragon", "monkey", "football", "baseball", "iloveyou", "master", "shadow",
+	"sunshine", "princess", "trustno1", "secret", "changeme", "default",
+	"summer", "winter", "spring", "autumn", "test", "guest", "user", "demo",
+	"example", "sample", "temp", "abc", "hello",
+}
+
+// placeholders are values that are obviously not real secrets
+var placeholders = regexp.MustCompile(`(?i)^(todo|tbd|fixme|changeme|change_me|replace_?me|your[_-].*|x+|\*+|<.*>|\$\{.*\}|null|none|empty)$`)
+
+// commonPattern matches a common word with trailing digits and symbols
+var commonPattern = regexp.MustCompile(`(?i)^(` + strings.Join(quoteAll(commonWords), "|") + `)[0-9]*[!@#$%^&*.?_-]*$`)
+
+// sequences are runs that keyboard walks and counting produce
+var sequences = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"01234567890",
+	"qwertyuiop", "asdfghjkl", "zxcvbnm",
+	"qwertzuiop", "yxcvbnm",
+	"1qaz2wsx3edc",
+}
+
+// quoteAll escapes words for a regular expression
+func quoteAll(words []string) []string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return quoted
+}
+
+// Check returns the reasons a value is weak
+func Check(value string, minLength int) []string {
+	var reasons []string
+	lower := strings.ToLower(value)
+
+	switch {
+	case placeholders.MatchString(value):
+		reasons = append(reasons, "looks like a placeholder")
+	case commonPattern.MatchString(value):
+		reasons = append(reasons, "is based on a common password")
+	case isSequence(lower):
+		reasons = append(reasons, "is a keyboard or counting sequence")
+	case distinctCharacters(value) <= 2:
+		reasons = append(reasons, "repeats the same characters")
+	}
+
+	if len([]rune(value)) < minLength {
+		reasons = append(reasons, fmt.Sprintf("is shorter than %d characters", minLength))
+	}
+	return reasons
+}
+
+// isSequence checks if a value is a run of a sequence, forwards or backwards
+func isSequence(value string) bool {
+	if len(value) < 4 {
+		return false
+	}
+	reversed := []rune(value)
+	slices.Reverse(reversed)
+	for _, sequence := range sequences {
+		if strings.Contains(sequence, value) || strings.Contains(sequence, string(reversed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// distinctCharacters counts the different characters of a value
+func distinctCharacters(value string) int {
+	seen := make(map[rune]bool)
+	for _, r := range value {
+		seen[r] = true
+	}
+	return len(seen)
+}
+
+// CheckFile returns the weak values in the plaintext content of a file
+// Only values whose key matches the settings are checked. Files that are
+// neither YAML, JSON nor dotenv are skipped.
+func CheckFile(fileName string, content []byte, settings *config.LintSettings) ([]Finding, error) {
+	keys, err := regexp.Compile(settings.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lint keys: %w", err)
+	}
+
+	values := make(map[string]string)
+	if dotenv.IsDotenvFile(fileName) {
+		entries, err := dotenv.Parse(content)
+		if err != nil {
+			return nil, nil
+		}
+		for _, entry := range entries {
+			if !entry.IsComment() && keys.MatchString(entry.Key) {
+				values[fmt.Sprintf("[%q]", entry.Key)] = entry.Value
+			}
+		}
+	} else {
+		var document yaml.Node
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return nil, nil
+		}
+		collect(&document, "", false, keys, values)
+	}
+
+	var findings []Finding
+	for path, value := range values {
+		if slices.Contains(settings.Ignore, path) || value == "" || strings.HasPrefix(value, "ENC[") {
+			continue
+		}
+		for _, reason := range Check(value, settings.MinLength) {
+			findings = append(findings, Finding{Path: path, Reason: reason})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Path < findings[j].Path })
+	return findings, nil
+}
+
+// collect gathers the scalar values below keys matching the pattern
+func collect(node *yaml.Node, path string, selected bool, keys *regexp.Regexp, values map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collect(child, path, selected, keys, values)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			collect(node.Content[i+1], fmt.Sprintf("%s[%q]", path, key), selected || keys.MatchString(key), keys, values)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			collect(child, fmt.Sprintf("%s[%d]", path, i), selected, keys, values)
+		}
+	case yaml.ScalarNode:
+		if selected && node.Tag != "!!null" && !strings.Contains(node.Value, "\n") {
+			values[path] = node.Value
+		}
+	}
+}
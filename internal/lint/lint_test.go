@@ -0,0 +1,80 @@
+package lint
+
+import (
+	"simple-sops/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	weak := map[string]string{
+		"short":                   "shorter than 12",
+		"Summer2024!":             "common password",
+		"P@ssw0rd123":             "common password",
+		"changeme":                "placeholder",
+		"<your-token>":            "placeholder",
+		"your_password_here":      "placeholder",
+		"1234567890":              "sequence",
+		"qwertyuiop":              "sequence",
+		"zyxwvutsrqponm":          "sequence",
+		"aaaaaaaaaaaaaaaa":        "repeats",
+		"abababababababab":        "repeats",
+		"administrator12345!!!!!": "common password",
+	}
+	for value, expected := range weak {
+		reasons := Check(value, 12)
+		if !strings.Contains(strings.Join(reasons, ", "), expected) {
+			t.Errorf("Check(%q) = %v, expected a reason containing %q", value, reasons, expected)
+		}
+	}
+
+	for _, value := range []string{"c7Gq-2vLx_9TzKp", "correct horse battery staple", "8f14e45fceea167a5a36dedd4bea2543"} {
+		if reasons := Check(value, 12); len(reasons) > 0 {
+			t.Errorf("Expected %q to pass, got %v", value, reasons)
+		}
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	settings := &config.LintSettings{MinLength: 12, Keys: config.DefaultLintKeys, Ignore: []string{`["legacy"]["password"]`}}
+
+	content := []byte(`db:
+  host: localhost
+  user: admin
+  password: hunter2
+  passwords:
+    - letmein
+    - c7Gq-2vLx_9TzKp
+legacy:
+  password: changeme
+api_token: ENC[AES256_GCM,data:abc]
+empty_secret:
+`)
+	findings, err := CheckFile("secrets.yaml", content, settings)
+	if err != nil {
+		t.Fatalf("CheckFile failed: %v", err)
+	}
+
+	var paths []string
+	for _, finding := range findings {
+		paths = append(paths, finding.Path)
+	}
+	expected := `["db"]["password"],["db"]["passwords"][0],["db"]["passwords"][0]`
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("Expected findings at %s, got %+v", expected, findings)
+	}
+
+	// Dotenv files are checked by variable name
+	findings, err = CheckFile(".env", []byte("DB_PASSWORD=password1\nDB_HOST=localhost\n"), settings)
+	if err != nil {
+		t.Fatalf("CheckFile failed for dotenv: %v", err)
+	}
+	if len(findings) == 0 || findings[0].Path != `["DB_PASSWORD"]` {
+		t.Errorf("Unexpected dotenv findings: %+v", findings)
+	}
+
+	// Files in other formats are skipped
+	if findings, err := CheckFile("cert.pem", []byte("-----BEGIN CERTIFICATE-----\n:\n"), settings); err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings for other formats, got %+v, %v", findings, err)
+	}
+}
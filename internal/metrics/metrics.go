@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHost is the host metrics are served on if the address has none
+const DefaultHost = "127.0.0.1"
+
+// Counter is a value that only increases
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc increases the counter by one
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Value returns the current value
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Gauge is a value that is set to the latest measurement
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the value
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// Summary counts observations and adds them up, e.g. durations
+type Summary struct {
+	mu    sync.Mutex
+	count uint64
+	sum   float64
+}
+
+// Observe adds an observation
+func (s *Summary) Observe(value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.sum += value
+}
+
+// Values returns the number and the sum of the observations
+func (s *Summary) Values() (uint64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count, s.sum
+}
+
+// Metrics of simple-sops
+// They are always recorded, and only served by long-running modes.
+var (
+	// Decrypts counts files decrypted by sops
+	Decrypts = &Counter{}
+	// DecryptCacheHits counts decryptions served from the cache of unchanged files
+	DecryptCacheHits = &Counter{}
+	// DecryptFailures counts failed decryptions
+	DecryptFailures = &Counter{}
+	// KeyFetchSeconds observes how long getting the Age key took
+	KeyFetchSeconds = &Summary{}
+	// LastSyncTimestamp is the Unix time the files were last decrypted successfully
+	LastSyncTimestamp = &Gauge{}
+)
+
+// ObserveKeyFetch records the duration of getting the Age key since start
+func ObserveKeyFetch(start time.Time) {
+	KeyFetchSeconds.Observe(time.Since(start).Seconds())
+}
+
+// MarkSynced records that the files were decrypted successfully now
+func MarkSynced() {
+	LastSyncTimestamp.Set(float64(time.Now().Unix()))
+}
+
+// WriteText writes the metrics in the Prometheus text format
+func WriteText(w io.Writer) error {
+	count, sum := KeyFetchSeconds.Values()
+	_, err := fmt.Fprintf(w, `# HELP simple_sops_decrypts_total Files decrypted by sops.
+# TYPE simple_sops_decrypts_total counter
+simple_sops_decrypts_total %d
+# HELP simple_sops_decrypt_cache_hits_total Decryptions served from the cache of unchanged files.
+# TYPE simple_sops_decrypt_cache_hits_total counter
+simple_sops_decrypt_cache_hits_total %d
+# HELP simple_sops_decrypt_failures_total Failed decryptions.
+# TYPE simple_sops_decrypt_failures_total counter
+simple_sops_decrypt_failures_total %d
+# HELP simple_sops_key_fetch_seconds Time taken to get the Age key.
+# TYPE simple_sops_key_fetch_seconds summary
+simple_sops_key_fetch_seconds_sum %s
+simple_sops_key_fetch_seconds_count %d
+# HELP simple_sops_last_sync_timestamp_seconds Unix time of the last successful decryption of all files, 0 if none.
+# TYPE simple_sops_last_sync_timestamp_seconds gauge
+simple_sops_last_sync_timestamp_seconds %s
+`,
+		Decrypts.Value(),
+		DecryptCacheHits.Value(),
+		DecryptFailures.Value(),
+		formatFloat(sum), count,
+		formatFloat(LastSyncTimestamp.Value()))
+	return err
+}
+
+// formatFloat formats a value the way Prometheus parses it
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Handler serves the metrics at any path
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
+
+// Serve serves the metrics at /metrics on addr until the returned function is called
+// An address without a host, like :9090, is served on localhost only.
+func Serve(addr string) (func(), error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics address %s: %w", addr, err)
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go server.Serve(listener)
+
+	return func() { server.Close() }, nil
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	decrypts := Decrypts.Value()
+	Decrypts.Inc()
+	DecryptFailures.Inc()
+	KeyFetchSeconds.Observe(0.5)
+	LastSyncTimestamp.Set(1700000000)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text format, got %s", recorder.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE simple_sops_decrypts_total counter\n",
+		"simple_sops_decrypts_total " + strconv.FormatUint(decrypts+1, 10) + "\n",
+		"simple_sops_key_fetch_seconds_count 1\n",
+		"simple_sops_key_fetch_seconds_sum 0.5\n",
+		"simple_sops_last_sync_timestamp_seconds 1700000000\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the metrics, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestServeInvalidAddress(t *testing.T) {
+	if _, err := Serve("9090"); err == nil {
+		t.Error("Expected an error for an address without a port separator")
+	}
+}
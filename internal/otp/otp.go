@@ -0,0 +1,125 @@
+// Package otp computes time-based one-time passwords (RFC 6238) from
+// otpauth:// URIs, so encrypted files can hold 2FA secrets
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TOTP holds the parameters of a time-based one-time password
+type TOTP struct {
+	// Secret is the decoded shared secret
+	Secret []byte
+	// Algorithm is the HMAC hash: SHA1, SHA256 or SHA512
+	Algorithm string
+	// Digits is the length of the code, 6 or 8
+	Digits int
+	// Period is how long a code is valid
+	Period time.Duration
+}
+
+// algorithms maps otpauth algorithm names to hash functions
+var algorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// Parse parses an otpauth://totp/ URI
+// Missing parameters get the defaults of the Key Uri Format: SHA1, 6 digits
+// and a period of 30 seconds.
+func Parse(uri string) (*TOTP, error) {
+	parsed, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || parsed.Scheme != "otpauth" {
+		return nil, fmt.Errorf("not an otpauth:// URI")
+	}
+	if parsed.Host != "totp" {
+		return nil, fmt.Errorf("unsupported OTP type %q, only totp is supported", parsed.Host)
+	}
+
+	query := parsed.Query()
+	secret, err := decodeSecret(query.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+
+	totp := &TOTP{Secret: secret, Algorithm: "SHA1", Digits: 6, Period: 30 * time.Second}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		totp.Algorithm = strings.ToUpper(algorithm)
+		if _, ok := algorithms[totp.Algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported algorithm %q, expected SHA1, SHA256 or SHA512", algorithm)
+		}
+	}
+
+	if digits := query.Get("digits"); digits != "" {
+		totp.Digits, err = strconv.Atoi(digits)
+		if err != nil || totp.Digits < 6 || totp.Digits > 8 {
+			return nil, fmt.Errorf("invalid digits %q, expected 6 to 8", digits)
+		}
+	}
+
+	if period := query.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid period %q, expected a number of seconds", period)
+		}
+		totp.Period = time.Duration(seconds) * time.Second
+	}
+
+	return totp, nil
+}
+
+// decodeSecret decodes a base32 secret, which is often written without
+// padding, in lower case or in groups separated by spaces
+func decodeSecret(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("otpauth URI has no secret")
+	}
+
+	normalized := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(normalized, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: not base32")
+	}
+	return decoded, nil
+}
+
+// Code returns the code that is valid at a time
+func (t *TOTP) Code(at time.Time) string {
+	counter := uint64(at.Unix()) / uint64(t.Period/time.Second)
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(algorithms[t.Algorithm], t.Secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation as defined by RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%modulo)
+}
+
+// Remaining returns how long the code of a time stays valid
+func (t *TOTP) Remaining(at time.Time) time.Duration {
+	period := int64(t.Period / time.Second)
+	return time.Duration(period-at.Unix()%period) * time.Second
+}
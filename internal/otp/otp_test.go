@@ -0,0 +1,74 @@
+package otp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// Test vectors from RFC 6238, appendix B
+	secrets := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1234567890, "SHA256", "91819424"},
+		{2000000000, "SHA512", "38618901"},
+	}
+
+	for _, tt := range tests {
+		secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(secrets[tt.algorithm]))
+		uri := "otpauth://totp/Example:alice?secret=" + url.QueryEscape(secret) + "&algorithm=" + tt.algorithm + "&digits=8"
+
+		totp, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", uri, err)
+		}
+		if got := totp.Code(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("Code(%d) with %s = %s, want %s", tt.unix, tt.algorithm, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	// Defaults and a lower case secret in groups
+	totp, err := Parse("otpauth://totp/GitHub:alice?secret=jbsw%20y3dp%20ehpk%203pxp&issuer=GitHub")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if totp.Algorithm != "SHA1" || totp.Digits != 6 || totp.Period != 30*time.Second {
+		t.Errorf("Unexpected defaults: %+v", totp)
+	}
+	if string(totp.Secret) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("Unexpected secret: %q", totp.Secret)
+	}
+	if remaining := totp.Remaining(time.Unix(65, 0)); remaining != 25*time.Second {
+		t.Errorf("Expected 25s remaining, got %s", remaining)
+	}
+
+	invalid := []string{
+		"https://example.com",
+		"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/alice",
+		"otpauth://totp/alice?secret=not-base32!",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=0",
+	}
+	for _, uri := range invalid {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("Expected error for %s", uri)
+		}
+	}
+}
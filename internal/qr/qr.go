@@ -0,0 +1,32 @@
+// Package qr renders values as QR codes in the terminal
+package qr
+
+import (
+	"fmt"
+	"io"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// MaxLength is the longest value that still fits into a scannable QR code
+// Larger codes exist, but most terminals and phone cameras can't resolve them.
+const MaxLength = 1024
+
+// Render writes a value as a QR code drawn with block characters
+// The light modules are drawn, so the code scans on dark terminal backgrounds.
+func Render(w io.Writer, value string) error {
+	if value == "" {
+		return fmt.Errorf("can't render an empty value as a QR code")
+	}
+	if len(value) > MaxLength {
+		return fmt.Errorf("value is too long for a QR code (%d bytes, at most %d)", len(value), MaxLength)
+	}
+
+	code, err := qrcode.New(value, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to create QR code: %w", err)
+	}
+
+	_, err = io.WriteString(w, code.ToSmallString(false))
+	return err
+}
@@ -0,0 +1,38 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "AGE-SECRET-KEY-1QQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQ"); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// The value itself is never printed, only the code
+	output := buf.String()
+	if strings.Contains(output, "AGE-SECRET-KEY") {
+		t.Error("Expected the value not to be printed")
+	}
+
+	// Every line of the code has the same width
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) < 10 {
+		t.Fatalf("Expected a QR code, got %d lines", len(lines))
+	}
+	width := len([]rune(lines[0]))
+	for i, line := range lines {
+		if len([]rune(line)) != width {
+			t.Errorf("Line %d has width %d, expected %d", i, len([]rune(line)), width)
+		}
+	}
+
+	for _, value := range []string{"", strings.Repeat("x", MaxLength+1)} {
+		if err := Render(&buf, value); err == nil {
+			t.Errorf("Expected error for a value of %d bytes", len(value))
+		}
+	}
+}
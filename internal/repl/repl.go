@@ -0,0 +1,239 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Prompt is shown before every command when the session is interactive
+const Prompt = "simple-sops> "
+
+// usage lists the commands of a session
+const usage = `Commands:
+  get <file> <path>           Print a value, e.g. get secrets.yaml db.password
+  set <file> <path> <value>   Set a value to the rest of the line
+  ls [dir]                    List the encrypted files below a directory
+  edit <file>                 Open a file in $EDITOR
+  help                        Show this help
+  exit                        End the session
+Paths are dotted, like db.password or items.0, or sops tree paths like ["db"]["password"].`
+
+// Session runs commands against encrypted files with a key that is fetched once
+type Session struct {
+	keyPath string
+	in      io.Reader
+	out     io.Writer
+	prompt  bool
+
+	// waiting is set while an interactive session waits for a command
+	waiting atomic.Bool
+
+	// Operations on the files (swappable for tests)
+	get  func(filePath string, keyFile string, valuePath string) ([]byte, error)
+	set  func(filePath string, keyFile string, valuePath string, value []byte) error
+	edit func(filePath string, keyFile string) error
+	list func(dir string) ([]string, error)
+}
+
+// NewSession creates a session reading commands from in and writing values to out
+// The prompt is only shown if prompt is set, e.g. when in is a terminal.
+func NewSession(keyPath string, in io.Reader, out io.Writer, prompt bool) *Session {
+	return &Session{
+		keyPath: keyPath,
+		in:      in,
+		out:     out,
+		prompt:  prompt,
+		get: func(filePath string, keyFile string, valuePath string) ([]byte, error) {
+			return encrypt.ExtractValue(filePath, keyFile, valuePath, encrypt.NoDocument)
+		},
+		set: func(filePath string, keyFile string, valuePath string, value []byte) error {
+			// The key is on disk already, so it isn't fetched from 1Password again
+			return encrypt.PutValue(filePath, keyFile, valuePath, value, false, encrypt.NoDocument, false)
+		},
+		edit: func(filePath string, keyFile string) error {
+			return encrypt.EditFile(filePath, keyFile, false)
+		},
+		list: config.FindEncryptedFiles,
+	}
+}
+
+// Run executes commands until exit or the end of the input
+// A failed command is reported and the session goes on. Without a prompt,
+// e.g. for piped commands, Run fails at the end if any command failed.
+func (s *Session) Run() error {
+	scanner := bufio.NewScanner(s.in)
+	failed := 0
+	for {
+		if s.prompt {
+			fmt.Fprint(s.out, Prompt)
+		}
+		s.waiting.Store(s.prompt)
+		scanned := scanner.Scan()
+		s.waiting.Store(false)
+		if !scanned {
+			if s.prompt {
+				fmt.Fprintln(s.out)
+			}
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return s.result(failed)
+		}
+
+		done, err := s.Execute(scanner.Text())
+		if err != nil {
+			logging.Error("%v", err)
+			failed++
+		}
+		if done {
+			return s.result(failed)
+		}
+	}
+}
+
+// Interrupt cancels the line being typed, e.g. on Ctrl+C, and shows a new prompt
+// The terminal discards the typed input itself. A running command isn't
+// affected here, the programs it started get the interrupt too.
+func (s *Session) Interrupt() {
+	if s.waiting.Load() {
+		fmt.Fprint(s.out, "\n"+Prompt)
+	}
+}
+
+// result fails a session without a prompt if any of its commands failed
+func (s *Session) result(failed int) error {
+	if failed > 0 && !s.prompt {
+		return fmt.Errorf("%d command(s) failed", failed)
+	}
+	return nil
+}
+
+// Execute runs a single command line and reports whether the session ends
+func (s *Session) Execute(line string) (bool, error) {
+	name, rest := nextField(line)
+	switch name {
+	case "":
+		return false, nil
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		fmt.Fprintln(s.out, usage)
+		return false, nil
+	case "get":
+		file, rest := nextField(rest)
+		path, rest := nextField(rest)
+		if file == "" || path == "" || rest != "" {
+			return false, fmt.Errorf("usage: get <file> <path>")
+		}
+		valuePath, err := ValuePath(path)
+		if err != nil {
+			return false, err
+		}
+		value, err := s.get(file, s.keyPath, valuePath)
+		if err != nil {
+			return false, err
+		}
+		defer clear(value)
+		if _, err := s.out.Write(value); err != nil {
+			return false, err
+		}
+		if !bytes.HasSuffix(value, []byte("\n")) {
+			fmt.Fprintln(s.out)
+		}
+		return false, nil
+	case "set":
+		file, rest := nextField(rest)
+		path, value := nextField(rest)
+		if file == "" || path == "" || value == "" {
+			return false, fmt.Errorf("usage: set <file> <path> <value>")
+		}
+		valuePath, err := ValuePath(path)
+		if err != nil {
+			return false, err
+		}
+		return false, s.set(file, s.keyPath, valuePath, []byte(value))
+	case "ls":
+		dir, rest := nextField(rest)
+		if rest != "" {
+			return false, fmt.Errorf("usage: ls [dir]")
+		}
+		if dir == "" {
+			dir = "."
+		}
+		files, err := s.list(dir)
+		if err != nil {
+			return false, err
+		}
+		for _, file := range files {
+			if logging.IsPorcelainEnabled {
+				logging.Record(filepath.ToSlash(file), "ls", logging.ResultOK)
+				continue
+			}
+			fmt.Fprintln(s.out, filepath.ToSlash(file))
+		}
+		return false, nil
+	case "edit":
+		file, rest := nextField(rest)
+		if file == "" || rest != "" {
+			return false, fmt.Errorf("usage: edit <file>")
+		}
+		return false, s.edit(file, s.keyPath)
+	default:
+		return false, fmt.Errorf("unknown command %s, type help for the commands", name)
+	}
+}
+
+// ValuePath turns a dotted path like db.password into the sops tree path ["db"]["password"]
+// Numeric segments index lists, and sops tree paths are returned as they are.
+func ValuePath(path string) (string, error) {
+	if strings.HasPrefix(path, "[") {
+		return path, encrypt.ValidateValuePath(path)
+	}
+
+	var builder strings.Builder
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return "", fmt.Errorf("invalid path %q, expected a dotted path like db.password", path)
+		}
+		if _, err := strconv.Atoi(segment); err == nil {
+			builder.WriteString("[" + segment + "]")
+		} else {
+			builder.WriteString("[" + strconv.Quote(segment) + "]")
+		}
+	}
+	return builder.String(), nil
+}
+
+// nextField splits the first field off a command line
+// Fields are separated by whitespace outside double quotes, and a field that
+// is quoted as a whole, like "my file.yaml", loses its quotes.
+func nextField(line string) (string, string) {
+	line = strings.TrimLeft(line, " \t")
+	quoted := false
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' && (i == 0 || line[i-1] != '\\') {
+			quoted = !quoted
+		} else if !quoted && (line[i] == ' ' || line[i] == '\t') {
+			end = i
+			break
+		}
+	}
+
+	field := line[:end]
+	if len(field) >= 2 && field[0] == '"' && field[len(field)-1] == '"' {
+		if unquoted, err := strconv.Unquote(field); err == nil {
+			field = unquoted
+		}
+	}
+	return field, strings.TrimLeft(line[end:], " \t")
+}
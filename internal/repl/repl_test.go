@@ -0,0 +1,117 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	values := map[string]string{"secrets.yaml" + `["db"]["password"]`: "hunter2"}
+	var edited []string
+	input := strings.Join([]string{
+		"get secrets.yaml db.password",
+		"set secrets.yaml db.user app admin",
+		`get "my secrets.yaml" ["db"]["user"]`,
+		"bogus",
+		"edit secrets.yaml",
+		"ls",
+		"exit",
+		"get secrets.yaml db.password",
+	}, "\n")
+
+	var out bytes.Buffer
+	session := NewSession("key.txt", strings.NewReader(input), &out, false)
+	session.get = func(filePath string, keyFile string, valuePath string) ([]byte, error) {
+		if keyFile != "key.txt" {
+			t.Errorf("Expected the session key, got %s", keyFile)
+		}
+		value, ok := values[filePath+valuePath]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return []byte(value), nil
+	}
+	session.set = func(filePath string, keyFile string, valuePath string, value []byte) error {
+		values[filePath+valuePath] = string(value)
+		return nil
+	}
+	session.edit = func(filePath string, keyFile string) error {
+		edited = append(edited, filePath)
+		return nil
+	}
+	session.list = func(dir string) ([]string, error) {
+		return []string{dir + "/secrets.yaml"}, nil
+	}
+
+	// The failed get and the unknown command fail the piped session at the end
+	if err := session.Run(); err == nil || !strings.Contains(err.Error(), "2 command(s) failed") {
+		t.Fatalf("Run() error = %v, want 2 failed commands", err)
+	}
+
+	if got := values["secrets.yaml"+`["db"]["user"]`]; got != "app admin" {
+		t.Errorf("Expected set to store the rest of the line, got %q", got)
+	}
+	// Failed commands don't end the session, exit does
+	if want := "hunter2\n./secrets.yaml\n"; out.String() != want {
+		t.Errorf("Output = %q, want %q", out.String(), want)
+	}
+	if len(edited) != 1 || edited[0] != "secrets.yaml" {
+		t.Errorf("Expected secrets.yaml to be edited, got %v", edited)
+	}
+}
+
+func TestSessionInterrupt(t *testing.T) {
+	in, input := io.Pipe()
+	var out bytes.Buffer
+	session := NewSession("key.txt", in, &out, true)
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run() }()
+
+	// Wait for the prompt, an interrupt then shows a new one
+	for !session.waiting.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	session.Interrupt()
+
+	// The session goes on until exit
+	if _, err := io.WriteString(input, "exit\n"); err != nil {
+		t.Fatalf("Failed to write the command: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := Prompt + "\n" + Prompt; out.String() != want {
+		t.Errorf("Output = %q, want %q", out.String(), want)
+	}
+
+	// Interrupts while no command is read leave the output alone
+	session.Interrupt()
+	if want := Prompt + "\n" + Prompt; out.String() != want {
+		t.Errorf("Output after the session = %q, want %q", out.String(), want)
+	}
+}
+
+func TestValuePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"db.password", `["db"]["password"]`, false},
+		{"items.0.name", `["items"][0]["name"]`, false},
+		{`["tls"]["key"]`, `["tls"]["key"]`, false},
+		{"db..password", "", true},
+		{`["tls"`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := ValuePath(tt.path)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ValuePath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package run
+
+import (
+	"runtime"
+	"strings"
+)
+
+// baseEnv are the variables a clean environment keeps, so commands still find
+// their tools, home directory, terminal and locale
+var baseEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "COLORTERM", "LANG", "LC_*", "TZ", "TMPDIR",
+	// Windows needs these to start most programs
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP",
+	"USERPROFILE", "USERNAME", "APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES",
+}
+
+// CleanEnvironment returns the variables of environ a command needs to run
+// and those matching allow, given as NAME or PREFIX_*
+// Everything else, like unrelated tokens, isn't passed on.
+func CleanEnvironment(environ []string, allow []string) []string {
+	patterns := append(append([]string(nil), baseEnv...), allow...)
+
+	var clean []string
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		for _, pattern := range patterns {
+			if matchEnvName(pattern, name) {
+				clean = append(clean, entry)
+				break
+			}
+		}
+	}
+	return clean
+}
+
+// matchEnvName checks if a variable name matches a name or PREFIX_* pattern
+// Names are case-insensitive on Windows.
+func matchEnvName(pattern string, name string) bool {
+	if runtime.GOOS == "windows" {
+		pattern, name = strings.ToUpper(pattern), strings.ToUpper(name)
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
@@ -0,0 +1,130 @@
+package run
+
+import (
+	"fmt"
+	"simple-sops/internal/convert"
+	"simple-sops/internal/dotenv"
+	"simple-sops/internal/encrypt"
+	"simple-sops/pkg/logging"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hasTemplates checks if the command or any argument has a {{ }} template to fill with a secret value
+func hasTemplates(command string, args []string) bool {
+	if strings.Contains(command, "{{") {
+		return true
+	}
+	for _, arg := range args {
+		if strings.Contains(arg, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// runInterpolated runs a command with secret values filled into its arguments
+// The file is decrypted in memory only, and the filled arguments are never logged.
+func runInterpolated(encryptedFilePath string, keyPath string, command string, args []string, env []string, usePTY bool) error {
+	plaintext, err := encrypt.DecryptToBytes(encryptedFilePath, keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt file: %w", err)
+	}
+	defer clear(plaintext)
+
+	values, err := parseValues(encryptedFilePath, plaintext)
+	if err != nil {
+		return err
+	}
+	filledCommand, filledArgs, err := interpolate(command, args, values)
+	if err != nil {
+		// {{ }} may be meant for the command itself, like kubectl -o go-template
+		return fmt.Errorf("%w (use --no-template to pass {{ }} on unchanged)", err)
+	}
+
+	// Only the templates are logged, never the values
+	logging.Info("Running command: %s %s", command, strings.Join(args, " "))
+
+	if err := executeCommand(filledCommand, filledArgs, env, nil, usePTY); err != nil {
+		return err
+	}
+
+	logging.Success("Command completed successfully")
+
+	return nil
+}
+
+// parseValues parses a decrypted document into the values templates can refer to
+// YAML and JSON documents keep their structure, dotenv files are a flat map of
+// their variables.
+func parseValues(filePath string, plaintext []byte) (map[string]any, error) {
+	if dotenv.IsDotenvFile(filePath) {
+		entries, err := dotenv.Parse(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		values := make(map[string]any, len(entries))
+		for _, entry := range entries {
+			if !entry.IsComment() {
+				values[entry.Key] = entry.Value
+			}
+		}
+		return values, nil
+	}
+
+	if format := convert.FormatOf(filePath); format != "" {
+		converted, err := convert.ToJSON(format, plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		plaintext = converted
+	}
+
+	// JSON is YAML, so both are parsed the same way
+	var values map[string]any
+	if err := yaml.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s, values can only be filled in from YAML, JSON, dotenv, TOML and properties files: %w", filePath, err)
+	}
+	return values, nil
+}
+
+// interpolate fills the templates in the command and its arguments with values
+// A template refers to a value by its path, like {{ .api.token }}, or
+// {{ index . "api-keys" "github" }} for keys that aren't identifiers.
+// Referring to a missing value is an error.
+func interpolate(command string, args []string, values map[string]any) (string, []string, error) {
+	filledCommand, err := fillTemplate("command", command, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	filledArgs := make([]string, len(args))
+	for i, arg := range args {
+		filledArgs[i], err = fillTemplate(fmt.Sprintf("argument %d", i+1), arg, values)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return filledCommand, filledArgs, nil
+}
+
+// fillTemplate fills the templates of a single argument
+// The errors of text/template name the template and the path, not values.
+func fillTemplate(name string, text string, values map[string]any) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template in %s: %w", name, err)
+	}
+
+	var filled strings.Builder
+	if err := tmpl.Execute(&filled, values); err != nil {
+		return "", fmt.Errorf("failed to fill in %s: %w", name, err)
+	}
+	return filled.String(), nil
+}
@@ -0,0 +1,106 @@
+//go:build !windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"simple-sops/pkg/logging"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// forwardedSignals are relayed to the child's process group
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// controllingTerminal returns the stdin descriptor if it is our controlling terminal
+func controllingTerminal() (int, bool) {
+	fd := int(os.Stdin.Fd())
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	return fd, errno == 0
+}
+
+// configureProcessGroup puts the child into its own process group.
+// When attached to a terminal, the group becomes the foreground group so
+// keyboard signals reach the child directly. The returned function hands
+// the terminal back to us once the child is done.
+func configureProcessGroup(cmd *exec.Cmd) func() {
+	fd, isTerminal := controllingTerminal()
+	if !isTerminal {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		return func() {}
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: fd}
+	return func() {
+		// Taking the foreground back from a background group raises SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+
+		pgrp := int32(syscall.Getpgrp())
+		syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	}
+}
+
+// forwardSignal relays a signal to the child's process group
+func forwardSignal(process *os.Process, sig os.Signal) error {
+	unixSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return process.Signal(sig)
+	}
+	return syscall.Kill(-process.Pid, unixSig)
+}
+
+// exitCode returns the exit code of a finished process, using the shell
+// convention of 128+n for processes terminated by signal n
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// groupGracePeriod is how long processes left in the group get to exit after SIGTERM
+var groupGracePeriod = 5 * time.Second
+
+// reapProcessGroup terminates the processes the command left in its group
+// and waits until they are gone, since they may still use the decrypted file.
+// They get SIGTERM and, if they don't exit within the grace period, SIGKILL.
+func reapProcessGroup(pgid int) {
+	if !groupAlive(pgid) {
+		return
+	}
+
+	logging.Debug("Terminating processes left in process group %d", pgid)
+	syscall.Kill(-pgid, syscall.SIGTERM)
+	if waitForGroup(pgid, groupGracePeriod) {
+		return
+	}
+
+	logging.Debug("Killing processes left in process group %d", pgid)
+	syscall.Kill(-pgid, syscall.SIGKILL)
+	if !waitForGroup(pgid, groupGracePeriod) {
+		logging.Warn("Processes of process group %d are still running", pgid)
+	}
+}
+
+// waitForGroup waits until no process of the group is left, returning false on timeout
+func waitForGroup(pgid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for groupAlive(pgid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return true
+}
+
+// groupAlive reports whether any process of the group is left
+func groupAlive(pgid int) bool {
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || err == syscall.EPERM
+}
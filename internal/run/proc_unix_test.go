@@ -0,0 +1,42 @@
+//go:build !windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandReapsGroup(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// The command leaves a background process behind in its group
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	start := time.Now()
+	if err := executeCommand("sh", []string{"-c", "sleep 30 & echo $! > " + pidFile}, os.Environ(), nil, false); err != nil {
+		t.Fatalf("executeCommand failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the background process to be terminated, waited %v", elapsed)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Failed to read the pid: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("Invalid pid %q: %v", data, err)
+	}
+	if err := syscall.Kill(pid, 0); err != syscall.ESRCH {
+		t.Errorf("Expected the background process to be gone, got %v", err)
+	}
+}
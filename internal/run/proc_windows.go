@@ -0,0 +1,32 @@
+//go:build windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are relayed to the child process
+// Closing the console, logging off and shutting down arrive as SIGTERM.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// configureProcessGroup is a no-op on Windows where the console delivers
+// Ctrl+C to every attached process
+func configureProcessGroup(cmd *exec.Cmd) func() {
+	return func() {}
+}
+
+// forwardSignal terminates the child, Windows cannot deliver other signals
+func forwardSignal(process *os.Process, sig os.Signal) error {
+	return process.Kill()
+}
+
+// exitCode returns the exit code of a finished process
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
+
+// reapProcessGroup is a no-op on Windows, where the command has no process group
+func reapProcessGroup(pgid int) {}
@@ -0,0 +1,119 @@
+//go:build !windows
+
+package run
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// ptyDrainTimeout is how long output of the pseudo-terminal is relayed after
+// the command exited, in case a background process keeps it open
+const ptyDrainTimeout = time.Second
+
+// stdinPollInterval is how often the stdin relay checks whether the command exited
+const stdinPollInterval = 100 // milliseconds
+
+// relayStdin copies stdin to target until stop is closed
+// stdin is polled instead of read in a blocking call, so the relay ends with
+// the command and doesn't swallow input meant for whatever runs next, e.g.
+// the shell or a command restarted in watch mode. The returned channel is
+// closed once the relay stopped.
+func relayStdin(stdin *os.File, target io.Writer, stop <-chan struct{}) <-chan struct{} {
+	fd := int(stdin.Fd())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 4096)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+			ready, err := unix.Poll(fds, stdinPollInterval)
+			if err == unix.EINTR || (err == nil && ready == 0) {
+				continue
+			}
+			if err != nil {
+				return
+			}
+
+			n, err := unix.Read(fd, buf)
+			if n > 0 {
+				target.Write(buf[:n])
+			}
+			if err != nil || n == 0 {
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// isTerminal reports whether stdin and stdout are terminals a pseudo-terminal can relay
+// Output that is redirected or piped would otherwise get the terminal's
+// line endings and stderr mixed into it.
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// startWithPTY starts the command in a new session on a pseudo-terminal
+// Our terminal is switched to raw mode and relayed, so keys like Ctrl-C reach
+// the command through its own terminal, and the size follows ours. The
+// returned function restores the terminal once the command exited.
+func startWithPTY(cmd *exec.Cmd) (func(), error) {
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the size of the pseudo-terminal in sync with ours
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	go func() {
+		for range resize {
+			pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	resize <- syscall.SIGWINCH
+
+	stdinFd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		oldState = nil
+	}
+
+	stopRelay := make(chan struct{})
+	relayDone := relayStdin(os.Stdin, ptmx, stopRelay)
+	outputDone := make(chan struct{})
+	go func() {
+		io.Copy(os.Stdout, ptmx)
+		close(outputDone)
+	}()
+
+	return func() {
+		select {
+		case <-outputDone:
+		case <-time.After(ptyDrainTimeout):
+		}
+		close(stopRelay)
+		<-relayDone
+		signal.Stop(resize)
+		close(resize)
+		if oldState != nil {
+			term.Restore(stdinFd, oldState)
+		}
+		ptmx.Close()
+	}, nil
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package run
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandPTY(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Capture what is relayed from the pseudo-terminal
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = executeCommand("sh", []string{"-c", "test -t 0 && test -t 1 && echo on-a-tty; exit 4"}, os.Environ(), nil, true)
+	os.Stdout = stdout
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != 4 {
+		t.Errorf("Expected exit code 4, got %v", err)
+	}
+	if !strings.Contains(string(output), "on-a-tty") {
+		t.Errorf("Expected the command to run on a terminal, got %q", output)
+	}
+}
+
+func TestRelayStdinStops(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer writer.Close()
+
+	var relayed strings.Builder
+	stop := make(chan struct{})
+	done := relayStdin(reader, &relayed, stop)
+	writer.Write([]byte("ls\n"))
+	time.Sleep(3 * stdinPollInterval * time.Millisecond)
+
+	// The relay ends without more input, which stays for the next reader
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the relay to stop while stdin is idle")
+	}
+	if relayed.String() != "ls\n" {
+		t.Errorf("Expected the input to be relayed, got %q", relayed.String())
+	}
+
+	writer.Write([]byte("next\n"))
+	buf := make([]byte, 16)
+	n, _ := reader.Read(buf)
+	if string(buf[:n]) != "next\n" {
+		t.Errorf("Expected input after the relay stopped to stay in stdin, got %q", buf[:n])
+	}
+}
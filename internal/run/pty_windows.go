@@ -0,0 +1,18 @@
+//go:build windows
+
+package run
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isTerminal is false on Windows, which has no pseudo-terminals to relay
+func isTerminal() bool {
+	return false
+}
+
+// startWithPTY is not supported on Windows
+func startWithPTY(cmd *exec.Cmd) (func(), error) {
+	return nil, errors.New("pseudo-terminals are not supported on Windows")
+}
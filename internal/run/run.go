@@ -1,11 +1,13 @@
 package run
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"simple-sops/internal/encrypt"
 	"simple-sops/internal/keymgmt"
 	"simple-sops/pkg/logging"
@@ -13,13 +15,62 @@ import (
 	"syscall"
 )
 
+const (
+	// DefaultEnvName is the environment variable that receives the decrypted file path
+	DefaultEnvName = "DECRYPTED_FILE"
+	// PathPlaceholder is replaced with the decrypted file path in the command and its arguments
+	PathPlaceholder = "{}"
+)
+
+// envNamePattern matches valid environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options configures how a command is run with a decrypted file
+type Options struct {
+	// EnvName is the environment variable that receives the decrypted file path
+	EnvName string
+	// Shell runs the command string through $SHELL -c
+	Shell bool
+	// Watch restarts the command whenever the encrypted file changes
+	Watch bool
+	// NoPTY passes our terminal to the command instead of a pseudo-terminal
+	NoPTY bool
+	// CleanEnv starts the command from a minimal environment instead of ours
+	CleanEnv bool
+	// KeepEnv lists more variables a clean environment passes on, as NAME or PREFIX_*
+	KeepEnv []string
+	// NoTemplate passes {{ }} in the arguments on unchanged instead of filling in values
+	NoTemplate bool
+}
+
 // RunWithEncryptedFile executes a command with a temporarily decrypted file
-func RunWithEncryptedFile(encryptedFilePath string, outputPath string, command string, args []string, keyFile string, alwaysUseOnePassword bool) error {
+func RunWithEncryptedFile(encryptedFilePath string, outputPath string, command string, args []string, keyFile string, alwaysUseOnePassword bool, opts Options) error {
+	// Validate the environment variable name
+	if opts.EnvName == "" {
+		opts.EnvName = DefaultEnvName
+	}
+	if !envNamePattern.MatchString(opts.EnvName) {
+		return fmt.Errorf("invalid environment variable name: %s", opts.EnvName)
+	}
+
 	// Check if encrypted file exists
 	if _, err := os.Stat(encryptedFilePath); os.IsNotExist(err) {
 		return fmt.Errorf("encrypted file not found: %s", encryptedFilePath)
 	}
 
+	// Values filled into the arguments need no decrypted file
+	interpolated := !opts.NoTemplate && hasTemplates(command, args)
+	if interpolated {
+		switch {
+		case outputPath != "":
+			return fmt.Errorf("an output file can't be used when filling values into the arguments")
+		case opts.Shell:
+			return fmt.Errorf("--shell can't be used when filling values into the arguments, they aren't quoted for the shell")
+		case opts.Watch:
+			return fmt.Errorf("--watch can't be used when filling values into the arguments")
+		}
+	}
+
 	// Ensure we have the key available
 	keyPath, isTemp, err := keymgmt.EnsureAgeKey(keyFile, true, alwaysUseOnePassword)
 	if err != nil {
@@ -31,67 +82,171 @@ func RunWithEncryptedFile(encryptedFilePath string, outputPath string, command s
 		defer keymgmt.CleanupTempAgeKeyFile(keyPath)
 	}
 
+	// Interactive commands like ssh get a terminal of their own, unless the output is redirected
+	usePTY := !opts.NoPTY && isTerminal()
+
+	env := os.Environ()
+	if opts.CleanEnv {
+		env = CleanEnvironment(env, opts.KeepEnv)
+	}
+
+	if interpolated {
+		return runInterpolated(encryptedFilePath, keyPath, command, args, env, usePTY)
+	}
+
 	// Determine the output path
 	tempFileNeeded := outputPath == ""
 	var tempDir string
 
 	if tempFileNeeded {
 		// Create temporary directory for decrypted file
-		tempDir, err = os.MkdirTemp("", "simple-sops-*")
+		tempDir, err = keymgmt.CreateTempDir()
 		if err != nil {
-			return fmt.Errorf("failed to create temporary directory: %w", err)
+			return err
 		}
-		defer os.RemoveAll(tempDir)
+		defer keymgmt.RemoveSecurely(tempDir)
 
 		// Generate a temporary file path
 		outputPath = filepath.Join(tempDir, filepath.Base(encryptedFilePath)+".plain")
 	} else {
-		// For user-specified output path, ensure we clean it up afterwards
+		// For user-specified output path, ensure we clean it up afterwards,
+		// also if simple-sops is interrupted before the command runs
+		keymgmt.RemoveOnExit(outputPath)
 		defer func() {
 			if err := os.Remove(outputPath); err != nil {
 				logging.Debug("Failed to remove output file %s: %v", outputPath, err)
 			} else {
 				logging.Debug("Removed output file %s", outputPath)
+				keymgmt.KeepOnExit(outputPath)
+				encrypt.ForgetDecrypted(outputPath)
 			}
 		}()
 	}
 
+	// Point the command at the decrypted file
+	if opts.Shell {
+		command, args = shellCommand(command, args, outputPath)
+	} else {
+		command, args = substitutePath(command, args, encryptedFilePath, outputPath)
+	}
+
+	// Add output path to environment variables
+	env = append(env, fmt.Sprintf("%s=%s", opts.EnvName, outputPath))
+
+	if opts.Watch {
+		return watchAndRun(encryptedFilePath, outputPath, keyPath, command, args, env, usePTY)
+	}
+
 	// Decrypt the file to the output path
 	if err := encrypt.DecryptToFile(encryptedFilePath, outputPath, keyPath); err != nil {
 		return fmt.Errorf("failed to decrypt file: %w", err)
 	}
 
-	// Replace any references to the original file in command arguments
-	// with the path to the decrypted file
+	// Prepare to execute the command
+	logging.Info("Running command: %s %s", command, strings.Join(args, " "))
+
+	if err := executeCommand(command, args, env, nil, usePTY); err != nil {
+		return err
+	}
+
+	logging.Success("Command completed successfully")
+
+	return nil
+}
+
+// substitutePath points the command at the decrypted file. If the command or
+// any argument contains the {} placeholder, it is replaced with the decrypted
+// path. Otherwise arguments matching the encrypted file name are replaced.
+func substitutePath(command string, args []string, encryptedFilePath string, outputPath string) (string, []string) {
+	hasPlaceholder := strings.Contains(command, PathPlaceholder)
+	for _, arg := range args {
+		if strings.Contains(arg, PathPlaceholder) {
+			hasPlaceholder = true
+			break
+		}
+	}
+
+	substituted := make([]string, len(args))
+	if hasPlaceholder {
+		for i, arg := range args {
+			substituted[i] = strings.ReplaceAll(arg, PathPlaceholder, outputPath)
+		}
+		return strings.ReplaceAll(command, PathPlaceholder, outputPath), substituted
+	}
+
+	// Fall back to matching references to the original file
 	originalFileName := filepath.Base(encryptedFilePath)
 	for i, arg := range args {
 		if arg == originalFileName || arg == encryptedFilePath {
-			args[i] = outputPath
+			substituted[i] = outputPath
+		} else {
+			substituted[i] = arg
 		}
 	}
 
-	// Check if the command itself is the filename
 	if command == originalFileName || command == encryptedFilePath {
 		command = outputPath
 	}
 
-	// Prepare to execute the command
-	logging.Info("Running command: %s %s", command, strings.Join(args, " "))
-	cmd := exec.Command(command, args...)
+	return command, substituted
+}
 
-	// Add output path to environment variables
-	cmd.Env = append(os.Environ(), fmt.Sprintf("DECRYPTED_FILE=%s", outputPath))
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// shellCommand wraps a command string for execution with $SHELL -c,
+// replacing the {} placeholder with the quoted decrypted path
+func shellCommand(script string, args []string, outputPath string) (string, []string) {
+	script = strings.Join(append([]string{script}, args...), " ")
+	script = strings.ReplaceAll(script, PathPlaceholder, encrypt.ShellQuote(outputPath))
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	return shell, []string{"-c", script}
+}
+
+// ExitError reports that the command exited with a non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// executeCommand runs a command in its own process group, forwarding signals
+// to it and waiting for it and the processes it left in the group to exit.
+// Closing stop asks the command to terminate.
+// With usePTY the command runs on a pseudo-terminal relaying ours.
+// A non-zero exit is returned as *ExitError.
+func executeCommand(command string, args []string, env []string, stop <-chan struct{}, usePTY bool) error {
+	cmd := exec.Command(command, args...)
+	cmd.Env = env
 
 	// Set up signal handling to ensure cleanup
+	defer keymgmt.SuspendExitSignals()()
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(signalChan, forwardedSignals...)
+	defer signal.Stop(signalChan)
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
+	if usePTY {
+		restoreTerminal, err := startWithPTY(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to start command: %w", err)
+		}
+		defer restoreTerminal()
+	} else {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		restoreTerminal := configureProcessGroup(cmd)
+		defer restoreTerminal()
+
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to start command: %w", err)
+		}
 	}
 
 	// Handle process termination in a separate goroutine
@@ -100,25 +255,33 @@ func RunWithEncryptedFile(encryptedFilePath string, outputPath string, command s
 		cmdDone <- cmd.Wait()
 	}()
 
-	// Wait for either command completion or signal
-	select {
-	case err := <-cmdDone:
-		// Command completed
-		if err != nil {
+	// Forward signals until the command exits, so cleanup only happens afterwards
+	for {
+		select {
+		case err := <-cmdDone:
+			// Processes the command started may still use the decrypted file
+			reapProcessGroup(cmd.Process.Pid)
+			if err == nil {
+				return nil
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return &ExitError{Code: exitCode(exitErr.ProcessState)}
+			}
 			return fmt.Errorf("command execution failed: %w", err)
+		case sig := <-signalChan:
+			logging.Debug("Received signal %v, forwarding to command", sig)
+			if err := forwardSignal(cmd.Process, sig); err != nil {
+				logging.Error("Failed to forward signal: %v", err)
+			}
+		case <-stop:
+			logging.Debug("Stopping command")
+			if err := forwardSignal(cmd.Process, syscall.SIGTERM); err != nil {
+				logging.Error("Failed to stop command: %v", err)
+			}
+			stop = nil
 		}
-	case sig := <-signalChan:
-		// Signal received, kill process
-		logging.Info("Received signal %v, terminating command", sig)
-		if err := cmd.Process.Kill(); err != nil {
-			logging.Error("Failed to kill process: %v", err)
-		}
-		return fmt.Errorf("command terminated by signal: %v", sig)
 	}
-
-	logging.Success("Command completed successfully")
-
-	return nil
 }
 
 // ParseRunCommand parses the run command arguments
@@ -159,6 +322,25 @@ func ParseRunCommand(args []string) (encryptedFile string, outputFile string, co
 	return encryptedFile, outputFile, command, commandArgs, nil
 }
 
+// ParseShellRunCommand parses the run command arguments for shell mode,
+// keeping the command string intact instead of splitting it
+func ParseShellRunCommand(args []string) (encryptedFile string, outputFile string, script string, err error) {
+	if len(args) < 2 {
+		return "", "", "", fmt.Errorf("insufficient arguments. Usage: simple-sops run --shell [encrypted-file] [output-file (optional)] [command]")
+	}
+
+	encryptedFile = args[0]
+	rest := args[1:]
+
+	// Check if the second argument is an output file or the command
+	if len(rest) > 1 && !isCommand(rest[0]) {
+		outputFile = rest[0]
+		rest = rest[1:]
+	}
+
+	return encryptedFile, outputFile, strings.Join(rest, " "), nil
+}
+
 // isCommand checks if the argument is likely a command
 func isCommand(arg string) bool {
 	// If the argument starts with a quote, it's likely a command
@@ -2,7 +2,14 @@
 package run
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseRunCommand(t *testing.T) {
@@ -85,3 +92,166 @@ func TestIsCommand(t *testing.T) {
 		t.Error("Relative path shouldn't be recognized as a command")
 	}
 }
+
+func TestExecuteCommandExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Successful commands return nil
+	if err := executeCommand("sh", []string{"-c", "exit 0"}, os.Environ(), nil, false); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// The exact exit code is propagated
+	err := executeCommand("sh", []string{"-c", "exit 3"}, os.Environ(), nil, false)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected *ExitError, got %v", err)
+	}
+	if exitErr.Code != 3 {
+		t.Errorf("Expected exit code 3, got %d", exitErr.Code)
+	}
+
+	// Commands killed by a signal use the 128+n convention
+	err = executeCommand("sh", []string{"-c", "kill -TERM $$"}, os.Environ(), nil, false)
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected *ExitError, got %v", err)
+	}
+	if runtime.GOOS != "windows" && exitErr.Code != 143 {
+		t.Errorf("Expected exit code 143, got %d", exitErr.Code)
+	}
+}
+
+func TestSubstitutePath(t *testing.T) {
+	// Placeholders are replaced wherever they appear
+	command, args := substitutePath("cat", []string{"{}", "--file={}"}, "secrets.enc.env", "/tmp/plain")
+	if command != "cat" || args[0] != "/tmp/plain" || args[1] != "--file=/tmp/plain" {
+		t.Errorf("Placeholder substitution failed: %s %v", command, args)
+	}
+
+	// Without placeholders, references to the encrypted file are replaced
+	command, args = substitutePath("kubectl", []string{"apply", "-f", "secret.yaml"}, "dir/secret.yaml", "/tmp/plain")
+	if command != "kubectl" || args[2] != "/tmp/plain" {
+		t.Errorf("Filename substitution failed: %s %v", command, args)
+	}
+
+	// With a placeholder present, matching file names are left alone
+	_, args = substitutePath("diff", []string{"secret.yaml", "{}"}, "secret.yaml", "/tmp/plain")
+	if args[0] != "secret.yaml" || args[1] != "/tmp/plain" {
+		t.Errorf("Placeholder should take precedence: %v", args)
+	}
+}
+
+func TestCleanEnvironment(t *testing.T) {
+	environ := []string{"PATH=/bin", "HOME=/home/me", "LC_ALL=C", "GITHUB_TOKEN=secret", "AWS_PROFILE=prod", "AWS_REGION=eu", "KUBECONFIG=/k"}
+	got := CleanEnvironment(environ, []string{"AWS_*"})
+
+	want := []string{"PATH=/bin", "HOME=/home/me", "LC_ALL=C", "AWS_PROFILE=prod", "AWS_REGION=eu"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("CleanEnvironment() = %v, want %v", got, want)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	values, err := parseValues("secrets.enc.yaml", []byte("api:\n  token: s3cret\napi-keys:\n  github: ghp_x\n"))
+	if err != nil {
+		t.Fatalf("parseValues failed: %v", err)
+	}
+
+	command, args, err := interpolate("curl", []string{"-H", "Authorization: Bearer {{ .api.token }}", `{{ index . "api-keys" "github" }}`}, values)
+	if err != nil {
+		t.Fatalf("interpolate failed: %v", err)
+	}
+	if command != "curl" || len(args) != 3 || args[0] != "-H" || args[1] != "Authorization: Bearer s3cret" || args[2] != "ghp_x" {
+		t.Errorf("interpolate() = %s %v", command, args)
+	}
+
+	// A missing value fails without revealing the others
+	_, _, err = interpolate("curl", []string{"{{ .api.tokn }}"}, values)
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Expected an error without values for a missing value, got %v", err)
+	}
+
+	// Dotenv files are a flat map of their variables
+	values, err = parseValues("db.enc.env", []byte("export DB_PASSWORD=\"pa ss\"\n"))
+	if err != nil {
+		t.Fatalf("parseValues failed: %v", err)
+	}
+	if _, args, err := interpolate("psql", []string{"password={{ .DB_PASSWORD }}"}, values); err != nil || args[0] != "password=pa ss" {
+		t.Errorf("interpolate() = %v, %v", args, err)
+	}
+
+	if hasTemplates("cat", []string{"{}"}) || !hasTemplates("echo", []string{"x", "{{ .a }}"}) {
+		t.Error("hasTemplates should only detect {{ }} templates")
+	}
+
+	// Templates are filled without a flag, so they can't be combined with --shell
+	encrypted := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	if err := os.WriteFile(encrypted, []byte("a: b\n"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	err = RunWithEncryptedFile(encrypted, "", "echo {{ .a }}", nil, "", false, Options{Shell: true})
+	if err == nil || !strings.Contains(err.Error(), "--shell") {
+		t.Errorf("Expected templates with --shell to fail, got %v", err)
+	}
+}
+
+func TestParseShellRunCommand(t *testing.T) {
+	encryptedFile, outputFile, script, err := ParseShellRunCommand([]string{"secrets.enc", "jq .token  | xargs echo"})
+	if err != nil {
+		t.Fatalf("ParseShellRunCommand failed: %v", err)
+	}
+	if encryptedFile != "secrets.enc" || outputFile != "" {
+		t.Errorf("Unexpected files: %s, %s", encryptedFile, outputFile)
+	}
+	if script != "jq .token  | xargs echo" {
+		t.Errorf("Command string should be kept intact, got %q", script)
+	}
+
+	_, outputFile, script, err = ParseShellRunCommand([]string{"secrets.enc", "plain.json", "cat plain.json > /dev/null"})
+	if err != nil {
+		t.Fatalf("ParseShellRunCommand failed with output file: %v", err)
+	}
+	if outputFile != "plain.json" || script != "cat plain.json > /dev/null" {
+		t.Errorf("Unexpected output file or script: %s, %q", outputFile, script)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+
+	command, args := shellCommand("cat {} | wc -l", nil, "/tmp/it's plain")
+	if command != "/bin/sh" || len(args) != 2 || args[0] != "-c" {
+		t.Fatalf("Unexpected shell invocation: %s %v", command, args)
+	}
+	if args[1] != `cat '/tmp/it'\''s plain' | wc -l` {
+		t.Errorf("Unexpected script: %s", args[1])
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "secret.enc.yaml")
+	os.WriteFile(filePath, []byte("one"), 0644)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := watchFile(filePath, stop)
+
+	select {
+	case <-changed:
+		t.Fatal("File reported as changed before it was modified")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	os.WriteFile(filePath, []byte("second"), 0644)
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("File change was not detected")
+	}
+}
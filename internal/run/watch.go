@@ -0,0 +1,116 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/keymgmt"
+	"simple-sops/internal/metrics"
+	"simple-sops/pkg/logging"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often watch mode checks the encrypted file for changes
+var pollInterval = 500 * time.Millisecond
+
+// watchAndRun decrypts the file and runs the command, restarting it whenever
+// the encrypted file changes. Unchanged ciphertext is served from an in-memory
+// cache, so saving a file without changes doesn't invoke sops again.
+func watchAndRun(encryptedFilePath string, outputPath string, keyPath string, command string, args []string, env []string, usePTY bool) error {
+	cache := encrypt.NewDecryptCache(8)
+
+	// Stop the command before cleaning up instead of exiting right away
+	defer keymgmt.SuspendExitSignals()()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, forwardedSignals...)
+	defer signal.Stop(interrupt)
+
+	var lastErr error
+	for {
+		stopWatching := make(chan struct{})
+		changed := watchFile(encryptedFilePath, stopWatching)
+
+		plaintext, hit, err := cache.Decrypt(encryptedFilePath, keyPath)
+		if err != nil {
+			logging.Error("Failed to decrypt %s: %v", encryptedFilePath, err)
+		} else {
+			if hit {
+				logging.Debug("Using cached decryption of %s", encryptedFilePath)
+			}
+			if err := encrypt.WritePlaintextFile(outputPath, plaintext); err != nil {
+				close(stopWatching)
+				return fmt.Errorf("failed to write decrypted file: %w", err)
+			}
+			metrics.MarkSynced()
+
+			logging.Info("Running command: %s %s", command, strings.Join(args, " "))
+			lastErr = executeCommand(command, args, env, changed, usePTY)
+
+			var exitErr *ExitError
+			if lastErr != nil && !errors.As(lastErr, &exitErr) {
+				close(stopWatching)
+				return lastErr
+			}
+		}
+
+		// Exit if we were asked to stop while the command was running
+		select {
+		case <-interrupt:
+			close(stopWatching)
+			return lastErr
+		default:
+		}
+
+		// Wait for the next change unless it already happened
+		select {
+		case <-changed:
+		default:
+			if lastErr != nil {
+				logging.Info("Command failed: %v", lastErr)
+			}
+			logging.Info("Waiting for changes to %s (Ctrl-C to exit)...", encryptedFilePath)
+			select {
+			case <-changed:
+			case <-interrupt:
+				close(stopWatching)
+				return lastErr
+			}
+		}
+
+		close(stopWatching)
+		logging.Info("%s changed, restarting command", encryptedFilePath)
+	}
+}
+
+// watchFile returns a channel that is closed once the file's size or
+// modification time changes
+func watchFile(filePath string, stop <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{})
+
+	initial, _ := os.Stat(filePath)
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current, err := os.Stat(filePath)
+				if err != nil {
+					continue
+				}
+				if initial == nil || !current.ModTime().Equal(initial.ModTime()) || current.Size() != initial.Size() {
+					close(changed)
+					return
+				}
+			}
+		}
+	}()
+
+	return changed
+}
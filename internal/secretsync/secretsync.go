@@ -0,0 +1,289 @@
+package secretsync
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"simple-sops/internal/encrypt"
+	"simple-sops/internal/metrics"
+	"simple-sops/pkg/logging"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DataLink is the link in the target directory to the current version of the files
+// The files are links through it, so replacing it swaps all of them at once,
+// like Kubernetes does for mounted ConfigMaps and Secrets.
+const DataLink = "..data"
+
+// versionPrefix starts the names of the directories holding a version of the files
+const versionPrefix = "..version-"
+
+// Target is an encrypted file and the name of its decrypted copy
+type Target struct {
+	Source string
+	Name   string
+}
+
+// Syncer keeps decrypted copies of encrypted files in a directory
+type Syncer struct {
+	dir     string
+	files   []string
+	targets []Target
+	keyPath string
+	mode    os.FileMode
+	digests map[string][sha256.Size]byte
+
+	// decrypt returns the plaintext of a file (swappable for tests)
+	decrypt func(inputPath string, keyFile string) ([]byte, bool, error)
+}
+
+// NewSyncer creates a syncer decrypting the files into dir with the given mode
+// The copies are named after the encrypted files, without the infix of the
+// naming convention, so secrets/app.enc.yaml becomes app.yaml.
+func NewSyncer(dir string, files []string, keyPath string, mode os.FileMode, naming config.NamingConvention) (*Syncer, error) {
+	targets, err := targetsFor(files, naming)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := encrypt.NewDecryptCache(2 * len(targets))
+	return &Syncer{
+		dir:     dir,
+		files:   files,
+		targets: targets,
+		keyPath: keyPath,
+		mode:    mode,
+		decrypt: cache.Decrypt,
+	}, nil
+}
+
+// Reconfigure switches to another key and naming convention, e.g. after the config was reloaded
+// The next sync decrypts the files with the new key, the cache only serves
+// plaintexts of the same key, and writes them again, also if their content
+// didn't change.
+func (s *Syncer) Reconfigure(keyPath string, naming config.NamingConvention) error {
+	targets, err := targetsFor(s.files, naming)
+	if err != nil {
+		return err
+	}
+	s.targets = targets
+	s.keyPath = keyPath
+	s.digests = nil
+	return nil
+}
+
+// targetsFor names the decrypted copies of the files
+func targetsFor(files []string, naming config.NamingConvention) ([]Target, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files specified")
+	}
+
+	var targets []Target
+	sources := make(map[string]string)
+	for _, file := range files {
+		name := filepath.Base(file)
+		if plainName, ok := naming.PlainName(name); ok {
+			name = plainName
+		}
+		if strings.HasPrefix(name, "..") {
+			return nil, fmt.Errorf("%s can't be synced, names starting with .. are reserved", file)
+		}
+		if other, ok := sources[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be synced to %s", other, file, name)
+		}
+		sources[name] = file
+		targets = append(targets, Target{Source: file, Name: name})
+	}
+	return targets, nil
+}
+
+// Sync decrypts the files and swaps the directory to them if any of them changed
+// Unchanged ciphertext is served from a cache, so polling doesn't run sops.
+// If a file fails to decrypt, the current files are kept, so readers never
+// see a partial update.
+func (s *Syncer) Sync() (bool, error) {
+	plaintexts := make(map[string][]byte, len(s.targets))
+	digests := make(map[string][sha256.Size]byte, len(s.targets))
+	for _, target := range s.targets {
+		plaintext, hit, err := s.decrypt(target.Source, s.keyPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt %s: %w", target.Source, err)
+		}
+		if hit {
+			logging.Debug("Using cached decryption of %s", target.Source)
+		}
+		plaintexts[target.Name] = plaintext
+		digests[target.Name] = sha256.Sum256(plaintext)
+	}
+
+	if s.digests != nil && maps.Equal(digests, s.digests) {
+		metrics.MarkSynced()
+		return false, nil
+	}
+	if err := s.swap(plaintexts); err != nil {
+		return false, err
+	}
+	s.digests = digests
+	metrics.MarkSynced()
+	return true, nil
+}
+
+// Run syncs the files every interval until it receives a signal other than SIGHUP
+// Failures after the first sync are logged and the current files are kept
+// until the next sync succeeds. SIGHUP calls reload and syncs right away, or
+// keeps the current configuration if reload fails.
+func (s *Syncer) Run(interval time.Duration, signals <-chan os.Signal, reload func() error) error {
+	if _, err := s.Sync(); err != nil {
+		return err
+	}
+	logging.Info("Synced %d file(s) into %s", len(s.targets), s.dir)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return nil
+			}
+			logging.Info("Reloading the configuration")
+			if err := reload(); err != nil {
+				logging.Error("Failed to reload: %v, keeping the current configuration", err)
+				continue
+			}
+			s.syncAndLog()
+		case <-ticker.C:
+			s.syncAndLog()
+		}
+	}
+}
+
+// syncAndLog syncs the files and logs the result
+func (s *Syncer) syncAndLog() {
+	changed, err := s.Sync()
+	if err != nil {
+		logging.Error("%v, keeping the current files", err)
+	} else if changed {
+		logging.Info("Updated the files in %s", s.dir)
+	}
+}
+
+// swap writes a new version of the files and points the data link to it
+func (s *Syncer) swap(plaintexts map[string][]byte) error {
+	if err := os.MkdirAll(s.dir, dirMode(s.mode)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", s.dir, err)
+	}
+
+	version, err := os.MkdirTemp(s.dir, versionPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to create a version directory in %s: %w", s.dir, err)
+	}
+	if err := os.Chmod(version, dirMode(s.mode)); err != nil {
+		os.RemoveAll(version)
+		return fmt.Errorf("failed to set the permissions of %s: %w", version, err)
+	}
+	for name, plaintext := range plaintexts {
+		if err := writeFile(filepath.Join(version, name), plaintext, s.mode); err != nil {
+			os.RemoveAll(version)
+			return err
+		}
+	}
+
+	dataLink := filepath.Join(s.dir, DataLink)
+	previous, _ := os.Readlink(dataLink)
+	if err := replaceLink(filepath.Base(version), dataLink); err != nil {
+		os.RemoveAll(version)
+		return err
+	}
+
+	for _, target := range s.targets {
+		linkTarget := filepath.Join(DataLink, target.Name)
+		if current, err := os.Readlink(filepath.Join(s.dir, target.Name)); err == nil && current == linkTarget {
+			continue
+		}
+		if err := replaceLink(linkTarget, filepath.Join(s.dir, target.Name)); err != nil {
+			return err
+		}
+	}
+
+	s.removeStaleLinks()
+
+	// Remove the previous version, unless the link pointed somewhere else
+	if strings.HasPrefix(previous, versionPrefix) && !strings.ContainsRune(previous, filepath.Separator) {
+		if err := os.RemoveAll(filepath.Join(s.dir, previous)); err != nil {
+			logging.Warn("Failed to remove the previous version %s: %v", previous, err)
+		}
+	}
+	return nil
+}
+
+// removeStaleLinks removes links to files that are no longer synced, e.g. after a rename
+// Only links into the data link are touched, other files in the directory stay.
+func (s *Syncer) removeStaleLinks() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type()&os.ModeSymlink == 0 || slices.ContainsFunc(s.targets, func(target Target) bool { return target.Name == name }) {
+			continue
+		}
+		if target, err := os.Readlink(filepath.Join(s.dir, name)); err == nil && target == filepath.Join(DataLink, name) {
+			os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+}
+
+// replaceLink atomically creates or replaces a symlink
+func replaceLink(target string, link string) error {
+	temp := link + ".tmp"
+	os.Remove(temp)
+	if err := os.Symlink(target, temp); err != nil {
+		return fmt.Errorf("failed to create link %s: %w", link, err)
+	}
+	if err := os.Rename(temp, link); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to replace link %s: %w", link, err)
+	}
+	return nil
+}
+
+// writeFile writes a new file with exactly the given mode, regardless of the umask
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to set the permissions of %s: %w", path, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// dirMode returns the mode of directories that give access to files with the given mode
+// Whoever may read the files may also list the directory.
+func dirMode(fileMode os.FileMode) os.FileMode {
+	mode := os.FileMode(0700)
+	if fileMode&0040 != 0 {
+		mode |= 0050
+	}
+	if fileMode&0004 != 0 {
+		mode |= 0005
+	}
+	return mode
+}
@@ -0,0 +1,131 @@
+package secretsync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"simple-sops/internal/config"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeDecrypt returns the content of the "files" map as plaintext
+func fakeDecrypt(files map[string]string) func(string, string) ([]byte, bool, error) {
+	return func(inputPath string, keyFile string) ([]byte, bool, error) {
+		content, ok := files[inputPath]
+		if !ok {
+			return nil, false, errors.New("can't decrypt")
+		}
+		return []byte(content), false, nil
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	syncer, err := NewSyncer(dir, []string{"secrets/app.enc.yaml", "db.env"}, "key.txt", 0640, config.NamingConvention{})
+	if err != nil {
+		t.Fatalf("NewSyncer failed: %v", err)
+	}
+	files := map[string]string{"secrets/app.enc.yaml": "password: one\n", "db.env": "URL=db\n"}
+	syncer.decrypt = fakeDecrypt(files)
+
+	changed, err := syncer.Sync()
+	if err != nil || !changed {
+		t.Fatalf("Sync() = %v, %v, want true, nil", changed, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.yaml"))
+	if err != nil || string(data) != "password: one\n" {
+		t.Fatalf("Expected app.yaml to be decrypted, got %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "db.env"))
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected db.env with mode 0640, got %v, %v", info, err)
+	}
+	first, _ := os.Readlink(filepath.Join(dir, DataLink))
+
+	// Unchanged plaintext keeps the version
+	if changed, err := syncer.Sync(); err != nil || changed {
+		t.Errorf("Sync() without changes = %v, %v, want false, nil", changed, err)
+	}
+
+	// A change swaps to a new version and removes the old one
+	files["secrets/app.enc.yaml"] = "password: two\n"
+	if changed, err := syncer.Sync(); err != nil || !changed {
+		t.Fatalf("Sync() after a change = %v, %v, want true, nil", changed, err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "app.yaml"))
+	if string(data) != "password: two\n" {
+		t.Errorf("Expected the changed app.yaml, got %q", data)
+	}
+	second, _ := os.Readlink(filepath.Join(dir, DataLink))
+	if second == first {
+		t.Error("Expected the data link to point to a new version")
+	}
+	if _, err := os.Stat(filepath.Join(dir, first)); !os.IsNotExist(err) {
+		t.Errorf("Expected the previous version to be removed, got %v", err)
+	}
+
+	// A failed decryption keeps the current files
+	delete(files, "db.env")
+	files["secrets/app.enc.yaml"] = "password: three\n"
+	if _, err := syncer.Sync(); err == nil {
+		t.Fatal("Expected an error for a file that can't be decrypted")
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "app.yaml"))
+	if string(data) != "password: two\n" {
+		t.Errorf("Expected the files to be kept after a failure, got %q", data)
+	}
+}
+
+func TestNewSyncerNames(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewSyncer(dir, []string{"a/app.enc.yaml", "b/app.yaml"}, "key.txt", 0600, config.NamingConvention{}); err == nil || !strings.Contains(err.Error(), "app.yaml") {
+		t.Errorf("Expected an error for files with the same name, got %v", err)
+	}
+	if _, err := NewSyncer(dir, []string{"..data"}, "key.txt", 0600, config.NamingConvention{}); err == nil {
+		t.Error("Expected an error for a reserved name")
+	}
+}
+
+func TestRunReload(t *testing.T) {
+	dir := t.TempDir()
+	syncer, err := NewSyncer(dir, []string{"app.enc.yaml"}, "old.txt", 0600, config.NamingConvention{})
+	if err != nil {
+		t.Fatalf("NewSyncer failed: %v", err)
+	}
+	var keys []string
+	syncer.decrypt = func(inputPath string, keyFile string) ([]byte, bool, error) {
+		keys = append(keys, keyFile)
+		return []byte("password: one\n"), false, nil
+	}
+
+	signals := make(chan os.Signal, 3)
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	signals <- os.Interrupt
+	reloads := 0
+	err = syncer.Run(time.Hour, signals, func() error {
+		reloads++
+		// The first reload fails and keeps the configuration
+		if reloads == 1 {
+			return errors.New("key unavailable")
+		}
+		return syncer.Reconfigure("new.txt", config.NamingConvention{Infix: "secret"})
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(keys) != 2 || keys[0] != "old.txt" || keys[1] != "new.txt" {
+		t.Errorf("Expected a sync with the old key and one with the reloaded key, got %v", keys)
+	}
+	// The infix changed, so app.enc.yaml keeps its name and app.yaml is removed
+	if _, err := os.Stat(filepath.Join(dir, "app.enc.yaml")); err != nil {
+		t.Errorf("Expected the file under its new name: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "app.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected the link of the old name to be removed, got %v", err)
+	}
+}
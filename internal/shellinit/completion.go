@@ -0,0 +1,85 @@
+package shellinit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// completionFileName is the name of the completion script per shell
+var completionFileName = map[string]string{
+	"bash": "simple-sops",
+	"zsh":  "_simple-sops",
+	"fish": "simple-sops.fish",
+}
+
+// DetectShell returns the shell of the user from $SHELL
+func DetectShell() (string, error) {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if _, ok := completionFileName[shell]; !ok {
+		return "", fmt.Errorf("can't detect a supported shell from SHELL=%q, name one of bash, zsh or fish", os.Getenv("SHELL"))
+	}
+	return shell, nil
+}
+
+// CompletionPath returns where the completion script of a shell is loaded from
+// bash-completion and fish load scripts from their user directories on demand.
+// For zsh the first directory of an exported $FPATH inside the home directory
+// is used, ~/.zsh/completions otherwise, which onPath reports as possibly
+// missing from fpath.
+func CompletionPath(shell string) (path string, onPath bool, err error) {
+	fileName, ok := completionFileName[shell]
+	if !ok {
+		return "", false, fmt.Errorf("installing completions is not supported for %s (supported: bash, zsh, fish)", shell)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	switch shell {
+	case "bash":
+		if dir := os.Getenv("BASH_COMPLETION_USER_DIR"); dir != "" {
+			return filepath.Join(dir, "completions", fileName), true, nil
+		}
+		return filepath.Join(dataHome(home), "bash-completion", "completions", fileName), true, nil
+	case "zsh":
+		for _, dir := range filepath.SplitList(os.Getenv("FPATH")) {
+			if dir != "" && strings.HasPrefix(dir, home+string(filepath.Separator)) {
+				return filepath.Join(dir, fileName), true, nil
+			}
+		}
+		return filepath.Join(home, ".zsh", "completions", fileName), false, nil
+	default:
+		return filepath.Join(configHome(home), "fish", "completions", fileName), true, nil
+	}
+}
+
+// InstallCompletion writes a completion script to its directory
+func InstallCompletion(path string, script []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create completion directory: %w", err)
+	}
+	if err := os.WriteFile(path, script, 0644); err != nil {
+		return fmt.Errorf("failed to write completion script: %w", err)
+	}
+	return nil
+}
+
+// dataHome returns $XDG_DATA_HOME or its default
+func dataHome(home string) string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, ".local", "share")
+}
+
+// configHome returns $XDG_CONFIG_HOME or its default
+func configHome(home string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, ".config")
+}
@@ -0,0 +1,58 @@
+package shellinit
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletionPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("BASH_COMPLETION_USER_DIR", "")
+	t.Setenv("FPATH", "")
+
+	tests := []struct {
+		name   string
+		shell  string
+		env    map[string]string
+		want   string
+		onPath bool
+	}{
+		{"bash", "bash", nil, filepath.Join(home, ".local", "share", "bash-completion", "completions", "simple-sops"), true},
+		{"bash user dir", "bash", map[string]string{"BASH_COMPLETION_USER_DIR": "/opt/bc"}, filepath.Join("/opt/bc", "completions", "simple-sops"), true},
+		{"fish", "fish", nil, filepath.Join(home, ".config", "fish", "completions", "simple-sops.fish"), true},
+		{"fish XDG", "fish", map[string]string{"XDG_CONFIG_HOME": "/xdg"}, filepath.Join("/xdg", "fish", "completions", "simple-sops.fish"), true},
+		{"zsh without FPATH", "zsh", nil, filepath.Join(home, ".zsh", "completions", "_simple-sops"), false},
+		{"zsh FPATH in home", "zsh", map[string]string{"FPATH": "/usr/share/zsh/functions:" + filepath.Join(home, ".zfunc")}, filepath.Join(home, ".zfunc", "_simple-sops"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, onPath, err := CompletionPath(tt.shell)
+			if err != nil || got != tt.want || onPath != tt.onPath {
+				t.Errorf("Expected %s (on path %v), got %s (%v, %v)", tt.want, tt.onPath, got, onPath, err)
+			}
+		})
+	}
+
+	if _, _, err := CompletionPath("powershell"); err == nil {
+		t.Error("Expected an error for powershell")
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/local/bin/fish")
+	if shell, err := DetectShell(); err != nil || shell != "fish" {
+		t.Errorf("Expected fish, got %s (%v)", shell, err)
+	}
+
+	t.Setenv("SHELL", "/bin/tcsh")
+	if _, err := DetectShell(); err == nil {
+		t.Error("Expected an error for tcsh")
+	}
+}
@@ -0,0 +1,143 @@
+package shellinit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// header starts every generated script
+const header = `# simple-sops shell integration
+# Keys loaded with get-key are used by run, decrypt, edit, put and rotate,
+# and removed by clear-key or when the shell exits.
+`
+
+// posixFunctions wrap simple-sops in bash and zsh
+// The wrapper keeps the temporary key of get-key in the shell's environment,
+// which the simple-sops process itself can't change.
+const posixFunctions = `
+__simple_sops_clear_key() {
+  if [ -n "${__SIMPLE_SOPS_TEMP_KEY:-}" ]; then
+    SOPS_AGE_KEY_FILE="$__SIMPLE_SOPS_TEMP_KEY" command simple-sops --quiet clear-key >/dev/null 2>&1
+    if [ "${SOPS_AGE_KEY_FILE:-}" = "$__SIMPLE_SOPS_TEMP_KEY" ]; then
+      unset SOPS_AGE_KEY_FILE
+    fi
+    unset __SIMPLE_SOPS_TEMP_KEY
+  fi
+}
+
+simple-sops() {
+  case "${1:-}" in
+    get-key)
+      shift
+      local key_file
+      key_file="$(command simple-sops get-key --print-path "$@")" || return
+      __simple_sops_clear_key
+      export SOPS_AGE_KEY_FILE="$key_file"
+      __SIMPLE_SOPS_TEMP_KEY="$key_file"
+      echo "SOPS Age key loaded, SOPS_AGE_KEY_FILE set to $key_file"
+      ;;
+    clear-key)
+      command simple-sops "$@" && unset SOPS_AGE_KEY_FILE __SIMPLE_SOPS_TEMP_KEY
+      ;;
+    run|decrypt|edit|put|rotate)
+      if [ -n "${__SIMPLE_SOPS_TEMP_KEY:-}" ]; then
+        local subcommand="$1"
+        shift
+        command simple-sops "$subcommand" --key-file "$__SIMPLE_SOPS_TEMP_KEY" "$@"
+      else
+        command simple-sops "$@"
+      fi
+      ;;
+    *)
+      command simple-sops "$@"
+      ;;
+  esac
+}
+`
+
+// bashExitHook clears the key when bash exits, keeping an existing EXIT trap
+const bashExitHook = `
+__simple_sops_install_exit_trap() {
+  eval "set -- $(trap -p EXIT)"
+  case "${3:-}" in
+    *__simple_sops_clear_key*) return ;;
+  esac
+  __SIMPLE_SOPS_PREVIOUS_EXIT_TRAP="${3:-}"
+  trap '__simple_sops_clear_key; eval "$__SIMPLE_SOPS_PREVIOUS_EXIT_TRAP"' EXIT
+}
+__simple_sops_install_exit_trap
+unset -f __simple_sops_install_exit_trap
+`
+
+// zshExitHook clears the key when zsh exits
+const zshExitHook = `
+autoload -Uz add-zsh-hook
+add-zsh-hook zshexit __simple_sops_clear_key
+`
+
+// fishFunctions wrap simple-sops in fish and clear the key on fish_exit
+const fishFunctions = `
+function __simple_sops_clear_key
+    if set -q __simple_sops_temp_key
+        SOPS_AGE_KEY_FILE=$__simple_sops_temp_key command simple-sops --quiet clear-key >/dev/null 2>&1
+        if test "$SOPS_AGE_KEY_FILE" = "$__simple_sops_temp_key"
+            set -e SOPS_AGE_KEY_FILE
+        end
+        set -e __simple_sops_temp_key
+    end
+end
+
+function simple-sops --wraps simple-sops --description "simple-sops with key lifecycle handling"
+    switch "$argv[1]"
+        case get-key
+            set -l key_file (command simple-sops get-key --print-path $argv[2..-1]); or return
+            __simple_sops_clear_key
+            set -gx SOPS_AGE_KEY_FILE $key_file
+            set -g __simple_sops_temp_key $key_file
+            echo "SOPS Age key loaded, SOPS_AGE_KEY_FILE set to $key_file"
+        case clear-key
+            command simple-sops $argv; or return
+            set -e SOPS_AGE_KEY_FILE
+            set -e __simple_sops_temp_key
+        case run decrypt edit put rotate
+            if set -q __simple_sops_temp_key
+                command simple-sops $argv[1] --key-file $__simple_sops_temp_key $argv[2..-1]
+            else
+                command simple-sops $argv
+            end
+        case '*'
+            command simple-sops $argv
+    end
+end
+
+function __simple_sops_on_exit --on-event fish_exit
+    __simple_sops_clear_key
+end
+`
+
+// scripts are the generated scripts by shell
+var scripts = map[string]string{
+	"bash": header + posixFunctions + bashExitHook,
+	"zsh":  header + posixFunctions + zshExitHook,
+	"fish": header + fishFunctions,
+}
+
+// Shells returns the supported shells
+func Shells() []string {
+	shells := make([]string, 0, len(scripts))
+	for shell := range scripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// Script returns the shell integration script for a shell
+func Script(shell string) (string, error) {
+	script, ok := scripts[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s (supported: %s)", shell, strings.Join(Shells(), ", "))
+	}
+	return script, nil
+}
@@ -0,0 +1,101 @@
+package shellinit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSimpleSops stands in for the binary: get-key creates a temporary key,
+// clear-key removes it and other commands print their arguments
+const fakeSimpleSops = `#!/bin/sh
+[ "$1" = "--quiet" ] && shift
+case "$1" in
+  get-key)
+    dir="$(mktemp -d "$TEST_TMP/simple-sops-XXXXXX")"
+    echo "AGE-SECRET-KEY-1TEST" > "$dir/age-key.txt"
+    echo "$dir/age-key.txt"
+    ;;
+  clear-key)
+    rm -rf "$(dirname "$SOPS_AGE_KEY_FILE")"
+    ;;
+  *)
+    echo "args: $*"
+    ;;
+esac
+`
+
+func TestScript(t *testing.T) {
+	for _, shell := range Shells() {
+		script, err := Script(shell)
+		if err != nil {
+			t.Fatalf("Script(%q) failed: %v", shell, err)
+		}
+		if !strings.Contains(script, "get-key --print-path") || !strings.Contains(script, "__simple_sops_clear_key") {
+			t.Errorf("Script for %s lacks the key lifecycle functions:\n%s", shell, script)
+		}
+	}
+
+	if _, err := Script("tcsh"); err == nil {
+		t.Error("Expected error for unsupported shell, got nil")
+	}
+}
+
+func TestBashKeyLifecycle(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+
+	tempDir := t.TempDir()
+	binDir := filepath.Join(tempDir, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatalf("Failed to create bin directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "simple-sops"), []byte(fakeSimpleSops), 0755); err != nil {
+		t.Fatalf("Failed to write fake binary: %v", err)
+	}
+
+	// An existing EXIT trap keeps running after the one of the script
+	script, _ := Script("bash")
+	session := "trap 'echo previous trap' EXIT\n" + script + `
+simple-sops get-key
+echo "key: $SOPS_AGE_KEY_FILE"
+simple-sops run secrets.yaml cat {}
+simple-sops status
+`
+
+	cmd := exec.Command(bash, "--norc", "--noprofile", "-c", session)
+	cmd.Env = append(os.Environ(), "PATH="+binDir+":"+os.Getenv("PATH"), "TEST_TMP="+tempDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash session failed: %v\n%s", err, output)
+	}
+
+	var keyFile string
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "key: ") {
+			keyFile = strings.TrimPrefix(line, "key: ")
+		}
+	}
+	if keyFile == "" {
+		t.Fatalf("Expected get-key to set SOPS_AGE_KEY_FILE, got:\n%s", output)
+	}
+
+	for _, expected := range []string{
+		"args: run --key-file " + keyFile + " secrets.yaml cat {}",
+		"args: status\n",
+		"previous trap",
+	} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// The exit trap removed the key
+	if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed when the shell exits", keyFile)
+	}
+}
@@ -0,0 +1,170 @@
+package timing
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Phase is a step of processing a file whose duration is measured
+type Phase string
+
+const (
+	// KeyFetch is fetching the Age key, e.g. from 1Password
+	KeyFetch Phase = "key fetch"
+	// SopsExec is running sops
+	SopsExec Phase = "sops exec"
+	// ConfigIO is reading and writing .sops.yaml, including waiting for its lock
+	ConfigIO Phase = "config io"
+)
+
+// phases is the order of the phases in the report
+var phases = []Phase{KeyFetch, SopsExec, ConfigIO}
+
+// sharedLabel names the durations not spent on a single file, like fetching the key once for all files
+const sharedLabel = "(shared)"
+
+var (
+	mu        sync.Mutex
+	enabled   bool
+	started   time.Time
+	files     []string
+	durations map[string]map[Phase]time.Duration
+
+	// Use a variable for time.Now to allow mocking in tests
+	now = time.Now
+)
+
+// Enable enables or disables measuring durations
+// Enabling it discards the durations measured before.
+func Enable(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	enabled = on
+	started = now()
+	files = nil
+	durations = make(map[string]map[Phase]time.Duration)
+}
+
+// Enabled reports whether durations are measured
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Track starts measuring a phase of a file and returns the function stopping it
+// An empty file records a duration shared by all files. Nothing is measured
+// unless timings are enabled.
+func Track(file string, phase Phase) func() {
+	if !Enabled() {
+		return func() {}
+	}
+
+	start := now()
+	return func() {
+		Record(file, phase, now().Sub(start))
+	}
+}
+
+// Record adds the duration of a phase to a file
+func Record(file string, phase Phase, duration time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !enabled {
+		return
+	}
+
+	if file == "" {
+		file = sharedLabel
+	}
+	if _, ok := durations[file]; !ok {
+		files = append(files, file)
+		durations[file] = make(map[Phase]time.Duration)
+	}
+	durations[file][phase] += duration
+}
+
+// Report prints the durations of every file and the statistics of each phase
+func Report(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !enabled {
+		return
+	}
+
+	fmt.Fprintf(w, "\nTimings (total %s):\n", formatDuration(now().Sub(started)))
+	if len(files) == 0 {
+		fmt.Fprintln(w, "  No phases were measured.")
+		return
+	}
+
+	width := len("FILE")
+	for _, file := range files {
+		width = max(width, len(file))
+	}
+
+	fmt.Fprintf(w, "  %-*s", width, "FILE")
+	for _, phase := range phases {
+		fmt.Fprintf(w, "  %10s", phase)
+	}
+	fmt.Fprintf(w, "  %10s\n", "total")
+
+	for _, file := range files {
+		var total time.Duration
+		fmt.Fprintf(w, "  %-*s", width, file)
+		for _, phase := range phases {
+			duration, ok := durations[file][phase]
+			total += duration
+			if !ok {
+				fmt.Fprintf(w, "  %10s", "-")
+				continue
+			}
+			fmt.Fprintf(w, "  %10s", formatDuration(duration))
+		}
+		fmt.Fprintf(w, "  %10s\n", formatDuration(total))
+	}
+
+	// Statistics only make sense for bulk runs
+	perFile := slices.DeleteFunc(slices.Clone(files), func(file string) bool { return file == sharedLabel })
+	if len(perFile) < 2 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n  %d files:\n", len(perFile))
+	for _, phase := range phases {
+		var total, slowest time.Duration
+		var slowestFile string
+		count := 0
+		for _, file := range perFile {
+			duration, ok := durations[file][phase]
+			if !ok {
+				continue
+			}
+			count++
+			total += duration
+			if duration >= slowest {
+				slowest, slowestFile = duration, file
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "  %-10s total %s, avg %s, max %s (%s)\n", phase, formatDuration(total),
+			formatDuration(total/time.Duration(count)), formatDuration(slowest), slowestFile)
+	}
+}
+
+// formatDuration rounds a duration to a readable precision
+func formatDuration(duration time.Duration) string {
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
@@ -0,0 +1,63 @@
+package timing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock advancing by step on every call
+func fakeClock(step time.Duration) func() time.Time {
+	current := time.Unix(0, 0)
+	return func() time.Time {
+		current = current.Add(step)
+		return current
+	}
+}
+
+func TestTrackDisabled(t *testing.T) {
+	Enable(false)
+	Track("secrets.yaml", SopsExec)()
+
+	var out bytes.Buffer
+	Report(&out)
+	if out.Len() != 0 {
+		t.Errorf("Expected no report when disabled, got %q", out.String())
+	}
+}
+
+func TestReport(t *testing.T) {
+	originalNow := now
+	defer func() {
+		now = originalNow
+		Enable(false)
+	}()
+	now = fakeClock(10 * time.Millisecond)
+
+	Enable(true)
+	Track("", KeyFetch)()
+	Track("a.yaml", ConfigIO)()
+	Track("a.yaml", SopsExec)()
+	Record("b.yaml", SopsExec, 30*time.Millisecond)
+
+	var out bytes.Buffer
+	Report(&out)
+	report := out.String()
+
+	for _, want := range []string{
+		"(shared)        10ms           -           -        10ms",
+		"a.yaml             -        10ms        10ms        20ms",
+		"b.yaml             -        30ms           -        30ms",
+		"2 files:",
+		"sops exec  total 40ms, avg 20ms, max 30ms (b.yaml)",
+		"config io  total 10ms, avg 10ms, max 10ms (a.yaml)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "key fetch  total") {
+		t.Errorf("Expected no statistics for shared phases, got:\n%s", report)
+	}
+}
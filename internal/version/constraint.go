@@ -0,0 +1,126 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a list of version comparisons that must all hold, e.g. ">=3.8, <4"
+type Constraint struct {
+	raw         string
+	comparisons []comparison
+}
+
+// comparison compares a version with an operator
+// Versions without a patch or minor number only compare the given parts, so
+// =3.9 matches every 3.9.x release.
+type comparison struct {
+	operator string
+	version  [3]int
+	parts    int
+}
+
+// operators are the supported comparison operators, longest first
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses comparisons separated by commas or spaces
+// A version without an operator must match exactly.
+func ParseConstraint(value string) (Constraint, error) {
+	constraint := Constraint{raw: strings.TrimSpace(value)}
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		operator := "="
+		for _, candidate := range operators {
+			if strings.HasPrefix(field, candidate) {
+				operator = candidate
+				field = strings.TrimPrefix(field, candidate)
+				break
+			}
+		}
+		// Allow a space between the operator and the version
+		if field == "" && i+1 < len(fields) {
+			i++
+			field = fields[i]
+		}
+
+		parsed, parts, ok := parseVersionParts(strings.TrimPrefix(field, "v"))
+		if !ok {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q", value)
+		}
+		constraint.comparisons = append(constraint.comparisons, comparison{operator: operator, version: parsed, parts: parts})
+	}
+
+	if len(constraint.comparisons) == 0 {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	return constraint, nil
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether the version a tool reported satisfies the constraint
+// ok is false if the reported version holds no version number.
+func (c Constraint) Check(reported string) (satisfied bool, ok bool) {
+	have, ok := parseVersion(reported)
+	if !ok {
+		return false, false
+	}
+
+	for _, comparison := range c.comparisons {
+		if !comparison.holds(have) {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// holds compares a version against the comparison
+func (c comparison) holds(have [3]int) bool {
+	order := 0
+	for i := 0; i < c.parts; i++ {
+		if have[i] != c.version[i] {
+			order = 1
+			if have[i] < c.version[i] {
+				order = -1
+			}
+			break
+		}
+	}
+
+	switch c.operator {
+	case ">=":
+		return order >= 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case "<":
+		return order < 0
+	case "!=":
+		return order != 0
+	default:
+		return order == 0
+	}
+}
+
+// parseVersionParts parses a version of one to three numbers and counts its parts
+func parseVersionParts(value string) ([3]int, int, bool) {
+	var parsed [3]int
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return parsed, 0, false
+	}
+	for i, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return parsed, 0, false
+		}
+		parsed[i], _ = strconv.Atoi(part)
+	}
+	return parsed, len(parts), true
+}
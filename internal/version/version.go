@@ -0,0 +1,138 @@
+package version
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X simple-sops/internal/version.Version=v1.2.3 -X simple-sops/internal/version.Commit=... -X simple-sops/internal/version.Date=..."
+// Values left empty are filled from the build info Go embeds in the binary.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info describes the build of the running binary
+type Info struct {
+	Version   string
+	Commit    string
+	Date      string
+	Modified  bool
+	GoVersion string
+	Platform  string
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		fillFromBuildInfo(&info, buildInfo)
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// fillFromBuildInfo fills empty fields from the module and VCS build settings
+func fillFromBuildInfo(info *Info, buildInfo *debug.BuildInfo) {
+	if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+		info.Version = buildInfo.Main.Version
+	}
+
+	// Only use the VCS settings if the commit wasn't set explicitly
+	if info.Commit != "" {
+		return
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+}
+
+// ToolVersion returns the version a tool reports with --version
+// Returns false if the tool is not installed or fails.
+func ToolVersion(name string) (string, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return "", false
+	}
+	return firstLine(string(output)), true
+}
+
+// versionPattern matches the first version number in a tool's output
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// AtLeast checks if the version a tool reported is at least minimum, e.g. 3.9.0
+// ok is false if the reported version holds no version number.
+func AtLeast(reported string, minimum string) (atLeast bool, ok bool) {
+	have, ok := parseVersion(reported)
+	if !ok {
+		return false, false
+	}
+	want, ok := parseVersion(minimum)
+	if !ok {
+		return false, false
+	}
+
+	for i := range have {
+		if have[i] != want[i] {
+			return have[i] > want[i], true
+		}
+	}
+	return true, true
+}
+
+// parseVersion returns the major, minor and patch number of the first version in a string
+func parseVersion(value string) ([3]int, bool) {
+	var parts [3]int
+	match := versionPattern.FindStringSubmatch(value)
+	if match == nil {
+		return parts, false
+	}
+
+	for i, part := range match[1:] {
+		if part != "" {
+			parts[i], _ = strconv.Atoi(part)
+		}
+	}
+	return parts, true
+}
+
+// firstLine returns the first non-empty line of command output
+// sops prints update notices after its version, which are dropped.
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFillFromBuildInfo(t *testing.T) {
+	buildInfo := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2025-01-15T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	info := Info{}
+	fillFromBuildInfo(&info, buildInfo)
+	if info.Version != "v1.4.0" || info.Commit != "0123456789abcdef" || info.Date != "2025-01-15T10:00:00Z" || !info.Modified {
+		t.Errorf("Unexpected info from build info: %+v", info)
+	}
+
+	// Values set with -ldflags take precedence
+	info = Info{Version: "v2.0.0", Commit: "fedcba"}
+	fillFromBuildInfo(&info, buildInfo)
+	if info.Version != "v2.0.0" || info.Commit != "fedcba" || info.Date != "" || info.Modified {
+		t.Errorf("Expected explicit values to be kept, got %+v", info)
+	}
+
+	// Development builds have no module version
+	info = Info{}
+	fillFromBuildInfo(&info, &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}})
+	if info.Version != "" {
+		t.Errorf("Expected no version for a development build, got %q", info.Version)
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	tests := map[string]string{
+		"sops 3.9.0 (latest)\n":                            "sops 3.9.0 (latest)",
+		"\nsops 3.8.1\n[warning] a newer version exists\n": "sops 3.8.1",
+		"v1.2.0\n": "v1.2.0",
+		"":         "",
+	}
+
+	for output, expected := range tests {
+		if got := firstLine(output); got != expected {
+			t.Errorf("firstLine(%q) = %q, expected %q", output, got, expected)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		reported string
+		minimum  string
+		atLeast  bool
+		ok       bool
+	}{
+		{"sops 3.9.0 (latest)", "3.9.0", true, true},
+		{"sops 3.8.1", "3.9.0", false, true},
+		{"sops 3.10.2", "3.9.0", true, true},
+		{"sops 4.0", "3.9.0", true, true},
+		{"sops dev", "3.9.0", false, false},
+	}
+
+	for _, test := range tests {
+		atLeast, ok := AtLeast(test.reported, test.minimum)
+		if atLeast != test.atLeast || ok != test.ok {
+			t.Errorf("AtLeast(%q, %q) = %v, %v, expected %v, %v", test.reported, test.minimum, atLeast, ok, test.atLeast, test.ok)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		reported   string
+		satisfied  bool
+	}{
+		{">=3.8.0, <4.0.0", "sops 3.9.1", true},
+		{">=3.8.0, <4.0.0", "sops 3.7.3", false},
+		{">=3.8.0, <4.0.0", "sops 4.0.0", false},
+		{">= 3.9 < 4", "sops 3.10.0 (latest)", true},
+		{"3.9", "sops 3.9.4", true},
+		{"3.9", "sops 3.10.0", false},
+		{"=3.9.1", "sops 3.9.1", true},
+		{"!=3.9.0", "sops 3.9.0", false},
+		{">3.9", "sops 3.9.9", false},
+		{"<=v3.9", "sops 3.9.9", true},
+	}
+
+	for _, test := range tests {
+		constraint, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) error = %v", test.constraint, err)
+		}
+		satisfied, ok := constraint.Check(test.reported)
+		if !ok || satisfied != test.satisfied {
+			t.Errorf("%q.Check(%q) = %v, %v, expected %v", test.constraint, test.reported, satisfied, ok, test.satisfied)
+		}
+	}
+
+	for _, invalid := range []string{"", ">=", "~3.9", "3.x", "1.2.3.4"} {
+		if _, err := ParseConstraint(invalid); err == nil {
+			t.Errorf("ParseConstraint(%q) accepted an invalid constraint", invalid)
+		}
+	}
+	constraint, _ := ParseConstraint(">=3.8")
+	if _, ok := constraint.Check("sops dev"); ok {
+		t.Error("Check() parsed a version from output without one")
+	}
+}
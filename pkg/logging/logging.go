@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +14,10 @@ var (
 	IsDebugEnabled bool
 	// IsQuietEnabled controls minimal output (exported for tests)
 	IsQuietEnabled bool
+	// IsPorcelainEnabled replaces messages with records for scripts (exported for tests)
+	IsPorcelainEnabled bool
+	// IsNonInteractive makes prompts answer without waiting for input (exported for tests)
+	IsNonInteractive bool
 
 	// Function variables that can be swapped for testing
 	promptChoiceFunc = defaultPromptChoice
@@ -30,27 +35,80 @@ func SetQuietMode(quiet bool) {
 	IsQuietEnabled = quiet
 }
 
+// SetPorcelainMode enables or disables the output for scripts
+// Messages for humans are dropped from stdout, which only carries records.
+func SetPorcelainMode(porcelain bool) {
+	IsPorcelainEnabled = porcelain
+}
+
+// SetNonInteractive enables or disables answering prompts without input
+// Confirmations are declined, inputs are empty and choices fail, so nothing
+// waits for a terminal that isn't there, e.g. on CI.
+func SetNonInteractive(nonInteractive bool) {
+	IsNonInteractive = nonInteractive
+}
+
 // Debug logs a debug message (only if debug mode is enabled)
 func Debug(format string, args ...interface{}) {
 	if IsDebugEnabled {
-		fmt.Fprintf(os.Stdout, "[DEBUG] "+format+"\n", args...)
+		fmt.Fprintf(messageOutput(), "[DEBUG] "+format+"\n", args...)
 	}
 }
 
-// Info logs an informational message (unless quiet mode is enabled)
+// Info logs an informational message (unless quiet or porcelain mode is enabled)
 func Info(format string, args ...interface{}) {
-	if !IsQuietEnabled {
+	if !IsQuietEnabled && !IsPorcelainEnabled {
 		fmt.Fprintf(os.Stdout, format+"\n", args...)
 	}
 }
 
-// Success logs a success message (unless quiet mode is enabled)
+// Success logs a success message (unless quiet or porcelain mode is enabled)
 func Success(format string, args ...interface{}) {
-	if !IsQuietEnabled {
+	if !IsQuietEnabled && !IsPorcelainEnabled {
 		fmt.Fprintf(os.Stdout, format+"\n", args...)
 	}
 }
 
+// Results of records
+const (
+	ResultOK     = "ok"
+	ResultFailed = "failed"
+	// ResultOverdue marks a file that exceeds the rotation policy
+	ResultOverdue = "overdue"
+)
+
+// Record prints what happened to a file in porcelain mode
+// Records are tab-separated lines of file, action and result, e.g.
+// "secrets.yaml\tencrypt\tok". File names with tabs, newlines, quotes or
+// backslashes are quoted like Go strings.
+func Record(file string, action string, result string) {
+	if IsPorcelainEnabled {
+		fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", porcelainField(file), action, result)
+	}
+}
+
+// porcelainField quotes a field that would break the record format
+func porcelainField(value string) string {
+	if strings.ContainsAny(value, "\t\n\r\"\\") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// messageOutput is where messages and prompts go that aren't part of the output
+// In porcelain mode stdout only carries records.
+func messageOutput() *os.File {
+	if IsPorcelainEnabled {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// Warn logs a warning message (always shown)
+func Warn(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
 // Error logs an error message (always shown)
 func Error(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
@@ -98,18 +156,22 @@ func Testing() bool {
 
 // Default implementation of PromptChoice
 func defaultPromptChoice(prompt string, choices []string) (int, error) {
+	if IsNonInteractive {
+		return 0, fmt.Errorf("can't ask %q in non-interactive mode", prompt)
+	}
+
 	// In test mode, avoid actual prompts
 	if isTestMode() {
 		// Default to first choice in test mode
 		return 1, nil
 	}
 
-	fmt.Println(prompt)
+	fmt.Fprintln(messageOutput(), prompt)
 	for i, choice := range choices {
-		fmt.Printf("%d. %s\n", i+1, choice)
+		fmt.Fprintf(messageOutput(), "%d. %s\n", i+1, choice)
 	}
 	var response int
-	fmt.Print("Choose option: ")
+	fmt.Fprint(messageOutput(), "Choose option: ")
 	_, err := fmt.Scanln(&response)
 	if err != nil {
 		return 0, err
@@ -122,6 +184,10 @@ func defaultPromptChoice(prompt string, choices []string) (int, error) {
 
 // Default implementation of PromptInput
 func defaultPromptInput(prompt string) string {
+	if IsNonInteractive {
+		return ""
+	}
+
 	// In test mode, avoid actual prompts
 	if isTestMode() {
 		// Return empty string in test mode
@@ -129,13 +195,19 @@ func defaultPromptInput(prompt string) string {
 	}
 
 	var response string
-	fmt.Print(prompt + ": ")
+	fmt.Fprint(messageOutput(), prompt+": ")
 	fmt.Scanln(&response)
 	return response
 }
 
 // Default implementation of Confirm
 func defaultConfirm(prompt string) bool {
+	// Without input nothing is confirmed
+	if IsNonInteractive {
+		Debug("Declining %q in non-interactive mode", prompt)
+		return false
+	}
+
 	// In test mode, avoid actual prompts
 	if isTestMode() {
 		// Default to confirming in test mode
@@ -143,7 +215,7 @@ func defaultConfirm(prompt string) bool {
 	}
 
 	var response string
-	fmt.Printf("%s [y/N]: ", prompt)
+	fmt.Fprintf(messageOutput(), "%s [y/N]: ", prompt)
 	fmt.Scanln(&response)
 	return response == "y" || response == "Y"
 }
@@ -120,3 +120,57 @@ func TestError(t *testing.T) {
 		t.Error("Expected error output even with quiet mode, got nothing")
 	}
 }
+
+func TestWarn(t *testing.T) {
+	// Warnings should always output regardless of quiet mode
+	SetQuietMode(true)
+	output := captureError(func() {
+		Warn("test warning")
+	})
+	if output != "Warning: test warning\n" {
+		t.Errorf("Expected warning output, got: %q", output)
+	}
+
+	// Reset for other tests
+	SetQuietMode(false)
+}
+
+func TestPorcelain(t *testing.T) {
+	SetPorcelainMode(true)
+	defer SetPorcelainMode(false)
+
+	output := captureOutput(func() {
+		Info("Encrypting secrets.yaml...")
+		Success("File encrypted successfully: secrets.yaml")
+		Record("secrets.yaml", "encrypt", ResultOK)
+		Record("odd\tname.yaml", "encrypt", ResultFailed)
+	})
+	expected := "secrets.yaml\tencrypt\tok\n\"odd\\tname.yaml\"\tencrypt\tfailed\n"
+	if output != expected {
+		t.Errorf("Expected only records in porcelain mode, got: %q", output)
+	}
+
+	// Records are only printed in porcelain mode
+	SetPorcelainMode(false)
+	output = captureOutput(func() {
+		Record("secrets.yaml", "encrypt", ResultOK)
+	})
+	if output != "" {
+		t.Errorf("Expected no records without porcelain mode, got: %q", output)
+	}
+}
+
+func TestNonInteractive(t *testing.T) {
+	SetNonInteractive(true)
+	defer SetNonInteractive(false)
+
+	if defaultConfirm("Overwrite it?") {
+		t.Error("Expected confirmations to be declined in non-interactive mode")
+	}
+	if input := defaultPromptInput("Name"); input != "" {
+		t.Errorf("Expected empty input in non-interactive mode, got %q", input)
+	}
+	if _, err := defaultPromptChoice("Pick one", []string{"a", "b"}); err == nil {
+		t.Error("Expected choices to fail in non-interactive mode")
+	}
+}
@@ -17,14 +17,8 @@ cd "$(dirname "$0")"
 # Create output directory for coverage reports
 mkdir -p coverage
 
-# List of packages to test
-packages=(
-  "./internal/config"
-  "./internal/encrypt"
-  "./internal/keymgmt"
-  "./internal/run"
-  "./pkg/logging"
-)
+# Test every package that has tests, so new packages aren't missed
+mapfile -t packages < <(go list -f '{{if or .TestGoFiles .XTestGoFiles}}{{.ImportPath}}{{end}}' ./...)
 
 # Initialize variables
 total_coverage=0
@@ -45,7 +39,7 @@ export TEST_MODE=1
 
 for pkg in "${packages[@]}"; do
   echo "Testing package: $pkg"
-  pkg_name=$(basename "$pkg")
+  pkg_name=${pkg//\//_}
 
   # Run tests with coverage
   if ! go test -v -coverprofile="coverage/$pkg_name.out" "$pkg"; then
@@ -75,7 +69,7 @@ if [ "$package_count" -gt 0 ]; then
   # Combine all coverage files into one
   echo "mode: set" >coverage/all.out
   for pkg in "${packages[@]}"; do
-    pkg_name=$(basename "$pkg")
+    pkg_name=${pkg//\//_}
     if [ -f "coverage/$pkg_name.out" ]; then
       grep -v "mode: set" "coverage/$pkg_name.out" >>coverage/all.out
     fi